@@ -3,6 +3,7 @@ package app
 import (
 	"bankapi/config"
 	"bankapi/constants"
+	"bankapi/keyring"
 	"bankapi/middleware"
 	"bankapi/router"
 	"bankapi/rpc"
@@ -95,6 +96,10 @@ func SetupTestRouter() (*gin.Engine, func()) {
 	}
 	fmt.Println("Migrations applied successfully!")
 
+	if err := keyring.Init(appCtx, db); err != nil {
+		log.Fatalf("Failed to load jwt keyring: %v", err)
+	}
+
 	grpcOptions := []grpc.DialOption{}
 	grpcOptions = append(grpcOptions, grpc.WithTransportCredentials(insecure.NewCredentials()))
 
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BankBreakerConfig holds the circuit breaker and bulkhead limits of a KVB endpoint
+type BankBreakerConfig struct {
+	FailureThreshold int
+	OpenTimeout      time.Duration
+	MaxConcurrent    int
+}
+
+// Default breaker values, used when no endpoint specific value is set
+const (
+	DefaultBreakerFailureThreshold = 5
+	DefaultBreakerOpenTimeout      = 30
+	DefaultBulkheadMaxConcurrent   = 50
+)
+
+// GetBankBreakerConfig returns the breaker configuration of a bank endpoint.
+// Values are read from KVB_BREAKER_<ENDPOINT>_* and fall back to KVB_BREAKER_*,
+// e.g. /fintech/upi/req-pay is configured with KVB_BREAKER_FINTECH_UPI_REQ_PAY_MAX_CONCURRENT.
+func GetBankBreakerConfig(endpoint string) *BankBreakerConfig {
+	prefix := fmt.Sprintf("KVB_BREAKER_%s", endpointEnvName(endpoint))
+
+	failureThreshold := getEnvInt("KVB_BREAKER_FAILURE_THRESHOLD", DefaultBreakerFailureThreshold)
+	openTimeout := getEnvInt("KVB_BREAKER_OPEN_TIMEOUT", DefaultBreakerOpenTimeout)
+	maxConcurrent := getEnvInt("KVB_BREAKER_MAX_CONCURRENT", DefaultBulkheadMaxConcurrent)
+
+	return &BankBreakerConfig{
+		FailureThreshold: getEnvInt(prefix+"_FAILURE_THRESHOLD", failureThreshold),
+		OpenTimeout:      time.Duration(getEnvInt(prefix+"_OPEN_TIMEOUT", openTimeout)) * time.Second,
+		MaxConcurrent:    getEnvInt(prefix+"_MAX_CONCURRENT", maxConcurrent),
+	}
+}
+
+func endpointEnvName(endpoint string) string {
+	replacer := strings.NewReplacer("/", "_", "-", "_", ".", "_")
+	return strings.ToUpper(strings.Trim(replacer.Replace(endpoint), "_"))
+}
@@ -0,0 +1,24 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+// DefaultBankErrorRegistryReload is how often the registry file is checked for changes, in seconds
+const DefaultBankErrorRegistryReload = 30
+
+// BankErrorRegistryConfig points to an optional file that extends the embedded
+// bank error registry
+type BankErrorRegistryConfig struct {
+	File           string
+	ReloadInterval time.Duration
+}
+
+// GetBankErrorRegistryConfig reads BANK_ERROR_REGISTRY_FILE and BANK_ERROR_REGISTRY_RELOAD
+func GetBankErrorRegistryConfig() *BankErrorRegistryConfig {
+	return &BankErrorRegistryConfig{
+		File:           os.Getenv("BANK_ERROR_REGISTRY_FILE"),
+		ReloadInterval: time.Duration(getEnvInt("BANK_ERROR_REGISTRY_RELOAD", DefaultBankErrorRegistryReload)) * time.Second,
+	}
+}
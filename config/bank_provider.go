@@ -0,0 +1,14 @@
+package config
+
+import "os"
+
+// DefaultBankProvider serves users that have no partner bank assigned
+const DefaultBankProvider = "kvb"
+
+// GetDefaultBankProvider reads BANK_PROVIDER_DEFAULT
+func GetDefaultBankProvider() string {
+	if provider := os.Getenv("BANK_PROVIDER_DEFAULT"); provider != "" {
+		return provider
+	}
+	return DefaultBankProvider
+}
@@ -0,0 +1,35 @@
+package config
+
+import "time"
+
+// BankSessionConfig holds the refresh settings of the KVB oauth token and the
+// debit card control session
+type BankSessionConfig struct {
+	RefreshAhead      time.Duration
+	LockTTL           time.Duration
+	CardPublicKeyTTL  time.Duration
+	CardLoginTTL      time.Duration
+	CardRefreshAhead  time.Duration
+	RefreshWaitPeriod time.Duration
+}
+
+// Default session values, in seconds
+const (
+	DefaultTokenRefreshAhead     = 60
+	DefaultSessionLockTTL        = 10
+	DefaultCardPublicKeyTTL      = 86400
+	DefaultCardLoginTTL          = 900
+	DefaultCardRefreshAhead      = 30
+	DefaultSessionRefreshWaitMax = 10
+)
+
+func GetBankSessionConfig() *BankSessionConfig {
+	return &BankSessionConfig{
+		RefreshAhead:      time.Duration(getEnvInt("KVB_TOKEN_REFRESH_AHEAD", DefaultTokenRefreshAhead)) * time.Second,
+		LockTTL:           time.Duration(getEnvInt("KVB_SESSION_LOCK_TTL", DefaultSessionLockTTL)) * time.Second,
+		CardPublicKeyTTL:  time.Duration(getEnvInt("KVB_CARD_PUBLIC_KEY_TTL", DefaultCardPublicKeyTTL)) * time.Second,
+		CardLoginTTL:      time.Duration(getEnvInt("KVB_CARD_LOGIN_TTL", DefaultCardLoginTTL)) * time.Second,
+		CardRefreshAhead:  time.Duration(getEnvInt("KVB_CARD_REFRESH_AHEAD", DefaultCardRefreshAhead)) * time.Second,
+		RefreshWaitPeriod: time.Duration(getEnvInt("KVB_SESSION_REFRESH_WAIT", DefaultSessionRefreshWaitMax)) * time.Second,
+	}
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+// Bank traffic modes, live sends requests to KVB untouched
+const (
+	BankTrafficLive   = "live"
+	BankTrafficRecord = "record"
+	BankTrafficReplay = "replay"
+)
+
+const DefaultBankFixtureDir = "testdata/kvb"
+
+// BankTrafficConfig selects whether outbound bank traffic is recorded to or
+// replayed from fixture files
+type BankTrafficConfig struct {
+	Mode         string
+	FixtureDir   string
+	RedactFields map[string]string
+}
+
+// GetBankTrafficConfig reads KVB_TRAFFIC_MODE, KVB_FIXTURE_DIR and
+// KVB_REDACT_FIELDS. Extra redacted fields are given as field:kind pairs,
+// e.g. KVB_REDACT_FIELDS=CustName:name,RegMobile:mobile
+func GetBankTrafficConfig() *BankTrafficConfig {
+	mode := strings.ToLower(os.Getenv("KVB_TRAFFIC_MODE"))
+	if mode != BankTrafficRecord && mode != BankTrafficReplay {
+		mode = BankTrafficLive
+	}
+
+	fixtureDir := os.Getenv("KVB_FIXTURE_DIR")
+	if fixtureDir == "" {
+		fixtureDir = DefaultBankFixtureDir
+	}
+
+	redactFields := make(map[string]string)
+	for _, pair := range strings.Split(os.Getenv("KVB_REDACT_FIELDS"), ",") {
+		field, kind, found := strings.Cut(strings.TrimSpace(pair), ":")
+		if !found || field == "" || kind == "" {
+			continue
+		}
+		redactFields[field] = kind
+	}
+
+	return &BankTrafficConfig{
+		Mode:         mode,
+		FixtureDir:   fixtureDir,
+		RedactFields: redactFields,
+	}
+}
@@ -0,0 +1,25 @@
+package config
+
+import "time"
+
+// Default beneficiary values, the cooling period is in seconds and the
+// cooling limit in rupees
+const (
+	DefaultBeneficiaryCoolingPeriod = 24 * 60 * 60
+	DefaultBeneficiaryCoolingLimit  = 10000
+)
+
+type BeneficiaryConfig struct {
+	// CoolingPeriod starts when a beneficiary is added, the payments to it
+	// are limited to CoolingLimit in total until it ends
+	CoolingPeriod time.Duration
+	CoolingLimit  int64
+}
+
+// GetBeneficiaryConfig reads BENEFICIARY_COOLING_PERIOD and BENEFICIARY_COOLING_LIMIT
+func GetBeneficiaryConfig() *BeneficiaryConfig {
+	return &BeneficiaryConfig{
+		CoolingPeriod: time.Duration(getEnvInt("BENEFICIARY_COOLING_PERIOD", DefaultBeneficiaryCoolingPeriod)) * time.Second,
+		CoolingLimit:  int64(getEnvInt("BENEFICIARY_COOLING_LIMIT", DefaultBeneficiaryCoolingLimit)),
+	}
+}
@@ -0,0 +1,38 @@
+package config
+
+// Default bulk payout values, the file size is in bytes
+const (
+	DefaultBulkPayoutMaxRows     = 500
+	DefaultBulkPayoutMaxFileSize = 2 * 1024 * 1024
+	DefaultBulkPayoutConcurrency = 5
+)
+
+type BulkPayoutConfig struct {
+	// MaxRows is how many payouts a file may have
+	MaxRows int
+	// MaxFileSize is the largest file accepted
+	MaxFileSize int64
+	// Concurrency is how many payments of a bulk payout are submitted to the bank at once
+	Concurrency int
+}
+
+// GetBulkPayoutConfig reads BULK_PAYOUT_MAX_ROWS, BULK_PAYOUT_MAX_FILE_SIZE
+// and BULK_PAYOUT_CONCURRENCY
+func GetBulkPayoutConfig() *BulkPayoutConfig {
+	bulkPayoutConfig := &BulkPayoutConfig{
+		MaxRows:     getEnvInt("BULK_PAYOUT_MAX_ROWS", DefaultBulkPayoutMaxRows),
+		MaxFileSize: int64(getEnvInt("BULK_PAYOUT_MAX_FILE_SIZE", DefaultBulkPayoutMaxFileSize)),
+		Concurrency: getEnvInt("BULK_PAYOUT_CONCURRENCY", DefaultBulkPayoutConcurrency),
+	}
+	if bulkPayoutConfig.MaxRows <= 0 {
+		bulkPayoutConfig.MaxRows = DefaultBulkPayoutMaxRows
+	}
+	if bulkPayoutConfig.MaxFileSize <= 0 {
+		bulkPayoutConfig.MaxFileSize = DefaultBulkPayoutMaxFileSize
+	}
+	if bulkPayoutConfig.Concurrency <= 0 {
+		bulkPayoutConfig.Concurrency = DefaultBulkPayoutConcurrency
+	}
+
+	return bulkPayoutConfig
+}
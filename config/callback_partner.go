@@ -0,0 +1,146 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Partners calling the callback routes, each has its own credentials
+const (
+	CallbackPartnerSms           = "sms"
+	CallbackPartnerPayment       = "payment"
+	CallbackPartnerKycAudit      = "kyc_audit"
+	CallbackPartnerAccountCreate = "account_create"
+	CallbackPartnerRewards       = "rewards"
+	CallbackPartnerInternal      = "internal"
+	CallbackPartnerAdmin         = "admin"
+)
+
+// Ways a partner authenticates its callbacks
+const (
+	// CallbackAuthHmac signs the timestamp, nonce, method, uri and body with the partner secret
+	CallbackAuthHmac = "hmac"
+	// CallbackAuthApiKey sends the partner secret as X-API-Key, for partners that cannot sign yet
+	CallbackAuthApiKey = "api_key"
+)
+
+// CallbackPartners are all the partners, their configuration is checked on startup
+var CallbackPartners = []string{
+	CallbackPartnerSms,
+	CallbackPartnerPayment,
+	CallbackPartnerKycAudit,
+	CallbackPartnerAccountCreate,
+	CallbackPartnerRewards,
+	CallbackPartnerInternal,
+	CallbackPartnerAdmin,
+}
+
+// DefaultCallbackMaxSkew is how old a signed callback may be, in seconds
+const DefaultCallbackMaxSkew = 300
+
+// DefaultCallbackMaxBodyBytes is the largest signed callback body read
+const DefaultCallbackMaxBodyBytes = 1 << 20
+
+type CallbackPartner struct {
+	Name   string
+	Auth   string
+	Secret string
+	// AllowedIPs are the networks the partner calls from, any address when empty
+	AllowedIPs   []*net.IPNet
+	MaxSkew      time.Duration
+	MaxBodyBytes int64
+}
+
+// CallbackPartnersRequiringSecret guard internal routes, startup fails
+// without their own secret
+var CallbackPartnersRequiringSecret = []string{
+	CallbackPartnerInternal,
+	CallbackPartnerAdmin,
+}
+
+// GetCallbackPartner reads CALLBACK_<PARTNER>_SECRET, CALLBACK_<PARTNER>_AUTH,
+// CALLBACK_<PARTNER>_ALLOWED_IPS, CALLBACK_MAX_SKEW and CALLBACK_MAX_BODY_BYTES.
+// Partners are on hmac auth unless configured for api_key. A partner without
+// its own secret is not configured and all its callbacks are rejected.
+func GetCallbackPartner(name string) (*CallbackPartner, error) {
+	prefix := "CALLBACK_" + strings.ToUpper(name) + "_"
+
+	partner := &CallbackPartner{
+		Name:         name,
+		Auth:         strings.ToLower(os.Getenv(prefix + "AUTH")),
+		Secret:       os.Getenv(prefix + "SECRET"),
+		MaxSkew:      time.Duration(getEnvInt("CALLBACK_MAX_SKEW", DefaultCallbackMaxSkew)) * time.Second,
+		MaxBodyBytes: int64(getEnvInt("CALLBACK_MAX_BODY_BYTES", DefaultCallbackMaxBodyBytes)),
+	}
+
+	switch partner.Auth {
+	case "":
+		partner.Auth = CallbackAuthHmac
+	case CallbackAuthHmac, CallbackAuthApiKey:
+	default:
+		return nil, fmt.Errorf("invalid %sAUTH %q", prefix, partner.Auth)
+	}
+
+	for _, entry := range strings.Split(os.Getenv(prefix+"ALLOWED_IPS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			if strings.Contains(entry, ":") {
+				entry += "/128"
+			} else {
+				entry += "/32"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %sALLOWED_IPS: %w", prefix, err)
+		}
+		partner.AllowedIPs = append(partner.AllowedIPs, network)
+	}
+
+	return partner, nil
+}
+
+// ValidateCallbackPartners returns the first configuration error of the
+// partners, including a partner of CallbackPartnersRequiringSecret without a secret
+func ValidateCallbackPartners() error {
+	for _, name := range CallbackPartners {
+		partner, err := GetCallbackPartner(name)
+		if err != nil {
+			return fmt.Errorf("failed to load callback partner %s: %w", name, err)
+		}
+
+		if partner.Secret == "" && slices.Contains(CallbackPartnersRequiringSecret, name) {
+			return fmt.Errorf("callback partner %s has no CALLBACK_%s_SECRET", name, strings.ToUpper(name))
+		}
+	}
+	return nil
+}
+
+// AllowsIP reports whether the partner may call from ip
+func (p *CallbackPartner) AllowsIP(ip string) bool {
+	if len(p.AllowedIPs) == 0 {
+		return true
+	}
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	for _, network := range p.AllowedIPs {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+
+	return false
+}
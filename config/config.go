@@ -2,8 +2,10 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -33,6 +35,55 @@ func GetServerConfig() *ServerConfig {
 	}
 }
 
+// TrustedProxiesNone is the TRUSTED_PROXIES of a server no load balancer is in
+// front of, such as a local one
+const TrustedProxiesNone = "none"
+
+// GetTrustedProxies returns the load balancers in TRUSTED_PROXIES whose
+// X-Forwarded-For is believed for the client ip, none for TrustedProxiesNone
+func GetTrustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" && proxy != TrustedProxiesNone {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+// ValidateTrustedProxies returns an error when TRUSTED_PROXIES is not set or
+// is not a list of ips and cidrs. Without it behind a load balancer the client
+// ip of the callback allow lists and the rate limits is the load balancer.
+func ValidateTrustedProxies() error {
+	value := strings.TrimSpace(os.Getenv("TRUSTED_PROXIES"))
+	if value == "" {
+		return fmt.Errorf("TRUSTED_PROXIES is not set, list the load balancers or set it to %s", TrustedProxiesNone)
+	}
+	if value == TrustedProxiesNone {
+		return nil
+	}
+
+	for _, proxy := range GetTrustedProxies() {
+		if net.ParseIP(proxy) != nil {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(proxy); err != nil {
+			return fmt.Errorf("invalid TRUSTED_PROXIES entry %q", proxy)
+		}
+	}
+	return nil
+}
+
+// ISTLocation is the time zone of the dates of the bank, Indian Standard Time
+func ISTLocation() *time.Location {
+	location, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		// images without tzdata
+		location = time.FixedZone("IST", 5*60*60+30*60)
+	}
+	return location
+}
+
 // getEnvInt safely retrieves an integer value from environment variables
 // with a fallback default value
 func getEnvInt(key string, defaultValue int) int {
@@ -0,0 +1,33 @@
+package config
+
+import "time"
+
+// Default device registry values, the payment limit is in rupees and the rest in seconds
+const (
+	DefaultDeviceCoolingPeriod       = 24 * 60 * 60
+	DefaultDeviceCoolingPaymentLimit = 10000
+	DefaultDeviceChangeOtpTTL        = 5 * 60
+	DefaultDeviceKeyChallengeTTL     = 2 * 60
+)
+
+type DeviceConfig struct {
+	// CoolingPeriod starts when a new device is trusted, its payments are
+	// limited to CoolingPaymentLimit in total until it ends
+	CoolingPeriod       time.Duration
+	CoolingPaymentLimit float64
+	// ChangeOtpTTL is how long the otp and sim binding of a device change are valid
+	ChangeOtpTTL time.Duration
+	// KeyChallengeTTL is how long a device key challenge can be signed
+	KeyChallengeTTL time.Duration
+}
+
+// GetDeviceConfig reads DEVICE_COOLING_PERIOD, DEVICE_COOLING_PAYMENT_LIMIT,
+// DEVICE_CHANGE_OTP_TTL and DEVICE_KEY_CHALLENGE_TTL
+func GetDeviceConfig() *DeviceConfig {
+	return &DeviceConfig{
+		CoolingPeriod:       time.Duration(getEnvInt("DEVICE_COOLING_PERIOD", DefaultDeviceCoolingPeriod)) * time.Second,
+		CoolingPaymentLimit: float64(getEnvInt("DEVICE_COOLING_PAYMENT_LIMIT", DefaultDeviceCoolingPaymentLimit)),
+		ChangeOtpTTL:        time.Duration(getEnvInt("DEVICE_CHANGE_OTP_TTL", DefaultDeviceChangeOtpTTL)) * time.Second,
+		KeyChallengeTTL:     time.Duration(getEnvInt("DEVICE_KEY_CHALLENGE_TTL", DefaultDeviceKeyChallengeTTL)) * time.Second,
+	}
+}
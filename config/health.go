@@ -0,0 +1,43 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// Default health check values, in seconds
+const (
+	DefaultHealthCacheTTL     = 5
+	DefaultHealthCheckTimeout = 2
+)
+
+// DefaultHealthCriticalChecks fail readiness when down, other checks only degrade it
+const DefaultHealthCriticalChecks = "postgres,redis,mongodb,payment_service,asynq"
+
+type HealthConfig struct {
+	CacheTTL time.Duration
+	Timeout  time.Duration
+	Critical map[string]bool
+}
+
+// GetHealthConfig reads HEALTH_CACHE_TTL, HEALTH_CHECK_TIMEOUT and HEALTH_CRITICAL_CHECKS
+func GetHealthConfig() *HealthConfig {
+	criticalChecks := os.Getenv("HEALTH_CRITICAL_CHECKS")
+	if criticalChecks == "" {
+		criticalChecks = DefaultHealthCriticalChecks
+	}
+
+	critical := make(map[string]bool)
+	for _, name := range strings.Split(criticalChecks, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			critical[name] = true
+		}
+	}
+
+	return &HealthConfig{
+		CacheTTL: time.Duration(getEnvInt("HEALTH_CACHE_TTL", DefaultHealthCacheTTL)) * time.Second,
+		Timeout:  time.Duration(getEnvInt("HEALTH_CHECK_TIMEOUT", DefaultHealthCheckTimeout)) * time.Second,
+		Critical: critical,
+	}
+}
@@ -0,0 +1,21 @@
+package config
+
+import "time"
+
+// DefaultIdempotencyKeyTTL is how long a stored transfer outcome is replayed, in seconds
+const DefaultIdempotencyKeyTTL = 86400
+
+// DefaultIdempotencyKeyLease is how long a request holds its key before a retry
+// may take it over, in seconds. It is longer than the server write timeout.
+const DefaultIdempotencyKeyLease = 300
+
+// GetIdempotencyKeyLease reads IDEMPOTENCY_KEY_LEASE, after which a key left
+// PROCESSING by a request that died is taken over
+func GetIdempotencyKeyLease() time.Duration {
+	return time.Duration(getEnvInt("IDEMPOTENCY_KEY_LEASE", DefaultIdempotencyKeyLease)) * time.Second
+}
+
+// GetIdempotencyKeyTTL reads IDEMPOTENCY_KEY_TTL, after which an Idempotency-Key may be reused
+func GetIdempotencyKeyTTL() time.Duration {
+	return time.Duration(getEnvInt("IDEMPOTENCY_KEY_TTL", DefaultIdempotencyKeyTTL)) * time.Second
+}
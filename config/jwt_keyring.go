@@ -0,0 +1,43 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// Default jwt keyring values, in seconds
+const (
+	DefaultJwtKeyRotationInterval = 30 * 24 * 60 * 60
+	DefaultJwtKeyRefreshInterval  = 60
+)
+
+// DefaultJwtSigningAlgorithm is used for new keys, HS256 keeps the keys shared secrets
+const DefaultJwtSigningAlgorithm = "HS256"
+
+type JwtKeyringConfig struct {
+	// Algorithm of new keys, HS256, RS256 or ES256
+	Algorithm string
+	// RotationInterval is the age of the signing key after which a new one is created, 0 disables rotation
+	RotationInterval time.Duration
+	// RefreshInterval is how often the keys are reloaded, so keys rotated by another instance are picked up
+	RefreshInterval time.Duration
+	// AcceptLegacyTokens accepts tokens signed with JWT_KEY that have no key id
+	AcceptLegacyTokens bool
+}
+
+// GetJwtKeyringConfig reads JWT_SIGNING_ALGORITHM, JWT_KEY_ROTATION_INTERVAL,
+// JWT_KEY_REFRESH_INTERVAL and JWT_ACCEPT_LEGACY_TOKENS
+func GetJwtKeyringConfig() *JwtKeyringConfig {
+	algorithm := strings.ToUpper(os.Getenv("JWT_SIGNING_ALGORITHM"))
+	if algorithm == "" {
+		algorithm = DefaultJwtSigningAlgorithm
+	}
+
+	return &JwtKeyringConfig{
+		Algorithm:          algorithm,
+		RotationInterval:   time.Duration(getEnvInt("JWT_KEY_ROTATION_INTERVAL", DefaultJwtKeyRotationInterval)) * time.Second,
+		RefreshInterval:    time.Duration(getEnvInt("JWT_KEY_REFRESH_INTERVAL", DefaultJwtKeyRefreshInterval)) * time.Second,
+		AcceptLegacyTokens: os.Getenv("JWT_ACCEPT_LEGACY_TOKENS") != "false",
+	}
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default mpin lockout values, the periods and the otp ttl are in seconds
+const (
+	DefaultMpinMaxAttempts    = 5
+	DefaultMpinLockoutPeriods = "300,1800,86400"
+	DefaultMpinForgotOtpTTL   = 5 * 60
+	DefaultMpinHistorySize    = 3
+)
+
+type MpinLockoutConfig struct {
+	// MaxAttempts incorrect mpins in a row lock the mpin
+	MaxAttempts int
+	// Periods are the lengths of the lockouts in a row, the last one repeats
+	Periods []time.Duration
+	// ForgotOtpTTL is how long the otp of a forgotten mpin reset is valid
+	ForgotOtpTTL time.Duration
+}
+
+// GetMpinLockoutConfig reads MAX_MPIN_ATTEMPTS, MPIN_LOCKOUT_PERIODS as
+// comma separated seconds and MPIN_FORGOT_OTP_TTL
+func GetMpinLockoutConfig() (*MpinLockoutConfig, error) {
+	lockoutConfig := &MpinLockoutConfig{
+		MaxAttempts:  getEnvInt("MAX_MPIN_ATTEMPTS", DefaultMpinMaxAttempts),
+		ForgotOtpTTL: time.Duration(getEnvInt("MPIN_FORGOT_OTP_TTL", DefaultMpinForgotOtpTTL)) * time.Second,
+	}
+	if lockoutConfig.MaxAttempts <= 0 {
+		return nil, fmt.Errorf("invalid MAX_MPIN_ATTEMPTS %d", lockoutConfig.MaxAttempts)
+	}
+
+	value := os.Getenv("MPIN_LOCKOUT_PERIODS")
+	if value == "" {
+		value = DefaultMpinLockoutPeriods
+	}
+
+	for _, period := range strings.Split(value, ",") {
+		seconds, err := strconv.Atoi(strings.TrimSpace(period))
+		if err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("invalid MPIN_LOCKOUT_PERIODS %q, expected comma separated seconds", value)
+		}
+		lockoutConfig.Periods = append(lockoutConfig.Periods, time.Duration(seconds)*time.Second)
+	}
+
+	return lockoutConfig, nil
+}
+
+// LockoutPeriod is the length of the nth lockout in a row, counted from 1
+func (c *MpinLockoutConfig) LockoutPeriod(lockoutCount int) time.Duration {
+	if lockoutCount < 1 {
+		lockoutCount = 1
+	}
+	if lockoutCount > len(c.Periods) {
+		lockoutCount = len(c.Periods)
+	}
+
+	return c.Periods[lockoutCount-1]
+}
+
+// GetMpinHistorySize reads MPIN_HISTORY_SIZE, the number of previous mpins a
+// new mpin may not match
+func GetMpinHistorySize() int {
+	size := getEnvInt("MPIN_HISTORY_SIZE", DefaultMpinHistorySize)
+	if size < 1 {
+		return DefaultMpinHistorySize
+	}
+
+	return size
+}
@@ -0,0 +1,28 @@
+package config
+
+import "time"
+
+// Versions of encrypted request payloads, sent as the X-Payload-Version header
+const (
+	// PayloadVersionLegacy is the request payload itself, requests without the header
+	PayloadVersionLegacy = 1
+	// PayloadVersionEnvelope wraps the payload with a timestamp and a nonce
+	PayloadVersionEnvelope = 2
+)
+
+// DefaultPayloadMaxSkew is how old an encrypted request may be, in seconds
+const DefaultPayloadMaxSkew = 300
+
+type PayloadEnvelopeConfig struct {
+	// MinVersion rejects older payloads, 2 once every client sends envelopes
+	MinVersion int
+	MaxSkew    time.Duration
+}
+
+// GetPayloadEnvelopeConfig reads PAYLOAD_MIN_VERSION and PAYLOAD_MAX_SKEW
+func GetPayloadEnvelopeConfig() *PayloadEnvelopeConfig {
+	return &PayloadEnvelopeConfig{
+		MinVersion: getEnvInt("PAYLOAD_MIN_VERSION", PayloadVersionLegacy),
+		MaxSkew:    time.Duration(getEnvInt("PAYLOAD_MAX_SKEW", DefaultPayloadMaxSkew)) * time.Second,
+	}
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rate limit policies, each route group shares the budget of its policy
+const (
+	RateLimitOtp           = "otp"
+	RateLimitMpin          = "mpin"
+	RateLimitAuthorization = "authorization"
+	RateLimitVpaValidation = "vpa_validation"
+)
+
+// Identities a rate limit is counted against
+const (
+	RateLimitByUser   = "user"
+	RateLimitByDevice = "device"
+	// RateLimitByMobile is the mobile_number of the json body of a login
+	RateLimitByMobile = "mobile"
+	RateLimitByIP     = "ip"
+)
+
+// Default escalation values, in seconds
+const (
+	DefaultRateLimitViolationWindow = 60 * 60
+	DefaultRateLimitBlockDuration   = 15 * 60
+	DefaultRateLimitBlockAfter      = 5
+)
+
+type RateLimit struct {
+	Policy string
+	// Limit requests are allowed in any sliding Window
+	Limit  int
+	Window time.Duration
+	// KeyBy are the identities counted separately, a request must fit the budget of each
+	KeyBy []string
+}
+
+// defaultRateLimits are "<limit>/<window seconds>"
+var defaultRateLimits = map[string]string{
+	RateLimitOtp:           "5/300",
+	RateLimitMpin:          "5/300",
+	RateLimitAuthorization: "30/60",
+	RateLimitVpaValidation: "30/60",
+}
+
+// rateLimitKeys leave the ip out, users behind one carrier NAT or, without
+// TRUSTED_PROXIES, everyone behind the load balancer would share a budget
+var rateLimitKeys = map[string][]string{
+	RateLimitOtp:           {RateLimitByUser, RateLimitByDevice},
+	RateLimitMpin:          {RateLimitByUser, RateLimitByDevice},
+	RateLimitAuthorization: {RateLimitByDevice, RateLimitByMobile},
+	RateLimitVpaValidation: {RateLimitByUser, RateLimitByDevice},
+}
+
+// GetRateLimit reads RATE_LIMIT_<POLICY> as "<limit>/<window seconds>"
+func GetRateLimit(policy string) (*RateLimit, error) {
+	defaultValue, ok := defaultRateLimits[policy]
+	if !ok {
+		return nil, fmt.Errorf("unknown rate limit policy %q", policy)
+	}
+
+	key := "RATE_LIMIT_" + strings.ToUpper(policy)
+	value := os.Getenv(key)
+	if value == "" {
+		value = defaultValue
+	}
+
+	limitValue, windowValue, found := strings.Cut(value, "/")
+	limit, limitErr := strconv.Atoi(strings.TrimSpace(limitValue))
+	window, windowErr := strconv.Atoi(strings.TrimSpace(windowValue))
+	if !found || limitErr != nil || windowErr != nil || limit <= 0 || window <= 0 {
+		return nil, fmt.Errorf("invalid %s %q, expected <limit>/<window seconds>", key, value)
+	}
+
+	return &RateLimit{
+		Policy: policy,
+		Limit:  limit,
+		Window: time.Duration(window) * time.Second,
+		KeyBy:  rateLimitKeys[policy],
+	}, nil
+}
+
+type RateLimitEscalationConfig struct {
+	// BlockAfter violations of a user (or ip before authentication, behind
+	// TRUSTED_PROXIES only) within ViolationWindow block it for BlockDuration
+	BlockAfter      int
+	ViolationWindow time.Duration
+	BlockDuration   time.Duration
+}
+
+// GetRateLimitEscalationConfig reads RATE_LIMIT_BLOCK_AFTER, RATE_LIMIT_VIOLATION_WINDOW and RATE_LIMIT_BLOCK_DURATION
+func GetRateLimitEscalationConfig() *RateLimitEscalationConfig {
+	return &RateLimitEscalationConfig{
+		BlockAfter:      getEnvInt("RATE_LIMIT_BLOCK_AFTER", DefaultRateLimitBlockAfter),
+		ViolationWindow: time.Duration(getEnvInt("RATE_LIMIT_VIOLATION_WINDOW", DefaultRateLimitViolationWindow)) * time.Second,
+		BlockDuration:   time.Duration(getEnvInt("RATE_LIMIT_BLOCK_DURATION", DefaultRateLimitBlockDuration)) * time.Second,
+	}
+}
@@ -36,3 +36,8 @@ func GetRedis() *database.InMemory {
 	}
 	return redis
 }
+
+// SetRedis replaces the redis returned by GetRedis, tests use it to run against an in memory server
+func SetRedis(memory *database.InMemory) {
+	redis = memory
+}
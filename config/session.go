@@ -0,0 +1,21 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+// DefaultRefreshTokenTTL is how long an unused device session stays logged in, in seconds
+const DefaultRefreshTokenTTL = 30 * 24 * 60 * 60
+
+// GetRefreshTokenTTL reads REFRESH_TOKEN_TTL
+func GetRefreshTokenTTL() time.Duration {
+	return time.Duration(getEnvInt("REFRESH_TOKEN_TTL", DefaultRefreshTokenTTL)) * time.Second
+}
+
+// IsLegacySessionKeyAllowed reads SESSION_KEY_EXCHANGE_LEGACY. Logins without
+// a public key are rejected unless it is true, apps opted in this way keep
+// using the key of the user as their session key.
+func IsLegacySessionKeyAllowed() bool {
+	return os.Getenv("SESSION_KEY_EXCHANGE_LEGACY") == "true"
+}
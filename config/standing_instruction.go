@@ -0,0 +1,43 @@
+package config
+
+import (
+	"time"
+)
+
+// Default standing instruction values, the reminder lead is in seconds
+const (
+	DefaultStandingInstructionRunHour      = 9
+	DefaultStandingInstructionReminderLead = 24 * 60 * 60
+	DefaultStandingInstructionMaxRetries   = 3
+)
+
+type StandingInstructionConfig struct {
+	// RunHour is the hour of the day, in Location, scheduled transfers run at
+	RunHour int
+	// ReminderLead is how long before a run the pre-debit reminder is sent
+	ReminderLead time.Duration
+	// MaxRetries is how many times a run the bank failed is retried
+	MaxRetries int
+	// Location is the time zone of the schedules, dates are in IST
+	Location *time.Location
+}
+
+// GetStandingInstructionConfig reads STANDING_INSTRUCTION_RUN_HOUR,
+// STANDING_INSTRUCTION_REMINDER_LEAD and STANDING_INSTRUCTION_MAX_RETRIES
+func GetStandingInstructionConfig() *StandingInstructionConfig {
+	standingInstructionConfig := &StandingInstructionConfig{
+		RunHour:      getEnvInt("STANDING_INSTRUCTION_RUN_HOUR", DefaultStandingInstructionRunHour),
+		ReminderLead: time.Duration(getEnvInt("STANDING_INSTRUCTION_REMINDER_LEAD", DefaultStandingInstructionReminderLead)) * time.Second,
+		MaxRetries:   getEnvInt("STANDING_INSTRUCTION_MAX_RETRIES", DefaultStandingInstructionMaxRetries),
+	}
+	if standingInstructionConfig.RunHour < 0 || standingInstructionConfig.RunHour > 23 {
+		standingInstructionConfig.RunHour = DefaultStandingInstructionRunHour
+	}
+	if standingInstructionConfig.MaxRetries < 0 {
+		standingInstructionConfig.MaxRetries = DefaultStandingInstructionMaxRetries
+	}
+
+	standingInstructionConfig.Location = ISTLocation()
+
+	return standingInstructionConfig
+}
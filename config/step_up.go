@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Step-up policies, each sensitive route group requires the factors of its policy
+const (
+	StepUpDeviceBound         = "device_bound"
+	StepUpProfile             = "profile"
+	StepUpCardControl         = "card_control"
+	StepUpBeneficiary         = "beneficiary"
+	StepUpDeviceKey           = "device_key"
+	StepUpStandingInstruction = "standing_instruction"
+	StepUpBulkPayout          = "bulk_payout"
+	StepUpTransferLimit       = "transfer_limit"
+)
+
+// Factors a step-up policy can require
+const (
+	// StepUpFactorMpin is an mpin verified on the session
+	StepUpFactorMpin = "mpin"
+	// StepUpFactorOtp is a step-up otp verified on the session
+	StepUpFactorOtp = "otp"
+	// StepUpFactorDevice is a request from a trusted device
+	StepUpFactorDevice = "device"
+)
+
+type StepUpPolicy struct {
+	Policy string
+	// Factors are all required
+	Factors []string
+	// MaxAge is how long ago the mpin or otp may have been verified
+	MaxAge time.Duration
+}
+
+// defaultStepUpPolicies are "<factor>,<factor>/<max age seconds>"
+var defaultStepUpPolicies = map[string]string{
+	StepUpDeviceBound:         "device/0",
+	StepUpProfile:             "mpin,device/900",
+	StepUpCardControl:         "mpin,device/300",
+	StepUpBeneficiary:         "mpin,device/300",
+	StepUpDeviceKey:           "mpin,device/300",
+	StepUpStandingInstruction: "mpin,device/300",
+	StepUpBulkPayout:          "mpin,otp,device/300",
+	StepUpTransferLimit:       "mpin,device/300",
+}
+
+// GetStepUpPolicy reads STEP_UP_<POLICY> as "<factor>,<factor>/<max age seconds>"
+func GetStepUpPolicy(policy string) (*StepUpPolicy, error) {
+	defaultValue, ok := defaultStepUpPolicies[policy]
+	if !ok {
+		return nil, fmt.Errorf("unknown step-up policy %q", policy)
+	}
+
+	key := "STEP_UP_" + strings.ToUpper(policy)
+	value := os.Getenv(key)
+	if value == "" {
+		value = defaultValue
+	}
+
+	factorsValue, maxAgeValue, found := strings.Cut(value, "/")
+	maxAge, err := strconv.Atoi(strings.TrimSpace(maxAgeValue))
+	if !found || err != nil || maxAge < 0 {
+		return nil, fmt.Errorf("invalid %s %q, expected <factor>,<factor>/<max age seconds>", key, value)
+	}
+
+	stepUpPolicy := &StepUpPolicy{
+		Policy: policy,
+		MaxAge: time.Duration(maxAge) * time.Second,
+	}
+
+	for _, factor := range strings.Split(factorsValue, ",") {
+		factor = strings.TrimSpace(factor)
+		switch factor {
+		case StepUpFactorMpin, StepUpFactorOtp:
+			if maxAge == 0 {
+				return nil, fmt.Errorf("invalid %s %q, %s needs a max age", key, value, factor)
+			}
+		case StepUpFactorDevice:
+		default:
+			return nil, fmt.Errorf("invalid %s %q, unknown factor %q", key, value, factor)
+		}
+		stepUpPolicy.Factors = append(stepUpPolicy.Factors, factor)
+	}
+
+	return stepUpPolicy, nil
+}
+
+// DefaultStepUpGrantTTL is how long a verified mpin or otp is kept, in seconds,
+// it bounds the max age of every policy
+const DefaultStepUpGrantTTL = 15 * 60
+
+// GetStepUpGrantTTL reads STEP_UP_GRANT_TTL
+func GetStepUpGrantTTL() time.Duration {
+	return time.Duration(getEnvInt("STEP_UP_GRANT_TTL", DefaultStepUpGrantTTL)) * time.Second
+}
+
+// DefaultStepUpOtpTTL is how long a step-up otp is valid, in seconds
+const DefaultStepUpOtpTTL = 5 * 60
+
+// GetStepUpOtpTTL reads STEP_UP_OTP_TTL
+func GetStepUpOtpTTL() time.Duration {
+	return time.Duration(getEnvInt("STEP_UP_OTP_TTL", DefaultStepUpOtpTTL)) * time.Second
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Payment modes with transfer limits
+const (
+	TransferModeNeft = "NEFT"
+	TransferModeImps = "IMPS"
+	TransferModeIft  = "IFT"
+	TransferModeUpi  = "UPI"
+)
+
+// TransferModes are the payment modes with transfer limits
+var TransferModes = []string{TransferModeNeft, TransferModeImps, TransferModeIft, TransferModeUpi}
+
+// Tiers of users with their own default transfer limits
+const (
+	// TransferTierMinKyc is a user without an approved video kyc
+	TransferTierMinKyc = "MIN_KYC"
+	// TransferTierNewAccount is a full kyc user whose account is newer than
+	// TRANSFER_LIMIT_NEW_ACCOUNT_DAYS
+	TransferTierNewAccount = "NEW_ACCOUNT"
+	TransferTierFullKyc    = "FULL_KYC"
+)
+
+// Default transfer limit values, the new account period is in days and the
+// otp hold in seconds
+const (
+	DefaultTransferLimitNewAccountDays  = 30
+	DefaultTransferLimitFullKycStatuses = "APPROVED"
+	DefaultTransferLimitOtpHold         = 30 * 60
+)
+
+type TransferLimit struct {
+	Tier string
+	Mode string
+	// PerTransaction, Daily and Monthly are in rupees, days and months are in IST
+	PerTransaction int64
+	Daily          int64
+	Monthly        int64
+}
+
+// defaultTransferLimits are "<per transaction>/<daily>/<monthly>" in rupees
+var defaultTransferLimits = map[string]map[string]string{
+	TransferTierMinKyc: {
+		TransferModeNeft: "10000/25000/50000",
+		TransferModeImps: "10000/25000/50000",
+		TransferModeIft:  "10000/25000/50000",
+		TransferModeUpi:  "10000/25000/50000",
+	},
+	TransferTierNewAccount: {
+		TransferModeNeft: "50000/100000/300000",
+		TransferModeImps: "50000/100000/300000",
+		TransferModeIft:  "50000/100000/300000",
+		TransferModeUpi:  "25000/50000/200000",
+	},
+	TransferTierFullKyc: {
+		TransferModeNeft: "1000000/1000000/5000000",
+		TransferModeImps: "500000/500000/2500000",
+		TransferModeIft:  "1000000/1000000/5000000",
+		TransferModeUpi:  "100000/100000/1000000",
+	},
+}
+
+// GetTransferLimit reads TRANSFER_LIMIT_<TIER>_<MODE> as
+// "<per transaction>/<daily>/<monthly>" in rupees
+func GetTransferLimit(tier, mode string) (*TransferLimit, error) {
+	defaultValue, ok := defaultTransferLimits[tier][mode]
+	if !ok {
+		return nil, fmt.Errorf("unknown transfer limit %s %s", tier, mode)
+	}
+
+	key := "TRANSFER_LIMIT_" + tier + "_" + mode
+	value := os.Getenv(key)
+	if value == "" {
+		value = defaultValue
+	}
+
+	parts := strings.Split(value, "/")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid %s %q, expected <per transaction>/<daily>/<monthly>", key, value)
+	}
+
+	amounts := make([]int64, len(parts))
+	for i, part := range parts {
+		amount, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err != nil || amount <= 0 {
+			return nil, fmt.Errorf("invalid %s %q, expected <per transaction>/<daily>/<monthly>", key, value)
+		}
+		amounts[i] = amount
+	}
+
+	return &TransferLimit{
+		Tier:           tier,
+		Mode:           mode,
+		PerTransaction: amounts[0],
+		Daily:          amounts[1],
+		Monthly:        amounts[2],
+	}, nil
+}
+
+type TransferLimitConfig struct {
+	// NewAccountPeriod is how long a full kyc account has the new account limits
+	NewAccountPeriod time.Duration
+	// FullKycStatuses are the video kyc audit statuses of a full kyc user, in upper case
+	FullKycStatuses []string
+	// OtpHold is how long the usage of a payment waiting for its otp is
+	// counted, it is counted again when the otp is entered later
+	OtpHold time.Duration
+}
+
+// GetTransferLimitConfig reads TRANSFER_LIMIT_NEW_ACCOUNT_DAYS,
+// TRANSFER_LIMIT_FULL_KYC_STATUSES and TRANSFER_LIMIT_OTP_HOLD
+func GetTransferLimitConfig() *TransferLimitConfig {
+	newAccountDays := getEnvInt("TRANSFER_LIMIT_NEW_ACCOUNT_DAYS", DefaultTransferLimitNewAccountDays)
+	if newAccountDays < 0 {
+		newAccountDays = DefaultTransferLimitNewAccountDays
+	}
+
+	otpHold := getEnvInt("TRANSFER_LIMIT_OTP_HOLD", DefaultTransferLimitOtpHold)
+	if otpHold <= 0 {
+		otpHold = DefaultTransferLimitOtpHold
+	}
+
+	statuses := os.Getenv("TRANSFER_LIMIT_FULL_KYC_STATUSES")
+	if statuses == "" {
+		statuses = DefaultTransferLimitFullKycStatuses
+	}
+
+	transferLimitConfig := &TransferLimitConfig{
+		NewAccountPeriod: time.Duration(newAccountDays) * 24 * time.Hour,
+		OtpHold:          time.Duration(otpHold) * time.Second,
+	}
+	for _, status := range strings.Split(statuses, ",") {
+		if status = strings.ToUpper(strings.TrimSpace(status)); status != "" {
+			transferLimitConfig.FullKycStatuses = append(transferLimitConfig.FullKycStatuses, status)
+		}
+	}
+
+	return transferLimitConfig
+}
@@ -12,6 +12,7 @@ const (
 	FORGOT_MPIN = "FORGOT_MPIN"
 	VERIFY_MPIN = "VERIFY_MPIN"
 	UPDATE_MPIN = "UPDATE_MPIN"
+	MPIN_LOCKED = "MPIN_LOCKED"
 
 	// debit-card
 	DEBIT_CARD_PIN_RESET         = "DEBIT_CARD_PIN_RESET"
@@ -39,4 +40,57 @@ const (
 
 	// faq
 	FAQ = "FAQ"
+
+	// partner callbacks
+	CALLBACK_REJECTED = "CALLBACK_REJECTED"
+
+	// encrypted request envelopes
+	PAYLOAD_REPLAY_REJECTED = "PAYLOAD_REPLAY_REJECTED"
+
+	// rate limits
+	RATE_LIMIT_BLOCKED = "RATE_LIMIT_BLOCKED"
+
+	// idempotency keys
+	IDEMPOTENCY = "IDEMPOTENCY"
+
+	// trusted devices
+	DEVICE              = "DEVICE"
+	DEVICE_CHANGED      = "DEVICE_CHANGED"
+	DEVICE_DEREGISTERED = "DEVICE_DEREGISTERED"
+
+	// step-up authentication
+	STEP_UP          = "STEP_UP"
+	STEP_UP_REQUIRED = "STEP_UP_REQUIRED"
+
+	// device keys
+	DEVICE_KEY_REGISTERED = "DEVICE_KEY_REGISTERED"
+	DEVICE_KEY_REVOKED    = "DEVICE_KEY_REVOKED"
+	DEVICE_KEY_LOGIN      = "DEVICE_KEY_LOGIN"
+	DEVICE_KEY_STEP_UP    = "DEVICE_KEY_STEP_UP"
+
+	// standing instructions
+	STANDING_INSTRUCTION          = "STANDING_INSTRUCTION"
+	STANDING_INSTRUCTION_CREATED  = "STANDING_INSTRUCTION_CREATED"
+	STANDING_INSTRUCTION_UPDATED  = "STANDING_INSTRUCTION_UPDATED"
+	STANDING_INSTRUCTION_REMINDER = "STANDING_INSTRUCTION_REMINDER"
+	STANDING_INSTRUCTION_OTP      = "STANDING_INSTRUCTION_OTP"
+	STANDING_INSTRUCTION_FAILED   = "STANDING_INSTRUCTION_FAILED"
+
+	// bulk payouts
+	BULK_PAYOUT           = "BULK_PAYOUT"
+	BULK_PAYOUT_UPLOADED  = "BULK_PAYOUT_UPLOADED"
+	BULK_PAYOUT_CONFIRMED = "BULK_PAYOUT_CONFIRMED"
+	BULK_PAYOUT_COMPLETED = "BULK_PAYOUT_COMPLETED"
+
+	// transfer limits
+	TRANSFER_LIMIT         = "TRANSFER_LIMIT"
+	TRANSFER_LIMIT_UPDATED = "TRANSFER_LIMIT_UPDATED"
+
+	// beneficiaries
+	BENEFICIARY_ADDED    = "BENEFICIARY_ADDED"
+	BENEFICIARY_UPDATED  = "BENEFICIARY_UPDATED"
+	BENEFICIARY_DISABLED = "BENEFICIARY_DISABLED"
+	BENEFICIARY_ENABLED  = "BENEFICIARY_ENABLED"
+	BENEFICIARY_DELETED  = "BENEFICIARY_DELETED"
+	BENEFICIARY_SYNCED   = "BENEFICIARY_SYNCED"
 )
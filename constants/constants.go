@@ -265,6 +265,9 @@ var (
 	ErrOtpIsRequired           = errors.New("otp is required")
 	ErrBeneficiaryIdIsRequired = errors.New("beneficiary id is required")
 	ErrKycConsentNotProvided   = errors.New("kyc consent for given number is not provided")
+	ErrRefreshTokenReused      = errors.New("refresh token reused")
+	ErrDeviceNotTrusted        = errors.New("device is not trusted")
+	ErrMpinLocked              = errors.New("mpin is locked")
 )
 
 const (
@@ -364,7 +367,24 @@ const (
 )
 
 const (
-	TokenKeyFormat = "token:%s"
+	TokenKeyFormat         = "token:%s"
+	SessionKeyFormat       = "session:%s"
+	CallbackNonceKeyFormat = "callback_nonce:%s:%s"
+	PayloadNonceKeyFormat  = "payload_nonce:%s:%s"
+
+	RateLimitKeyFormat          = "rate_limit:%s:%s:%s"
+	RateLimitViolationKeyFormat = "rate_limit_violations:%s"
+	RateLimitBlockKeyFormat     = "rate_limit_block:%s"
+
+	DeviceChangeOtpKeyFormat = "device_change_otp:%s:%s"
+	DeviceChangeSimKeyFormat = "device_change_sim:%s"
+
+	StepUpGrantKeyFormat = "step_up:%s:%s:%s"
+	StepUpOtpKeyFormat   = "step_up_otp:%s:%s"
+
+	DeviceKeyChallengeKeyFormat = "device_key_challenge:%s"
+
+	ForgotMpinOtpKeyFormat = "forgot_mpin_otp:%s"
 
 	ATM                      = "01"
 	POS                      = "02"
@@ -405,6 +425,11 @@ var AllowedDomains = []string{
 
 const (
 	AuditLogType = "audit_logs"
+
+	StandingInstructionExecuteType  = "standing_instruction:execute"
+	StandingInstructionReminderType = "standing_instruction:reminder"
+
+	BulkPayoutExecuteType = "bulk_payout:execute"
 )
 
 const (
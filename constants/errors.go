@@ -1,7 +1,5 @@
 package constants
 
-import "strings"
-
 const (
 	RetryErrorMessage            = "We cannot process your request this time. Please try again later."
 	InputErrorMessage            = "Please enter correct input."
@@ -10,6 +8,60 @@ const (
 	UpiInvalidMpinErrorMessage   = "Please enter correct Upi Mpin."
 	AddressUpdateInProgressError = "Your address modification request is in progress, bank will notify once your address will be updated"
 	AadhaarNumberMismatchError   = "Please enter the correct first six digits of your Aadhaar number."
+	BankUnavailableErrorMessage  = "Bank services are temporarily unavailable. Please try again after some time."
+
+	IdempotencyKeyMissingErrorMessage    = "Idempotency-Key header is required for payments."
+	IdempotencyKeyInvalidErrorMessage    = "Idempotency-Key header must be at most 255 characters."
+	IdempotencyKeyReusedErrorMessage     = "Idempotency-Key has already been used for a different payment."
+	IdempotencyKeyInProgressErrorMessage = "A payment with this Idempotency-Key is already in progress."
+
+	InvalidRefreshTokenErrorMessage = "Your session has expired. Please login again."
+
+	SessionKeyExchangeRequiredErrorMessage = "Please update the app to continue."
+
+	RateLimitedErrorMessage = "Too many attempts. Please try again later."
+
+	DeviceNotTrustedErrorMessage       = "This device is not registered. Please register it to continue."
+	DeviceCoolingLimitErrorMessage     = "Payments from a newly registered device are limited to ₹%s in total until %s. You can pay up to ₹%s more from it for now."
+	DeviceChangeInvalidOtpErrorMessage = "Please enter the correct otp."
+
+	StepUpRequiredErrorMessage   = "Please verify it's you to continue."
+	StepUpInvalidOtpErrorMessage = "Please enter the correct otp."
+
+	MpinLockedErrorMessage           = "Your MPIN is locked after too many incorrect attempts. Please try again in %s or reset your MPIN."
+	ForgotMpinInvalidOtpErrorMessage = "Please enter the correct otp."
+
+	MpinRepeatedDigitsErrorMessage = "Your MPIN cannot repeat the same digits. Please choose a different MPIN."
+	MpinSequentialErrorMessage     = "Your MPIN cannot be a run of digits like 1234 or 4321. Please choose a different MPIN."
+	MpinDateOfBirthErrorMessage    = "Your MPIN cannot be part of your date of birth. Please choose a different MPIN."
+	MpinMobileNumberErrorMessage   = "Your MPIN cannot be part of your mobile number. Please choose a different MPIN."
+	MpinReusedErrorMessage         = "Your MPIN cannot be one of your last %d MPINs. Please choose a different MPIN."
+
+	StandingInstructionBeneficiaryErrorMessage = "Scheduled transfers can only be made to a verified beneficiary."
+	StandingInstructionScheduleErrorMessage    = "The schedule has no upcoming transfer. Please check the start and end dates."
+	StandingInstructionStatusErrorMessage      = "This scheduled transfer cannot be %s."
+	StandingInstructionOtpErrorMessage         = "This scheduled transfer is not waiting for an otp."
+
+	BulkPayoutFileErrorMessage   = "Please upload a CSV or XLSX file with the columns beneficiary name, account number, IFSC, amount and remarks."
+	BulkPayoutRowsErrorMessage   = "The file must have between 1 and %d payouts."
+	BulkPayoutStatusErrorMessage = "This bulk payout cannot be %s."
+	BulkPayoutNoValidRowsMessage = "The file has no valid payouts. Please correct the rows and upload it again."
+	BulkPayoutOtpErrorMessage    = "This payout is not waiting for an otp."
+
+	TransferLimitPerTransactionErrorMessage = "%s payments are limited to ₹%s per transaction. Please try a smaller amount."
+	TransferLimitDailyErrorMessage          = "This payment is above your daily %s limit. You can send up to ₹%s more today."
+	TransferLimitMonthlyErrorMessage        = "This payment is above your monthly %s limit. You can send up to ₹%s more this month."
+	TransferLimitAboveTierErrorMessage      = "Your %s limit cannot be above ₹%d."
+	TransferLimitOrderErrorMessage          = "The per transaction limit cannot be above the daily limit, and the daily limit cannot be above the monthly limit."
+
+	PaymentReservationNotFoundErrorMessage  = "No payment is waiting for an otp. Please make the payment again."
+	PaymentReservationMismatchErrorMessage  = "The otp can only be sent again for the same amount and account. Please make the payment again."
+	PaymentReservationConfirmedErrorMessage = "This payment is already confirmed."
+
+	BeneficiaryCoolingLimitErrorMessage = "Payments to a newly added beneficiary are limited to ₹%s until %s. You can send up to ₹%s more to them for now."
+	BeneficiaryDisabledErrorMessage     = "This beneficiary is disabled. Enable it to send money to them."
+	BeneficiaryNotFoundErrorMessage     = "Beneficiary not found."
+	BeneficiaryStatusErrorMessage       = "The beneficiary is already %s."
 )
 
 const (
@@ -19,22 +71,24 @@ const (
 	RetryErrorMW999  = "MW999"
 	RetryUpiError1   = "1"
 	DocumentUpload99 = "99"
-)
 
-var RetryBankErrors = map[string]string{
-	RetryErrorMW9999: RetryErrorMessage,
-	RetryErrorMW9997: RetryErrorMessage,
-	RetryErrorMW9998: RetryErrorMessage,
-	RetryErrorMW999:  RetryErrorMessage,
-	RetryUpiError1:   RetryErrorMessage,
-	DocumentUpload99: RetryErrorMessage,
-}
-
-var RetryUpiErrors = map[string]string{
-	RetryUpiError1: RetryErrorMessage,
-	RetryErrorMW9997: RetryErrorMessage,
-	RetryErrorMW9998: RetryErrorMessage,
-}
+	BankUnavailableErrorCode = "BANK_UNAVAILABLE"
+
+	IdempotencyKeyReusedErrorCode     = "IDEMPOTENCY_KEY_REUSED"
+	IdempotencyKeyInProgressErrorCode = "IDEMPOTENCY_KEY_IN_PROGRESS"
+
+	RateLimitedErrorCode = "RATE_LIMITED"
+
+	DeviceNotTrustedErrorCode = "DEVICE_NOT_TRUSTED"
+
+	StepUpRequiredErrorCode = "STEP_UP_REQUIRED"
+
+	MpinRepeatedDigitsErrorCode = "MPIN_REPEATED_DIGITS"
+	MpinSequentialErrorCode     = "MPIN_SEQUENTIAL"
+	MpinDateOfBirthErrorCode    = "MPIN_MATCHES_DOB"
+	MpinMobileNumberErrorCode   = "MPIN_MATCHES_MOBILE"
+	MpinReusedErrorCode         = "MPIN_REUSED"
+)
 
 const (
 	ConsentErrorCodeMW0011         = "MW0011"
@@ -52,48 +106,12 @@ const (
 	DebitCardControlErrorCodeMW500 = "MW500"
 )
 
-var ConsentErrorMessages = map[string]string{
-	ConsentErrorCodeMW0011:         InputErrorMessage,
-	ConsentErrorCodeMW0012:         InputErrorMessage,
-	ConsentErrorCodeMW0013:         InputErrorMessage,
-	ConsentErrorCodeMW0014:         InputErrorMessage,
-	ConsentErrorCodeMW0015:         InputErrorMessage,
-	ConsentErrorCodeMW0016:         InputErrorMessage,
-	DebitCardControlErrorCode91:    InputErrorMessage,
-	DebitCardControlErrorCode02:    InputErrorMessage,
-	DebitCardControlErrorCode17:    InputErrorMessage,
-	DebitCardControlErrorCode10:    InputErrorMessage,
-	DebitCardControlErrorCode01:    InputErrorMessage,
-	DebitCardControlErrorCode11:    InputErrorMessage,
-	DebitCardControlErrorCodeMW500: InputErrorMessage,
-}
-
-func GetConsentErrorMessage(errorCode string) (string, bool) {
-	message, exists := ConsentErrorMessages[errorCode]
-	return message, exists
-}
-
 const (
 	AccountDetailFetchErrorCodeMW0001 = "MW0001"
 	AccountDetailFetchErrorCodeMW0002 = "MW0002"
 	AccountDetailFetchErrorCodeMW0004 = "MW0004"
 )
 
-var AccountDetailFetchErrorMessages = map[string]string{
-	AccountDetailFetchErrorCodeMW0001: InputErrorMessage,
-	AccountDetailFetchErrorCodeMW0002: InputErrorMessage,
-	AccountDetailFetchErrorCodeMW0004: InputErrorMessage,
-}
-
-func GetAccountDetailFetchErrorMessage(errorCode string) (string, bool) {
-	message, exists := AccountDetailFetchErrorMessages[errorCode]
-	return message, exists
-}
-func GetCardControlService(errorCode string) (string, bool) {
-	message, exists := ConsentErrorMessages[errorCode]
-	return message, exists
-}
-
 const (
 	CasaTxnErrorCode3611   = "3611"
 	CasaTxnErrorCode6083   = "6083"
@@ -102,44 +120,12 @@ const (
 	CasaTxnErrorCodeMW0011 = "MW0011"
 )
 
-var CasaTxnErrorMessages = map[string]string{
-	CasaTxnErrorCode2778:   InputErrorMessage,
-	CasaTxnErrorCodeMW0010: InputErrorMessage,
-	CasaTxnErrorCodeMW0011: InputErrorMessage,
-}
-
-var CasaTxnErrorForRetry = map[string]string{
-	CasaTxnErrorCode3611: RetryErrorMessage,
-	CasaTxnErrorCode6083: RetryErrorMessage,
-}
-
-func GetCasaTxnErrorMessage(errorCode string) (string, bool) {
-	message, exists := CasaTxnErrorMessages[errorCode]
-	return message, exists
-}
-
-func GetCasaTxnErrorRetryMessage(errorCode string) (string, bool) {
-	message, exists := CasaTxnErrorForRetry[errorCode]
-	return message, exists
-}
-
 const (
 	DemographicErrorCodeMW0001 = "MW0001"
 	DemographicErrorCodeMW0002 = "MW0002"
 	DemographicErrorCodeMW0003 = "MW0003"
 )
 
-var DemographicErrorMessages = map[string]string{
-	DemographicErrorCodeMW0001: InputErrorMessage,
-	DemographicErrorCodeMW0002: InputErrorMessage,
-	DemographicErrorCodeMW0003: InputErrorMessage,
-}
-
-func GetDemographicErrorMessage(errorCode string) (string, bool) {
-	message, exists := DemographicErrorMessages[errorCode]
-	return message, exists
-}
-
 const (
 	VCIPInvokeErrorCode205        = "205"
 	VCIPInvokeErrorCode200        = "200"
@@ -148,41 +134,6 @@ const (
 	VCIPInvokeErrorCode200Failure = "failure"
 )
 
-var VcipInvokeErrorMessages = map[string]string{
-	VCIPInvokeErrorCode205: "The given Mobile Number already registered. Please try again with another number",
-	VCIPInvokeErrorCode200: "Close the session and Reinitiate the request since agent unavailable to start VKYC.",
-}
-
-var VcipInvokeErrorForRetry = map[string]string{
-	VCIPInvokeErrorCode500: RetryErrorMessage,
-}
-
-func GetVcipInvokeErrorMessage(errorCode, status string) (string, bool) {
-	message, exists := VcipInvokeErrorMessages[errorCode]
-	if !exists {
-		return "", false
-	}
-
-	if errorCode == VCIPInvokeErrorCode200 {
-		if strings.ToLower(status) == VCIPInvokeErrorCode200Failure {
-			return message, true
-		}
-
-		if strings.ToLower(status) == VCIPInvokeErrorCode200Success {
-			return "", false
-		}
-
-		return message, true
-	}
-
-	return message, true
-}
-
-func GetVcipInvokeErrorRetryMessage(errorCode string) (string, bool) {
-	message, exists := VcipInvokeErrorForRetry[errorCode]
-	return message, exists
-}
-
 const (
 	AccountCreationErrorCodeCI95  = "CI95"
 	AccountCreationErrorCode80004 = "80004"
@@ -192,41 +143,10 @@ const (
 	AccountCreationErrorCode93143 = "93143"
 )
 
-var AccountCreationErrorMessages = map[string]string{
-	AccountCreationErrorCode80004: InputErrorMessage,
-	AccountCreationErrorCode80026: InputErrorMessage,
-	AccountCreationErrorCode93155: "We cannot process your request this time, Annual Turnover code Not Found.",
-}
-
-var AccountCreationRetryableErrorCodes = map[string]string{
-	AccountCreationErrorCodeCI95:  RetryErrorMessage,
-	AccountCreationErrorCode93134: RetryErrorMessage,
-	AccountCreationErrorCode93143: RetryErrorMessage,
-}
-
-func GetAccountCreationRetryErrorCode(errorCode string) (string, bool) {
-	message, exists := AccountCreationRetryableErrorCodes[errorCode]
-	return message, exists
-}
-
-func GetAccountCreationErrorMessage(errorCode string) (string, bool) {
-	message, exists := AccountCreationErrorMessages[errorCode]
-	return message, exists
-}
-
 const (
 	IfscSyncErrorCode01 = "01"
 )
 
-var IfscSyncErrorMessages = map[string]string{
-	IfscSyncErrorCode01: InputErrorMessage,
-}
-
-func GetIfscSyncErrorMessage(errorCode string) (string, bool) {
-	message, exists := IfscSyncErrorMessages[errorCode]
-	return message, exists
-}
-
 const (
 	NomineeErrorCode99     = "99"
 	NomineeErrorCodeMW0002 = "MW0002"
@@ -237,59 +157,12 @@ const (
 	NomineeErrorCode90     = "90"
 )
 
-var NomineeErrorMessages = map[string]string{
-	NomineeErrorCodeMW0002: InputErrorMessage,
-	NomineeErrorCodeMW0004: InputErrorMessage,
-	NomineeErrorCodeMW0005: InputErrorMessage,
-	NomineeErrorCodeMW0008: InputErrorMessage,
-	NomineeErrorCodeMW0010: InputErrorMessage,
-}
-
-var NomineeErrorRetryMessages = map[string]string{
-	NomineeErrorCode90: RetryErrorMessage,
-	NomineeErrorCode99: RetryErrorMessage,
-}
-
-func GetNomineeErrorMessage(errorCode string) (string, bool) {
-	message, exists := NomineeErrorMessages[errorCode]
-	return message, exists
-}
-
-func GetNomineeErrorRetryMessages(errorCode string) (string, bool) {
-	message, exists := NomineeErrorRetryMessages[errorCode]
-	return message, exists
-}
-
 const (
 	BeneficiaryErrorCodeMW0001 = "MW0001"
 	BeneficiaryErrorCodeMW0002 = "MW0002"
 	BeneficiaryErrorCodeMW0003 = "MW0003"
 )
 
-var BeneficiaryErrorMessages = map[string]string{
-	BeneficiaryErrorCodeMW0001: InputErrorMessage,
-	BeneficiaryErrorCodeMW0002: InputErrorMessage,
-	BeneficiaryErrorCodeMW0003: InputErrorMessage,
-}
-
-// Scenario 3- Beneficiary details sent to KVB, but not received the response. (Back Ofice TimeOut)
-var BeneficiaryRetryErrorMessages = map[string]string{
-	RetryErrorMW9999: RetryErrorMessage,
-	RetryErrorMW9997: RetryErrorMessage,
-	RetryErrorMW9998: RetryErrorMessage,
-}
-
-func GetBeneficiaryErrorMessage(errorCode string) (string, bool) {
-	message, exists := BeneficiaryErrorMessages[errorCode]
-	return message, exists
-}
-
-// Scenario 3- Beneficiary details sent to KVB, but not received the response. (Back Ofice TimeOut)
-func GetBeneficiaryRetryErrorMessage(errorCode string) (string, bool) {
-	message, exists := BeneficiaryRetryErrorMessages[errorCode]
-	return message, exists
-}
-
 const (
 	PaymentCallbackErrorCodeMW0011 = "MW0011"
 	PaymentCallbackErrorCodeMW0014 = "MW0014"
@@ -303,44 +176,10 @@ const (
 	PaymentCallbackErrorCodeMW0028 = "MW0028"
 )
 
-var PaymentCallbackErrorMessages = map[string]string{
-	PaymentCallbackErrorCodeMW0016: InputErrorMessage,
-	PaymentCallbackErrorCodeMW0017: InputErrorMessage,
-}
-
-var PaymentCallbackRetryErrorMessages = map[string]string{
-	PaymentCallbackErrorCodeMW0011: RetryErrorMessage,
-	PaymentCallbackErrorCodeMW0018: RetryErrorMessage,
-	PaymentCallbackErrorCodeMW0021: RetryErrorMessage,
-	PaymentCallbackErrorCodeMW0023: RetryErrorMessage,
-	PaymentCallbackErrorCodeMW0024: RetryErrorMessage,
-	PaymentCallbackErrorCodeMW0027: RetryErrorMessage,
-	PaymentCallbackErrorCodeMW0028: RetryErrorMessage,
-}
-
-func GetPaymentCallbackErrorMessage(errorCode string) (string, bool) {
-	message, exists := PaymentCallbackErrorMessages[errorCode]
-	return message, exists
-}
-
-func GetPaymentCallbackRetryErrorMessage(errorCode string) (string, bool) {
-	message, exists := PaymentCallbackRetryErrorMessages[errorCode]
-	return message, exists
-}
-
 const (
 	QuickTransferBeneficiaryErrorCodeMW0002 = "MW0002"
 )
 
-var QuickTransferBeneficiaryErrorMessages = map[string]string{
-	QuickTransferBeneficiaryErrorCodeMW0002: InputErrorMessage,
-}
-
-func GetQuickTransferBeneficiaryErrorMessage(errorCode string) (string, bool) {
-	message, exists := QuickTransferBeneficiaryErrorMessages[errorCode]
-	return message, exists
-}
-
 const (
 	VirtualDebitCardErrorCodeAN3101 = "AN3101"
 	VirtualDebitCardErrorCodeMW0013 = "MW0013"
@@ -351,69 +190,17 @@ const (
 	VirtualDebitCardErrorCodeLN1100 = "LN1100"
 )
 
-var VirtualDebitCardErrorMessages = map[string]string{
-	VirtualDebitCardErrorCodeAN3101: InputErrorMessage,
-	VirtualDebitCardErrorCodeMW0013: InputErrorMessage,
-	VirtualDebitCardErrorCodeMW0014: InputErrorMessage,
-	VirtualDebitCardErrorCodeMW0001: InputErrorMessage,
-}
-
-var VirtualDebitCardErrorMessagesRetry = map[string]string{
-	VirtualDebitCardErrorCodeCE4000: RetryErrorMessage,
-	VirtualDebitCardErrorCodePX1103: RetryErrorMessage,
-	VirtualDebitCardErrorCodeLN1100: RetryErrorMessage,
-}
-
-func GetVirtualDebitCardErrorMessage(errorCode string) (string, bool) {
-	message, exists := VirtualDebitCardErrorMessages[errorCode]
-	return message, exists
-}
-
-func GetVirtualDebitCardErrorMessageRetry(errorCode string) (string, bool) {
-	message, exists := VirtualDebitCardErrorMessagesRetry[errorCode]
-	return message, exists
-}
-
 const (
 	DebitCardFetchErrorCodeMW0010 = "MW0010"
 	DebitCardFetchErrorCodeMW0030 = "MW0030"
 	DebitCardFetchErrorCodePX1103 = "PX1103"
 )
 
-var DebitCardFetchErrorMessages = map[string]string{
-	DebitCardFetchErrorCodeMW0010: InputErrorMessage,
-	DebitCardFetchErrorCodeMW0030: InputErrorMessage,
-}
-
-var DebitCardFetchErrorCodeRetry = map[string]string{
-	DebitCardFetchErrorCodePX1103: RetryErrorMessage,
-}
-
-func GetDebitCardFetchErrorMessage(errorCode string) (string, bool) {
-	message, exists := DebitCardFetchErrorMessages[errorCode]
-	return message, exists
-}
-
-func GetDebitCardFetchRetryErrorMessage(errorCode string) (string, bool) {
-	message, exists := DebitCardFetchErrorCodeRetry[errorCode]
-	return message, exists
-}
-
 const (
 	PhysicalDebitCardErrorCodeMW0031 = "MW0031"
 	PhysicalDebitCardErrorCodeMW0030 = "MW0030"
 )
 
-var PhysicalDebitCardErrorMessages = map[string]string{
-	PhysicalDebitCardErrorCodeMW0031: InputErrorMessage,
-	PhysicalDebitCardErrorCodeMW0030: InputErrorMessage,
-}
-
-func GetPhysicalDebitCardErrorMessage(errorCode string) (string, bool) {
-	message, exists := PhysicalDebitCardErrorMessages[errorCode]
-	return message, exists
-}
-
 const (
 	OTPErrorCodeMW0029 = "MW0029"
 	OTPErrorCodeMW0028 = "MW0028"
@@ -421,26 +208,6 @@ const (
 	OTPErrorCode90     = "90"
 )
 
-var OTPErrorMessages = map[string]string{
-	OTPErrorCode90: "OTP Expired. Please initiate Resent OTP.",
-}
-
-var OTPRetryErrorMessages = map[string]string{
-	OTPErrorCodeMW0029: RetryErrorMessage,
-	OTPErrorCodeMW0028: RetryErrorMessage,
-	OTPErrorCodeMW0030: RetryErrorMessage,
-}
-
-func GetOTPErrorMessage(errorCode string) (string, bool) {
-	message, exists := OTPErrorMessages[errorCode]
-	return message, exists
-}
-
-func GetOTPRetryErrorMessage(errorCode string) (string, bool) {
-	message, exists := OTPRetryErrorMessages[errorCode]
-	return message, exists
-}
-
 const (
 	DebitCardSetPinErrorCodeMW0030 = "MW0030"
 	DebitCardSetPinErrorCodeMW0053 = "MW0053"
@@ -454,32 +221,6 @@ const (
 	DebitCardSetPinErrorCodeMW0049 = "MW0049"
 )
 
-var DebitCardSetPinRetryErrorMessages = map[string]string{
-	DebitCardSetPinErrorCodeMW0030: RetryErrorMessage,
-	DebitCardSetPinErrorCodeMW0053: RetryErrorMessage,
-	DebitCardSetPinErrorCodeMW0054: RetryErrorMessage,
-	DebitCardSetPinErrorCodeMW0055: RetryErrorMessage,
-	DebitCardSetPinErrorCodeMW0056: RetryErrorMessage,
-	DebitCardSetPinErrorCodeMW0057: RetryErrorMessage,
-	DebitCardSetPinErrorCodeMW0052: RetryErrorMessage,
-	DebitCardSetPinErrorCodeMW0051: RetryErrorMessage,
-	DebitCardSetPinErrorCodeMW0050: RetryErrorMessage,
-}
-
-var DebitCardSetPinErrorMessages = map[string]string{
-	DebitCardSetPinErrorCodeMW0049: RetryErrorMessage,
-}
-
-func GetDebitCardSetPinRetryErrorMessage(errorCode string) (string, bool) {
-	message, exists := DebitCardSetPinRetryErrorMessages[errorCode]
-	return message, exists
-}
-
-func GetDebitCardSetPinErrorMessage(errorCode string) (string, bool) {
-	message, exists := DebitCardSetPinErrorMessages[errorCode]
-	return message, exists
-}
-
 const (
 	UpiErrorMessageNoRecordsFound = "no records found"
 	UpiErrorMessageNoDataFound    = "no data found"
@@ -0,0 +1,165 @@
+package devicekey
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+
+	"bankapi/config"
+	"bankapi/constants"
+	"bankapi/models"
+	"bankapi/security"
+)
+
+// Purposes of a challenge, a signed login challenge logs the device in and a
+// signed step-up challenge stands in for the mpin on the session
+const (
+	PurposeLogin  = "login"
+	PurposeStepUp = "step_up"
+)
+
+const (
+	challengeIdSize    = 16
+	challengeNonceSize = 32
+)
+
+var ErrInvalidChallenge = errors.New("device key challenge is invalid or has expired")
+
+// Challenge is what the device signs with its key, the signed message is the Nonce
+type Challenge struct {
+	Id        string `json:"challenge_id"`
+	Nonce     string `json:"challenge"`
+	Purpose   string `json:"purpose"`
+	UserId    string `json:"-"`
+	DeviceId  string `json:"-"`
+	KeyId     string `json:"-"`
+	SessionId string `json:"-"`
+	ExpiresIn int64  `json:"expires_in"`
+}
+
+// storedChallenge is the redis value of a challenge
+type storedChallenge struct {
+	Nonce     string `json:"nonce"`
+	Purpose   string `json:"purpose"`
+	UserId    string `json:"user_id"`
+	DeviceId  string `json:"device_id"`
+	KeyId     string `json:"key_id"`
+	SessionId string `json:"session_id"`
+}
+
+// BoundDeviceData returns the device data of the user when deviceId is the
+// device it is bound to. signingKey is the decrypted signing key of the user.
+func BoundDeviceData(db *sql.DB, userId, signingKey, deviceId string) (*models.DeviceData, error) {
+	deviceData, err := models.FindOneDeviceByUserID(db, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	boundDeviceId, err := security.Decrypt(deviceData.DeviceId, []byte(strings.TrimSpace(signingKey)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt device id: %w", err)
+	}
+
+	if deviceId == "" || boundDeviceId != deviceId {
+		return nil, constants.ErrDeviceNotTrusted
+	}
+
+	return deviceData, nil
+}
+
+// Issue creates a challenge for the key, it can be signed once within DEVICE_KEY_CHALLENGE_TTL
+func Issue(ctx context.Context, key *models.DeviceKey, purpose, sessionId string) (*Challenge, error) {
+	id, err := security.GenerateOpaqueToken(challengeIdSize)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := security.GenerateOpaqueToken(challengeNonceSize)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := json.Marshal(&storedChallenge{
+		Nonce:     nonce,
+		Purpose:   purpose,
+		UserId:    key.UserId,
+		DeviceId:  key.DeviceId,
+		KeyId:     key.Id,
+		SessionId: sessionId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := config.GetDeviceConfig().KeyChallengeTTL
+	if err := config.GetRedis().GetClient().Set(ctx, fmt.Sprintf(constants.DeviceKeyChallengeKeyFormat, id), value, ttl).Err(); err != nil {
+		return nil, fmt.Errorf("failed to save device key challenge: %w", err)
+	}
+
+	return &Challenge{
+		Id:        id,
+		Nonce:     nonce,
+		Purpose:   purpose,
+		UserId:    key.UserId,
+		DeviceId:  key.DeviceId,
+		KeyId:     key.Id,
+		SessionId: sessionId,
+		ExpiresIn: int64(ttl.Seconds()),
+	}, nil
+}
+
+// Verify consumes a challenge and checks its signature by the key it was
+// issued for. The challenge must have been issued for the purpose and the
+// device of the request, and the key must still be active.
+func Verify(ctx context.Context, db *sql.DB, challengeId, purpose, deviceId, signature string) (*Challenge, *models.DeviceKey, error) {
+	value, err := config.GetRedis().GetClient().GetDel(ctx, fmt.Sprintf(constants.DeviceKeyChallengeKeyFormat, challengeId)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil, ErrInvalidChallenge
+		}
+		return nil, nil, fmt.Errorf("failed to get device key challenge: %w", err)
+	}
+
+	var stored storedChallenge
+	if err := json.Unmarshal([]byte(value), &stored); err != nil {
+		return nil, nil, ErrInvalidChallenge
+	}
+
+	if stored.Purpose != purpose || stored.DeviceId != deviceId {
+		return nil, nil, ErrInvalidChallenge
+	}
+
+	key, err := models.GetDeviceKey(db, stored.KeyId)
+	if err != nil {
+		if errors.Is(err, constants.ErrNoDataFound) {
+			return nil, nil, ErrInvalidChallenge
+		}
+		return nil, nil, err
+	}
+
+	if key.Status != models.DeviceKeyStatusActive || key.UserId != stored.UserId {
+		return nil, nil, ErrInvalidChallenge
+	}
+
+	if err := security.VerifyDeviceKeySignature(key.Algorithm, key.PublicKey, []byte(stored.Nonce), signature); err != nil {
+		return nil, nil, err
+	}
+
+	if err := models.TouchDeviceKey(db, key.Id); err != nil {
+		return nil, nil, err
+	}
+
+	return &Challenge{
+		Id:        challengeId,
+		Purpose:   stored.Purpose,
+		UserId:    stored.UserId,
+		DeviceId:  stored.DeviceId,
+		KeyId:     stored.KeyId,
+		SessionId: stored.SessionId,
+	}, key, nil
+}
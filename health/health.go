@@ -0,0 +1,199 @@
+package health
+
+import (
+	"bankapi/config"
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	StatusUp       = "UP"
+	StatusDown     = "DOWN"
+	StatusDegraded = "DEGRADED"
+)
+
+// Probe returns nil when the dependency is usable
+type Probe func(ctx context.Context) error
+
+// CheckResult is the last outcome of a dependency check
+type CheckResult struct {
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	Critical  bool      `json:"critical"`
+	LatencyMs int64     `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Report is the readiness of the service. It is DOWN when a critical check
+// fails and DEGRADED when only non critical checks fail.
+type Report struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+type check struct {
+	name   string
+	probe  Probe
+	result *CheckResult
+	// running is closed when the probe started for an expired result finishes
+	running chan struct{}
+}
+
+// Checker runs the registered checks and caches their results, so probes
+// hitting the pod every few seconds do not load the dependencies.
+type Checker struct {
+	mu       sync.Mutex
+	checks   map[string]*check
+	cacheTTL time.Duration
+	timeout  time.Duration
+	critical map[string]bool
+}
+
+func NewChecker(healthConfig *config.HealthConfig) *Checker {
+	return &Checker{
+		checks:   make(map[string]*check),
+		cacheTTL: healthConfig.CacheTTL,
+		timeout:  healthConfig.Timeout,
+		critical: healthConfig.Critical,
+	}
+}
+
+// Register adds a check, replacing a check with the same name
+func (c *Checker) Register(name string, probe Probe) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks[name] = &check{name: name, probe: probe}
+}
+
+// Ready runs the checks with an expired result and returns the report. A
+// check is run once at a time, concurrent calls wait for its result. The
+// probes do not hold the lock and are not cancelled with ctx, a client giving
+// up must not leave a failed result for the next probe.
+func (c *Checker) Ready(ctx context.Context) *Report {
+	c.mu.Lock()
+	now := time.Now()
+
+	var pending []chan struct{}
+	for _, chk := range c.checks {
+		if chk.running == nil {
+			if chk.result != nil && now.Sub(chk.result.CheckedAt) < c.cacheTTL {
+				continue
+			}
+			chk.running = make(chan struct{})
+			go c.refresh(context.WithoutCancel(ctx), chk)
+		}
+		pending = append(pending, chk.running)
+	}
+	c.mu.Unlock()
+
+	for _, running := range pending {
+		<-running
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	report := &Report{Status: StatusUp}
+	for _, chk := range c.checks {
+		result := CheckResult{
+			Name:     chk.name,
+			Status:   StatusDown,
+			Critical: c.critical[chk.name],
+			Error:    "check has not completed",
+		}
+		if chk.result != nil {
+			result = *chk.result
+		}
+		report.Checks = append(report.Checks, result)
+
+		if result.Status == StatusUp {
+			continue
+		}
+		if result.Critical {
+			report.Status = StatusDown
+		} else if report.Status == StatusUp {
+			report.Status = StatusDegraded
+		}
+	}
+	sort.Slice(report.Checks, func(i, j int) bool {
+		return report.Checks[i].Name < report.Checks[j].Name
+	})
+
+	return report
+}
+
+// refresh runs the probe of chk and caches its result, a probe that was
+// cancelled says nothing about the dependency and is not cached
+func (c *Checker) refresh(ctx context.Context, chk *check) {
+	result, err := c.run(ctx, chk)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !errors.Is(err, context.Canceled) {
+		chk.result = result
+	}
+	close(chk.running)
+	chk.running = nil
+}
+
+func (c *Checker) run(ctx context.Context, chk *check) (*CheckResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	startTime := time.Now()
+
+	// not every client honours ctx, the probe is abandoned after the timeout
+	done := make(chan error, 1)
+	go func() {
+		done <- chk.probe(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = errors.New("check timed out")
+	}
+
+	result := &CheckResult{
+		Name:      chk.name,
+		Status:    StatusUp,
+		Critical:  c.critical[chk.name],
+		LatencyMs: time.Since(startTime).Milliseconds(),
+		CheckedAt: time.Now(),
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+
+	return result, err
+}
+
+var (
+	checker     *Checker
+	checkerOnce sync.Once
+)
+
+func defaultChecker() *Checker {
+	checkerOnce.Do(func() {
+		checker = NewChecker(config.GetHealthConfig())
+	})
+	return checker
+}
+
+// Register adds a check to the readiness of the process
+func Register(name string, probe Probe) {
+	defaultChecker().Register(name, probe)
+}
+
+// Ready returns the readiness of the process
+func Ready(ctx context.Context) *Report {
+	return defaultChecker().Ready(ctx)
+}
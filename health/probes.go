@@ -0,0 +1,98 @@
+package health
+
+import (
+	"bankapi/security"
+	"bankapi/services"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+
+	"bitbucket.org/paydoh/paydoh-commons/database"
+	"github.com/dutchcoders/go-clamd"
+	"github.com/hibiken/asynq"
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// Names of the dependency checks, used in HEALTH_CRITICAL_CHECKS
+const (
+	CheckPostgres       = "postgres"
+	CheckRedis          = "redis"
+	CheckMongoDB        = "mongodb"
+	CheckPaymentService = "payment_service"
+	CheckAsynq          = "asynq"
+	CheckKVBToken       = "kvb_token"
+	CheckClamAV         = "clamav"
+)
+
+func Postgres(db *sql.DB) Probe {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+func Redis(memory *database.InMemory) Probe {
+	return func(ctx context.Context) error {
+		return memory.GetClient().Ping(ctx).Err()
+	}
+}
+
+func MongoDB(client *mongo.Client) Probe {
+	return func(ctx context.Context) error {
+		return client.Ping(ctx, nil)
+	}
+}
+
+// GRPC fails while the connection cannot reach the server. An idle connection
+// is asked to connect, it is reported by the next check.
+func GRPC(conn *grpc.ClientConn) Probe {
+	return func(ctx context.Context) error {
+		switch state := conn.GetState(); state {
+		case connectivity.TransientFailure, connectivity.Shutdown:
+			return fmt.Errorf("connection is %s", state)
+		case connectivity.Idle:
+			conn.Connect()
+		}
+		return nil
+	}
+}
+
+// Asynq fails when the asynq server of this process has no active heartbeat in redis
+func Asynq(redisOpt asynq.RedisConnOpt) Probe {
+	inspector := asynq.NewInspector(redisOpt)
+	host, _ := os.Hostname()
+	pid := os.Getpid()
+
+	return func(ctx context.Context) error {
+		servers, err := inspector.Servers()
+		if err != nil {
+			return err
+		}
+
+		for _, server := range servers {
+			if server.Host == host && server.PID == pid {
+				if server.Status != "active" {
+					return fmt.Errorf("server is %s", server.Status)
+				}
+				return nil
+			}
+		}
+		return fmt.Errorf("server %s:%d is not running", host, pid)
+	}
+}
+
+// KVBToken fails when no bank token is cached and none can be fetched
+func KVBToken(bankService *services.BankApiService) Probe {
+	return func(ctx context.Context) error {
+		_, err := bankService.GenerateToken(ctx)
+		return err
+	}
+}
+
+func ClamAV() Probe {
+	return func(ctx context.Context) error {
+		return clamd.NewClamd(security.ClamAVAddress).Ping()
+	}
+}
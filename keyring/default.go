@@ -0,0 +1,92 @@
+package keyring
+
+import (
+	"bankapi/config"
+	"bankapi/constants"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"bitbucket.org/paydoh/paydoh-commons/jsonwebtoken"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var defaultKeyring *Keyring
+
+// Init starts the keyring of the process, the tokens of the service are signed
+// and verified with it from then on
+func Init(ctx context.Context, db *sql.DB) error {
+	keyring := NewKeyring(db, config.GetJwtKeyringConfig())
+	if err := keyring.Start(ctx); err != nil {
+		return err
+	}
+
+	defaultKeyring = keyring
+	return nil
+}
+
+// Default returns the keyring of the process, nil before Init
+func Default() *Keyring {
+	return defaultKeyring
+}
+
+// GenerateJWT signs an access token with the keyring. Before Init it falls
+// back to the static JWT_KEY.
+func GenerateJWT(subject, userAgent, userIP string, expiresIn time.Duration, issuer string) (string, error) {
+	if defaultKeyring == nil {
+		return jsonwebtoken.GenerateJWT(subject, userAgent, userIP, constants.JwtKey, expiresIn, issuer)
+	}
+
+	return defaultKeyring.Sign(subject, userAgent, userIP, expiresIn, issuer)
+}
+
+// VerifyWithClaims verifies an access token. Tokens without a key id were
+// signed with the static JWT_KEY and are accepted while JWT_ACCEPT_LEGACY_TOKENS
+// is not false.
+func VerifyWithClaims(token string) (*jsonwebtoken.ResponseClaims, error) {
+	if defaultKeyring == nil {
+		return jsonwebtoken.VerifyWithClaims(token, constants.JwtKey)
+	}
+
+	if !hasKeyId(token) {
+		if !defaultKeyring.config.AcceptLegacyTokens {
+			return nil, errors.New("invalid token")
+		}
+		return jsonwebtoken.VerifyWithClaims(token, constants.JwtKey)
+	}
+
+	claims, err := defaultKeyring.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &jsonwebtoken.ResponseClaims{
+		UserId:     claims.Subject,
+		DeviceInfo: claims.DeviceInfo,
+		UserIp:     claims.UserIp,
+	}, nil
+}
+
+func hasKeyId(token string) bool {
+	header, _, found := strings.Cut(token, ".")
+	if !found {
+		return false
+	}
+
+	decoded, err := jwt.DecodeSegment(header)
+	if err != nil {
+		return false
+	}
+
+	var fields struct {
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(decoded, &fields); err != nil {
+		return false
+	}
+
+	return fields.Kid != ""
+}
@@ -0,0 +1,218 @@
+package keyring
+
+import (
+	"bankapi/constants"
+	"bankapi/models"
+	"bankapi/security"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"database/sql"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Signing algorithms of the keyring
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+)
+
+const (
+	hmacSecretSize = 32
+	rsaKeySize     = 2048
+	kidSize        = 12
+)
+
+// Key is a loaded key of the keyring
+type Key struct {
+	Kid       string
+	Algorithm string
+	CreatedAt time.Time
+	Retired   bool
+
+	method     jwt.SigningMethod
+	signingKey interface{}
+	verifyKey  interface{}
+	// publicKey is nil for HMAC keys, they are never published
+	publicKey crypto.PublicKey
+}
+
+func signingMethod(algorithm string) (jwt.SigningMethod, error) {
+	switch algorithm {
+	case AlgorithmHS256:
+		return jwt.SigningMethodHS256, nil
+	case AlgorithmRS256:
+		return jwt.SigningMethodRS256, nil
+	case AlgorithmES256:
+		return jwt.SigningMethodES256, nil
+	default:
+		return nil, fmt.Errorf("unsupported jwt signing algorithm %q", algorithm)
+	}
+}
+
+// generateKey creates a key row, its secret or private key encrypted with AES_PASSPHRASE
+func generateKey(algorithm string) (*models.JwtKey, error) {
+	if _, err := signingMethod(algorithm); err != nil {
+		return nil, err
+	}
+
+	kid, err := security.GenerateOpaqueToken(kidSize)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &models.JwtKey{Kid: kid, Algorithm: algorithm}
+
+	var private []byte
+	switch algorithm {
+	case AlgorithmHS256:
+		private = make([]byte, hmacSecretSize)
+		if _, err := rand.Read(private); err != nil {
+			return nil, err
+		}
+
+	default:
+		var signer crypto.Signer
+		if algorithm == AlgorithmRS256 {
+			signer, err = rsa.GenerateKey(rand.Reader, rsaKeySize)
+		} else {
+			signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate %s key: %w", algorithm, err)
+		}
+
+		der, err := x509.MarshalPKCS8PrivateKey(signer)
+		if err != nil {
+			return nil, err
+		}
+		private = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+		publicDer, err := x509.MarshalPKIXPublicKey(signer.Public())
+		if err != nil {
+			return nil, err
+		}
+		key.PublicKey = sql.NullString{
+			String: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDer})),
+			Valid:  true,
+		}
+	}
+
+	key.PrivateKey, err = security.Encrypt(private, []byte(constants.AesPassPhrase))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt jwt key: %w", err)
+	}
+
+	return key, nil
+}
+
+func parseKey(row models.JwtKey) (*Key, error) {
+	method, err := signingMethod(row.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	private, err := security.Decrypt(row.PrivateKey, []byte(constants.AesPassPhrase))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt jwt key: %w", err)
+	}
+
+	key := &Key{
+		Kid:       row.Kid,
+		Algorithm: row.Algorithm,
+		CreatedAt: row.CreatedAt,
+		Retired:   row.RetiredAt.Valid,
+		method:    method,
+	}
+
+	if row.Algorithm == AlgorithmHS256 {
+		key.signingKey = []byte(private)
+		key.verifyKey = []byte(private)
+		return key, nil
+	}
+
+	block, _ := pem.Decode([]byte(private))
+	if block == nil {
+		return nil, errors.New("invalid private key")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("private key cannot sign")
+	}
+
+	key.signingKey = signer
+	key.verifyKey = signer.Public()
+	key.publicKey = signer.Public()
+
+	return key, nil
+}
+
+// JWK is the public key of a keyring key as a JSON Web Key
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is the key set other services use to verify access tokens
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the asymmetric keys, HMAC keys are secrets
+// and are left out
+func (k *Keyring) JWKS() *JWKS {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	set := &JWKS{Keys: []JWK{}}
+	for _, key := range k.keys {
+		jwk := JWK{Kid: key.Kid, Use: "sig", Alg: key.Algorithm}
+
+		switch public := key.publicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = encodeInt(public.N)
+			jwk.E = encodeInt(big.NewInt(int64(public.E)))
+		case *ecdsa.PublicKey:
+			jwk.Kty = "EC"
+			jwk.Crv = public.Curve.Params().Name
+			size := (public.Curve.Params().BitSize + 7) / 8
+			jwk.X = base64.RawURLEncoding.EncodeToString(public.X.FillBytes(make([]byte, size)))
+			jwk.Y = base64.RawURLEncoding.EncodeToString(public.Y.FillBytes(make([]byte, size)))
+		default:
+			continue
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
+
+func encodeInt(value *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(value.Bytes())
+}
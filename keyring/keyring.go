@@ -0,0 +1,231 @@
+package keyring
+
+import (
+	"bankapi/config"
+	"bankapi/constants"
+	"bankapi/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Claims are the claims of an access token signed by the keyring
+type Claims struct {
+	jwt.RegisteredClaims
+	DeviceInfo string `json:"device_info"`
+	UserIp     string `json:"user_ip"`
+}
+
+// Keyring holds the keys that sign and verify access tokens. The newest key
+// signs and sets its id as the kid header, every key that has not expired
+// verifies, so rotating the signing key does not log users out.
+type Keyring struct {
+	db     *sql.DB
+	config *config.JwtKeyringConfig
+
+	mu      sync.RWMutex
+	keys    map[string]*Key
+	current *Key
+}
+
+func NewKeyring(db *sql.DB, keyringConfig *config.JwtKeyringConfig) *Keyring {
+	return &Keyring{
+		db:     db,
+		config: keyringConfig,
+		keys:   make(map[string]*Key),
+	}
+}
+
+// Load reads the keys that have not expired from the database
+func (k *Keyring) Load() error {
+	rows, err := models.ListJwtKeys(k.db)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*Key, len(rows))
+	var current *Key
+	for _, row := range rows {
+		key, err := parseKey(row)
+		if err != nil {
+			return fmt.Errorf("failed to parse jwt key %s: %w", row.Kid, err)
+		}
+		keys[key.Kid] = key
+
+		if current == nil && !key.Retired {
+			current = key
+		}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.keys = keys
+	k.current = current
+
+	return nil
+}
+
+// Rotate creates a signing key with algorithm, the configured algorithm when
+// empty. Unless forced, the key is only created when the signing key is older
+// than the rotation interval. The previous keys keep verifying the tokens they
+// signed until those expire.
+func (k *Keyring) Rotate(algorithm string, force bool) (bool, error) {
+	rotateBefore := time.Now()
+	if !force {
+		rotateBefore = rotateBefore.Add(-k.config.RotationInterval)
+	}
+
+	return k.rotate(algorithm, rotateBefore)
+}
+
+func (k *Keyring) rotate(algorithm string, rotateBefore time.Time) (bool, error) {
+	if algorithm == "" {
+		algorithm = k.config.Algorithm
+	}
+
+	key, err := generateKey(algorithm)
+	if err != nil {
+		return false, err
+	}
+
+	// other instances sign with the old key until their next refresh
+	retiredExpiresAt := time.Now().Add(constants.JwtExpTime + k.config.RefreshInterval)
+
+	rotated, err := models.RotateJwtKey(k.db, key, rotateBefore, retiredExpiresAt)
+	if err != nil {
+		return false, err
+	}
+
+	return rotated, k.Load()
+}
+
+// Start loads the keys, creating the first signing key, and keeps them fresh
+// until ctx is done. The signing key is rotated once it is older than the
+// rotation interval or the configured algorithm changes.
+func (k *Keyring) Start(ctx context.Context) error {
+	if err := k.Load(); err != nil {
+		return err
+	}
+
+	if k.Current() == nil {
+		if _, err := k.Rotate("", true); err != nil {
+			return err
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(k.config.RefreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := k.refresh(); err != nil {
+					log.Println("jwt keyring:", err)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (k *Keyring) refresh() error {
+	if err := k.Load(); err != nil {
+		return err
+	}
+
+	current := k.Current()
+	if current == nil {
+		_, err := k.Rotate("", true)
+		return err
+	}
+
+	if current.Algorithm != k.config.Algorithm {
+		// replaces the key only while it is still the signing key
+		_, err := k.rotate("", current.CreatedAt.Add(time.Microsecond))
+		return err
+	}
+
+	if k.config.RotationInterval > 0 && time.Since(current.CreatedAt) >= k.config.RotationInterval {
+		_, err := k.Rotate("", false)
+		return err
+	}
+
+	return nil
+}
+
+// Current returns the signing key, nil before the first key is loaded
+func (k *Keyring) Current() *Key {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.current
+}
+
+func (k *Keyring) key(kid string) (*Key, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	key, ok := k.keys[kid]
+	return key, ok
+}
+
+// Sign returns an access token for subject signed with the current key
+func (k *Keyring) Sign(subject, userAgent, userIP string, expiresIn time.Duration, issuer string) (string, error) {
+	key := k.Current()
+	if key == nil {
+		return "", errors.New("jwt keyring has no signing key")
+	}
+
+	now := time.Now()
+	claims := &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			Issuer:    issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
+		},
+		DeviceInfo: userAgent,
+		UserIp:     userIP,
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.Kid
+
+	return token.SignedString(key.signingKey)
+}
+
+// Verify checks a token signed by any key of the keyring
+func (k *Keyring) Verify(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := k.key(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+
+		if token.Method.Alg() != key.Algorithm {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+
+		return key.verifyKey, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
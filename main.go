@@ -12,9 +12,15 @@ import (
 
 	"bankapi/config"
 	"bankapi/constants"
+	"bankapi/health"
+	"bankapi/keyring"
+	"bankapi/metrics"
 	"bankapi/middleware"
+	// registers the go migrations
+	_ "bankapi/migrations"
 	"bankapi/router"
 	"bankapi/rpc"
+	bankServices "bankapi/services"
 	"bankapi/stores"
 
 	"bitbucket.org/paydoh/paydoh-commons/amazon"
@@ -25,6 +31,7 @@ import (
 	"github.com/gin-contrib/gzip"
 	"github.com/gin-contrib/pprof"
 	"github.com/gin-gonic/gin"
+	asynqLib "github.com/hibiken/asynq"
 	_ "github.com/lib/pq"
 	"github.com/pressly/goose"
 	"google.golang.org/grpc"
@@ -56,6 +63,22 @@ func main() {
 
 	app := gin.Default()
 
+	// the client ip of the callback allow lists comes from X-Forwarded-For only behind these
+	if err := config.ValidateTrustedProxies(); err != nil {
+		log.Fatalf("Invalid trusted proxy configuration: %v", err)
+	}
+	if err := app.SetTrustedProxies(config.GetTrustedProxies()); err != nil {
+		log.Fatalf("Failed to set trusted proxies: %v", err)
+	}
+
+	if err := config.ValidateCallbackPartners(); err != nil {
+		log.Fatalf("Invalid callback configuration: %v", err)
+	}
+
+	if err := bankServices.ValidateBankProviders(); err != nil {
+		log.Fatalf("Invalid bank provider configuration: %v", err)
+	}
+
 	// Register pprof handlers
 	pprof.Register(app)
 
@@ -120,6 +143,10 @@ func main() {
 
 	defer db.Close()
 
+	if err := keyring.Init(appCtx, db); err != nil {
+		log.Fatalf("Failed to load jwt keyring: %v", err)
+	}
+
 	grpcOptions := []grpc.DialOption{}
 	grpcOptions = append(grpcOptions, grpc.WithTransportCredentials(insecure.NewCredentials()))
 
@@ -133,10 +160,24 @@ func main() {
 
 	s := stores.NewStores(loggerSrv, db, memory, dbdoc, appCtx, paymentClient)
 
+	health.Register(health.CheckPostgres, health.Postgres(db))
+	health.Register(health.CheckRedis, health.Redis(memory))
+	health.Register(health.CheckMongoDB, health.MongoDB(client))
+	health.Register(health.CheckPaymentService, health.GRPC(conn))
+	health.Register(health.CheckAsynq, health.Asynq(asynqLib.RedisClientOpt{
+		Addr:     constants.RedisURL,
+		Username: constants.RedisUserName,
+		Password: constants.RedisPassword,
+		DB:       constants.RedisDB,
+	}))
+	health.Register(health.CheckKVBToken, health.KVBToken(bankServices.NewBankApiService(loggerSrv, memory)))
+	health.Register(health.CheckClamAV, health.ClamAV())
+
 	app.Static("/static", "./templates/static")
 
 	xssMiddleware := responseMiddleware.NewXSSMiddlewareConfig()
 	app.Use(xssMiddleware.XssMiddlewareProtect())
+	app.Use(middleware.MetricsMiddleware())
 	app.Use(gzip.Gzip(gzip.DefaultCompression))
 	app.Use(s.BindStore(awsInstance))
 	app.Use(middleware.LoggerMiddleware(loggerSrv))
@@ -190,7 +231,10 @@ func main() {
 		}
 	}()
 
-	asynq.HandleFunc(constants.AuditLogType, s.AuditLogService.AuditLogHandler)
+	asynq.HandleFunc(constants.AuditLogType, metrics.InstrumentTask(constants.AuditLogType, s.AuditLogService.AuditLogHandler))
+	asynq.HandleFunc(constants.StandingInstructionExecuteType, metrics.InstrumentTask(constants.StandingInstructionExecuteType, s.StandingInstruction.ExecuteHandler))
+	asynq.HandleFunc(constants.StandingInstructionReminderType, metrics.InstrumentTask(constants.StandingInstructionReminderType, s.StandingInstruction.ReminderHandler))
+	asynq.HandleFunc(constants.BulkPayoutExecuteType, metrics.InstrumentTask(constants.BulkPayoutExecuteType, s.BulkPayout.ExecuteHandler))
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
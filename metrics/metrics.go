@@ -0,0 +1,193 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Bank services, the fintech/UPI APIs and card control are separate KVB integrations
+const (
+	ServiceBank        = "bank"
+	ServiceCardControl = "card_control"
+)
+
+// Retry reasons of bank calls
+const (
+	RetryTransport    = "transport"
+	RetryBankError    = "bank_error"
+	RetryUnauthorized = "unauthorized"
+	RetryStatus       = "status"
+)
+
+// KVB sessions counted by IncTokenRefresh
+const (
+	SessionOAuthToken    = "oauth_token"
+	SessionCardPublicKey = "card_public_key"
+	SessionCardLogin     = "card_login"
+)
+
+// Flows that fell back to the key of the user as the session key
+const (
+	LegacySessionLogin   = "login"
+	LegacySessionRefresh = "refresh"
+)
+
+// Task outcomes
+const (
+	TaskEnqueued      = "enqueued"
+	TaskEnqueueFailed = "enqueue_failed"
+	TaskSucceeded     = "succeeded"
+	TaskFailed        = "failed"
+)
+
+var registry = prometheus.NewRegistry()
+
+var (
+	bankRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "bankapi",
+		Subsystem: "kvb",
+		Name:      "requests_total",
+		Help:      "KVB calls by endpoint, final HTTP status and bank error code.",
+	}, []string{"service", "endpoint", "status", "error_code"})
+
+	bankRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "bankapi",
+		Subsystem: "kvb",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of KVB calls including retries.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 20, 30, 60},
+	}, []string{"service", "endpoint", "status"})
+
+	bankRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "bankapi",
+		Subsystem: "kvb",
+		Name:      "retries_total",
+		Help:      "Retried KVB call attempts by reason.",
+	}, []string{"service", "endpoint", "reason"})
+
+	bankTokenRefreshes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "bankapi",
+		Subsystem: "kvb",
+		Name:      "token_refreshes_total",
+		Help:      "Tokens and sessions fetched from KVB.",
+	}, []string{"session", "result"})
+
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "bankapi",
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Handled requests by route template and status.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "bankapi",
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of handled requests by route template.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	legacySessionKeys = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "bankapi",
+		Subsystem: "session",
+		Name:      "legacy_keys_total",
+		Help:      "Sessions using the key of the user as session key, by flow.",
+	}, []string{"flow"})
+
+	tasks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "bankapi",
+		Subsystem: "asynq",
+		Name:      "tasks_total",
+		Help:      "Background tasks by type and outcome.",
+	}, []string{"type", "result"})
+
+	taskDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "bankapi",
+		Subsystem: "asynq",
+		Name:      "task_duration_seconds",
+		Help:      "Processing time of background tasks.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"type"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		bankRequests,
+		bankRequestDuration,
+		bankRetries,
+		bankTokenRefreshes,
+		httpRequests,
+		httpRequestDuration,
+		legacySessionKeys,
+		tasks,
+		taskDuration,
+	)
+}
+
+// Handler serves the collected metrics in the prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObserveBankRequest records a finished KVB call. Status is 0 when no response
+// was received, errorCode is empty when the bank reported success.
+func ObserveBankRequest(service, endpoint string, status int, errorCode string, duration time.Duration) {
+	statusLabel := strconv.Itoa(status)
+	bankRequests.WithLabelValues(service, endpoint, statusLabel, errorCode).Inc()
+	bankRequestDuration.WithLabelValues(service, endpoint, statusLabel).Observe(duration.Seconds())
+}
+
+func IncBankRetry(service, endpoint, reason string) {
+	bankRetries.WithLabelValues(service, endpoint, reason).Inc()
+}
+
+// IncTokenRefresh counts a token or session fetched from KVB
+func IncTokenRefresh(session string, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	bankTokenRefreshes.WithLabelValues(session, result).Inc()
+}
+
+func ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpRequestDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// IncLegacySessionKey counts a session that skipped the session key exchange
+func IncLegacySessionKey(flow string) {
+	legacySessionKeys.WithLabelValues(flow).Inc()
+}
+
+func IncTask(taskType, result string) {
+	tasks.WithLabelValues(taskType, result).Inc()
+}
+
+func ObserveTask(taskType string, err error, duration time.Duration) {
+	result := TaskSucceeded
+	if err != nil {
+		result = TaskFailed
+	}
+	tasks.WithLabelValues(taskType, result).Inc()
+	taskDuration.WithLabelValues(taskType).Observe(duration.Seconds())
+}
+
+// InstrumentTask wraps an asynq handler to record the outcome and duration of every task
+func InstrumentTask(taskType string, handler func(context.Context, *asynq.Task) error) func(context.Context, *asynq.Task) error {
+	return func(ctx context.Context, t *asynq.Task) error {
+		startTime := time.Now()
+		err := handler(ctx, t)
+		ObserveTask(taskType, err, time.Since(startTime))
+		return err
+	}
+}
@@ -4,21 +4,22 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
+	"time"
 
 	"bitbucket.org/paydoh/paydoh-commons/customerror"
-	"bitbucket.org/paydoh/paydoh-commons/jsonwebtoken"
 	"bitbucket.org/paydoh/paydoh-commons/responses"
 	"github.com/gin-gonic/gin"
 
 	"bankapi/config"
 	"bankapi/constants"
+	"bankapi/keyring"
 	"bankapi/models"
-	"bankapi/security"
+	"bankapi/sessionkey"
 )
 
-// AuthMiddleware validates the authorization header and sets the user_id, key, device_ip, os, os_version and lat_long in the gin context.
+// AuthMiddleware validates the authorization header and sets the user_id, key, sign_key, device_ip, os, os_version and lat_long in the gin context.
+// key is the session key the request and response payloads are encrypted with, sign_key is the key of the user for their data at rest.
 // It returns an error if the authorization header is not provided, the token is invalid, or the required headers are not provided.
 func AuthMiddleware() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
@@ -43,7 +44,7 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 		token := headerParts[1]
 
-		tokenData, err := VerifyJwtToken(token, ctx.Request.Header.Get("User-Agent"), ctx.Request.Header.Get("X-Device-Ip"))
+		tokenData, err := VerifyJwtToken(ctx, token, ctx.Request.Header.Get("User-Agent"), ctx.Request.Header.Get("X-Device-Ip"))
 		if err != nil {
 			responses.StatusUnauthorized(
 				ctx,
@@ -54,7 +55,6 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 
 		userID := tokenData.UserId
-		signKey := tokenData.SigningKey
 
 		deviceIp := ctx.Request.Header.Get("X-Device-Ip")
 		if deviceIp == "" {
@@ -84,87 +84,90 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// routes a device may call before it is trusted
 		skipURLs := []string{
 			"/api/authentication/initiate-sim-verification",
 			"/api/authentication/sim-verification-status",
+			"/api/authentication/logout",
 			"/api/upi/remapping-upi-id",
 			"/api/upi/simbinding/sms-verification",
 			"/api/get-secrets",
+			"/api/device/change/initiate",
+			"/api/device/change/status",
+			"/api/device/change/verify",
 		}
 
 		deviceID := ctx.Request.Header.Get("X-Device-ID")
-		if deviceID != "" {
-			accountData, _ := models.GetAccountDataByUserIdV2(userID)
-
-			if !containsURL(skipURLs, ctx.Request.RequestURI) && accountData != nil && accountData.UpiId.Valid {
-				deviceData, err := models.FindOneDeviceByUserIDV2(userID)
-				if err != nil {
-					ctx.JSON(
-						http.StatusOK,
-						gin.H{
-							"status":     http.StatusUnauthorized,
-							"message":    "Internal Server Error",
-							"ERROR_CODE": "ER001",
-							"error":      customerror.NewError(err),
-						},
-					)
-					ctx.Abort()
-					return
-				}
-
-				decryptedDeviceId, err := security.Decrypt(deviceData.DeviceId, []byte(strings.TrimSpace(signKey)))
-				if err != nil {
+		if !containsURL(skipURLs, ctx.Request.URL.Path) {
+			if err := verifyTrustedDevice(ctx, userID, deviceID); err != nil {
+				if !errors.Is(err, constants.ErrDeviceNotTrusted) {
 					responses.StatusInternalServerError(ctx, customerror.NewError(err), "")
 					ctx.Abort()
 					return
 				}
 
-				if deviceID != decryptedDeviceId && !deviceData.IsActive {
-					ctx.JSON(
-						http.StatusOK,
-						gin.H{
-							"status":     http.StatusUnauthorized,
-							"message":    "Unauthorized, device id mismatch",
-							"ERROR_CODE": "ER001",
-							"error":      customerror.NewError(errors.New("unauthorized")),
-						},
-					)
-					ctx.Abort()
-					return
-				}
+				ctx.JSON(
+					http.StatusOK,
+					gin.H{
+						"status":     http.StatusUnauthorized,
+						"message":    constants.DeviceNotTrustedErrorMessage,
+						"ERROR_CODE": constants.DeviceNotTrustedErrorCode,
+						"error":      customerror.NewError(err),
+					},
+				)
+				ctx.Abort()
+				return
 			}
 		}
 
 		ctx.Set("user_id", userID)
-		ctx.Set("key", signKey)
+		ctx.Set("key", tokenData.Key)
+		ctx.Set("sign_key", tokenData.SigningKey)
 		ctx.Set("device_ip", deviceIp)
 		ctx.Set("os", os)
 		ctx.Set("os_version", osVersion)
 		ctx.Set("lat_long", latLong)
+		ctx.Set("session_id", tokenData.SessionId)
+		ctx.Set("device_id", deviceID)
 		ctx.Next()
 	}
 }
 
-func CallbackMiddleware() gin.HandlerFunc {
-	return func(ctx *gin.Context) {
-		apiKey := ctx.GetHeader("X-API-Key")
+// deviceLastSeenInterval limits the writes of the last seen time of a device
+const deviceLastSeenInterval = time.Hour
 
-		if apiKey == "" {
-			responses.StatusBadRequest(ctx, customerror.NewError(errors.New("api key is not provided")), "")
-			ctx.Abort()
-			return
-		}
+// verifyTrustedDevice requires the device of a user who bound a device to be
+// trusted
+func verifyTrustedDevice(ctx *gin.Context, userID, deviceID string) error {
+	db := config.GetDB()
 
-		key := os.Getenv("CALLBACK_API_KEY")
-
-		if apiKey != key {
-			responses.StatusBadRequest(ctx, customerror.NewError(errors.New("api key is not valid")), "")
-			ctx.Abort()
-			return
+	if deviceID != "" {
+		device, err := models.FindTrustedDevice(db, userID, deviceID)
+		if err == nil {
+			if device.Status != models.DeviceStatusTrusted {
+				return constants.ErrDeviceNotTrusted
+			}
+			if device.LastSeenAt == nil || time.Since(*device.LastSeenAt) > deviceLastSeenInterval {
+				if err := models.TouchTrustedDevice(db, device.Id); err != nil {
+					logMiddlewareError(ctx, constants.DEVICE, "verifyTrustedDevice: "+err.Error())
+				}
+			}
+			return nil
+		}
+		if !errors.Is(err, constants.ErrNoDataFound) {
+			return err
 		}
+	}
 
-		ctx.Next()
+	if _, err := models.FindOneDeviceByUserIDV2(userID); err != nil {
+		// users who have not bound a device yet are onboarding
+		if errors.Is(err, constants.ErrDeviceNotFound) {
+			return nil
+		}
+		return err
 	}
+
+	return constants.ErrDeviceNotTrusted
 }
 
 // containsURL checks if the given URL exists in the slice of URLs
@@ -177,8 +180,21 @@ func containsURL(urls []string, url string) bool {
 	return false
 }
 
-func VerifyJwtToken(token, userAgent, userIP string) (*jsonwebtoken.ResponseClaims, error) {
-	tokenData, err := jsonwebtoken.VerifyWithClaims(token, constants.JwtKey)
+// TokenClaims is the user of a verified access token
+type TokenClaims struct {
+	UserId string
+	// Key encrypts the request and response payloads of the session
+	Key string
+	// SigningKey is the key of the user for their data at rest
+	SigningKey string
+	// SessionId is the device session of the token, empty for tokens issued before sessions
+	SessionId string
+}
+
+// VerifyJwtToken verifies an access token and reads the keys of its session.
+// Tokens of sessions that exchanged a key carry the session id only.
+func VerifyJwtToken(ctx *gin.Context, token, userAgent, userIP string) (*TokenClaims, error) {
+	tokenData, err := keyring.VerifyWithClaims(token)
 	if err != nil {
 		return nil, err
 	}
@@ -187,24 +203,57 @@ func VerifyJwtToken(token, userAgent, userIP string) (*jsonwebtoken.ResponseClai
 		return nil, errors.New("invalid token")
 	}
 
+	// logout and revoked devices delete the session (token blacklist)
+	if !strings.Contains(tokenData.UserId, "|") {
+		session, err := sessionkey.Load(tokenData.UserId)
+		if err != nil {
+			return nil, sessionkey.ErrSessionNotFound
+		}
+
+		return &TokenClaims{
+			UserId:     session.UserId,
+			Key:        session.Key,
+			SigningKey: session.SigningKey,
+			SessionId:  tokenData.UserId,
+		}, nil
+	}
+
 	data, err := extractAndSwapUserIDAndSignKey(tokenData.UserId)
 	if err != nil {
 		return nil, err
 	}
 
-	// for logout(token blacklist)
-	_, err = config.GetRedis().Get(fmt.Sprintf(constants.TokenKeyFormat, data["user_id"]))
-	if err != nil {
-		fmt.Println(err)
-		return nil, errors.New("generate new token to continue")
+	claims := &TokenClaims{
+		UserId:     data["user_id"],
+		Key:        data["sign_key"],
+		SigningKey: data["sign_key"],
+		SessionId:  data["session_id"],
+	}
+
+	if claims.SessionId == "" {
+		if _, err := config.GetRedis().Get(fmt.Sprintf(constants.TokenKeyFormat, claims.UserId)); err != nil {
+			logMiddlewareError(ctx, constants.AUTHORIZATION, "VerifyJwtToken: "+err.Error())
+			return nil, sessionkey.ErrSessionNotFound
+		}
+		return claims, nil
 	}
 
-	jwtData := &jsonwebtoken.ResponseClaims{}
+	session, err := sessionkey.Load(claims.SessionId)
+	if err != nil {
+		// sessions saved before their keys were kept server side hold the user id only
+		if errors.Is(err, sessionkey.ErrLegacySession) {
+			return claims, nil
+		}
+		return nil, err
+	}
 
-	jwtData.UserId = data["user_id"]
-	jwtData.SigningKey = data["sign_key"]
+	if session.UserId != claims.UserId {
+		return nil, errors.New("invalid token")
+	}
+	claims.Key = session.Key
+	claims.SigningKey = session.SigningKey
 
-	return jwtData, nil
+	return claims, nil
 }
 
 func extractAndSwapUserIDAndSignKey(userID string) (map[string]string, error) {
@@ -219,5 +268,10 @@ func extractAndSwapUserIDAndSignKey(userID string) (map[string]string, error) {
 	swappedUserId := signKey[:3] + userId[3:]
 	swappedSignKey := userId[:3] + signKey[3:]
 
-	return map[string]string{"user_id": swappedUserId, "sign_key": swappedSignKey}, nil
+	data := map[string]string{"user_id": swappedUserId, "sign_key": swappedSignKey}
+	if len(tokenDataParts) > 2 {
+		data["session_id"] = tokenDataParts[2]
+	}
+
+	return data, nil
 }
@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bitbucket.org/paydoh/paydoh-commons/customerror"
+	"bitbucket.org/paydoh/paydoh-commons/responses"
+	"github.com/gin-gonic/gin"
+
+	"bankapi/config"
+	"bankapi/constants"
+	"bankapi/services"
+)
+
+const maxCallbackNonceLength = 128
+
+// CallbackSignature returns the hex HMAC-SHA256 a partner sends as X-Signature.
+// It covers "<timestamp>\n<nonce>\n<method>\n<request uri>\n<body>", so a
+// signature cannot be replayed on another route or with another body.
+func CallbackSignature(secret, timestamp, nonce, method, requestURI string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n", timestamp, nonce, method, requestURI)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CallbackMiddleware authenticates the callbacks of a partner. The partner
+// must call from its allowed ips and either sign the request with its secret
+// (X-Timestamp, X-Nonce and X-Signature headers) or, on api_key auth, send the
+// secret as X-API-Key. Signed requests older than CALLBACK_MAX_SKEW or with a
+// nonce seen before are rejected. Every rejection is audit logged. The
+// partners are validated on startup, see config.ValidateCallbackPartners.
+func CallbackMiddleware(partnerName string) gin.HandlerFunc {
+	partner, err := config.GetCallbackPartner(partnerName)
+	if err != nil {
+		log.Printf("callback partner %s: %v", partnerName, err)
+		partner = &config.CallbackPartner{Name: partnerName}
+	}
+
+	return func(ctx *gin.Context) {
+		if !partner.AllowsIP(ctx.ClientIP()) {
+			rejectCallback(ctx, partner, http.StatusForbidden, "ip is not allowed")
+			return
+		}
+
+		if partner.Secret == "" {
+			rejectCallback(ctx, partner, http.StatusUnauthorized, "partner is not configured")
+			return
+		}
+
+		if partner.Auth == config.CallbackAuthApiKey {
+			if !hmac.Equal([]byte(ctx.GetHeader("X-API-Key")), []byte(partner.Secret)) {
+				rejectCallback(ctx, partner, http.StatusUnauthorized, "api key is not valid")
+				return
+			}
+
+			ctx.Next()
+			return
+		}
+
+		timestamp := ctx.GetHeader("X-Timestamp")
+		nonce := ctx.GetHeader("X-Nonce")
+		signature := ctx.GetHeader("X-Signature")
+		if timestamp == "" || nonce == "" || signature == "" {
+			rejectCallback(ctx, partner, http.StatusUnauthorized, "signature headers are not provided")
+			return
+		}
+
+		seconds, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			rejectCallback(ctx, partner, http.StatusUnauthorized, "timestamp is not valid")
+			return
+		}
+
+		skew := time.Since(time.Unix(seconds, 0))
+		if skew > partner.MaxSkew || skew < -partner.MaxSkew {
+			rejectCallback(ctx, partner, http.StatusUnauthorized, "timestamp is stale")
+			return
+		}
+
+		if len(nonce) > maxCallbackNonceLength {
+			rejectCallback(ctx, partner, http.StatusUnauthorized, "nonce is not valid")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, partner.MaxBodyBytes))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				rejectCallback(ctx, partner, http.StatusRequestEntityTooLarge, "body is too large")
+				return
+			}
+			rejectCallback(ctx, partner, http.StatusBadRequest, "body cannot be read")
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		expected := CallbackSignature(partner.Secret, timestamp, nonce, ctx.Request.Method, ctx.Request.URL.RequestURI(), body)
+		if !hmac.Equal([]byte(strings.ToLower(signature)), []byte(expected)) {
+			rejectCallback(ctx, partner, http.StatusUnauthorized, "signature is not valid")
+			return
+		}
+
+		// the nonce is kept while its timestamp is accepted, a replay finds it
+		nonceKey := fmt.Sprintf(constants.CallbackNonceKeyFormat, partner.Name, nonce)
+		fresh, err := config.GetRedis().GetClient().SetNX(ctx.Request.Context(), nonceKey, timestamp, 2*partner.MaxSkew).Result()
+		if err != nil {
+			responses.StatusInternalServerError(ctx, customerror.NewError(err), "")
+			ctx.Abort()
+			return
+		}
+		if !fresh {
+			rejectCallback(ctx, partner, http.StatusUnauthorized, "nonce has already been used")
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+func rejectCallback(ctx *gin.Context, partner *config.CallbackPartner, status int, reason string) {
+	auditLog := &services.AuditLog{
+		SourceIP:       ctx.ClientIP(),
+		RequestURL:     ctx.Request.URL.Path,
+		HTTPMethod:     ctx.Request.Method,
+		RequestBody:    fmt.Sprintf("partner=%s reason=%s", partner.Name, reason),
+		ResponseStatus: status,
+		Action:         constants.CALLBACK_REJECTED,
+	}
+	if auditLogSrv, ok := ctx.Value("audit_log_service").(services.AuditLogService); ok {
+		if err := auditLogSrv.Save(ctx, auditLog); err != nil {
+			log.Println("callback rejection audit log:", err)
+		}
+	}
+
+	err := customerror.NewError(errors.New("callback " + reason))
+	if status == http.StatusForbidden {
+		responses.StatusForbidden(ctx, err)
+	} else if status == http.StatusBadRequest {
+		responses.StatusBadRequest(ctx, err, "")
+	} else if status == http.StatusRequestEntityTooLarge {
+		ctx.JSON(status, gin.H{
+			"status":  status,
+			"message": err.Error(),
+			"error":   err,
+		})
+	} else {
+		responses.StatusUnauthorized(ctx, err)
+	}
+	ctx.Abort()
+}
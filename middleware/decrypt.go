@@ -1,45 +1,152 @@
 package middleware
 
 import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"bankapi/config"
+	"bankapi/constants"
 	"bankapi/requests"
 	"bankapi/security"
+	"bankapi/services"
 
 	"bitbucket.org/paydoh/paydoh-commons/customerror"
 	"bitbucket.org/paydoh/paydoh-commons/responses"
 	"github.com/gin-gonic/gin"
 )
 
+const maxPayloadNonceLength = 128
+
+// DecryptMiddleware decrypts the data of POST requests with the key of the
+// session, which AuthMiddleware reads server side by the session id. From
+// X-Payload-Version 2 the data is an envelope with a timestamp and a nonce,
+// envelopes older than PAYLOAD_MAX_SKEW or with a nonce seen before are
+// rejected so a captured request cannot be replayed. Requests below
+// PAYLOAD_MIN_VERSION are rejected.
 func DecryptMiddleware() gin.HandlerFunc {
+	envelopeConfig := config.GetPayloadEnvelopeConfig()
+
 	return func(ctx *gin.Context) {
-		if ctx.Request.Method == "POST" {
-			encryptedRequest := requests.NewEncryptedRequest()
-			if err := encryptedRequest.Validate(ctx); err != nil {
-				responses.StatusBadRequest(
-					ctx,
-					customerror.NewError(err),
-					"",
-				)
+		if ctx.Request.Method != http.MethodPost {
+			ctx.Next()
+			return
+		}
+
+		version := config.PayloadVersionLegacy
+		if header := ctx.GetHeader("X-Payload-Version"); header != "" {
+			parsed, err := strconv.Atoi(header)
+			if err != nil || parsed < config.PayloadVersionLegacy {
+				responses.StatusBadRequest(ctx, customerror.NewError(errors.New("invalid X-Payload-Version header")), "")
 				ctx.Abort()
 				return
 			}
+			version = parsed
+		}
+
+		if version < envelopeConfig.MinVersion {
+			responses.StatusBadRequest(ctx, customerror.NewError(fmt.Errorf("payload version %d is no longer supported, update the app", version)), "")
+			ctx.Abort()
+			return
+		}
 
-			signingKey := ctx.MustGet("key").(string)
-			decrypted, err := security.Decrypt(encryptedRequest.Data, []byte(signingKey))
+		encryptedRequest := requests.NewEncryptedRequest()
+		if err := encryptedRequest.Validate(ctx); err != nil {
+			responses.StatusBadRequest(
+				ctx,
+				customerror.NewError(err),
+				"",
+			)
+			ctx.Abort()
+			return
+		}
+
+		sessionKey := ctx.MustGet("key").(string)
+		decrypted, err := security.Decrypt(encryptedRequest.Data, []byte(sessionKey))
+		if err != nil {
+			responses.StatusBadRequest(
+				ctx,
+				customerror.NewError(err),
+				"",
+			)
+			ctx.Abort()
+			return
+		}
+
+		if version >= config.PayloadVersionEnvelope {
+			decrypted, err = openEnvelope(ctx, envelopeConfig, decrypted)
 			if err != nil {
-				responses.StatusBadRequest(
-					ctx,
-					customerror.NewError(err),
-					"",
-				)
-				ctx.Abort()
 				return
 			}
-
-			ctx.Set("decrypted", decrypted)
-			ctx.Next()
 		}
 
+		ctx.Set("decrypted", decrypted)
 		ctx.Next()
 	}
+}
+
+// openEnvelope checks the timestamp and nonce of an envelope and returns its
+// payload. It responds and aborts on a rejected envelope.
+func openEnvelope(ctx *gin.Context, envelopeConfig *config.PayloadEnvelopeConfig, decrypted string) (string, error) {
+	envelope := requests.NewPayloadEnvelope()
+	if err := envelope.Unmarshal(decrypted); err != nil {
+		responses.StatusBadRequest(ctx, customerror.NewError(err), "")
+		ctx.Abort()
+		return "", err
+	}
+
+	skew := time.Since(time.Unix(envelope.Timestamp, 0))
+	if skew > envelopeConfig.MaxSkew || skew < -envelopeConfig.MaxSkew {
+		return "", rejectReplay(ctx, "request has expired")
+	}
+
+	if len(envelope.Nonce) > maxPayloadNonceLength {
+		return "", rejectReplay(ctx, "request nonce is not valid")
+	}
+
+	// the nonce is kept while its timestamp is accepted, a replay finds it
+	nonceKey := fmt.Sprintf(constants.PayloadNonceKeyFormat, ctx.GetString("user_id"), envelope.Nonce)
+	fresh, err := config.GetRedis().GetClient().SetNX(ctx.Request.Context(), nonceKey, envelope.Timestamp, 2*envelopeConfig.MaxSkew).Result()
+	if err != nil {
+		responses.StatusInternalServerError(ctx, customerror.NewError(err), "")
+		ctx.Abort()
+		return "", err
+	}
+	if !fresh {
+		return "", rejectReplay(ctx, "request has already been processed")
+	}
+
+	data, err := envelope.Data()
+	if err != nil {
+		responses.StatusBadRequest(ctx, customerror.NewError(err), "")
+		ctx.Abort()
+		return "", err
+	}
+
+	return data, nil
+}
+
+func rejectReplay(ctx *gin.Context, reason string) error {
+	if auditLogSrv, ok := ctx.Value("audit_log_service").(services.AuditLogService); ok {
+		if err := auditLogSrv.Save(ctx, &services.AuditLog{
+			UserID:         ctx.GetString("user_id"),
+			SourceIP:       ctx.ClientIP(),
+			DeviceID:       ctx.GetHeader("X-Device-ID"),
+			RequestURL:     ctx.Request.URL.Path,
+			HTTPMethod:     ctx.Request.Method,
+			RequestBody:    "reason=" + reason,
+			ResponseStatus: http.StatusUnauthorized,
+			Action:         constants.PAYLOAD_REPLAY_REJECTED,
+		}); err != nil {
+			log.Println("payload replay audit log:", err)
+		}
+	}
 
+	err := errors.New(reason)
+	responses.StatusUnauthorized(ctx, customerror.NewError(err))
+	ctx.Abort()
+	return err
 }
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+
+	"bitbucket.org/paydoh/paydoh-commons/customerror"
+	"bitbucket.org/paydoh/paydoh-commons/responses"
+	"github.com/gin-gonic/gin"
+
+	"bankapi/config"
+	"bankapi/constants"
+	"bankapi/models"
+	"bankapi/utils"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// idempotencyWriter keeps a copy of the response body so it can be replayed
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyWriter) WriteString(data string) (int, error) {
+	w.body.WriteString(data)
+	return w.ResponseWriter.WriteString(data)
+}
+
+// IdempotencyMiddleware guards fund transfer routes with the Idempotency-Key header.
+// It must run after AuthMiddleware and DecryptMiddleware. The first request with a
+// key is processed and its response stored, repeats with the same payload get the
+// stored response and a key reused with another payload is rejected. A repeat
+// while the first request is processing gets a 409. A server error before the
+// payment was sent to the bank is not stored, the key is released so the
+// client can retry. Once it was sent the bank may have made the payment, so
+// the error is stored like any other outcome.
+func IdempotencyMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := strings.TrimSpace(ctx.GetHeader("Idempotency-Key"))
+		if key == "" {
+			responses.StatusBadRequest(ctx, customerror.NewError(errors.New(constants.IdempotencyKeyMissingErrorMessage)), "")
+			ctx.Abort()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			responses.StatusBadRequest(ctx, customerror.NewError(errors.New(constants.IdempotencyKeyInvalidErrorMessage)), "")
+			ctx.Abort()
+			return
+		}
+
+		route := ctx.FullPath()
+		requestHash := utils.RequestHash(route, []byte(ctx.GetString("decrypted")))
+		db := config.GetDB()
+
+		idempotencyKey, claimed, err := models.ClaimIdempotencyKey(db, &models.IdempotencyKey{
+			UserId:      ctx.GetString("user_id"),
+			Key:         key,
+			Route:       route,
+			RequestHash: requestHash,
+		}, config.GetIdempotencyKeyTTL(), config.GetIdempotencyKeyLease())
+		if err != nil {
+			responses.StatusInternalServerError(ctx, customerror.NewError(err), "")
+			ctx.Abort()
+			return
+		}
+
+		if !claimed {
+			replayIdempotentResponse(ctx, idempotencyKey, route, requestHash)
+			ctx.Abort()
+			return
+		}
+
+		writer := &idempotencyWriter{ResponseWriter: ctx.Writer, body: &bytes.Buffer{}}
+		ctx.Writer = writer
+		ctx.Request = ctx.Request.WithContext(utils.WithBankCallTracking(ctx.Request.Context()))
+
+		// a panicking handler has no outcome to replay, let the client retry
+		// unless the payment may have reached the bank
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				if utils.BankCalled(ctx.Request.Context()) {
+					panic(recovered)
+				}
+				if err := models.ReleaseIdempotencyKey(db, idempotencyKey); err != nil {
+					logMiddlewareError(ctx, constants.IDEMPOTENCY, err.Error())
+				}
+				panic(recovered)
+			}
+		}()
+
+		ctx.Next()
+
+		if writer.Status() >= http.StatusInternalServerError && !utils.BankCalled(ctx.Request.Context()) {
+			if err := models.ReleaseIdempotencyKey(db, idempotencyKey); err != nil {
+				logMiddlewareError(ctx, constants.IDEMPOTENCY, err.Error())
+			}
+			return
+		}
+
+		if err := models.CompleteIdempotencyKey(db, idempotencyKey, writer.Status(), writer.body.String()); err != nil {
+			logMiddlewareError(ctx, constants.IDEMPOTENCY, err.Error())
+		}
+	}
+}
+
+func replayIdempotentResponse(ctx *gin.Context, idempotencyKey *models.IdempotencyKey, route, requestHash string) {
+	if idempotencyKey.Route != route || idempotencyKey.RequestHash != requestHash {
+		responses.StatusBadRequest(
+			ctx,
+			customerror.NewError(errors.New(constants.IdempotencyKeyReusedErrorMessage)),
+			constants.IdempotencyKeyReusedErrorCode,
+		)
+		return
+	}
+
+	if idempotencyKey.Status != models.IdempotencyCompleted {
+		ctx.JSON(http.StatusConflict, gin.H{
+			"status":     http.StatusConflict,
+			"message":    constants.IdempotencyKeyInProgressErrorMessage,
+			"ERROR_CODE": constants.IdempotencyKeyInProgressErrorCode,
+			"error":      customerror.NewError(errors.New(constants.IdempotencyKeyInProgressErrorMessage)),
+		})
+		return
+	}
+
+	status := http.StatusOK
+	if idempotencyKey.ResponseStatus.Valid {
+		status = int(idempotencyKey.ResponseStatus.Int64)
+	}
+
+	ctx.Header("Idempotent-Replayed", "true")
+	ctx.Data(status, "application/json; charset=utf-8", []byte(idempotencyKey.ResponseBody.String))
+}
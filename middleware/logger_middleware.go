@@ -18,7 +18,9 @@ import (
 
 func LoggerMiddleware(loggerService *services.LoggerService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if strings.HasPrefix(c.Request.URL.Path, "/swagger") || c.Request.URL.Path == "/health" {
+		c.Set("logger_service", loggerService)
+
+		if strings.HasPrefix(c.Request.URL.Path, "/swagger") || strings.HasPrefix(c.Request.URL.Path, "/health") || c.Request.URL.Path == "/metrics" {
 			c.Next()
 			return
 		}
@@ -101,6 +103,7 @@ func LoggerMiddleware(loggerService *services.LoggerService) gin.HandlerFunc {
 		ctx = context.WithValue(ctx, "device_id", c.Request.Header.Get("X-Device-ID"))
 		ctx = context.WithValue(ctx, "package_id", c.Request.Header.Get("X-Package-ID"))
 		ctx = context.WithValue(ctx, "user_agent", c.Request.Header.Get("User-Agent"))
+		ctx = context.WithValue(ctx, "language", c.Request.Header.Get("Accept-Language"))
 
 		c.Request = c.Request.WithContext(ctx)
 
@@ -135,6 +138,22 @@ func LoggerMiddleware(loggerService *services.LoggerService) gin.HandlerFunc {
 	}
 }
 
+// logMiddlewareError logs an error of a middleware with the logger bound by LoggerMiddleware
+func logMiddlewareError(c *gin.Context, action, message string) {
+	loggerService, ok := c.Value("logger_service").(*services.LoggerService)
+	if !ok {
+		return
+	}
+
+	loggerService.LogError(&services.LogEntry{
+		Action:        action,
+		UserID:        c.GetString("user_id"),
+		RequestMethod: c.Request.Method,
+		RequestURI:    c.Request.RequestURI,
+		Message:       message,
+	})
+}
+
 // reads the request body
 func ReadRequestBody(r *http.Request) ([]byte, error) {
 	if r.Body == nil {
@@ -179,7 +198,7 @@ func getUserIDFromToken(ctx *gin.Context) string {
 		return ""
 	}
 
-	tokenData, err := VerifyJwtToken(token, ctx.Request.Header.Get("User-Agent"), ctx.Request.Header.Get("X-Device-Ip"))
+	tokenData, err := VerifyJwtToken(ctx, token, ctx.Request.Header.Get("User-Agent"), ctx.Request.Header.Get("X-Device-Ip"))
 	if err != nil {
 		return ""
 	}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"bankapi/metrics"
+)
+
+// MetricsMiddleware records request count and latency per route template, so
+// path parameters do not create a series per value.
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		startTime := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		metrics.ObserveHTTPRequest(c.Request.Method, route, c.Writer.Status(), time.Since(startTime))
+	}
+}
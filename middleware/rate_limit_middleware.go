@@ -0,0 +1,272 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"bitbucket.org/paydoh/paydoh-commons/customerror"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+
+	"bankapi/config"
+	"bankapi/constants"
+	"bankapi/security"
+	"bankapi/services"
+)
+
+// slidingWindowScript keeps one sorted set of request times per identity. A
+// request is counted in every set or, when any set is full, in none and the
+// wait in milliseconds until the fullest set has room is returned.
+var slidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+local wait = 0
+for _, key in ipairs(KEYS) do
+	redis.call("ZREMRANGEBYSCORE", key, 0, now - window)
+	if redis.call("ZCARD", key) >= limit then
+		local oldest = redis.call("ZRANGE", key, 0, 0, "WITHSCORES")
+		local keyWait = tonumber(oldest[2]) + window - now
+		if keyWait > wait then
+			wait = keyWait
+		end
+	end
+end
+
+if wait > 0 then
+	return wait
+end
+
+for _, key in ipairs(KEYS) do
+	redis.call("ZADD", key, now, ARGV[4])
+	redis.call("PEXPIRE", key, window)
+end
+return 0
+`)
+
+// RateLimitEscalation is called on every rate limit violation with the
+// subject of the request and its violations in the violation window
+type RateLimitEscalation func(ctx *gin.Context, rateLimit *config.RateLimit, subject string, violations int64)
+
+var (
+	rateLimitEscalation   RateLimitEscalation = BlockAbusiveDevice
+	rateLimitEscalationMu sync.RWMutex
+)
+
+// SetRateLimitEscalation replaces the escalation of rate limit violations
+func SetRateLimitEscalation(escalation RateLimitEscalation) {
+	rateLimitEscalationMu.Lock()
+	defer rateLimitEscalationMu.Unlock()
+
+	rateLimitEscalation = escalation
+}
+
+func getRateLimitEscalation() RateLimitEscalation {
+	rateLimitEscalationMu.RLock()
+	defer rateLimitEscalationMu.RUnlock()
+
+	return rateLimitEscalation
+}
+
+// RateLimitMiddleware limits the requests to a route with the budget of a
+// policy, counted per route for each of the user, device and mobile number the
+// policy is keyed by. Rejected requests get 429 with a Retry-After header.
+// Requests are let through when redis is unavailable, the limits must not take
+// the api down.
+func RateLimitMiddleware(policy string) gin.HandlerFunc {
+	rateLimit, err := config.GetRateLimit(policy)
+	if err != nil {
+		log.Fatalf("Failed to load rate limit %s: %v", policy, err)
+	}
+
+	return func(ctx *gin.Context) {
+		client := config.GetRedis().GetClient()
+		subject := rateLimitSubject(ctx)
+
+		if subject != "" {
+			blockedFor, err := client.PTTL(ctx.Request.Context(), fmt.Sprintf(constants.RateLimitBlockKeyFormat, subject)).Result()
+			if err != nil {
+				log.Println("rate limit:", err)
+				ctx.Next()
+				return
+			}
+			if blockedFor > 0 {
+				rejectRateLimited(ctx, blockedFor)
+				return
+			}
+		}
+
+		wait, err := allowRequest(ctx.Request.Context(), client, rateLimit, rateLimitKeys(ctx, rateLimit))
+		if err != nil {
+			log.Println("rate limit:", err)
+			ctx.Next()
+			return
+		}
+		if wait == 0 {
+			ctx.Next()
+			return
+		}
+
+		if subject == "" {
+			rejectRateLimited(ctx, wait)
+			return
+		}
+
+		escalation := config.GetRateLimitEscalationConfig()
+		violationKey := fmt.Sprintf(constants.RateLimitViolationKeyFormat, subject)
+		violations, err := client.Incr(ctx.Request.Context(), violationKey).Result()
+		if err == nil && violations == 1 {
+			client.Expire(ctx.Request.Context(), violationKey, escalation.ViolationWindow)
+		}
+		if err == nil {
+			getRateLimitEscalation()(ctx, rateLimit, subject, violations)
+		}
+
+		rejectRateLimited(ctx, wait)
+	}
+}
+
+// BlockAbusiveDevice blocks the subject for RATE_LIMIT_BLOCK_DURATION once it
+// has RATE_LIMIT_BLOCK_AFTER violations and writes an audit entry
+func BlockAbusiveDevice(ctx *gin.Context, rateLimit *config.RateLimit, subject string, violations int64) {
+	escalation := config.GetRateLimitEscalationConfig()
+	if escalation.BlockAfter <= 0 || violations != int64(escalation.BlockAfter) {
+		return
+	}
+
+	blockKey := fmt.Sprintf(constants.RateLimitBlockKeyFormat, subject)
+	if err := config.GetRedis().Set(blockKey, rateLimit.Policy, escalation.BlockDuration); err != nil {
+		log.Println("rate limit block:", err)
+		return
+	}
+
+	if auditLogSrv, ok := ctx.Value("audit_log_service").(services.AuditLogService); ok {
+		if err := auditLogSrv.Save(ctx, &services.AuditLog{
+			UserID:         ctx.GetString("user_id"),
+			SourceIP:       ctx.ClientIP(),
+			RequestURL:     ctx.Request.URL.Path,
+			HTTPMethod:     ctx.Request.Method,
+			RequestBody:    fmt.Sprintf("policy=%s subject=%s violations=%d blocked_for=%s", rateLimit.Policy, subject, violations, escalation.BlockDuration),
+			ResponseStatus: http.StatusTooManyRequests,
+			Action:         constants.RATE_LIMIT_BLOCKED,
+		}); err != nil {
+			log.Println("rate limit block audit log:", err)
+		}
+	}
+}
+
+func allowRequest(ctx context.Context, client *redis.Client, rateLimit *config.RateLimit, keys []string) (time.Duration, error) {
+	member, err := security.GenerateOpaqueToken(12)
+	if err != nil {
+		return 0, err
+	}
+
+	wait, err := slidingWindowScript.Run(
+		ctx,
+		client,
+		keys,
+		time.Now().UnixMilli(),
+		rateLimit.Window.Milliseconds(),
+		rateLimit.Limit,
+		member,
+	).Int64()
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(wait) * time.Millisecond, nil
+}
+
+func rateLimitKeys(ctx *gin.Context, rateLimit *config.RateLimit) []string {
+	route := ctx.FullPath()
+
+	var keys []string
+	for _, keyBy := range rateLimit.KeyBy {
+		var value string
+		switch keyBy {
+		case config.RateLimitByUser:
+			value = ctx.GetString("user_id")
+		case config.RateLimitByDevice:
+			value = ctx.GetHeader("X-Device-ID")
+		case config.RateLimitByMobile:
+			value = requestMobileNumber(ctx)
+		case config.RateLimitByIP:
+			value = ctx.ClientIP()
+		}
+
+		if value != "" {
+			keys = append(keys, fmt.Sprintf(constants.RateLimitKeyFormat, rateLimit.Policy, route, keyBy+":"+value))
+		}
+	}
+
+	// a request without any identity is still counted by ip
+	if len(keys) == 0 {
+		keys = append(keys, fmt.Sprintf(constants.RateLimitKeyFormat, rateLimit.Policy, route, config.RateLimitByIP+":"+ctx.ClientIP()))
+	}
+
+	return keys
+}
+
+// requestMobileNumber is the mobile_number of a json body, the body is put
+// back for the handler
+func requestMobileNumber(ctx *gin.Context) string {
+	if ctx.Request.Body == nil {
+		return ""
+	}
+
+	body, err := io.ReadAll(ctx.Request.Body)
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+
+	var payload struct {
+		MobileNumber string `json:"mobile_number"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+
+	return payload.MobileNumber
+}
+
+// rateLimitSubject is what escalation blocks, the authenticated user or the
+// ip before authentication. The device id header and the mobile number are
+// not used, a client could change the first on every request to never be
+// blocked and block anyone with the second. The ip is only used behind
+// TRUSTED_PROXIES, without them it is the load balancer and blocking it would
+// block everyone. Requests with no subject are limited but never blocked.
+func rateLimitSubject(ctx *gin.Context) string {
+	if userId := ctx.GetString("user_id"); userId != "" {
+		return config.RateLimitByUser + ":" + userId
+	}
+	if len(config.GetTrustedProxies()) == 0 {
+		return ""
+	}
+	return config.RateLimitByIP + ":" + ctx.ClientIP()
+}
+
+func rejectRateLimited(ctx *gin.Context, retryAfter time.Duration) {
+	ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	ctx.JSON(
+		http.StatusTooManyRequests,
+		gin.H{
+			"status":     http.StatusTooManyRequests,
+			"message":    constants.RateLimitedErrorMessage,
+			"ERROR_CODE": constants.RateLimitedErrorCode,
+			"error":      customerror.NewError(errors.New(constants.RateLimitedErrorMessage)),
+		},
+	)
+	ctx.Abort()
+}
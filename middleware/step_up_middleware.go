@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"bitbucket.org/paydoh/paydoh-commons/customerror"
+	"bitbucket.org/paydoh/paydoh-commons/responses"
+	"github.com/gin-gonic/gin"
+
+	"bankapi/config"
+	"bankapi/constants"
+	"bankapi/services"
+	"bankapi/stepup"
+)
+
+// StepUpMiddleware requires the factors of a step-up policy before a
+// sensitive route, a recent mpin or otp verified on the session and a trusted
+// device. Requests missing a factor get 403 with the factors to verify, the app
+// verifies them and retries. It must run after AuthMiddleware.
+func StepUpMiddleware(policy string) gin.HandlerFunc {
+	stepUpPolicy, err := config.GetStepUpPolicy(policy)
+	if err != nil {
+		log.Fatalf("Failed to load step-up policy %s: %v", policy, err)
+	}
+	if grantTTL := config.GetStepUpGrantTTL(); stepUpPolicy.MaxAge > grantTTL {
+		log.Fatalf("Step-up policy %s max age %s is longer than STEP_UP_GRANT_TTL %s", policy, stepUpPolicy.MaxAge, grantTTL)
+	}
+
+	return func(ctx *gin.Context) {
+		subject := &stepup.Subject{
+			UserId:    ctx.GetString("user_id"),
+			SessionId: ctx.GetString("session_id"),
+			DeviceId:  ctx.GetString("device_id"),
+		}
+
+		missing, err := stepup.Missing(ctx.Request.Context(), config.GetDB(), stepUpPolicy, subject)
+		if err != nil {
+			responses.StatusInternalServerError(ctx, customerror.NewError(err), "")
+			ctx.Abort()
+			return
+		}
+		if len(missing) == 0 {
+			ctx.Next()
+			return
+		}
+
+		if auditLogSrv, ok := ctx.Value("audit_log_service").(services.AuditLogService); ok {
+			if err := auditLogSrv.Save(ctx, &services.AuditLog{
+				UserID:         subject.UserId,
+				SourceIP:       ctx.ClientIP(),
+				DeviceID:       subject.DeviceId,
+				RequestURL:     ctx.Request.URL.Path,
+				HTTPMethod:     ctx.Request.Method,
+				RequestBody:    fmt.Sprintf("policy=%s missing=%s", policy, strings.Join(missing, ",")),
+				ResponseStatus: http.StatusForbidden,
+				Action:         constants.STEP_UP_REQUIRED,
+			}); err != nil {
+				log.Println("step-up audit log:", err)
+			}
+		}
+
+		ctx.JSON(
+			http.StatusForbidden,
+			gin.H{
+				"status":     http.StatusForbidden,
+				"message":    constants.StepUpRequiredErrorMessage,
+				"ERROR_CODE": constants.StepUpRequiredErrorCode,
+				"error":      customerror.NewError(errors.New(constants.StepUpRequiredErrorMessage)),
+				"data": gin.H{
+					"policy":      policy,
+					"factors":     missing,
+					"max_age_sec": int64(stepUpPolicy.MaxAge.Seconds()),
+				},
+			},
+		)
+		ctx.Abort()
+	}
+}
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Stores the outcome of fund transfer requests per Idempotency-Key so that
+-- retried requests are answered without submitting the transfer again
+CREATE TABLE idempotency_keys (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id VARCHAR(50) NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    route VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(64) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'PROCESSING',
+    response_status INT,
+    response_body TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    CONSTRAINT idempotency_keys_user_key_unique UNIQUE (user_id, idempotency_key)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS idempotency_keys;
+-- +goose StatementEnd
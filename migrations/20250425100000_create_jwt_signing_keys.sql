@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Keys that sign access tokens, identified by the kid header of a token. The
+-- newest key signs, retired keys only verify until they expire.
+CREATE TABLE jwt_signing_keys (
+    kid VARCHAR(64) PRIMARY KEY,
+    algorithm VARCHAR(10) NOT NULL,
+    private_key TEXT NOT NULL,
+    public_key TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    retired_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS jwt_signing_keys;
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The devices a user may bank from. A new device is PENDING until its sim is
+-- bound and the user confirms it with an otp and the mpin, then it is TRUSTED
+-- and its payments are limited until cooling_until.
+CREATE TABLE trusted_devices (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id VARCHAR(50) NOT NULL,
+    device_id VARCHAR(120) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    os VARCHAR(50),
+    os_version VARCHAR(50),
+    package_id VARCHAR(255),
+    device_token TEXT,
+    sim_vendor_id TEXT,
+    sim_verified_at TIMESTAMP WITH TIME ZONE,
+    trusted_at TIMESTAMP WITH TIME ZONE,
+    cooling_until TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    last_seen_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    UNIQUE (user_id, device_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS trusted_devices;
+-- +goose StatementEnd
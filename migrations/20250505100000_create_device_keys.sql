@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Public keys the app registers for a device after an mpin verification. The
+-- device signs login and step-up challenges with the private key after a
+-- biometric prompt. A device has one ACTIVE key, the key of a device that is
+-- no longer bound in device_data is REVOKED.
+CREATE TABLE device_keys (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id VARCHAR(50) NOT NULL,
+    device_data_id UUID NOT NULL REFERENCES device_data(id) ON DELETE CASCADE,
+    device_id VARCHAR(120) NOT NULL,
+    algorithm VARCHAR(20) NOT NULL,
+    public_key TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX idx_device_keys_active ON device_keys (user_id, device_id) WHERE status = 'ACTIVE';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_device_keys_active;
+DROP TABLE IF EXISTS device_keys;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- An mpin is locked until locked_until after MAX_MPIN_ATTEMPTS incorrect
+-- attempts, each lockout in a row is longer than the last.
+ALTER TABLE mpin_attempt_data
+ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ NULL,
+ADD COLUMN IF NOT EXISTS lockout_count INT NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS last_lockout_at TIMESTAMPTZ NULL;
+
+CREATE INDEX IF NOT EXISTS idx_mpin_attempt_data_locked_until ON mpin_attempt_data (locked_until) WHERE locked_until IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_mpin_attempt_data_locked_until;
+
+ALTER TABLE mpin_attempt_data
+DROP COLUMN IF EXISTS locked_until,
+DROP COLUMN IF EXISTS lockout_count,
+DROP COLUMN IF EXISTS last_lockout_at;
+-- +goose StatementEnd
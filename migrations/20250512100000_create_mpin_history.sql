@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- bcrypt hashes of the mpins a user has set, the newest MPIN_HISTORY_SIZE are
+-- kept and a new mpin may not match any of them. The current mpins are copied
+-- in so the rule holds for users who set theirs before the table existed.
+CREATE TABLE mpin_history (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id VARCHAR(50) NOT NULL,
+    mpin_hash TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_mpin_history_user_id ON mpin_history (user_id, created_at DESC);
+
+INSERT INTO mpin_history (user_id, mpin_hash, created_at)
+SELECT user_id, mpin, COALESCE(updated_at, created_at) FROM mpin_data;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_mpin_history_user_id;
+
+DROP TABLE IF EXISTS mpin_history;
+-- +goose StatementEnd
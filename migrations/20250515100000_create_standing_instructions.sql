@@ -0,0 +1,54 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Scheduled and recurring transfers to a verified beneficiary. The account
+-- number is kept encrypted for the runs, benf_account is masked like in
+-- beneficiaries. next_run_at is the run the scheduled task is for, it is null
+-- once the schedule is over.
+CREATE TABLE standing_instructions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id VARCHAR(50) NOT NULL,
+    benf_id VARCHAR(50) NOT NULL,
+    benf_name VARCHAR(100) NOT NULL,
+    benf_ifsc VARCHAR(11) NOT NULL,
+    benf_account VARCHAR(50) NOT NULL,
+    benf_account_encrypted TEXT NOT NULL,
+    benf_acct_type VARCHAR(50) NOT NULL,
+    benf_mobile_number VARCHAR(12) NOT NULL,
+    payment_mode VARCHAR(10) NOT NULL,
+    amount NUMERIC(15, 2) NOT NULL,
+    remarks VARCHAR(100) NOT NULL,
+    frequency VARCHAR(10) NOT NULL,
+    day_of_month INT NOT NULL DEFAULT 0,
+    start_date DATE NOT NULL,
+    end_date DATE,
+    next_run_at TIMESTAMP WITH TIME ZONE,
+    status VARCHAR(20) NOT NULL,
+    device_id VARCHAR(120) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_standing_instructions_user_id ON standing_instructions (user_id);
+
+-- One row per run of a standing instruction, attempts counts the retries of
+-- a run the bank failed
+CREATE TABLE standing_instruction_executions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    standing_instruction_id UUID NOT NULL REFERENCES standing_instructions(id) ON DELETE CASCADE,
+    user_id VARCHAR(50) NOT NULL,
+    run_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    txn_identifier VARCHAR(50),
+    attempts INT NOT NULL DEFAULT 0,
+    error_message TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    UNIQUE (standing_instruction_id, run_at)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS standing_instruction_executions;
+DROP TABLE IF EXISTS standing_instructions;
+-- +goose StatementEnd
@@ -0,0 +1,52 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Payouts to many accounts uploaded as a CSV or XLSX file. A bulk payout is
+-- a PREVIEW until the user confirms it, its valid rows are then paid by a task.
+CREATE TABLE bulk_payouts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id VARCHAR(50) NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    file_type VARCHAR(10) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    total_rows INT NOT NULL DEFAULT 0,
+    valid_rows INT NOT NULL DEFAULT 0,
+    total_amount NUMERIC(15, 2) NOT NULL DEFAULT 0,
+    device_id VARCHAR(120) NOT NULL DEFAULT '',
+    confirmed_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_bulk_payouts_user_id ON bulk_payouts (user_id);
+
+-- One row per payout of the file. The account number is kept encrypted for
+-- the payment, benf_account is masked. utr is the reference number the bank
+-- gave the payment.
+CREATE TABLE bulk_payout_rows (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    bulk_payout_id UUID NOT NULL REFERENCES bulk_payouts(id) ON DELETE CASCADE,
+    row_number INT NOT NULL,
+    benf_name VARCHAR(100) NOT NULL,
+    benf_account VARCHAR(50) NOT NULL,
+    benf_account_encrypted TEXT NOT NULL,
+    benf_ifsc VARCHAR(11) NOT NULL,
+    benf_acct_type VARCHAR(10) NOT NULL,
+    payment_mode VARCHAR(10) NOT NULL,
+    amount NUMERIC(15, 2) NOT NULL DEFAULT 0,
+    remarks VARCHAR(100) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL,
+    error_message TEXT,
+    txn_identifier VARCHAR(50),
+    utr VARCHAR(50),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    UNIQUE (bulk_payout_id, row_number)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS bulk_payout_rows;
+DROP TABLE IF EXISTS bulk_payouts;
+-- +goose StatementEnd
@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Limits a user set below the limits of their tier, a 0 keeps the tier limit.
+-- Limits are in rupees.
+CREATE TABLE user_transfer_limits (
+    user_id VARCHAR(50) NOT NULL,
+    payment_mode VARCHAR(10) NOT NULL,
+    per_transaction BIGINT NOT NULL DEFAULT 0,
+    daily BIGINT NOT NULL DEFAULT 0,
+    monthly BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, payment_mode)
+);
+
+-- Amount in paise sent per payment mode in a DAY or MONTH starting on
+-- period_start (IST). Payments add to it before they are sent to the bank
+-- and take it back when the bank rejects them.
+CREATE TABLE transfer_limit_usage (
+    user_id VARCHAR(50) NOT NULL,
+    payment_mode VARCHAR(10) NOT NULL,
+    period VARCHAR(10) NOT NULL,
+    period_start DATE NOT NULL,
+    amount BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, payment_mode, period, period_start)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS transfer_limit_usage;
+DROP TABLE IF EXISTS user_transfer_limits;
+-- +goose StatementEnd
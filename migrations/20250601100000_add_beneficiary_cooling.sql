@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A newly added beneficiary is cooling until cooling_until, the payments to it
+-- are capped and cooling_used is the amount in paise sent to it so far.
+ALTER TABLE beneficiaries
+    ADD COLUMN IF NOT EXISTS cooling_until TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    ADD COLUMN IF NOT EXISTS cooling_used BIGINT NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE beneficiaries
+    DROP COLUMN IF EXISTS cooling_used,
+    DROP COLUMN IF EXISTS cooling_until;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The bank has no api to disable or delete a beneficiary, a beneficiary the
+-- user disabled or deleted is kept here and is not paid or listed.
+-- sync_drift is the last difference found with the bank list and fixed.
+ALTER TABLE beneficiaries
+    ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    ADD COLUMN IF NOT EXISTS sync_drift VARCHAR(50) DEFAULT NULL,
+    ADD COLUMN IF NOT EXISTS synced_at TIMESTAMP WITH TIME ZONE DEFAULT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE beneficiaries
+    DROP COLUMN IF EXISTS synced_at,
+    DROP COLUMN IF EXISTS sync_drift,
+    DROP COLUMN IF EXISTS deleted_at,
+    DROP COLUMN IF EXISTS disabled_at;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The transfer limit usage counted for a payment waiting for its otp. A
+-- RESERVED payment is RELEASED when its otp fails or is not entered before
+-- expires_at, and CONFIRMED when the bank accepts its otp. Amount is in paise
+-- and benf_account is masked, as in beneficiaries.
+CREATE TABLE payment_reservations (
+    txn_identifier VARCHAR(100) PRIMARY KEY,
+    user_id VARCHAR(50) NOT NULL,
+    payment_mode VARCHAR(10) NOT NULL,
+    amount BIGINT NOT NULL,
+    benf_account VARCHAR(50) NOT NULL,
+    benf_ifsc VARCHAR(20) NOT NULL,
+    day DATE NOT NULL,
+    month DATE NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_payment_reservations_status_expires_at ON payment_reservations (status, expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS payment_reservations;
+-- +goose StatementEnd
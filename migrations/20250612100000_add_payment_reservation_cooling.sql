@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The cooling usage counted for a payment waiting for its otp, released and
+-- confirmed with its transfer limit usage. cooling_amount is in paise.
+ALTER TABLE payment_reservations
+    ADD COLUMN IF NOT EXISTS cooling_beneficiary_id UUID DEFAULT NULL,
+    ADD COLUMN IF NOT EXISTS cooling_amount BIGINT NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE payment_reservations
+    DROP COLUMN IF EXISTS cooling_amount,
+    DROP COLUMN IF EXISTS cooling_beneficiary_id;
+-- +goose StatementEnd
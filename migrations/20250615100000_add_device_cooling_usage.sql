@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The payments of a device counted against its cooling limit, and the part of
+-- a payment waiting for its otp that was counted for its device. Amounts are
+-- in paise.
+ALTER TABLE trusted_devices
+    ADD COLUMN IF NOT EXISTS cooling_used BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE payment_reservations
+    ADD COLUMN IF NOT EXISTS cooling_device_id UUID DEFAULT NULL,
+    ADD COLUMN IF NOT EXISTS device_cooling_amount BIGINT NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE payment_reservations
+    DROP COLUMN IF EXISTS device_cooling_amount,
+    DROP COLUMN IF EXISTS cooling_device_id;
+
+ALTER TABLE trusted_devices
+    DROP COLUMN IF EXISTS cooling_used;
+-- +goose StatementEnd
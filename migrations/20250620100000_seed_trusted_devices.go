@@ -0,0 +1,105 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"bankapi/security"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upSeedTrustedDevices, downSeedTrustedDevices)
+}
+
+// seededDevice is the device a user onboarded with, its device id is
+// encrypted with the signing key of the user
+type seededDevice struct {
+	userId      string
+	deviceId    string
+	signingKey  string
+	os          sql.NullString
+	osVersion   sql.NullString
+	packageId   sql.NullString
+	deviceToken sql.NullString
+}
+
+// upSeedTrustedDevices trusts the device of every user without a trusted
+// device, as requests from it did when they enrolled it on the fly. It is a
+// go migration as the device id can only be decrypted with the signing key.
+func upSeedTrustedDevices(tx *sql.Tx) error {
+	rows, err := tx.Query(
+		`SELECT d.user_id, d.device_id, u.signing_key, d.os, d.os_version, d.package_id, d.device_token
+		FROM device_data d
+		JOIN user_data u ON u.user_id = d.user_id
+		WHERE NOT EXISTS (
+			SELECT 1 FROM trusted_devices t WHERE t.user_id = d.user_id AND t.status = 'TRUSTED'
+		)`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get device data: %w", err)
+	}
+	defer rows.Close()
+
+	var devices []seededDevice
+	for rows.Next() {
+		var device seededDevice
+		if err := rows.Scan(
+			&device.userId,
+			&device.deviceId,
+			&device.signingKey,
+			&device.os,
+			&device.osVersion,
+			&device.packageId,
+			&device.deviceToken,
+		); err != nil {
+			return fmt.Errorf("failed to scan device data: %w", err)
+		}
+		devices = append(devices, device)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, device := range devices {
+		deviceId, err := security.Decrypt(device.deviceId, []byte(strings.TrimSpace(device.signingKey)))
+		if err != nil {
+			// such a device could not be enrolled by its requests either
+			log.Printf("seed trusted devices: skipping the device of user %s: %v", device.userId, err)
+			continue
+		}
+
+		if _, err := tx.Exec(
+			`INSERT INTO trusted_devices(
+				user_id,
+				device_id,
+				status,
+				os,
+				os_version,
+				package_id,
+				device_token,
+				trusted_at
+			) VALUES ($1, $2, 'TRUSTED', $3, $4, $5, $6, now())
+			ON CONFLICT (user_id, device_id) DO NOTHING`,
+			device.userId,
+			deviceId,
+			device.os,
+			device.osVersion,
+			device.packageId,
+			device.deviceToken,
+		); err != nil {
+			return fmt.Errorf("failed to seed trusted device: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// downSeedTrustedDevices keeps the seeded devices, they cannot be told apart
+// from the devices enrolled by requests
+func downSeedTrustedDevices(tx *sql.Tx) error {
+	return nil
+}
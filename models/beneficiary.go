@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"bankapi/config"
 	"bankapi/constants"
 	"bankapi/responses"
 
@@ -29,6 +30,10 @@ type BeneficiaryDetail struct {
 	BenfStatus       string `json:"beneficiary_status"`
 	BenfActivateTime string `json:"beneficiary_activate_time"`
 	TxnIdentifier    string `json:"txn_identifier"`
+	// FullLimitAt is when the cooling period of a newly added beneficiary
+	// ends, until then the payments to it are limited to CoolingLimit in total
+	FullLimitAt  string `json:"full_limit_at,omitempty"`
+	CoolingLimit string `json:"cooling_limit,omitempty"`
 }
 
 // For send same transaction while beneficary operation for the same record we are using this struct TxnIdentifier
@@ -49,6 +54,9 @@ type BeneficiaryDTO struct {
 	BenfActivateTime string               `json:"beneficiary_activate_time"`
 	ActivatedDtTime  string               `json:"activatedDtTime"`
 	IsActive         bool                 `json:"is_active"`
+	CoolingUntil     sql.NullTime         `json:"-"`
+	DisabledAt       sql.NullTime         `json:"-"`
+	DeletedAt        sql.NullTime         `json:"-"`
 	CreatedAt        time.Time            `json:"created_at"`
 	UpdatedAt        time.Time            `json:"updated_at"`
 }
@@ -83,6 +91,8 @@ func (b *BeneficiaryDetail) Bind(response *responses.FetchBeneficiaryResponse, d
 		}
 	}
 
+	setBeneficiaryState(bankBeneficiaries, data)
+
 	beneficiaries := make([]BeneficiaryDetail, 0, len(bankBeneficiaries))
 	for _, ben := range bankBeneficiaries {
 		beneficiaries = append(beneficiaries, *ben)
@@ -181,6 +191,49 @@ func (b *BeneficiaryDetail) FetchBind(response *responses.FetchBeneficiaryRespon
 	return beneficiaries, nil
 }
 
+// setBeneficiaryState applies what the user changed here to the beneficiaries
+// the bank returned, the bank has no api for it. Deleted beneficiaries are
+// left out, disabled ones are shown as disabled with the nickname the user
+// set, and those still in their cooling period show when full limits unlock.
+func setBeneficiaryState(beneficiaries map[string]*BeneficiaryDetail, data []BeneficiaryDTO) {
+	local := make(map[string]BeneficiaryDTO)
+	for _, beneficiary := range data {
+		if existing, ok := local[beneficiary.BenfId]; ok && !existing.DeletedAt.Valid {
+			continue
+		}
+		local[beneficiary.BenfId] = beneficiary
+	}
+	if len(local) == 0 {
+		return
+	}
+
+	coolingLimit := FormatPaise(config.GetBeneficiaryConfig().CoolingLimit * 100)
+	for key, ben := range beneficiaries {
+		beneficiary, ok := local[ben.BenfId]
+		if !ok {
+			continue
+		}
+
+		if beneficiary.DeletedAt.Valid {
+			delete(beneficiaries, key)
+			continue
+		}
+
+		if beneficiary.DisabledAt.Valid {
+			ben.BenfStatus = BeneficiaryStatusDisabled
+		}
+
+		if beneficiary.BenfNickName.String != "" {
+			ben.BenfNickName = beneficiary.BenfNickName.String
+		}
+
+		if beneficiary.CoolingUntil.Valid && beneficiary.CoolingUntil.Time.After(time.Now()) {
+			ben.FullLimitAt = beneficiary.CoolingUntil.Time.Format(time.RFC3339)
+			ben.CoolingLimit = coolingLimit
+		}
+	}
+}
+
 func maskAccountNumber(accountNo string) string {
 	if len(accountNo) <= 4 {
 		return accountNo
@@ -365,7 +418,7 @@ func FindBeneficiariesByUserId(db *sql.DB, userId string) ([]BeneficiaryDTO, err
 
 	beneficiaries := make([]BeneficiaryDTO, 0)
 
-	rows, err := db.Query("SELECT id, benf_id, user_id, benf_name, benf_nickname, benf_mobile_number, benf_ifsc, benf_acct_type, payment_mode, benf_activated_time, created_at, updated_at, is_active, benf_account, txn_identifier FROM beneficiaries WHERE user_id = $1", userId)
+	rows, err := db.Query("SELECT id, benf_id, user_id, benf_name, benf_nickname, benf_mobile_number, benf_ifsc, benf_acct_type, payment_mode, benf_activated_time, created_at, updated_at, is_active, benf_account, txn_identifier, cooling_until, disabled_at, deleted_at FROM beneficiaries WHERE user_id = $1", userId)
 	if err != nil {
 		return nil, err
 	}
@@ -390,6 +443,9 @@ func FindBeneficiariesByUserId(db *sql.DB, userId string) ([]BeneficiaryDTO, err
 			&ben.IsActive,
 			&ben.BenfAccountNo,
 			&ben.TxnIdentifier,
+			&ben.CoolingUntil,
+			&ben.DisabledAt,
+			&ben.DeletedAt,
 		)
 		if err != nil {
 			if err == sql.ErrNoRows {
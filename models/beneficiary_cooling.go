@@ -0,0 +1,155 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"bankapi/config"
+	"bankapi/constants"
+)
+
+// BeneficiaryCoolingReservation is a payment counted against the cooling
+// limit of a beneficiary, ReleaseBeneficiaryCooling takes it back when the
+// bank rejects the payment. Once the bank sends the otp it is kept with the
+// PaymentReservation of the payment, and taken back when the otp fails or
+// expires.
+type BeneficiaryCoolingReservation struct {
+	BeneficiaryId string
+	// Amount is in paise
+	Amount int64
+}
+
+// StartBeneficiaryCooling starts the cooling period of a beneficiary the user
+// just added, it returns when the full limits unlock. A beneficiary the user
+// disabled or deleted before is enabled again.
+func StartBeneficiaryCooling(db *sql.DB, userId, benfId string) (time.Time, error) {
+	coolingUntil := time.Now().Add(config.GetBeneficiaryConfig().CoolingPeriod)
+
+	_, err := db.Exec(
+		`UPDATE beneficiaries
+		SET cooling_until = $3, cooling_used = 0, disabled_at = NULL, deleted_at = NULL, updated_at = now()
+		WHERE benf_id = $1 AND user_id = $2`,
+		benfId,
+		userId,
+		coolingUntil,
+	)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to start beneficiary cooling: %w", err)
+	}
+
+	return coolingUntil, nil
+}
+
+// ReserveBeneficiaryCooling counts a payment against the cooling limit of the
+// beneficiary with the account, it returns nil when no beneficiary with the
+// account is cooling. The account is matched masked, as it is saved, so a
+// quick transfer to a cooling beneficiary is capped too.
+func ReserveBeneficiaryCooling(db *sql.DB, userId, benfAcctNo, benfIfsc, amount string) (*BeneficiaryCoolingReservation, error) {
+	if len(benfAcctNo) <= 4 {
+		return nil, nil
+	}
+
+	return reserveBeneficiaryCooling(db, userId, maskAccountNumber(benfAcctNo), benfIfsc, amount)
+}
+
+func reserveBeneficiaryCooling(db *sql.DB, userId, maskedAccount, benfIfsc, amount string) (*BeneficiaryCoolingReservation, error) {
+	var (
+		id           string
+		coolingUntil time.Time
+		coolingUsed  int64
+	)
+	err := db.QueryRow(
+		`SELECT id, cooling_until, cooling_used
+		FROM beneficiaries
+		WHERE user_id = $1 AND benf_account = $2 AND benf_ifsc = $3 AND cooling_until > now()
+		ORDER BY cooling_until DESC
+		LIMIT 1`,
+		userId,
+		maskedAccount,
+		benfIfsc,
+	).Scan(&id, &coolingUntil, &coolingUsed)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get beneficiary cooling: %w", err)
+	}
+
+	paise, err := ParseAmountPaise(amount)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := config.GetBeneficiaryConfig().CoolingLimit * 100
+
+	err = db.QueryRow(
+		`UPDATE beneficiaries SET cooling_used = cooling_used + $2, updated_at = now()
+		WHERE id = $1 AND cooling_until > now() AND cooling_used + $2 <= $3
+		RETURNING cooling_used`,
+		id,
+		paise,
+		limit,
+	).Scan(&coolingUsed)
+	if errors.Is(err, sql.ErrNoRows) {
+		remaining := limit - coolingUsed
+		if remaining < 0 {
+			remaining = 0
+		}
+		return nil, fmt.Errorf(constants.BeneficiaryCoolingLimitErrorMessage,
+			FormatPaise(limit),
+			coolingUntil.In(config.ISTLocation()).Format("02 Jan 2006, 03:04 PM"),
+			FormatPaise(remaining),
+		)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to add beneficiary cooling usage: %w", err)
+	}
+
+	return &BeneficiaryCoolingReservation{BeneficiaryId: id, Amount: paise}, nil
+}
+
+// ReleaseBeneficiaryCooling takes back the cooling usage of a payment the
+// bank rejected when it was submitted
+func ReleaseBeneficiaryCooling(db *sql.DB, reservation *BeneficiaryCoolingReservation) error {
+	return releaseBeneficiaryCoolingUsage(db, reservation)
+}
+
+func releaseBeneficiaryCoolingUsage(exec execer, reservation *BeneficiaryCoolingReservation) error {
+	if reservation == nil {
+		return nil
+	}
+
+	_, err := exec.Exec(
+		`UPDATE beneficiaries SET cooling_used = GREATEST(cooling_used - $2, 0), updated_at = now()
+		WHERE id = $1`,
+		reservation.BeneficiaryId,
+		reservation.Amount,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to release beneficiary cooling usage: %w", err)
+	}
+
+	return nil
+}
+
+// addBeneficiaryCoolingUsage counts a payment the bank already accepted
+// against the cooling limit, whatever is left of it
+func addBeneficiaryCoolingUsage(exec execer, reservation *BeneficiaryCoolingReservation) error {
+	if reservation == nil {
+		return nil
+	}
+
+	_, err := exec.Exec(
+		`UPDATE beneficiaries SET cooling_used = cooling_used + $2, updated_at = now()
+		WHERE id = $1`,
+		reservation.BeneficiaryId,
+		reservation.Amount,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add beneficiary cooling usage: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,302 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"bankapi/constants"
+	"bankapi/responses"
+)
+
+// Statuses of a beneficiary the user changed here
+const (
+	BeneficiaryStatusActive   = "active"
+	BeneficiaryStatusDisabled = "disabled"
+	BeneficiaryStatusDeleted  = "deleted"
+)
+
+// BeneficiaryState is a beneficiary after the user changed it
+type BeneficiaryState struct {
+	BenfId       string `json:"beneficiary_id"`
+	BenfNickName string `json:"beneficiary_nickname,omitempty"`
+	BenfStatus   string `json:"beneficiary_status"`
+}
+
+// Differences between the beneficiaries saved here and the bank list
+const (
+	// BeneficiaryDriftMissingLocal is a beneficiary the bank has and is not
+	// saved here, it is saved
+	BeneficiaryDriftMissingLocal = "MISSING_LOCAL"
+	// BeneficiaryDriftMissingAtBank is an active beneficiary the bank does not
+	// have, it is made inactive so the user adds it again
+	BeneficiaryDriftMissingAtBank = "MISSING_AT_BANK"
+	// BeneficiaryDriftNotActivated is a beneficiary the bank activated while
+	// its otp confirmation failed here, it is activated
+	BeneficiaryDriftNotActivated = "NOT_ACTIVATED"
+	// BeneficiaryDriftDetails is a beneficiary with other details at the bank,
+	// the details of the bank are saved
+	BeneficiaryDriftDetails = "DETAILS"
+)
+
+// BeneficiaryDrift is a difference between a beneficiary saved here and the bank list
+type BeneficiaryDrift struct {
+	BenfId string `json:"beneficiary_id"`
+	Drift  string `json:"drift"`
+	Fixed  bool   `json:"fixed"`
+	// Bank is the beneficiary in the bank list, nil when the bank does not have it
+	Bank *responses.BeneficiaryDetails `json:"-"`
+	// Local is the beneficiary saved here, nil when it is not saved
+	Local *BeneficiaryDTO `json:"-"`
+}
+
+// ReconcileBeneficiaries compares the beneficiaries saved for a user with the
+// bank list. Beneficiaries the user disabled or deleted stay at the bank, as
+// the bank has no api to remove them, and are not a drift.
+func ReconcileBeneficiaries(local []BeneficiaryDTO, bank []responses.BeneficiaryDetails) []BeneficiaryDrift {
+	saved := make(map[string]*BeneficiaryDTO)
+	for i := range local {
+		beneficiary := &local[i]
+		if existing, ok := saved[beneficiary.BenfId]; ok && !existing.DeletedAt.Valid {
+			continue
+		}
+		saved[beneficiary.BenfId] = beneficiary
+	}
+
+	drifts := make([]BeneficiaryDrift, 0)
+	atBank := make(map[string]bool)
+	for i := range bank {
+		bankBeneficiary := &bank[i]
+		atBank[bankBeneficiary.BenfID] = true
+
+		beneficiary, ok := saved[bankBeneficiary.BenfID]
+		switch {
+		case !ok:
+			drifts = append(drifts, BeneficiaryDrift{BenfId: bankBeneficiary.BenfID, Drift: BeneficiaryDriftMissingLocal, Bank: bankBeneficiary})
+		case beneficiary.DisabledAt.Valid || beneficiary.DeletedAt.Valid:
+		case !beneficiary.IsActive:
+			drifts = append(drifts, BeneficiaryDrift{BenfId: bankBeneficiary.BenfID, Drift: BeneficiaryDriftNotActivated, Bank: bankBeneficiary, Local: beneficiary})
+		case beneficiaryDetailsDiffer(beneficiary, bankBeneficiary):
+			drifts = append(drifts, BeneficiaryDrift{BenfId: bankBeneficiary.BenfID, Drift: BeneficiaryDriftDetails, Bank: bankBeneficiary, Local: beneficiary})
+		}
+	}
+
+	for benfId, beneficiary := range saved {
+		if atBank[benfId] || !beneficiary.IsActive || beneficiary.DisabledAt.Valid || beneficiary.DeletedAt.Valid {
+			continue
+		}
+		drifts = append(drifts, BeneficiaryDrift{BenfId: benfId, Drift: BeneficiaryDriftMissingAtBank, Local: beneficiary})
+	}
+
+	return drifts
+}
+
+func beneficiaryDetailsDiffer(local *BeneficiaryDTO, bank *responses.BeneficiaryDetails) bool {
+	return !strings.EqualFold(local.BenfName, bank.BenfName) ||
+		!strings.EqualFold(local.BenfIfsc, bank.BenfIFSC) ||
+		local.BenfAccountNo != maskAccountNumber(bank.BenfAcctNo) ||
+		local.BenfMobNo != bank.BenfMob ||
+		!strings.EqualFold(local.BenfAcctType, bank.BenfAcctType) ||
+		!strings.EqualFold(local.PaymentMode, bank.PaymentMode)
+}
+
+// FixBeneficiaryDrift saves a beneficiary as the bank list has it, or makes
+// it inactive when the bank does not have it, and records the drift. A
+// beneficiary it makes active must start its cooling period with
+// StartBeneficiaryCooling.
+func FixBeneficiaryDrift(db *sql.DB, userId string, drift *BeneficiaryDrift) error {
+	switch drift.Drift {
+	case BeneficiaryDriftMissingLocal:
+		ben := NewBeneficiaryDTO()
+		_ = ben.BindData(
+			drift.Bank.BenfID,
+			userId,
+			drift.Bank.BenfName,
+			"",
+			drift.Bank.BenfMob,
+			drift.Bank.BenfAcctNo,
+			drift.Bank.BenfIFSC,
+			drift.Bank.BenfAcctType,
+			drift.Bank.PaymentMode,
+			drift.Bank.BenfActivateTime,
+			"",
+		)
+		ben.IsActive = true
+
+		if len(ben.BenfAccountNo) <= 4 {
+			return fmt.Errorf("invalid bank beneficiary account %s", drift.BenfId)
+		}
+
+		id, err := InsertBeneficiaryDetails(db, ben)
+		if err != nil {
+			return fmt.Errorf("failed to save bank beneficiary: %w", err)
+		}
+
+		return markBeneficiarySynced(db,
+			`UPDATE beneficiaries SET sync_drift = $2, synced_at = now() WHERE id = $1`,
+			id,
+			drift.Drift,
+		)
+
+	case BeneficiaryDriftMissingAtBank:
+		return markBeneficiarySynced(db,
+			`UPDATE beneficiaries SET is_active = false, sync_drift = $2, synced_at = now(), updated_at = now()
+			WHERE id = $1`,
+			drift.Local.Id,
+			drift.Drift,
+		)
+
+	case BeneficiaryDriftNotActivated, BeneficiaryDriftDetails:
+		return markBeneficiarySynced(db,
+			`UPDATE beneficiaries
+			SET benf_name = $3, benf_ifsc = $4, benf_account = $5, benf_mobile_number = $6, benf_acct_type = $7,
+				payment_mode = $8, benf_activated_time = COALESCE(NULLIF($9, ''), benf_activated_time),
+				is_active = true, sync_drift = $2, synced_at = now(), updated_at = now()
+			WHERE id = $1`,
+			drift.Local.Id,
+			drift.Drift,
+			drift.Bank.BenfName,
+			drift.Bank.BenfIFSC,
+			maskAccountNumber(drift.Bank.BenfAcctNo),
+			drift.Bank.BenfMob,
+			drift.Bank.BenfAcctType,
+			drift.Bank.PaymentMode,
+			drift.Bank.BenfActivateTime,
+		)
+	}
+
+	return fmt.Errorf("unknown beneficiary drift %s", drift.Drift)
+}
+
+func markBeneficiarySynced(db *sql.DB, query string, args ...interface{}) error {
+	if _, err := db.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to fix beneficiary drift: %w", err)
+	}
+
+	return nil
+}
+
+// FindBeneficiaryOfUser returns a beneficiary of a user that is not deleted
+func FindBeneficiaryOfUser(db *sql.DB, userId, benfId string) (*BeneficiaryDTO, error) {
+	ben := NewBeneficiaryDTO()
+
+	err := db.QueryRow(
+		`SELECT id, benf_id, user_id, benf_name, benf_nickname, benf_account, is_active, disabled_at, deleted_at
+		FROM beneficiaries
+		WHERE user_id = $1 AND benf_id = $2 AND deleted_at IS NULL
+		ORDER BY created_at DESC
+		LIMIT 1`,
+		userId,
+		benfId,
+	).Scan(&ben.Id, &ben.BenfId, &ben.UserId, &ben.BenfName, &ben.BenfNickName, &ben.BenfAccountNo, &ben.IsActive, &ben.DisabledAt, &ben.DeletedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, constants.ErrNoDataFound
+		}
+		return nil, fmt.Errorf("failed to get beneficiary: %w", err)
+	}
+
+	return ben, nil
+}
+
+// UpdateBeneficiaryNickname sets the nickname the user sees for a
+// beneficiary, the bank keeps it under its beneficiary id
+func UpdateBeneficiaryNickname(db *sql.DB, userId, benfId, nickName string) error {
+	return updateBeneficiaryState(db,
+		`UPDATE beneficiaries SET benf_nickname = $3, updated_at = now()
+		WHERE user_id = $1 AND benf_id = $2 AND deleted_at IS NULL`,
+		userId,
+		benfId,
+		nickName,
+	)
+}
+
+// DisableBeneficiary stops the payments to an active beneficiary until the
+// user enables it
+func DisableBeneficiary(db *sql.DB, userId, benfId string) error {
+	return updateBeneficiaryState(db,
+		`UPDATE beneficiaries SET is_active = false, disabled_at = now(), updated_at = now()
+		WHERE user_id = $1 AND benf_id = $2 AND is_active = true AND deleted_at IS NULL`,
+		userId,
+		benfId,
+	)
+}
+
+// EnableBeneficiary allows the payments to a beneficiary the user disabled again
+func EnableBeneficiary(db *sql.DB, userId, benfId string) error {
+	return updateBeneficiaryState(db,
+		`UPDATE beneficiaries SET is_active = true, disabled_at = NULL, updated_at = now()
+		WHERE user_id = $1 AND benf_id = $2 AND disabled_at IS NOT NULL AND deleted_at IS NULL`,
+		userId,
+		benfId,
+	)
+}
+
+// DeleteBeneficiary removes a beneficiary from the list of the user and stops
+// the payments to it, it is kept as the bank still has it
+func DeleteBeneficiary(db *sql.DB, userId, benfId string) error {
+	return updateBeneficiaryState(db,
+		`UPDATE beneficiaries SET is_active = false, deleted_at = now(), updated_at = now()
+		WHERE user_id = $1 AND benf_id = $2 AND deleted_at IS NULL`,
+		userId,
+		benfId,
+	)
+}
+
+// updateBeneficiaryState returns constants.ErrNoDataFound when no beneficiary
+// could be updated
+func updateBeneficiaryState(db *sql.DB, query string, args ...interface{}) error {
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update beneficiary: %w", err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update beneficiary: %w", err)
+	}
+
+	if updated == 0 {
+		return constants.ErrNoDataFound
+	}
+
+	return nil
+}
+
+// CheckBeneficiaryPayable returns an error when the account is of a
+// beneficiary the user disabled or deleted and not added again. The account
+// is matched masked, as it is saved, so a quick transfer to it is rejected
+// too. An account that is not a beneficiary is payable.
+func CheckBeneficiaryPayable(db *sql.DB, userId, benfAcctNo, benfIfsc string) error {
+	var disabledAt, deletedAt sql.NullTime
+
+	err := db.QueryRow(
+		`SELECT disabled_at, deleted_at
+		FROM beneficiaries
+		WHERE user_id = $1 AND benf_account = $2 AND benf_ifsc = $3
+			AND (disabled_at IS NOT NULL OR deleted_at IS NOT NULL)
+			AND NOT EXISTS (
+				SELECT 1 FROM beneficiaries
+				WHERE user_id = $1 AND benf_account = $2 AND benf_ifsc = $3
+					AND is_active = true AND disabled_at IS NULL AND deleted_at IS NULL
+			)
+		ORDER BY disabled_at IS NULL, created_at DESC
+		LIMIT 1`,
+		userId,
+		maskAccountNumber(benfAcctNo),
+		benfIfsc,
+	).Scan(&disabledAt, &deletedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to get beneficiary: %w", err)
+	}
+
+	if disabledAt.Valid && !deletedAt.Valid {
+		return errors.New(constants.BeneficiaryDisabledErrorMessage)
+	}
+
+	return errors.New(constants.BeneficiaryNotFoundErrorMessage)
+}
@@ -0,0 +1,409 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"bankapi/constants"
+)
+
+// Statuses of a bulk payout
+const (
+	// BulkPayoutStatusPreview is a file checked and waiting for the user to confirm it
+	BulkPayoutStatusPreview    = "PREVIEW"
+	BulkPayoutStatusQueued     = "QUEUED"
+	BulkPayoutStatusProcessing = "PROCESSING"
+	// BulkPayoutStatusAwaitingOtp is a bulk payout whose rows were submitted
+	// and some still wait for the otp the bank sent for them
+	BulkPayoutStatusAwaitingOtp = "AWAITING_OTP"
+	BulkPayoutStatusCompleted   = "COMPLETED"
+)
+
+// Statuses of a row of a bulk payout
+const (
+	BulkPayoutRowStatusValid   = "VALID"
+	BulkPayoutRowStatusInvalid = "INVALID"
+	// BulkPayoutRowStatusProcessing is a row being submitted to the bank
+	BulkPayoutRowStatusProcessing = "PROCESSING"
+	// BulkPayoutRowStatusAwaitingOtp is a row the bank sent the user an otp
+	// for, it is paid once the otp is confirmed
+	BulkPayoutRowStatusAwaitingOtp = "AWAITING_OTP"
+	BulkPayoutRowStatusSuccess     = "SUCCESS"
+	BulkPayoutRowStatusFailed      = "FAILED"
+)
+
+type BulkPayout struct {
+	Id          string          `json:"id"`
+	UserId      string          `json:"-"`
+	FileName    string          `json:"file_name"`
+	FileType    string          `json:"file_type"`
+	Status      string          `json:"status"`
+	TotalRows   int             `json:"total_rows"`
+	ValidRows   int             `json:"valid_rows"`
+	TotalAmount string          `json:"total_amount"`
+	DeviceId    string          `json:"-"`
+	ConfirmedAt sql.NullTime    `json:"-"`
+	CompletedAt sql.NullTime    `json:"-"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	Rows        []BulkPayoutRow `json:"rows,omitempty"`
+}
+
+type BulkPayoutRow struct {
+	Id                   string     `json:"id"`
+	BulkPayoutId         string     `json:"-"`
+	RowNumber            int        `json:"row_number"`
+	BenfName             string     `json:"beneficiary_name"`
+	BenfAccount          string     `json:"account_number"`
+	BenfAccountEncrypted string     `json:"-"`
+	BenfIfsc             string     `json:"beneficiary_ifsc"`
+	BenfAcctType         string     `json:"beneficiary_account_type"`
+	PaymentMode          string     `json:"payment_mode"`
+	Amount               string     `json:"amount"`
+	Remarks              string     `json:"remarks"`
+	Status               string     `json:"status"`
+	ErrorMessage         NullString `json:"error_message"`
+	TxnIdentifier        NullString `json:"txn_identifier"`
+	Utr                  NullString `json:"utr"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+}
+
+func NewBulkPayout() *BulkPayout {
+	return &BulkPayout{}
+}
+
+func NewBulkPayoutRow() *BulkPayoutRow {
+	return &BulkPayoutRow{}
+}
+
+const bulkPayoutColumns = `
+	id,
+	user_id,
+	file_name,
+	file_type,
+	status,
+	total_rows,
+	valid_rows,
+	total_amount,
+	device_id,
+	confirmed_at,
+	completed_at,
+	created_at,
+	updated_at`
+
+func scanBulkPayout(row interface{ Scan(...interface{}) error }, bp *BulkPayout) error {
+	return row.Scan(
+		&bp.Id,
+		&bp.UserId,
+		&bp.FileName,
+		&bp.FileType,
+		&bp.Status,
+		&bp.TotalRows,
+		&bp.ValidRows,
+		&bp.TotalAmount,
+		&bp.DeviceId,
+		&bp.ConfirmedAt,
+		&bp.CompletedAt,
+		&bp.CreatedAt,
+		&bp.UpdatedAt,
+	)
+}
+
+const bulkPayoutRowColumns = `
+	id,
+	bulk_payout_id,
+	row_number,
+	benf_name,
+	benf_account,
+	benf_account_encrypted,
+	benf_ifsc,
+	benf_acct_type,
+	payment_mode,
+	amount,
+	remarks,
+	status,
+	error_message,
+	txn_identifier,
+	utr,
+	created_at,
+	updated_at`
+
+func scanBulkPayoutRow(row interface{ Scan(...interface{}) error }, r *BulkPayoutRow) error {
+	return row.Scan(
+		&r.Id,
+		&r.BulkPayoutId,
+		&r.RowNumber,
+		&r.BenfName,
+		&r.BenfAccount,
+		&r.BenfAccountEncrypted,
+		&r.BenfIfsc,
+		&r.BenfAcctType,
+		&r.PaymentMode,
+		&r.Amount,
+		&r.Remarks,
+		&r.Status,
+		&r.ErrorMessage,
+		&r.TxnIdentifier,
+		&r.Utr,
+		&r.CreatedAt,
+		&r.UpdatedAt,
+	)
+}
+
+// InsertBulkPayout saves a new bulk payout with its rows and sets their ids
+func InsertBulkPayout(db *sql.DB, bp *BulkPayout) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin bulk payout: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(
+		`INSERT INTO bulk_payouts (user_id, file_name, file_type, status, total_rows, valid_rows, total_amount, device_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at`,
+		bp.UserId,
+		bp.FileName,
+		bp.FileType,
+		bp.Status,
+		bp.TotalRows,
+		bp.ValidRows,
+		bp.TotalAmount,
+		bp.DeviceId,
+	).Scan(&bp.Id, &bp.CreatedAt, &bp.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert bulk payout: %w", err)
+	}
+
+	for i := range bp.Rows {
+		r := &bp.Rows[i]
+		r.BulkPayoutId = bp.Id
+		err := tx.QueryRow(
+			`INSERT INTO bulk_payout_rows (
+				bulk_payout_id, row_number, benf_name, benf_account, benf_account_encrypted,
+				benf_ifsc, benf_acct_type, payment_mode, amount, remarks, status, error_message
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			RETURNING id, created_at, updated_at`,
+			r.BulkPayoutId,
+			r.RowNumber,
+			r.BenfName,
+			r.BenfAccount,
+			r.BenfAccountEncrypted,
+			r.BenfIfsc,
+			r.BenfAcctType,
+			r.PaymentMode,
+			r.Amount,
+			r.Remarks,
+			r.Status,
+			r.ErrorMessage,
+		).Scan(&r.Id, &r.CreatedAt, &r.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to insert bulk payout row %d: %w", r.RowNumber, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit bulk payout: %w", err)
+	}
+
+	return nil
+}
+
+func GetBulkPayout(db *sql.DB, id string) (*BulkPayout, error) {
+	bp := NewBulkPayout()
+	err := scanBulkPayout(db.QueryRow(
+		`SELECT`+bulkPayoutColumns+`
+		FROM bulk_payouts
+		WHERE id = $1`,
+		id,
+	), bp)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, constants.ErrNoDataFound
+		}
+		return nil, fmt.Errorf("failed to get bulk payout: %w", err)
+	}
+
+	return bp, nil
+}
+
+// ListBulkPayouts returns the bulk payouts of a user without their rows, newest first
+func ListBulkPayouts(db *sql.DB, userId string) ([]BulkPayout, error) {
+	rows, err := db.Query(
+		`SELECT`+bulkPayoutColumns+`
+		FROM bulk_payouts
+		WHERE user_id = $1
+		ORDER BY created_at DESC`,
+		userId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bulk payouts: %w", err)
+	}
+	defer rows.Close()
+
+	bulkPayouts := make([]BulkPayout, 0)
+	for rows.Next() {
+		bp := NewBulkPayout()
+		if err := scanBulkPayout(rows, bp); err != nil {
+			return nil, fmt.Errorf("failed to scan bulk payout: %w", err)
+		}
+		bulkPayouts = append(bulkPayouts, *bp)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list bulk payouts: %w", err)
+	}
+
+	return bulkPayouts, nil
+}
+
+// ListBulkPayoutRows returns the rows of a bulk payout in file order
+func ListBulkPayoutRows(db *sql.DB, bulkPayoutId string) ([]BulkPayoutRow, error) {
+	rows, err := db.Query(
+		`SELECT`+bulkPayoutRowColumns+`
+		FROM bulk_payout_rows
+		WHERE bulk_payout_id = $1
+		ORDER BY row_number`,
+		bulkPayoutId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bulk payout rows: %w", err)
+	}
+	defer rows.Close()
+
+	bulkPayoutRows := make([]BulkPayoutRow, 0)
+	for rows.Next() {
+		r := NewBulkPayoutRow()
+		if err := scanBulkPayoutRow(rows, r); err != nil {
+			return nil, fmt.Errorf("failed to scan bulk payout row: %w", err)
+		}
+		bulkPayoutRows = append(bulkPayoutRows, *r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list bulk payout rows: %w", err)
+	}
+
+	return bulkPayoutRows, nil
+}
+
+// GetBulkPayoutRow returns a row of a bulk payout
+func GetBulkPayoutRow(db *sql.DB, bulkPayoutId, id string) (*BulkPayoutRow, error) {
+	r := NewBulkPayoutRow()
+	err := scanBulkPayoutRow(db.QueryRow(
+		`SELECT`+bulkPayoutRowColumns+`
+		FROM bulk_payout_rows
+		WHERE id = $1 AND bulk_payout_id = $2`,
+		id,
+		bulkPayoutId,
+	), r)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, constants.ErrNoDataFound
+		}
+		return nil, fmt.Errorf("failed to get bulk payout row: %w", err)
+	}
+
+	return r, nil
+}
+
+// CountBulkPayoutRows returns the number of rows of a bulk payout with a status
+func CountBulkPayoutRows(db *sql.DB, bulkPayoutId, status string) (int, error) {
+	var count int
+	err := db.QueryRow(
+		`SELECT COUNT(*) FROM bulk_payout_rows WHERE bulk_payout_id = $1 AND status = $2`,
+		bulkPayoutId,
+		status,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count bulk payout rows: %w", err)
+	}
+
+	return count, nil
+}
+
+// ConfirmBulkPayout queues a bulk payout the user confirmed from deviceId. It
+// returns false when the bulk payout was not a preview, so a bulk payout is
+// confirmed only once.
+func ConfirmBulkPayout(db *sql.DB, id, deviceId string) (bool, error) {
+	return updateBulkPayout(db,
+		`UPDATE bulk_payouts SET status = $3, device_id = $4, confirmed_at = now(), updated_at = now()
+		WHERE id = $1 AND status = $2`,
+		id,
+		BulkPayoutStatusPreview,
+		BulkPayoutStatusQueued,
+		deviceId,
+	)
+}
+
+// UpdateBulkPayoutStatus moves a bulk payout from one status to another, it
+// returns false when the bulk payout was not in the from status
+func UpdateBulkPayoutStatus(db *sql.DB, id, from, to string) (bool, error) {
+	return updateBulkPayout(db,
+		`UPDATE bulk_payouts
+		SET status = $3, completed_at = CASE WHEN $4 THEN now() ELSE completed_at END, updated_at = now()
+		WHERE id = $1 AND status = $2`,
+		id,
+		from,
+		to,
+		to == BulkPayoutStatusCompleted,
+	)
+}
+
+func updateBulkPayout(db *sql.DB, query string, args ...interface{}) (bool, error) {
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to update bulk payout: %w", err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to update bulk payout: %w", err)
+	}
+
+	return updated > 0, nil
+}
+
+// ClaimBulkPayoutRow marks a valid row as being submitted. It returns false
+// when the row was already claimed, so no row is paid twice.
+func ClaimBulkPayoutRow(db *sql.DB, id string) (bool, error) {
+	result, err := db.Exec(
+		`UPDATE bulk_payout_rows SET status = $3, updated_at = now()
+		WHERE id = $1 AND status = $2`,
+		id,
+		BulkPayoutRowStatusValid,
+		BulkPayoutRowStatusProcessing,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim bulk payout row: %w", err)
+	}
+
+	claimed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim bulk payout row: %w", err)
+	}
+
+	return claimed > 0, nil
+}
+
+// UpdateBulkPayoutRowResult sets the outcome of the payment of a row
+func UpdateBulkPayoutRowResult(db *sql.DB, id, status, txnIdentifier, utr, errorMessage string) error {
+	_, err := db.Exec(
+		`UPDATE bulk_payout_rows
+		SET status = $2, txn_identifier = NULLIF($3, ''), utr = NULLIF($4, ''), error_message = NULLIF($5, ''), updated_at = now()
+		WHERE id = $1`,
+		id,
+		status,
+		txnIdentifier,
+		utr,
+		errorMessage,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update bulk payout row: %w", err)
+	}
+
+	return nil
+}
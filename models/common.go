@@ -7,12 +7,17 @@ import (
 )
 
 type AuthValues struct {
-	UserId    string `json:"user_id"`
-	Key       string `json:"key"`
-	DeviceIp  string `json:"device_ip"`
-	OS        string `json:"os"`
-	OSVersion string `json:"os_version"`
-	LatLong   string `json:"lat_long"`
+	UserId string `json:"user_id"`
+	// Key encrypts the request and response payloads of the session
+	Key string `json:"key"`
+	// SigningKey is the key of the user for their data at rest
+	SigningKey string `json:"-"`
+	DeviceIp   string `json:"device_ip"`
+	OS         string `json:"os"`
+	OSVersion  string `json:"os_version"`
+	LatLong    string `json:"lat_long"`
+	SessionId  string `json:"session_id"`
+	DeviceId   string `json:"device_id"`
 }
 
 type RequestPayload struct {
@@ -0,0 +1,186 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"bankapi/config"
+	"bankapi/constants"
+)
+
+// DeviceCoolingReservation is a payment counted against the cooling limit of
+// a newly trusted device, ReleaseDeviceCooling takes it back when the payment
+// fails. Once the bank sends the otp of a payment it is kept with the
+// PaymentReservation of the payment, like the cooling of its beneficiary.
+type DeviceCoolingReservation struct {
+	// TrustedDeviceId is the id of the trusted_devices row
+	TrustedDeviceId string
+	// Amount is in paise
+	Amount int64
+}
+
+// ReserveDeviceCooling counts a payment against DEVICE_COOLING_PAYMENT_LIMIT,
+// the total a device may pay until its cooling period ends. It returns nil
+// when the device is not cooling. The usage is added in one statement that
+// checks the limit, so concurrent payments cannot go over it together.
+func ReserveDeviceCooling(db *sql.DB, userId, deviceId, amount string) (*DeviceCoolingReservation, error) {
+	id, coolingUntil, coolingUsed, err := deviceCooling(db, userId, deviceId)
+	if err != nil || id == "" {
+		return nil, err
+	}
+
+	return reserveDeviceCooling(db, id, coolingUntil, coolingUsed, amount)
+}
+
+// deviceCooling returns the trusted_devices row of a device in its cooling
+// period with its usage, id is empty when the device is not cooling
+func deviceCooling(db *sql.DB, userId, deviceId string) (id string, coolingUntil time.Time, coolingUsed int64, err error) {
+	if deviceId == "" {
+		return "", time.Time{}, 0, nil
+	}
+
+	err = db.QueryRow(
+		`SELECT id, cooling_until, cooling_used
+		FROM trusted_devices
+		WHERE user_id = $1 AND device_id = $2 AND status = $3 AND cooling_until > now()`,
+		userId,
+		deviceId,
+		DeviceStatusTrusted,
+	).Scan(&id, &coolingUntil, &coolingUsed)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", time.Time{}, 0, nil
+		}
+		return "", time.Time{}, 0, fmt.Errorf("failed to get device cooling: %w", err)
+	}
+
+	return id, coolingUntil, coolingUsed, nil
+}
+
+func deviceCoolingLimitError(limit int64, coolingUntil time.Time, coolingUsed int64) error {
+	remaining := limit - coolingUsed
+	if remaining < 0 {
+		remaining = 0
+	}
+	return fmt.Errorf(constants.DeviceCoolingLimitErrorMessage,
+		FormatPaise(limit),
+		coolingUntil.In(config.ISTLocation()).Format("02 Jan 2006, 03:04 PM"),
+		FormatPaise(remaining),
+	)
+}
+
+func reserveDeviceCooling(db *sql.DB, id string, coolingUntil time.Time, coolingUsed int64, amount string) (*DeviceCoolingReservation, error) {
+	paise, err := ParseAmountPaise(amount)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := int64(config.GetDeviceConfig().CoolingPaymentLimit * 100)
+
+	err = db.QueryRow(
+		`UPDATE trusted_devices SET cooling_used = cooling_used + $2
+		WHERE id = $1 AND cooling_until > now() AND cooling_used + $2 <= $3
+		RETURNING cooling_used`,
+		id,
+		paise,
+		limit,
+	).Scan(&coolingUsed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, deviceCoolingLimitError(limit, coolingUntil, coolingUsed)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to add device cooling usage: %w", err)
+	}
+
+	return &DeviceCoolingReservation{TrustedDeviceId: id, Amount: paise}, nil
+}
+
+// holdDeviceCooling counts a released payment again against the cooling
+// limit of its device, it returns nil when the device is no longer cooling
+func holdDeviceCooling(db *sql.DB, reservation *DeviceCoolingReservation) (*DeviceCoolingReservation, error) {
+	if reservation == nil {
+		return nil, nil
+	}
+
+	var (
+		coolingUntil time.Time
+		coolingUsed  int64
+	)
+	err := db.QueryRow(
+		`SELECT cooling_until, cooling_used FROM trusted_devices WHERE id = $1 AND cooling_until > now()`,
+		reservation.TrustedDeviceId,
+	).Scan(&coolingUntil, &coolingUsed)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get device cooling: %w", err)
+	}
+
+	return reserveDeviceCooling(db, reservation.TrustedDeviceId, coolingUntil, coolingUsed, FormatPaise(reservation.Amount))
+}
+
+// CheckDeviceCoolingLimit rejects an amount above what is left of the
+// cooling limit of the device without counting it, for payments made later
+// such as standing instructions
+func CheckDeviceCoolingLimit(db *sql.DB, userId, deviceId, amount string) error {
+	id, coolingUntil, coolingUsed, err := deviceCooling(db, userId, deviceId)
+	if err != nil || id == "" {
+		return err
+	}
+
+	paise, err := ParseAmountPaise(amount)
+	if err != nil {
+		return err
+	}
+
+	limit := int64(config.GetDeviceConfig().CoolingPaymentLimit * 100)
+	if coolingUsed+paise > limit {
+		return deviceCoolingLimitError(limit, coolingUntil, coolingUsed)
+	}
+
+	return nil
+}
+
+// ReleaseDeviceCooling takes back the cooling usage of a payment that failed
+func ReleaseDeviceCooling(db *sql.DB, reservation *DeviceCoolingReservation) error {
+	return releaseDeviceCoolingUsage(db, reservation)
+}
+
+func releaseDeviceCoolingUsage(exec execer, reservation *DeviceCoolingReservation) error {
+	if reservation == nil {
+		return nil
+	}
+
+	_, err := exec.Exec(
+		`UPDATE trusted_devices SET cooling_used = GREATEST(cooling_used - $2, 0) WHERE id = $1`,
+		reservation.TrustedDeviceId,
+		reservation.Amount,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to release device cooling usage: %w", err)
+	}
+
+	return nil
+}
+
+// addDeviceCoolingUsage counts a payment the bank already accepted against
+// the cooling limit, whatever is left of it
+func addDeviceCoolingUsage(exec execer, reservation *DeviceCoolingReservation) error {
+	if reservation == nil {
+		return nil
+	}
+
+	_, err := exec.Exec(
+		`UPDATE trusted_devices SET cooling_used = cooling_used + $2 WHERE id = $1`,
+		reservation.TrustedDeviceId,
+		reservation.Amount,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add device cooling usage: %w", err)
+	}
+
+	return nil
+}
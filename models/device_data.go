@@ -101,7 +101,11 @@ func (d *DeviceData) Create(userId, deviceIp, os, osVersion, key string, request
 }
 
 func InsertDevice(db *sql.DB, deviceData *DeviceData) error {
-	_, err := db.Exec(
+	return insertDevice(db, deviceData)
+}
+
+func insertDevice(exec execer, deviceData *DeviceData) error {
+	_, err := exec.Exec(
 		"INSERT INTO device_data (user_id, device_id, sim_vendor_id, device_ip, os, os_version, package_id, device_token) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
 		deviceData.UserId,
 		deviceData.DeviceId,
@@ -121,6 +125,10 @@ func InsertDevice(db *sql.DB, deviceData *DeviceData) error {
 }
 
 func UpdateDevice(db *sql.DB, deviceData *DeviceData, userId string) error {
+	return updateDevice(db, deviceData, userId)
+}
+
+func updateDevice(exec execer, deviceData *DeviceData, userId string) error {
 	var columns []string
 	var params []interface{}
 
@@ -180,7 +188,7 @@ func UpdateDevice(db *sql.DB, deviceData *DeviceData, userId string) error {
 		params = append(params, userId)
 
 		fmt.Println("QUERY ", query)
-		_, err := db.Exec(query, params...)
+		_, err := exec.Exec(query, params...)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,225 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"bankapi/constants"
+)
+
+// Signature algorithms of a device key
+const (
+	DeviceKeyAlgorithmES256   = "ES256"
+	DeviceKeyAlgorithmEd25519 = "Ed25519"
+)
+
+// Statuses of a device key
+const (
+	DeviceKeyStatusActive  = "ACTIVE"
+	DeviceKeyStatusRevoked = "REVOKED"
+)
+
+type DeviceKey struct {
+	Id           string       `json:"id"`
+	UserId       string       `json:"-"`
+	DeviceDataId string       `json:"-"`
+	DeviceId     string       `json:"-"`
+	Algorithm    string       `json:"algorithm"`
+	PublicKey    string       `json:"public_key"`
+	Status       string       `json:"status"`
+	LastUsedAt   *time.Time   `json:"last_used_at"`
+	RevokedAt    sql.NullTime `json:"-"`
+	CreatedAt    time.Time    `json:"created_at"`
+	Current      bool         `json:"current"`
+}
+
+func NewDeviceKey() *DeviceKey {
+	return &DeviceKey{}
+}
+
+const deviceKeyColumns = `
+	id,
+	user_id,
+	device_data_id,
+	device_id,
+	algorithm,
+	public_key,
+	status,
+	last_used_at,
+	revoked_at,
+	created_at`
+
+func scanDeviceKey(row interface{ Scan(...interface{}) error }, key *DeviceKey) error {
+	return row.Scan(
+		&key.Id,
+		&key.UserId,
+		&key.DeviceDataId,
+		&key.DeviceId,
+		&key.Algorithm,
+		&key.PublicKey,
+		&key.Status,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+		&key.CreatedAt,
+	)
+}
+
+// CreateDeviceKey registers the key of a device, the previous key of the
+// device is revoked
+func CreateDeviceKey(db *sql.DB, key *DeviceKey) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin device key: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		`UPDATE device_keys SET status = $3, revoked_at = now()
+		WHERE user_id = $1 AND device_id = $2 AND status = $4`,
+		key.UserId,
+		key.DeviceId,
+		DeviceKeyStatusRevoked,
+		DeviceKeyStatusActive,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke previous device key: %w", err)
+	}
+
+	err = tx.QueryRow(
+		`INSERT INTO device_keys(
+			user_id,
+			device_data_id,
+			device_id,
+			algorithm,
+			public_key,
+			status
+		) VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`,
+		key.UserId,
+		key.DeviceDataId,
+		key.DeviceId,
+		key.Algorithm,
+		key.PublicKey,
+		DeviceKeyStatusActive,
+	).Scan(&key.Id, &key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create device key: %w", err)
+	}
+	key.Status = DeviceKeyStatusActive
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit device key: %w", err)
+	}
+
+	return nil
+}
+
+func GetDeviceKey(db *sql.DB, id string) (*DeviceKey, error) {
+	key := NewDeviceKey()
+	err := scanDeviceKey(db.QueryRow(
+		`SELECT`+deviceKeyColumns+`
+		FROM device_keys
+		WHERE id = $1`,
+		id,
+	), key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, constants.ErrNoDataFound
+		}
+		return nil, fmt.Errorf("failed to get device key: %w", err)
+	}
+
+	return key, nil
+}
+
+// FindActiveDeviceKey returns the active key of a device of a user
+func FindActiveDeviceKey(db *sql.DB, userId, deviceId string) (*DeviceKey, error) {
+	key := NewDeviceKey()
+	err := scanDeviceKey(db.QueryRow(
+		`SELECT`+deviceKeyColumns+`
+		FROM device_keys
+		WHERE user_id = $1 AND device_id = $2 AND status = $3`,
+		userId,
+		deviceId,
+		DeviceKeyStatusActive,
+	), key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, constants.ErrNoDataFound
+		}
+		return nil, fmt.Errorf("failed to get device key: %w", err)
+	}
+
+	return key, nil
+}
+
+// ListDeviceKeys returns the active keys of a user, newest first
+func ListDeviceKeys(db *sql.DB, userId string) ([]DeviceKey, error) {
+	rows, err := db.Query(
+		`SELECT`+deviceKeyColumns+`
+		FROM device_keys
+		WHERE user_id = $1 AND status = $2
+		ORDER BY created_at DESC`,
+		userId,
+		DeviceKeyStatusActive,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list device keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []DeviceKey{}
+	for rows.Next() {
+		var key DeviceKey
+		if err := scanDeviceKey(rows, &key); err != nil {
+			return nil, fmt.Errorf("failed to scan device key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func RevokeDeviceKey(db *sql.DB, id string) error {
+	_, err := db.Exec(
+		`UPDATE device_keys SET status = $2, revoked_at = now() WHERE id = $1 AND status <> $2`,
+		id,
+		DeviceKeyStatusRevoked,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke device key: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeDeviceKeysOfDevices revokes the keys of devices a user no longer banks from
+func RevokeDeviceKeysOfDevices(db *sql.DB, userId string, deviceIds []string) error {
+	for _, deviceId := range deviceIds {
+		_, err := db.Exec(
+			`UPDATE device_keys SET status = $3, revoked_at = now()
+			WHERE user_id = $1 AND device_id = $2 AND status = $4`,
+			userId,
+			deviceId,
+			DeviceKeyStatusRevoked,
+			DeviceKeyStatusActive,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to revoke device keys: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// TouchDeviceKey records a verified signature of the key
+func TouchDeviceKey(db *sql.DB, id string) error {
+	_, err := db.Exec(`UPDATE device_keys SET last_used_at = now() WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to update device key: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"bankapi/constants"
+)
+
+// Idempotency key states, a key stays PROCESSING until the request it guards has an outcome
+const (
+	IdempotencyProcessing = "PROCESSING"
+	IdempotencyCompleted  = "COMPLETED"
+)
+
+type IdempotencyKey struct {
+	Id             string         `json:"id"`
+	UserId         string         `json:"user_id"`
+	Key            string         `json:"idempotency_key"`
+	Route          string         `json:"route"`
+	RequestHash    string         `json:"request_hash"`
+	Status         string         `json:"status"`
+	ResponseStatus sql.NullInt64  `json:"response_status"`
+	ResponseBody   sql.NullString `json:"response_body"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+func NewIdempotencyKey() *IdempotencyKey {
+	return &IdempotencyKey{}
+}
+
+// ClaimIdempotencyKey inserts the key in PROCESSING state. A key older than
+// expiresAfter, or left PROCESSING for longer than lease by a request that
+// never finished, is taken over. When the key is held by an earlier request
+// the stored row is returned and claimed is false.
+func ClaimIdempotencyKey(db *sql.DB, key *IdempotencyKey, expiresAfter, lease time.Duration) (*IdempotencyKey, bool, error) {
+	err := db.QueryRow(
+		`INSERT INTO idempotency_keys(
+			user_id,
+			idempotency_key,
+			route,
+			request_hash,
+			status
+		) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, idempotency_key) DO UPDATE SET
+			route = EXCLUDED.route,
+			request_hash = EXCLUDED.request_hash,
+			status = EXCLUDED.status,
+			response_status = NULL,
+			response_body = NULL,
+			created_at = now(),
+			updated_at = now()
+		WHERE idempotency_keys.created_at < $6
+			OR (idempotency_keys.status = $5 AND idempotency_keys.updated_at < $7)
+		RETURNING id, created_at`,
+		key.UserId,
+		key.Key,
+		key.Route,
+		key.RequestHash,
+		IdempotencyProcessing,
+		time.Now().Add(-expiresAfter),
+		time.Now().Add(-lease),
+	).Scan(&key.Id, &key.CreatedAt)
+
+	if err == nil {
+		key.Status = IdempotencyProcessing
+		return key, true, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+
+	existing, err := GetIdempotencyKey(db, key.UserId, key.Key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return existing, false, nil
+}
+
+func GetIdempotencyKey(db *sql.DB, userId, key string) (*IdempotencyKey, error) {
+	idempotencyKey := NewIdempotencyKey()
+	row := db.QueryRow(
+		`SELECT
+			id,
+			user_id,
+			idempotency_key,
+			route,
+			request_hash,
+			status,
+			response_status,
+			response_body,
+			created_at,
+			updated_at
+		FROM idempotency_keys
+		WHERE
+			user_id = $1 AND idempotency_key = $2`,
+		userId,
+		key,
+	)
+
+	if err := row.Scan(
+		&idempotencyKey.Id,
+		&idempotencyKey.UserId,
+		&idempotencyKey.Key,
+		&idempotencyKey.Route,
+		&idempotencyKey.RequestHash,
+		&idempotencyKey.Status,
+		&idempotencyKey.ResponseStatus,
+		&idempotencyKey.ResponseBody,
+		&idempotencyKey.CreatedAt,
+		&idempotencyKey.UpdatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, constants.ErrNoDataFound
+		}
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	return idempotencyKey, nil
+}
+
+// CompleteIdempotencyKey stores the outcome returned for the key, unless the
+// claim of key was taken over after its lease ran out
+func CompleteIdempotencyKey(db *sql.DB, key *IdempotencyKey, responseStatus int, responseBody string) error {
+	if _, err := db.Exec(
+		`UPDATE idempotency_keys SET
+			status = $1,
+			response_status = $2,
+			response_body = $3,
+			updated_at = now()
+		WHERE id = $4 AND created_at = $5`,
+		IdempotencyCompleted,
+		responseStatus,
+		responseBody,
+		key.Id,
+		key.CreatedAt,
+	); err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey removes a key whose request has no outcome to replay,
+// it ended before reaching the bank or failed with a server error
+func ReleaseIdempotencyKey(db *sql.DB, key *IdempotencyKey) error {
+	if _, err := db.Exec(
+		`DELETE FROM idempotency_keys WHERE id = $1 AND created_at = $2`,
+		key.Id,
+		key.CreatedAt,
+	); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
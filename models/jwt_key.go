@@ -0,0 +1,116 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// jwtKeyRotationLock serialises rotations of instances sharing the database
+const jwtKeyRotationLock = 7201
+
+// JwtKey is a key of the jwt keyring. PrivateKey is the AES encrypted secret
+// or PEM private key, PublicKey is the PEM public key of asymmetric keys.
+type JwtKey struct {
+	Kid        string
+	Algorithm  string
+	PrivateKey string
+	PublicKey  sql.NullString
+	CreatedAt  time.Time
+	RetiredAt  sql.NullTime
+	ExpiresAt  sql.NullTime
+}
+
+// ListJwtKeys returns the keys that have not expired, newest first
+func ListJwtKeys(db *sql.DB) ([]JwtKey, error) {
+	rows, err := db.Query(
+		`SELECT
+			kid,
+			algorithm,
+			private_key,
+			public_key,
+			created_at,
+			retired_at,
+			expires_at
+		FROM jwt_signing_keys
+		WHERE expires_at IS NULL OR expires_at > now()
+		ORDER BY created_at DESC`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jwt keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []JwtKey{}
+	for rows.Next() {
+		var key JwtKey
+		if err := rows.Scan(
+			&key.Kid,
+			&key.Algorithm,
+			&key.PrivateKey,
+			&key.PublicKey,
+			&key.CreatedAt,
+			&key.RetiredAt,
+			&key.ExpiresAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan jwt key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// RotateJwtKey makes key the signing key when the current signing key was
+// created before rotateBefore. The retired keys keep verifying tokens until
+// retiredExpiresAt. It returns false when another instance rotated first.
+func RotateJwtKey(db *sql.DB, key *JwtKey, rotateBefore, retiredExpiresAt time.Time) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin jwt key rotation: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, jwtKeyRotationLock); err != nil {
+		return false, fmt.Errorf("failed to lock jwt key rotation: %w", err)
+	}
+
+	var recent bool
+	err = tx.QueryRow(
+		`SELECT EXISTS(SELECT 1 FROM jwt_signing_keys WHERE retired_at IS NULL AND created_at >= $1)`,
+		rotateBefore,
+	).Scan(&recent)
+	if err != nil {
+		return false, fmt.Errorf("failed to check jwt signing key: %w", err)
+	}
+	if recent {
+		return false, nil
+	}
+
+	_, err = tx.Exec(
+		`UPDATE jwt_signing_keys SET retired_at = now(), expires_at = $1 WHERE retired_at IS NULL`,
+		retiredExpiresAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to retire jwt signing key: %w", err)
+	}
+
+	err = tx.QueryRow(
+		`INSERT INTO jwt_signing_keys(kid, algorithm, private_key, public_key)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at`,
+		key.Kid,
+		key.Algorithm,
+		key.PrivateKey,
+		key.PublicKey,
+	).Scan(&key.CreatedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to create jwt signing key: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit jwt key rotation: %w", err)
+	}
+
+	return true, nil
+}
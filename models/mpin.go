@@ -1,6 +1,7 @@
 package models
 
 import (
+	"bankapi/config"
 	"bankapi/constants"
 	"bankapi/requests"
 	"database/sql"
@@ -72,18 +73,26 @@ func FindOneMpinByUserId(db *sql.DB, userId string) (*MPIN, error) {
 }
 
 type MpinAttemptData struct {
-	ID          uuid.UUID    `json:"id"`
-	UserId      string       `json:"user_id"`
-	Attempts    int          `db:"attempts"`
-	LastAttempt sql.NullTime `db:"last_attempt"`
-	CreatedAt   time.Time    `db:"created_at"`
-	UpdatedAt   time.Time    `db:"updated_at"`
+	ID            uuid.UUID    `json:"id"`
+	UserId        string       `json:"user_id"`
+	Attempts      int          `db:"attempts"`
+	LastAttempt   sql.NullTime `db:"last_attempt"`
+	LockedUntil   sql.NullTime `db:"locked_until"`
+	LockoutCount  int          `db:"lockout_count"`
+	LastLockoutAt sql.NullTime `db:"last_lockout_at"`
+	CreatedAt     time.Time    `db:"created_at"`
+	UpdatedAt     time.Time    `db:"updated_at"`
 }
 
 func NewMpinAttemptData() *MpinAttemptData {
 	return &MpinAttemptData{}
 }
 
+// IsLocked reports whether the mpin is in a lockout
+func (m *MpinAttemptData) IsLocked() bool {
+	return m.LockedUntil.Valid && time.Now().Before(m.LockedUntil.Time)
+}
+
 func GetMpinAttempts(db *sql.DB, userId string) (*MpinAttemptData, error) {
 	attemptData := NewMpinAttemptData()
 
@@ -93,6 +102,9 @@ func GetMpinAttempts(db *sql.DB, userId string) (*MpinAttemptData, error) {
 			user_id, 
 			attempts, 
 			last_attempt, 
+			locked_until,
+			lockout_count,
+			last_lockout_at,
 			created_at, 
 			updated_at 
 		FROM 
@@ -107,6 +119,9 @@ func GetMpinAttempts(db *sql.DB, userId string) (*MpinAttemptData, error) {
 		&attemptData.UserId,
 		&attemptData.Attempts,
 		&attemptData.LastAttempt,
+		&attemptData.LockedUntil,
+		&attemptData.LockoutCount,
+		&attemptData.LastLockoutAt,
 		&attemptData.CreatedAt,
 		&attemptData.UpdatedAt,
 	); err != nil {
@@ -172,11 +187,81 @@ func UpdateMpinAttempts(db *sql.DB, data *MpinAttemptData) error {
 	return nil
 }
 
+// RecordMpinFailure counts an incorrect mpin. The attempt that reaches
+// maxAttempts locks the mpin for the next of the lockout periods and starts
+// the count over, locked reports whether it did. An attempt racing with the
+// one that locked the mpin is not counted, it returns constants.ErrMpinLocked
+// with the lockout in data.
+func RecordMpinFailure(db *sql.DB, userId string, lockoutConfig *config.MpinLockoutConfig) (data *MpinAttemptData, locked bool, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin mpin attempt: %w", err)
+	}
+	defer tx.Rollback()
+
+	data = NewMpinAttemptData()
+	data.UserId = userId
+	err = tx.QueryRow(
+		`SELECT attempts, lockout_count, locked_until FROM mpin_attempt_data WHERE user_id = $1 FOR UPDATE`,
+		userId,
+	).Scan(&data.Attempts, &data.LockoutCount, &data.LockedUntil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to retrieve mpin attempt data: %w", err)
+	}
+
+	if data.IsLocked() {
+		return data, false, constants.ErrMpinLocked
+	}
+
+	now := time.Now()
+	data.Attempts++
+	data.LastAttempt = sql.NullTime{Time: now, Valid: true}
+
+	if data.Attempts >= lockoutConfig.MaxAttempts {
+		locked = true
+		data.Attempts = 0
+		data.LockoutCount++
+		data.LockedUntil = sql.NullTime{Time: now.Add(lockoutConfig.LockoutPeriod(data.LockoutCount)), Valid: true}
+		data.LastLockoutAt = sql.NullTime{Time: now, Valid: true}
+	}
+
+	_, err = tx.Exec(
+		`UPDATE mpin_attempt_data
+		SET
+			attempts = $2,
+			last_attempt = $3,
+			lockout_count = $4,
+			locked_until = COALESCE($5, locked_until),
+			last_lockout_at = COALESCE($6, last_lockout_at),
+			updated_at = NOW()
+		WHERE user_id = $1`,
+		userId,
+		data.Attempts,
+		data.LastAttempt,
+		data.LockoutCount,
+		data.LockedUntil,
+		data.LastLockoutAt,
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to update mpin attempts for user_id %s: %w", userId, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("failed to commit mpin attempt: %w", err)
+	}
+
+	return data, locked, nil
+}
+
+// ResetMpinAttempts clears the attempts and lockouts of a user, after a
+// correct mpin or a forgotten mpin reset
 func ResetMpinAttempts(db *sql.DB, userId string) error {
 	query := `
 		UPDATE mpin_attempt_data 
 		SET 
 			attempts = 0, 
+			lockout_count = 0,
+			locked_until = NULL,
 			last_attempt = NOW(), 
 			updated_at = NOW() 
 		WHERE 
@@ -194,6 +279,51 @@ func ResetMpinAttempts(db *sql.DB, userId string) error {
 	return nil
 }
 
+type MpinLockout struct {
+	UserId        string    `json:"user_id"`
+	MobileNumber  string    `json:"mobile_number"`
+	LockoutCount  int       `json:"lockout_count"`
+	LockedUntil   time.Time `json:"locked_until"`
+	LastLockoutAt time.Time `json:"last_lockout_at"`
+}
+
+// ListMpinLockouts returns the users whose mpin is locked now, longest lockout first
+func ListMpinLockouts(db *sql.DB) ([]MpinLockout, error) {
+	rows, err := db.Query(
+		`SELECT
+			m.user_id,
+			COALESCE(u.mobile_number, ''),
+			m.lockout_count,
+			m.locked_until,
+			COALESCE(m.last_lockout_at, m.locked_until)
+		FROM mpin_attempt_data m
+		LEFT JOIN user_data u ON u.user_id = m.user_id
+		WHERE m.locked_until > NOW()
+		ORDER BY m.locked_until DESC`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mpin lockouts: %w", err)
+	}
+	defer rows.Close()
+
+	lockouts := []MpinLockout{}
+	for rows.Next() {
+		var lockout MpinLockout
+		if err := rows.Scan(
+			&lockout.UserId,
+			&lockout.MobileNumber,
+			&lockout.LockoutCount,
+			&lockout.LockedUntil,
+			&lockout.LastLockoutAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan mpin lockout: %w", err)
+		}
+		lockouts = append(lockouts, lockout)
+	}
+
+	return lockouts, rows.Err()
+}
+
 func UpdateMpinAfterReset(db *sql.DB, encryptedMpin, userId string) error {
 	query := `
 	    UPDATE mpin_data
@@ -236,3 +366,59 @@ func UpdateMpinAfterForgottenResetByUserId(db *sql.DB, encryptedMpin string, use
 
 	return nil
 }
+
+// InsertMpinHistory records the hash of a newly set mpin and keeps only the
+// newest historySize entries of the user
+func InsertMpinHistory(db *sql.DB, userId, mpinHash string, historySize int) error {
+	if _, err := db.Exec(
+		`INSERT INTO mpin_history (user_id, mpin_hash) VALUES ($1, $2)`,
+		userId,
+		mpinHash,
+	); err != nil {
+		return fmt.Errorf("failed to insert mpin history for user_id %s: %w", userId, err)
+	}
+
+	if _, err := db.Exec(
+		`DELETE FROM mpin_history
+		WHERE user_id = $1 AND id NOT IN (
+			SELECT id FROM mpin_history WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2
+		)`,
+		userId,
+		historySize,
+	); err != nil {
+		return fmt.Errorf("failed to prune mpin history for user_id %s: %w", userId, err)
+	}
+
+	return nil
+}
+
+// IsMpinInHistory reports whether mpin matches one of the newest historySize
+// mpins of the user
+func IsMpinInHistory(db *sql.DB, userId, mpin string, historySize int) (bool, error) {
+	rows, err := db.Query(
+		`SELECT mpin_hash FROM mpin_history WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2`,
+		userId,
+		historySize,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to retrieve mpin history: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var mpinHash string
+		if err := rows.Scan(&mpinHash); err != nil {
+			return false, fmt.Errorf("failed to scan mpin history: %w", err)
+		}
+
+		if bcrypt.CompareHashAndPassword([]byte(mpinHash), []byte(mpin)) == nil {
+			return true, nil
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("failed to retrieve mpin history: %w", err)
+	}
+
+	return false, nil
+}
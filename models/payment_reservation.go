@@ -0,0 +1,438 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"bankapi/constants"
+)
+
+// Statuses of a payment reservation
+const (
+	PaymentReservationStatusReserved  = "RESERVED"
+	PaymentReservationStatusReleased  = "RELEASED"
+	PaymentReservationStatusConfirmed = "CONFIRMED"
+)
+
+// PaymentReservation is the transfer limit and cooling usage counted for a
+// payment the bank sent an otp for. It is kept until the otp is confirmed,
+// and released when the otp fails or is not entered before ExpiresAt.
+type PaymentReservation struct {
+	TxnIdentifier string
+	UserId        string
+	PaymentMode   string
+	// Amount is in paise
+	Amount      int64
+	BenfAccount string
+	BenfIfsc    string
+	Day         string
+	Month       string
+	Status      string
+	ExpiresAt   time.Time
+	// Cooling is nil when the beneficiary was not cooling
+	Cooling *BeneficiaryCoolingReservation
+	// DeviceCooling is nil when the device was not cooling
+	DeviceCooling *DeviceCoolingReservation
+}
+
+// execer runs a statement on a db or in a transaction
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// NewPaymentReservation keeps the usage of a payment submitted to the bank
+// until its otp is entered
+func NewPaymentReservation(txnIdentifier string, reservation *TransferLimitReservation, cooling *BeneficiaryCoolingReservation, deviceCooling *DeviceCoolingReservation, benfAcctNo, benfIfsc string, expiresAt time.Time) *PaymentReservation {
+	return &PaymentReservation{
+		TxnIdentifier: txnIdentifier,
+		UserId:        reservation.UserId,
+		PaymentMode:   reservation.PaymentMode,
+		Amount:        reservation.Amount,
+		BenfAccount:   maskAccountNumber(benfAcctNo),
+		BenfIfsc:      benfIfsc,
+		Day:           reservation.Day,
+		Month:         reservation.Month,
+		Status:        PaymentReservationStatusReserved,
+		ExpiresAt:     expiresAt,
+		Cooling:       cooling,
+		DeviceCooling: deviceCooling,
+	}
+}
+
+// Matches reports whether a resent payment is the payment the reservation was made for
+func (p *PaymentReservation) Matches(mode, amount, benfAcctNo, benfIfsc string) bool {
+	paise, err := ParseAmountPaise(amount)
+	if err != nil {
+		return false
+	}
+
+	return p.PaymentMode == strings.ToUpper(mode) &&
+		p.Amount == paise &&
+		p.BenfAccount == maskAccountNumber(benfAcctNo) &&
+		p.BenfIfsc == benfIfsc
+}
+
+func (p *PaymentReservation) transferLimitReservation() *TransferLimitReservation {
+	return &TransferLimitReservation{
+		UserId:      p.UserId,
+		PaymentMode: p.PaymentMode,
+		Day:         p.Day,
+		Month:       p.Month,
+		Amount:      p.Amount,
+	}
+}
+
+// coolingColumns are the cooling usage of a reservation as it is saved
+type coolingColumns struct {
+	beneficiaryId sql.NullString
+	amount        int64
+	deviceId      sql.NullString
+	deviceAmount  int64
+}
+
+// scanCooling sets the cooling usage of a reservation read from the db
+func (p *PaymentReservation) scanCooling(columns *coolingColumns) {
+	p.Cooling = nil
+	if columns.beneficiaryId.Valid {
+		p.Cooling = &BeneficiaryCoolingReservation{BeneficiaryId: columns.beneficiaryId.String, Amount: columns.amount}
+	}
+
+	p.DeviceCooling = nil
+	if columns.deviceId.Valid {
+		p.DeviceCooling = &DeviceCoolingReservation{TrustedDeviceId: columns.deviceId.String, Amount: columns.deviceAmount}
+	}
+}
+
+// coolingColumns returns the cooling beneficiary and device of a reservation to save
+func (p *PaymentReservation) coolingColumns() *coolingColumns {
+	columns := &coolingColumns{}
+	if p.Cooling != nil {
+		columns.beneficiaryId = sql.NullString{String: p.Cooling.BeneficiaryId, Valid: true}
+		columns.amount = p.Cooling.Amount
+	}
+	if p.DeviceCooling != nil {
+		columns.deviceId = sql.NullString{String: p.DeviceCooling.TrustedDeviceId, Valid: true}
+		columns.deviceAmount = p.DeviceCooling.Amount
+	}
+	return columns
+}
+
+// SavePaymentReservation saves the reservation of a payment waiting for its otp
+func SavePaymentReservation(db *sql.DB, p *PaymentReservation) error {
+	cooling := p.coolingColumns()
+
+	_, err := db.Exec(
+		`INSERT INTO payment_reservations (txn_identifier, user_id, payment_mode, amount, benf_account, benf_ifsc, day, month, status, expires_at, cooling_beneficiary_id, cooling_amount, cooling_device_id, device_cooling_amount)
+		VALUES ($1, $2, $3, $4, $5, $6, $7::date, $8::date, $9, $10, $11, $12, $13, $14)`,
+		p.TxnIdentifier,
+		p.UserId,
+		p.PaymentMode,
+		p.Amount,
+		p.BenfAccount,
+		p.BenfIfsc,
+		p.Day,
+		p.Month,
+		p.Status,
+		p.ExpiresAt,
+		cooling.beneficiaryId,
+		cooling.amount,
+		cooling.deviceId,
+		cooling.deviceAmount,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save payment reservation: %w", err)
+	}
+
+	return nil
+}
+
+// GetPaymentReservation returns the reservation of a payment of a user
+func GetPaymentReservation(db *sql.DB, userId, txnIdentifier string) (*PaymentReservation, error) {
+	var (
+		p          PaymentReservation
+		day, month time.Time
+		cooling    coolingColumns
+	)
+	err := db.QueryRow(
+		`SELECT txn_identifier, user_id, payment_mode, amount, benf_account, benf_ifsc, day, month, status, expires_at, cooling_beneficiary_id, cooling_amount, cooling_device_id, device_cooling_amount
+		FROM payment_reservations
+		WHERE txn_identifier = $1 AND user_id = $2`,
+		txnIdentifier,
+		userId,
+	).Scan(&p.TxnIdentifier, &p.UserId, &p.PaymentMode, &p.Amount, &p.BenfAccount, &p.BenfIfsc, &day, &month, &p.Status, &p.ExpiresAt, &cooling.beneficiaryId, &cooling.amount, &cooling.deviceId, &cooling.deviceAmount)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, constants.ErrNoDataFound
+		}
+		return nil, fmt.Errorf("failed to get payment reservation: %w", err)
+	}
+
+	p.Day = day.Format("2006-01-02")
+	p.Month = month.Format("2006-01-02")
+	p.scanCooling(&cooling)
+
+	return &p, nil
+}
+
+// ExtendPaymentReservation keeps a payment waiting for its otp until
+// expiresAt, as when the otp is sent again
+func ExtendPaymentReservation(db *sql.DB, txnIdentifier string, expiresAt time.Time) error {
+	_, err := db.Exec(
+		`UPDATE payment_reservations SET expires_at = $2, updated_at = now()
+		WHERE txn_identifier = $1 AND status = $3`,
+		txnIdentifier,
+		expiresAt,
+		PaymentReservationStatusReserved,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to extend payment reservation: %w", err)
+	}
+
+	return nil
+}
+
+// HoldPaymentReservation makes sure the usage of a payment is counted before
+// its otp is sent to the bank. A released payment is counted again with the
+// limits of today and the cooling limits of its beneficiary and device, so an
+// otp entered late cannot go over them.
+func HoldPaymentReservation(db *sql.DB, p *PaymentReservation, expiresAt time.Time) error {
+	switch p.Status {
+	case PaymentReservationStatusReserved:
+		return nil
+	case PaymentReservationStatusConfirmed:
+		return errors.New(constants.PaymentReservationConfirmedErrorMessage)
+	}
+
+	deviceCooling, err := holdDeviceCooling(db, p.DeviceCooling)
+	if err != nil {
+		return err
+	}
+
+	cooling, err := reserveBeneficiaryCooling(db, p.UserId, p.BenfAccount, p.BenfIfsc, FormatPaise(p.Amount))
+	if err != nil {
+		if releaseErr := ReleaseDeviceCooling(db, deviceCooling); releaseErr != nil {
+			return releaseErr
+		}
+		return err
+	}
+
+	reservation, err := ReserveTransferLimit(db, p.UserId, p.PaymentMode, FormatPaise(p.Amount))
+	if err != nil {
+		if releaseErr := ReleaseDeviceCooling(db, deviceCooling); releaseErr != nil {
+			return releaseErr
+		}
+		if releaseErr := ReleaseBeneficiaryCooling(db, cooling); releaseErr != nil {
+			return releaseErr
+		}
+		return err
+	}
+
+	held := *p
+	held.Day, held.Month, held.ExpiresAt = reservation.Day, reservation.Month, expiresAt
+	held.Cooling, held.DeviceCooling = cooling, deviceCooling
+	columns := held.coolingColumns()
+
+	result, err := db.Exec(
+		`UPDATE payment_reservations
+		SET status = $2, day = $3::date, month = $4::date, expires_at = $5, cooling_beneficiary_id = $7, cooling_amount = $8, cooling_device_id = $9, device_cooling_amount = $10, updated_at = now()
+		WHERE txn_identifier = $1 AND status = $6`,
+		p.TxnIdentifier,
+		PaymentReservationStatusReserved,
+		held.Day,
+		held.Month,
+		held.ExpiresAt,
+		PaymentReservationStatusReleased,
+		columns.beneficiaryId,
+		columns.amount,
+		columns.deviceId,
+		columns.deviceAmount,
+	)
+	if err == nil {
+		var rows int64
+		if rows, err = result.RowsAffected(); err == nil && rows == 0 {
+			err = errors.New(constants.PaymentReservationConfirmedErrorMessage)
+		}
+	}
+	if err != nil {
+		if releaseErr := ReleaseDeviceCooling(db, deviceCooling); releaseErr != nil {
+			return releaseErr
+		}
+		if releaseErr := ReleaseBeneficiaryCooling(db, cooling); releaseErr != nil {
+			return releaseErr
+		}
+		if releaseErr := ReleaseTransferLimit(db, reservation); releaseErr != nil {
+			return releaseErr
+		}
+		return err
+	}
+
+	held.Status = PaymentReservationStatusReserved
+	*p = held
+
+	return nil
+}
+
+// ReleasePaymentReservation takes back the usage of a payment whose otp
+// failed or expired, a payment already released or confirmed is left as is
+func ReleasePaymentReservation(db *sql.DB, txnIdentifier string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin payment reservation release: %w", err)
+	}
+	defer tx.Rollback()
+
+	var (
+		p          PaymentReservation
+		day, month time.Time
+		cooling    coolingColumns
+	)
+	err = tx.QueryRow(
+		`SELECT user_id, payment_mode, amount, day, month, cooling_beneficiary_id, cooling_amount, cooling_device_id, device_cooling_amount
+		FROM payment_reservations
+		WHERE txn_identifier = $1 AND status = $2
+		FOR UPDATE`,
+		txnIdentifier,
+		PaymentReservationStatusReserved,
+	).Scan(&p.UserId, &p.PaymentMode, &p.Amount, &day, &month, &cooling.beneficiaryId, &cooling.amount, &cooling.deviceId, &cooling.deviceAmount)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get payment reservation: %w", err)
+	}
+	p.Day = day.Format("2006-01-02")
+	p.Month = month.Format("2006-01-02")
+	p.scanCooling(&cooling)
+
+	if err := releaseTransferLimitUsage(tx, p.transferLimitReservation()); err != nil {
+		return err
+	}
+
+	if err := releaseBeneficiaryCoolingUsage(tx, p.Cooling); err != nil {
+		return err
+	}
+
+	if err := releaseDeviceCoolingUsage(tx, p.DeviceCooling); err != nil {
+		return err
+	}
+
+	if err := setPaymentReservationStatus(tx, txnIdentifier, PaymentReservationStatusReleased); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit payment reservation release: %w", err)
+	}
+
+	return nil
+}
+
+// ConfirmPaymentReservation keeps the usage of a payment the bank accepted.
+// A payment released while its otp was with the bank is counted again, as
+// the money was sent.
+func ConfirmPaymentReservation(db *sql.DB, txnIdentifier string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin payment reservation confirm: %w", err)
+	}
+	defer tx.Rollback()
+
+	var (
+		p          PaymentReservation
+		day, month time.Time
+		cooling    coolingColumns
+	)
+	err = tx.QueryRow(
+		`SELECT user_id, payment_mode, amount, day, month, status, cooling_beneficiary_id, cooling_amount, cooling_device_id, device_cooling_amount
+		FROM payment_reservations
+		WHERE txn_identifier = $1
+		FOR UPDATE`,
+		txnIdentifier,
+	).Scan(&p.UserId, &p.PaymentMode, &p.Amount, &day, &month, &p.Status, &cooling.beneficiaryId, &cooling.amount, &cooling.deviceId, &cooling.deviceAmount)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get payment reservation: %w", err)
+	}
+
+	switch p.Status {
+	case PaymentReservationStatusConfirmed:
+		return nil
+	case PaymentReservationStatusReleased:
+		p.Day, p.Month = transferLimitPeriods(time.Now())
+		if err := addTransferLimitUsage(tx, p.transferLimitReservation()); err != nil {
+			return err
+		}
+		p.scanCooling(&cooling)
+		if err := addBeneficiaryCoolingUsage(tx, p.Cooling); err != nil {
+			return err
+		}
+		if err := addDeviceCoolingUsage(tx, p.DeviceCooling); err != nil {
+			return err
+		}
+	}
+
+	if err := setPaymentReservationStatus(tx, txnIdentifier, PaymentReservationStatusConfirmed); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit payment reservation confirm: %w", err)
+	}
+
+	return nil
+}
+
+// ExpirePaymentReservations releases the payments whose otp was not entered
+// in time and returns how many were released
+func ExpirePaymentReservations(db *sql.DB) (int, error) {
+	rows, err := db.Query(
+		`SELECT txn_identifier FROM payment_reservations WHERE status = $1 AND expires_at < now()`,
+		PaymentReservationStatusReserved,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get expired payment reservations: %w", err)
+	}
+
+	var txnIdentifiers []string
+	for rows.Next() {
+		var txnIdentifier string
+		if err := rows.Scan(&txnIdentifier); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan expired payment reservation: %w", err)
+		}
+		txnIdentifiers = append(txnIdentifiers, txnIdentifier)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to get expired payment reservations: %w", err)
+	}
+
+	released := 0
+	for _, txnIdentifier := range txnIdentifiers {
+		if err := ReleasePaymentReservation(db, txnIdentifier); err != nil {
+			return released, err
+		}
+		released++
+	}
+
+	return released, nil
+}
+
+func setPaymentReservationStatus(exec execer, txnIdentifier, status string) error {
+	_, err := exec.Exec(
+		`UPDATE payment_reservations SET status = $2, updated_at = now() WHERE txn_identifier = $1`,
+		txnIdentifier,
+		status,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update payment reservation: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,256 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"bankapi/constants"
+)
+
+// Reasons a session was revoked
+const (
+	SessionRevokedLogout  = "LOGOUT"
+	SessionRevokedByUser  = "REVOKED_BY_USER"
+	SessionRevokedRelogin = "RELOGIN"
+	SessionRevokedReuse   = "REFRESH_TOKEN_REUSE"
+	SessionRevokedDevice  = "DEVICE_REVOKED"
+	// SessionRevokedDeviceMismatch is a refresh token presented by another device
+	SessionRevokedDeviceMismatch = "REFRESH_DEVICE_MISMATCH"
+)
+
+type UserSession struct {
+	Id            string         `json:"id"`
+	UserId        string         `json:"-"`
+	DeviceId      sql.NullString `json:"-"`
+	UserAgent     string         `json:"user_agent"`
+	IpAddress     string         `json:"ip_address"`
+	CreatedAt     time.Time      `json:"created_at"`
+	LastUsedAt    time.Time      `json:"last_used_at"`
+	ExpiresAt     time.Time      `json:"expires_at"`
+	RevokedAt     sql.NullTime   `json:"-"`
+	RevokedReason sql.NullString `json:"-"`
+	Current       bool           `json:"current"`
+}
+
+type RefreshToken struct {
+	Id        string
+	SessionId string
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+func NewUserSession() *UserSession {
+	return &UserSession{}
+}
+
+func CreateUserSession(db *sql.DB, session *UserSession) error {
+	err := db.QueryRow(
+		`INSERT INTO user_sessions(
+			user_id,
+			device_id,
+			user_agent,
+			ip_address,
+			expires_at
+		) VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, last_used_at`,
+		session.UserId,
+		session.DeviceId,
+		session.UserAgent,
+		session.IpAddress,
+		session.ExpiresAt,
+	).Scan(&session.Id, &session.CreatedAt, &session.LastUsedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create user session: %w", err)
+	}
+
+	return nil
+}
+
+func GetUserSession(db *sql.DB, id string) (*UserSession, error) {
+	session := NewUserSession()
+	err := db.QueryRow(
+		`SELECT
+			id,
+			user_id,
+			device_id,
+			COALESCE(user_agent, ''),
+			COALESCE(ip_address, ''),
+			created_at,
+			last_used_at,
+			expires_at,
+			revoked_at,
+			revoked_reason
+		FROM user_sessions
+		WHERE id = $1`,
+		id,
+	).Scan(
+		&session.Id,
+		&session.UserId,
+		&session.DeviceId,
+		&session.UserAgent,
+		&session.IpAddress,
+		&session.CreatedAt,
+		&session.LastUsedAt,
+		&session.ExpiresAt,
+		&session.RevokedAt,
+		&session.RevokedReason,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, constants.ErrNoDataFound
+		}
+		return nil, fmt.Errorf("failed to get user session: %w", err)
+	}
+
+	return session, nil
+}
+
+// ListActiveUserSessions returns the sessions of a user that are neither revoked nor expired
+func ListActiveUserSessions(db *sql.DB, userId string) ([]UserSession, error) {
+	rows, err := db.Query(
+		`SELECT
+			id,
+			user_id,
+			device_id,
+			COALESCE(user_agent, ''),
+			COALESCE(ip_address, ''),
+			created_at,
+			last_used_at,
+			expires_at
+		FROM user_sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > now()
+		ORDER BY last_used_at DESC`,
+		userId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []UserSession{}
+	for rows.Next() {
+		var session UserSession
+		if err := rows.Scan(
+			&session.Id,
+			&session.UserId,
+			&session.DeviceId,
+			&session.UserAgent,
+			&session.IpAddress,
+			&session.CreatedAt,
+			&session.LastUsedAt,
+			&session.ExpiresAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan user session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// TouchUserSession records a refresh of the session and extends its expiry
+func TouchUserSession(db *sql.DB, id string, expiresAt time.Time) error {
+	_, err := db.Exec(
+		`UPDATE user_sessions SET last_used_at = now(), expires_at = $2 WHERE id = $1`,
+		id,
+		expiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update user session: %w", err)
+	}
+
+	return nil
+}
+
+func RevokeUserSession(db *sql.DB, id, reason string) error {
+	_, err := db.Exec(
+		`UPDATE user_sessions SET revoked_at = now(), revoked_reason = $2
+		WHERE id = $1 AND revoked_at IS NULL`,
+		id,
+		reason,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke user session: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeUserSessions revokes the active sessions of a user, limited to one
+// device when deviceId is set, and returns their ids
+func RevokeUserSessions(db *sql.DB, userId, deviceId, reason string) ([]string, error) {
+	rows, err := db.Query(
+		`UPDATE user_sessions SET revoked_at = now(), revoked_reason = $3
+		WHERE user_id = $1 AND ($2 = '' OR device_id = $2) AND revoked_at IS NULL
+		RETURNING id`,
+		userId,
+		deviceId,
+		reason,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke user sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan revoked session: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func CreateRefreshToken(db *sql.DB, sessionId, tokenHash string, expiresAt time.Time) error {
+	_, err := db.Exec(
+		`INSERT INTO refresh_tokens(session_id, token_hash, expires_at) VALUES ($1, $2, $3)`,
+		sessionId,
+		tokenHash,
+		expiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	return nil
+}
+
+// ConsumeRefreshToken marks a refresh token as used. A token that was used
+// before is returned with constants.ErrRefreshTokenReused, so the caller can
+// revoke its session.
+func ConsumeRefreshToken(db *sql.DB, tokenHash string) (*RefreshToken, error) {
+	token := &RefreshToken{TokenHash: tokenHash}
+
+	err := db.QueryRow(
+		`UPDATE refresh_tokens SET used_at = now()
+		WHERE token_hash = $1 AND used_at IS NULL
+		RETURNING id, session_id, expires_at, used_at`,
+		tokenHash,
+	).Scan(&token.Id, &token.SessionId, &token.ExpiresAt, &token.UsedAt)
+	if err == nil {
+		return token, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to consume refresh token: %w", err)
+	}
+
+	err = db.QueryRow(
+		`SELECT id, session_id, expires_at, used_at FROM refresh_tokens WHERE token_hash = $1`,
+		tokenHash,
+	).Scan(&token.Id, &token.SessionId, &token.ExpiresAt, &token.UsedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, constants.ErrNoDataFound
+		}
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	return token, constants.ErrRefreshTokenReused
+}
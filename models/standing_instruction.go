@@ -0,0 +1,450 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"bankapi/config"
+	"bankapi/constants"
+)
+
+// Frequencies of a standing instruction
+const (
+	StandingInstructionOnce    = "ONCE"
+	StandingInstructionWeekly  = "WEEKLY"
+	StandingInstructionMonthly = "MONTHLY"
+)
+
+// Statuses of a standing instruction
+const (
+	StandingInstructionStatusActive    = "ACTIVE"
+	StandingInstructionStatusPaused    = "PAUSED"
+	StandingInstructionStatusCompleted = "COMPLETED"
+	StandingInstructionStatusCancelled = "CANCELLED"
+)
+
+// Statuses of a run of a standing instruction
+const (
+	// ExecutionStatusPending is a run being submitted or waiting for a retry
+	ExecutionStatusPending = "PENDING"
+	// ExecutionStatusAwaitingOtp is a run the bank sent the user an otp for
+	ExecutionStatusAwaitingOtp = "AWAITING_OTP"
+	ExecutionStatusSuccess     = "SUCCESS"
+	ExecutionStatusFailed      = "FAILED"
+	ExecutionStatusSkipped     = "SKIPPED"
+)
+
+const standingInstructionDateLayout = "2006-01-02"
+
+type StandingInstruction struct {
+	Id                   string     `json:"id"`
+	UserId               string     `json:"-"`
+	BenfId               string     `json:"beneficiary_id"`
+	BenfName             string     `json:"beneficiary_name"`
+	BenfIfsc             string     `json:"beneficiary_ifsc"`
+	BenfAccount          string     `json:"account_number"`
+	BenfAccountEncrypted string     `json:"-"`
+	BenfAcctType         string     `json:"beneficiary_account_type"`
+	BenfMobNo            string     `json:"beneficiary_mobile_number"`
+	PaymentMode          string     `json:"payment_mode"`
+	Amount               string     `json:"amount"`
+	Remarks              string     `json:"remarks"`
+	Frequency            string     `json:"frequency"`
+	DayOfMonth           int        `json:"day_of_month,omitempty"`
+	StartDate            string     `json:"start_date"`
+	EndDate              NullString `json:"end_date"`
+	NextRunAt            *time.Time `json:"next_run_at"`
+	Status               string     `json:"status"`
+	DeviceId             string     `json:"-"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+}
+
+func NewStandingInstruction() *StandingInstruction {
+	return &StandingInstruction{}
+}
+
+// NextRunAfter returns the first run of the schedule after t, runs are at
+// STANDING_INSTRUCTION_RUN_HOUR on the days of the schedule. Monthly runs on
+// a day the month does not have are on its last day. It returns false when
+// no run is left before the end date.
+func (si *StandingInstruction) NextRunAfter(t time.Time) (time.Time, bool) {
+	standingInstructionConfig := config.GetStandingInstructionConfig()
+	location := standingInstructionConfig.Location
+
+	start, err := time.ParseInLocation(standingInstructionDateLayout, si.StartDate, location)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	runOn := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, standingInstructionConfig.RunHour, 0, 0, 0, location)
+	}
+	firstRun := runOn(start.Year(), start.Month(), start.Day())
+
+	var next time.Time
+	switch si.Frequency {
+	case StandingInstructionOnce:
+		next = firstRun
+	case StandingInstructionWeekly:
+		next = firstRun
+		if !next.After(t) {
+			weeks := int(t.Sub(next).Hours() / (24 * 7))
+			next = next.AddDate(0, 0, 7*weeks)
+			for !next.After(t) {
+				next = next.AddDate(0, 0, 7)
+			}
+		}
+	case StandingInstructionMonthly:
+		day := si.DayOfMonth
+		if day == 0 {
+			day = start.Day()
+		}
+
+		month := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, location)
+		if local := t.In(location); local.After(month) {
+			month = time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, location)
+		}
+		for {
+			lastDay := month.AddDate(0, 1, -1).Day()
+			next = runOn(month.Year(), month.Month(), min(day, lastDay))
+			if !next.Before(firstRun) && next.After(t) {
+				break
+			}
+			month = month.AddDate(0, 1, 0)
+		}
+	default:
+		return time.Time{}, false
+	}
+
+	if !next.After(t) {
+		return time.Time{}, false
+	}
+
+	if si.EndDate.Valid {
+		end, err := time.ParseInLocation(standingInstructionDateLayout, si.EndDate.String, location)
+		if err != nil || !next.Before(end.AddDate(0, 0, 1)) {
+			return time.Time{}, false
+		}
+	}
+
+	return next, true
+}
+
+const standingInstructionColumns = `
+	id,
+	user_id,
+	benf_id,
+	benf_name,
+	benf_ifsc,
+	benf_account,
+	benf_account_encrypted,
+	benf_acct_type,
+	benf_mobile_number,
+	payment_mode,
+	amount,
+	remarks,
+	frequency,
+	day_of_month,
+	to_char(start_date, 'YYYY-MM-DD'),
+	to_char(end_date, 'YYYY-MM-DD'),
+	next_run_at,
+	status,
+	device_id,
+	created_at,
+	updated_at`
+
+func scanStandingInstruction(row interface{ Scan(...interface{}) error }, si *StandingInstruction) error {
+	return row.Scan(
+		&si.Id,
+		&si.UserId,
+		&si.BenfId,
+		&si.BenfName,
+		&si.BenfIfsc,
+		&si.BenfAccount,
+		&si.BenfAccountEncrypted,
+		&si.BenfAcctType,
+		&si.BenfMobNo,
+		&si.PaymentMode,
+		&si.Amount,
+		&si.Remarks,
+		&si.Frequency,
+		&si.DayOfMonth,
+		&si.StartDate,
+		&si.EndDate,
+		&si.NextRunAt,
+		&si.Status,
+		&si.DeviceId,
+		&si.CreatedAt,
+		&si.UpdatedAt,
+	)
+}
+
+// InsertStandingInstruction saves a new standing instruction and sets its id
+func InsertStandingInstruction(db *sql.DB, si *StandingInstruction) error {
+	err := db.QueryRow(
+		`INSERT INTO standing_instructions (
+			user_id, benf_id, benf_name, benf_ifsc, benf_account, benf_account_encrypted,
+			benf_acct_type, benf_mobile_number, payment_mode, amount, remarks, frequency,
+			day_of_month, start_date, end_date, next_run_at, status, device_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14::date, $15::date, $16, $17, $18)
+		RETURNING id, created_at, updated_at`,
+		si.UserId,
+		si.BenfId,
+		si.BenfName,
+		si.BenfIfsc,
+		si.BenfAccount,
+		si.BenfAccountEncrypted,
+		si.BenfAcctType,
+		si.BenfMobNo,
+		si.PaymentMode,
+		si.Amount,
+		si.Remarks,
+		si.Frequency,
+		si.DayOfMonth,
+		si.StartDate,
+		si.EndDate,
+		si.NextRunAt,
+		si.Status,
+		si.DeviceId,
+	).Scan(&si.Id, &si.CreatedAt, &si.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert standing instruction: %w", err)
+	}
+
+	return nil
+}
+
+func GetStandingInstruction(db *sql.DB, id string) (*StandingInstruction, error) {
+	si := NewStandingInstruction()
+	err := scanStandingInstruction(db.QueryRow(
+		`SELECT`+standingInstructionColumns+`
+		FROM standing_instructions
+		WHERE id = $1`,
+		id,
+	), si)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, constants.ErrNoDataFound
+		}
+		return nil, fmt.Errorf("failed to get standing instruction: %w", err)
+	}
+
+	return si, nil
+}
+
+// ListStandingInstructions returns the standing instructions of a user, newest first
+func ListStandingInstructions(db *sql.DB, userId string) ([]StandingInstruction, error) {
+	return queryStandingInstructions(db,
+		`SELECT`+standingInstructionColumns+`
+		FROM standing_instructions
+		WHERE user_id = $1
+		ORDER BY created_at DESC`,
+		userId,
+	)
+}
+
+// ListScheduledStandingInstructions returns the active standing instructions
+// with a run left, to schedule their tasks again
+func ListScheduledStandingInstructions(db *sql.DB) ([]StandingInstruction, error) {
+	return queryStandingInstructions(db,
+		`SELECT`+standingInstructionColumns+`
+		FROM standing_instructions
+		WHERE status = $1 AND next_run_at IS NOT NULL`,
+		StandingInstructionStatusActive,
+	)
+}
+
+func queryStandingInstructions(db *sql.DB, query string, args ...interface{}) ([]StandingInstruction, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list standing instructions: %w", err)
+	}
+	defer rows.Close()
+
+	standingInstructions := make([]StandingInstruction, 0)
+	for rows.Next() {
+		si := NewStandingInstruction()
+		if err := scanStandingInstruction(rows, si); err != nil {
+			return nil, fmt.Errorf("failed to scan standing instruction: %w", err)
+		}
+		standingInstructions = append(standingInstructions, *si)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list standing instructions: %w", err)
+	}
+
+	return standingInstructions, nil
+}
+
+// UpdateStandingInstructionSchedule sets the status and next run of a
+// standing instruction
+func UpdateStandingInstructionSchedule(db *sql.DB, id, status string, nextRunAt *time.Time) error {
+	_, err := db.Exec(
+		`UPDATE standing_instructions SET status = $2, next_run_at = $3, updated_at = now()
+		WHERE id = $1`,
+		id,
+		status,
+		nextRunAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update standing instruction: %w", err)
+	}
+
+	return nil
+}
+
+type StandingInstructionExecution struct {
+	Id                    string     `json:"id"`
+	StandingInstructionId string     `json:"standing_instruction_id"`
+	UserId                string     `json:"-"`
+	RunAt                 time.Time  `json:"run_at"`
+	Status                string     `json:"status"`
+	TxnIdentifier         NullString `json:"txn_identifier"`
+	Attempts              int        `json:"attempts"`
+	ErrorMessage          NullString `json:"error_message"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+}
+
+func NewStandingInstructionExecution() *StandingInstructionExecution {
+	return &StandingInstructionExecution{}
+}
+
+const standingInstructionExecutionColumns = `
+	id,
+	standing_instruction_id,
+	user_id,
+	run_at,
+	status,
+	txn_identifier,
+	attempts,
+	error_message,
+	created_at,
+	updated_at`
+
+func scanStandingInstructionExecution(row interface{ Scan(...interface{}) error }, execution *StandingInstructionExecution) error {
+	return row.Scan(
+		&execution.Id,
+		&execution.StandingInstructionId,
+		&execution.UserId,
+		&execution.RunAt,
+		&execution.Status,
+		&execution.TxnIdentifier,
+		&execution.Attempts,
+		&execution.ErrorMessage,
+		&execution.CreatedAt,
+		&execution.UpdatedAt,
+	)
+}
+
+// StartStandingInstructionExecution returns the run of a standing instruction
+// at runAt with one more attempt, the run is created PENDING on its first attempt
+func StartStandingInstructionExecution(db *sql.DB, si *StandingInstruction, runAt time.Time) (*StandingInstructionExecution, error) {
+	execution := NewStandingInstructionExecution()
+	err := scanStandingInstructionExecution(db.QueryRow(
+		`INSERT INTO standing_instruction_executions (standing_instruction_id, user_id, run_at, status, attempts)
+		VALUES ($1, $2, $3, $4, 1)
+		ON CONFLICT (standing_instruction_id, run_at)
+		DO UPDATE SET attempts = standing_instruction_executions.attempts + 1, updated_at = now()
+		RETURNING`+standingInstructionExecutionColumns,
+		si.Id,
+		si.UserId,
+		runAt,
+		ExecutionStatusPending,
+	), execution)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start standing instruction execution: %w", err)
+	}
+
+	return execution, nil
+}
+
+// SkipStandingInstructionExecution records the run at runAt as skipped, a run
+// already made is kept
+func SkipStandingInstructionExecution(db *sql.DB, si *StandingInstruction, runAt time.Time) error {
+	_, err := db.Exec(
+		`INSERT INTO standing_instruction_executions (standing_instruction_id, user_id, run_at, status)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (standing_instruction_id, run_at) DO NOTHING`,
+		si.Id,
+		si.UserId,
+		runAt,
+		ExecutionStatusSkipped,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to skip standing instruction execution: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateStandingInstructionExecution sets the status of a run, with the
+// transaction it made and the error of a failed attempt
+func UpdateStandingInstructionExecution(db *sql.DB, id, status, txnIdentifier, errorMessage string) error {
+	_, err := db.Exec(
+		`UPDATE standing_instruction_executions
+		SET status = $2, txn_identifier = COALESCE(NULLIF($3, ''), txn_identifier), error_message = NULLIF($4, ''), updated_at = now()
+		WHERE id = $1`,
+		id,
+		status,
+		txnIdentifier,
+		errorMessage,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update standing instruction execution: %w", err)
+	}
+
+	return nil
+}
+
+func GetStandingInstructionExecution(db *sql.DB, id string) (*StandingInstructionExecution, error) {
+	execution := NewStandingInstructionExecution()
+	err := scanStandingInstructionExecution(db.QueryRow(
+		`SELECT`+standingInstructionExecutionColumns+`
+		FROM standing_instruction_executions
+		WHERE id = $1`,
+		id,
+	), execution)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, constants.ErrNoDataFound
+		}
+		return nil, fmt.Errorf("failed to get standing instruction execution: %w", err)
+	}
+
+	return execution, nil
+}
+
+// ListStandingInstructionExecutions returns the runs of a standing instruction, newest first
+func ListStandingInstructionExecutions(db *sql.DB, standingInstructionId string) ([]StandingInstructionExecution, error) {
+	rows, err := db.Query(
+		`SELECT`+standingInstructionExecutionColumns+`
+		FROM standing_instruction_executions
+		WHERE standing_instruction_id = $1
+		ORDER BY run_at DESC`,
+		standingInstructionId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list standing instruction executions: %w", err)
+	}
+	defer rows.Close()
+
+	executions := make([]StandingInstructionExecution, 0)
+	for rows.Next() {
+		execution := NewStandingInstructionExecution()
+		if err := scanStandingInstructionExecution(rows, execution); err != nil {
+			return nil, fmt.Errorf("failed to scan standing instruction execution: %w", err)
+		}
+		executions = append(executions, *execution)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list standing instruction executions: %w", err)
+	}
+
+	return executions, nil
+}
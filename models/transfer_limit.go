@@ -0,0 +1,506 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"bankapi/config"
+	"bankapi/constants"
+)
+
+// Periods of transfer limit usage
+const (
+	TransferLimitPeriodDay   = "DAY"
+	TransferLimitPeriodMonth = "MONTH"
+)
+
+// MaxAmountRupees is the largest amount ParseAmountPaise accepts. It is far
+// above any transfer limit, and in paise it cannot overflow an int64.
+const MaxAmountRupees = 1_000_000_000_000
+
+// UserTransferLimit is a limit a user set below the limit of their tier, in
+// rupees. A 0 keeps the limit of the tier.
+type UserTransferLimit struct {
+	UserId         string    `json:"-"`
+	PaymentMode    string    `json:"payment_mode"`
+	PerTransaction int64     `json:"per_transaction"`
+	Daily          int64     `json:"daily"`
+	Monthly        int64     `json:"monthly"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// TransferLimitReservation is the usage counted for a payment before it is
+// sent to the bank, ReleaseTransferLimit takes it back when the bank rejects
+// it. Once the bank sends the otp it is kept as a PaymentReservation until
+// the otp is confirmed.
+type TransferLimitReservation struct {
+	UserId      string
+	PaymentMode string
+	Day         string
+	Month       string
+	// Amount is in paise
+	Amount int64
+}
+
+// TransferLimitHeadroom is what a user can still send over a payment mode,
+// amounts are in rupees
+type TransferLimitHeadroom struct {
+	PaymentMode         string `json:"payment_mode"`
+	Tier                string `json:"tier"`
+	Custom              bool   `json:"custom"`
+	PerTransactionLimit string `json:"per_transaction_limit"`
+	DailyLimit          string `json:"daily_limit"`
+	MonthlyLimit        string `json:"monthly_limit"`
+	DailyUsed           string `json:"daily_used"`
+	MonthlyUsed         string `json:"monthly_used"`
+	DailyRemaining      string `json:"daily_remaining"`
+	MonthlyRemaining    string `json:"monthly_remaining"`
+}
+
+// GetTransferLimitTier returns the tier of a user. Users without an approved
+// video kyc audit are MIN_KYC, full kyc users are NEW_ACCOUNT until their
+// account is TRANSFER_LIMIT_NEW_ACCOUNT_DAYS old.
+func GetTransferLimitTier(db *sql.DB, userId string) (string, error) {
+	account, err := GetAccountDataByUserId(db, userId)
+	if err != nil {
+		return "", fmt.Errorf("failed to get account data: %w", err)
+	}
+
+	var auditStatus string
+	err = db.QueryRow(
+		`SELECT vkyc_audit_status FROM kyc_audit_data WHERE user_id = $1`,
+		userId,
+	).Scan(&auditStatus)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("failed to get kyc audit status: %w", err)
+	}
+
+	transferLimitConfig := config.GetTransferLimitConfig()
+
+	fullKyc := false
+	for _, status := range transferLimitConfig.FullKycStatuses {
+		if strings.ToUpper(strings.TrimSpace(auditStatus)) == status {
+			fullKyc = true
+			break
+		}
+	}
+
+	switch {
+	case !fullKyc:
+		return config.TransferTierMinKyc, nil
+	case time.Since(account.CreatedAt) < transferLimitConfig.NewAccountPeriod:
+		return config.TransferTierNewAccount, nil
+	default:
+		return config.TransferTierFullKyc, nil
+	}
+}
+
+// GetUserTransferLimits returns the limits a user set, by payment mode
+func GetUserTransferLimits(db *sql.DB, userId string) (map[string]UserTransferLimit, error) {
+	rows, err := db.Query(
+		`SELECT user_id, payment_mode, per_transaction, daily, monthly, updated_at
+		FROM user_transfer_limits
+		WHERE user_id = $1`,
+		userId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user transfer limits: %w", err)
+	}
+	defer rows.Close()
+
+	limits := make(map[string]UserTransferLimit)
+	for rows.Next() {
+		var limit UserTransferLimit
+		if err := rows.Scan(&limit.UserId, &limit.PaymentMode, &limit.PerTransaction, &limit.Daily, &limit.Monthly, &limit.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user transfer limit: %w", err)
+		}
+		limits[limit.PaymentMode] = limit
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get user transfer limits: %w", err)
+	}
+
+	return limits, nil
+}
+
+// SaveUserTransferLimit sets the limits of a user for a payment mode, a limit
+// with only zeros goes back to the limits of the tier
+func SaveUserTransferLimit(db *sql.DB, limit *UserTransferLimit) error {
+	if limit.PerTransaction == 0 && limit.Daily == 0 && limit.Monthly == 0 {
+		_, err := db.Exec(
+			`DELETE FROM user_transfer_limits WHERE user_id = $1 AND payment_mode = $2`,
+			limit.UserId,
+			limit.PaymentMode,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to delete user transfer limit: %w", err)
+		}
+		return nil
+	}
+
+	_, err := db.Exec(
+		`INSERT INTO user_transfer_limits (user_id, payment_mode, per_transaction, daily, monthly)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, payment_mode)
+		DO UPDATE SET per_transaction = $3, daily = $4, monthly = $5, updated_at = now()`,
+		limit.UserId,
+		limit.PaymentMode,
+		limit.PerTransaction,
+		limit.Daily,
+		limit.Monthly,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save user transfer limit: %w", err)
+	}
+
+	return nil
+}
+
+// EffectiveTransferLimit is the limit of a tier lowered by the limits the user set
+func EffectiveTransferLimit(tierLimit *config.TransferLimit, userLimit *UserTransferLimit) *config.TransferLimit {
+	limit := *tierLimit
+	if userLimit == nil {
+		return &limit
+	}
+
+	if userLimit.PerTransaction > 0 && userLimit.PerTransaction < limit.PerTransaction {
+		limit.PerTransaction = userLimit.PerTransaction
+	}
+	if userLimit.Daily > 0 && userLimit.Daily < limit.Daily {
+		limit.Daily = userLimit.Daily
+	}
+	if userLimit.Monthly > 0 && userLimit.Monthly < limit.Monthly {
+		limit.Monthly = userLimit.Monthly
+	}
+
+	return &limit
+}
+
+// GetEffectiveTransferLimit returns the tier of a user and their limit for a payment mode
+func GetEffectiveTransferLimit(db *sql.DB, userId, mode string) (*config.TransferLimit, bool, error) {
+	tier, err := GetTransferLimitTier(db, userId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	tierLimit, err := config.GetTransferLimit(tier, mode)
+	if err != nil {
+		return nil, false, err
+	}
+
+	userLimits, err := GetUserTransferLimits(db, userId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	userLimit, custom := userLimits[mode]
+	if !custom {
+		return tierLimit, false, nil
+	}
+
+	return EffectiveTransferLimit(tierLimit, &userLimit), true, nil
+}
+
+// ReserveTransferLimit counts a payment against the limits of the user before
+// it is sent to the bank. The daily and monthly usage are added only when
+// they stay within the limits, in one transaction, so concurrent payments
+// cannot go over them together.
+func ReserveTransferLimit(db *sql.DB, userId, mode, amount string) (*TransferLimitReservation, error) {
+	mode = strings.ToUpper(mode)
+
+	paise, err := ParseAmountPaise(amount)
+	if err != nil {
+		return nil, err
+	}
+
+	limit, _, err := GetEffectiveTransferLimit(db, userId, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	if paise > limit.PerTransaction*100 {
+		return nil, fmt.Errorf(constants.TransferLimitPerTransactionErrorMessage, mode, FormatPaise(limit.PerTransaction*100))
+	}
+
+	day, month := transferLimitPeriods(time.Now())
+	reservation := &TransferLimitReservation{
+		UserId:      userId,
+		PaymentMode: mode,
+		Day:         day,
+		Month:       month,
+		Amount:      paise,
+	}
+
+	dailyOk, monthlyOk, err := reserveTransferLimitUsage(db, reservation, limit)
+	if err != nil {
+		return nil, err
+	}
+	if dailyOk && monthlyOk {
+		return reservation, nil
+	}
+
+	usage, err := GetTransferLimitUsage(db, userId, day, month)
+	if err != nil {
+		return nil, err
+	}
+
+	if !dailyOk {
+		return nil, fmt.Errorf(constants.TransferLimitDailyErrorMessage, mode, FormatPaise(remainingPaise(limit.Daily, usage[mode].Daily)))
+	}
+	return nil, fmt.Errorf(constants.TransferLimitMonthlyErrorMessage, mode, FormatPaise(remainingPaise(limit.Monthly, usage[mode].Monthly)))
+}
+
+func reserveTransferLimitUsage(db *sql.DB, reservation *TransferLimitReservation, limit *config.TransferLimit) (dailyOk bool, monthlyOk bool, err error) {
+	if reservation.Amount > limit.Daily*100 {
+		return false, true, nil
+	}
+	if reservation.Amount > limit.Monthly*100 {
+		return true, false, nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return false, false, fmt.Errorf("failed to begin transfer limit usage: %w", err)
+	}
+	defer tx.Rollback()
+
+	add := func(period, periodStart string, max int64) (bool, error) {
+		var total int64
+		err := tx.QueryRow(
+			`INSERT INTO transfer_limit_usage (user_id, payment_mode, period, period_start, amount)
+			VALUES ($1, $2, $3, $4::date, $5)
+			ON CONFLICT (user_id, payment_mode, period, period_start)
+			DO UPDATE SET amount = transfer_limit_usage.amount + EXCLUDED.amount, updated_at = now()
+			WHERE transfer_limit_usage.amount + EXCLUDED.amount <= $6
+			RETURNING amount`,
+			reservation.UserId,
+			reservation.PaymentMode,
+			period,
+			periodStart,
+			reservation.Amount,
+			max,
+		).Scan(&total)
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to add transfer limit usage: %w", err)
+		}
+		return true, nil
+	}
+
+	if dailyOk, err = add(TransferLimitPeriodDay, reservation.Day, limit.Daily*100); err != nil || !dailyOk {
+		return false, true, err
+	}
+	if monthlyOk, err = add(TransferLimitPeriodMonth, reservation.Month, limit.Monthly*100); err != nil || !monthlyOk {
+		return true, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, false, fmt.Errorf("failed to commit transfer limit usage: %w", err)
+	}
+
+	return true, true, nil
+}
+
+// ReleaseTransferLimit takes back the usage of a payment the bank rejected
+func ReleaseTransferLimit(db *sql.DB, reservation *TransferLimitReservation) error {
+	return releaseTransferLimitUsage(db, reservation)
+}
+
+func releaseTransferLimitUsage(exec execer, reservation *TransferLimitReservation) error {
+	_, err := exec.Exec(
+		`UPDATE transfer_limit_usage
+		SET amount = GREATEST(amount - $6, 0), updated_at = now()
+		WHERE user_id = $1 AND payment_mode = $2
+			AND ((period = $3 AND period_start = $4::date) OR (period = $5 AND period_start = $7::date))`,
+		reservation.UserId,
+		reservation.PaymentMode,
+		TransferLimitPeriodDay,
+		reservation.Day,
+		TransferLimitPeriodMonth,
+		reservation.Amount,
+		reservation.Month,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to release transfer limit usage: %w", err)
+	}
+
+	return nil
+}
+
+// addTransferLimitUsage counts a payment the bank already accepted, whatever
+// the limits are
+func addTransferLimitUsage(exec execer, reservation *TransferLimitReservation) error {
+	for _, period := range []struct{ period, start string }{
+		{TransferLimitPeriodDay, reservation.Day},
+		{TransferLimitPeriodMonth, reservation.Month},
+	} {
+		_, err := exec.Exec(
+			`INSERT INTO transfer_limit_usage (user_id, payment_mode, period, period_start, amount)
+			VALUES ($1, $2, $3, $4::date, $5)
+			ON CONFLICT (user_id, payment_mode, period, period_start)
+			DO UPDATE SET amount = transfer_limit_usage.amount + EXCLUDED.amount, updated_at = now()`,
+			reservation.UserId,
+			reservation.PaymentMode,
+			period.period,
+			period.start,
+			reservation.Amount,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to add transfer limit usage: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// TransferLimitUsage is the amount in paise sent over a payment mode today and this month
+type TransferLimitUsage struct {
+	Daily   int64
+	Monthly int64
+}
+
+// GetTransferLimitUsage returns the usage of a user on a day and month, by payment mode
+func GetTransferLimitUsage(db *sql.DB, userId, day, month string) (map[string]TransferLimitUsage, error) {
+	rows, err := db.Query(
+		`SELECT payment_mode, period, amount
+		FROM transfer_limit_usage
+		WHERE user_id = $1
+			AND ((period = $2 AND period_start = $3::date) OR (period = $4 AND period_start = $5::date))`,
+		userId,
+		TransferLimitPeriodDay,
+		day,
+		TransferLimitPeriodMonth,
+		month,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfer limit usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := make(map[string]TransferLimitUsage)
+	for rows.Next() {
+		var (
+			mode, period string
+			amount       int64
+		)
+		if err := rows.Scan(&mode, &period, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan transfer limit usage: %w", err)
+		}
+
+		modeUsage := usage[mode]
+		if period == TransferLimitPeriodDay {
+			modeUsage.Daily = amount
+		} else {
+			modeUsage.Monthly = amount
+		}
+		usage[mode] = modeUsage
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get transfer limit usage: %w", err)
+	}
+
+	return usage, nil
+}
+
+// GetTransferLimitHeadroom returns the limits of a user for every payment
+// mode with what they sent and can still send today and this month
+func GetTransferLimitHeadroom(db *sql.DB, userId string) ([]TransferLimitHeadroom, error) {
+	tier, err := GetTransferLimitTier(db, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	userLimits, err := GetUserTransferLimits(db, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	day, month := transferLimitPeriods(time.Now())
+	usage, err := GetTransferLimitUsage(db, userId, day, month)
+	if err != nil {
+		return nil, err
+	}
+
+	headroom := make([]TransferLimitHeadroom, 0, len(config.TransferModes))
+	for _, mode := range config.TransferModes {
+		tierLimit, err := config.GetTransferLimit(tier, mode)
+		if err != nil {
+			return nil, err
+		}
+
+		limit := tierLimit
+		userLimit, custom := userLimits[mode]
+		if custom {
+			limit = EffectiveTransferLimit(tierLimit, &userLimit)
+		}
+
+		headroom = append(headroom, TransferLimitHeadroom{
+			PaymentMode:         mode,
+			Tier:                tier,
+			Custom:              custom,
+			PerTransactionLimit: FormatPaise(limit.PerTransaction * 100),
+			DailyLimit:          FormatPaise(limit.Daily * 100),
+			MonthlyLimit:        FormatPaise(limit.Monthly * 100),
+			DailyUsed:           FormatPaise(usage[mode].Daily),
+			MonthlyUsed:         FormatPaise(usage[mode].Monthly),
+			DailyRemaining:      FormatPaise(remainingPaise(limit.Daily, usage[mode].Daily)),
+			MonthlyRemaining:    FormatPaise(remainingPaise(limit.Monthly, usage[mode].Monthly)),
+		})
+	}
+
+	return headroom, nil
+}
+
+// transferLimitPeriods returns the day and the first day of the month of t in IST
+func transferLimitPeriods(t time.Time) (string, string) {
+	t = t.In(config.ISTLocation())
+	return t.Format("2006-01-02"), t.Format("2006-01") + "-01"
+}
+
+func remainingPaise(limitRupees, usedPaise int64) int64 {
+	if remaining := limitRupees*100 - usedPaise; remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// ParseAmountPaise parses an amount in rupees with at most two decimals into paise
+func ParseAmountPaise(amount string) (int64, error) {
+	rupees, paise, hasPaise := strings.Cut(strings.TrimSpace(amount), ".")
+	if hasPaise && (len(paise) == 0 || len(paise) > 2) {
+		return 0, fmt.Errorf("invalid amount %q", amount)
+	}
+	if len(paise) == 1 {
+		paise += "0"
+	}
+
+	r, err := strconv.ParseInt(rupees, 10, 64)
+	if err != nil || r < 0 {
+		return 0, fmt.Errorf("invalid amount %q", amount)
+	}
+	if r > MaxAmountRupees {
+		return 0, fmt.Errorf("amount %q is above %d", amount, MaxAmountRupees)
+	}
+
+	var p int64
+	if paise != "" {
+		if p, err = strconv.ParseInt(paise, 10, 64); err != nil || p < 0 {
+			return 0, fmt.Errorf("invalid amount %q", amount)
+		}
+	}
+
+	return r*100 + p, nil
+}
+
+// FormatPaise formats an amount in paise as rupees with two decimals
+func FormatPaise(paise int64) string {
+	return fmt.Sprintf("%d.%02d", paise/100, paise%100)
+}
@@ -0,0 +1,397 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"bankapi/constants"
+)
+
+// Statuses of a trusted device
+const (
+	DeviceStatusPending = "PENDING"
+	DeviceStatusTrusted = "TRUSTED"
+	DeviceStatusRevoked = "REVOKED"
+)
+
+type TrustedDevice struct {
+	Id            string         `json:"id"`
+	UserId        string         `json:"-"`
+	DeviceId      string         `json:"-"`
+	Status        string         `json:"status"`
+	OS            NullString     `json:"os"`
+	OSVersion     NullString     `json:"os_version"`
+	PackageId     sql.NullString `json:"-"`
+	DeviceToken   sql.NullString `json:"-"`
+	SimVendorId   sql.NullString `json:"-"`
+	SimVerifiedAt sql.NullTime   `json:"-"`
+	TrustedAt     *time.Time     `json:"trusted_at"`
+	CoolingUntil  *time.Time     `json:"cooling_until"`
+	RevokedAt     sql.NullTime   `json:"-"`
+	LastSeenAt    *time.Time     `json:"last_seen_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+	Current       bool           `json:"current"`
+}
+
+func NewTrustedDevice() *TrustedDevice {
+	return &TrustedDevice{}
+}
+
+// InCoolingPeriod reports whether the payments of the device are still limited
+func (d *TrustedDevice) InCoolingPeriod() bool {
+	return d.CoolingUntil != nil && time.Now().Before(*d.CoolingUntil)
+}
+
+const trustedDeviceColumns = `
+	id,
+	user_id,
+	device_id,
+	status,
+	os,
+	os_version,
+	package_id,
+	device_token,
+	sim_vendor_id,
+	sim_verified_at,
+	trusted_at,
+	cooling_until,
+	revoked_at,
+	last_seen_at,
+	created_at`
+
+func scanTrustedDevice(row interface{ Scan(...interface{}) error }, device *TrustedDevice) error {
+	return row.Scan(
+		&device.Id,
+		&device.UserId,
+		&device.DeviceId,
+		&device.Status,
+		&device.OS,
+		&device.OSVersion,
+		&device.PackageId,
+		&device.DeviceToken,
+		&device.SimVendorId,
+		&device.SimVerifiedAt,
+		&device.TrustedAt,
+		&device.CoolingUntil,
+		&device.RevokedAt,
+		&device.LastSeenAt,
+		&device.CreatedAt,
+	)
+}
+
+// FindTrustedDevice returns a registered device of a user, whatever its status
+func FindTrustedDevice(db *sql.DB, userId, deviceId string) (*TrustedDevice, error) {
+	device := NewTrustedDevice()
+	err := scanTrustedDevice(db.QueryRow(
+		`SELECT`+trustedDeviceColumns+`
+		FROM trusted_devices
+		WHERE user_id = $1 AND device_id = $2`,
+		userId,
+		deviceId,
+	), device)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, constants.ErrNoDataFound
+		}
+		return nil, fmt.Errorf("failed to get trusted device: %w", err)
+	}
+
+	return device, nil
+}
+
+func GetTrustedDevice(db *sql.DB, id string) (*TrustedDevice, error) {
+	device := NewTrustedDevice()
+	err := scanTrustedDevice(db.QueryRow(
+		`SELECT`+trustedDeviceColumns+`
+		FROM trusted_devices
+		WHERE id = $1`,
+		id,
+	), device)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, constants.ErrNoDataFound
+		}
+		return nil, fmt.Errorf("failed to get trusted device: %w", err)
+	}
+
+	return device, nil
+}
+
+// ListTrustedDevices returns the trusted devices of a user, most recently seen first
+func ListTrustedDevices(db *sql.DB, userId string) ([]TrustedDevice, error) {
+	rows, err := db.Query(
+		`SELECT`+trustedDeviceColumns+`
+		FROM trusted_devices
+		WHERE user_id = $1 AND status = $2
+		ORDER BY last_seen_at DESC NULLS LAST, created_at DESC`,
+		userId,
+		DeviceStatusTrusted,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trusted devices: %w", err)
+	}
+	defer rows.Close()
+
+	devices := []TrustedDevice{}
+	for rows.Next() {
+		var device TrustedDevice
+		if err := scanTrustedDevice(rows, &device); err != nil {
+			return nil, fmt.Errorf("failed to scan trusted device: %w", err)
+		}
+		devices = append(devices, device)
+	}
+
+	return devices, rows.Err()
+}
+
+// HasTrustedDevices reports whether a user has trusted any device
+func HasTrustedDevices(db *sql.DB, userId string) (bool, error) {
+	var exists bool
+	err := db.QueryRow(
+		`SELECT EXISTS(SELECT 1 FROM trusted_devices WHERE user_id = $1 AND status = $2)`,
+		userId,
+		DeviceStatusTrusted,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check trusted devices: %w", err)
+	}
+
+	return exists, nil
+}
+
+// InsertEnrolledDevice saves the device a user onboards with and trusts it,
+// without a cooling period
+func InsertEnrolledDevice(db *sql.DB, deviceData *DeviceData, device *TrustedDevice) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin enrolled device: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertDevice(tx, deviceData); err != nil {
+		return fmt.Errorf("failed to insert device data: %w", err)
+	}
+
+	if err := enrollTrustedDevice(tx, device); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit enrolled device: %w", err)
+	}
+
+	return nil
+}
+
+// enrollTrustedDevice trusts a device without a cooling period. It does
+// nothing when the device is already registered.
+func enrollTrustedDevice(exec execer, device *TrustedDevice) error {
+	_, err := exec.Exec(
+		`INSERT INTO trusted_devices(
+			user_id,
+			device_id,
+			status,
+			os,
+			os_version,
+			package_id,
+			device_token,
+			trusted_at,
+			last_seen_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, now(), now())
+		ON CONFLICT (user_id, device_id) DO NOTHING`,
+		device.UserId,
+		device.DeviceId,
+		DeviceStatusTrusted,
+		device.OS,
+		device.OSVersion,
+		device.PackageId,
+		device.DeviceToken,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to enroll trusted device: %w", err)
+	}
+
+	return nil
+}
+
+// CreatePendingDevice registers a new device of a user awaiting sim binding
+// and confirmation. A revoked device starts over, other pending devices of the
+// user are dropped so only one device change is in progress.
+func CreatePendingDevice(db *sql.DB, device *TrustedDevice) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin pending device: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		`DELETE FROM trusted_devices WHERE user_id = $1 AND device_id <> $2 AND status = $3`,
+		device.UserId,
+		device.DeviceId,
+		DeviceStatusPending,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to drop pending devices: %w", err)
+	}
+
+	err = tx.QueryRow(
+		`INSERT INTO trusted_devices(
+			user_id,
+			device_id,
+			status,
+			os,
+			os_version,
+			package_id,
+			device_token,
+			sim_vendor_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (user_id, device_id) DO UPDATE SET
+			status = EXCLUDED.status,
+			os = EXCLUDED.os,
+			os_version = EXCLUDED.os_version,
+			package_id = EXCLUDED.package_id,
+			device_token = EXCLUDED.device_token,
+			sim_vendor_id = EXCLUDED.sim_vendor_id,
+			sim_verified_at = NULL,
+			trusted_at = NULL,
+			cooling_until = NULL,
+			revoked_at = NULL
+		WHERE trusted_devices.status <> $9
+		RETURNING id, created_at`,
+		device.UserId,
+		device.DeviceId,
+		DeviceStatusPending,
+		device.OS,
+		device.OSVersion,
+		device.PackageId,
+		device.DeviceToken,
+		device.SimVendorId,
+		DeviceStatusTrusted,
+	).Scan(&device.Id, &device.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("device is already trusted")
+		}
+		return fmt.Errorf("failed to create pending device: %w", err)
+	}
+	device.Status = DeviceStatusPending
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit pending device: %w", err)
+	}
+
+	return nil
+}
+
+// MarkPendingDeviceSimVerified records the sim binding of the device change of a user
+func MarkPendingDeviceSimVerified(db *sql.DB, userId string) error {
+	_, err := db.Exec(
+		`UPDATE trusted_devices SET sim_verified_at = now() WHERE user_id = $1 AND status = $2`,
+		userId,
+		DeviceStatusPending,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update pending device: %w", err)
+	}
+
+	return nil
+}
+
+// TrustDevice completes a device change, the device is trusted with limited
+// payments until coolingUntil and the other devices of the user are revoked.
+// The device data of the user is pointed at the device in the same
+// transaction, inserted when the user has none. It returns the device ids of
+// the revoked devices.
+func TrustDevice(db *sql.DB, device *TrustedDevice, coolingUntil time.Time, deviceData *DeviceData, deviceDataExists bool) ([]string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin trusting device: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(
+		`UPDATE trusted_devices
+		SET status = $2, trusted_at = now(), cooling_until = $3, cooling_used = 0, last_seen_at = now()
+		WHERE id = $1 AND status = $4
+		RETURNING trusted_at, cooling_until`,
+		device.Id,
+		DeviceStatusTrusted,
+		coolingUntil,
+		DeviceStatusPending,
+	).Scan(&device.TrustedAt, &device.CoolingUntil)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, constants.ErrNoDataFound
+		}
+		return nil, fmt.Errorf("failed to trust device: %w", err)
+	}
+	device.Status = DeviceStatusTrusted
+
+	rows, err := tx.Query(
+		`UPDATE trusted_devices SET status = $3, revoked_at = now()
+		WHERE user_id = $1 AND id <> $2 AND status = $4
+		RETURNING device_id`,
+		device.UserId,
+		device.Id,
+		DeviceStatusRevoked,
+		DeviceStatusTrusted,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke previous devices: %w", err)
+	}
+	defer rows.Close()
+
+	var revoked []string
+	for rows.Next() {
+		var deviceId string
+		if err := rows.Scan(&deviceId); err != nil {
+			return nil, fmt.Errorf("failed to scan revoked device: %w", err)
+		}
+		revoked = append(revoked, deviceId)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if !deviceDataExists {
+		if err := insertDevice(tx, deviceData); err != nil {
+			return nil, fmt.Errorf("failed to insert device data: %w", err)
+		}
+	}
+
+	// the insert leaves the sim unverified and the device inactive
+	if err := updateDevice(tx, deviceData, device.UserId); err != nil {
+		return nil, fmt.Errorf("failed to update device data: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit trusted device: %w", err)
+	}
+
+	return revoked, nil
+}
+
+func RevokeTrustedDevice(db *sql.DB, id string) error {
+	_, err := db.Exec(
+		`UPDATE trusted_devices SET status = $2, revoked_at = now() WHERE id = $1 AND status <> $2`,
+		id,
+		DeviceStatusRevoked,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke trusted device: %w", err)
+	}
+
+	return nil
+}
+
+// TouchTrustedDevice records a request from the device
+func TouchTrustedDevice(db *sql.DB, id string) error {
+	_, err := db.Exec(`UPDATE trusted_devices SET last_seen_at = now() WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to update trusted device: %w", err)
+	}
+
+	return nil
+}
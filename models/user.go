@@ -127,6 +127,22 @@ func GetUserDataByUserId(db *sql.DB, userId string) (*UserData, error) {
 	return userData, nil
 }
 
+// GetUserBankProvider returns the partner bank of a user, empty when the default bank serves the user
+func GetUserBankProvider(db *sql.DB, userId string) (string, error) {
+	var provider sql.NullString
+	if err := db.QueryRow(
+		`SELECT bank_provider FROM user_data WHERE user_id=$1`,
+		userId,
+	).Scan(&provider); err != nil {
+		if err == sql.ErrNoRows {
+			return "", constants.ErrUserNotFound
+		}
+		return "", err
+	}
+
+	return provider.String, nil
+}
+
 func GetUserDataByMobileNumber(db *sql.DB, mobileNumber string) (*UserData, error) {
 	userData := NewUserData()
 	row := db.QueryRow(
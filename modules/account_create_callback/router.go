@@ -1,12 +1,12 @@
 package account_create_callback
 
 import (
+	"bankapi/config"
 	"bankapi/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
 func Routes(app *gin.RouterGroup) {
-	app.Use(middleware.CallbackMiddleware())
-	app.POST("/account-create", AccountCreateCallback)
+	app.POST("/account-create", middleware.CallbackMiddleware(config.CallbackPartnerAccountCreate), AccountCreateCallback)
 }
@@ -63,7 +63,7 @@ func InitiateSimVerification(c *gin.Context) {
 		return
 	}
 
-	result, err := store.Authentication.InitiateSimVerification(c.Request.Context(), authValues.UserId, authValues.DeviceIp, authValues.OS, authValues.OSVersion, authValues.Key, request)
+	result, err := store.Authentication.InitiateSimVerification(c.Request.Context(), authValues.UserId, authValues.DeviceIp, authValues.OS, authValues.OSVersion, authValues.Key, authValues.SigningKey, request)
 
 	if err != nil {
 		fmt.Println("ERR STORE ", err)
@@ -165,7 +165,7 @@ func UserLogout(c *gin.Context) {
 		return
 	}
 
-	err = store.Authentication.Logout(c.Request.Context(), authValues.UserId)
+	err = store.Authentication.Logout(c.Request.Context(), authValues.UserId, authValues.SessionId)
 	if err != nil {
 		responses.StatusInternalServerError(
 			c,
@@ -0,0 +1,112 @@
+package authorizationmodule
+
+import (
+	"bitbucket.org/paydoh/paydoh-commons/customerror"
+	"bitbucket.org/paydoh/paydoh-commons/responses"
+	"github.com/gin-gonic/gin"
+
+	"bankapi/requests"
+	"bankapi/stores"
+)
+
+// DeviceKeyLoginChallenge godoc
+// @Summary Device key login challenge
+// @Description Issues a login challenge for the key registered on the device, the device signs it after a biometric prompt
+// @Tags authorization apis
+// @Accept  json
+// @Produce  json
+// @Param X-Device-ID header string true "With the device id"
+// @Param deviceKeyLoginChallengeRequest body requests.DeviceKeyLoginChallengeRequest true "Device Key Login Challenge Request"
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 429 {object} responses.MobileTeamErrorResponse "Too many attempts, retry after the Retry-After header"
+// @Router /api/authorization/device-key/challenge [post]
+func DeviceKeyLoginChallenge(c *gin.Context) {
+	stores, err := stores.GetStores(c)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	request := requests.NewDeviceKeyLoginChallengeRequest()
+	if err := request.Validate(c); err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	challenge, err := stores.Authorization.DeviceKeyLoginChallenge(c.Request.Context(), request)
+	if err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		challenge,
+		"successfully issued challenge",
+		"",
+	)
+}
+
+// DeviceKeyLogin godoc
+// @Summary Login with a device key
+// @Description Exchanges a signed login challenge for an access and refresh token, in place of the mpin
+// @Tags authorization apis
+// @Accept  json
+// @Produce  json
+// @Param X-Device-ID header string true "With the device id"
+// @Param deviceKeyLoginRequest body requests.DeviceKeyLoginRequest true "Signed challenge and the public key of the session key exchange"
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 401 {object} responses.MobileTeamErrorResponse "Error response for Unauthorized"
+// @Failure 429 {object} responses.MobileTeamErrorResponse "Too many attempts, retry after the Retry-After header"
+// @Router /api/authorization/device-key/login [post]
+func DeviceKeyLogin(c *gin.Context) {
+	stores, err := stores.GetStores(c)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	request := requests.NewDeviceKeyLoginRequest()
+	if err := request.Validate(c); err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	result, err := stores.Authorization.DeviceKeyLogin(c.Request.Context(), request)
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		result,
+		"successfully logged in",
+		"",
+	)
+}
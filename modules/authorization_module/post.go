@@ -10,7 +10,7 @@ import (
 )
 
 // @Summary Api get authorization Token for all the api
-// @Description Using the mobile_number get the authorization token for the other apis.
+// @Description Using the mobile_number get the authorization token for the other apis. A login with user_id from a device with a device key needs a login challenge signed by that key.
 // @Tags authorization apis
 // @Accept  json
 // @Produce  json
@@ -18,6 +18,7 @@ import (
 // @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
 // @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
 // @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Failure 429 {object} responses.MobileTeamErrorResponse "Too many attempts, retry after the Retry-After header"
 // @Router /api/authorization/ [post]
 func Authorization(c *gin.Context) {
 	stores, err := stores.GetStores(c)
@@ -82,3 +83,53 @@ func Authorization(c *gin.Context) {
 		)
 	}
 }
+
+// RefreshToken godoc
+// @Summary Refresh the access token
+// @Description Exchanges a refresh token for a new access and refresh token. A refresh token can be used once, reusing one logs its device out.
+// @Tags authorization apis
+// @Accept  json
+// @Produce  json
+// @Param refreshTokenRequest body requests.RefreshTokenRequest true "Refresh Token Request"
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 401 {object} responses.MobileTeamErrorResponse "Error response for Unauthorized"
+// @Failure 429 {object} responses.MobileTeamErrorResponse "Too many attempts, retry after the Retry-After header"
+// @Router /api/authorization/refresh [post]
+func RefreshToken(c *gin.Context) {
+	stores, err := stores.GetStores(c)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	request := requests.NewRefreshTokenRequest()
+	if err := request.Validate(c); err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	result, err := stores.Authorization.RefreshToken(c.Request.Context(), request)
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		result,
+		"successfully refreshed token",
+		"",
+	)
+}
@@ -1,6 +1,9 @@
 package authorizationmodule
 
 import (
+	"bankapi/config"
+	"bankapi/middleware"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -8,7 +11,17 @@ func Routes(app *gin.RouterGroup) {
 
 	authorization := app.Group("/authorization")
 	{
-		authorization.POST("", Authorization)
+		authorization.POST("", middleware.RateLimitMiddleware(config.RateLimitAuthorization), Authorization)
+		authorization.POST("/refresh", middleware.RateLimitMiddleware(config.RateLimitAuthorization), RefreshToken)
+		authorization.POST("/device-key/challenge", middleware.RateLimitMiddleware(config.RateLimitAuthorization), DeviceKeyLoginChallenge)
+		authorization.POST("/device-key/login", middleware.RateLimitMiddleware(config.RateLimitMpin), DeviceKeyLogin)
+	}
+
+	sessions := authorization.Group("/sessions")
+	sessions.Use(middleware.AuthMiddleware())
+	{
+		sessions.GET("", GetSessions)
+		sessions.DELETE("/:id", RevokeSession)
 	}
 
 	userAuthenticated := authorization.Group("/authenticated")
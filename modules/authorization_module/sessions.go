@@ -0,0 +1,129 @@
+package authorizationmodule
+
+import (
+	"errors"
+
+	"bitbucket.org/paydoh/paydoh-commons/customerror"
+	"bitbucket.org/paydoh/paydoh-commons/responses"
+	"github.com/gin-gonic/gin"
+
+	"bankapi/constants"
+	"bankapi/stores"
+)
+
+// GetSessions godoc
+// @Summary List logged in devices
+// @Description Lists the active device sessions of the user, the session of the request is marked current
+// @Tags authorization apis
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param X-Lat-Long header string true "With the lat long"
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Router /api/authorization/sessions [get]
+func GetSessions(c *gin.Context) {
+	authValues, err := stores.GetAuthValue(c)
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	store, err := stores.GetStores(c)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	sessions, err := store.Authorization.ListSessions(c.Request.Context(), authValues)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		sessions,
+		"successfully fetched sessions",
+		"",
+	)
+}
+
+// RevokeSession godoc
+// @Summary Log a device out
+// @Description Revokes a device session of the user, its tokens stop working immediately
+// @Tags authorization apis
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param X-Lat-Long header string true "With the lat long"
+// @Param id path string true "Session id"
+// @Success 200 {object} responses.MobileTeamSuccessResponseWithoutData "success response"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Router /api/authorization/sessions/{id} [delete]
+func RevokeSession(c *gin.Context) {
+	authValues, err := stores.GetAuthValue(c)
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	store, err := stores.GetStores(c)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	if err := store.Authorization.RevokeSession(c.Request.Context(), authValues, c.Param("id")); err != nil {
+		if errors.Is(err, constants.ErrNoDataFound) {
+			responses.StatusNotFound(
+				c,
+				customerror.NewError(errors.New("session not found")),
+				"",
+			)
+			return
+		}
+
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		nil,
+		"successfully revoked session",
+		"",
+	)
+}
@@ -0,0 +1,26 @@
+package bank_errors
+
+import (
+	"bankapi/services"
+
+	"bitbucket.org/paydoh/paydoh-commons/responses"
+	"github.com/gin-gonic/gin"
+)
+
+// GetBankErrorList godoc
+// @Summary Get bank error registry
+// @Description Lists the KVB error codes per operation with their messages and retry behaviour, for the support team
+// @Tags Bank Errors
+// @Accept json
+// @Produce json
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 401 {object} responses.MobileTeamErrorResponse "Error response for Unauthorized"
+// @Router /api/bank-errors/list [get]
+func GetBankErrorList(c *gin.Context) {
+	responses.StatusOk(
+		c,
+		services.BankErrors().Snapshot(),
+		"successfully fetched bank error registry",
+		"",
+	)
+}
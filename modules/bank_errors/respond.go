@@ -0,0 +1,37 @@
+package bank_errors
+
+import (
+	"errors"
+	"net/http"
+
+	"bitbucket.org/paydoh/paydoh-commons/customerror"
+	"github.com/gin-gonic/gin"
+
+	"bankapi/responses"
+)
+
+// StatusBankError responds with the http status the bank error registry has
+// for a KVB error, so the app can tell a wrong input from an outage, it
+// reports whether err was one
+func StatusBankError(c *gin.Context, err error) bool {
+	var bankErr *responses.BankErrorResponse
+	if !errors.As(err, &bankErr) {
+		return false
+	}
+
+	status := bankErr.HTTPStatus
+	if status == 0 {
+		status = http.StatusBadRequest
+	}
+
+	c.JSON(
+		status,
+		gin.H{
+			"status":     status,
+			"message":    bankErr.ErrorMessage,
+			"ERROR_CODE": bankErr.ErrorCode,
+			"error":      customerror.NewError(bankErr),
+		},
+	)
+	return true
+}
@@ -0,0 +1,16 @@
+package bank_errors
+
+import (
+	"bankapi/config"
+	"bankapi/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Routes(app *gin.RouterGroup) {
+	bankErrors := app.Group("/bank-errors")
+	bankErrors.Use(middleware.CallbackMiddleware(config.CallbackPartnerInternal))
+	{
+		bankErrors.GET("/list", GetBankErrorList)
+	}
+}
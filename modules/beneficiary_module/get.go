@@ -63,3 +63,58 @@ func SearchBeneficiary(c *gin.Context) {
 		"",
 	)
 }
+
+// @Summary Api to sync the beneficiaries with the bank
+// @Tags Beneficiary API
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param X-Lat-Long header string true "With the lat long"
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Router /api/beneficiary/sync [get]
+func SyncBeneficiaries(c *gin.Context) {
+	store, err := stores.GetStores(c)
+
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	authValues, err := stores.GetAuthValue(c)
+
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	result, err := store.Beneficiary.SyncBeneficiaries(c.Request.Context(), authValues)
+
+	if err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		result,
+		"successfully synced beneficiaries",
+		"",
+	)
+}
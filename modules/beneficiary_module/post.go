@@ -5,6 +5,7 @@ import (
 	"bitbucket.org/paydoh/paydoh-commons/responses"
 	"github.com/gin-gonic/gin"
 
+	"bankapi/modules/bank_errors"
 	"bankapi/requests"
 	"bankapi/stores"
 )
@@ -23,6 +24,7 @@ import (
 // @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
 // @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
 // @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Failure 403 {object} responses.MobileTeamErrorResponse "Step-up required, verify the factors in data and retry"
 // @Router /api/beneficiary/add-beneficiary [post]
 func AddNewBeneficiary(c *gin.Context) {
 	store, err := stores.GetStores(c)
@@ -71,6 +73,10 @@ func AddNewBeneficiary(c *gin.Context) {
 	result, err := store.Beneficiary.AddBeneficiary(c.Request.Context(), authValues, request)
 
 	if err != nil {
+		if bank_errors.StatusBankError(c, err) {
+			return
+		}
+
 		responses.StatusBadRequest(
 			c,
 			customerror.NewError(err),
@@ -101,6 +107,7 @@ func AddNewBeneficiary(c *gin.Context) {
 // @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
 // @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
 // @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Failure 429 {object} responses.MobileTeamErrorResponse "Too many attempts, retry after the Retry-After header"
 // @Router /api/beneficiary/beneficiary-otp [post]
 func BeneficiaryOTP(c *gin.Context) {
 	store, err := stores.GetStores(c)
@@ -175,6 +182,7 @@ func BeneficiaryOTP(c *gin.Context) {
 // @Param X-OS header string true "With the os"
 // @Param X-OS-Version header string true "With the os version"
 // @Param X-Lat-Long header string true "With the lat long"
+// @Param Idempotency-Key header string true "Unique key of the payment, repeats return the first outcome"
 // @Param encryptedRequest body requests.EncryptedRequest true "Encrypted Request"
 // @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
 // @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
@@ -227,6 +235,10 @@ func BeneficiaryPayment(c *gin.Context) {
 	result, err := store.Beneficiary.BeneficiaryPayment(c.Request.Context(), authValues, request)
 
 	if err != nil {
+		if bank_errors.StatusBankError(c, err) {
+			return
+		}
+
 		responses.StatusBadRequest(
 			c,
 			customerror.NewError(err),
@@ -253,6 +265,7 @@ func BeneficiaryPayment(c *gin.Context) {
 // @Param X-OS header string true "With the os"
 // @Param X-OS-Version header string true "With the os version"
 // @Param X-Lat-Long header string true "With the lat long"
+// @Param Idempotency-Key header string true "Unique key of the payment, repeats return the first outcome"
 // @Param encryptedRequest body requests.EncryptedRequest true "Encrypted Request"
 // @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
 // @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
@@ -305,6 +318,10 @@ func BeneficiaryPaymentOTP(c *gin.Context) {
 	result, err := store.Beneficiary.BeneficiaryPaymentOTP(c.Request.Context(), authValues, request.Otp)
 
 	if err != nil {
+		if bank_errors.StatusBankError(c, err) {
+			return
+		}
+
 		responses.StatusBadRequest(
 			c,
 			customerror.NewError(err),
@@ -461,6 +478,10 @@ func QuickTransferTemplate(c *gin.Context) {
 	result, err := store.Beneficiary.CreateQuickTransferTemplate(c.Request.Context(), authValues, request)
 
 	if err != nil {
+		if bank_errors.StatusBankError(c, err) {
+			return
+		}
+
 		responses.StatusBadRequest(
 			c,
 			customerror.NewError(err),
@@ -476,3 +497,317 @@ func QuickTransferTemplate(c *gin.Context) {
 		"",
 	)
 }
+
+// @Summary Api to update the nickname of a beneficiary
+// @Tags Beneficiary API
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param X-Lat-Long header string true "With the lat long"
+// @Param encryptedRequest body requests.EncryptedRequest true "Encrypted Request"
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Router /api/beneficiary/update-nickname [post]
+func UpdateBeneficiaryNickname(c *gin.Context) {
+	store, err := stores.GetStores(c)
+
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	authValues, err := stores.GetAuthValue(c)
+
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	requestPayload, err := stores.GetRequestPayload(c)
+
+	if err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	request := requests.NewUpdateBeneficiaryNicknameRequest()
+
+	if err := request.Validate(requestPayload.Payload); err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	result, err := store.Beneficiary.UpdateBeneficiaryNickname(c.Request.Context(), authValues, request)
+
+	if err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		result,
+		"successfully updated beneficiary",
+		"",
+	)
+}
+
+// @Summary Api to disable a beneficiary
+// @Tags Beneficiary API
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param X-Lat-Long header string true "With the lat long"
+// @Param encryptedRequest body requests.EncryptedRequest true "Encrypted Request"
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Router /api/beneficiary/disable [post]
+func DisableBeneficiary(c *gin.Context) {
+	store, err := stores.GetStores(c)
+
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	authValues, err := stores.GetAuthValue(c)
+
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	requestPayload, err := stores.GetRequestPayload(c)
+
+	if err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	request := requests.NewBeneficiaryRequest()
+
+	if err := request.Validate(requestPayload.Payload); err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	result, err := store.Beneficiary.SetBeneficiaryStatus(c.Request.Context(), authValues, request.BenfId, true)
+
+	if err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		result,
+		"successfully disabled beneficiary",
+		"",
+	)
+}
+
+// @Summary Api to enable a disabled beneficiary
+// @Tags Beneficiary API
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param X-Lat-Long header string true "With the lat long"
+// @Param encryptedRequest body requests.EncryptedRequest true "Encrypted Request"
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Failure 403 {object} responses.MobileTeamErrorResponse "Step-up required, verify the factors in data and retry"
+// @Router /api/beneficiary/enable [post]
+func EnableBeneficiary(c *gin.Context) {
+	store, err := stores.GetStores(c)
+
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	authValues, err := stores.GetAuthValue(c)
+
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	requestPayload, err := stores.GetRequestPayload(c)
+
+	if err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	request := requests.NewBeneficiaryRequest()
+
+	if err := request.Validate(requestPayload.Payload); err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	result, err := store.Beneficiary.SetBeneficiaryStatus(c.Request.Context(), authValues, request.BenfId, false)
+
+	if err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		result,
+		"successfully enabled beneficiary",
+		"",
+	)
+}
+
+// @Summary Api to delete a beneficiary
+// @Tags Beneficiary API
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param X-Lat-Long header string true "With the lat long"
+// @Param encryptedRequest body requests.EncryptedRequest true "Encrypted Request"
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Failure 403 {object} responses.MobileTeamErrorResponse "Step-up required, verify the factors in data and retry"
+// @Router /api/beneficiary/delete [post]
+func DeleteBeneficiary(c *gin.Context) {
+	store, err := stores.GetStores(c)
+
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	authValues, err := stores.GetAuthValue(c)
+
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	requestPayload, err := stores.GetRequestPayload(c)
+
+	if err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	request := requests.NewBeneficiaryRequest()
+
+	if err := request.Validate(requestPayload.Payload); err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	result, err := store.Beneficiary.DeleteBeneficiary(c.Request.Context(), authValues, request.BenfId)
+
+	if err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		result,
+		"successfully deleted beneficiary",
+		"",
+	)
+}
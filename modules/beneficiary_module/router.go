@@ -1,6 +1,7 @@
 package beneficiarymodule
 
 import (
+	"bankapi/config"
 	"bankapi/middleware"
 
 	"github.com/gin-gonic/gin"
@@ -13,13 +14,18 @@ func Routes(api *gin.RouterGroup) {
 	{
 		// GET apis
 		beneficiary.GET("/search", SearchBeneficiary)
+		beneficiary.GET("/sync", SyncBeneficiaries)
 
 		// POST apis
-		beneficiary.POST("/add-beneficiary", AddNewBeneficiary)
-		beneficiary.POST("/beneficiary-otp", BeneficiaryOTP)
-		beneficiary.POST("/payment", BeneficiaryPayment)
-		beneficiary.POST("/payment-otp", BeneficiaryPaymentOTP)
+		beneficiary.POST("/add-beneficiary", middleware.StepUpMiddleware(config.StepUpBeneficiary), AddNewBeneficiary)
+		beneficiary.POST("/beneficiary-otp", middleware.RateLimitMiddleware(config.RateLimitOtp), BeneficiaryOTP)
+		beneficiary.POST("/payment", middleware.IdempotencyMiddleware(), BeneficiaryPayment)
+		beneficiary.POST("/payment-otp", middleware.IdempotencyMiddleware(), BeneficiaryPaymentOTP)
 		beneficiary.POST("/payment-status", BeneficiaryPaymentStatus)
 		beneficiary.POST("/quick-transfer-template", QuickTransferTemplate)
+		beneficiary.POST("/update-nickname", UpdateBeneficiaryNickname)
+		beneficiary.POST("/disable", DisableBeneficiary)
+		beneficiary.POST("/enable", middleware.StepUpMiddleware(config.StepUpBeneficiary), EnableBeneficiary)
+		beneficiary.POST("/delete", middleware.StepUpMiddleware(config.StepUpBeneficiary), DeleteBeneficiary)
 	}
 }
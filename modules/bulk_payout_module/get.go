@@ -0,0 +1,200 @@
+package bulkpayoutmodule
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"bitbucket.org/paydoh/paydoh-commons/customerror"
+	"bitbucket.org/paydoh/paydoh-commons/responses"
+	"github.com/gin-gonic/gin"
+
+	"bankapi/constants"
+	"bankapi/requests"
+	"bankapi/stores"
+)
+
+// GetBulkPayouts godoc
+// @Summary List bulk payouts
+// @Description Lists the bulk payouts of the user with their status and totals, without their rows
+// @Tags bulk payout apis
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-ID header string true "With the device id"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param X-Lat-Long header string true "With the lat long"
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Router /api/bulk-payouts [get]
+func GetBulkPayouts(c *gin.Context) {
+	authValues, err := stores.GetAuthValue(c)
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	store, err := stores.GetStores(c)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	bulkPayouts, err := store.BulkPayout.ListBulkPayouts(c.Request.Context(), authValues)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		bulkPayouts,
+		"successfully fetched bulk payouts",
+		"",
+	)
+}
+
+// GetBulkPayout godoc
+// @Summary Bulk payout preview and status
+// @Description Returns a bulk payout with its rows, the error of each invalid row and, once paid, the status and UTR of each payout
+// @Tags bulk payout apis
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-ID header string true "With the device id"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param X-Lat-Long header string true "With the lat long"
+// @Param id path string true "Bulk payout id"
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 404 {object} responses.MobileTeamErrorResponse "Error response for Not Found"
+// @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Router /api/bulk-payouts/{id} [get]
+func GetBulkPayout(c *gin.Context) {
+	authValues, err := stores.GetAuthValue(c)
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	store, err := stores.GetStores(c)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	bulkPayout, err := store.BulkPayout.GetBulkPayout(c.Request.Context(), authValues, c.Param("id"))
+	if err != nil {
+		if errors.Is(err, constants.ErrNoDataFound) {
+			responses.StatusNotFound(
+				c,
+				customerror.NewError(errors.New("bulk payout not found")),
+				"",
+			)
+			return
+		}
+
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		bulkPayout,
+		"successfully fetched bulk payout",
+		"",
+	)
+}
+
+// DownloadBulkPayoutResult godoc
+// @Summary Download the result of a bulk payout
+// @Description Downloads the rows of a bulk payout with the status, UTR and error of each, as a file of the type that was uploaded
+// @Tags bulk payout apis
+// @Produce  text/csv
+// @Produce  application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-ID header string true "With the device id"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param X-Lat-Long header string true "With the lat long"
+// @Param id path string true "Bulk payout id"
+// @Success 200 "result file"
+// @Failure 404 {object} responses.MobileTeamErrorResponse "Error response for Not Found"
+// @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Router /api/bulk-payouts/{id}/result [get]
+func DownloadBulkPayoutResult(c *gin.Context) {
+	authValues, err := stores.GetAuthValue(c)
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	store, err := stores.GetStores(c)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	data, fileName, err := store.BulkPayout.ResultFile(c.Request.Context(), authValues, c.Param("id"))
+	if err != nil {
+		if errors.Is(err, constants.ErrNoDataFound) {
+			responses.StatusNotFound(
+				c,
+				customerror.NewError(errors.New("bulk payout not found")),
+				"",
+			)
+			return
+		}
+
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	contentType := "text/csv"
+	if strings.HasSuffix(fileName, "."+requests.BulkPayoutFileXlsx) {
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+fileName)
+	c.Data(http.StatusOK, contentType, data)
+}
@@ -0,0 +1,248 @@
+package bulkpayoutmodule
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"bitbucket.org/paydoh/paydoh-commons/customerror"
+	"bitbucket.org/paydoh/paydoh-commons/responses"
+	"github.com/gin-gonic/gin"
+
+	"bankapi/config"
+	"bankapi/constants"
+	"bankapi/requests"
+	"bankapi/stores"
+)
+
+// UploadBulkPayout godoc
+// @Summary Upload a bulk payout file
+// @Description Checks a CSV or XLSX file with the columns beneficiary name, account number, IFSC, amount and remarks (and optionally account type, SA or CA) and returns a preview with the error of each invalid row. Nothing is paid until the bulk payout is confirmed.
+// @Tags bulk payout apis
+// @Accept  multipart/form-data
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-ID header string true "With the device id"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param X-Lat-Long header string true "With the lat long"
+// @Param file formData file true "CSV or XLSX file, the first row is the header"
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Router /api/bulk-payouts [post]
+func UploadBulkPayout(c *gin.Context) {
+	authValues, err := stores.GetAuthValue(c)
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	store, err := stores.GetStores(c)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	maxFileSize := config.GetBulkPayoutConfig().MaxFileSize
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxFileSize+1024*1024)
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(errors.New(constants.BulkPayoutFileErrorMessage)),
+			"",
+		)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxFileSize+1))
+	if err != nil || int64(len(data)) > maxFileSize {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(errors.New(constants.BulkPayoutFileErrorMessage)),
+			"",
+		)
+		return
+	}
+
+	bulkPayout, err := store.BulkPayout.UploadBulkPayout(c.Request.Context(), authValues, header.Filename, data)
+	if err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		bulkPayout,
+		"successfully checked bulk payout",
+		"",
+	)
+}
+
+// ConfirmBulkPayout godoc
+// @Summary Confirm a bulk payout
+// @Description Submits the valid rows of a previewed bulk payout to the bank, a few at a time in the background. Needs a recent mpin and otp verification. The bank sends an otp for each submitted payout, which is paid once it is confirmed. The status and UTR of each payout are in the bulk payout and its result file.
+// @Tags bulk payout apis
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-ID header string true "With the device id"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param X-Lat-Long header string true "With the lat long"
+// @Param id path string true "Bulk payout id"
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 403 {object} responses.MobileTeamErrorResponse "Step-up required, verify the factors in data and retry"
+// @Failure 404 {object} responses.MobileTeamErrorResponse "Error response for Not Found"
+// @Router /api/bulk-payouts/{id}/confirm [post]
+func ConfirmBulkPayout(c *gin.Context) {
+	authValues, err := stores.GetAuthValue(c)
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	store, err := stores.GetStores(c)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	bulkPayout, err := store.BulkPayout.ConfirmBulkPayout(c.Request.Context(), authValues, c.Param("id"))
+	if err != nil {
+		if errors.Is(err, constants.ErrNoDataFound) {
+			responses.StatusNotFound(
+				c,
+				customerror.NewError(errors.New("bulk payout not found")),
+				"",
+			)
+			return
+		}
+
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		bulkPayout,
+		"successfully confirmed bulk payout",
+		"",
+	)
+}
+
+// ConfirmBulkPayoutRow godoc
+// @Summary Confirm a payout of a bulk payout
+// @Description Pays a submitted payout with the otp the bank sent for it, a wrong otp keeps it waiting
+// @Tags bulk payout apis
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-ID header string true "With the device id"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param X-Lat-Long header string true "With the lat long"
+// @Param id path string true "Bulk payout id"
+// @Param row_id path string true "Row id"
+// @Param encryptedRequest body requests.EncryptedRequest true "Encrypted requests.BulkPayoutOtpRequest"
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 404 {object} responses.MobileTeamErrorResponse "Error response for Not Found"
+// @Router /api/bulk-payouts/{id}/rows/{row_id}/confirm [post]
+func ConfirmBulkPayoutRow(c *gin.Context) {
+	authValues, err := stores.GetAuthValue(c)
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	store, err := stores.GetStores(c)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	requestPayload, err := stores.GetRequestPayload(c)
+	if err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			authValues.Key,
+		)
+		return
+	}
+
+	request := requests.NewBulkPayoutOtpRequest()
+	if err := request.Validate(requestPayload.Payload); err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			authValues.Key,
+		)
+		return
+	}
+
+	row, err := store.BulkPayout.ConfirmBulkPayoutRow(c.Request.Context(), authValues, c.Param("id"), c.Param("row_id"), request.Otp)
+	if err != nil {
+		if errors.Is(err, constants.ErrNoDataFound) {
+			responses.StatusNotFound(
+				c,
+				customerror.NewError(errors.New("bulk payout row not found")),
+				authValues.Key,
+			)
+			return
+		}
+
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			authValues.Key,
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		row,
+		"successfully confirmed payout",
+		authValues.Key,
+	)
+}
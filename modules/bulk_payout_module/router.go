@@ -0,0 +1,23 @@
+package bulkpayoutmodule
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"bankapi/config"
+	"bankapi/middleware"
+
+	responseMiddleware "bitbucket.org/paydoh/paydoh-commons/middleware"
+)
+
+func Routes(app *gin.RouterGroup) {
+	bulkPayout := app.Group("/bulk-payouts")
+	bulkPayout.Use(middleware.AuthMiddleware())
+	{
+		bulkPayout.GET("", GetBulkPayouts)
+		bulkPayout.POST("", UploadBulkPayout)
+		bulkPayout.GET("/:id", GetBulkPayout)
+		bulkPayout.POST("/:id/confirm", middleware.StepUpMiddleware(config.StepUpBulkPayout), ConfirmBulkPayout)
+		bulkPayout.GET("/:id/result", DownloadBulkPayoutResult)
+		bulkPayout.POST("/:id/rows/:row_id/confirm", middleware.RateLimitMiddleware(config.RateLimitOtp), middleware.DecryptMiddleware(), responseMiddleware.ResponseEncryptionMiddleware(), ConfirmBulkPayoutRow)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"bitbucket.org/paydoh/paydoh-commons/responses"
 	"github.com/gin-gonic/gin"
 
+	"bankapi/modules/bank_errors"
 	"bankapi/requests"
 	"bankapi/stores"
 )
@@ -69,6 +70,10 @@ func UpdateConsent(c *gin.Context) {
 	}
 
 	if err := store.Consent.UpdateConsent(c.Request.Context(), request, authValues); err != nil {
+		if bank_errors.StatusBankError(c, err) {
+			return
+		}
+
 		responses.StatusBadRequest(
 			c,
 			customerror.NewError(err),
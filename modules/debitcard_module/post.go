@@ -1,6 +1,7 @@
 package debitcardmodule
 
 import (
+	"bankapi/modules/bank_errors"
 	"bankapi/requests"
 	"bankapi/stores"
 
@@ -95,6 +96,10 @@ func GetDebitCardDetails(c *gin.Context) {
 	result, err := s.DebitCard.DebitCardDetail(c.Request.Context(), authValues)
 
 	if err != nil {
+		if bank_errors.StatusBankError(c, err) {
+			return
+		}
+
 		responses.StatusBadRequest(c, customerror.NewError(err), "")
 		return
 	}
@@ -167,6 +172,7 @@ func GetTransactionLimit(c *gin.Context) {
 // @Success 200 {object} responses.MobileTeamSuccessResponseWithoutData "success response"
 // @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
 // @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Failure 403 {object} responses.MobileTeamErrorResponse "Step-up required, verify the factors in data and retry"
 // @Router /api/debitcard/set-txn-limit [post]
 func SetTransactonLimit(c *gin.Context) {
 	s, err := stores.GetStores(c)
@@ -197,6 +203,10 @@ func SetTransactonLimit(c *gin.Context) {
 
 	result, err := s.DebitCard.SetTransactionLimit(c.Request.Context(), auth, request)
 	if err != nil {
+		if bank_errors.StatusBankError(c, err) {
+			return
+		}
+
 		responses.StatusBadRequest(c, customerror.NewError(err), "")
 		return
 	}
@@ -260,6 +270,7 @@ func GetCardStatus(c *gin.Context) {
 // @Success 200 {object} responses.MobileTeamSuccessResponseWithoutData "success response"
 // @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
 // @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Failure 403 {object} responses.MobileTeamErrorResponse "Step-up required, verify the factors in data and retry"
 // @Router /api/debitcard/set-card-status [post]
 func SetCardStatus(c *gin.Context) {
 	s, err := stores.GetStores(c)
@@ -286,6 +297,10 @@ func SetCardStatus(c *gin.Context) {
 
 	response, err := s.DebitCard.SetCardStatus(c.Request.Context(), auth, request)
 	if err != nil {
+		if bank_errors.StatusBankError(c, err) {
+			return
+		}
+
 		responses.StatusBadRequest(
 			c,
 			customerror.NewError(err),
@@ -343,6 +358,10 @@ func SetDebitCardPin(c *gin.Context) {
 	}
 	result, err := s.DebitCard.SetDebitCardPin(c.Request.Context(), authValue, req)
 	if err != nil {
+		if bank_errors.StatusBankError(c, err) {
+			return
+		}
+
 		responses.StatusBadRequest(c, customerror.NewError(err), "")
 		return
 	}
@@ -391,6 +410,10 @@ func VerifyOTP(c *gin.Context) {
 
 	result, err := s.DebitCard.VerifyDebitCardOTP(c.Request.Context(), authValue, req)
 	if err != nil {
+		if bank_errors.StatusBankError(c, err) {
+			return
+		}
+
 		responses.StatusBadRequest(c, customerror.NewError(err), "")
 		return
 	}
@@ -1,6 +1,7 @@
 package debitcardmodule
 
 import (
+	"bankapi/config"
 	"bankapi/middleware"
 
 	"github.com/gin-gonic/gin"
@@ -20,8 +21,8 @@ func Routes(app *gin.RouterGroup) {
 		debitcard.GET("/track-status", TrackDebitCardStatus)
 
 		debitcard.POST("/get-limit-list", GetTransactionLimit)
-		debitcard.POST("/set-txn-limit", SetTransactonLimit)
+		debitcard.POST("/set-txn-limit", middleware.StepUpMiddleware(config.StepUpCardControl), SetTransactonLimit)
 		debitcard.GET("/get-card-status", GetCardStatus)
-		debitcard.POST("/set-card-status", SetCardStatus)
+		debitcard.POST("/set-card-status", middleware.StepUpMiddleware(config.StepUpCardControl), SetCardStatus)
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"bitbucket.org/paydoh/paydoh-commons/responses"
 	"github.com/gin-gonic/gin"
 
+	"bankapi/modules/bank_errors"
 	"bankapi/stores"
 )
 
@@ -47,6 +48,10 @@ func GetDemographicData(c *gin.Context) {
 	result, err := s.Demographic.GetDemographicData(c.Request.Context(), authValues)
 
 	if err != nil {
+		if bank_errors.StatusBankError(c, err) {
+			return
+		}
+
 		responses.StatusBadRequest(
 			c,
 			customerror.NewError(err),
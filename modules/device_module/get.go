@@ -0,0 +1,131 @@
+package devicemodule
+
+import (
+	"errors"
+
+	"bitbucket.org/paydoh/paydoh-commons/customerror"
+	"bitbucket.org/paydoh/paydoh-commons/responses"
+	"github.com/gin-gonic/gin"
+
+	"bankapi/constants"
+	"bankapi/stores"
+)
+
+// GetDevices godoc
+// @Summary List trusted devices
+// @Description Lists the trusted devices of the user, the device of the request is marked current
+// @Tags device apis
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-ID header string true "With the device id"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param X-Lat-Long header string true "With the lat long"
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Router /api/device [get]
+func GetDevices(c *gin.Context) {
+	authValues, err := stores.GetAuthValue(c)
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	store, err := stores.GetStores(c)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	devices, err := store.Device.ListDevices(c.Request.Context(), authValues)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		devices,
+		"successfully fetched devices",
+		"",
+	)
+}
+
+// GetDeviceChangeStatus godoc
+// @Summary Device change status
+// @Description Reports whether the sim of the device being registered is bound
+// @Tags device apis
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-ID header string true "With the device id"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param X-Lat-Long header string true "With the lat long"
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 404 {object} responses.MobileTeamErrorResponse "Error response for Not Found"
+// @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Router /api/device/change/status [get]
+func GetDeviceChangeStatus(c *gin.Context) {
+	authValues, err := stores.GetAuthValue(c)
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	store, err := stores.GetStores(c)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	result, err := store.Device.GetDeviceChangeStatus(c.Request.Context(), authValues)
+	if err != nil {
+		if errors.Is(err, constants.ErrNoDataFound) {
+			responses.StatusNotFound(
+				c,
+				customerror.NewError(errors.New("no device change in progress")),
+				"",
+			)
+			return
+		}
+
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		result,
+		"successfully fetched device change status",
+		"",
+	)
+}
@@ -0,0 +1,327 @@
+package devicemodule
+
+import (
+	"errors"
+
+	"bitbucket.org/paydoh/paydoh-commons/customerror"
+	"bitbucket.org/paydoh/paydoh-commons/responses"
+	"github.com/gin-gonic/gin"
+
+	"bankapi/constants"
+	"bankapi/requests"
+	"bankapi/stores"
+)
+
+// GetDeviceKeys godoc
+// @Summary List device keys
+// @Description Lists the biometric login keys of the user, the key of the device of the request is marked current
+// @Tags device apis
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-ID header string true "With the device id"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param X-Lat-Long header string true "With the lat long"
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Router /api/device/keys [get]
+func GetDeviceKeys(c *gin.Context) {
+	authValues, err := stores.GetAuthValue(c)
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	store, err := stores.GetStores(c)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	keys, err := store.Device.ListDeviceKeys(c.Request.Context(), authValues)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		keys,
+		"successfully fetched device keys",
+		"",
+	)
+}
+
+// RegisterDeviceKey godoc
+// @Summary Register a device key
+// @Description Registers the ES256 or Ed25519 public key (base64 DER) of the device for biometric login and step-up, replacing its previous key. Needs a recent mpin verification.
+// @Tags device apis
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-ID header string true "With the device id"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param X-Lat-Long header string true "With the lat long"
+// @Param encryptedRequest body requests.EncryptedRequest true "Encrypted requests.RegisterDeviceKeyRequest"
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 403 {object} responses.MobileTeamErrorResponse "Step-up required, verify the factors in data and retry"
+// @Router /api/device/keys [post]
+func RegisterDeviceKey(c *gin.Context) {
+	authValues, err := stores.GetAuthValue(c)
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	store, err := stores.GetStores(c)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	requestPayload, err := stores.GetRequestPayload(c)
+	if err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			authValues.Key,
+		)
+		return
+	}
+
+	request := requests.NewRegisterDeviceKeyRequest()
+	if err := request.Validate(requestPayload.Payload); err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			authValues.Key,
+		)
+		return
+	}
+
+	key, err := store.Device.RegisterDeviceKey(c.Request.Context(), authValues, request)
+	if err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			authValues.Key,
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		key,
+		"successfully registered device key",
+		authValues.Key,
+	)
+}
+
+// RevokeDeviceKey godoc
+// @Summary Revoke a device key
+// @Description Turns biometric login off for a key of the user
+// @Tags device apis
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-ID header string true "With the device id"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param X-Lat-Long header string true "With the lat long"
+// @Param id path string true "Device key id"
+// @Success 200 {object} responses.MobileTeamSuccessResponseWithoutData "success response"
+// @Failure 404 {object} responses.MobileTeamErrorResponse "Error response for Not Found"
+// @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Router /api/device/keys/{id} [delete]
+func RevokeDeviceKey(c *gin.Context) {
+	authValues, err := stores.GetAuthValue(c)
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	store, err := stores.GetStores(c)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	if err := store.Device.RevokeDeviceKey(c.Request.Context(), authValues, c.Param("id")); err != nil {
+		if errors.Is(err, constants.ErrNoDataFound) {
+			responses.StatusNotFound(
+				c,
+				customerror.NewError(errors.New("device key not found")),
+				"",
+			)
+			return
+		}
+
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		nil,
+		"successfully revoked device key",
+		"",
+	)
+}
+
+// IssueStepUpChallenge godoc
+// @Summary Device key step-up challenge
+// @Description Issues a challenge the device signs with its key after a biometric prompt, in place of entering the mpin
+// @Tags device apis
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-ID header string true "With the device id"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param X-Lat-Long header string true "With the lat long"
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 429 {object} responses.MobileTeamErrorResponse "Too many attempts, retry after the Retry-After header"
+// @Router /api/device/keys/step-up/challenge [post]
+func IssueStepUpChallenge(c *gin.Context) {
+	authValues, err := stores.GetAuthValue(c)
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	store, err := stores.GetStores(c)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	challenge, err := store.Device.IssueStepUpChallenge(c.Request.Context(), authValues)
+	if err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		challenge,
+		"successfully issued challenge",
+		"",
+	)
+}
+
+// VerifyStepUpChallenge godoc
+// @Summary Verify a device key step-up challenge
+// @Description Verifies the signature of a step-up challenge, it counts as a recent mpin verification of the session
+// @Tags device apis
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-ID header string true "With the device id"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param X-Lat-Long header string true "With the lat long"
+// @Param deviceKeySignatureRequest body requests.DeviceKeySignatureRequest true "Signed challenge"
+// @Success 200 {object} responses.MobileTeamSuccessResponseWithoutData "success response"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 401 {object} responses.MobileTeamErrorResponse "Error response for Unauthorized"
+// @Failure 429 {object} responses.MobileTeamErrorResponse "Too many attempts, retry after the Retry-After header"
+// @Router /api/device/keys/step-up/verify [post]
+func VerifyStepUpChallenge(c *gin.Context) {
+	authValues, err := stores.GetAuthValue(c)
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	store, err := stores.GetStores(c)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	request := requests.NewDeviceKeySignatureRequest()
+	if err := request.Validate(c); err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	if err := store.Device.VerifyStepUpChallenge(c.Request.Context(), authValues, request); err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		nil,
+		"successfully verified challenge",
+		"",
+	)
+}
@@ -0,0 +1,156 @@
+package devicemodule
+
+import (
+	"bitbucket.org/paydoh/paydoh-commons/customerror"
+	"bitbucket.org/paydoh/paydoh-commons/responses"
+	"github.com/gin-gonic/gin"
+
+	"bankapi/requests"
+	"bankapi/stores"
+)
+
+// InitiateDeviceChange godoc
+// @Summary Register a new device
+// @Description Starts moving the account to the device of the request. An otp is sent to the registered mobile number and the returned message is sent by sms to bind the sim of the device.
+// @Tags device apis
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-ID header string true "With the device id"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param X-Lat-Long header string true "With the lat long"
+// @Param authenticationRequest body requests.AuthenticationRequest true "Encrypted device data"
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Failure 429 {object} responses.MobileTeamErrorResponse "Too many attempts, retry after the Retry-After header"
+// @Router /api/device/change/initiate [post]
+func InitiateDeviceChange(c *gin.Context) {
+	authValues, err := stores.GetAuthValue(c)
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	store, err := stores.GetStores(c)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	request := requests.NewAuthenticationRequest()
+
+	if err := request.Validate(c); err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	result, err := store.Device.InitiateDeviceChange(c.Request.Context(), authValues, request)
+	if err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		result,
+		"successfully initiated device change",
+		"",
+	)
+}
+
+// VerifyDeviceChange godoc
+// @Summary Confirm a new device
+// @Description Trusts the device of the request once its sim is bound, with the otp and the mpin. The previous device is logged out and notified, payments from the new device are limited during the cooling period.
+// @Tags device apis
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-ID header string true "With the device id"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param X-Lat-Long header string true "With the lat long"
+// @Param deviceChangeVerifyRequest body requests.DeviceChangeVerifyRequest true "Otp and mpin"
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Failure 429 {object} responses.MobileTeamErrorResponse "Too many attempts, retry after the Retry-After header"
+// @Router /api/device/change/verify [post]
+func VerifyDeviceChange(c *gin.Context) {
+	authValues, err := stores.GetAuthValue(c)
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	store, err := stores.GetStores(c)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			authValues.Key,
+		)
+		return
+	}
+
+	requestPayload, err := stores.GetRequestPayload(c)
+	if err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			authValues.Key,
+		)
+		return
+	}
+
+	request := requests.NewDeviceChangeVerifyRequest()
+
+	if err := request.Validate(requestPayload.Payload); err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			authValues.Key,
+		)
+		return
+	}
+
+	device, err := store.Device.VerifyDeviceChange(c.Request.Context(), authValues, request)
+	if err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			authValues.Key,
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		device,
+		"successfully registered device",
+		authValues.Key,
+	)
+}
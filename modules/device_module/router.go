@@ -0,0 +1,29 @@
+package devicemodule
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"bankapi/config"
+	"bankapi/middleware"
+
+	responseMiddleware "bitbucket.org/paydoh/paydoh-commons/middleware"
+)
+
+func Routes(app *gin.RouterGroup) {
+	device := app.Group("/device")
+	device.Use(middleware.AuthMiddleware())
+	{
+		device.GET("", GetDevices)
+		device.DELETE("/:id", DeregisterDevice)
+
+		device.POST("/change/initiate", middleware.RateLimitMiddleware(config.RateLimitOtp), InitiateDeviceChange)
+		device.GET("/change/status", GetDeviceChangeStatus)
+		device.POST("/change/verify", middleware.RateLimitMiddleware(config.RateLimitMpin), middleware.DecryptMiddleware(), responseMiddleware.ResponseEncryptionMiddleware(), VerifyDeviceChange)
+
+		device.GET("/keys", GetDeviceKeys)
+		device.POST("/keys", middleware.StepUpMiddleware(config.StepUpDeviceKey), middleware.DecryptMiddleware(), responseMiddleware.ResponseEncryptionMiddleware(), RegisterDeviceKey)
+		device.DELETE("/keys/:id", RevokeDeviceKey)
+		device.POST("/keys/step-up/challenge", middleware.RateLimitMiddleware(config.RateLimitMpin), IssueStepUpChallenge)
+		device.POST("/keys/step-up/verify", middleware.RateLimitMiddleware(config.RateLimitMpin), VerifyStepUpChallenge)
+	}
+}
@@ -1,6 +1,7 @@
 package get_user_details_module
 
 import (
+	"bankapi/modules/bank_errors"
 	"bankapi/stores"
 
 	"bitbucket.org/paydoh/paydoh-commons/customerror"
@@ -44,6 +45,10 @@ func UserDetails(c *gin.Context) {
 
 	result, err := s.UserDetail.GetUserDetailData(c.Request.Context(), authValues)
 	if err != nil {
+		if bank_errors.StatusBankError(c, err) {
+			return
+		}
+
 		responses.StatusBadRequest(
 			c,
 			customerror.NewError(err),
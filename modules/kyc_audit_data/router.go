@@ -1,12 +1,12 @@
 package kyc_audit_data
 
 import (
+	"bankapi/config"
 	"bankapi/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
 func Routes(app *gin.RouterGroup) {
-	app.Use(middleware.CallbackMiddleware())
-	app.POST("/audit-completion", KycAuditComplition)
+	app.POST("/audit-completion", middleware.CallbackMiddleware(config.CallbackPartnerKycAudit), KycAuditComplition)
 }
@@ -5,6 +5,7 @@ import (
 	"bitbucket.org/paydoh/paydoh-commons/responses"
 	"github.com/gin-gonic/gin"
 
+	"bankapi/modules/bank_errors"
 	"bankapi/requests"
 	"bankapi/stores"
 )
@@ -70,6 +71,10 @@ func AddNewNominee(c *gin.Context) {
 	result, err := s.Nominee.AddNominee(c.Request.Context(), authValues, request)
 
 	if err != nil {
+		if bank_errors.StatusBankError(c, err) {
+			return
+		}
+
 		responses.StatusBadRequest(
 			c,
 			customerror.NewError(err),
@@ -100,6 +105,7 @@ func AddNewNominee(c *gin.Context) {
 // @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
 // @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
 // @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Failure 429 {object} responses.MobileTeamErrorResponse "Too many attempts, retry after the Retry-After header"
 // @Router /api/nominee/verify-otp [post]
 func VerifyNomineeOtp(c *gin.Context) {
 	s, err := stores.GetStores(c)
@@ -148,6 +154,10 @@ func VerifyNomineeOtp(c *gin.Context) {
 	result, err := s.Nominee.VerifyNomineeOtp(c.Request.Context(), authValues, request)
 
 	if err != nil {
+		if bank_errors.StatusBankError(c, err) {
+			return
+		}
+
 		responses.StatusBadRequest(
 			c,
 			customerror.NewError(err),
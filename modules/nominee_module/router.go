@@ -3,6 +3,7 @@ package nominee_module
 import (
 	"github.com/gin-gonic/gin"
 
+	"bankapi/config"
 	"bankapi/middleware"
 )
 
@@ -12,7 +13,7 @@ func Routes(api *gin.RouterGroup) {
 	nominee.Use(middleware.DecryptMiddleware())
 	{
 		nominee.POST("/add-nominee", AddNewNominee)
-		nominee.POST("/verify-otp", VerifyNomineeOtp)
+		nominee.POST("/verify-otp", middleware.RateLimitMiddleware(config.RateLimitOtp), VerifyNomineeOtp)
 		nominee.GET("/fetch", FetchNominee)
 	}
 }
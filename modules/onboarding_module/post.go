@@ -5,6 +5,7 @@ import (
 	"bitbucket.org/paydoh/paydoh-commons/responses"
 	"github.com/gin-gonic/gin"
 
+	"bankapi/modules/bank_errors"
 	"bankapi/requests"
 	"bankapi/stores"
 )
@@ -154,6 +155,10 @@ func CreateAccount(c *gin.Context) {
 	result, err := s.Onboarding.CreateBankAccount(c.Request.Context(), authValues, request)
 
 	if err != nil {
+		if bank_errors.StatusBankError(c, err) {
+			return
+		}
+
 		responses.StatusBadRequest(
 			c,
 			customerror.NewError(err),
@@ -487,3 +487,43 @@ func GetIfscDataByIfscCode(c *gin.Context) {
 		"",
 	)
 }
+
+// @Summary Api to list the locked mpins.
+// @Description Lists the users whose mpin is locked now with the lockout count and the end of the lockout, for support. Called by the admin service.
+// @Tags Open Bank apis
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 401 {object} responses.MobileTeamErrorResponse "Error response for Unauthorized"
+// @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Router /api/admin/mpin-lockouts [get]
+func GetMpinLockouts(c *gin.Context) {
+	s, err := stores.GetStores(c)
+
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	result, err := s.Open.ListMpinLockouts(c.Request.Context())
+
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		result,
+		"successfully fetched mpin lockouts",
+		"",
+	)
+}
@@ -1,11 +1,15 @@
 package openmodules
 
 import (
+	"errors"
+	"net/http"
+
 	"bitbucket.org/paydoh/paydoh-commons/customerror"
 	"bitbucket.org/paydoh/paydoh-commons/responses"
 	"github.com/gin-gonic/gin"
 
 	"bankapi/requests"
+	"bankapi/security"
 	"bankapi/stores"
 )
 
@@ -31,6 +35,7 @@ import (
 // @Success 200 {object} responses.MobileTeamSuccessResponseWithoutData "success response"
 // @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
 // @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Failure 403 {object} responses.MobileTeamErrorResponse "Step-up required, verify the factors in data and retry"
 // @Router /api/open/shipping-address [post]
 func UpdateShippingAddress(c *gin.Context) {
 	s, err := stores.GetStores(c)
@@ -115,6 +120,7 @@ func UpdateShippingAddress(c *gin.Context) {
 // @Success 200 {object} responses.MobileTeamSuccessResponseWithoutData "success response"
 // @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
 // @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Failure 403 {object} responses.MobileTeamErrorResponse "Step-up required, verify the factors in data and retry"
 // @Router /api/open/update-shipping-address [post]
 func ShippingAddressUpdate(c *gin.Context) {
 	s, err := stores.GetStores(c)
@@ -342,7 +348,7 @@ func UpdatePaymentStatus(c *gin.Context) {
 // @Param X-OS-Version header string true "With the os version"
 // @Param setMpinRequest body requests.MpinRequest true "Add mpin request"
 // @Success 200 {object} responses.MobileTeamSuccessResponseWithoutData "success response"
-// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request, a rejected mpin has an ERROR_CODE of MPIN_REPEATED_DIGITS, MPIN_SEQUENTIAL, MPIN_MATCHES_DOB, MPIN_MATCHES_MOBILE or MPIN_REUSED"
 // @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
 // @Router /api/open/set-mpin [post]
 func SetMpin(c *gin.Context) {
@@ -390,6 +396,10 @@ func SetMpin(c *gin.Context) {
 	}
 
 	if err := s.Open.SetMpin(c.Request.Context(), request, authValues); err != nil {
+		if mpinPolicyError(c, err) {
+			return
+		}
+
 		responses.StatusBadRequest(
 			c,
 			customerror.NewError(err),
@@ -419,6 +429,7 @@ func SetMpin(c *gin.Context) {
 // @Success 200 {object} responses.MobileTeamSuccessResponseWithoutData "success response"
 // @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
 // @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Failure 429 {object} responses.MobileTeamErrorResponse "Too many attempts, retry after the Retry-After header"
 // @Router /api/open/verify-mpin [post]
 func VerifyMpin(c *gin.Context) {
 	s, err := stores.GetStores(c)
@@ -492,8 +503,9 @@ func VerifyMpin(c *gin.Context) {
 // @Param X-OS-Version header string true "With the os version"
 // @Param resetMpinRequest body requests.ResetMpinRequest true "Add reset-mpin request"
 // @Success 200 {object} responses.MobileTeamSuccessResponseWithoutData "success response"
-// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request, a rejected mpin has an ERROR_CODE of MPIN_REPEATED_DIGITS, MPIN_SEQUENTIAL, MPIN_MATCHES_DOB, MPIN_MATCHES_MOBILE or MPIN_REUSED"
 // @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Failure 429 {object} responses.MobileTeamErrorResponse "Too many attempts, retry after the Retry-After header"
 // @Router /api/open/reset-mpin [post]
 func ReSetMpin(c *gin.Context) {
 	s, err := stores.GetStores(c)
@@ -536,6 +548,10 @@ func ReSetMpin(c *gin.Context) {
 	}
 
 	if err := s.Open.ResetMpin(c.Request.Context(), request, authValues); err != nil {
+		if mpinPolicyError(c, err) {
+			return
+		}
+
 		responses.StatusBadRequest(
 			c,
 			customerror.NewError(err),
@@ -565,9 +581,10 @@ func ReSetMpin(c *gin.Context) {
 // @Param X-OS header string true "With the OS"
 // @Param X-OS-Version header string true "With the OS version"
 // @Param forgotMpinRequest body requests.ForgotMpinResetRequest true "Add forgotten M-PIN reset request"
-// @Success 200 {object} responses.MobileTeamSuccessResponseWithoutData "success response"
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response, an otp is sent to the registered mobile number"
 // @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
 // @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Failure 429 {object} responses.MobileTeamErrorResponse "Too many attempts, retry after the Retry-After header"
 // @Router /api/open/verify-forgot-mpin [post]
 func verifyForgetMpinReset(c *gin.Context) {
 	s, err := stores.GetStores(c)
@@ -609,7 +626,8 @@ func verifyForgetMpinReset(c *gin.Context) {
 		return
 	}
 
-	if err := s.Open.VerifyForgottenMpinResetRequest(c.Request.Context(), request, authValues); err != nil {
+	result, err := s.Open.VerifyForgottenMpinResetRequest(c.Request.Context(), request, authValues)
+	if err != nil {
 		responses.StatusBadRequest(
 			c,
 			customerror.NewError(err),
@@ -620,7 +638,7 @@ func verifyForgetMpinReset(c *gin.Context) {
 
 	responses.StatusOk(
 		c,
-		nil,
+		result,
 		"User details have been successfully verified for resetting the forgotten M-PIN",
 		authValues.Key,
 	)
@@ -637,8 +655,9 @@ func verifyForgetMpinReset(c *gin.Context) {
 // @Param X-OS-Version header string true "With the OS version"
 // @Param UpdateMpinRequest body requests.UpdateMpinRequest true "Update M-PIN after reset request"
 // @Success 200 {object} responses.MobileTeamSuccessResponseWithoutData "success response"
-// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request, a rejected mpin has an ERROR_CODE of MPIN_REPEATED_DIGITS, MPIN_SEQUENTIAL, MPIN_MATCHES_DOB, MPIN_MATCHES_MOBILE or MPIN_REUSED"
 // @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Failure 429 {object} responses.MobileTeamErrorResponse "Too many attempts, retry after the Retry-After header"
 // @Router /api/open/update-mpin [post]
 func UpdateForgetMpinReset(c *gin.Context) {
 	s, err := stores.GetStores(c)
@@ -681,6 +700,10 @@ func UpdateForgetMpinReset(c *gin.Context) {
 	}
 
 	if err := s.Open.UpdateMpin(c.Request.Context(), request, authValues); err != nil {
+		if mpinPolicyError(c, err) {
+			return
+		}
+
 		responses.StatusBadRequest(
 			c,
 			customerror.NewError(err),
@@ -744,3 +767,146 @@ func UpdateFcmToken(c *gin.Context) {
 		"",
 	)
 }
+
+// @Summary Api to send a step-up otp
+// @Tags Open Bank apis
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Failure 429 {object} responses.MobileTeamErrorResponse "Too many attempts, retry after the Retry-After header"
+// @Router /api/open/step-up/otp [post]
+func SendStepUpOtp(c *gin.Context) {
+	s, err := stores.GetStores(c)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	authValues, err := stores.GetAuthValue(c)
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	data, err := s.Open.SendStepUpOtp(c.Request.Context(), authValues)
+	if err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			authValues.Key,
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		data,
+		"otp sent successfully",
+		authValues.Key,
+	)
+}
+
+// @Summary Api to verify a step-up otp
+// @Tags Open Bank apis
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param stepUpOtpRequest body requests.StepUpOtpRequest true "Add step-up otp request"
+// @Success 200 {object} responses.MobileTeamSuccessResponseWithoutData "success response"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Failure 429 {object} responses.MobileTeamErrorResponse "Too many attempts, retry after the Retry-After header"
+// @Router /api/open/step-up/verify-otp [post]
+func VerifyStepUpOtp(c *gin.Context) {
+	s, err := stores.GetStores(c)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	authValues, err := stores.GetAuthValue(c)
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	requestPayload, err := stores.GetRequestPayload(c)
+	if err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			authValues.Key,
+		)
+		return
+	}
+
+	request := requests.NewStepUpOtpRequest()
+	if err := request.Validate(requestPayload.Payload); err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			authValues.Key,
+		)
+		return
+	}
+
+	if err := s.Open.VerifyStepUpOtp(c.Request.Context(), request, authValues); err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			authValues.Key,
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		nil,
+		"successfully verified otp",
+		authValues.Key,
+	)
+}
+
+// mpinPolicyError responds with the error code of a rejected mpin so the app
+// can tell the user which rule it broke, it reports whether err was one
+func mpinPolicyError(c *gin.Context, err error) bool {
+	var policyErr *security.MpinPolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+
+	c.JSON(
+		http.StatusBadRequest,
+		gin.H{
+			"status":     http.StatusBadRequest,
+			"message":    policyErr.Message,
+			"ERROR_CODE": policyErr.Code,
+			"error":      customerror.NewError(policyErr),
+		},
+	)
+	return true
+}
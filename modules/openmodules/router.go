@@ -3,6 +3,7 @@ package openmodules
 import (
 	"github.com/gin-gonic/gin"
 
+	"bankapi/config"
 	"bankapi/middleware"
 
 	responseMiddleware "bitbucket.org/paydoh/paydoh-commons/middleware"
@@ -17,24 +18,27 @@ func Routes(api *gin.RouterGroup) {
 		open.GET("/state", GetStates)
 		open.GET("/cities/:state", GetCities)
 
-		open.POST("/shipping-address", UpdateShippingAddress)
+		open.POST("/shipping-address", middleware.StepUpMiddleware(config.StepUpProfile), UpdateShippingAddress)
 		open.GET("/shipping-address", GetShippingAddress)
-		open.POST("/update-shipping-address", ShippingAddressUpdate)
+		open.POST("/update-shipping-address", middleware.StepUpMiddleware(config.StepUpProfile), ShippingAddressUpdate)
 
 		open.POST("/get-receipt-id", middleware.DecryptMiddleware(), responseMiddleware.ResponseEncryptionMiddleware(), GetReceiptId)
 		open.POST("/payment-status", middleware.DecryptMiddleware(), responseMiddleware.ResponseEncryptionMiddleware(), UpdatePaymentStatus)
 		open.GET("/payment-status", responseMiddleware.ResponseEncryptionMiddleware(), GetPaymentStatus)
 
 		open.POST("/set-mpin", middleware.DecryptMiddleware(), responseMiddleware.ResponseEncryptionMiddleware(), SetMpin)
-		open.POST("/verify-mpin", middleware.DecryptMiddleware(), responseMiddleware.ResponseEncryptionMiddleware(), VerifyMpin)
-		open.POST("/reset-mpin", middleware.DecryptMiddleware(), responseMiddleware.ResponseEncryptionMiddleware(), ReSetMpin)
-		open.POST("/verify-forgot-mpin", middleware.DecryptMiddleware(), responseMiddleware.ResponseEncryptionMiddleware(), verifyForgetMpinReset)
-		open.POST("/update-mpin", middleware.DecryptMiddleware(), responseMiddleware.ResponseEncryptionMiddleware(), UpdateForgetMpinReset)
+		open.POST("/verify-mpin", middleware.RateLimitMiddleware(config.RateLimitMpin), middleware.DecryptMiddleware(), responseMiddleware.ResponseEncryptionMiddleware(), VerifyMpin)
+		open.POST("/reset-mpin", middleware.RateLimitMiddleware(config.RateLimitMpin), middleware.DecryptMiddleware(), responseMiddleware.ResponseEncryptionMiddleware(), ReSetMpin)
+		open.POST("/verify-forgot-mpin", middleware.RateLimitMiddleware(config.RateLimitMpin), middleware.DecryptMiddleware(), responseMiddleware.ResponseEncryptionMiddleware(), verifyForgetMpinReset)
+		open.POST("/update-mpin", middleware.RateLimitMiddleware(config.RateLimitOtp), middleware.DecryptMiddleware(), responseMiddleware.ResponseEncryptionMiddleware(), UpdateForgetMpinReset)
+
+		open.POST("/step-up/otp", middleware.RateLimitMiddleware(config.RateLimitOtp), responseMiddleware.ResponseEncryptionMiddleware(), SendStepUpOtp)
+		open.POST("/step-up/verify-otp", middleware.RateLimitMiddleware(config.RateLimitOtp), middleware.DecryptMiddleware(), responseMiddleware.ResponseEncryptionMiddleware(), VerifyStepUpOtp)
 
 		open.GET("/ifsc-data/:bank", GetIfscData)
 		open.GET("/ifsc-data/banks", GetBanks)
 		open.GET("/ifsc-data/banks/:ifsc", GetIfscDataByIfscCode)
 
-		open.POST("/update-fcm-token", UpdateFcmToken)
+		open.POST("/update-fcm-token", middleware.StepUpMiddleware(config.StepUpDeviceBound), UpdateFcmToken)
 	}
 }
@@ -1,12 +1,12 @@
 package payment_callback
 
 import (
+	"bankapi/config"
 	"bankapi/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
 func Routes(app *gin.RouterGroup) {
-	app.Use(middleware.CallbackMiddleware())
-	app.POST("/normal-payment", PaymentCallBackAPI)
+	app.POST("/normal-payment", middleware.CallbackMiddleware(config.CallbackPartnerPayment), PaymentCallBackAPI)
 }
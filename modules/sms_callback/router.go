@@ -1,9 +1,12 @@
 package sms_callback
 
 import (
+	"bankapi/config"
+	"bankapi/middleware"
+
 	"github.com/gin-gonic/gin"
 )
 
 func Routes(app *gin.RouterGroup) {
-	app.GET("/sms-received", SmsReceivedCallback)
+	app.GET("/sms-received", middleware.CallbackMiddleware(config.CallbackPartnerSms), SmsReceivedCallback)
 }
@@ -0,0 +1,76 @@
+package standinginstructionmodule
+
+import (
+	"errors"
+
+	"bitbucket.org/paydoh/paydoh-commons/customerror"
+	"bitbucket.org/paydoh/paydoh-commons/responses"
+	"github.com/gin-gonic/gin"
+
+	"bankapi/constants"
+	"bankapi/stores"
+)
+
+// CancelStandingInstruction godoc
+// @Summary Cancel a standing instruction
+// @Description Stops the runs of a standing instruction for good, its history is kept
+// @Tags standing instruction apis
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-ID header string true "With the device id"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param X-Lat-Long header string true "With the lat long"
+// @Param id path string true "Standing instruction id"
+// @Success 200 {object} responses.MobileTeamSuccessResponseWithoutData "success response"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 404 {object} responses.MobileTeamErrorResponse "Error response for Not Found"
+// @Router /api/standing-instructions/{id} [delete]
+func CancelStandingInstruction(c *gin.Context) {
+	authValues, err := stores.GetAuthValue(c)
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	store, err := stores.GetStores(c)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	if err := store.StandingInstruction.CancelStandingInstruction(c.Request.Context(), authValues, c.Param("id")); err != nil {
+		if errors.Is(err, constants.ErrNoDataFound) {
+			responses.StatusNotFound(
+				c,
+				customerror.NewError(errors.New("standing instruction not found")),
+				"",
+			)
+			return
+		}
+
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		nil,
+		"successfully cancelled standing instruction",
+		"",
+	)
+}
@@ -0,0 +1,298 @@
+package standinginstructionmodule
+
+import (
+	"context"
+	"errors"
+
+	"bitbucket.org/paydoh/paydoh-commons/customerror"
+	"bitbucket.org/paydoh/paydoh-commons/responses"
+	"github.com/gin-gonic/gin"
+
+	"bankapi/constants"
+	"bankapi/models"
+	"bankapi/requests"
+	"bankapi/stores"
+)
+
+// CreateStandingInstruction godoc
+// @Summary Schedule a transfer
+// @Description Schedules a transfer to a verified beneficiary, once on start_date or every WEEKLY or MONTHLY from it until end_date. A reminder is sent before every run and the user confirms each payment with the otp the bank sends. Needs a recent mpin verification.
+// @Tags standing instruction apis
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-ID header string true "With the device id"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param X-Lat-Long header string true "With the lat long"
+// @Param encryptedRequest body requests.EncryptedRequest true "Encrypted requests.StandingInstructionRequest"
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 403 {object} responses.MobileTeamErrorResponse "Step-up required, verify the factors in data and retry"
+// @Router /api/standing-instructions [post]
+func CreateStandingInstruction(c *gin.Context) {
+	authValues, err := stores.GetAuthValue(c)
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	store, err := stores.GetStores(c)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	requestPayload, err := stores.GetRequestPayload(c)
+	if err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			authValues.Key,
+		)
+		return
+	}
+
+	request := requests.NewStandingInstructionRequest()
+	if err := request.Validate(requestPayload.Payload); err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			authValues.Key,
+		)
+		return
+	}
+
+	standingInstruction, err := store.StandingInstruction.CreateStandingInstruction(c.Request.Context(), authValues, request)
+	if err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			authValues.Key,
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		standingInstruction,
+		"successfully scheduled transfer",
+		authValues.Key,
+	)
+}
+
+// PauseStandingInstruction godoc
+// @Summary Pause a standing instruction
+// @Description Stops the runs of a standing instruction until it is resumed
+// @Tags standing instruction apis
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-ID header string true "With the device id"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param X-Lat-Long header string true "With the lat long"
+// @Param id path string true "Standing instruction id"
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 404 {object} responses.MobileTeamErrorResponse "Error response for Not Found"
+// @Router /api/standing-instructions/{id}/pause [post]
+func PauseStandingInstruction(c *gin.Context) {
+	updateStandingInstruction(c, "successfully paused standing instruction", func(store *stores.Stores, ctx context.Context, authValues *models.AuthValues, id string) (*models.StandingInstruction, error) {
+		return store.StandingInstruction.PauseStandingInstruction(ctx, authValues, id)
+	})
+}
+
+// ResumeStandingInstruction godoc
+// @Summary Resume a standing instruction
+// @Description Schedules the runs of a paused standing instruction again from now, runs missed while paused are not made. Needs a recent mpin verification.
+// @Tags standing instruction apis
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-ID header string true "With the device id"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param X-Lat-Long header string true "With the lat long"
+// @Param id path string true "Standing instruction id"
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 403 {object} responses.MobileTeamErrorResponse "Step-up required, verify the factors in data and retry"
+// @Failure 404 {object} responses.MobileTeamErrorResponse "Error response for Not Found"
+// @Router /api/standing-instructions/{id}/resume [post]
+func ResumeStandingInstruction(c *gin.Context) {
+	updateStandingInstruction(c, "successfully resumed standing instruction", func(store *stores.Stores, ctx context.Context, authValues *models.AuthValues, id string) (*models.StandingInstruction, error) {
+		return store.StandingInstruction.ResumeStandingInstruction(ctx, authValues, id)
+	})
+}
+
+// SkipStandingInstruction godoc
+// @Summary Skip the next run of a standing instruction
+// @Description Skips the next run of a standing instruction, the runs after it are made
+// @Tags standing instruction apis
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-ID header string true "With the device id"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param X-Lat-Long header string true "With the lat long"
+// @Param id path string true "Standing instruction id"
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 404 {object} responses.MobileTeamErrorResponse "Error response for Not Found"
+// @Router /api/standing-instructions/{id}/skip [post]
+func SkipStandingInstruction(c *gin.Context) {
+	updateStandingInstruction(c, "successfully skipped the next transfer", func(store *stores.Stores, ctx context.Context, authValues *models.AuthValues, id string) (*models.StandingInstruction, error) {
+		return store.StandingInstruction.SkipStandingInstruction(ctx, authValues, id)
+	})
+}
+
+func updateStandingInstruction(c *gin.Context, message string, update func(store *stores.Stores, ctx context.Context, authValues *models.AuthValues, id string) (*models.StandingInstruction, error)) {
+	authValues, err := stores.GetAuthValue(c)
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	store, err := stores.GetStores(c)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	standingInstruction, err := update(store, c.Request.Context(), authValues, c.Param("id"))
+	if err != nil {
+		if errors.Is(err, constants.ErrNoDataFound) {
+			responses.StatusNotFound(
+				c,
+				customerror.NewError(errors.New("standing instruction not found")),
+				"",
+			)
+			return
+		}
+
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		standingInstruction,
+		message,
+		"",
+	)
+}
+
+// ConfirmStandingInstructionExecution godoc
+// @Summary Confirm a scheduled transfer
+// @Description Confirms the payment of a run waiting for the otp the bank sent, a wrong otp keeps it waiting
+// @Tags standing instruction apis
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "With the bearer started"
+// @Param X-Device-ID header string true "With the device id"
+// @Param X-Device-Ip header string true "With the device ip"
+// @Param X-OS header string true "With the os"
+// @Param X-OS-Version header string true "With the os version"
+// @Param X-Lat-Long header string true "With the lat long"
+// @Param id path string true "Standing instruction id"
+// @Param execution_id path string true "Execution id"
+// @Param encryptedRequest body requests.EncryptedRequest true "Encrypted requests.StandingInstructionOtpRequest"
+// @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
+// @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
+// @Failure 404 {object} responses.MobileTeamErrorResponse "Error response for Not Found"
+// @Router /api/standing-instructions/{id}/executions/{execution_id}/confirm [post]
+func ConfirmStandingInstructionExecution(c *gin.Context) {
+	authValues, err := stores.GetAuthValue(c)
+	if err != nil {
+		responses.StatusUnauthorized(
+			c,
+			customerror.NewError(err),
+		)
+		return
+	}
+
+	store, err := stores.GetStores(c)
+	if err != nil {
+		responses.StatusInternalServerError(
+			c,
+			customerror.NewError(err),
+			"",
+		)
+		return
+	}
+
+	requestPayload, err := stores.GetRequestPayload(c)
+	if err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			authValues.Key,
+		)
+		return
+	}
+
+	request := requests.NewStandingInstructionOtpRequest()
+	if err := request.Validate(requestPayload.Payload); err != nil {
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			authValues.Key,
+		)
+		return
+	}
+
+	execution, err := store.StandingInstruction.ConfirmExecution(c.Request.Context(), authValues, c.Param("id"), c.Param("execution_id"), request.Otp)
+	if err != nil {
+		if errors.Is(err, constants.ErrNoDataFound) {
+			responses.StatusNotFound(
+				c,
+				customerror.NewError(errors.New("standing instruction execution not found")),
+				authValues.Key,
+			)
+			return
+		}
+
+		responses.StatusBadRequest(
+			c,
+			customerror.NewError(err),
+			authValues.Key,
+		)
+		return
+	}
+
+	responses.StatusOk(
+		c,
+		execution,
+		"successfully confirmed scheduled transfer",
+		authValues.Key,
+	)
+}
@@ -0,0 +1,27 @@
+package standinginstructionmodule
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"bankapi/config"
+	"bankapi/middleware"
+
+	responseMiddleware "bitbucket.org/paydoh/paydoh-commons/middleware"
+)
+
+func Routes(app *gin.RouterGroup) {
+	standingInstruction := app.Group("/standing-instructions")
+	standingInstruction.Use(middleware.AuthMiddleware())
+	{
+		standingInstruction.GET("", GetStandingInstructions)
+		standingInstruction.POST("", middleware.StepUpMiddleware(config.StepUpStandingInstruction), middleware.DecryptMiddleware(), responseMiddleware.ResponseEncryptionMiddleware(), CreateStandingInstruction)
+		standingInstruction.DELETE("/:id", CancelStandingInstruction)
+
+		standingInstruction.POST("/:id/pause", PauseStandingInstruction)
+		standingInstruction.POST("/:id/resume", middleware.StepUpMiddleware(config.StepUpStandingInstruction), ResumeStandingInstruction)
+		standingInstruction.POST("/:id/skip", SkipStandingInstruction)
+
+		standingInstruction.GET("/:id/executions", GetStandingInstructionExecutions)
+		standingInstruction.POST("/:id/executions/:execution_id/confirm", middleware.RateLimitMiddleware(config.RateLimitOtp), middleware.DecryptMiddleware(), responseMiddleware.ResponseEncryptionMiddleware(), ConfirmStandingInstructionExecution)
+	}
+}
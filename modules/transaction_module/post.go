@@ -1,6 +1,7 @@
 package transaction_module
 
 import (
+	"bankapi/modules/bank_errors"
 	"bankapi/requests"
 	"bankapi/stores"
 
@@ -69,6 +70,10 @@ func Transactions(c *gin.Context) {
 	request.UserId = authValues.UserId
 	responseData, err := store.TransactionHistory.GetTransactionData(c.Request.Context(), *request)
 	if err != nil {
+		if bank_errors.StatusBankError(c, err) {
+			return
+		}
+
 		responses.StatusInternalServerError(
 			c,
 			customerror.NewError(err),
@@ -124,6 +129,10 @@ func GetInternalTransactions(c *gin.Context) {
 
 	responseData, err := store.TransactionHistory.GetTransactionData(c.Request.Context(), *request)
 	if err != nil {
+		if bank_errors.StatusBankError(c, err) {
+			return
+		}
+
 		responses.StatusInternalServerError(
 			c,
 			customerror.NewError(err),
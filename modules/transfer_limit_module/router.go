@@ -0,0 +1,19 @@
+package transferlimitmodule
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"bankapi/config"
+	"bankapi/middleware"
+
+	responseMiddleware "bitbucket.org/paydoh/paydoh-commons/middleware"
+)
+
+func Routes(app *gin.RouterGroup) {
+	transferLimit := app.Group("/transfer-limits")
+	transferLimit.Use(middleware.AuthMiddleware())
+	{
+		transferLimit.GET("", GetTransferLimits)
+		transferLimit.POST("", middleware.StepUpMiddleware(config.StepUpTransferLimit), middleware.DecryptMiddleware(), responseMiddleware.ResponseEncryptionMiddleware(), SetTransferLimit)
+	}
+}
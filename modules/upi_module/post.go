@@ -5,6 +5,7 @@ import (
 	"bitbucket.org/paydoh/paydoh-commons/responses"
 	"github.com/gin-gonic/gin"
 
+	"bankapi/modules/bank_errors"
 	"bankapi/requests"
 	"bankapi/stores"
 )
@@ -70,6 +71,10 @@ func CreateUpiId(c *gin.Context) {
 	result, err := s.Upi.CreateUpiID(c.Request.Context(), authValues, request)
 
 	if err != nil {
+		if bank_errors.StatusBankError(c, err) {
+			return
+		}
+
 		// delete user id
 		// models.DeleteClientIDByUserId(authValues.UserId)
 
@@ -253,6 +258,7 @@ func SetUpiPin(c *gin.Context) {
 // @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
 // @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
 // @Failure 500 {object} responses.MobileTeamErrorResponse "Error response for Internal Server Error"
+// @Failure 429 {object} responses.MobileTeamErrorResponse "Too many attempts, retry after the Retry-After header"
 // @Router /api/upi/pay-val-vpa [Post]
 func ValidateVpaPayment(c *gin.Context) {
 	s, err := stores.GetStores(c)
@@ -325,6 +331,7 @@ func ValidateVpaPayment(c *gin.Context) {
 // @Param X-OS header string true "With the os"
 // @Param X-OS-Version header string true "With the os version"
 // @Param X-Lat-Long header string true "With the lat long"
+// @Param Idempotency-Key header string true "Unique key of the payment, repeats return the first outcome"
 // @Param encryptedRequest body requests.EncryptedRequest true "Encrypted Request"
 // @Success 200 {object} responses.MobileTeamSuccessResponse "success response"
 // @Failure 400 {object} responses.MobileTeamErrorResponse "Error response for Bad Request"
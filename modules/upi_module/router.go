@@ -3,6 +3,7 @@ package upi_module
 import (
 	"github.com/gin-gonic/gin"
 
+	"bankapi/config"
 	"bankapi/middleware"
 )
 
@@ -16,8 +17,8 @@ func Routes(app *gin.RouterGroup) {
 		upi.POST("/remapping-upi-id", RemapUpiId)
 		upi.POST("/aadhar-verification", UsersReqlistaccount)
 		upi.POST("/set-upi-pin", SetUpiPin)
-		upi.POST("/pay-val-vpa", ValidateVpaPayment)
-		upi.POST("/pay-vpa", PayWithVpa)
+		upi.POST("/pay-val-vpa", middleware.RateLimitMiddleware(config.RateLimitVpaValidation), ValidateVpaPayment)
+		upi.POST("/pay-vpa", middleware.IdempotencyMiddleware(), PayWithVpa)
 		upi.POST("/account-balance", GetAccountBalance)
 		upi.POST("/payeename", GetPayeeName)
 		upi.POST("/get-upi-token", GetUpiToken)
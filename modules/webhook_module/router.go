@@ -1,6 +1,7 @@
 package webhookmodule
 
 import (
+	"bankapi/config"
 	"bankapi/middleware"
 
 	"github.com/gin-gonic/gin"
@@ -15,7 +16,7 @@ func Routes(c *gin.RouterGroup) {
 	}
 
 	{
-		webhookmodule.POST("/rewards-point", middleware.CallbackMiddleware(), ProvideRewardPoint)
+		webhookmodule.POST("/rewards-point", middleware.CallbackMiddleware(config.CallbackPartnerRewards), ProvideRewardPoint)
 
 	}
 }
@@ -11,6 +11,13 @@ import (
 type AuthorizationRequest struct {
 	MobileNumber string `json:"mobile_number,omitempty" validate:"required,numeric,min=10,max=10"`
 	UserId       string `json:"user_id,omitempty"`
+	// ClientPublicKey is the base64 DER (PKIX) P-256 public key of the app
+	// for the ECDH exchange of the session key
+	ClientPublicKey string `json:"client_public_key,omitempty" validate:"max=512"`
+	// ChallengeId and Signature are a login challenge signed by the device
+	// key, a login with user_id from a device with a key needs them
+	ChallengeId string `json:"challenge_id,omitempty" validate:"max=64"`
+	Signature   string `json:"signature,omitempty" validate:"max=512"`
 }
 
 func NewAuthorizationRequest() *AuthorizationRequest {
@@ -32,3 +39,15 @@ func (request *AuthorizationRequest) Validate(c *gin.Context) error {
 func (request *AuthorizationRequest) ToJSON() ([]byte, error) {
 	return json.Marshal(request)
 }
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required,max=128"`
+}
+
+func NewRefreshTokenRequest() *RefreshTokenRequest {
+	return &RefreshTokenRequest{}
+}
+
+func (request *RefreshTokenRequest) Validate(c *gin.Context) error {
+	return customvalidation.ValidatePayload(c, request)
+}
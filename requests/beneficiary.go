@@ -44,6 +44,9 @@ type PaymentRequest struct {
 	RetryFlag     string `json:"retry_flag" validate:"required"`
 	Otp           string `json:"otp"`
 	QuickTransfer string `json:"quick_transfer" validate:"required"`
+	// TxnIdentifier sends the payment again with the identifier of an earlier
+	// attempt, a new one is made when it is empty
+	TxnIdentifier string `json:"-"`
 }
 
 type QuickTransferBeneficiaryRegistrationRequest struct {
@@ -209,3 +212,61 @@ func (r *CheckPaymentStatus) Marshal() ([]byte, error) {
 func (r *CheckPaymentStatus) Unmarshal(data []byte) error {
 	return json.Unmarshal(data, r)
 }
+
+type UpdateBeneficiaryNicknameRequest struct {
+	BenfId       string `json:"beneficiary_id" validate:"required"`
+	BenfNickName string `json:"beneficiary_nickname" validate:"required,max=100"`
+}
+
+func NewUpdateBeneficiaryNicknameRequest() *UpdateBeneficiaryNicknameRequest {
+	return &UpdateBeneficiaryNicknameRequest{}
+}
+
+func (r *UpdateBeneficiaryNicknameRequest) Validate(payload string) error {
+	if err := r.Unmarshal([]byte(payload)); err != nil {
+		return err
+	}
+
+	if err := customvalidation.ValidateStruct(r); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *UpdateBeneficiaryNicknameRequest) Marshal() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *UpdateBeneficiaryNicknameRequest) Unmarshal(data []byte) error {
+	return json.Unmarshal(data, r)
+}
+
+// BeneficiaryRequest names the beneficiary to disable, enable or delete
+type BeneficiaryRequest struct {
+	BenfId string `json:"beneficiary_id" validate:"required"`
+}
+
+func NewBeneficiaryRequest() *BeneficiaryRequest {
+	return &BeneficiaryRequest{}
+}
+
+func (r *BeneficiaryRequest) Validate(payload string) error {
+	if err := r.Unmarshal([]byte(payload)); err != nil {
+		return err
+	}
+
+	if err := customvalidation.ValidateStruct(r); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *BeneficiaryRequest) Marshal() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *BeneficiaryRequest) Unmarshal(data []byte) error {
+	return json.Unmarshal(data, r)
+}
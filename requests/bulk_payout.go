@@ -0,0 +1,247 @@
+package requests
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"bitbucket.org/paydoh/paydoh-commons/customvalidation"
+	"github.com/xuri/excelize/v2"
+
+	"bankapi/constants"
+)
+
+// File types of a bulk payout upload
+const (
+	BulkPayoutFileCsv  = "csv"
+	BulkPayoutFileXlsx = "xlsx"
+)
+
+const defaultBulkPayoutRemarks = "Bulk payout"
+
+var (
+	ErrBulkPayoutFile = errors.New(constants.BulkPayoutFileErrorMessage)
+
+	bulkPayoutIfscRegex    = regexp.MustCompile(`^[A-Z]{4}0[A-Z0-9]{6}$`)
+	bulkPayoutAccountRegex = regexp.MustCompile(`^\d{12,28}$`)
+	bulkPayoutAmountRegex  = regexp.MustCompile(`^\d+(\.\d{1,2})?$`)
+)
+
+// bulkPayoutColumns maps the headers a file may use, without case, spaces or
+// punctuation, to its columns
+var bulkPayoutColumns = map[string]string{
+	"beneficiaryname": "name",
+	"name":            "name",
+	"accountnumber":   "account",
+	"accountno":       "account",
+	"account":         "account",
+	"ifsc":            "ifsc",
+	"ifsccode":        "ifsc",
+	"amount":          "amount",
+	"remarks":         "remarks",
+	"accounttype":     "account_type",
+}
+
+// BulkPayoutRow is a payout of a bulk payout file, RowNumber is its line in
+// the file. Account type is an optional column, SA when not set.
+type BulkPayoutRow struct {
+	RowNumber    int
+	BenfName     string
+	BenfAcctNo   string
+	BenfIfsc     string
+	BenfAcctType string
+	Amount       string
+	Remarks      string
+}
+
+// BulkPayoutOtpRequest is the otp the bank sent for a payout of a bulk payout
+type BulkPayoutOtpRequest struct {
+	Otp string `json:"otp" validate:"required,numeric"`
+}
+
+func NewBulkPayoutOtpRequest() *BulkPayoutOtpRequest {
+	return &BulkPayoutOtpRequest{}
+}
+
+func (r *BulkPayoutOtpRequest) Validate(payload string) error {
+	if err := json.Unmarshal([]byte(payload), r); err != nil {
+		return err
+	}
+
+	if err := customvalidation.ValidateStruct(r); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ParseBulkPayoutFile reads the payouts of a CSV or XLSX file, the type is
+// taken from the file name. The first row is the header, empty rows are left out.
+func ParseBulkPayoutFile(fileName string, data []byte) (string, []BulkPayoutRow, error) {
+	var (
+		fileType = strings.TrimPrefix(strings.ToLower(filepath.Ext(fileName)), ".")
+		records  [][]string
+		err      error
+	)
+
+	switch fileType {
+	case BulkPayoutFileCsv:
+		records, err = readBulkPayoutCsv(data)
+	case BulkPayoutFileXlsx:
+		records, err = readBulkPayoutXlsx(data)
+	default:
+		return "", nil, ErrBulkPayoutFile
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(records) == 0 {
+		return "", nil, ErrBulkPayoutFile
+	}
+
+	columns := make(map[string]int)
+	for i, header := range records[0] {
+		if column, ok := bulkPayoutColumns[normalizeBulkPayoutHeader(header)]; ok {
+			if _, exists := columns[column]; !exists {
+				columns[column] = i
+			}
+		}
+	}
+	for _, column := range []string{"name", "account", "ifsc", "amount"} {
+		if _, ok := columns[column]; !ok {
+			return "", nil, ErrBulkPayoutFile
+		}
+	}
+
+	cell := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	rows := make([]BulkPayoutRow, 0, len(records)-1)
+	for i, record := range records[1:] {
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		rows = append(rows, BulkPayoutRow{
+			RowNumber:    i + 2,
+			BenfName:     cell(record, "name"),
+			BenfAcctNo:   strings.ReplaceAll(cell(record, "account"), " ", ""),
+			BenfIfsc:     strings.ToUpper(cell(record, "ifsc")),
+			BenfAcctType: strings.ToUpper(cell(record, "account_type")),
+			Amount:       strings.ReplaceAll(cell(record, "amount"), ",", ""),
+			Remarks:      cell(record, "remarks"),
+		})
+	}
+
+	return fileType, rows, nil
+}
+
+// Validate checks the fields of a row and normalizes its amount to two
+// decimals, the error is the message shown for the row. The ifsc is checked
+// against the ifsc data by the store.
+func (r *BulkPayoutRow) Validate() error {
+	if r.BenfName == "" {
+		return errors.New("beneficiary name is required")
+	}
+	if utf8.RuneCountInString(r.BenfName) > 100 {
+		return errors.New("beneficiary name must be at most 100 characters")
+	}
+
+	if !bulkPayoutAccountRegex.MatchString(r.BenfAcctNo) {
+		return errors.New("account number must be 12 to 28 digits")
+	}
+
+	if !bulkPayoutIfscRegex.MatchString(r.BenfIfsc) {
+		return errors.New("ifsc must be 11 characters like ABCD0123456")
+	}
+
+	switch r.BenfAcctType {
+	case "":
+		r.BenfAcctType = "SA"
+	case "SA", "CA":
+	default:
+		return errors.New("account type must be SA or CA")
+	}
+
+	if !bulkPayoutAmountRegex.MatchString(r.Amount) {
+		return errors.New("amount must be a number with at most 2 decimals")
+	}
+	amount, err := strconv.ParseFloat(r.Amount, 64)
+	if err != nil || amount <= 0 {
+		return errors.New("amount must be greater than 0")
+	}
+	r.Amount = strconv.FormatFloat(amount, 'f', 2, 64)
+
+	if r.Remarks == "" {
+		r.Remarks = defaultBulkPayoutRemarks
+	}
+	if utf8.RuneCountInString(r.Remarks) > 100 {
+		return errors.New("remarks must be at most 100 characters")
+	}
+
+	return nil
+}
+
+func readBulkPayoutCsv(data []byte) ([][]string, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var records [][]string
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrBulkPayoutFile, err.Error())
+		}
+		records = append(records, record)
+	}
+}
+
+// readBulkPayoutXlsx reads the first sheet, with raw cell values so long
+// account numbers are not formatted as exponents
+func readBulkPayoutXlsx(data []byte) ([][]string, error) {
+	file, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrBulkPayoutFile, err.Error())
+	}
+	defer file.Close()
+
+	sheets := file.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, ErrBulkPayoutFile
+	}
+
+	records, err := file.GetRows(sheets[0], excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrBulkPayoutFile, err.Error())
+	}
+
+	return records, nil
+}
+
+func normalizeBulkPayoutHeader(header string) string {
+	var normalized strings.Builder
+	for _, r := range strings.ToLower(header) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			normalized.WriteRune(r)
+		}
+	}
+	return normalized.String()
+}
@@ -0,0 +1,39 @@
+package requests
+
+import (
+	"encoding/json"
+	"errors"
+
+	"bitbucket.org/paydoh/paydoh-commons/customvalidation"
+)
+
+type DeviceChangeVerifyRequest struct {
+	Otp  string `json:"otp" validate:"required,numeric"`
+	Mpin string `json:"mpin" validate:"required"`
+}
+
+func NewDeviceChangeVerifyRequest() *DeviceChangeVerifyRequest {
+	return &DeviceChangeVerifyRequest{}
+}
+
+func (r *DeviceChangeVerifyRequest) Validate(payload string) error {
+	if err := json.Unmarshal([]byte(payload), r); err != nil {
+		return err
+	}
+
+	if err := customvalidation.ValidateStruct(r); err != nil {
+		return err
+	}
+	if len(r.Mpin) != 4 {
+		return errors.New("Mpin must be 4 digits")
+	}
+
+	return nil
+}
+
+type SendOtpRequest struct {
+	MobileNumber string `json:"mobile_number"`
+	Otp          string `json:"otp"`
+	Purpose      string `json:"purpose"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
@@ -0,0 +1,66 @@
+package requests
+
+import (
+	"encoding/json"
+
+	"bitbucket.org/paydoh/paydoh-commons/customvalidation"
+	"github.com/gin-gonic/gin"
+)
+
+type RegisterDeviceKeyRequest struct {
+	// PublicKey is the base64 DER (PKIX) public key
+	PublicKey string `json:"public_key" validate:"required,max=1024"`
+	Algorithm string `json:"algorithm" validate:"required,oneof=ES256 Ed25519"`
+}
+
+func NewRegisterDeviceKeyRequest() *RegisterDeviceKeyRequest {
+	return &RegisterDeviceKeyRequest{}
+}
+
+func (r *RegisterDeviceKeyRequest) Validate(payload string) error {
+	if err := json.Unmarshal([]byte(payload), r); err != nil {
+		return err
+	}
+
+	return customvalidation.ValidateStruct(r)
+}
+
+type DeviceKeyLoginChallengeRequest struct {
+	MobileNumber string `json:"mobile_number" validate:"required,numeric,min=10,max=10"`
+}
+
+func NewDeviceKeyLoginChallengeRequest() *DeviceKeyLoginChallengeRequest {
+	return &DeviceKeyLoginChallengeRequest{}
+}
+
+func (r *DeviceKeyLoginChallengeRequest) Validate(c *gin.Context) error {
+	return customvalidation.ValidatePayload(c, r)
+}
+
+type DeviceKeySignatureRequest struct {
+	ChallengeId string `json:"challenge_id" validate:"required,max=64"`
+	// Signature is the base64 signature of the challenge
+	Signature string `json:"signature" validate:"required,max=512"`
+}
+
+func NewDeviceKeySignatureRequest() *DeviceKeySignatureRequest {
+	return &DeviceKeySignatureRequest{}
+}
+
+func (r *DeviceKeySignatureRequest) Validate(c *gin.Context) error {
+	return customvalidation.ValidatePayload(c, r)
+}
+
+type DeviceKeyLoginRequest struct {
+	DeviceKeySignatureRequest
+	// ClientPublicKey is the ECDH public key of the session key exchange
+	ClientPublicKey string `json:"client_public_key,omitempty" validate:"max=512"`
+}
+
+func NewDeviceKeyLoginRequest() *DeviceKeyLoginRequest {
+	return &DeviceKeyLoginRequest{}
+}
+
+func (r *DeviceKeyLoginRequest) Validate(c *gin.Context) error {
+	return customvalidation.ValidatePayload(c, r)
+}
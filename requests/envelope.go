@@ -0,0 +1,47 @@
+package requests
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// PayloadEnvelope is the decrypted data of an encrypted request from payload
+// version 2 on. The timestamp and nonce are encrypted with the payload, so a
+// captured request cannot be replayed with fresh ones.
+type PayloadEnvelope struct {
+	Timestamp int64           `json:"timestamp"`
+	Nonce     string          `json:"nonce"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+func NewPayloadEnvelope() *PayloadEnvelope {
+	return &PayloadEnvelope{}
+}
+
+func (e *PayloadEnvelope) Unmarshal(decrypted string) error {
+	if err := json.Unmarshal([]byte(decrypted), e); err != nil {
+		return errors.New("invalid request envelope")
+	}
+
+	if e.Timestamp == 0 || strings.TrimSpace(e.Nonce) == "" || len(e.Payload) == 0 {
+		return errors.New("request envelope must have a timestamp, nonce and payload")
+	}
+
+	return nil
+}
+
+// Data returns the payload as the handlers read it, a JSON string payload is
+// unquoted for clients that encode the request before wrapping it
+func (e *PayloadEnvelope) Data() (string, error) {
+	if e.Payload[0] != '"' {
+		return string(e.Payload), nil
+	}
+
+	var data string
+	if err := json.Unmarshal(e.Payload, &data); err != nil {
+		return "", err
+	}
+
+	return data, nil
+}
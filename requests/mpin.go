@@ -85,6 +85,8 @@ func (r *ForgotMpinResetRequest) Validate(payload string) error {
 
 type UpdateMpinRequest struct {
 	Mpin string `json:"mpin" validate:"required"`
+	// Otp is sent by the forgot mpin verification
+	Otp string `json:"otp" validate:"required,numeric"`
 }
 
 func NewUpdateMpinRequest() *UpdateMpinRequest {
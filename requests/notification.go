@@ -77,3 +77,9 @@ func (request *NotificationRequest) CreateNotificationPayload(
 
 	return nil
 }
+
+type SendSmsRequest struct {
+	MobileNumber string `json:"mobile_number"`
+	Message      string `json:"message"`
+	Purpose      string `json:"purpose"`
+}
@@ -694,9 +694,12 @@ func (r *OutgoingBeneficiaryOtpRequest) Bind(applicantId string, accountNumber s
 }
 
 func (r *OutgoingPaymentRequest) Bind(applicantId, accountNumber string, request *PaymentRequest) error {
-	transactionId, err := security.GenerateRandomUUID(15)
-	if err != nil {
-		return err
+	transactionId := request.TxnIdentifier
+	if transactionId == "" {
+		var err error
+		if transactionId, err = security.GenerateRandomUUID(15); err != nil {
+			return err
+		}
 	}
 
 	r.ApplicantId = applicantId
@@ -0,0 +1,62 @@
+package requests
+
+import (
+	"encoding/json"
+
+	"bitbucket.org/paydoh/paydoh-commons/customvalidation"
+)
+
+// StandingInstructionRequest schedules a transfer to a verified beneficiary,
+// once on start_date or every week or month from it. Monthly transfers run on
+// day_of_month, the day of start_date when it is not set.
+type StandingInstructionRequest struct {
+	PaymentMode  string `json:"payment_mode" validate:"required,oneof=NEFT IMPS IFT"`
+	BenfId       string `json:"beneficiary_id" validate:"required"`
+	BenfName     string `json:"beneficiary_name" validate:"required"`
+	BenfIfsc     string `json:"beneficiary_ifsc" validate:"required,ifsc_code,len=11"`
+	BenfAcctNo   string `json:"account_number" validate:"required,numeric,min=12,max=28"`
+	BenfAcctType string `json:"beneficiary_account_type" validate:"required"`
+	BenfMobNo    string `json:"beneficiary_mobile_number" validate:"required,mobile_number,len=10"`
+	Amount       string `json:"amount" validate:"required,numeric,gt=0"`
+	Remarks      string `json:"remarks" validate:"required,max=100"`
+	Frequency    string `json:"frequency" validate:"required,oneof=ONCE WEEKLY MONTHLY"`
+	DayOfMonth   int    `json:"day_of_month" validate:"omitempty,min=1,max=31"`
+	StartDate    string `json:"start_date" validate:"required,datetime=2006-01-02"`
+	EndDate      string `json:"end_date" validate:"omitempty,datetime=2006-01-02"`
+}
+
+type StandingInstructionOtpRequest struct {
+	Otp string `json:"otp" validate:"required,numeric"`
+}
+
+func NewStandingInstructionRequest() *StandingInstructionRequest {
+	return &StandingInstructionRequest{}
+}
+
+func NewStandingInstructionOtpRequest() *StandingInstructionOtpRequest {
+	return &StandingInstructionOtpRequest{}
+}
+
+func (r *StandingInstructionRequest) Validate(payload string) error {
+	if err := json.Unmarshal([]byte(payload), r); err != nil {
+		return err
+	}
+
+	if err := customvalidation.ValidateStruct(r); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *StandingInstructionOtpRequest) Validate(payload string) error {
+	if err := json.Unmarshal([]byte(payload), r); err != nil {
+		return err
+	}
+
+	if err := customvalidation.ValidateStruct(r); err != nil {
+		return err
+	}
+
+	return nil
+}
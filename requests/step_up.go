@@ -0,0 +1,27 @@
+package requests
+
+import (
+	"encoding/json"
+
+	"bitbucket.org/paydoh/paydoh-commons/customvalidation"
+)
+
+type StepUpOtpRequest struct {
+	Otp string `json:"otp" validate:"required,numeric"`
+}
+
+func NewStepUpOtpRequest() *StepUpOtpRequest {
+	return &StepUpOtpRequest{}
+}
+
+func (r *StepUpOtpRequest) Validate(payload string) error {
+	if err := json.Unmarshal([]byte(payload), r); err != nil {
+		return err
+	}
+
+	if err := customvalidation.ValidateStruct(r); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package requests
+
+import (
+	"encoding/json"
+
+	"bitbucket.org/paydoh/paydoh-commons/customvalidation"
+)
+
+// TransferLimitRequest sets the limits of a payment mode below the limits of
+// the tier of the user, in rupees. A 0 keeps the limit of the tier.
+type TransferLimitRequest struct {
+	PaymentMode    string `json:"payment_mode" validate:"required,oneof=NEFT IMPS IFT UPI"`
+	PerTransaction int64  `json:"per_transaction" validate:"min=0"`
+	Daily          int64  `json:"daily" validate:"min=0"`
+	Monthly        int64  `json:"monthly" validate:"min=0"`
+}
+
+func NewTransferLimitRequest() *TransferLimitRequest {
+	return &TransferLimitRequest{}
+}
+
+func (r *TransferLimitRequest) Validate(payload string) error {
+	if err := json.Unmarshal([]byte(payload), r); err != nil {
+		return err
+	}
+
+	if err := customvalidation.ValidateStruct(r); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -3,11 +3,16 @@ package responses
 import "encoding/json"
 
 type BankErrorResponse struct {
-	ErrorCode     string `json:"ErrorCode"`
-	ErrorMessage  string `json:"ErrorMessage"`
-	ErrorCode1    string `json:"rc"`
-	ErrorMessage1 string `json:"desc"`
+	ErrorCode     string       `json:"ErrorCode"`
+	ErrorMessage  string       `json:"ErrorMessage"`
+	ErrorCode1    string       `json:"rc"`
+	ErrorMessage1 string       `json:"desc"`
 	UpiError      UpiBaseError `json:"Response"`
+
+	// set from the bank error registry, not part of the KVB payload
+	Retryable  bool `json:"-"`
+	MaxRetries int  `json:"-"`
+	HTTPStatus int  `json:"-"`
 }
 
 func (b *BankErrorResponse) Marshal() ([]byte, error) {
@@ -1,9 +1,11 @@
 package router
 
 import (
+	"bankapi/config"
 	"bankapi/middleware"
 	"bankapi/modules/account_create_callback"
 	addressupdate "bankapi/modules/address_update"
+	"bankapi/modules/bank_errors"
 	"bankapi/modules/faq"
 	"bankapi/modules/mail"
 	"bankapi/modules/sms_callback"
@@ -12,10 +14,12 @@ import (
 	authenticationmodule "bankapi/modules/authentication_module"
 	authorizationmodule "bankapi/modules/authorization_module"
 	beneficiarymodule "bankapi/modules/beneficiary_module"
+	bulkpayoutmodule "bankapi/modules/bulk_payout_module"
 	consentmodule "bankapi/modules/consent_module"
 	encryptRouter "bankapi/modules/data_encryption"
 	debitCard "bankapi/modules/debitcard_module"
 	"bankapi/modules/demographic_module"
+	devicemodule "bankapi/modules/device_module"
 	get_user_details "bankapi/modules/get_user_details"
 	"bankapi/modules/kyc_audit_data"
 	"bankapi/modules/kyc_module"
@@ -24,8 +28,10 @@ import (
 	onboardingmodule "bankapi/modules/onboarding_module"
 	"bankapi/modules/openmodules"
 	"bankapi/modules/payment_callback"
+	standinginstructionmodule "bankapi/modules/standing_instruction_module"
 	"bankapi/modules/statementmodule"
 	transactionsRouter "bankapi/modules/transaction_module"
+	transferlimitmodule "bankapi/modules/transfer_limit_module"
 	"bankapi/modules/upi_module"
 	webhookmodule "bankapi/modules/webhook_module"
 
@@ -38,6 +44,7 @@ func ProjectModules(app *gin.Engine) {
 	{
 		authorizationmodule.Routes(api)
 		authenticationmodule.Routes(api)
+		devicemodule.Routes(api)
 		webhookmodule.Routes(api)
 		openmodules.Routes(api)
 		onboardingmodule.Routes(api)
@@ -45,6 +52,9 @@ func ProjectModules(app *gin.Engine) {
 		demographic_module.Routes(api)
 		nominee_module.Routes(api)
 		beneficiarymodule.Routes(api)
+		standinginstructionmodule.Routes(api)
+		bulkpayoutmodule.Routes(api)
+		transferlimitmodule.Routes(api)
 		consentmodule.Routes(api)
 		upi_module.Routes(api)
 		statementmodule.Routes(api)
@@ -56,6 +66,7 @@ func ProjectModules(app *gin.Engine) {
 		staticParameters.Routes(api)
 		mail.Routes(api)
 		faq.Routes(api)
+		bank_errors.Routes(api)
 	}
 
 	// callback APIs
@@ -70,7 +81,8 @@ func ProjectModules(app *gin.Engine) {
 
 	// internal service call apis
 	{
-		app.POST("api/transaction/history/internal", middleware.CallbackMiddleware(), transactionsRouter.GetInternalTransactions)
+		app.POST("api/transaction/history/internal", middleware.CallbackMiddleware(config.CallbackPartnerInternal), transactionsRouter.GetInternalTransactions)
+		app.GET("api/admin/mpin-lockouts", middleware.CallbackMiddleware(config.CallbackPartnerAdmin), openmodules.GetMpinLockouts)
 	}
 
 }
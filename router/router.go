@@ -1,7 +1,13 @@
 package router
 
 import (
+	"bankapi/config"
 	"bankapi/docs"
+	"bankapi/health"
+	"bankapi/keyring"
+	"bankapi/metrics"
+	"bankapi/middleware"
+	"bankapi/services"
 	"net/http"
 	"os"
 
@@ -38,6 +44,11 @@ func Endpoints(app *gin.Engine) {
 	// })
 
 	CheckHealth(app)
+	CheckLiveness(app)
+	CheckReadiness(app)
+	CheckBankHealth(app)
+	Metrics(app)
+	JWKS(app)
 
 	ProjectModules(app)
 }
@@ -52,3 +63,74 @@ func CheckHealth(app *gin.Engine) {
 		})
 	})
 }
+
+// @Tags Health API
+// @Router /health/live [get]
+func CheckLiveness(app *gin.Engine) {
+	app.GET("/health/live", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status": health.StatusUp,
+		})
+	})
+}
+
+// @Tags Health API
+// @Router /health/ready [get]
+func CheckReadiness(app *gin.Engine) {
+	app.GET("/health/ready", func(c *gin.Context) {
+		report := health.Ready(c.Request.Context())
+
+		status := http.StatusOK
+		if report.Status == health.StatusDown {
+			status = http.StatusServiceUnavailable
+		}
+
+		c.JSON(status, report)
+	})
+}
+
+// CheckBankHealth is served to the internal partner only, unlike the liveness
+// and readiness probes
+// @Tags Health API
+// @Router /health/bank [get]
+func CheckBankHealth(app *gin.Engine) {
+	app.GET("/health/bank", middleware.CallbackMiddleware(config.CallbackPartnerInternal), func(c *gin.Context) {
+		breakers := services.BankBreakerStatus()
+
+		status := "UP"
+		for _, breaker := range breakers {
+			if breaker.State != services.BreakerClosed {
+				status = "DEGRADED"
+				break
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":   status,
+			"breakers": breakers,
+		})
+	})
+}
+
+// Metrics is served to the internal partner only, it shows bank traffic by endpoint
+// @Tags Health API
+// @Router /metrics [get]
+func Metrics(app *gin.Engine) {
+	app.GET("/metrics", middleware.CallbackMiddleware(config.CallbackPartnerInternal), gin.WrapH(metrics.Handler()))
+}
+
+// JWKS publishes the public keys of asymmetric jwt keys, so other services can
+// verify access tokens without holding a secret
+// @Tags Health API
+// @Router /.well-known/jwks.json [get]
+func JWKS(app *gin.Engine) {
+	app.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		ring := keyring.Default()
+		if ring == nil {
+			c.JSON(http.StatusOK, keyring.JWKS{Keys: []keyring.JWK{}})
+			return
+		}
+
+		c.JSON(http.StatusOK, ring.JWKS())
+	})
+}
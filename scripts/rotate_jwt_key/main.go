@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bankapi/config"
+	"bankapi/keyring"
+	"flag"
+	"fmt"
+	"os"
+
+	"bitbucket.org/paydoh/paydoh-commons/settings"
+	_ "github.com/lib/pq"
+)
+
+// Rotates the jwt signing key now. Running instances pick the new key up on
+// their next refresh, tokens signed with the old key stay valid until they expire.
+func main() {
+	algorithm := flag.String("algorithm", "", "algorithm of the new key, HS256, RS256 or ES256 (default JWT_SIGNING_ALGORITHM)")
+	flag.Parse()
+
+	settings.LoadEnvFile()
+
+	db := config.InitDB()
+
+	defer db.Close()
+
+	ring := keyring.NewKeyring(db, config.GetJwtKeyringConfig())
+	if _, err := ring.Rotate(*algorithm, true); err != nil {
+		fmt.Printf("Failed to rotate jwt key: %v\n", err)
+		os.Exit(1)
+	}
+
+	current := ring.Current()
+	fmt.Printf("✅ Signing with key %s (%s)\n", current.Kid, current.Algorithm)
+}
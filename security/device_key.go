@@ -0,0 +1,91 @@
+package security
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// Device key algorithms, as models.DeviceKeyAlgorithmES256 and models.DeviceKeyAlgorithmEd25519
+const (
+	deviceKeyES256   = "ES256"
+	deviceKeyEd25519 = "Ed25519"
+)
+
+var errInvalidDeviceKeySignature = errors.New("invalid device key signature")
+
+// ParseDevicePublicKey parses the base64 DER (PKIX) public key a device
+// registers, an ES256 key must be on P-256
+func ParseDevicePublicKey(algorithm, encoded string) (interface{}, error) {
+	der, err := decodeBase64(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key encoding: %w", err)
+	}
+
+	publicKey, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+
+	switch algorithm {
+	case deviceKeyES256:
+		key, ok := publicKey.(*ecdsa.PublicKey)
+		if !ok || key.Curve != elliptic.P256() {
+			return nil, errors.New("ES256 needs a P-256 public key")
+		}
+		return key, nil
+	case deviceKeyEd25519:
+		key, ok := publicKey.(ed25519.PublicKey)
+		if !ok {
+			return nil, errors.New("Ed25519 needs an Ed25519 public key")
+		}
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unsupported device key algorithm %q", algorithm)
+}
+
+// VerifyDeviceKeySignature checks the base64 signature of message by a
+// device key. ES256 signatures are ASN.1 DER over the sha256 of the message,
+// as the android keystore and the ios secure enclave produce them.
+func VerifyDeviceKeySignature(algorithm, encodedKey string, message []byte, encodedSignature string) error {
+	publicKey, err := ParseDevicePublicKey(algorithm, encodedKey)
+	if err != nil {
+		return err
+	}
+
+	signature, err := decodeBase64(encodedSignature)
+	if err != nil {
+		return errInvalidDeviceKeySignature
+	}
+
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(message)
+		if !ecdsa.VerifyASN1(key, digest[:], signature) {
+			return errInvalidDeviceKeySignature
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, message, signature) {
+			return errInvalidDeviceKeySignature
+		}
+	}
+
+	return nil
+}
+
+// decodeBase64 accepts the standard and the url safe alphabet, with or without padding
+func decodeBase64(encoded string) ([]byte, error) {
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if decoded, err := encoding.DecodeString(encoded); err == nil {
+			return decoded, nil
+		}
+	}
+
+	return nil, errors.New("invalid base64")
+}
@@ -0,0 +1,118 @@
+package security
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"bankapi/constants"
+)
+
+// MpinPolicyError is the reason a new mpin is rejected, Code is shown by the app
+type MpinPolicyError struct {
+	Code    string
+	Message string
+}
+
+func (e *MpinPolicyError) Error() string {
+	return e.Message
+}
+
+// NewMpinReusedError rejects an mpin matching one of the last historySize mpins
+func NewMpinReusedError(historySize int) *MpinPolicyError {
+	return &MpinPolicyError{
+		Code:    constants.MpinReusedErrorCode,
+		Message: fmt.Sprintf(constants.MpinReusedErrorMessage, historySize),
+	}
+}
+
+// dateOfBirthLayouts are the formats date_of_birth is stored in
+var dateOfBirthLayouts = []string{"02-01-2006", "2006-01-02", "02/01/2006", "2006/01/02", "02012006"}
+
+// CheckMpinStrength rejects mpins that are easy to guess: one digit or one pair
+// of digits repeated, ascending or descending runs, the date of birth of the
+// user as DDMM, MMDD, MMYY or YYYY, and any part of their mobile number. An
+// empty dateOfBirth or mobileNumber skips that rule.
+func CheckMpinStrength(mpin, dateOfBirth, mobileNumber string) error {
+	if isRepeatedDigits(mpin) {
+		return &MpinPolicyError{Code: constants.MpinRepeatedDigitsErrorCode, Message: constants.MpinRepeatedDigitsErrorMessage}
+	}
+
+	if isSequentialDigits(mpin) {
+		return &MpinPolicyError{Code: constants.MpinSequentialErrorCode, Message: constants.MpinSequentialErrorMessage}
+	}
+
+	for _, fragment := range dateOfBirthFragments(dateOfBirth) {
+		if mpin == fragment {
+			return &MpinPolicyError{Code: constants.MpinDateOfBirthErrorCode, Message: constants.MpinDateOfBirthErrorMessage}
+		}
+	}
+
+	if mobileNumber != "" && strings.Contains(mobileNumber, mpin) {
+		return &MpinPolicyError{Code: constants.MpinMobileNumberErrorCode, Message: constants.MpinMobileNumberErrorMessage}
+	}
+
+	return nil
+}
+
+// isRepeatedDigits matches 1111 and 1212
+func isRepeatedDigits(mpin string) bool {
+	if len(mpin) < 2 {
+		return false
+	}
+
+	for period := 1; period <= 2; period++ {
+		if len(mpin)%period != 0 || len(mpin) == period {
+			continue
+		}
+		if strings.Repeat(mpin[:period], len(mpin)/period) == mpin {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isSequentialDigits matches 1234 and 4321
+func isSequentialDigits(mpin string) bool {
+	if len(mpin) < 2 {
+		return false
+	}
+
+	step := int(mpin[1]) - int(mpin[0])
+	if step != 1 && step != -1 {
+		return false
+	}
+
+	for i := 2; i < len(mpin); i++ {
+		if int(mpin[i])-int(mpin[i-1]) != step {
+			return false
+		}
+	}
+
+	return true
+}
+
+func dateOfBirthFragments(dateOfBirth string) []string {
+	dateOfBirth = strings.TrimSpace(dateOfBirth)
+	if len(dateOfBirth) > 10 {
+		// stored as a timestamp
+		dateOfBirth = dateOfBirth[:10]
+	}
+
+	for _, layout := range dateOfBirthLayouts {
+		date, err := time.Parse(layout, dateOfBirth)
+		if err != nil {
+			continue
+		}
+
+		return []string{
+			date.Format("0201"),
+			date.Format("0102"),
+			date.Format("0106"),
+			date.Format("2006"),
+		}
+	}
+
+	return nil
+}
@@ -4,6 +4,8 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -181,6 +183,22 @@ func GenerateOTP(length int) (string, error) {
 	return string(buffer), nil
 }
 
+// GenerateOpaqueToken returns a url safe random token of size random bytes
+func GenerateOpaqueToken(size int) (string, error) {
+	buffer := make([]byte, size)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buffer), nil
+}
+
+// HashToken returns the hex sha256 of a token, tokens are only stored hashed
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 func IsValidAmountFormat(str string) bool {
 	regex := `^\d{1,18}(\.\d{2})?$`
 	match, _ := regexp.MatchString(regex, str)
@@ -198,8 +216,11 @@ func IsValidFileExtension(filename string) (string, bool) {
 	return ext, false
 }
 
+// ClamAVAddress is the ClamAV daemon used to scan uploaded documents
+const ClamAVAddress = "tcp://localhost:3310"
+
 func ScanDocument(filePath string) (bool, string, error) {
-	c := clamd.NewClamd("tcp://localhost:3310") // ClamAV service address
+	c := clamd.NewClamd(ClamAVAddress)
 	f, err := os.Open(filePath)
 	if err != nil {
 		return false, "", err
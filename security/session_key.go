@@ -0,0 +1,72 @@
+package security
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	sessionKeyInfo = "paydoh-session-key"
+	sessionKeySize = 16
+)
+
+var ErrInvalidSessionPublicKey = errors.New("client_public_key must be a base64 DER (PKIX) P-256 public key")
+
+// DeriveSessionKey runs the server side of the ECDH handshake of a login. It
+// returns the base64 DER (PKIX) public key the app needs and the session key,
+// the hex of 16 bytes of HKDF-SHA256 over the shared secret without a salt and
+// with "paydoh-session-key" as info. The app derives the same key and uses it
+// as the AES-GCM key of its payloads.
+func DeriveSessionKey(clientPublicKey string) (serverPublicKey string, sessionKey string, err error) {
+	der, err := decodeBase64(clientPublicKey)
+	if err != nil {
+		return "", "", ErrInvalidSessionPublicKey
+	}
+
+	parsed, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return "", "", ErrInvalidSessionPublicKey
+	}
+
+	ecdsaKey, ok := parsed.(*ecdsa.PublicKey)
+	if !ok {
+		return "", "", ErrInvalidSessionPublicKey
+	}
+
+	clientKey, err := ecdsaKey.ECDH()
+	if err != nil || clientKey.Curve() != ecdh.P256() {
+		return "", "", ErrInvalidSessionPublicKey
+	}
+
+	serverKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate session key pair: %w", err)
+	}
+
+	secret, err := serverKey.ECDH(clientKey)
+	if err != nil {
+		return "", "", ErrInvalidSessionPublicKey
+	}
+
+	key := make([]byte, sessionKeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, nil, []byte(sessionKeyInfo)), key); err != nil {
+		return "", "", fmt.Errorf("failed to derive session key: %w", err)
+	}
+
+	serverDer, err := x509.MarshalPKIXPublicKey(serverKey.PublicKey())
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode session public key: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(serverDer), hex.EncodeToString(key), nil
+}
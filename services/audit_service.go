@@ -3,6 +3,7 @@ package services
 import (
 	"bankapi/config"
 	"bankapi/constants"
+	"bankapi/metrics"
 	"bankapi/utils"
 	"context"
 	"fmt"
@@ -108,9 +109,11 @@ func (a *auditService) Save(ctx context.Context, auditLog *AuditLog) error {
 	}
 
 	if _, _, err := a.taskEnqueuer.EnqueueNow(constants.AuditLogType, auditLogData, "default"); err != nil {
+		metrics.IncTask(constants.AuditLogType, metrics.TaskEnqueueFailed)
 		a.logger.Logger.Error("Failed to save audit log")
 		return err
 	}
+	metrics.IncTask(constants.AuditLogType, metrics.TaskEnqueued)
 	return nil
 }
 
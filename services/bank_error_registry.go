@@ -0,0 +1,342 @@
+package services
+
+import (
+	"bankapi/config"
+	"bankapi/constants"
+	_ "embed"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	commonSrv "bitbucket.org/paydoh/paydoh-commons/services"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultBankErrorOperation = "default"
+	defaultBankErrorLanguage  = "en"
+)
+
+//go:embed bank_errors.yaml
+var embeddedBankErrors []byte
+
+// BankErrorEntry is how a KVB error code of an operation is reported to the user
+type BankErrorEntry struct {
+	Operation  string            `yaml:"operation" json:"operation"`
+	Code       string            `yaml:"code" json:"code"`
+	Messages   map[string]string `yaml:"messages" json:"messages"`
+	Retryable  bool              `yaml:"retryable" json:"retryable"`
+	MaxRetries int               `yaml:"max_retries" json:"max_retries"`
+	HTTPStatus int               `yaml:"http_status" json:"http_status"`
+}
+
+// Message returns the message in the first language of an Accept-Language
+// style value, falling back to english
+func (e *BankErrorEntry) Message(language string) string {
+	if language != "" {
+		tag := strings.TrimSpace(strings.Split(strings.Split(language, ",")[0], ";")[0])
+		tag = strings.ToLower(strings.Split(tag, "-")[0])
+		if msg, ok := e.Messages[tag]; ok && msg != "" {
+			return msg
+		}
+	}
+	return e.Messages[defaultBankErrorLanguage]
+}
+
+type bankErrorFile struct {
+	Operations map[string][]string `yaml:"operations"`
+	Errors     []BankErrorEntry    `yaml:"errors"`
+}
+
+// BankErrorRegistrySnapshot is the registry content as listed for the support team
+type BankErrorRegistrySnapshot struct {
+	Source     string              `json:"source"`
+	LoadedAt   time.Time           `json:"loaded_at"`
+	Operations map[string][]string `json:"operations"`
+	Errors     []BankErrorEntry    `json:"errors"`
+}
+
+// BankErrorRegistry maps KVB error codes per operation to user messages and
+// retry behaviour. The embedded registry can be extended with a file that is
+// reloaded when it changes.
+type BankErrorRegistry struct {
+	mu         sync.RWMutex
+	log        *commonSrv.LoggerService
+	file       string
+	modTime    time.Time
+	loadedAt   time.Time
+	operations map[string][]string
+	endpoints  map[string]string
+	entries    map[string]map[string]*BankErrorEntry
+}
+
+var (
+	bankErrors     *BankErrorRegistry
+	bankErrorsOnce sync.Once
+)
+
+// BankErrors returns the process wide registry, the reload of the override
+// file starts with the first call
+func BankErrors() *BankErrorRegistry {
+	return InitBankErrors(nil)
+}
+
+// InitBankErrors loads the process wide registry with the logger its reload
+// errors are reported to, it is called by NewBankApiService
+func InitBankErrors(log *commonSrv.LoggerService) *BankErrorRegistry {
+	bankErrorsOnce.Do(func() {
+		registryConfig := config.GetBankErrorRegistryConfig()
+
+		bankErrors = &BankErrorRegistry{file: registryConfig.File, log: log}
+		if err := bankErrors.Reload(); err != nil {
+			// the embedded registry is always valid, only the override file can fail
+			bankErrors.logError(fmt.Sprintf("failed to load bank error registry file %s: %v", registryConfig.File, err))
+		}
+
+		if registryConfig.File != "" && registryConfig.ReloadInterval > 0 {
+			go bankErrors.watch(registryConfig.ReloadInterval)
+		}
+	})
+	return bankErrors
+}
+
+// NewBankErrorRegistry loads the embedded registry extended with file, if set
+func NewBankErrorRegistry(file string) (*BankErrorRegistry, error) {
+	registry := &BankErrorRegistry{file: file}
+	if err := registry.Reload(); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+// Reload rebuilds the registry from the embedded entries and the override
+// file. On error the previous content is kept.
+func (r *BankErrorRegistry) Reload() error {
+	base, err := parseBankErrors(embeddedBankErrors)
+	if err != nil {
+		return fmt.Errorf("failed to parse embedded bank errors: %w", err)
+	}
+
+	var modTime time.Time
+	var loadErr error
+	if r.file != "" {
+		override, stat, err := readBankErrorFile(r.file)
+		if err != nil {
+			loadErr = err
+		} else {
+			modTime = stat
+			for operation, endpoints := range override.Operations {
+				base.Operations[operation] = endpoints
+			}
+			base.Errors = append(base.Errors, override.Errors...)
+		}
+	}
+
+	operations, endpoints, entries := indexBankErrors(base)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// a broken override file must not drop entries that were loaded from it before
+	if loadErr != nil && r.entries != nil {
+		return loadErr
+	}
+
+	r.operations = operations
+	r.endpoints = endpoints
+	r.entries = entries
+	r.modTime = modTime
+	r.loadedAt = time.Now()
+
+	return loadErr
+}
+
+// Lookup finds the entry of code for the operation serving endpoint, then in
+// the default operation
+func (r *BankErrorRegistry) Lookup(endpoint, code string) (*BankErrorEntry, bool) {
+	if code == "" {
+		return nil, false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if operation, ok := r.endpoints[endpoint]; ok {
+		if entry, ok := r.entries[operation][code]; ok {
+			return entry, true
+		}
+	}
+
+	entry, ok := r.entries[defaultBankErrorOperation][code]
+	return entry, ok
+}
+
+// Retry returns the entry of the first of codes the interceptor retries for
+// endpoint, or of the first one found. Only the retryable errors of the
+// default operation are retried there, an operation can override them. The
+// retryable errors of an operation are retried by the store calling it, see
+// BankApiService.HandleBankSpecificError.
+func (r *BankErrorRegistry) Retry(endpoint string, codes ...string) (*BankErrorEntry, bool) {
+	var found *BankErrorEntry
+	for _, code := range codes {
+		entry, ok := r.Lookup(endpoint, code)
+		if !ok {
+			continue
+		}
+		if entry.Retryable && entry.Operation == defaultBankErrorOperation && entry.MaxRetries > 0 {
+			return entry, true
+		}
+		if found == nil {
+			found = entry
+		}
+	}
+	return found, false
+}
+
+// Snapshot returns a copy of the registry content sorted by operation and code
+func (r *BankErrorRegistry) Snapshot() *BankErrorRegistrySnapshot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	snapshot := &BankErrorRegistrySnapshot{
+		Source:     "embedded",
+		LoadedAt:   r.loadedAt,
+		Operations: make(map[string][]string, len(r.operations)),
+	}
+	if r.file != "" && !r.modTime.IsZero() {
+		snapshot.Source = "embedded+" + r.file
+	}
+
+	for operation, endpoints := range r.operations {
+		snapshot.Operations[operation] = append([]string{}, endpoints...)
+	}
+
+	for _, codes := range r.entries {
+		for _, entry := range codes {
+			snapshot.Errors = append(snapshot.Errors, *entry)
+		}
+	}
+	sort.Slice(snapshot.Errors, func(i, j int) bool {
+		if snapshot.Errors[i].Operation != snapshot.Errors[j].Operation {
+			return snapshot.Errors[i].Operation < snapshot.Errors[j].Operation
+		}
+		return snapshot.Errors[i].Code < snapshot.Errors[j].Code
+	})
+
+	return snapshot
+}
+
+func (r *BankErrorRegistry) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		info, err := os.Stat(r.file)
+		if err != nil {
+			continue
+		}
+
+		r.mu.RLock()
+		changed := !info.ModTime().Equal(r.modTime)
+		r.mu.RUnlock()
+
+		if !changed {
+			continue
+		}
+
+		if err := r.Reload(); err != nil {
+			r.logError(fmt.Sprintf("failed to reload bank error registry file %s: %v", r.file, err))
+		}
+	}
+}
+
+func (r *BankErrorRegistry) logError(message string) {
+	if r.log == nil {
+		return
+	}
+
+	r.log.LogError(&commonSrv.LogEntry{
+		Action:  constants.BANK,
+		Message: message,
+	})
+}
+
+func readBankErrorFile(file string) (*bankErrorFile, time.Time, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	parsed, err := parseBankErrors(data)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return parsed, info.ModTime(), nil
+}
+
+func parseBankErrors(data []byte) (*bankErrorFile, error) {
+	parsed := &bankErrorFile{}
+	if err := yaml.Unmarshal(data, parsed); err != nil {
+		return nil, err
+	}
+
+	if parsed.Operations == nil {
+		parsed.Operations = make(map[string][]string)
+	}
+
+	for i := range parsed.Errors {
+		entry := &parsed.Errors[i]
+
+		if entry.Code == "" {
+			return nil, fmt.Errorf("bank error %d has no code", i+1)
+		}
+		if entry.Operation == "" {
+			entry.Operation = defaultBankErrorOperation
+		}
+		if entry.Messages[defaultBankErrorLanguage] == "" {
+			return nil, fmt.Errorf("bank error %s/%s has no %q message", entry.Operation, entry.Code, defaultBankErrorLanguage)
+		}
+		if entry.HTTPStatus == 0 {
+			entry.HTTPStatus = http.StatusBadRequest
+		}
+		if http.StatusText(entry.HTTPStatus) == "" {
+			return nil, fmt.Errorf("bank error %s/%s has invalid http status %d", entry.Operation, entry.Code, entry.HTTPStatus)
+		}
+		if entry.MaxRetries < 0 {
+			return nil, fmt.Errorf("bank error %s/%s has negative max_retries", entry.Operation, entry.Code)
+		}
+	}
+
+	return parsed, nil
+}
+
+// indexBankErrors builds the lookup maps, later entries override earlier ones
+func indexBankErrors(parsed *bankErrorFile) (map[string][]string, map[string]string, map[string]map[string]*BankErrorEntry) {
+	endpoints := make(map[string]string)
+	for operation, paths := range parsed.Operations {
+		for _, path := range paths {
+			endpoints[path] = operation
+		}
+	}
+
+	entries := make(map[string]map[string]*BankErrorEntry)
+	for i := range parsed.Errors {
+		entry := parsed.Errors[i]
+		if entries[entry.Operation] == nil {
+			entries[entry.Operation] = make(map[string]*BankErrorEntry)
+		}
+		entries[entry.Operation][entry.Code] = &entry
+	}
+
+	return parsed.Operations, endpoints, entries
+}
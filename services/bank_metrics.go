@@ -0,0 +1,49 @@
+package services
+
+import (
+	"bankapi/constants"
+	"bankapi/metrics"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// observeBankCall records a finished KVB call with the HTTP status of its last
+// attempt and the bank error code, so e.g. the MW0014 rate of /fintech/payment
+// can be read from bankapi_kvb_requests_total directly.
+func observeBankCall(service, endpoint string, startTime time.Time, status int, resp *http.Response, err error) {
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	metrics.ObserveBankRequest(service, endpoint, status, bankErrorCode(err), time.Since(startTime))
+}
+
+// bankErrorCode returns the KVB error code carried by err, empty for success
+// and for calls that got no answer from the bank
+func bankErrorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	if IsBankUnavailable(err) {
+		return constants.BankUnavailableErrorCode
+	}
+
+	var retryErr *BankErrorWithRetry
+	if errors.As(err, &retryErr) && retryErr.BankError != nil {
+		if retryErr.BankError.ErrorCode != "" {
+			return retryErr.BankError.ErrorCode
+		}
+		return retryErr.BankError.UpiError.ResponseCode
+	}
+
+	var cardErr *BankCardControlErrorWithRetry
+	if errors.As(err, &cardErr) && cardErr.BankError != nil {
+		if cardErr.BankError.ErrorCode1 != "" {
+			return cardErr.BankError.ErrorCode1
+		}
+		return cardErr.BankError.ErrorCode
+	}
+
+	return ""
+}
@@ -0,0 +1,492 @@
+package services
+
+import (
+	"bankapi/config"
+	"bankapi/requests"
+	"bankapi/responses"
+	"bankapi/utils"
+	"context"
+	"fmt"
+	"sync"
+
+	"bitbucket.org/paydoh/paydoh-commons/database"
+	commonSrv "bitbucket.org/paydoh/paydoh-commons/services"
+)
+
+// BankProvider is a partner bank. Stores only talk to the bank through it, so
+// a second partner bank can be onboarded next to KVB and tests can replace the
+// bank with a fake.
+type BankProvider interface {
+	// Name is the provider name stored in user_data.bank_provider
+	Name() string
+
+	AccountProvider
+	BeneficiaryProvider
+	PaymentProvider
+	StatementProvider
+	NomineeProvider
+	UpiProvider
+	CardProvider
+	BankErrorHandler
+}
+
+// AccountProvider covers onboarding and the accounts of a customer
+type AccountProvider interface {
+	VerifySim(ctx context.Context, request *requests.OutgoingSimVerificationRequest) (*responses.SimVerificationResponse, error)
+	SmsVerification(ctx context.Context, request *requests.OutgoingSmsVerificationRequest) (*responses.SimVerificationResponse, error)
+	VcipInvoke(ctx context.Context, request *requests.OutGoingVcipInvokeRequest) (*responses.KycInvokeResponse, error)
+	GetDemographicData(ctx context.Context, request *requests.OutgoingDemographicRequest) (*responses.DemographicResponse, error)
+	CreatebankAccount(ctx context.Context, request *requests.OutgoingCreateBankAccountRequest) (*responses.ImmediateCreateBankResponse, error)
+	GetAccountDetail(ctx context.Context, request *requests.GetAccountDetail) (*responses.AccountDetailResponse, error)
+	PostUserConsent(ctx context.Context, request *requests.OutgoingConsentRequest, consentDetails []requests.ConsentDetails) (*responses.ConsentResponseV2, error)
+	UploadAddressProof(ctx context.Context, req *requests.UploadAddressProofReq) (*responses.UploadAddressProofResponse, error)
+	UpdateAddress(ctx context.Context, req *requests.UpdateAddressReq) (*responses.UpdateAddressResponse, error)
+	GetIfscData(request *requests.OutgoingSyncRequest) (*responses.IfscDataResponse, error)
+}
+
+// BeneficiaryProvider covers beneficiary registration
+type BeneficiaryProvider interface {
+	GetBeneficiaries(ctx context.Context, request *requests.OutgoingBeneficiarySearchRequest) (*responses.FetchBeneficiaryResponse, error)
+	AddBeneficiary(ctx context.Context, request *requests.OutgoingAddBeneficiaryRequest) (*responses.BeneficiarySubmissionResponse, error)
+	SubmitOtpBeneficiaryAddition(ctx context.Context, request *requests.OutgoingBeneficiaryOtpRequest) (*responses.BeneficiaryOTPValidationResponse, error)
+	QuickTransferTemplateAdd(ctx context.Context, request *requests.OutBeneficiaryTemplateRequest) (*responses.QuickTransferBeneficiaryAdditionResponse, error)
+}
+
+// PaymentProvider covers fund transfers
+type PaymentProvider interface {
+	PaymentSubmission(ctx context.Context, request *requests.OutgoingPaymentRequest) (*responses.PaymentSubmissionResponse, error)
+	PaymentSubmissionOTP(ctx context.Context, request *requests.OutgoingPaymentRequestOTP) (*responses.PaymentSubmissionOtpResponse, error)
+	RewardsTransfer(ctx context.Context, request *requests.OutgoingRewardTransactionRequest) (*responses.RewardsFundsTransferResponse, error)
+}
+
+// StatementProvider covers statements and transaction history
+type StatementProvider interface {
+	GetBankStatement(ctx context.Context, request *requests.OutgoingStatementRequest) (*responses.StatementResponse, error)
+	FetchTransactionHistory(ctx context.Context, requestData requests.KVBTransactionRequest) (*responses.TransactionResponse, error)
+}
+
+// NomineeProvider covers nominee registration
+type NomineeProvider interface {
+	CreateAddNominee(ctx context.Context, request *requests.OutgoingAddNomineeRequest) (*responses.OtpGenerationResponse, error)
+	VerifyNomineeOTP(ctx context.Context, request *requests.OutgoingVerifyNomineeOTP) (*responses.OtpAuthenticationResponse, error)
+	FetchNominee(ctx context.Context, request *requests.OutgoingFetchNomineeRequest) (*responses.FetchNomineeResponse, error)
+}
+
+// UpiProvider covers UPI onboarding, payments and collect requests
+type UpiProvider interface {
+	MobileMapping(ctx context.Context, request *requests.OutgoingMobileMappingType0ApiRequest) (*responses.MobileMappingType0ApiResponse, error)
+	VerifyUpiService(ctx context.Context, request *requests.OutgoingVerifyUserApiRequest) (*responses.VerifyUserApiResponse, error)
+	MobileMapping1(ctx context.Context, request *requests.OutgoingMobileMappingType1ApiRequest) (*responses.MobileMappingType1ApiResponse, error)
+	LcValidator(ctx context.Context, request *requests.OutgoingLCValidatorApiRequest) (*responses.LcValidatorApiResponse, error)
+	ProfileCreation(ctx context.Context, request *requests.OutgoingProfileCreationApiRequest) (*responses.ProfileCreationApiResponse, error)
+	ReMapping(ctx context.Context, request *requests.OutgoingRemappingApiRequest) (*responses.ReMappingApiResponse, error)
+	AlreadyUserRequestListKeys(ctx context.Context, request *requests.OutgoingReqListKeysApiRequest) (*responses.RequestListKeysResponse, error)
+	NewUserRequestListKeys(ctx context.Context, request *requests.OutgoingReqListKeysApiRequest) (*responses.NewRequestKeyListApiResponse, error)
+	ExistingUserRequestListKeys(ctx context.Context, request *requests.OutgoingExistingReqlistkeysApiRequest) (*responses.ExistingUserReqListApiResponse, error)
+	GetXmlRequestListKeys(ctx context.Context) (*responses.XmlRequestListKeyApiResponse, error)
+	CreateUpiIdRequestListAccounts(ctx context.Context, request *requests.OutgoingCreateupiidRequestListAccountApiRequest) (*responses.CreateUpiIdRequestListAccountApiResponse, error)
+	RequestPspAvailability(ctx context.Context, request *requests.OutgoingPspAvailabilityApiRequest) (*responses.PspAvailabilityApiResponse, error)
+	RequestAddBankAccount(ctx context.Context, request *requests.OutgoingAddBankApiRequest) (*responses.AddBankApiResponse, error)
+	RequestCheckAccountBalance(ctx context.Context, request *requests.OutgoingReqBalEnqApiRequest) (*responses.ReqBalEnqApiResponse, error)
+	AadharRequestListAccount(ctx context.Context, request *requests.OutgoingAadharRequestListAccountsApiRequest) (*responses.AadharRequestListAccountApiResponse, error)
+	SetUpiPinReqOtp(ctx context.Context, request *requests.OutgoingSetUpiPinReqOtpApiRequest) (*responses.ReqOtpApiResponse, error)
+	SetUpiPinReqRegMobile(ctx context.Context, request *requests.OutgoingSetUpiPinReqRegMobApiRequest) (*responses.ReqRegMobApiResponse, error)
+	ValidateVpaAddress(ctx context.Context, request *requests.OutgoingReqValAddApiRequest) (*responses.ReqValAddApiResponse, error)
+	PayWithVpa(ctx context.Context, request *requests.OutgoingReqPayApiRequest) (*responses.ReqPayApiResponse, error)
+	LinkBankAccount(ctx context.Context, request *requests.OutgoingAccountLinkApiRequest) (*responses.AccountLinkApiResponse, error)
+	CollectDetails(ctx context.Context, request *requests.OutgoingUpiMoneyCollectDetailsApiRequest) (*responses.UpiMoneyCollectDetailsResponse, error)
+	CollectCount(ctx context.Context, request *requests.OutgoingUpiMoneyCollectCountApiRequest) (*responses.UpiMoneyCollectCountResponse, error)
+	CollectApproval(ctx context.Context, request *requests.OutgoingUpiMoneyCollectApprovalApiRequest) (*responses.UpiMoneyCollectApprovalResponse, error)
+	UpiTransactionHistory(ctx context.Context, request *requests.OutgoingUpiTransactionHistoryApiRequest) (*responses.UpiTransactionHistoryApiResponse, error)
+	UpiChangeUpiPin(ctx context.Context, request *requests.OutgoingUpiReqSetCreRequest) (*responses.UpiChangePinApiResponse, error)
+	GetUpiID(request *requests.GetUpiIDRequest) (*responses.AccountLinkedResponse, error)
+}
+
+// CardProvider covers debit card issuance and card controls
+type CardProvider interface {
+	DebitCardVirtualGeneration(ctx context.Context, request *requests.GenerateVirtualDebitcardOutGoingReq) (*responses.GenerateDebitcardResponse, error)
+	DebitCardPhysicalGeneration(ctx context.Context, request *requests.GeneratePhysicalDebitCardOutGoingReq) (*responses.GeneratePhysicalDebitCardRes, error)
+	GetDebitCardDetails(ctx context.Context, request *requests.GetDebitcardDetailRequest) (*responses.DebitcardDetailResponse, error)
+	SetDebitCardPin(ctx context.Context, request *requests.SetDebitCardPin) (*responses.SetDebitCardPinResponse, error)
+	SendOTPForDebitCard(ctx context.Context, request *requests.SetDebitCardOTPReq) (*responses.SetDebitCardOTPResponse, error)
+	VerifyOTPForDebitCard(ctx context.Context, request *requests.SetDebitCardOTPReq) (*responses.VerifyDebitCardOTPResponse, error)
+	AddCard(ctx context.Context, req *requests.AddCardRequest, transactionID string) (*responses.AddCardResponse, error)
+	ListCard(ctx context.Context, req *requests.ListCardRequest, transactionID string) ([]responses.ListCardResponse, error)
+	ListCardControl(ctx context.Context, req *requests.ListCardControlRequest, transactionID string) ([]responses.ListCardControlResponse, error)
+	FetchTransaction(ctx context.Context, req *requests.FetchTransactionRequest, transactionID string) (*responses.FetchTransactionResponse, error)
+	EditTransaction(ctx context.Context, req *requests.EditTransactionRequest, transactionID string) (*responses.EditTransactionResponse, error)
+	CardBlock(ctx context.Context, req *requests.CardBlockRequest, transactionID string) (*responses.CardBlockResponse, error)
+	// the card control public key and logins are cached by the provider
+	CardControlPublicKey(ctx context.Context, req interface{}, transactionID string) (string, error)
+	CardControlLogin(ctx context.Context, userID string, req *requests.LoginRequest, transactionID string) (*responses.LoginResponse, error)
+	InvalidateCardControlLogin(ctx context.Context, userID, customerID string) error
+}
+
+// BankErrorHandler maps the errors of a provider to bank error responses
+type BankErrorHandler interface {
+	ExtractBankError(err error) *responses.BankErrorResponse
+	HandleBankSpecificError(ctx context.Context, err error) *responses.BankErrorResponse
+}
+
+// BankProviderFactory builds a provider with the logger and redis of a store
+type BankProviderFactory func(log *commonSrv.LoggerService, memory *database.InMemory) BankProvider
+
+// BankProviderSelector returns the provider name of the user of ctx, empty for the default provider
+type BankProviderSelector func(ctx context.Context) string
+
+var (
+	bankProvidersMu sync.RWMutex
+	bankProviders   = map[string]BankProviderFactory{
+		BankProviderKVB: func(log *commonSrv.LoggerService, memory *database.InMemory) BankProvider {
+			return NewKVBProvider(log, memory)
+		},
+	}
+	bankProviderSelector BankProviderSelector
+)
+
+// RegisterBankProvider makes a provider available to stores created afterwards
+func RegisterBankProvider(name string, factory BankProviderFactory) {
+	bankProvidersMu.Lock()
+	defer bankProvidersMu.Unlock()
+
+	bankProviders[name] = factory
+}
+
+// UnregisterBankProvider removes a provider added by RegisterBankProvider
+func UnregisterBankProvider(name string) {
+	bankProvidersMu.Lock()
+	defer bankProvidersMu.Unlock()
+
+	delete(bankProviders, name)
+}
+
+// SetBankProviderSelector sets how the provider of a user is found
+func SetBankProviderSelector(selector BankProviderSelector) {
+	bankProvidersMu.Lock()
+	defer bankProvidersMu.Unlock()
+
+	bankProviderSelector = selector
+}
+
+// NewBankProvider returns the provider used by stores. Calls are sent to the
+// provider of the user in the context, or to BANK_PROVIDER_DEFAULT.
+func NewBankProvider(log *commonSrv.LoggerService, memory *database.InMemory) BankProvider {
+	bankProvidersMu.RLock()
+	defer bankProvidersMu.RUnlock()
+
+	router := &bankProviderRouter{providers: make(map[string]BankProvider, len(bankProviders))}
+	for name, factory := range bankProviders {
+		router.providers[name] = factory(log, memory)
+	}
+
+	// an unknown BANK_PROVIDER_DEFAULT is refused by ValidateBankProviders on startup
+	router.fallback = router.providers[config.GetDefaultBankProvider()]
+
+	return router
+}
+
+// ValidateBankProviders returns an error when BANK_PROVIDER_DEFAULT is not a
+// registered provider
+func ValidateBankProviders() error {
+	bankProvidersMu.RLock()
+	defer bankProvidersMu.RUnlock()
+
+	if _, ok := bankProviders[config.GetDefaultBankProvider()]; !ok {
+		return fmt.Errorf("unknown bank provider %q in BANK_PROVIDER_DEFAULT", config.GetDefaultBankProvider())
+	}
+
+	return nil
+}
+
+// bankProviderRouter sends every call to the provider of the user making it
+type bankProviderRouter struct {
+	providers map[string]BankProvider
+	fallback  BankProvider
+}
+
+func (r *bankProviderRouter) provider(ctx context.Context) BankProvider {
+	// with a single partner bank there is nothing to look up
+	if len(r.providers) == 1 {
+		return r.fallback
+	}
+
+	bankProvidersMu.RLock()
+	selector := bankProviderSelector
+	bankProvidersMu.RUnlock()
+
+	if selector == nil {
+		return r.fallback
+	}
+
+	if provider, ok := r.providers[selector(ctx)]; ok {
+		return provider
+	}
+	return r.fallback
+}
+
+func (r *bankProviderRouter) Name() string {
+	return r.fallback.Name()
+}
+
+func (r *bankProviderRouter) VerifySim(ctx context.Context, request *requests.OutgoingSimVerificationRequest) (*responses.SimVerificationResponse, error) {
+	return r.provider(ctx).VerifySim(ctx, request)
+}
+
+func (r *bankProviderRouter) SmsVerification(ctx context.Context, request *requests.OutgoingSmsVerificationRequest) (*responses.SimVerificationResponse, error) {
+	return r.provider(ctx).SmsVerification(ctx, request)
+}
+
+func (r *bankProviderRouter) VcipInvoke(ctx context.Context, request *requests.OutGoingVcipInvokeRequest) (*responses.KycInvokeResponse, error) {
+	return r.provider(ctx).VcipInvoke(ctx, request)
+}
+
+func (r *bankProviderRouter) GetDemographicData(ctx context.Context, request *requests.OutgoingDemographicRequest) (*responses.DemographicResponse, error) {
+	return r.provider(ctx).GetDemographicData(ctx, request)
+}
+
+func (r *bankProviderRouter) CreatebankAccount(ctx context.Context, request *requests.OutgoingCreateBankAccountRequest) (*responses.ImmediateCreateBankResponse, error) {
+	return r.provider(ctx).CreatebankAccount(ctx, request)
+}
+
+func (r *bankProviderRouter) GetAccountDetail(ctx context.Context, request *requests.GetAccountDetail) (*responses.AccountDetailResponse, error) {
+	return r.provider(ctx).GetAccountDetail(ctx, request)
+}
+
+func (r *bankProviderRouter) PostUserConsent(ctx context.Context, request *requests.OutgoingConsentRequest, consentDetails []requests.ConsentDetails) (*responses.ConsentResponseV2, error) {
+	return r.provider(ctx).PostUserConsent(ctx, request, consentDetails)
+}
+
+func (r *bankProviderRouter) UploadAddressProof(ctx context.Context, req *requests.UploadAddressProofReq) (*responses.UploadAddressProofResponse, error) {
+	return r.provider(ctx).UploadAddressProof(ctx, req)
+}
+
+func (r *bankProviderRouter) UpdateAddress(ctx context.Context, req *requests.UpdateAddressReq) (*responses.UpdateAddressResponse, error) {
+	return r.provider(ctx).UpdateAddress(ctx, req)
+}
+
+func (r *bankProviderRouter) GetIfscData(request *requests.OutgoingSyncRequest) (*responses.IfscDataResponse, error) {
+	return r.fallback.GetIfscData(request)
+}
+
+func (r *bankProviderRouter) GetBeneficiaries(ctx context.Context, request *requests.OutgoingBeneficiarySearchRequest) (*responses.FetchBeneficiaryResponse, error) {
+	return r.provider(ctx).GetBeneficiaries(ctx, request)
+}
+
+func (r *bankProviderRouter) AddBeneficiary(ctx context.Context, request *requests.OutgoingAddBeneficiaryRequest) (*responses.BeneficiarySubmissionResponse, error) {
+	return r.provider(ctx).AddBeneficiary(ctx, request)
+}
+
+func (r *bankProviderRouter) SubmitOtpBeneficiaryAddition(ctx context.Context, request *requests.OutgoingBeneficiaryOtpRequest) (*responses.BeneficiaryOTPValidationResponse, error) {
+	return r.provider(ctx).SubmitOtpBeneficiaryAddition(ctx, request)
+}
+
+func (r *bankProviderRouter) QuickTransferTemplateAdd(ctx context.Context, request *requests.OutBeneficiaryTemplateRequest) (*responses.QuickTransferBeneficiaryAdditionResponse, error) {
+	return r.provider(ctx).QuickTransferTemplateAdd(ctx, request)
+}
+
+func (r *bankProviderRouter) PaymentSubmission(ctx context.Context, request *requests.OutgoingPaymentRequest) (*responses.PaymentSubmissionResponse, error) {
+	utils.MarkBankCalled(ctx)
+	return r.provider(ctx).PaymentSubmission(ctx, request)
+}
+
+func (r *bankProviderRouter) PaymentSubmissionOTP(ctx context.Context, request *requests.OutgoingPaymentRequestOTP) (*responses.PaymentSubmissionOtpResponse, error) {
+	utils.MarkBankCalled(ctx)
+	return r.provider(ctx).PaymentSubmissionOTP(ctx, request)
+}
+
+func (r *bankProviderRouter) RewardsTransfer(ctx context.Context, request *requests.OutgoingRewardTransactionRequest) (*responses.RewardsFundsTransferResponse, error) {
+	return r.provider(ctx).RewardsTransfer(ctx, request)
+}
+
+func (r *bankProviderRouter) GetBankStatement(ctx context.Context, request *requests.OutgoingStatementRequest) (*responses.StatementResponse, error) {
+	return r.provider(ctx).GetBankStatement(ctx, request)
+}
+
+func (r *bankProviderRouter) FetchTransactionHistory(ctx context.Context, requestData requests.KVBTransactionRequest) (*responses.TransactionResponse, error) {
+	return r.provider(ctx).FetchTransactionHistory(ctx, requestData)
+}
+
+func (r *bankProviderRouter) CreateAddNominee(ctx context.Context, request *requests.OutgoingAddNomineeRequest) (*responses.OtpGenerationResponse, error) {
+	return r.provider(ctx).CreateAddNominee(ctx, request)
+}
+
+func (r *bankProviderRouter) VerifyNomineeOTP(ctx context.Context, request *requests.OutgoingVerifyNomineeOTP) (*responses.OtpAuthenticationResponse, error) {
+	return r.provider(ctx).VerifyNomineeOTP(ctx, request)
+}
+
+func (r *bankProviderRouter) FetchNominee(ctx context.Context, request *requests.OutgoingFetchNomineeRequest) (*responses.FetchNomineeResponse, error) {
+	return r.provider(ctx).FetchNominee(ctx, request)
+}
+
+func (r *bankProviderRouter) MobileMapping(ctx context.Context, request *requests.OutgoingMobileMappingType0ApiRequest) (*responses.MobileMappingType0ApiResponse, error) {
+	return r.provider(ctx).MobileMapping(ctx, request)
+}
+
+func (r *bankProviderRouter) VerifyUpiService(ctx context.Context, request *requests.OutgoingVerifyUserApiRequest) (*responses.VerifyUserApiResponse, error) {
+	return r.provider(ctx).VerifyUpiService(ctx, request)
+}
+
+func (r *bankProviderRouter) MobileMapping1(ctx context.Context, request *requests.OutgoingMobileMappingType1ApiRequest) (*responses.MobileMappingType1ApiResponse, error) {
+	return r.provider(ctx).MobileMapping1(ctx, request)
+}
+
+func (r *bankProviderRouter) LcValidator(ctx context.Context, request *requests.OutgoingLCValidatorApiRequest) (*responses.LcValidatorApiResponse, error) {
+	return r.provider(ctx).LcValidator(ctx, request)
+}
+
+func (r *bankProviderRouter) ProfileCreation(ctx context.Context, request *requests.OutgoingProfileCreationApiRequest) (*responses.ProfileCreationApiResponse, error) {
+	return r.provider(ctx).ProfileCreation(ctx, request)
+}
+
+func (r *bankProviderRouter) ReMapping(ctx context.Context, request *requests.OutgoingRemappingApiRequest) (*responses.ReMappingApiResponse, error) {
+	return r.provider(ctx).ReMapping(ctx, request)
+}
+
+func (r *bankProviderRouter) AlreadyUserRequestListKeys(ctx context.Context, request *requests.OutgoingReqListKeysApiRequest) (*responses.RequestListKeysResponse, error) {
+	return r.provider(ctx).AlreadyUserRequestListKeys(ctx, request)
+}
+
+func (r *bankProviderRouter) NewUserRequestListKeys(ctx context.Context, request *requests.OutgoingReqListKeysApiRequest) (*responses.NewRequestKeyListApiResponse, error) {
+	return r.provider(ctx).NewUserRequestListKeys(ctx, request)
+}
+
+func (r *bankProviderRouter) ExistingUserRequestListKeys(ctx context.Context, request *requests.OutgoingExistingReqlistkeysApiRequest) (*responses.ExistingUserReqListApiResponse, error) {
+	return r.provider(ctx).ExistingUserRequestListKeys(ctx, request)
+}
+
+func (r *bankProviderRouter) GetXmlRequestListKeys(ctx context.Context) (*responses.XmlRequestListKeyApiResponse, error) {
+	return r.provider(ctx).GetXmlRequestListKeys(ctx)
+}
+
+func (r *bankProviderRouter) CreateUpiIdRequestListAccounts(ctx context.Context, request *requests.OutgoingCreateupiidRequestListAccountApiRequest) (*responses.CreateUpiIdRequestListAccountApiResponse, error) {
+	return r.provider(ctx).CreateUpiIdRequestListAccounts(ctx, request)
+}
+
+func (r *bankProviderRouter) RequestPspAvailability(ctx context.Context, request *requests.OutgoingPspAvailabilityApiRequest) (*responses.PspAvailabilityApiResponse, error) {
+	return r.provider(ctx).RequestPspAvailability(ctx, request)
+}
+
+func (r *bankProviderRouter) RequestAddBankAccount(ctx context.Context, request *requests.OutgoingAddBankApiRequest) (*responses.AddBankApiResponse, error) {
+	return r.provider(ctx).RequestAddBankAccount(ctx, request)
+}
+
+func (r *bankProviderRouter) RequestCheckAccountBalance(ctx context.Context, request *requests.OutgoingReqBalEnqApiRequest) (*responses.ReqBalEnqApiResponse, error) {
+	return r.provider(ctx).RequestCheckAccountBalance(ctx, request)
+}
+
+func (r *bankProviderRouter) AadharRequestListAccount(ctx context.Context, request *requests.OutgoingAadharRequestListAccountsApiRequest) (*responses.AadharRequestListAccountApiResponse, error) {
+	return r.provider(ctx).AadharRequestListAccount(ctx, request)
+}
+
+func (r *bankProviderRouter) SetUpiPinReqOtp(ctx context.Context, request *requests.OutgoingSetUpiPinReqOtpApiRequest) (*responses.ReqOtpApiResponse, error) {
+	return r.provider(ctx).SetUpiPinReqOtp(ctx, request)
+}
+
+func (r *bankProviderRouter) SetUpiPinReqRegMobile(ctx context.Context, request *requests.OutgoingSetUpiPinReqRegMobApiRequest) (*responses.ReqRegMobApiResponse, error) {
+	return r.provider(ctx).SetUpiPinReqRegMobile(ctx, request)
+}
+
+func (r *bankProviderRouter) ValidateVpaAddress(ctx context.Context, request *requests.OutgoingReqValAddApiRequest) (*responses.ReqValAddApiResponse, error) {
+	return r.provider(ctx).ValidateVpaAddress(ctx, request)
+}
+
+func (r *bankProviderRouter) PayWithVpa(ctx context.Context, request *requests.OutgoingReqPayApiRequest) (*responses.ReqPayApiResponse, error) {
+	utils.MarkBankCalled(ctx)
+	return r.provider(ctx).PayWithVpa(ctx, request)
+}
+
+func (r *bankProviderRouter) LinkBankAccount(ctx context.Context, request *requests.OutgoingAccountLinkApiRequest) (*responses.AccountLinkApiResponse, error) {
+	return r.provider(ctx).LinkBankAccount(ctx, request)
+}
+
+func (r *bankProviderRouter) CollectDetails(ctx context.Context, request *requests.OutgoingUpiMoneyCollectDetailsApiRequest) (*responses.UpiMoneyCollectDetailsResponse, error) {
+	return r.provider(ctx).CollectDetails(ctx, request)
+}
+
+func (r *bankProviderRouter) CollectCount(ctx context.Context, request *requests.OutgoingUpiMoneyCollectCountApiRequest) (*responses.UpiMoneyCollectCountResponse, error) {
+	return r.provider(ctx).CollectCount(ctx, request)
+}
+
+func (r *bankProviderRouter) CollectApproval(ctx context.Context, request *requests.OutgoingUpiMoneyCollectApprovalApiRequest) (*responses.UpiMoneyCollectApprovalResponse, error) {
+	return r.provider(ctx).CollectApproval(ctx, request)
+}
+
+func (r *bankProviderRouter) UpiTransactionHistory(ctx context.Context, request *requests.OutgoingUpiTransactionHistoryApiRequest) (*responses.UpiTransactionHistoryApiResponse, error) {
+	return r.provider(ctx).UpiTransactionHistory(ctx, request)
+}
+
+func (r *bankProviderRouter) UpiChangeUpiPin(ctx context.Context, request *requests.OutgoingUpiReqSetCreRequest) (*responses.UpiChangePinApiResponse, error) {
+	return r.provider(ctx).UpiChangeUpiPin(ctx, request)
+}
+
+func (r *bankProviderRouter) GetUpiID(request *requests.GetUpiIDRequest) (*responses.AccountLinkedResponse, error) {
+	return r.fallback.GetUpiID(request)
+}
+
+func (r *bankProviderRouter) DebitCardVirtualGeneration(ctx context.Context, request *requests.GenerateVirtualDebitcardOutGoingReq) (*responses.GenerateDebitcardResponse, error) {
+	return r.provider(ctx).DebitCardVirtualGeneration(ctx, request)
+}
+
+func (r *bankProviderRouter) DebitCardPhysicalGeneration(ctx context.Context, request *requests.GeneratePhysicalDebitCardOutGoingReq) (*responses.GeneratePhysicalDebitCardRes, error) {
+	return r.provider(ctx).DebitCardPhysicalGeneration(ctx, request)
+}
+
+func (r *bankProviderRouter) GetDebitCardDetails(ctx context.Context, request *requests.GetDebitcardDetailRequest) (*responses.DebitcardDetailResponse, error) {
+	return r.provider(ctx).GetDebitCardDetails(ctx, request)
+}
+
+func (r *bankProviderRouter) SetDebitCardPin(ctx context.Context, request *requests.SetDebitCardPin) (*responses.SetDebitCardPinResponse, error) {
+	return r.provider(ctx).SetDebitCardPin(ctx, request)
+}
+
+func (r *bankProviderRouter) SendOTPForDebitCard(ctx context.Context, request *requests.SetDebitCardOTPReq) (*responses.SetDebitCardOTPResponse, error) {
+	return r.provider(ctx).SendOTPForDebitCard(ctx, request)
+}
+
+func (r *bankProviderRouter) VerifyOTPForDebitCard(ctx context.Context, request *requests.SetDebitCardOTPReq) (*responses.VerifyDebitCardOTPResponse, error) {
+	return r.provider(ctx).VerifyOTPForDebitCard(ctx, request)
+}
+
+func (r *bankProviderRouter) AddCard(ctx context.Context, req *requests.AddCardRequest, transactionID string) (*responses.AddCardResponse, error) {
+	return r.provider(ctx).AddCard(ctx, req, transactionID)
+}
+
+func (r *bankProviderRouter) ListCard(ctx context.Context, req *requests.ListCardRequest, transactionID string) ([]responses.ListCardResponse, error) {
+	return r.provider(ctx).ListCard(ctx, req, transactionID)
+}
+
+func (r *bankProviderRouter) ListCardControl(ctx context.Context, req *requests.ListCardControlRequest, transactionID string) ([]responses.ListCardControlResponse, error) {
+	return r.provider(ctx).ListCardControl(ctx, req, transactionID)
+}
+
+func (r *bankProviderRouter) FetchTransaction(ctx context.Context, req *requests.FetchTransactionRequest, transactionID string) (*responses.FetchTransactionResponse, error) {
+	return r.provider(ctx).FetchTransaction(ctx, req, transactionID)
+}
+
+func (r *bankProviderRouter) EditTransaction(ctx context.Context, req *requests.EditTransactionRequest, transactionID string) (*responses.EditTransactionResponse, error) {
+	return r.provider(ctx).EditTransaction(ctx, req, transactionID)
+}
+
+func (r *bankProviderRouter) CardBlock(ctx context.Context, req *requests.CardBlockRequest, transactionID string) (*responses.CardBlockResponse, error) {
+	return r.provider(ctx).CardBlock(ctx, req, transactionID)
+}
+
+func (r *bankProviderRouter) CardControlPublicKey(ctx context.Context, req interface{}, transactionID string) (string, error) {
+	return r.provider(ctx).CardControlPublicKey(ctx, req, transactionID)
+}
+
+func (r *bankProviderRouter) CardControlLogin(ctx context.Context, userID string, req *requests.LoginRequest, transactionID string) (*responses.LoginResponse, error) {
+	return r.provider(ctx).CardControlLogin(ctx, userID, req, transactionID)
+}
+
+func (r *bankProviderRouter) InvalidateCardControlLogin(ctx context.Context, userID, customerID string) error {
+	return r.provider(ctx).InvalidateCardControlLogin(ctx, userID, customerID)
+}
+
+func (r *bankProviderRouter) ExtractBankError(err error) *responses.BankErrorResponse {
+	return r.fallback.ExtractBankError(err)
+}
+
+func (r *bankProviderRouter) HandleBankSpecificError(ctx context.Context, err error) *responses.BankErrorResponse {
+	return r.provider(ctx).HandleBankSpecificError(ctx, err)
+}
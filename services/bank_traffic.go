@@ -0,0 +1,395 @@
+package services
+
+import (
+	"bankapi/config"
+	"bankapi/constants"
+	"bankapi/requests"
+	"bankapi/responses"
+	"bankapi/utils"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	commonSrv "bitbucket.org/paydoh/paydoh-commons/services"
+)
+
+// BankFixture is one decrypted request/response pair of a KVB endpoint
+type BankFixture struct {
+	Endpoint   string          `json:"endpoint"`
+	Method     string          `json:"method"`
+	StatusCode int             `json:"status_code"`
+	Encrypted  bool            `json:"encrypted"`
+	Request    json.RawMessage `json:"request,omitempty"`
+	Response   json.RawMessage `json:"response,omitempty"`
+	RecordedAt time.Time       `json:"recorded_at"`
+}
+
+// bankEnvelope opens and seals the encrypted payloads exchanged with KVB.
+// Card control payloads are wrapped twice, see utils.DebitCardGenerateEncryptedReq.
+type bankEnvelope struct {
+	key  string
+	card bool
+}
+
+func (e bankEnvelope) openRequest(raw []byte) ([]byte, error) {
+	encReq := requests.EncryptedReq{}
+	if err := json.Unmarshal(raw, &encReq); err != nil || encReq.EncryptReq == "" {
+		return nil, errors.New("request is not encrypted")
+	}
+
+	body, err := utils.DecryptResponse(encReq.EncryptReq, e.key)
+	if err != nil {
+		return nil, err
+	}
+
+	if !e.card {
+		return body, nil
+	}
+
+	wrapped := make(map[string]string)
+	if err := json.Unmarshal(body, &wrapped); err != nil {
+		return nil, err
+	}
+	return utils.DecryptResponse(wrapped["input"], e.key)
+}
+
+func (e bankEnvelope) openResponse(raw []byte) ([]byte, error) {
+	encRes := responses.EncryptedRes{}
+	if err := json.Unmarshal(raw, &encRes); err != nil || encRes.EncryptRes == "" {
+		return nil, errors.New("response is not encrypted")
+	}
+
+	if e.card {
+		return utils.DebitCardDecryptResponse(encRes.EncryptRes, e.key)
+	}
+	return utils.DecryptResponse(encRes.EncryptRes, e.key)
+}
+
+func (e bankEnvelope) sealResponse(body []byte) ([]byte, error) {
+	if e.card {
+		inner, err := e.encrypt(body)
+		if err != nil {
+			return nil, err
+		}
+
+		body, err = json.Marshal(map[string]string{"response": inner})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	encrypted, err := e.encrypt(body)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(responses.EncryptedRes{EncryptRes: encrypted})
+}
+
+func (e bankEnvelope) encrypt(body []byte) (string, error) {
+	sealed, err := utils.GenerateEncryptedReqV2(body, e.key)
+	if err != nil {
+		return "", err
+	}
+
+	encReq := requests.EncryptedReq{}
+	if err := json.Unmarshal(sealed, &encReq); err != nil {
+		return "", err
+	}
+	return encReq.EncryptReq, nil
+}
+
+// newBankTransport returns the transport used under the interceptors, wrapped
+// for recording or replay when KVB_TRAFFIC_MODE asks for it.
+func newBankTransport(log *commonSrv.LoggerService, card bool) http.RoundTripper {
+	trafficConfig := config.GetBankTrafficConfig()
+
+	envelope := bankEnvelope{key: constants.BankEncryptionKey, card: card}
+	if card {
+		envelope.key = constants.CardControlEncryptionKey
+	}
+
+	switch trafficConfig.Mode {
+	case config.BankTrafficRecord:
+		return &TrafficRecorder{
+			Transport: http.DefaultTransport,
+			log:       log,
+			dir:       trafficConfig.FixtureDir,
+			envelope:  envelope,
+			redactor:  NewRedactor(trafficConfig.RedactFields),
+		}
+	case config.BankTrafficReplay:
+		return &TrafficReplayer{
+			dir:      trafficConfig.FixtureDir,
+			envelope: envelope,
+		}
+	default:
+		return http.DefaultTransport
+	}
+}
+
+// TrafficRecorder forwards requests to KVB and writes the decrypted, redacted
+// exchange of every call to a fixture file.
+type TrafficRecorder struct {
+	Transport http.RoundTripper
+	log       *commonSrv.LoggerService
+	dir       string
+	envelope  bankEnvelope
+	redactor  *Redactor
+}
+
+// fixtureMu numbers the fixtures of every recorder, each bank service has its
+// own recorder writing to the same directory
+var fixtureMu sync.Mutex
+
+func (t *TrafficRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		req.Body.Close()
+		reqBody = body
+		req.Body = io.NopCloser(bytes.NewBuffer(body))
+	}
+
+	resp, err := t.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewBuffer(respBody))
+
+	fixture := &BankFixture{
+		Endpoint:   req.URL.Path,
+		Method:     req.Method,
+		StatusCode: resp.StatusCode,
+		RecordedAt: time.Now(),
+	}
+
+	if plain, err := t.envelope.openRequest(reqBody); err == nil {
+		reqBody = plain
+	}
+	fixture.Request = jsonOrString(t.redactor.Redact(reqBody))
+
+	if plain, err := t.envelope.openResponse(respBody); err == nil {
+		fixture.Encrypted = true
+		respBody = plain
+	}
+	fixture.Response = jsonOrString(t.redactor.Redact(respBody))
+
+	// a failed write must not fail the bank call being recorded
+	if err := saveFixture(t.dir, fixture); err != nil {
+		t.log.LogError(&commonSrv.LogEntry{
+			Action:     "TRAFFIC_RECORDER",
+			Message:    fmt.Sprintf("TrafficRecorder: failed to record bank fixture: %v", err),
+			RequestURI: req.URL.Path,
+		})
+	}
+
+	return resp, nil
+}
+
+// saveFixture writes the fixture after the last one of its endpoint. Files
+// are created exclusively, so a fixture is never overwritten.
+func saveFixture(baseDir string, fixture *BankFixture) error {
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fixtureMu.Lock()
+	defer fixtureMu.Unlock()
+
+	dir := fixtureDir(baseDir, fixture.Endpoint)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for number := len(entries) + 1; ; number++ {
+		file, err := os.OpenFile(filepath.Join(dir, fmt.Sprintf("%04d.json", number)), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = file.Write(data)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}
+}
+
+// TrafficReplayer serves recorded fixtures instead of calling KVB. Fixtures of
+// an endpoint are served in recording order, the last one is repeated.
+type TrafficReplayer struct {
+	dir      string
+	envelope bankEnvelope
+	mu       sync.Mutex
+	fixtures map[string][]*BankFixture
+	served   map[string]int
+}
+
+func (t *TrafficReplayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		io.Copy(io.Discard, req.Body)
+		req.Body.Close()
+	}
+
+	fixture, err := t.next(req.URL.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := t.responseBody(fixture)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fixture.StatusCode, http.StatusText(fixture.StatusCode)),
+		StatusCode:    fixture.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewBuffer(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func (t *TrafficReplayer) responseBody(fixture *BankFixture) ([]byte, error) {
+	if fixture.Encrypted {
+		body, err := t.envelope.sealResponse(fixture.Response)
+		if err != nil {
+			return nil, fmt.Errorf("failed to seal fixture response: %w", err)
+		}
+		return body, nil
+	}
+
+	// bodies that were not JSON are stored quoted
+	var text string
+	if err := json.Unmarshal(fixture.Response, &text); err == nil {
+		return []byte(text), nil
+	}
+	return fixture.Response, nil
+}
+
+func (t *TrafficReplayer) next(endpoint string) (*BankFixture, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.fixtures == nil {
+		t.fixtures = make(map[string][]*BankFixture)
+		t.served = make(map[string]int)
+	}
+
+	fixtures, loaded := t.fixtures[endpoint]
+	if !loaded {
+		var err error
+		fixtures, err = loadFixtures(fixtureDir(t.dir, endpoint))
+		if err != nil {
+			return nil, err
+		}
+		t.fixtures[endpoint] = fixtures
+	}
+
+	if len(fixtures) == 0 {
+		if strings.Contains(endpoint, "/oauth/cc/accesstoken") {
+			return replayToken(endpoint), nil
+		}
+		return nil, fmt.Errorf("no bank fixture recorded for %s", endpoint)
+	}
+
+	index := t.served[endpoint]
+	if index >= len(fixtures) {
+		index = len(fixtures) - 1
+	}
+	t.served[endpoint]++
+
+	return fixtures[index], nil
+}
+
+func loadFixtures(dir string) ([]*BankFixture, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bank fixtures: %w", err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	fixtures := make([]*BankFixture, 0, len(names))
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bank fixture %s: %w", name, err)
+		}
+
+		fixture := &BankFixture{}
+		if err := json.Unmarshal(data, fixture); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal bank fixture %s: %w", name, err)
+		}
+		fixtures = append(fixtures, fixture)
+	}
+
+	return fixtures, nil
+}
+
+// replayToken stands in for the oauth token when the recording started with a cached token
+func replayToken(endpoint string) *BankFixture {
+	token, _ := json.Marshal(responses.TokenResponse{
+		AccessToken: "replay",
+		ExpiresIn:   "3600",
+		TokenType:   "Bearer",
+	})
+	return &BankFixture{Endpoint: endpoint, StatusCode: http.StatusOK, Response: token}
+}
+
+func fixtureDir(dir, endpoint string) string {
+	name := strings.ReplaceAll(strings.Trim(endpoint, "/"), "/", "_")
+	return filepath.Join(dir, name)
+}
+
+// jsonOrString keeps JSON bodies readable in fixtures and quotes anything else
+func jsonOrString(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if json.Valid(body) {
+		return body
+	}
+	quoted, _ := json.Marshal(string(body))
+	return quoted
+}
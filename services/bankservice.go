@@ -5,8 +5,6 @@ import (
 	"errors"
 	"io"
 	"net/http"
-	"net/url"
-	"strconv"
 	"time"
 
 	"bitbucket.org/paydoh/paydoh-commons/database"
@@ -22,6 +20,7 @@ import (
 
 type BankApiService struct {
 	service       *httpservice.HttpService
+	tokens        *TokenManager
 	Memory        *database.InMemory
 	LoggerService *commonSrv.LoggerService
 }
@@ -31,7 +30,11 @@ func NewBankApiService(log *commonSrv.LoggerService, memory *database.InMemory)
 		Memory:        memory,
 		LoggerService: log,
 	}
+	bankApiService.tokens = NewTokenManager(memory, bankApiService.requestToken)
+	InitBankErrors(log)
+
 	interceptor := &Interceptor{
+		Transport:  newBankTransport(log, false),
 		MaxRetries: 3,
 		log:        log,
 		bankSrv:    bankApiService,
@@ -42,11 +45,21 @@ func NewBankApiService(log *commonSrv.LoggerService, memory *database.InMemory)
 }
 
 func (b *BankApiService) ExtractBankError(err error) *responses.BankErrorResponse {
-	var urlErr *url.Error
-	if errors.As(err, &urlErr) {
-		var customErr *BankErrorWithRetry
-		if errors.As(urlErr.Err, &customErr) {
-			return customErr.BankError
+	// the interceptor errors come wrapped in a *url.Error
+	var customErr *BankErrorWithRetry
+	if errors.As(err, &customErr) {
+		return customErr.BankError
+	}
+	var cardControlErr *BankCardControlErrorWithRetry
+	if errors.As(err, &cardControlErr) {
+		return cardControlErr.BankError
+	}
+
+	// breaker open or bulkhead full, KVB was not called
+	if IsBankUnavailable(err) {
+		return &responses.BankErrorResponse{
+			ErrorCode:    constants.BankUnavailableErrorCode,
+			ErrorMessage: constants.BankUnavailableErrorMessage,
 		}
 	}
 
@@ -62,20 +75,57 @@ func (b *BankApiService) ExtractBankError(err error) *responses.BankErrorRespons
 	return nil
 }
 
-type ErrorMessageMapper func(errorCode string) (string, bool)
-
-func (b *BankApiService) HandleBankSpecificError(err error, mapper ErrorMessageMapper) *responses.BankErrorResponse {
-	if bankErr := b.ExtractBankError(err); bankErr != nil {
-		if msg, exists := mapper(bankErr.ErrorCode); exists {
-			bankErr.ErrorMessage = msg
-			return bankErr
+// HandleBankSpecificError extracts the bank error of err and sets its message,
+// retry and http status from the bank error registry, the message in the
+// language of the request. Retryable is only set for the errors of the
+// operation, the default errors were already retried by the interceptor;
+// the store retries a retryable error MaxRetries times.
+func (b *BankApiService) HandleBankSpecificError(ctx context.Context, err error) *responses.BankErrorResponse {
+	bankErr := b.ExtractBankError(err)
+	if bankErr == nil {
+		return nil
+	}
+
+	bankErr.Retryable = false
+	bankErr.MaxRetries = 0
+	bankErr.HTTPStatus = http.StatusBadRequest
+
+	endpoint := bankErrorEndpoint(err)
+	for _, code := range []string{bankErr.ErrorCode, bankErr.ErrorCode1} {
+		entry, exists := BankErrors().Lookup(endpoint, code)
+		if !exists {
+			continue
 		}
-		return bankErr
+
+		bankErr.ErrorMessage = entry.Message(utils.GetLanguageFromContext(ctx))
+		bankErr.Retryable = entry.Retryable && entry.Operation != defaultBankErrorOperation
+		bankErr.MaxRetries = entry.MaxRetries
+		bankErr.HTTPStatus = entry.HTTPStatus
+		break
 	}
-	return nil
+
+	return bankErr
+}
+
+// bankErrorEndpoint returns the KVB path recorded on a bank error, empty when unknown
+func bankErrorEndpoint(err error) string {
+	var retryErr *BankErrorWithRetry
+	if errors.As(err, &retryErr) {
+		return retryErr.Endpoint
+	}
+	var cardControlErr *BankCardControlErrorWithRetry
+	if errors.As(err, &cardControlErr) {
+		return cardControlErr.Endpoint
+	}
+	return ""
 }
 
 func (s *BankApiService) GenerateToken(ctx context.Context) (*responses.TokenResponse, error) {
+	return s.tokens.Token(ctx)
+}
+
+// requestToken posts the client credentials to KVB, caching is left to the token manager
+func (s *BankApiService) requestToken(ctx context.Context) (*responses.TokenResponse, error) {
 
 	startTime := time.Now()
 
@@ -91,132 +141,88 @@ func (s *BankApiService) GenerateToken(ctx context.Context) (*responses.TokenRes
 		AppVersion:    utils.GetAppVersionFromContext(ctx),
 	}
 
-	tokenResponseString, _ := s.Memory.Get("kvb_auth_token")
+	username := constants.KvbUserName
+	password := constants.KvbPassword
 
-	logData.Message = "GenerateToken: Token retrieved from cache"
-	logData.ResponseSize = len(tokenResponseString)
-	logData.EndTime = time.Now()
+	logData.Message = "GenerateToken: Retrieving new token"
 
-	if tokenResponseString == "" || tokenResponseString == "null" {
-		username := constants.KvbUserName
-		password := constants.KvbPassword
+	formFields := map[string]interface{}{
+		"grant_type": "client_credentials",
+	}
 
-		logData.Message = "GenerateToken: Retrieving new token"
+	authorizationDetails := httpservice.NewAuthDetails(httpservice.BasicAuth, username, password, "")
 
-		formFields := map[string]interface{}{
-			"grant_type": "client_credentials",
-		}
+	response, err := s.service.PostFormNoFile("/nbfc/v1/oauth/cc/accesstoken", formFields, authorizationDetails)
+	if err != nil {
+		logData.Message = "GenerateToken: Error making POST request"
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
 
-		authorizationDetails := httpservice.NewAuthDetails(httpservice.BasicAuth, username, password, "")
+	defer response.Body.Close()
 
-		response, err := s.service.PostFormNoFile("/nbfc/v1/oauth/cc/accesstoken", formFields, authorizationDetails)
-		if err != nil {
-			logData.Message = "GenerateToken: Error making POST request"
-			s.LoggerService.LogError(logData)
-			return nil, err
-		}
-
-		defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		logData.Message = "GenerateToken: Received non-OK response status"
+		logData.ResponseSize = int(response.ContentLength)
+		logData.EndTime = time.Now()
+		s.LoggerService.LogError(logData)
 
-		if response.StatusCode != http.StatusOK {
-			logData.Message = "GenerateToken: Received non-OK response status"
-			logData.ResponseSize = int(response.ContentLength)
-			logData.EndTime = time.Now()
-			s.LoggerService.LogError(logData)
+		if response.StatusCode == http.StatusUnauthorized {
+			return nil, errors.New("unauthorized")
+		}
 
-			if response.StatusCode == http.StatusUnauthorized {
-				return nil, errors.New("unauthorized")
-			}
+		if response.StatusCode == http.StatusForbidden {
+			return nil, errors.New("forbidden")
+		}
 
-			if response.StatusCode == http.StatusForbidden {
-				return nil, errors.New("forbidden")
+		if response.StatusCode == http.StatusBadRequest {
+			errorTokenResponse := responses.NewTokenErrorResponse()
+			body, err := io.ReadAll(response.Body)
+			if err != nil {
+				logData.Message = "GenerateToken: Error reading error token response body"
+				s.LoggerService.LogError(logData)
+				return nil, err
 			}
 
-			if response.StatusCode == http.StatusBadRequest {
-				errorTokenResponse := responses.NewTokenErrorResponse()
-				body, err := io.ReadAll(response.Body)
-				if err != nil {
-					logData.Message = "GenerateToken: Error reading error token response body"
-					s.LoggerService.LogError(logData)
-					return nil, err
-				}
-
-				if err := errorTokenResponse.Unmarshal(body); err != nil {
-					logData.Message = "GenerateToken: Error unmarshaling error token response"
-					s.LoggerService.LogError(logData)
-					return nil, err
-				}
-
-				logData.Message = "GenerateToken: Received error from token endpoint"
+			if err := errorTokenResponse.Unmarshal(body); err != nil {
+				logData.Message = "GenerateToken: Error unmarshaling error token response"
 				s.LoggerService.LogError(logData)
-
-				return nil, errors.New(errorTokenResponse.ErrorDescription)
+				return nil, err
 			}
 
-			logData.Message = "GenerateToken: Unknown error occurred"
+			logData.Message = "GenerateToken: Received error from token endpoint"
 			s.LoggerService.LogError(logData)
 
-			return nil, errors.New("unknown error")
-		}
-
-		body, err := io.ReadAll(response.Body)
-		if err != nil {
-			logData.Message = "GenerateToken: Error reading response body"
-			s.LoggerService.LogError(logData)
-			return nil, err
+			return nil, errors.New(errorTokenResponse.ErrorDescription)
 		}
 
-		token := responses.NewTokenResponse()
-		if err := token.Unmarshal(body); err != nil {
-			logData.Message = "GenerateToken: Error unmarshaling token response"
-			s.LoggerService.LogError(logData)
-			return nil, err
-		}
-
-		tokenData, err := token.Marshal()
-		if err != nil {
-			logData.Message = "GenerateToken: Error marshaling token data"
-			s.LoggerService.LogError(logData)
-			return nil, err
-		}
-
-		expiry, err := strconv.Atoi(token.ExpiresIn)
-		if err != nil {
-			logData.Message = "GenerateToken: Error converting token expiry"
-			s.LoggerService.LogError(logData)
-			return nil, err
-		}
+		logData.Message = "GenerateToken: Unknown error occurred"
+		s.LoggerService.LogError(logData)
 
-		if err := s.Memory.Set("kvb_auth_token", string(tokenData), time.Duration(expiry)*time.Second); err != nil {
-			logData.Message = "GenerateToken: Error caching token"
-			s.LoggerService.LogError(logData)
-			return nil, err
-		}
+		return nil, errors.New("unknown error")
+	}
 
-		logData.Message = "GenerateToken: Token generated and cached successfully"
-		logData.ResponseSize = len(body)
-		logData.ResponseBody = string(body)
-		logData.EndTime = time.Now()
-		logData.Latency = time.Since(startTime).Seconds()
-		s.LoggerService.LogInfo(logData)
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		logData.Message = "GenerateToken: Error reading response body"
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
 
-		return token, nil
+	token := responses.NewTokenResponse()
+	if err := token.Unmarshal(body); err != nil {
+		logData.Message = "GenerateToken: Error unmarshaling token response"
+		s.LoggerService.LogError(logData)
+		return nil, err
 	}
 
-	logData.Message = "GenerateToken: Token retrieved from cache and unmarshaled successfully"
-	logData.ResponseSize = len(tokenResponseString)
+	logData.Message = "GenerateToken: Token generated successfully"
+	logData.ResponseSize = len(body)
 	logData.EndTime = time.Now()
 	logData.Latency = time.Since(startTime).Seconds()
 	s.LoggerService.LogInfo(logData)
 
-	tokenResponse := responses.NewTokenResponse()
-	if err := tokenResponse.Unmarshal([]byte(tokenResponseString)); err != nil {
-		logData.Message = "GenerateToken: Error unmarshaling cached token"
-		s.LoggerService.LogError(logData)
-		return nil, err
-	}
-
-	return tokenResponse, nil
+	return token, nil
 }
 
 func (s *BankApiService) VerifySim(ctx context.Context, request *requests.OutgoingSimVerificationRequest) (*responses.SimVerificationResponse, error) {
@@ -957,7 +963,7 @@ func (s *BankApiService) AddBeneficiary(ctx context.Context, request *requests.O
 		bankErr := &responses.BankErrorResponse{
 			ErrorCode: beneficiarySubmissionResponse.ErrorCode,
 		}
-		return nil, bankErr
+		return nil, &BankErrorWithRetry{BankError: bankErr, Endpoint: "/fintech/beneficiary-registration"}
 	}
 
 	logData.Message = "AddBeneficiary API call completed successfully"
@@ -1032,7 +1038,7 @@ func (s *BankApiService) SubmitOtpBeneficiaryAddition(ctx context.Context, r *re
 		bankErr := &responses.BankErrorResponse{
 			ErrorCode: beneficiaryOtpValidationResponse.ErrorCode,
 		}
-		return nil, bankErr
+		return nil, &BankErrorWithRetry{BankError: bankErr, Endpoint: "/fintech/beneficiary-registration"}
 	}
 
 	logData.Message = "SubmitOtpBeneficiaryAddition API call completed successfully"
@@ -2,6 +2,7 @@ package services
 
 import (
 	"bankapi/constants"
+	"bankapi/metrics"
 	"bankapi/responses"
 	"bankapi/utils"
 	"bytes"
@@ -14,6 +15,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	commonSrv "bitbucket.org/paydoh/paydoh-commons/services"
 )
@@ -28,6 +30,8 @@ type CardControlInterceptor struct {
 type BankCardControlErrorWithRetry struct {
 	BankError   *responses.BankErrorResponse
 	ShouldRetry bool
+	// Endpoint is the KVB path that returned the error, used for the error registry lookup
+	Endpoint string
 }
 
 func (e *BankCardControlErrorWithRetry) Error() string {
@@ -49,6 +53,7 @@ func (i *CardControlInterceptor) RoundTrip(req *http.Request) (*http.Response, e
 	if i.Transport == nil {
 		i.Transport = http.DefaultTransport
 	}
+	startTime := time.Now()
 	logEntry := &commonSrv.LogEntry{
 		Action:      "INTERCEPTOR",
 		Message:     "Bank API Request",
@@ -73,15 +78,33 @@ func (i *CardControlInterceptor) RoundTrip(req *http.Request) (*http.Response, e
 	clonedReq.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
 	clonedReq.ContentLength = int64(len(bodyBytes))
 	clonedReq.Header = req.Header
-	return i.processRequest(clonedReq, bodyBytes, skipEncryption, logEntry)
+	resp, err := i.processRequest(clonedReq, bodyBytes, skipEncryption, logEntry)
+	observeBankCall(metrics.ServiceCardControl, req.URL.Path, startTime, logEntry.ResponseStatusCode, resp, err)
+	return resp, err
 }
 
 func (i *CardControlInterceptor) processRequest(clonedReq *http.Request, bodyBytes []byte, skipEncryption bool, logEntry *commonSrv.LogEntry) (*http.Response, error) {
 	var lastErr error
+	var retryReason string
+	bankRetries := 0
 
 	for attempt := 1; attempt <= i.MaxRetries; attempt++ {
+		if attempt > 1 {
+			metrics.IncBankRetry(metrics.ServiceCardControl, clonedReq.URL.Path, retryReason)
+		}
+
+		// every attempt goes through the breaker, an open one ends the retries
+		release, err := bankBreakers.Acquire(clonedReq.URL.Path)
+		if err != nil {
+			logEntry.Message = fmt.Sprintf("Bank request rejected: %v", err)
+			i.log.LogError(logEntry)
+			return nil, err
+		}
+
 		resp, err := i.Transport.RoundTrip(clonedReq)
 		if err != nil {
+			release(true)
+			retryReason = metrics.RetryTransport
 			if urlErr, ok := err.(*url.Error); ok {
 				// Check for DNS resolution errors
 				if dnsErr, ok := urlErr.Err.(*net.DNSError); ok {
@@ -124,9 +147,13 @@ func (i *CardControlInterceptor) processRequest(clonedReq *http.Request, bodyByt
 
 		if resp.StatusCode == http.StatusOK {
 			response, err := i.handleResponse(resp, bodyBytes, skipEncryption, logEntry)
+			release(isBankFailure(response, err))
 			if err != nil {
-				if retryErr, ok := err.(*BankCardControlErrorWithRetry); ok && retryErr.ShouldRetry {
+				// a bank error is retried at most max_retries times of its registry entry
+				if retryErr, ok := err.(*BankCardControlErrorWithRetry); ok && retryErr.ShouldRetry && bankRetries < retryErr.BankError.MaxRetries {
+					bankRetries++
 					lastErr = retryErr
+					retryReason = metrics.RetryBankError
 					logEntry.Message = fmt.Sprintf("Retrying request (attempt %d/%d)", attempt+1, i.MaxRetries)
 					i.log.LogInfo(logEntry)
 					clonedReq.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
@@ -136,8 +163,11 @@ func (i *CardControlInterceptor) processRequest(clonedReq *http.Request, bodyByt
 			}
 			return response, nil
 		} else if resp.StatusCode == http.StatusUnauthorized {
+			release(false)
 			resp.Body.Close()
-			tokenData, err := i.handleUnauthorized(attempt, logEntry)
+			retryReason = metrics.RetryUnauthorized
+			failedToken := strings.TrimPrefix(clonedReq.Header.Get("Authorization"), "Bearer ")
+			tokenData, err := i.handleUnauthorized(attempt, failedToken, logEntry)
 			if err != nil {
 				lastErr = err
 				i.log.LogError(logEntry)
@@ -148,7 +178,9 @@ func (i *CardControlInterceptor) processRequest(clonedReq *http.Request, bodyByt
 			clonedReq.Header.Set("Authorization", "Bearer "+tokenData.AccessToken)
 			continue
 		} else if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusRequestTimeout {
+			release(false)
 			resp.Body.Close()
+			retryReason = metrics.RetryStatus
 			logEntry.Message = fmt.Sprintf("Retrying request due to status %d (attempt %d/%d)", resp.StatusCode, attempt+1, i.MaxRetries)
 			logEntry.ResponseStatusCode = resp.StatusCode
 			i.log.LogInfo(logEntry)
@@ -156,7 +188,9 @@ func (i *CardControlInterceptor) processRequest(clonedReq *http.Request, bodyByt
 			lastErr = fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 			continue
 		} else {
+			release(isBankFailure(resp, nil))
 			lastErr = fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+			retryReason = metrics.RetryStatus
 			i.log.LogError(logEntry)
 			continue
 		}
@@ -184,7 +218,7 @@ func (i *CardControlInterceptor) handleResponse(resp *http.Response, bodyBytes [
 	decryptedBody, err := i.handleEncryptedResponse(encryptedBody, logEntry)
 	if err != nil {
 		if err.Error() == "'encryptRes' key is not getting in response" {
-			if err := i.processDebitCardControlError(encryptedBody, logEntry); err != nil {
+			if err := i.processDebitCardControlError(encryptedBody, resp.Request.URL.Path, logEntry); err != nil {
 				return nil, err
 			}
 		}
@@ -192,7 +226,7 @@ func (i *CardControlInterceptor) handleResponse(resp *http.Response, bodyBytes [
 		return nil, err
 	}
 
-	if err := i.processDebitCardControlError(decryptedBody, logEntry); err != nil {
+	if err := i.processDebitCardControlError(decryptedBody, resp.Request.URL.Path, logEntry); err != nil {
 		return nil, err
 	}
 
@@ -235,12 +269,13 @@ func (i *CardControlInterceptor) prepareRequestBody(req *http.Request, skipEncry
 	return bodyBytes, nil
 }
 
-func (i *CardControlInterceptor) handleUnauthorized(attempt int, logEntry *commonSrv.LogEntry) (*responses.TokenResponse, error) {
-	if err := i.bankSrv.Memory.Delete("kvb_auth_token"); err != nil {
-		logEntry.Message = fmt.Sprintf("Error deleting auth token on attempt %d: %v", attempt+1, err)
+func (i *CardControlInterceptor) handleUnauthorized(attempt int, failedToken string, logEntry *commonSrv.LogEntry) (*responses.TokenResponse, error) {
+	ctx := context.Background()
+	// only the rejected token is dropped, a token refreshed meanwhile by another request is reused
+	if err := i.bankSrv.tokens.Invalidate(ctx, failedToken); err != nil {
+		logEntry.Message = fmt.Sprintf("Error invalidating auth token on attempt %d: %v", attempt+1, err)
 		return nil, err
 	}
-	ctx := context.Background()
 	tokenData, err := i.bankSrv.GenerateToken(ctx)
 	if err != nil {
 		logEntry.Message = fmt.Sprintf("Error generating new token on attempt %d: %v", attempt+1, err)
@@ -271,23 +306,25 @@ func (i *CardControlInterceptor) handleEncryptedResponse(encryptedBody []byte, l
 	return decryptRespData, nil
 }
 
-func (i *CardControlInterceptor) processDebitCardControlError(decryptedBody []byte, logEntry *commonSrv.LogEntry) error {
+func (i *CardControlInterceptor) processDebitCardControlError(decryptedBody []byte, endpoint string, logEntry *commonSrv.LogEntry) error {
 
 	var debitCardError responses.BankErrorResponse
 	var array []responses.BankErrorResponse
 
 	if err := json.Unmarshal(decryptedBody, &debitCardError); err == nil {
-		return i.ProcessBankErrorResponse([]responses.BankErrorResponse{debitCardError}, logEntry)
+		return i.ProcessBankErrorResponse([]responses.BankErrorResponse{debitCardError}, endpoint, logEntry)
 	}
 
 	if err := json.Unmarshal(decryptedBody, &array); err == nil {
-		return i.ProcessBankErrorResponse(array, logEntry)
+		return i.ProcessBankErrorResponse(array, endpoint, logEntry)
 	}
 
 	return fmt.Errorf("failed to unmarshal response into known formats")
 }
 
-func (i *CardControlInterceptor) ProcessBankErrorResponse(response []responses.BankErrorResponse, logEntry *commonSrv.LogEntry) error {
+// ProcessBankErrorResponse returns the error of a card control response,
+// retried and worded as the error registry says for endpoint
+func (i *CardControlInterceptor) ProcessBankErrorResponse(response []responses.BankErrorResponse, endpoint string, logEntry *commonSrv.LogEntry) error {
 	if len(response) > 0 {
 		debitCardError := response[0]
 		if debitCardError.ErrorCode1 == "0" || debitCardError.ErrorCode1 == "00" ||
@@ -295,30 +332,22 @@ func (i *CardControlInterceptor) ProcessBankErrorResponse(response []responses.B
 			return nil
 		}
 
-		if msg, exists := constants.RetryBankErrors[debitCardError.ErrorCode1]; exists {
-			logEntry.Message = fmt.Sprintf("Retriable bank error: %s - %s", debitCardError.ErrorCode1, debitCardError.ErrorMessage1)
-			i.log.LogError(logEntry)
-			debitCardError.ErrorMessage = msg
-			return &BankCardControlErrorWithRetry{BankError: &debitCardError, ShouldRetry: true}
-		}
-
-		if msg, exists := constants.RetryBankErrors[debitCardError.ErrorCode]; exists {
-			logEntry.Message = fmt.Sprintf("Retriable bank error: %s - %s", debitCardError.ErrorCode1, debitCardError.ErrorMessage1)
+		entry, retry := BankErrors().Retry(endpoint, debitCardError.ErrorCode1, debitCardError.ErrorCode)
+		if retry {
+			logEntry.Message = fmt.Sprintf("Retriable bank error: %s %s - %s", debitCardError.ErrorCode1, debitCardError.ErrorCode, debitCardError.ErrorMessage1)
 			i.log.LogError(logEntry)
-			debitCardError.ErrorMessage = msg
-			return &BankCardControlErrorWithRetry{BankError: &debitCardError, ShouldRetry: true}
+			debitCardError.ErrorMessage = entry.Message("")
+			debitCardError.MaxRetries = entry.MaxRetries
+			return &BankCardControlErrorWithRetry{BankError: &debitCardError, ShouldRetry: true, Endpoint: endpoint}
 		}
 
-		if msg, exists := constants.GetCardControlService(debitCardError.ErrorCode1); exists {
-			logEntry.Message = fmt.Sprintf("Non-retriable bank error: %s - %s", debitCardError.ErrorCode1, debitCardError.ErrorMessage1)
-			i.log.LogError(logEntry)
-			debitCardError.ErrorMessage = msg
-			return &BankCardControlErrorWithRetry{BankError: &debitCardError, ShouldRetry: false}
+		if entry != nil {
+			debitCardError.ErrorMessage = entry.Message("")
 		}
 
 		logEntry.Message = fmt.Sprintf("Non-retriable bank error: %s - %s", debitCardError.ErrorCode1, debitCardError.ErrorMessage1)
 		i.log.LogError(logEntry)
-		return &BankCardControlErrorWithRetry{BankError: &debitCardError, ShouldRetry: false}
+		return &BankCardControlErrorWithRetry{BankError: &debitCardError, ShouldRetry: false, Endpoint: endpoint}
 	}
 
 	logEntry.Message = "no response found"
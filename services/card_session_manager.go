@@ -0,0 +1,171 @@
+package services
+
+import (
+	"bankapi/config"
+	"bankapi/metrics"
+	"bankapi/requests"
+	"bankapi/responses"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"bitbucket.org/paydoh/paydoh-commons/database"
+)
+
+const (
+	kvbCardPublicKeyKey = "kvb_card_public_key"
+	kvbCardLoginKey     = "kvb_card_login:%s"
+)
+
+// cachedCardSession is a card control public key or login response as cached in redis
+type cachedCardSession struct {
+	PublicKey string                   `json:"public_key"`
+	Login     *responses.LoginResponse `json:"login,omitempty"`
+	ExpiresAt time.Time                `json:"expires_at"`
+}
+
+// CardSessionManager caches the card control public key and the per user
+// login, with the same single-flight and redis lock as the oauth token.
+type CardSessionManager struct {
+	cache        *sessionCache
+	publicKeyTTL time.Duration
+	loginTTL     time.Duration
+	service      *DebitcardControlApiService
+}
+
+func NewCardSessionManager(memory *database.InMemory, service *DebitcardControlApiService) *CardSessionManager {
+	sessionConfig := config.GetBankSessionConfig()
+
+	return &CardSessionManager{
+		cache: &sessionCache{
+			memory:       memory,
+			refreshAhead: sessionConfig.CardRefreshAhead,
+			lockTTL:      sessionConfig.LockTTL,
+			waitPeriod:   sessionConfig.RefreshWaitPeriod,
+		},
+		publicKeyTTL: sessionConfig.CardPublicKeyTTL,
+		loginTTL:     sessionConfig.CardLoginTTL,
+		service:      service,
+	}
+}
+
+// PublicKey returns the cached card control public key, fetching it from KVB when missing
+func (m *CardSessionManager) PublicKey(ctx context.Context, req interface{}, transactionID string) (string, error) {
+	raw, err := m.cache.get(ctx, kvbCardPublicKeyKey, cardSessionExpiry, func(ctx context.Context) (string, time.Time, error) {
+		response, err := m.service.KeyFetch(ctx, req, transactionID)
+		metrics.IncTokenRefresh(metrics.SessionCardPublicKey, err)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+
+		if response == nil || response.PublicKey == "" {
+			return "", time.Time{}, errors.New("public key fetching error")
+		}
+
+		return marshalCardSession(&cachedCardSession{
+			PublicKey: response.PublicKey,
+			ExpiresAt: time.Now().Add(m.publicKeyTTL),
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+
+	session, err := unmarshalCardSession(raw)
+	if err != nil {
+		return "", err
+	}
+
+	return session.PublicKey, nil
+}
+
+// Login returns the cached card control login of the user. A login made
+// with another public key is not reused.
+func (m *CardSessionManager) Login(ctx context.Context, userID string, req *requests.LoginRequest, transactionID string) (*responses.LoginResponse, error) {
+	key := fmt.Sprintf(kvbCardLoginKey, userID)
+
+	expiry := func(raw string) time.Time {
+		session, err := unmarshalCardSession(raw)
+		if err != nil || session.PublicKey != req.PublicKey {
+			return time.Time{}
+		}
+		return session.ExpiresAt
+	}
+
+	raw, err := m.cache.get(ctx, key, expiry, func(ctx context.Context) (string, time.Time, error) {
+		response, err := m.service.Login(ctx, req, transactionID)
+		metrics.IncTokenRefresh(metrics.SessionCardLogin, err)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+
+		session := &cachedCardSession{
+			PublicKey: req.PublicKey,
+			Login:     response,
+			ExpiresAt: time.Now().Add(m.loginTTL),
+		}
+
+		// a login without customer id has to be repeated, do not keep it
+		if response.CustomerID == "" {
+			session.ExpiresAt = time.Now()
+		}
+
+		return marshalCardSession(session)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := unmarshalCardSession(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.Login == nil {
+		return nil, errors.New("response getting empty from bank")
+	}
+
+	return session.Login, nil
+}
+
+// InvalidatePublicKey drops the cached public key if it is still the one KVB rejected
+func (m *CardSessionManager) InvalidatePublicKey(ctx context.Context, publicKey string) error {
+	return m.cache.invalidate(ctx, kvbCardPublicKeyKey, func(raw string) bool {
+		session, err := unmarshalCardSession(raw)
+		return err != nil || session.PublicKey == publicKey
+	})
+}
+
+// InvalidateLogin drops the cached login of the user if it still holds customerID
+func (m *CardSessionManager) InvalidateLogin(ctx context.Context, userID, customerID string) error {
+	return m.cache.invalidate(ctx, fmt.Sprintf(kvbCardLoginKey, userID), func(raw string) bool {
+		session, err := unmarshalCardSession(raw)
+		return err != nil || session.Login == nil || session.Login.CustomerID == customerID
+	})
+}
+
+func marshalCardSession(session *cachedCardSession) (string, time.Time, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to marshal card session: %w", err)
+	}
+	return string(data), session.ExpiresAt, nil
+}
+
+func unmarshalCardSession(raw string) (*cachedCardSession, error) {
+	session := &cachedCardSession{}
+	if err := json.Unmarshal([]byte(raw), session); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal card session: %w", err)
+	}
+	return session, nil
+}
+
+func cardSessionExpiry(raw string) time.Time {
+	session, err := unmarshalCardSession(raw)
+	if err != nil {
+		return time.Time{}
+	}
+	return session.ExpiresAt
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"bankapi/config"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "CLOSED"
+	BreakerOpen     BreakerState = "OPEN"
+	BreakerHalfOpen BreakerState = "HALF_OPEN"
+)
+
+// BankUnavailable is returned without calling KVB when the endpoint breaker is
+// open or its bulkhead is full.
+type BankUnavailable struct {
+	Endpoint string
+	Reason   string
+}
+
+func (e *BankUnavailable) Error() string {
+	return fmt.Sprintf("bank endpoint %s unavailable: %s", e.Endpoint, e.Reason)
+}
+
+// IsBankUnavailable reports whether err was caused by an open breaker or a full bulkhead
+func IsBankUnavailable(err error) bool {
+	var unavailableErr *BankUnavailable
+	return errors.As(err, &unavailableErr)
+}
+
+type BreakerStatus struct {
+	Endpoint         string       `json:"endpoint"`
+	State            BreakerState `json:"state"`
+	Failures         int          `json:"failures"`
+	InFlight         int          `json:"in_flight"`
+	MaxConcurrent    int          `json:"max_concurrent"`
+	FailureThreshold int          `json:"failure_threshold"`
+	OpenedAt         *time.Time   `json:"opened_at,omitempty"`
+}
+
+type circuitBreaker struct {
+	mu       sync.Mutex
+	endpoint string
+	config   *config.BankBreakerConfig
+	state    BreakerState
+	failures int
+	inFlight int
+	trial    bool
+	openedAt time.Time
+	now      func() time.Time
+}
+
+// acquire takes a bulkhead slot, the returned func must be called with the
+// outcome of the call to release it. Only the trial call of a half open
+// breaker decides whether it closes.
+func (b *circuitBreaker) acquire() (func(failed bool), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen {
+		if b.now().Sub(b.openedAt) < b.config.OpenTimeout {
+			return nil, &BankUnavailable{Endpoint: b.endpoint, Reason: "circuit open"}
+		}
+		b.state = BreakerHalfOpen
+	}
+
+	trial := b.state == BreakerHalfOpen
+	if trial && b.trial {
+		return nil, &BankUnavailable{Endpoint: b.endpoint, Reason: "circuit half open"}
+	}
+
+	if b.config.MaxConcurrent > 0 && b.inFlight >= b.config.MaxConcurrent {
+		return nil, &BankUnavailable{Endpoint: b.endpoint, Reason: "too many concurrent requests"}
+	}
+
+	b.trial = b.trial || trial
+	b.inFlight++
+
+	var once sync.Once
+	return func(failed bool) {
+		once.Do(func() { b.release(trial, failed) })
+	}, nil
+}
+
+func (b *circuitBreaker) release(trial, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.inFlight--
+
+	if trial {
+		b.trial = false
+		if failed {
+			b.open()
+			return
+		}
+		b.state = BreakerClosed
+		b.failures = 0
+		return
+	}
+
+	// a call made before the breaker opened says nothing about the bank now
+	if b.state != BreakerClosed {
+		return
+	}
+
+	if !failed {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.config.FailureThreshold {
+		b.open()
+	}
+}
+
+func (b *circuitBreaker) open() {
+	b.state = BreakerOpen
+	b.openedAt = b.now()
+}
+
+func (b *circuitBreaker) status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := BreakerStatus{
+		Endpoint:         b.endpoint,
+		State:            b.state,
+		Failures:         b.failures,
+		InFlight:         b.inFlight,
+		MaxConcurrent:    b.config.MaxConcurrent,
+		FailureThreshold: b.config.FailureThreshold,
+	}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
+
+// BreakerRegistry keeps one breaker per bank endpoint path
+type BreakerRegistry struct {
+	mu        sync.Mutex
+	breakers  map[string]*circuitBreaker
+	configure func(endpoint string) *config.BankBreakerConfig
+	now       func() time.Time
+}
+
+func NewBreakerRegistry(configure func(endpoint string) *config.BankBreakerConfig, now func() time.Time) *BreakerRegistry {
+	if configure == nil {
+		configure = config.GetBankBreakerConfig
+	}
+	if now == nil {
+		now = time.Now
+	}
+	return &BreakerRegistry{
+		breakers:  make(map[string]*circuitBreaker),
+		configure: configure,
+		now:       now,
+	}
+}
+
+// bankBreakers is shared by every interceptor so that stores holding their
+// own BankApiService still see the same endpoint state.
+var bankBreakers = NewBreakerRegistry(nil, nil)
+
+func (r *BreakerRegistry) breaker(endpoint string) *circuitBreaker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.breakers[endpoint]
+	if !ok {
+		b = &circuitBreaker{
+			endpoint: endpoint,
+			config:   r.configure(endpoint),
+			state:    BreakerClosed,
+			now:      r.now,
+		}
+		r.breakers[endpoint] = b
+	}
+	return b
+}
+
+// Acquire fails fast with BankUnavailable when the endpoint cannot take the
+// call. The interceptors acquire every attempt, so retries count against the
+// breaker and stop once it opens.
+func (r *BreakerRegistry) Acquire(endpoint string) (func(failed bool), error) {
+	return r.breaker(endpoint).acquire()
+}
+
+func (r *BreakerRegistry) Status() []BreakerStatus {
+	r.mu.Lock()
+	breakers := make([]*circuitBreaker, 0, len(r.breakers))
+	for _, b := range r.breakers {
+		breakers = append(breakers, b)
+	}
+	r.mu.Unlock()
+
+	statuses := make([]BreakerStatus, 0, len(breakers))
+	for _, b := range breakers {
+		statuses = append(statuses, b.status())
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Endpoint < statuses[j].Endpoint
+	})
+	return statuses
+}
+
+// BankBreakerStatus returns the breaker state of every bank endpoint called so far
+func BankBreakerStatus() []BreakerStatus {
+	return bankBreakers.Status()
+}
+
+// isBankFailure decides whether a finished call counts against the breaker.
+// Business errors from KVB (non retriable error codes) mean the bank is up.
+func isBankFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		var retryErr *BankErrorWithRetry
+		if errors.As(err, &retryErr) {
+			return retryErr.ShouldRetry
+		}
+		var cardErr *BankCardControlErrorWithRetry
+		if errors.As(err, &cardErr) {
+			return cardErr.ShouldRetry
+		}
+		return true
+	}
+	return resp != nil && resp.StatusCode >= http.StatusInternalServerError
+}
@@ -22,6 +22,7 @@ type DebitcardControlApiService struct {
 	memory        *database.InMemory
 	LoggerService *commonSrv.LoggerService
 	BankService   *BankApiService
+	Sessions      *CardSessionManager
 }
 
 func NewDebitcardControlApiService(log *commonSrv.LoggerService, memory *database.InMemory) *DebitcardControlApiService {
@@ -29,17 +30,35 @@ func NewDebitcardControlApiService(log *commonSrv.LoggerService, memory *databas
 
 	interceptor := &CardControlInterceptor{
 		MaxRetries: 3,
-		Transport:  http.DefaultTransport,
+		Transport:  newBankTransport(log, true),
 		log:        log,
 		bankSrv:    bankservice,
 	}
 
-	return &DebitcardControlApiService{
+	debitcardControlService := &DebitcardControlApiService{
 		service:       httpservice.NewHttpService(constants.KvbUatURL, interceptor),
 		memory:        memory,
 		LoggerService: log,
 		BankService:   bankservice,
 	}
+	debitcardControlService.Sessions = NewCardSessionManager(memory, debitcardControlService)
+
+	return debitcardControlService
+}
+
+// CardControlPublicKey returns the card control public key cached by Sessions
+func (s *DebitcardControlApiService) CardControlPublicKey(ctx context.Context, req interface{}, transactionID string) (string, error) {
+	return s.Sessions.PublicKey(ctx, req, transactionID)
+}
+
+// CardControlLogin returns the card control login of the user cached by Sessions
+func (s *DebitcardControlApiService) CardControlLogin(ctx context.Context, userID string, req *requests.LoginRequest, transactionID string) (*responses.LoginResponse, error) {
+	return s.Sessions.Login(ctx, userID, req, transactionID)
+}
+
+// InvalidateCardControlLogin drops the cached card control login of the user
+func (s *DebitcardControlApiService) InvalidateCardControlLogin(ctx context.Context, userID, customerID string) error {
+	return s.Sessions.InvalidateLogin(ctx, userID, customerID)
 }
 
 func (s *DebitcardControlApiService) KeyFetch(ctx context.Context, req interface{}, transactionID string) (*responses.ExternalKeyFetch, error) {
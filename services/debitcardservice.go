@@ -28,6 +28,7 @@ func NewDebitcardApiService(log *commonSrv.LoggerService, memory *database.InMem
 	bankservice := NewBankApiService(log, memory)
 
 	interceptor := &Interceptor{
+		Transport:  newBankTransport(log, false),
 		MaxRetries: 3,
 		log:        log,
 		bankSrv:    bankservice,
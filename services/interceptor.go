@@ -2,6 +2,7 @@ package services
 
 import (
 	"bankapi/constants"
+	"bankapi/metrics"
 	"bankapi/responses"
 	"bankapi/utils"
 	"bytes"
@@ -12,6 +13,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	commonSrv "bitbucket.org/paydoh/paydoh-commons/services"
 )
@@ -26,6 +28,8 @@ type Interceptor struct {
 type BankErrorWithRetry struct {
 	BankError   *responses.BankErrorResponse
 	ShouldRetry bool
+	// Endpoint is the KVB path that returned the error, used for the error registry lookup
+	Endpoint string
 }
 
 func (e *BankErrorWithRetry) Error() string {
@@ -49,6 +53,8 @@ func (i *Interceptor) RoundTrip(req *http.Request) (*http.Response, error) {
 		i.Transport = http.DefaultTransport
 	}
 
+	startTime := time.Now()
+
 	logEntry := &commonSrv.LogEntry{
 		Action:      "INTERCEPTOR",
 		Message:     "Bank API Request",
@@ -76,15 +82,33 @@ func (i *Interceptor) RoundTrip(req *http.Request) (*http.Response, error) {
 	clonedReq.ContentLength = int64(len(bodyBytes))
 	clonedReq.Header = req.Header
 
-	return i.processRequest(clonedReq, bodyBytes, skipEncryption, logEntry)
+	resp, err := i.processRequest(clonedReq, bodyBytes, skipEncryption, logEntry)
+	observeBankCall(metrics.ServiceBank, req.URL.Path, startTime, logEntry.ResponseStatusCode, resp, err)
+	return resp, err
 }
 
 func (i *Interceptor) processRequest(clonedReq *http.Request, bodyBytes []byte, skipEncryption bool, logEntry *commonSrv.LogEntry) (*http.Response, error) {
 	var lastErr error
+	var retryReason string
+	bankRetries := 0
 	for attempt := 1; attempt <= i.MaxRetries; attempt++ {
+		if attempt > 1 {
+			metrics.IncBankRetry(metrics.ServiceBank, clonedReq.URL.Path, retryReason)
+		}
+
+		// every attempt goes through the breaker, an open one ends the retries
+		release, err := bankBreakers.Acquire(clonedReq.URL.Path)
+		if err != nil {
+			logEntry.Message = fmt.Sprintf("Bank request rejected: %v", err)
+			i.log.LogError(logEntry)
+			return nil, err
+		}
+
 		resp, err := i.Transport.RoundTrip(clonedReq)
 		if err != nil {
+			release(true)
 			lastErr = fmt.Errorf("request failed: %w", err)
+			retryReason = metrics.RetryTransport
 			i.log.LogError(logEntry)
 			continue
 		}
@@ -93,9 +117,16 @@ func (i *Interceptor) processRequest(clonedReq *http.Request, bodyBytes []byte,
 
 		if resp.StatusCode == http.StatusOK {
 			response, err := i.handleResponse(resp, bodyBytes, skipEncryption, logEntry)
+			release(isBankFailure(response, err))
 			if err != nil {
-				if retryErr, ok := err.(*BankErrorWithRetry); ok && retryErr.ShouldRetry {
+				if bankErr, ok := err.(*BankErrorWithRetry); ok {
+					bankErr.Endpoint = clonedReq.URL.Path
+				}
+				// a bank error is retried at most max_retries times of its registry entry
+				if retryErr, ok := err.(*BankErrorWithRetry); ok && retryErr.ShouldRetry && bankRetries < retryErr.BankError.MaxRetries {
+					bankRetries++
 					lastErr = retryErr
+					retryReason = metrics.RetryBankError
 					logEntry.Message = fmt.Sprintf("Retrying request (attempt %d/%d)", attempt+1, i.MaxRetries)
 					i.log.LogInfo(logEntry)
 					clonedReq.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
@@ -105,8 +136,11 @@ func (i *Interceptor) processRequest(clonedReq *http.Request, bodyBytes []byte,
 			}
 			return response, nil
 		} else if resp.StatusCode == http.StatusUnauthorized {
+			release(false)
 			resp.Body.Close()
-			tokenData, err := i.handleUnauthorized(attempt, logEntry)
+			retryReason = metrics.RetryUnauthorized
+			failedToken := strings.TrimPrefix(clonedReq.Header.Get("Authorization"), "Bearer ")
+			tokenData, err := i.handleUnauthorized(attempt, failedToken, logEntry)
 			if err != nil {
 				lastErr = err
 				i.log.LogError(logEntry)
@@ -117,6 +151,7 @@ func (i *Interceptor) processRequest(clonedReq *http.Request, bodyBytes []byte,
 			clonedReq.Header.Set("Authorization", "Bearer "+tokenData.AccessToken)
 			continue
 		} else {
+			release(isBankFailure(resp, nil))
 			return resp, nil
 		}
 	}
@@ -141,7 +176,7 @@ func (i *Interceptor) handleResponse(resp *http.Response, bodyBytes []byte, skip
 	decryptedBody, err := i.handleEncryptedResponse(encryptedBody, logEntry)
 	if err != nil {
 		if err.Error() == "'encryptRes' key is not getting in response" {
-			if err := i.processBankError(encryptedBody, logEntry); err != nil {
+			if err := i.processBankError(encryptedBody, resp.Request.URL.Path, logEntry); err != nil {
 				return nil, err
 			}
 		}
@@ -160,7 +195,7 @@ func (i *Interceptor) handleResponse(resp *http.Response, bodyBytes []byte, skip
 		i.log.LogInfo(logEntry)
 	} else {
 		// handle bank error
-		if err := i.processBankError(decryptedBody, logEntry); err != nil {
+		if err := i.processBankError(decryptedBody, resp.Request.URL.Path, logEntry); err != nil {
 			return nil, err
 		}
 	}
@@ -207,13 +242,15 @@ func (i *Interceptor) prepareRequestBody(req *http.Request, skipEncryption bool,
 	return bodyBytes, nil
 }
 
-func (i *Interceptor) handleUnauthorized(attempt int, logEntry *commonSrv.LogEntry) (*responses.TokenResponse, error) {
-	if err := i.bankSrv.Memory.Delete("kvb_auth_token"); err != nil {
-		logEntry.Message = fmt.Sprintf("Error deleting auth token on attempt %d: %v", attempt+1, err)
+func (i *Interceptor) handleUnauthorized(attempt int, failedToken string, logEntry *commonSrv.LogEntry) (*responses.TokenResponse, error) {
+	ctx := context.Background()
+
+	// only the rejected token is dropped, a token refreshed meanwhile by another request is reused
+	if err := i.bankSrv.tokens.Invalidate(ctx, failedToken); err != nil {
+		logEntry.Message = fmt.Sprintf("Error invalidating auth token on attempt %d: %v", attempt+1, err)
 		return nil, err
 	}
 
-	ctx := context.Background()
 	tokenData, err := i.bankSrv.GenerateToken(ctx)
 	if err != nil {
 		logEntry.Message = fmt.Sprintf("Error generating new token on attempt %d: %v", attempt+1, err)
@@ -250,7 +287,7 @@ func (i *Interceptor) handleEncryptedResponse(encryptedBody []byte, logEntry *co
 	return decryptRespData, nil
 }
 
-func (i *Interceptor) processBankError(decryptedBody []byte, logEntry *commonSrv.LogEntry) error {
+func (i *Interceptor) processBankError(decryptedBody []byte, endpoint string, logEntry *commonSrv.LogEntry) error {
 	var bankError responses.BankErrorResponse
 	if err := json.Unmarshal(decryptedBody, &bankError); err != nil {
 		return fmt.Errorf("failed to unmarshal response: %w", err)
@@ -262,25 +299,18 @@ func (i *Interceptor) processBankError(decryptedBody []byte, logEntry *commonSrv
 		return nil
 	}
 
-	// check retry bank error
-	if msg, exists := constants.RetryBankErrors[bankError.ErrorCode]; exists {
-		logEntry.Message = fmt.Sprintf("Retriable bank error: %s - %s", bankError.ErrorCode, bankError.ErrorMessage)
-		logEntry.ResponseBody = string(decryptedBody)
-		i.log.LogError(logEntry)
-		bankError.ErrorMessage = msg
-		return &BankErrorWithRetry{BankError: &bankError, ShouldRetry: true}
-	}
-
-	// check retry upi error
-	if msg, exists := constants.RetryUpiErrors[bankError.UpiError.ResponseCode]; exists {
-		logEntry.Message = fmt.Sprintf("Retriable UPI error: %s - %s", bankError.UpiError.ResponseCode, bankError.UpiError.ResponseMessage)
+	// the bank and upi error codes are retried as the error registry says
+	entry, retry := BankErrors().Retry(endpoint, bankError.ErrorCode, bankError.UpiError.ResponseCode)
+	if retry {
+		logEntry.Message = fmt.Sprintf("Retriable bank error: %s %s - %s %s", bankError.ErrorCode, bankError.UpiError.ResponseCode, bankError.ErrorMessage, bankError.UpiError.ResponseMessage)
 		logEntry.ResponseBody = string(decryptedBody)
 		i.log.LogError(logEntry)
-		bankError.ErrorMessage = msg
-		return &BankErrorWithRetry{BankError: &bankError, ShouldRetry: true}
+		bankError.ErrorMessage = entry.Message("")
+		bankError.MaxRetries = entry.MaxRetries
+		return &BankErrorWithRetry{BankError: &bankError, ShouldRetry: true, Endpoint: endpoint}
 	}
 
 	logEntry.Message = fmt.Sprintf("Non-retriable bank error: %s - %s", bankError.ErrorCode, bankError.ErrorMessage)
 	i.log.LogError(logEntry)
-	return &BankErrorWithRetry{BankError: &bankError, ShouldRetry: false}
+	return &BankErrorWithRetry{BankError: &bankError, ShouldRetry: false, Endpoint: endpoint}
 }
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"bitbucket.org/paydoh/paydoh-commons/database"
+	commonSrv "bitbucket.org/paydoh/paydoh-commons/services"
+)
+
+const BankProviderKVB = "kvb"
+
+var _ BankProvider = (*KVBProvider)(nil)
+
+// KVBProvider is Karur Vysya Bank, served by the fintech, debit card and card
+// control APIs
+type KVBProvider struct {
+	*BankApiService
+	*DebitcardApiService
+	*DebitcardControlApiService
+}
+
+func NewKVBProvider(log *commonSrv.LoggerService, memory *database.InMemory) *KVBProvider {
+	return &KVBProvider{
+		BankApiService:             NewBankApiService(log, memory),
+		DebitcardApiService:        NewDebitcardApiService(log, memory),
+		DebitcardControlApiService: NewDebitcardControlApiService(log, memory),
+	}
+}
+
+func (p *KVBProvider) Name() string {
+	return BankProviderKVB
+}
@@ -175,3 +175,57 @@ func (noti *NotificationService) SendAccountInformation(request interface{}) (in
 
 	return notificationResponse, nil
 }
+
+// SendOtp sends an otp by sms to the registered mobile number of a user
+func (noti *NotificationService) SendOtp(request *requests.SendOtpRequest) error {
+
+	jsonData, err := json.Marshal(request)
+
+	if err != nil {
+		return err
+	}
+
+	response, err := noti.service.Post("/api/sms/send-otp", jsonData, map[string]string{
+		"Content-Type": "application/json",
+	})
+
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		responseData, _ := io.ReadAll(response.Body)
+		return fmt.Errorf("failed to send otp: %s", string(responseData))
+	}
+
+	return nil
+}
+
+// SendSms sends a text message to the registered mobile number of a user
+func (noti *NotificationService) SendSms(request *requests.SendSmsRequest) error {
+
+	jsonData, err := json.Marshal(request)
+
+	if err != nil {
+		return err
+	}
+
+	response, err := noti.service.Post("/api/sms/send", jsonData, map[string]string{
+		"Content-Type": "application/json",
+	})
+
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		responseData, _ := io.ReadAll(response.Body)
+		return fmt.Errorf("failed to send sms: %s", string(responseData))
+	}
+
+	return nil
+}
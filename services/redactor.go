@@ -0,0 +1,122 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Redaction kinds understood by the Redactor
+const (
+	RedactAccount = "account"
+	RedactAadhaar = "aadhaar"
+	RedactMobile  = "mobile"
+	RedactName    = "name"
+	RedactSecret  = "secret"
+)
+
+// defaultRedactFields covers the PII carried by the fintech and card control
+// payloads, keys are matched case-insensitively.
+var defaultRedactFields = map[string]string{
+	"AccountNo":      RedactAccount,
+	"AccountNumber":  RedactAccount,
+	"BenfAcctNo":     RedactAccount,
+	"accno":          RedactAccount,
+	"AcctNo":         RedactAccount,
+	"AadhaarNo":      RedactAadhaar,
+	"AadharNo":       RedactAadhaar,
+	"AadhaarNumber":  RedactAadhaar,
+	"Aadhaar":        RedactAadhaar,
+	"UID":            RedactAadhaar,
+	"MobileNo":       RedactMobile,
+	"MobileNumber":   RedactMobile,
+	"Mobile":         RedactMobile,
+	"BenfMobNo":      RedactMobile,
+	"mno":            RedactMobile,
+	"Name":           RedactName,
+	"FirstName":      RedactName,
+	"MiddleName":     RedactName,
+	"LastName":       RedactName,
+	"FullName":       RedactName,
+	"BenfName":       RedactName,
+	"CustomerName":   RedactName,
+	"CardholderName": RedactName,
+	"nm":             RedactName,
+	"access_token":   RedactSecret,
+	"statpwd":        RedactSecret,
+}
+
+// Redactor masks PII fields in JSON payloads before they are written to fixtures
+type Redactor struct {
+	fields map[string]string
+}
+
+// NewRedactor returns a redactor for the default fields plus extra, extra
+// entries override the kind of a default field.
+func NewRedactor(extra map[string]string) *Redactor {
+	fields := make(map[string]string, len(defaultRedactFields)+len(extra))
+	for field, kind := range defaultRedactFields {
+		fields[strings.ToLower(field)] = kind
+	}
+	for field, kind := range extra {
+		fields[strings.ToLower(field)] = kind
+	}
+	return &Redactor{fields: fields}
+}
+
+// Redact returns body with every configured field masked. Bodies that are not
+// JSON are returned unchanged.
+func (r *Redactor) Redact(body []byte) []byte {
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return body
+	}
+
+	redacted, err := json.Marshal(r.redact(data))
+	if err != nil {
+		return body
+	}
+	return redacted
+}
+
+func (r *Redactor) redact(data interface{}) interface{} {
+	switch value := data.(type) {
+	case map[string]interface{}:
+		for key, v := range value {
+			if kind, ok := r.fields[strings.ToLower(key)]; ok {
+				if s, isString := v.(string); isString {
+					value[key] = maskValue(kind, s)
+					continue
+				}
+			}
+			value[key] = r.redact(v)
+		}
+		return value
+	case []interface{}:
+		for i, v := range value {
+			value[i] = r.redact(v)
+		}
+		return value
+	default:
+		return data
+	}
+}
+
+// maskValue keeps the last four characters of numbers so fixtures stay
+// readable, names and secrets are replaced entirely.
+func maskValue(kind, value string) string {
+	if value == "" {
+		return value
+	}
+
+	switch kind {
+	case RedactAccount, RedactAadhaar, RedactMobile:
+		if len(value) <= 4 {
+			return strings.Repeat("X", len(value))
+		}
+		return strings.Repeat("X", len(value)-4) + value[len(value)-4:]
+	case RedactName:
+		return "REDACTED NAME"
+	default:
+		return "REDACTED"
+	}
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"bitbucket.org/paydoh/paydoh-commons/database"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+// sessionGroup is shared by every cache instance, stores create their own bank
+// services so the single-flight has to live at package level.
+var sessionGroup singleflight.Group
+
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+var errSessionRefreshTimeout = errors.New("timed out waiting for session refresh")
+
+// sessionFetcher loads a new value from the bank and returns its expiry
+type sessionFetcher func(ctx context.Context) (string, time.Time, error)
+
+// sessionExpiry reads the expiry back from a cached value
+type sessionExpiry func(raw string) time.Time
+
+// sessionCache keeps a bank session value in redis. Refreshes are collapsed
+// with single-flight inside the process and a redis lock across pods, and a
+// value is refreshed refreshAhead before it expires.
+type sessionCache struct {
+	memory       *database.InMemory
+	refreshAhead time.Duration
+	lockTTL      time.Duration
+	waitPeriod   time.Duration
+}
+
+func (c *sessionCache) get(ctx context.Context, key string, expiry sessionExpiry, fetch sessionFetcher) (string, error) {
+	raw, _ := c.memory.Get(key)
+	if c.fresh(raw, expiry) {
+		return raw, nil
+	}
+
+	// the refresh is shared, one caller going away must not fail the others
+	refreshCtx := context.WithoutCancel(ctx)
+
+	value, err, _ := sessionGroup.Do(key, func() (interface{}, error) {
+		return c.refresh(refreshCtx, key, expiry, fetch)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return value.(string), nil
+}
+
+func (c *sessionCache) refresh(ctx context.Context, key string, expiry sessionExpiry, fetch sessionFetcher) (string, error) {
+	lockKey := fmt.Sprintf("%s:lock", key)
+	lockValue := randomLockValue()
+	deadline := time.Now().Add(c.waitPeriod)
+
+	for {
+		// another pod may have refreshed while we were waiting
+		raw, _ := c.memory.Get(key)
+		if c.fresh(raw, expiry) {
+			return raw, nil
+		}
+
+		locked, err := c.memory.GetClient().SetNX(ctx, lockKey, lockValue, c.lockTTL).Result()
+		if err != nil {
+			return "", fmt.Errorf("failed to acquire session lock: %w", err)
+		}
+
+		if locked {
+			defer releaseLockScript.Run(context.Background(), c.memory.GetClient(), []string{lockKey}, lockValue)
+			return c.fetchAndStore(ctx, key, fetch)
+		}
+
+		// the refresh is running elsewhere, a value that has not expired yet is still good to use
+		if c.usable(raw, expiry) {
+			return raw, nil
+		}
+
+		if time.Now().After(deadline) {
+			return "", errSessionRefreshTimeout
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func (c *sessionCache) fetchAndStore(ctx context.Context, key string, fetch sessionFetcher) (string, error) {
+	raw, expiresAt, err := fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return raw, nil
+	}
+
+	if err := c.memory.Set(key, raw, ttl); err != nil {
+		return "", fmt.Errorf("failed to cache session: %w", err)
+	}
+
+	return raw, nil
+}
+
+// invalidate deletes the cached value only when matches accepts it, so a
+// value refreshed by another request in the meantime is kept.
+func (c *sessionCache) invalidate(ctx context.Context, key string, matches func(raw string) bool) error {
+	err := c.memory.GetClient().Watch(ctx, func(tx *redis.Tx) error {
+		raw, err := tx.Get(ctx, key).Result()
+		if err == redis.Nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if !matches(raw) {
+			return nil
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, key)
+			return nil
+		})
+		return err
+	}, key)
+
+	if err == redis.TxFailedErr {
+		// value changed while invalidating, it is no longer the failed one
+		return nil
+	}
+	return err
+}
+
+func (c *sessionCache) fresh(raw string, expiry sessionExpiry) bool {
+	if raw == "" || raw == "null" {
+		return false
+	}
+	return time.Now().Add(c.refreshAhead).Before(expiry(raw))
+}
+
+func (c *sessionCache) usable(raw string, expiry sessionExpiry) bool {
+	if raw == "" || raw == "null" {
+		return false
+	}
+	return time.Now().Before(expiry(raw))
+}
+
+func randomLockValue() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
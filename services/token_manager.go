@@ -0,0 +1,103 @@
+package services
+
+import (
+	"bankapi/config"
+	"bankapi/metrics"
+	"bankapi/responses"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"bitbucket.org/paydoh/paydoh-commons/database"
+)
+
+const kvbTokenKey = "kvb_auth_token"
+
+// cachedToken is the token response as cached in redis, ExpiresAt lets the
+// manager refresh the token before KVB starts rejecting it.
+type cachedToken struct {
+	responses.TokenResponse
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// TokenManager hands out the KVB oauth token. Concurrent refreshes are
+// collapsed into a single token request per cluster.
+type TokenManager struct {
+	cache   *sessionCache
+	request func(ctx context.Context) (*responses.TokenResponse, error)
+}
+
+func NewTokenManager(memory *database.InMemory, request func(ctx context.Context) (*responses.TokenResponse, error)) *TokenManager {
+	sessionConfig := config.GetBankSessionConfig()
+
+	return &TokenManager{
+		cache: &sessionCache{
+			memory:       memory,
+			refreshAhead: sessionConfig.RefreshAhead,
+			lockTTL:      sessionConfig.LockTTL,
+			waitPeriod:   sessionConfig.RefreshWaitPeriod,
+		},
+		request: request,
+	}
+}
+
+// Token returns the cached token, refreshing it when it is close to expiry
+func (m *TokenManager) Token(ctx context.Context) (*responses.TokenResponse, error) {
+	raw, err := m.cache.get(ctx, kvbTokenKey, tokenExpiry, m.fetch)
+	if err != nil {
+		return nil, err
+	}
+
+	token := cachedToken{}
+	if err := json.Unmarshal([]byte(raw), &token); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cached token: %w", err)
+	}
+
+	return &token.TokenResponse, nil
+}
+
+// Invalidate drops the cached token if it is still the one KVB rejected
+func (m *TokenManager) Invalidate(ctx context.Context, accessToken string) error {
+	return m.cache.invalidate(ctx, kvbTokenKey, func(raw string) bool {
+		token := cachedToken{}
+		if err := json.Unmarshal([]byte(raw), &token); err != nil {
+			return true
+		}
+		return token.AccessToken == accessToken
+	})
+}
+
+func (m *TokenManager) fetch(ctx context.Context) (string, time.Time, error) {
+	token, err := m.request(ctx)
+	metrics.IncTokenRefresh(metrics.SessionOAuthToken, err)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiresIn, err := strconv.Atoi(token.ExpiresIn)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to convert token expiry: %w", err)
+	}
+
+	cached := cachedToken{
+		TokenResponse: *token,
+		ExpiresAt:     time.Now().Add(time.Duration(expiresIn) * time.Second),
+	}
+
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to marshal token: %w", err)
+	}
+
+	return string(data), cached.ExpiresAt, nil
+}
+
+func tokenExpiry(raw string) time.Time {
+	token := cachedToken{}
+	if err := json.Unmarshal([]byte(raw), &token); err != nil {
+		return time.Time{}
+	}
+	return token.ExpiresAt
+}
@@ -0,0 +1,76 @@
+package sessionkey
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"bankapi/config"
+	"bankapi/constants"
+	"bankapi/security"
+)
+
+// Session is the redis value of a device session. Access tokens carry only
+// the session id, they are accepted while it exists and the keys of the
+// session are read from it, so revoking the session drops its keys too.
+type Session struct {
+	UserId string `json:"user_id"`
+	// Key encrypts the request and response payloads of the session, agreed
+	// with the app through ECDH at login
+	Key string `json:"key"`
+	// SigningKey is the decrypted signing key of the user, it encrypts the
+	// data of the user at rest
+	SigningKey string `json:"signing_key"`
+	// Exchanged is false for apps that logged in without a public key, their
+	// Key is the SigningKey
+	Exchanged bool `json:"exchanged"`
+}
+
+var (
+	// ErrSessionNotFound is a session that was revoked or has expired
+	ErrSessionNotFound = errors.New("generate new token to continue")
+	// ErrLegacySession is a session created before its keys were kept server side
+	ErrLegacySession = errors.New("session has no keys")
+)
+
+// Save keeps the session for ttl, encrypted with the AES passphrase like the
+// signing keys in user_data
+func Save(sessionId string, session *Session, ttl time.Duration) error {
+	value, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+
+	encrypted, err := security.Encrypt(value, []byte(constants.AesPassPhrase))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt session: %w", err)
+	}
+
+	if err := config.GetRedis().Set(fmt.Sprintf(constants.SessionKeyFormat, sessionId), encrypted, ttl); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+
+	return nil
+}
+
+// Load reads a session saved by Save
+func Load(sessionId string) (*Session, error) {
+	value, err := config.GetRedis().Get(fmt.Sprintf(constants.SessionKeyFormat, sessionId))
+	if err != nil || value == "" {
+		return nil, ErrSessionNotFound
+	}
+
+	// sessions created before held the user id only
+	decrypted, err := security.Decrypt(value, []byte(constants.AesPassPhrase))
+	if err != nil {
+		return nil, ErrLegacySession
+	}
+
+	session := &Session{}
+	if err := json.Unmarshal([]byte(decrypted), session); err != nil {
+		return nil, ErrLegacySession
+	}
+
+	return session, nil
+}
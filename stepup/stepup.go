@@ -0,0 +1,111 @@
+package stepup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"bankapi/config"
+	"bankapi/constants"
+	"bankapi/models"
+)
+
+// Subject identifies who a step-up factor is verified for. Grants are held
+// per session so a proof on one device does not unlock another.
+type Subject struct {
+	UserId    string
+	SessionId string
+	DeviceId  string
+}
+
+// SessionKey falls back to the user for tokens issued without a session
+func (s *Subject) SessionKey() string {
+	if s.SessionId != "" {
+		return s.SessionId
+	}
+	return "user"
+}
+
+func grantKey(subject *Subject, factor string) string {
+	return fmt.Sprintf(constants.StepUpGrantKeyFormat, subject.UserId, subject.SessionKey(), factor)
+}
+
+// Grant records that the subject verified a factor now, it is kept for
+// STEP_UP_GRANT_TTL
+func Grant(ctx context.Context, subject *Subject, factor string) error {
+	if subject.UserId == "" {
+		return errors.New("step-up grant without a user")
+	}
+
+	err := config.GetRedis().GetClient().Set(ctx, grantKey(subject, factor), time.Now().Unix(), config.GetStepUpGrantTTL()).Err()
+	if err != nil {
+		return fmt.Errorf("failed to save step-up grant: %w", err)
+	}
+
+	return nil
+}
+
+// Missing returns the factors of the policy the subject has not verified
+// within its max age, none when the request may go through
+func Missing(ctx context.Context, db *sql.DB, policy *config.StepUpPolicy, subject *Subject) ([]string, error) {
+	var missing []string
+	for _, factor := range policy.Factors {
+		var (
+			ok  bool
+			err error
+		)
+		switch factor {
+		case config.StepUpFactorDevice:
+			ok, err = deviceTrusted(db, subject)
+		default:
+			ok, err = grantFresh(ctx, subject, factor, policy.MaxAge)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			missing = append(missing, factor)
+		}
+	}
+
+	return missing, nil
+}
+
+func grantFresh(ctx context.Context, subject *Subject, factor string, maxAge time.Duration) (bool, error) {
+	value, err := config.GetRedis().GetClient().Get(ctx, grantKey(subject, factor)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get step-up grant: %w", err)
+	}
+
+	grantedAt, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false, nil
+	}
+
+	return time.Since(time.Unix(grantedAt, 0)) <= maxAge, nil
+}
+
+// deviceTrusted requires the device of the request to be trusted in the registry
+func deviceTrusted(db *sql.DB, subject *Subject) (bool, error) {
+	if subject.DeviceId == "" {
+		return false, nil
+	}
+
+	device, err := models.FindTrustedDevice(db, subject.UserId, subject.DeviceId)
+	if err != nil {
+		if errors.Is(err, constants.ErrNoDataFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return device.Status == models.DeviceStatusTrusted, nil
+}
@@ -21,13 +21,13 @@ type AddressStore struct {
 	db                  *sql.DB
 	memory              *database.InMemory
 	LoggerService       *commonSrv.LoggerService
-	bankService         *services.BankApiService
+	bankService         services.BankProvider
 	notificationService *services.NotificationService
 	auditLogSrv         services.AuditLogService
 }
 
 func NewStore(log *commonSrv.LoggerService, db *sql.DB, memory *database.InMemory, auditLogSrv services.AuditLogService) *AddressStore {
-	bankService := services.NewBankApiService(log, memory)
+	bankService := services.NewBankProvider(log, memory)
 	notification := services.NewNotificationService()
 	return &AddressStore{
 		db:                  db,
@@ -40,7 +40,7 @@ func NewAuthenticationStore(log *commonSrv.LoggerService, db *sql.DB, m *databas
 	}
 }
 
-func (store *AuthenticationStore) InitiateSimVerification(ctx context.Context, userId, deviceIp, os, osVersion, key string, request *requests.AuthenticationRequest) (interface{}, error) {
+func (store *AuthenticationStore) InitiateSimVerification(ctx context.Context, userId, deviceIp, os, osVersion, key, signingKey string, request *requests.AuthenticationRequest) (interface{}, error) {
 	logData := &commonSrv.LogEntry{
 		Action:     constants.AUTHENTICATION,
 		RequestURI: "/api/authentication/initiate-sim-verification",
@@ -63,7 +63,7 @@ func (store *AuthenticationStore) InitiateSimVerification(ctx context.Context, u
 
 			insertingDevice.UserId = userId
 
-			encryptedDeviceid, err := security.Encrypt([]byte(dataRequest.DeviceId), []byte(key))
+			encryptedDeviceid, err := security.Encrypt([]byte(dataRequest.DeviceId), []byte(signingKey))
 			if err != nil {
 				logData.Message = "InitiateSimVerification: Error while encrypting device id"
 				store.LoggerService.LogError(logData)
@@ -77,7 +77,7 @@ func (store *AuthenticationStore) InitiateSimVerification(ctx context.Context, u
 			}
 
 			if dataRequest.SimVendorId != "" {
-				encryptedSimVendorId, err := security.Encrypt([]byte(dataRequest.SimVendorId), []byte(key))
+				encryptedSimVendorId, err := security.Encrypt([]byte(dataRequest.SimVendorId), []byte(signingKey))
 
 				if err != nil {
 					logData.Message = "InitiateSimVerification: Error while encrypting sim vendor id"
@@ -105,7 +105,15 @@ func (store *AuthenticationStore) InitiateSimVerification(ctx context.Context, u
 				Valid:  dataRequest.DeviceToken != "",
 			}
 
-			if err := models.InsertDevice(store.db, insertingDevice); err != nil {
+			// the first device of a user is trusted, a later one goes through a device change
+			if err := models.InsertEnrolledDevice(store.db, insertingDevice, &models.TrustedDevice{
+				UserId:      userId,
+				DeviceId:    dataRequest.DeviceId,
+				OS:          models.NullString{NullString: insertingDevice.OS},
+				OSVersion:   models.NullString{NullString: insertingDevice.OSVersion},
+				PackageId:   sql.NullString{String: insertingDevice.PackageId, Valid: insertingDevice.PackageId != ""},
+				DeviceToken: insertingDevice.DeviceToken,
+			}); err != nil {
 				logData.Message = "InitiateSimVerification: Error while inserting device data"
 				store.LoggerService.LogError(logData)
 				return nil, err
@@ -113,7 +121,7 @@ func (store *AuthenticationStore) InitiateSimVerification(ctx context.Context, u
 
 			message := security.GenerateRandomCode(36)
 
-			encryptedMessage, err := security.Encrypt([]byte(message), []byte(key))
+			encryptedMessage, err := security.Encrypt([]byte(message), []byte(signingKey))
 
 			if err != nil {
 				logData.Message = "InitiateSimVerification: Error while encrypting message"
@@ -174,7 +182,7 @@ func (store *AuthenticationStore) InitiateSimVerification(ctx context.Context, u
 
 	message := security.GenerateRandomCode(36)
 
-	encryptedMessage, err := security.Encrypt([]byte(message), []byte(key))
+	encryptedMessage, err := security.Encrypt([]byte(message), []byte(signingKey))
 	if err != nil {
 		logData.Message = "InitiateSimVerification: Error while encrypting message"
 		store.LoggerService.LogError(logData)
@@ -266,7 +274,8 @@ func (s *AuthenticationStore) GetCurrentUserByMobileNumber(ctx context.Context,
 	return data, nil
 }
 
-func (s *AuthenticationStore) Logout(ctx context.Context, userId string) error {
+// Logout ends the device session of the request, other devices stay logged in
+func (s *AuthenticationStore) Logout(ctx context.Context, userId, sessionId string) error {
 	logoutEndpoint := "/api/authentication/logout"
 	logData := &commonSrv.LogEntry{
 		Action:     constants.AUTHENTICATION,
@@ -276,7 +285,17 @@ func (s *AuthenticationStore) Logout(ctx context.Context, userId string) error {
 		RequestID:  utils.GetRequestIDFromContext(ctx),
 	}
 
-	err := s.memory.Delete(fmt.Sprintf(constants.TokenKeyFormat, userId))
+	revocationKey := fmt.Sprintf(constants.TokenKeyFormat, userId)
+	if sessionId != "" {
+		if err := models.RevokeUserSession(s.db, sessionId, models.SessionRevokedLogout); err != nil {
+			logData.Message = "Logout: Error revoking user session"
+			s.LoggerService.LogError(logData)
+			return err
+		}
+		revocationKey = fmt.Sprintf(constants.SessionKeyFormat, sessionId)
+	}
+
+	err := s.memory.Delete(revocationKey)
 	if err != nil {
 		logData.Message = "Logout: Error deleting user data from memory"
 		s.LoggerService.LogError(logData)
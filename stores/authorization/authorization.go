@@ -8,7 +8,6 @@ import (
 	"time"
 
 	"bitbucket.org/paydoh/paydoh-commons/database"
-	"bitbucket.org/paydoh/paydoh-commons/jsonwebtoken"
 	"bitbucket.org/paydoh/paydoh-commons/pkg/task"
 	commonSrv "bitbucket.org/paydoh/paydoh-commons/services"
 	"bitbucket.org/paydoh/paydoh-commons/types"
@@ -16,6 +15,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 
 	"bankapi/constants"
+	"bankapi/devicekey"
 	"bankapi/models"
 	"bankapi/requests"
 	"bankapi/security"
@@ -150,18 +150,14 @@ func (store *AuthorizationStore) GetAuthorizationToken(ctx context.Context, requ
 				return nil, errors.New("device ip should not be empty")
 			}
 
-			token, err := store.generateToken(userid, insertedRow.SigningKey, utils.GetUserAgentFromContext(ctx), userIP)
+			tokens, err := store.createSession(ctx, userid, insertedRow.SigningKey, request.ClientPublicKey, utils.GetUserAgentFromContext(ctx), userIP)
 			if err != nil {
 				logData.Message = "GetAuthorizationToken: Error while generating jwt token"
 				store.LoggerService.LogError(logData)
 				return nil, err
 			}
 
-			response := map[string]interface{}{
-				"token": token,
-			}
-
-			return response, nil
+			return tokens, nil
 		}
 
 		logData.Message = "GetAuthorizationToken: Error while fetching user data"
@@ -174,17 +170,13 @@ func (store *AuthorizationStore) GetAuthorizationToken(ctx context.Context, requ
 		return nil, errors.New("device ip should not be empty")
 	}
 
-	token, err := store.generateToken(existingUser.UserId, existingUser.SigningKey, utils.GetUserAgentFromContext(ctx), userIP)
+	tokens, err := store.createSession(ctx, existingUser.UserId, existingUser.SigningKey, request.ClientPublicKey, utils.GetUserAgentFromContext(ctx), userIP)
 	if err != nil {
 		logData.Message = "GetAuthorizationToken: Error while generating jwt token"
 		store.LoggerService.LogError(logData)
 		return nil, err
 	}
 
-	response := map[string]interface{}{
-		"token": token,
-	}
-
 	requestJson, err := request.ToJSON()
 	if err != nil {
 		logData.Message = "GetAuthorizationToken: Error while marshaling request to json"
@@ -221,36 +213,7 @@ func (store *AuthorizationStore) GetAuthorizationToken(ctx context.Context, requ
 	logData.Message = "GetAuthorizationToken: Token generated successfully"
 	logData.EndTime = time.Now()
 	store.LoggerService.LogInfo(logData)
-	return response, nil
-}
-
-func (store *AuthorizationStore) generateToken(userId string, signingKey string, userAgent string, userIP string) (string, error) {
-	existingToken, err := store.Redis.Get(fmt.Sprintf(constants.TokenKeyFormat, userId))
-	if err == nil && existingToken != "" {
-		return existingToken, nil
-	}
-
-	decoded, err := security.Decrypt(signingKey, []byte(constants.AesPassPhrase))
-	if err != nil {
-		return "", fmt.Errorf("error decrypting signing key: %v", err)
-	}
-
-	userId3 := userId[:3]
-	signKey3 := decoded[:3]
-
-	encryptingText := fmt.Sprintf("%s%s|%s%s", signKey3, userId[3:], userId3, decoded[3:])
-
-	token, err := jsonwebtoken.GenerateJWT(encryptingText, userAgent, userIP, constants.JwtKey, constants.JwtExpTime, "paydoh-bank")
-	if err != nil {
-		return "", fmt.Errorf("error generating jwt token: %v", err)
-	}
-
-	err = store.Redis.Set(fmt.Sprintf(constants.TokenKeyFormat, userId), token, constants.JwtExpTime)
-	if err != nil {
-		return "", fmt.Errorf("error setting token in redis: %v", err)
-	}
-
-	return token, nil
+	return tokens, nil
 }
 
 func (store *AuthorizationStore) GetAuthorizationTokenByUserId(ctx context.Context, request *requests.AuthorizationRequest) (interface{}, error) {
@@ -286,34 +249,60 @@ func (store *AuthorizationStore) GetAuthorizationTokenByUserId(ctx context.Conte
 		return nil, errors.New("error while fetching user data")
 	}
 
-	decoded, err := security.Decrypt(existingUser.SigningKey, []byte(constants.AesPassPhrase))
-	if err != nil {
-		logData.Message = "GetAuthorizationTokenByUserId: Error while decrypting signing key"
-		store.LoggerService.LogError(logData)
+	if err := store.verifyUserIdLogin(ctx, existingUser.UserId, request, logData); err != nil {
 		return nil, err
 	}
 
-	encryptingText := fmt.Sprintf("%s|%s", existingUser.UserId, decoded)
-
-	token, err := jsonwebtoken.GenerateJWT(encryptingText, utils.GetUserAgentFromContext(ctx), utils.GetSourceIPFromContext(ctx), constants.JwtKey, constants.JwtExpTime, "paydoh-bank")
+	userIP := utils.GetSourceIPFromContext(ctx)
+	if userIP == "" {
+		return nil, errors.New("device ip should not be empty")
+	}
 
+	tokens, err := store.createSession(ctx, existingUser.UserId, existingUser.SigningKey, request.ClientPublicKey, utils.GetUserAgentFromContext(ctx), userIP)
 	if err != nil {
 		logData.Message = "GetAuthorizationTokenByUserId: Error while generating jwt token"
 		store.LoggerService.LogError(logData)
 		return nil, err
 	}
 
-	response := map[string]interface{}{
-		"token": token,
-	}
-
 	logData.Message = "GetAuthorizationTokenByUserId: Token generated successfully"
-	logData.ResponseSize = len(token)
-	logData.ResponseBody = string(token)
 	logData.EndTime = time.Now()
 	store.LoggerService.LogInfo(logData)
 
-	return response, nil
+	return tokens, nil
+}
+
+// verifyUserIdLogin checks the login challenge of a login with user_id. A
+// device with a key has to sign one, devices without a key login as before.
+func (store *AuthorizationStore) verifyUserIdLogin(ctx context.Context, userId string, request *requests.AuthorizationRequest, logData *commonSrv.LogEntry) error {
+	if request.ChallengeId == "" {
+		_, err := models.FindActiveDeviceKey(store.db, userId, utils.GetDeviceIdFromContext(ctx))
+		if errors.Is(err, constants.ErrNoDataFound) {
+			return nil
+		}
+		if err != nil {
+			logData.Message = "verifyUserIdLogin: Error while fetching device key:- " + err.Error()
+			store.LoggerService.LogError(logData)
+			return err
+		}
+
+		logData.Message = "verifyUserIdLogin: device key login challenge is missing"
+		store.LoggerService.LogError(logData)
+		return devicekey.ErrInvalidChallenge
+	}
+
+	_, user, err := store.verifyDeviceKeyLogin(ctx, request.ChallengeId, request.Signature, logData)
+	if err != nil {
+		return err
+	}
+
+	if user.UserId != userId {
+		logData.Message = "verifyUserIdLogin: challenge was signed for another user"
+		store.LoggerService.LogError(logData)
+		return devicekey.ErrInvalidChallenge
+	}
+
+	return nil
 }
 
 func (store *AuthorizationStore) SmsVerification(mobileNumber, msg, simOperator string) error {
@@ -325,6 +314,10 @@ func (store *AuthorizationStore) SmsVerification(mobileNumber, msg, simOperator
 		return err
 	}
 
+	if store.verifyDeviceChangeSms(user.UserId, msg) {
+		return nil
+	}
+
 	msgCode, err := store.Redis.Get(user.UserId)
 	if err != nil {
 		store.LoggerService.LogError(&commonSrv.LogEntry{
@@ -379,6 +372,38 @@ func (store *AuthorizationStore) SmsVerification(mobileNumber, msg, simOperator
 	return nil
 }
 
+// verifyDeviceChangeSms binds the sim of the device a user is moving to when
+// the sms carries the message of the device change
+func (store *AuthorizationStore) verifyDeviceChangeSms(userId, msg string) bool {
+	simKey := fmt.Sprintf(constants.DeviceChangeSimKeyFormat, userId)
+
+	msgCode, err := store.Redis.Get(simKey)
+	if err != nil || msgCode != msg {
+		return false
+	}
+
+	logData := &commonSrv.LogEntry{
+		Action: "ROUTE_MOBILE_CALLBACK",
+		UserID: userId,
+	}
+
+	if err := models.MarkPendingDeviceSimVerified(store.db, userId); err != nil {
+		logData.Message = "SmsVerification: Error updating pending device:- " + err.Error()
+		store.LoggerService.LogError(logData)
+		return false
+	}
+
+	if err := store.Redis.Delete(simKey); err != nil {
+		logData.Message = "SmsVerification: Error deleting device change message:- " + err.Error()
+		store.LoggerService.LogError(logData)
+	}
+
+	logData.Message = "SmsVerification: device change sim verified"
+	store.LoggerService.LogInfo(logData)
+
+	return true
+}
+
 func (store *AuthorizationStore) UpdateAuthorizationCompleteStep(userId string) error {
 	res, err := models.GetDataByStepName(constants.PERSONAL_DETAILS_STEP)
 	if err != nil {
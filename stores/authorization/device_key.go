@@ -0,0 +1,170 @@
+package authorization
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	commonSrv "bitbucket.org/paydoh/paydoh-commons/services"
+
+	"bankapi/config"
+	"bankapi/constants"
+	"bankapi/devicekey"
+	"bankapi/models"
+	"bankapi/requests"
+	"bankapi/security"
+	"bankapi/services"
+	"bankapi/stepup"
+	"bankapi/utils"
+)
+
+var errDeviceKeyLoginUnavailable = errors.New("biometric login is not set up on this device, please login with your mpin")
+
+// DeviceKeyLoginChallenge issues a login challenge for the key of the device
+// of the request. The device must still be the one bound in the device data.
+func (store *AuthorizationStore) DeviceKeyLoginChallenge(ctx context.Context, request *requests.DeviceKeyLoginChallengeRequest) (*devicekey.Challenge, error) {
+	logData := &commonSrv.LogEntry{
+		Action:     constants.AUTHORIZATION,
+		RequestURI: "/api/authorization/device-key/challenge",
+		Message:    "DeviceKeyLoginChallenge log",
+		RequestID:  utils.GetRequestIDFromContext(ctx),
+	}
+
+	deviceId := utils.GetDeviceIdFromContext(ctx)
+	if deviceId == "" {
+		return nil, errors.New("device id should not be empty")
+	}
+
+	user, err := models.GetUserDataByMobileNumber(store.db, request.MobileNumber)
+	if err != nil {
+		if errors.Is(err, constants.ErrUserNotFound) {
+			return nil, errDeviceKeyLoginUnavailable
+		}
+		return nil, err
+	}
+	logData.UserID = user.UserId
+
+	key, err := store.boundDeviceKey(user, deviceId)
+	if err != nil {
+		logData.Message = "DeviceKeyLoginChallenge: " + err.Error()
+		store.LoggerService.LogError(logData)
+		return nil, errDeviceKeyLoginUnavailable
+	}
+
+	challenge, err := devicekey.Issue(ctx, key, devicekey.PurposeLogin, "")
+	if err != nil {
+		logData.Message = "DeviceKeyLoginChallenge: Error issuing challenge:- " + err.Error()
+		store.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+// DeviceKeyLogin logs the device in with a signed login challenge. The
+// signature stands in for the mpin, the new session gets the mpin step-up grant.
+func (store *AuthorizationStore) DeviceKeyLogin(ctx context.Context, request *requests.DeviceKeyLoginRequest) (*TokenPair, error) {
+	logData := &commonSrv.LogEntry{
+		Action:     constants.AUTHORIZATION,
+		RequestURI: "/api/authorization/device-key/login",
+		Message:    "DeviceKeyLogin log",
+		RequestID:  utils.GetRequestIDFromContext(ctx),
+	}
+
+	deviceId := utils.GetDeviceIdFromContext(ctx)
+	challenge, user, err := store.verifyDeviceKeyLogin(ctx, request.ChallengeId, request.Signature, logData)
+	if err != nil {
+		return nil, err
+	}
+
+	userIP := utils.GetSourceIPFromContext(ctx)
+	if userIP == "" {
+		return nil, errors.New("device ip should not be empty")
+	}
+
+	tokens, err := store.createSession(ctx, user.UserId, user.SigningKey, request.ClientPublicKey, utils.GetUserAgentFromContext(ctx), userIP)
+	if err != nil {
+		logData.Message = "DeviceKeyLogin: Error while creating session"
+		store.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	subject := &stepup.Subject{UserId: user.UserId, SessionId: tokens.SessionId, DeviceId: deviceId}
+	if err := stepup.Grant(ctx, subject, config.StepUpFactorMpin); err != nil {
+		logData.Message = "DeviceKeyLogin: Error granting step-up:- " + err.Error()
+		store.LoggerService.LogError(logData)
+	}
+
+	if err := store.auditLogService.Save(ctx, &services.AuditLog{
+		UserID:         user.UserId,
+		ApplicantID:    user.ApplicantId,
+		SourceIP:       userIP,
+		DeviceID:       deviceId,
+		RequestURL:     "/api/authorization/device-key/login",
+		HTTPMethod:     http.MethodPost,
+		RequestBody:    fmt.Sprintf("key=%s session=%s", challenge.KeyId, tokens.SessionId),
+		ResponseStatus: http.StatusOK,
+		Action:         constants.DEVICE_KEY_LOGIN,
+	}); err != nil {
+		logData.Message = "DeviceKeyLogin: Error while saving audit log"
+		store.LoggerService.LogError(logData)
+	}
+
+	logData.Message = "DeviceKeyLogin: Token generated successfully"
+	store.LoggerService.LogInfo(logData)
+
+	return tokens, nil
+}
+
+// verifyDeviceKeyLogin returns the user of a login challenge signed by the key
+// of the device of the request
+func (store *AuthorizationStore) verifyDeviceKeyLogin(ctx context.Context, challengeId, signature string, logData *commonSrv.LogEntry) (*devicekey.Challenge, *models.UserData, error) {
+	deviceId := utils.GetDeviceIdFromContext(ctx)
+	challenge, _, err := devicekey.Verify(ctx, store.db, challengeId, devicekey.PurposeLogin, deviceId, signature)
+	if err != nil {
+		logData.Message = "verifyDeviceKeyLogin: " + err.Error()
+		store.LoggerService.LogError(logData)
+		return nil, nil, err
+	}
+	logData.UserID = challenge.UserId
+
+	user, err := models.GetUserDataByUserId(store.db, challenge.UserId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the device may have been changed since the challenge was issued
+	if _, err := store.boundDeviceKey(user, deviceId); err != nil {
+		logData.Message = "verifyDeviceKeyLogin: " + err.Error()
+		store.LoggerService.LogError(logData)
+		return nil, nil, errDeviceKeyLoginUnavailable
+	}
+
+	return challenge, user, nil
+}
+
+// boundDeviceKey returns the active key of the device when the device is the
+// one bound in the device data of the user
+func (store *AuthorizationStore) boundDeviceKey(user *models.UserData, deviceId string) (*models.DeviceKey, error) {
+	key, err := models.FindActiveDeviceKey(store.db, user.UserId, deviceId)
+	if err != nil {
+		return nil, err
+	}
+
+	signingKey, err := security.Decrypt(user.SigningKey, []byte(constants.AesPassPhrase))
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting signing key: %v", err)
+	}
+
+	deviceData, err := devicekey.BoundDeviceData(store.db, user.UserId, signingKey, deviceId)
+	if err != nil {
+		return nil, err
+	}
+
+	if deviceData.Id.String() != key.DeviceDataId {
+		return nil, constants.ErrDeviceNotTrusted
+	}
+
+	return key, nil
+}
@@ -0,0 +1,268 @@
+package authorization
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	commonSrv "bitbucket.org/paydoh/paydoh-commons/services"
+
+	"bankapi/config"
+	"bankapi/constants"
+	"bankapi/keyring"
+	"bankapi/metrics"
+	"bankapi/models"
+	"bankapi/requests"
+	"bankapi/security"
+	"bankapi/sessionkey"
+	"bankapi/utils"
+)
+
+const refreshTokenSize = 32
+
+// TokenPair is a short lived access token and the refresh token of its device session
+type TokenPair struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	SessionId    string `json:"session_id"`
+	ExpiresIn    int64  `json:"expires_in"`
+	// ServerPublicKey completes the ECDH exchange of the session key, it is
+	// set when the login sent a client_public_key
+	ServerPublicKey string `json:"server_public_key,omitempty"`
+}
+
+// createSession logs the device of the request in. An earlier session of the
+// same device is revoked, sessions of other devices stay active.
+// clientPublicKey is the ECDH public key of the app, the session key agreed
+// with it is kept server side and never sent.
+func (store *AuthorizationStore) createSession(ctx context.Context, userId, signingKey, clientPublicKey, userAgent, userIP string) (*TokenPair, error) {
+	keys, serverPublicKey, err := newSessionKeys(userId, signingKey, clientPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if !keys.Exchanged {
+		metrics.IncLegacySessionKey(metrics.LegacySessionLogin)
+	}
+
+	deviceId := utils.GetDeviceIdFromContext(ctx)
+	if deviceId != "" {
+		revoked, err := models.RevokeUserSessions(store.db, userId, deviceId, models.SessionRevokedRelogin)
+		if err != nil {
+			return nil, err
+		}
+		store.forgetSessions(ctx, revoked)
+	}
+
+	session := models.NewUserSession()
+	session.UserId = userId
+	session.DeviceId = sql.NullString{String: deviceId, Valid: deviceId != ""}
+	session.UserAgent = userAgent
+	session.IpAddress = userIP
+	session.ExpiresAt = time.Now().Add(config.GetRefreshTokenTTL())
+
+	if err := models.CreateUserSession(store.db, session); err != nil {
+		return nil, err
+	}
+
+	tokens, err := store.issueTokens(session, keys, userAgent, userIP)
+	if err != nil {
+		return nil, err
+	}
+	tokens.ServerPublicKey = serverPublicKey
+
+	return tokens, nil
+}
+
+// newSessionKeys agrees a session key with the app when it sent a public key.
+// Apps that did not are rejected unless SESSION_KEY_EXCHANGE_LEGACY lets them
+// keep using the key of the user.
+func newSessionKeys(userId, signingKey, clientPublicKey string) (*sessionkey.Session, string, error) {
+	decoded, err := security.Decrypt(signingKey, []byte(constants.AesPassPhrase))
+	if err != nil {
+		return nil, "", fmt.Errorf("error decrypting signing key: %v", err)
+	}
+
+	keys := &sessionkey.Session{
+		UserId:     userId,
+		Key:        decoded,
+		SigningKey: decoded,
+	}
+
+	if clientPublicKey == "" {
+		if !config.IsLegacySessionKeyAllowed() {
+			return nil, "", errors.New(constants.SessionKeyExchangeRequiredErrorMessage)
+		}
+		return keys, "", nil
+	}
+
+	serverPublicKey, sessionKey, err := security.DeriveSessionKey(clientPublicKey)
+	if err != nil {
+		return nil, "", err
+	}
+	keys.Key = sessionKey
+	keys.Exchanged = true
+
+	return keys, serverPublicKey, nil
+}
+
+// issueTokens signs an access token bound to the session and adds a refresh token to its family
+func (store *AuthorizationStore) issueTokens(session *models.UserSession, keys *sessionkey.Session, userAgent, userIP string) (*TokenPair, error) {
+	// the token carries the session id only, its keys are read from the session
+	subject := session.Id
+	if !keys.Exchanged {
+		// apps that did not exchange a session key read the key of the user from the token
+		userId := session.UserId
+		subject = fmt.Sprintf("%s%s|%s%s|%s", keys.SigningKey[:3], userId[3:], userId[:3], keys.SigningKey[3:], session.Id)
+	}
+
+	token, err := keyring.GenerateJWT(subject, userAgent, userIP, constants.JwtExpTime, "paydoh-bank")
+	if err != nil {
+		return nil, fmt.Errorf("error generating jwt token: %v", err)
+	}
+
+	refreshToken, err := security.GenerateOpaqueToken(refreshTokenSize)
+	if err != nil {
+		return nil, fmt.Errorf("error generating refresh token: %v", err)
+	}
+
+	if err := models.CreateRefreshToken(store.db, session.Id, security.HashToken(refreshToken), session.ExpiresAt); err != nil {
+		return nil, err
+	}
+
+	// access tokens of a session are accepted while it is saved
+	if err := sessionkey.Save(session.Id, keys, time.Until(session.ExpiresAt)); err != nil {
+		return nil, fmt.Errorf("error setting session in redis: %v", err)
+	}
+
+	return &TokenPair{
+		Token:        token,
+		RefreshToken: refreshToken,
+		SessionId:    session.Id,
+		ExpiresIn:    int64(constants.JwtExpTime.Seconds()),
+	}, nil
+}
+
+// RefreshToken exchanges a refresh token for a new token pair. Every refresh
+// token is valid once and only from the device of its session, presenting a
+// used one or one from another device means it was stolen, so the whole
+// session is revoked.
+func (store *AuthorizationStore) RefreshToken(ctx context.Context, request *requests.RefreshTokenRequest) (*TokenPair, error) {
+	logData := &commonSrv.LogEntry{
+		Action:     constants.AUTHORIZATION,
+		RequestURI: "/api/authorization/refresh",
+		Message:    "RefreshToken log",
+		RequestID:  utils.GetRequestIDFromContext(ctx),
+	}
+
+	refreshToken, err := models.ConsumeRefreshToken(store.db, security.HashToken(request.RefreshToken))
+	if err != nil {
+		if errors.Is(err, constants.ErrRefreshTokenReused) {
+			logData.Message = fmt.Sprintf("RefreshToken: reused refresh token, revoking session %s", refreshToken.SessionId)
+			store.LoggerService.LogError(logData)
+
+			if err := models.RevokeUserSession(store.db, refreshToken.SessionId, models.SessionRevokedReuse); err != nil {
+				return nil, err
+			}
+			store.forgetSessions(ctx, []string{refreshToken.SessionId})
+		}
+		return nil, errors.New(constants.InvalidRefreshTokenErrorMessage)
+	}
+
+	session, err := models.GetUserSession(store.db, refreshToken.SessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.RevokedAt.Valid || time.Now().After(refreshToken.ExpiresAt) {
+		return nil, errors.New(constants.InvalidRefreshTokenErrorMessage)
+	}
+
+	if deviceId := utils.GetDeviceIdFromContext(ctx); session.DeviceId.Valid && session.DeviceId.String != deviceId {
+		logData.UserID = session.UserId
+		logData.Message = fmt.Sprintf("RefreshToken: refresh token of device %s presented by device %s, revoking session %s", session.DeviceId.String, deviceId, session.Id)
+		store.LoggerService.LogError(logData)
+
+		if err := models.RevokeUserSession(store.db, session.Id, models.SessionRevokedDeviceMismatch); err != nil {
+			return nil, err
+		}
+		store.forgetSessions(ctx, []string{session.Id})
+		return nil, errors.New(constants.InvalidRefreshTokenErrorMessage)
+	}
+
+	keys, err := sessionkey.Load(session.Id)
+	if err != nil {
+		// sessions from before the keys were kept server side use the key of the user
+		if !config.IsLegacySessionKeyAllowed() {
+			return nil, errors.New(constants.InvalidRefreshTokenErrorMessage)
+		}
+
+		user, err := models.GetUserDataByUserId(store.db, session.UserId)
+		if err != nil {
+			return nil, err
+		}
+
+		keys, _, err = newSessionKeys(user.UserId, user.SigningKey, "")
+		if err != nil {
+			return nil, err
+		}
+		metrics.IncLegacySessionKey(metrics.LegacySessionRefresh)
+	}
+
+	session.ExpiresAt = time.Now().Add(config.GetRefreshTokenTTL())
+	if err := models.TouchUserSession(store.db, session.Id, session.ExpiresAt); err != nil {
+		return nil, err
+	}
+
+	logData.UserID = session.UserId
+	logData.Message = "RefreshToken: session refreshed"
+	store.LoggerService.LogInfo(logData)
+
+	return store.issueTokens(session, keys, utils.GetUserAgentFromContext(ctx), utils.GetSourceIPFromContext(ctx))
+}
+
+// ListSessions returns the logged in devices of a user, marking the one making the request
+func (store *AuthorizationStore) ListSessions(ctx context.Context, authValues *models.AuthValues) ([]models.UserSession, error) {
+	sessions, err := models.ListActiveUserSessions(store.db, authValues.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].Id == authValues.SessionId
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession logs a device of the user out, its access token stops working immediately
+func (store *AuthorizationStore) RevokeSession(ctx context.Context, authValues *models.AuthValues, sessionId string) error {
+	session, err := models.GetUserSession(store.db, sessionId)
+	if err != nil {
+		return err
+	}
+
+	if session.UserId != authValues.UserId {
+		return constants.ErrNoDataFound
+	}
+
+	if err := models.RevokeUserSession(store.db, session.Id, models.SessionRevokedByUser); err != nil {
+		return err
+	}
+	store.forgetSessions(ctx, []string{session.Id})
+
+	return nil
+}
+
+func (store *AuthorizationStore) forgetSessions(ctx context.Context, sessionIds []string) {
+	for _, sessionId := range sessionIds {
+		if err := store.Redis.Delete(fmt.Sprintf(constants.SessionKeyFormat, sessionId)); err != nil {
+			store.LoggerService.LogError(&commonSrv.LogEntry{
+				Action:    constants.AUTHORIZATION,
+				Message:   fmt.Sprintf("forgetSessions: Error deleting key of session %s:- %s", sessionId, err.Error()),
+				RequestID: utils.GetRequestIDFromContext(ctx),
+			})
+		}
+	}
+}
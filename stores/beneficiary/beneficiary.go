@@ -13,6 +13,7 @@ import (
 	commonSrv "bitbucket.org/paydoh/paydoh-commons/services"
 	"bitbucket.org/paydoh/paydoh-commons/types"
 
+	"bankapi/config"
 	"bankapi/constants"
 	"bankapi/models"
 	"bankapi/requests"
@@ -27,22 +28,24 @@ type Beneficiary interface {
 }
 
 type Store struct {
-	db            *sql.DB
-	memory        *database.InMemory
-	m             *database.Document
-	redis         *database.InMemory
-	bankService   *services.BankApiService
-	LoggerService *commonSrv.LoggerService
+	db                  *sql.DB
+	memory              *database.InMemory
+	m                   *database.Document
+	redis               *database.InMemory
+	bankService         services.BankProvider
+	notificationService *services.NotificationService
+	LoggerService       *commonSrv.LoggerService
 }
 
 func NewStore(log *commonSrv.LoggerService, db *sql.DB, m *database.Document, memory *database.InMemory) *Store {
 	return &Store{
-		db:            db,
-		memory:        memory,
-		m:             m,
-		redis:         memory,
-		LoggerService: log,
-		bankService:   services.NewBankApiService(log, memory),
+		db:                  db,
+		memory:              memory,
+		m:                   m,
+		redis:               memory,
+		LoggerService:       log,
+		bankService:         services.NewBankProvider(log, memory),
+		notificationService: services.NewNotificationService(),
 	}
 }
 
@@ -358,15 +361,13 @@ func (s *Store) AddBeneficiary(ctx context.Context, authValues *models.AuthValue
 
 	response, opErr = s.bankService.AddBeneficiary(ctx, request)
 	if opErr != nil {
-		bankErr := s.bankService.HandleBankSpecificError(opErr, func(errorCode string) (string, bool) {
-			return constants.GetBeneficiaryErrorMessage(errorCode)
-		})
+		bankErr := s.bankService.HandleBankSpecificError(ctx, opErr)
 
 		if bankErr != nil {
 			logData.Message = fmt.Sprintf("AddBeneficiary: Bank error encountered (ErrorCode: %s)", bankErr.ErrorCode)
 			s.LoggerService.LogError(logData)
 
-			if msg, retryable := constants.GetBeneficiaryRetryErrorMessage(bankErr.ErrorCode); retryable {
+			if bankErr.Retryable {
 				// Scenario 7- Beneficiary details sent to KVB, but Error Received (Technical issue)
 				if bankErr.ErrorCode == constants.RetryErrorMW9999 {
 					request.ResendOtp = "N"
@@ -406,19 +407,19 @@ func (s *Store) AddBeneficiary(ctx context.Context, authValues *models.AuthValue
 					}
 
 					return nil
-				}, 2)
+				}, bankErr.MaxRetries)
 
 				if retryErr != nil {
 					logData.Message = fmt.Sprintf("AddBeneficiary: Adding beneficiary failed after %d retries", retryCount)
 					s.LoggerService.LogError(logData)
-					return nil, errors.New(msg)
+					return nil, bankErr
 				}
 
 				logData.Message = fmt.Sprintf("AddBeneficiary: Request succeeded after %d retries", retryCount)
 				s.LoggerService.LogInfo(logData)
 				return response, nil
 			} else {
-				return nil, errors.New(bankErr.ErrorMessage)
+				return nil, bankErr
 			}
 		} else {
 			return nil, opErr
@@ -601,9 +602,7 @@ func (s *Store) ValidateOTPBeneficiary(ctx context.Context, authValues *models.A
 
 	response, opErr = s.bankService.SubmitOtpBeneficiaryAddition(ctx, request)
 	if opErr != nil {
-		bankErr := s.bankService.HandleBankSpecificError(opErr, func(errorCode string) (string, bool) {
-			return constants.GetBeneficiaryErrorMessage(errorCode)
-		})
+		bankErr := s.bankService.HandleBankSpecificError(ctx, opErr)
 
 		if bankErr != nil {
 			logData.Message = fmt.Sprintf("ValidateOTPBeneficiary: Bank error encountered (ErrorCode: %s)", bankErr.ErrorCode)
@@ -661,6 +660,7 @@ func (s *Store) ValidateOTPBeneficiary(ctx context.Context, authValues *models.A
 
 				logData.Message = fmt.Sprintf("ValidateOTPBeneficiary: OTP submission succeeded after %d retries", retryCount)
 				s.LoggerService.LogInfo(logData)
+				s.beneficiaryAdded(logData, authValues.UserId, beneficiaryId)
 				return response, nil
 			}
 
@@ -682,6 +682,8 @@ func (s *Store) ValidateOTPBeneficiary(ctx context.Context, authValues *models.A
 		return nil, err
 	}
 
+	s.beneficiaryAdded(logData, authValues.UserId, benfDataMap["benf_id"])
+
 	// Senerio 0 - Request Sucessfully submited and Response Received For Beneficiary addition to KVB for OTP verification we are saving this data in redis
 	// For Better approch we are passing this Key from the constants file
 	benfKey := fmt.Sprintf(constants.BeneficiaryKey, authValues.UserId)
@@ -728,16 +730,133 @@ func (s *Store) BeneficiaryPayment(ctx context.Context, authValues *models.AuthV
 		RequestID:  utils.GetRequestIDFromContext(ctx),
 	}
 
-	existingDevice, err := models.GetUserDataByUserId(s.db, authValues.UserId)
+	response, txnIdentifier, err := s.SubmitPayment(ctx, logData, authValues.UserId, authValues.DeviceId, r)
+	if err != nil {
+		return nil, err
+	}
 
+	// save transaction id to cache
+	err = s.memory.Set(fmt.Sprintf("beneficiary:payment:transaction:%s", authValues.UserId), txnIdentifier, time.Hour)
 	if err != nil {
-		logData.Message = "BeneficiaryPayment: Error getting user data"
+		return nil, err
+	}
+
+	byteudd, err := json.Marshal(response)
+
+	if err != nil {
+		logData.Message = "BeneficiaryPayment: Error marshaling response"
 		s.LoggerService.LogError(logData)
 		return nil, err
 	}
 
+	encrypted, err := security.Encrypt(byteudd, []byte(authValues.Key))
+
+	if err != nil {
+		logData.Message = "BeneficiaryPayment: Error encrypting response"
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	logData.Message = "BeneficiaryPayment: Response encrypted successfully"
+	logData.ResponseSize = len(byteudd)
+	logData.ResponseBody = string(byteudd)
+	logData.EndTime = time.Now()
+	s.LoggerService.LogInfo(logData)
+
+	return encrypted, nil
+}
+
+// SubmitPayment submits a payment to a beneficiary and records its
+// transaction, the bank sends the user the otp that confirms it. The cooling
+// limit of deviceId and the transfer limits of the user apply to the amount.
+func (s *Store) SubmitPayment(ctx context.Context, logData *commonSrv.LogEntry, userId, deviceId string, r *requests.PaymentRequest) (*responses.PaymentSubmissionResponse, string, error) {
+	return s.submitPayment(ctx, logData, userId, deviceId, r, true)
+}
+
+// SubmitPayoutPayment submits a quick transfer like SubmitPayment without
+// saving its account as an inactive beneficiary, for payouts to accounts the
+// user does not add
+func (s *Store) SubmitPayoutPayment(ctx context.Context, logData *commonSrv.LogEntry, userId, deviceId string, r *requests.PaymentRequest) (*responses.PaymentSubmissionResponse, string, error) {
+	return s.submitPayment(ctx, logData, userId, deviceId, r, false)
+}
+
+func (s *Store) submitPayment(ctx context.Context, logData *commonSrv.LogEntry, userId, deviceId string, r *requests.PaymentRequest, saveQuickTransfer bool) (_ *responses.PaymentSubmissionResponse, _ string, err error) {
+	existingDevice, err := models.GetUserDataByUserId(s.db, userId)
+
+	if err != nil {
+		logData.Message = "BeneficiaryPayment: Error getting user data"
+		s.LoggerService.LogError(logData)
+		return nil, "", err
+	}
+
 	if strings.ToLower(r.PaymentMode) == "ift" && r.BenfIfsc[:4] != "KVBL" {
-		return nil, errors.New("invalid ifsc code for ift payment mode")
+		return nil, "", errors.New("invalid ifsc code for ift payment mode")
+	}
+
+	if err := models.CheckBeneficiaryPayable(s.db, userId, r.BenfAcctNo, r.BenfIfsc); err != nil {
+		logData.Message = "BeneficiaryPayment: " + err.Error()
+		s.LoggerService.LogError(logData)
+		return nil, "", err
+	}
+
+	// a resent otp is for a payment already counted, what is counted is taken
+	// back when the payment is not submitted
+	var (
+		resendTxn                string
+		deviceCoolingReservation *models.DeviceCoolingReservation
+		coolingReservation       *models.BeneficiaryCoolingReservation
+		reservation              *models.TransferLimitReservation
+	)
+
+	if r.ResendOtp == "Y" {
+		resendTxn, err = s.resentPayment(userId, r)
+		if err != nil {
+			logData.Message = "BeneficiaryPayment: " + err.Error()
+			s.LoggerService.LogError(logData)
+			return nil, "", err
+		}
+	} else {
+		defer func() {
+			if err == nil {
+				return
+			}
+			if err := models.ReleaseDeviceCooling(s.db, deviceCoolingReservation); err != nil {
+				logData.Message = "BeneficiaryPayment: " + err.Error()
+				s.LoggerService.LogError(logData)
+			}
+			if err := models.ReleaseBeneficiaryCooling(s.db, coolingReservation); err != nil {
+				logData.Message = "BeneficiaryPayment: " + err.Error()
+				s.LoggerService.LogError(logData)
+			}
+			if reservation == nil {
+				return
+			}
+			if err := models.ReleaseTransferLimit(s.db, reservation); err != nil {
+				logData.Message = "BeneficiaryPayment: " + err.Error()
+				s.LoggerService.LogError(logData)
+			}
+		}()
+
+		deviceCoolingReservation, err = models.ReserveDeviceCooling(s.db, userId, deviceId, r.Amount)
+		if err != nil {
+			logData.Message = "BeneficiaryPayment: " + err.Error()
+			s.LoggerService.LogError(logData)
+			return nil, "", err
+		}
+
+		coolingReservation, err = models.ReserveBeneficiaryCooling(s.db, userId, r.BenfAcctNo, r.BenfIfsc, r.Amount)
+		if err != nil {
+			logData.Message = "BeneficiaryPayment: " + err.Error()
+			s.LoggerService.LogError(logData)
+			return nil, "", err
+		}
+
+		reservation, err = models.ReserveTransferLimit(s.db, userId, r.PaymentMode, r.Amount)
+		if err != nil {
+			logData.Message = "BeneficiaryPayment: " + err.Error()
+			s.LoggerService.LogError(logData)
+			return nil, "", err
+		}
 	}
 
 	existingAccount, err := models.GetAccountDataByUserId(s.db, existingDevice.UserId)
@@ -745,11 +864,11 @@ func (s *Store) BeneficiaryPayment(ctx context.Context, authValues *models.AuthV
 	if err != nil {
 		logData.Message = "BeneficiaryPayment: Error getting account data"
 		s.LoggerService.LogError(logData)
-		return nil, err
+		return nil, "", err
 	}
 
 	if r.BenfAcctNo == existingAccount.AccountNumber {
-		return nil, errors.New("self transfer not allowed")
+		return nil, "", errors.New("self transfer not allowed")
 	}
 
 	ifscData, err := models.GetIFSCData(s.db, r.BenfIfsc)
@@ -757,12 +876,12 @@ func (s *Store) BeneficiaryPayment(ctx context.Context, authValues *models.AuthV
 		if errors.Is(err, constants.ErrNoDataFound) {
 			logData.Message = "BeneficiaryPayment: Invalid IFSC code provided"
 			s.LoggerService.LogError(logData)
-			return nil, errors.New(constants.InputErrorMessage)
+			return nil, "", errors.New(constants.InputErrorMessage)
 		}
 
 		logData.Message = "BeneficiaryPayment: Error fetching IFSC data"
 		s.LoggerService.LogError(logData)
-		return nil, err
+		return nil, "", err
 	}
 
 	logData.Message = fmt.Sprintf("BeneficiaryPayment: IFSC data found: %v", ifscData.IfscCode)
@@ -773,18 +892,11 @@ func (s *Store) BeneficiaryPayment(ctx context.Context, authValues *models.AuthV
 	if err := request.Bind(existingDevice.ApplicantId, existingAccount.AccountNumber, r); err != nil {
 		logData.Message = "BeneficiaryPayment: Error binding payment request"
 		s.LoggerService.LogError(logData)
-		return nil, err
+		return nil, "", err
 	}
 
 	if request.ResendOtp == "Y" {
-		txn, err := s.memory.Get(fmt.Sprintf("beneficiary:payment:transaction:%s", authValues.UserId))
-		if err != nil {
-			return nil, err
-		}
-		if txn == "null" {
-			return nil, err
-		}
-		request.TxnIdentifier = txn
+		request.TxnIdentifier = resendTxn
 	}
 
 	var response *responses.PaymentSubmissionResponse
@@ -792,65 +904,61 @@ func (s *Store) BeneficiaryPayment(ctx context.Context, authValues *models.AuthV
 
 	response, opErr = s.bankService.PaymentSubmission(ctx, request)
 	if opErr != nil {
-		bankErr := s.bankService.HandleBankSpecificError(opErr, func(errorCode string) (string, bool) {
-			return constants.GetPaymentCallbackErrorMessage(errorCode)
-		})
-
-		if bankErr != nil {
-			logData.Message = fmt.Sprintf("Bank error encountered (ErrorCode: %s)", bankErr.ErrorCode)
-			s.LoggerService.LogError(logData)
+		bankErr := s.bankService.HandleBankSpecificError(ctx, opErr)
+		if bankErr == nil {
+			return nil, "", opErr
+		}
 
-			if bankErr.ErrorCode == constants.PaymentCallbackErrorCodeMW0014 {
-				response = &responses.PaymentSubmissionResponse{
-					ApplicantId:   request.ApplicantId,
-					AccountNo:     request.AccountNo,
-					TxnStatus:     response.TxnStatus,
-					ErrorCode:     bankErr.ErrorCode,
-					ErrorMessage:  bankErr.ErrorMessage,
-					TxnIdentifier: request.TxnIdentifier,
-					TxnRefNo:      response.TxnRefNo,
-				}
-			} else if msg, retryable := constants.GetPaymentCallbackRetryErrorMessage(bankErr.ErrorCode); retryable {
-				err := utils.RetryFunc(func() error {
-					response, opErr = s.bankService.PaymentSubmission(ctx, request)
-					return opErr
-				}, 2)
+		logData.Message = fmt.Sprintf("Bank error encountered (ErrorCode: %s)", bankErr.ErrorCode)
+		s.LoggerService.LogError(logData)
 
-				if err != nil {
-					logData.Message = "BeneficiaryPayment: Callback failed after retries"
-					s.LoggerService.LogError(logData)
-					return nil, errors.New(msg)
-				}
-			} else {
-				return nil, errors.New(bankErr.ErrorMessage)
+		switch {
+		case bankErr.ErrorCode == constants.PaymentCallbackErrorCodeMW0014:
+			// the bank answered with an error instead of a payment, so there is
+			// no status or reference to carry over
+			response = &responses.PaymentSubmissionResponse{
+				ApplicantId:   request.ApplicantId,
+				AccountNo:     request.AccountNo,
+				ErrorCode:     bankErr.ErrorCode,
+				ErrorMessage:  bankErr.ErrorMessage,
+				TxnIdentifier: request.TxnIdentifier,
+			}
+		case bankErr.Retryable:
+			if err := utils.RetryFunc(func() error {
+				response, opErr = s.bankService.PaymentSubmission(ctx, request)
+				return opErr
+			}, bankErr.MaxRetries); err != nil {
+				logData.Message = "BeneficiaryPayment: Callback failed after retries"
+				s.LoggerService.LogError(logData)
+				return nil, "", bankErr
 			}
+		default:
+			return nil, "", bankErr
 		}
-
-		return nil, opErr
 	}
 
 	if response == nil {
 		logData.Message = "BeneficiaryPayment: Unknown error"
 		s.LoggerService.LogError(logData)
-		return nil, err
+		return nil, "", errors.New(constants.RetryErrorMessage)
 	}
 
-	_, err = models.FindOneTransactionByUserAndTransactionId(s.db, authValues.UserId, request.TxnIdentifier)
+	_, err = models.FindOneTransactionByUserAndTransactionId(s.db, userId, request.TxnIdentifier)
 
 	if err != nil {
 		if !errors.Is(err, constants.ErrNoDataFound) {
 			logData.Message = "BeneficiaryPayment: Error finding transaction details"
 			s.LoggerService.LogError(logData)
-			return nil, err
+			return nil, "", err
 		}
 
 		// add quick transfer beneficiary in benficiaries table db with inactive flag
 		var benfID string
 		var benfUUID string
 
-		if strings.ToLower(r.QuickTransfer) == "y" {
+		if strings.ToLower(r.QuickTransfer) == "y" && saveQuickTransfer {
 			existingBeneficiary, err := models.FindBeneficiaryByNameAndIfscCodeV2(s.db,
-				authValues.UserId,
+				userId,
 				r.BenfName,
 				request.BenfIfsc,
 				r.BenfNickName,
@@ -859,7 +967,7 @@ func (s *Store) BeneficiaryPayment(ctx context.Context, authValues *models.AuthV
 			if err != nil && !errors.Is(err, constants.ErrNoDataFound) {
 				logData.Message = "BeneficiaryPayment: FindBeneficiaryByNameAndIfscCode Error fetching beneficiaries from the database"
 				s.LoggerService.LogError(logData)
-				return nil, err
+				return nil, "", err
 			}
 
 			if existingBeneficiary == nil {
@@ -871,7 +979,7 @@ func (s *Store) BeneficiaryPayment(ctx context.Context, authValues *models.AuthV
 				benfUUID, err = models.InsertBeneficiaryDetails(s.db, &models.BeneficiaryDTO{
 					BenfId:        benfID,
 					BenfNickName:  types.FromString(benfID),
-					UserId:        authValues.UserId,
+					UserId:        userId,
 					BenfName:      request.BenfName,
 					BenfMobNo:     request.BenfMobNo,
 					BenfIfsc:      request.BenfIfsc,
@@ -883,10 +991,10 @@ func (s *Store) BeneficiaryPayment(ctx context.Context, authValues *models.AuthV
 				if err != nil {
 					logData.Message = "BeneficiaryPayment: Error inserting beneficiary details"
 					s.LoggerService.LogError(logData)
-					return nil, err
+					return nil, "", err
 				}
 			}
-		} else {
+		} else if strings.ToLower(r.QuickTransfer) != "y" {
 			ben, err := models.GetBeneficiaryByID(s.db, r.BenfId, request.BenfAcctNo, request.BenfMobNo, request.BenfIfsc)
 			if err != nil {
 				logData.Message = "GetBeneficiaryByID: Error fetching beneficiary details"
@@ -901,7 +1009,7 @@ func (s *Store) BeneficiaryPayment(ctx context.Context, authValues *models.AuthV
 
 		// save transaction details to db
 		if err := models.InsertTransaction(s.db, &models.Transaction{
-			UserID:          authValues.UserId,
+			UserID:          userId,
 			TransactionID:   request.TxnIdentifier,
 			Amount:          types.FromString(request.Amount),
 			PaymentMode:     models.PaymentMode(request.PaymentMode),
@@ -910,19 +1018,105 @@ func (s *Store) BeneficiaryPayment(ctx context.Context, authValues *models.AuthV
 		}); err != nil {
 			logData.Message = "BeneficiaryPayment: Error inserting transaction details"
 			s.LoggerService.LogError(logData)
-			return nil, err
+			return nil, "", err
 		}
 	}
-	// save transaction id to cache
-	err = s.memory.Set(fmt.Sprintf("beneficiary:payment:transaction:%s", authValues.UserId), request.TxnIdentifier, time.Hour)
+
+	// the usage is kept until the otp is confirmed, or released when it fails
+	// or is not entered in time
+	expiresAt := time.Now().Add(config.GetTransferLimitConfig().OtpHold)
+	if reservation != nil {
+		err = models.SavePaymentReservation(s.db, models.NewPaymentReservation(request.TxnIdentifier, reservation, coolingReservation, deviceCoolingReservation, r.BenfAcctNo, r.BenfIfsc, expiresAt))
+	} else {
+		err = models.ExtendPaymentReservation(s.db, request.TxnIdentifier, expiresAt)
+	}
+	if err != nil {
+		logData.Message = "BeneficiaryPayment: " + err.Error()
+		s.LoggerService.LogError(logData)
+		return nil, "", err
+	}
+
+	return response, request.TxnIdentifier, nil
+}
+
+// ExpirePaymentReservations releases the usage of payments whose otp was not
+// entered within TRANSFER_LIMIT_OTP_HOLD
+func (s *Store) ExpirePaymentReservations(ctx context.Context) {
+	logData := &commonSrv.LogEntry{
+		Action:    constants.BENEFICIARY,
+		Message:   "ExpirePaymentReservations log",
+		RequestID: utils.GetRequestIDFromContext(ctx),
+	}
+
+	released, err := models.ExpirePaymentReservations(s.db)
+	if err != nil {
+		logData.Message = "ExpirePaymentReservations: Error releasing payment reservations:- " + err.Error()
+		s.LoggerService.LogError(logData)
+	}
+
+	if released > 0 {
+		logData.Message = fmt.Sprintf("ExpirePaymentReservations: released %d payment reservations", released)
+		s.LoggerService.LogInfo(logData)
+	}
+}
+
+// resentPayment returns the payment an otp is sent again for. It must be the
+// last payment of the user, not yet confirmed, and for the same amount and
+// account, as what is counted against the limits is not checked again.
+func (s *Store) resentPayment(userId string, r *requests.PaymentRequest) (string, error) {
+	txn, err := s.memory.Get(fmt.Sprintf("beneficiary:payment:transaction:%s", userId))
+	if err != nil {
+		return "", err
+	}
+	if txn == "" || txn == "null" {
+		return "", errors.New(constants.PaymentReservationNotFoundErrorMessage)
+	}
+
+	paymentReservation, err := models.GetPaymentReservation(s.db, userId, txn)
+	if err != nil {
+		if errors.Is(err, constants.ErrNoDataFound) {
+			return "", errors.New(constants.PaymentReservationNotFoundErrorMessage)
+		}
+		return "", err
+	}
+
+	if paymentReservation.Status == models.PaymentReservationStatusConfirmed {
+		return "", errors.New(constants.PaymentReservationConfirmedErrorMessage)
+	}
+
+	if !paymentReservation.Matches(r.PaymentMode, r.Amount, r.BenfAcctNo, r.BenfIfsc) {
+		return "", errors.New(constants.PaymentReservationMismatchErrorMessage)
+	}
+
+	return txn, nil
+}
+
+func (s *Store) BeneficiaryPaymentOTP(ctx context.Context, authValues *models.AuthValues, otp string) (interface{}, error) {
+	logData := &commonSrv.LogEntry{
+		Action:     constants.BENEFICIARY,
+		RequestURI: "/api/beneficiary/payment-otp",
+		Message:    "Initiating beneficiary payment OTP",
+		UserID:     utils.GetUserIDFromContext(ctx),
+		RequestID:  utils.GetRequestIDFromContext(ctx),
+	}
+
+	// get transaction id from cache
+	txn, err := s.memory.Get(fmt.Sprintf("beneficiary:payment:transaction:%s", authValues.UserId))
 	if err != nil {
 		return nil, err
 	}
 
+	response, err := s.ConfirmPayment(ctx, logData, authValues.UserId, txn, otp)
+	if err != nil {
+		return nil, err
+	}
+
+	response.TxnDateTime = time.Now()
+
 	byteudd, err := json.Marshal(response)
 
 	if err != nil {
-		logData.Message = "BeneficiaryPayment: Error marshaling response"
+		logData.Message = "BeneficiaryPaymentOTP: Error marshaling response"
 		s.LoggerService.LogError(logData)
 		return nil, err
 	}
@@ -930,12 +1124,12 @@ func (s *Store) BeneficiaryPayment(ctx context.Context, authValues *models.AuthV
 	encrypted, err := security.Encrypt(byteudd, []byte(authValues.Key))
 
 	if err != nil {
-		logData.Message = "BeneficiaryPayment: Error encrypting response"
+		logData.Message = "BeneficiaryPaymentOTP: Error encrypting response"
 		s.LoggerService.LogError(logData)
 		return nil, err
 	}
 
-	logData.Message = "BeneficiaryPayment: Response encrypted successfully"
+	logData.Message = "BeneficiaryPaymentOTP: Response encrypted successfully"
 	logData.ResponseSize = len(byteudd)
 	logData.ResponseBody = string(byteudd)
 	logData.EndTime = time.Now()
@@ -944,16 +1138,39 @@ func (s *Store) BeneficiaryPayment(ctx context.Context, authValues *models.AuthV
 	return encrypted, nil
 }
 
-func (s *Store) BeneficiaryPaymentOTP(ctx context.Context, authValues *models.AuthValues, otp string) (interface{}, error) {
-	logData := &commonSrv.LogEntry{
-		Action:     constants.BENEFICIARY,
-		RequestURI: "/api/beneficiary/payment-otp",
-		Message:    "Initiating beneficiary payment OTP",
-		UserID:     utils.GetUserIDFromContext(ctx),
-		RequestID:  utils.GetRequestIDFromContext(ctx),
+// ConfirmPayment confirms a payment submitted by SubmitPayment with the otp
+// the bank sent and updates its transaction. The usage of the payment is kept
+// when the bank accepts the otp and released when it does not, an otp
+// entered after the usage was released counts the payment again.
+func (s *Store) ConfirmPayment(ctx context.Context, logData *commonSrv.LogEntry, userId, txnIdentifier, otp string) (_ *responses.PaymentSubmissionOtpResponse, err error) {
+	paymentReservation, err := models.GetPaymentReservation(s.db, userId, txnIdentifier)
+	if err != nil {
+		if errors.Is(err, constants.ErrNoDataFound) {
+			return nil, errors.New(constants.PaymentReservationNotFoundErrorMessage)
+		}
+		logData.Message = "BeneficiaryPaymentOTP: " + err.Error()
+		s.LoggerService.LogError(logData)
+		return nil, err
 	}
 
-	existingDevice, err := models.GetUserDataByUserId(s.db, authValues.UserId)
+	if err := models.HoldPaymentReservation(s.db, paymentReservation, time.Now().Add(config.GetTransferLimitConfig().OtpHold)); err != nil {
+		logData.Message = "BeneficiaryPaymentOTP: " + err.Error()
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	defer func() {
+		release := models.ReleasePaymentReservation
+		if err == nil {
+			release = models.ConfirmPaymentReservation
+		}
+		if err := release(s.db, txnIdentifier); err != nil {
+			logData.Message = "BeneficiaryPaymentOTP: " + err.Error()
+			s.LoggerService.LogError(logData)
+		}
+	}()
+
+	existingDevice, err := models.GetUserDataByUserId(s.db, userId)
 
 	if err != nil {
 		logData.Message = "BeneficiaryPaymentOTP: Error getting user data"
@@ -977,39 +1194,32 @@ func (s *Store) BeneficiaryPaymentOTP(ctx context.Context, authValues *models.Au
 		return nil, err
 	}
 
-	// get transaction id from cache
-	txn, err := s.memory.Get(fmt.Sprintf("beneficiary:payment:transaction:%s", authValues.UserId))
-	if err != nil {
-		return nil, err
-	}
-	request.TxnIdentifier = txn
+	request.TxnIdentifier = txnIdentifier
 
 	var response *responses.PaymentSubmissionOtpResponse
 	var opErr error
 
 	response, opErr = s.bankService.PaymentSubmissionOTP(ctx, request)
 	if opErr != nil {
-		bankErr := s.bankService.HandleBankSpecificError(opErr, func(errorCode string) (string, bool) {
-			return constants.GetPaymentCallbackErrorMessage(errorCode)
-		})
+		bankErr := s.bankService.HandleBankSpecificError(ctx, opErr)
 
 		if bankErr != nil {
 			logData.Message = fmt.Sprintf("Bank error encountered (ErrorCode: %s)", bankErr.ErrorCode)
 			s.LoggerService.LogError(logData)
 
-			if msg, retryable := constants.GetPaymentCallbackRetryErrorMessage(bankErr.ErrorCode); retryable {
+			if bankErr.Retryable {
 				err := utils.RetryFunc(func() error {
 					response, opErr = s.bankService.PaymentSubmissionOTP(ctx, request)
 					return opErr
-				}, 2)
+				}, bankErr.MaxRetries)
 
 				if err != nil {
 					logData.Message = "BeneficiaryPaymentOTP: Callback failed after retries"
 					s.LoggerService.LogError(logData)
-					return nil, errors.New(msg)
+					return nil, bankErr
 				}
 			} else {
-				return nil, errors.New(bankErr.ErrorMessage)
+				return nil, bankErr
 			}
 		} else {
 			return nil, opErr
@@ -1019,7 +1229,7 @@ func (s *Store) BeneficiaryPaymentOTP(ctx context.Context, authValues *models.Au
 	if response == nil {
 		logData.Message = "BeneficiaryPaymentOTP: Unknown error"
 		s.LoggerService.LogError(logData)
-		return nil, err
+		return nil, errors.New(constants.RetryErrorMessage)
 	}
 
 	// update transaction status in db
@@ -1037,31 +1247,7 @@ func (s *Store) BeneficiaryPaymentOTP(ctx context.Context, authValues *models.Au
 		return nil, errors.New(response.ErrorMessage)
 	}
 
-	response.TxnDateTime = time.Now()
-
-	byteudd, err := json.Marshal(response)
-
-	if err != nil {
-		logData.Message = "BeneficiaryPaymentOTP: Error marshaling response"
-		s.LoggerService.LogError(logData)
-		return nil, err
-	}
-
-	encrypted, err := security.Encrypt(byteudd, []byte(authValues.Key))
-
-	if err != nil {
-		logData.Message = "BeneficiaryPaymentOTP: Error encrypting response"
-		s.LoggerService.LogError(logData)
-		return nil, err
-	}
-
-	logData.Message = "BeneficiaryPaymentOTP: Response encrypted successfully"
-	logData.ResponseSize = len(byteudd)
-	logData.ResponseBody = string(byteudd)
-	logData.EndTime = time.Now()
-	s.LoggerService.LogInfo(logData)
-
-	return encrypted, nil
+	return response, nil
 }
 
 func (s *Store) CreateQuickTransferTemplate(ctx context.Context, authValues *models.AuthValues, r *requests.QuickTransferBeneficiaryRegistrationRequest) (interface{}, error) {
@@ -1107,14 +1293,12 @@ func (s *Store) CreateQuickTransferTemplate(ctx context.Context, authValues *mod
 	response, err := s.bankService.QuickTransferTemplateAdd(ctx, outgoingTemplateRequest)
 
 	if err != nil {
-		bankErr := s.bankService.HandleBankSpecificError(err, func(errorCode string) (string, bool) {
-			return constants.GetQuickTransferBeneficiaryErrorMessage(errorCode)
-		})
+		bankErr := s.bankService.HandleBankSpecificError(ctx, err)
 
 		if bankErr != nil {
 			logData.Message = fmt.Sprintf("CreateQuickTransferTemplate: Bank error encountered (ErrorCode: %s)", bankErr.ErrorCode)
 			s.LoggerService.LogError(logData)
-			return nil, errors.New(bankErr.ErrorMessage)
+			return nil, bankErr
 		}
 
 		return nil, err
@@ -1344,3 +1528,72 @@ func (s *Store) VerifyAndConfirmBeneficiary(ctx context.Context, authValues *mod
 
 	return nil, errors.New("beneficiary not found")
 }
+
+// beneficiaryAdded starts the cooling period of a beneficiary the user added
+// and tells them on push and sms, so a beneficiary they did not add is noticed
+func (s *Store) beneficiaryAdded(logData *commonSrv.LogEntry, userId, benfId string) {
+	coolingUntil, err := models.StartBeneficiaryCooling(s.db, userId, benfId)
+	if err != nil {
+		logData.Message = "beneficiaryAdded: " + err.Error()
+		s.LoggerService.LogError(logData)
+		return
+	}
+
+	// the bank beneficiary id can be made up from the account number, the
+	// user is told the name they know with the masked account instead
+	beneficiary := "A new beneficiary"
+	if ben, err := models.FindBeneficiaryOfUser(s.db, userId, benfId); err != nil {
+		logData.Message = "beneficiaryAdded: Error getting beneficiary:- " + err.Error()
+		s.LoggerService.LogError(logData)
+	} else {
+		name := ben.BenfName
+		if ben.BenfNickName.String != "" {
+			name = ben.BenfNickName.String
+		}
+		beneficiary = fmt.Sprintf("%s (A/c %s)", name, ben.BenfAccountNo)
+	}
+
+	body := fmt.Sprintf("%s was added as a beneficiary. Payments to them are limited to ₹%s until %s. If you did not add them, please contact support right away.",
+		beneficiary,
+		models.FormatPaise(config.GetBeneficiaryConfig().CoolingLimit*100),
+		coolingUntil.In(config.ISTLocation()).Format("02 Jan 2006, 03:04 PM"),
+	)
+
+	deviceData, err := models.FindOneDeviceByUserID(s.db, userId)
+	if err == nil && deviceData.DeviceToken.Valid && deviceData.DeviceToken.String != "" {
+		request := &requests.NotificationRequest{}
+		if err := request.CreateNotificationPayload(
+			[]requests.NotificationUser{{
+				UserId:      userId,
+				DeviceToken: deviceData.DeviceToken.String,
+				OS:          deviceData.OS.String,
+				PackageId:   deviceData.PackageId,
+			}},
+			"Beneficiary added",
+			body,
+			constants.BENEFICIARY_ADDED,
+			constants.BENEFICIARY_ADDED,
+		); err == nil {
+			if _, err := s.notificationService.SendNotification(request); err != nil {
+				logData.Message = "beneficiaryAdded: Error sending notification:- " + err.Error()
+				s.LoggerService.LogError(logData)
+			}
+		}
+	}
+
+	user, err := models.GetUserDataByUserId(s.db, userId)
+	if err != nil {
+		logData.Message = "beneficiaryAdded: Error getting user data"
+		s.LoggerService.LogError(logData)
+		return
+	}
+
+	if err := s.notificationService.SendSms(&requests.SendSmsRequest{
+		MobileNumber: user.MobileNumber,
+		Message:      body,
+		Purpose:      constants.BENEFICIARY_ADDED,
+	}); err != nil {
+		logData.Message = "beneficiaryAdded: Error sending sms:- " + err.Error()
+		s.LoggerService.LogError(logData)
+	}
+}
@@ -0,0 +1,246 @@
+package beneficiary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	commonSrv "bitbucket.org/paydoh/paydoh-commons/services"
+
+	"bankapi/constants"
+	"bankapi/models"
+	"bankapi/requests"
+	"bankapi/responses"
+	"bankapi/security"
+	"bankapi/utils"
+)
+
+// The bank has no api to change or remove a beneficiary, so the nickname,
+// disabled and deleted beneficiaries are kept here and applied over the bank
+// list in models.BeneficiaryDetail.Bind, and payments to a disabled or
+// deleted beneficiary are rejected in SubmitPayment.
+
+// UpdateBeneficiaryNickname sets the nickname the user sees for a beneficiary
+func (s *Store) UpdateBeneficiaryNickname(ctx context.Context, authValues *models.AuthValues, r *requests.UpdateBeneficiaryNicknameRequest) (interface{}, error) {
+	logData := &commonSrv.LogEntry{
+		Action:     constants.BENEFICIARY_UPDATED,
+		RequestURI: "/api/beneficiary/update-nickname",
+		Message:    "UpdateBeneficiaryNickname log",
+		UserID:     authValues.UserId,
+		RequestID:  utils.GetRequestIDFromContext(ctx),
+	}
+
+	if err := models.UpdateBeneficiaryNickname(s.db, authValues.UserId, r.BenfId, r.BenfNickName); err != nil {
+		if errors.Is(err, constants.ErrNoDataFound) {
+			return nil, errors.New(constants.BeneficiaryNotFoundErrorMessage)
+		}
+		logData.Message = "UpdateBeneficiaryNickname: Error updating beneficiary:- " + err.Error()
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	return s.beneficiaryChanged(logData, authValues, &models.BeneficiaryState{
+		BenfId:       r.BenfId,
+		BenfNickName: r.BenfNickName,
+		BenfStatus:   models.BeneficiaryStatusActive,
+	})
+}
+
+// SetBeneficiaryStatus disables a beneficiary so no payment can be made to
+// it, or enables a beneficiary the user disabled
+func (s *Store) SetBeneficiaryStatus(ctx context.Context, authValues *models.AuthValues, benfId string, disabled bool) (interface{}, error) {
+	logData := &commonSrv.LogEntry{
+		Action:     constants.BENEFICIARY_ENABLED,
+		RequestURI: "/api/beneficiary/enable",
+		Message:    "SetBeneficiaryStatus log",
+		UserID:     authValues.UserId,
+		RequestID:  utils.GetRequestIDFromContext(ctx),
+	}
+
+	update, status := models.EnableBeneficiary, models.BeneficiaryStatusActive
+	if disabled {
+		update, status = models.DisableBeneficiary, models.BeneficiaryStatusDisabled
+		logData.Action = constants.BENEFICIARY_DISABLED
+		logData.RequestURI = "/api/beneficiary/disable"
+	}
+
+	if err := update(s.db, authValues.UserId, benfId); err != nil {
+		if !errors.Is(err, constants.ErrNoDataFound) {
+			logData.Message = "SetBeneficiaryStatus: Error updating beneficiary:- " + err.Error()
+			s.LoggerService.LogError(logData)
+			return nil, err
+		}
+
+		// nothing was updated, the beneficiary is missing or already has the status
+		if _, err := models.FindBeneficiaryOfUser(s.db, authValues.UserId, benfId); err != nil {
+			if errors.Is(err, constants.ErrNoDataFound) {
+				return nil, errors.New(constants.BeneficiaryNotFoundErrorMessage)
+			}
+			logData.Message = "SetBeneficiaryStatus: Error getting beneficiary:- " + err.Error()
+			s.LoggerService.LogError(logData)
+			return nil, err
+		}
+		return nil, fmt.Errorf(constants.BeneficiaryStatusErrorMessage, status)
+	}
+
+	return s.beneficiaryChanged(logData, authValues, &models.BeneficiaryState{
+		BenfId:     benfId,
+		BenfStatus: status,
+	})
+}
+
+// DeleteBeneficiary removes a beneficiary from the list of the user, adding
+// it again starts a new cooling period
+func (s *Store) DeleteBeneficiary(ctx context.Context, authValues *models.AuthValues, benfId string) (interface{}, error) {
+	logData := &commonSrv.LogEntry{
+		Action:     constants.BENEFICIARY_DELETED,
+		RequestURI: "/api/beneficiary/delete",
+		Message:    "DeleteBeneficiary log",
+		UserID:     authValues.UserId,
+		RequestID:  utils.GetRequestIDFromContext(ctx),
+	}
+
+	if err := models.DeleteBeneficiary(s.db, authValues.UserId, benfId); err != nil {
+		if errors.Is(err, constants.ErrNoDataFound) {
+			return nil, errors.New(constants.BeneficiaryNotFoundErrorMessage)
+		}
+		logData.Message = "DeleteBeneficiary: Error deleting beneficiary:- " + err.Error()
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	return s.beneficiaryChanged(logData, authValues, &models.BeneficiaryState{
+		BenfId:     benfId,
+		BenfStatus: models.BeneficiaryStatusDeleted,
+	})
+}
+
+// SyncBeneficiaries compares the beneficiaries saved here with the bank list,
+// fixes what differs and returns what was found. It is only run on request,
+// listing the beneficiaries does not change them.
+func (s *Store) SyncBeneficiaries(ctx context.Context, authValues *models.AuthValues) (interface{}, error) {
+	logData := &commonSrv.LogEntry{
+		Action:     constants.BENEFICIARY_SYNCED,
+		RequestURI: "/api/beneficiary/sync",
+		Message:    "SyncBeneficiaries log",
+		UserID:     authValues.UserId,
+		RequestID:  utils.GetRequestIDFromContext(ctx),
+	}
+
+	existingDevice, err := models.GetUserDataByUserId(s.db, authValues.UserId)
+	if err != nil {
+		logData.Message = "SyncBeneficiaries: Error getting user data"
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	existingAccount, err := models.GetAccountDataByUserId(s.db, existingDevice.UserId)
+	if err != nil {
+		logData.Message = "SyncBeneficiaries: Error getting account data"
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	request := requests.NewOutgoingBeneficiarySearchRequest()
+	if err := request.Bind(existingDevice.ApplicantId, existingAccount.AccountNumber); err != nil {
+		logData.Message = "SyncBeneficiaries: Error binding beneficiary search request"
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	response, err := s.bankService.GetBeneficiaries(ctx, request)
+	if err != nil {
+		logData.Message = "SyncBeneficiaries: Error calling bank service"
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	data, err := models.FindBeneficiariesByUserId(s.db, existingDevice.UserId)
+	if err != nil {
+		logData.Message = "SyncBeneficiaries: Error getting existing beneficiaries from database"
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	drifts, err := s.reconcileBeneficiaries(logData, existingDevice.UserId, response, data)
+	if err != nil {
+		return nil, err
+	}
+
+	byteudd, err := json.Marshal(drifts)
+	if err != nil {
+		logData.Message = "SyncBeneficiaries: Error marshaling sync response"
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	encrypted, err := security.Encrypt(byteudd, []byte(authValues.Key))
+	if err != nil {
+		logData.Message = "SyncBeneficiaries: Error encrypting sync response"
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	return encrypted, nil
+}
+
+// reconcileBeneficiaries fixes the beneficiaries saved here that differ from
+// the bank list. A bank list with an error is not trusted, as it would make
+// every beneficiary look missing at the bank. A beneficiary that becomes
+// active, such as one added at the bank outside the app, starts its cooling
+// period and the user is told about it as for one added here.
+func (s *Store) reconcileBeneficiaries(logData *commonSrv.LogEntry, userId string, response *responses.FetchBeneficiaryResponse, data []models.BeneficiaryDTO) ([]models.BeneficiaryDrift, error) {
+	if response == nil {
+		return nil, errors.New("beneficiary list not received from bank")
+	}
+
+	switch response.ErrorCode {
+	case "0", "00", constants.BeneficiaryFetchResponseNoRecordFound:
+	default:
+		return nil, fmt.Errorf("beneficiary list not received from bank: %s", response.ErrorMessage)
+	}
+
+	drifts := models.ReconcileBeneficiaries(data, response.BeneficiaryDetails)
+	for i := range drifts {
+		if err := models.FixBeneficiaryDrift(s.db, userId, &drifts[i]); err != nil {
+			logData.Message = fmt.Sprintf("reconcileBeneficiaries: %s %s:- %s", drifts[i].BenfId, drifts[i].Drift, err.Error())
+			s.LoggerService.LogError(logData)
+			continue
+		}
+		drifts[i].Fixed = true
+
+		if drifts[i].Drift == models.BeneficiaryDriftMissingLocal || drifts[i].Drift == models.BeneficiaryDriftNotActivated {
+			s.beneficiaryAdded(logData, userId, drifts[i].BenfId)
+		}
+
+		logData.Message = fmt.Sprintf("reconcileBeneficiaries: fixed %s %s", drifts[i].BenfId, drifts[i].Drift)
+		s.LoggerService.LogInfo(logData)
+	}
+
+	return drifts, nil
+}
+
+func (s *Store) beneficiaryChanged(logData *commonSrv.LogEntry, authValues *models.AuthValues, state *models.BeneficiaryState) (interface{}, error) {
+	byteudd, err := json.Marshal(state)
+	if err != nil {
+		logData.Message = "beneficiaryChanged: Error marshaling beneficiary response"
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	encrypted, err := security.Encrypt(byteudd, []byte(authValues.Key))
+	if err != nil {
+		logData.Message = "beneficiaryChanged: Error encrypting beneficiary response"
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	logData.Message = fmt.Sprintf("beneficiaryChanged: beneficiary %s is %s", state.BenfId, state.BenfStatus)
+	logData.ResponseBody = string(byteudd)
+	logData.EndTime = time.Now()
+	s.LoggerService.LogInfo(logData)
+
+	return encrypted, nil
+}
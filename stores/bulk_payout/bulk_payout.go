@@ -0,0 +1,626 @@
+package bulkpayout
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"bitbucket.org/paydoh/paydoh-commons/pkg/task"
+	commonSrv "bitbucket.org/paydoh/paydoh-commons/services"
+	"github.com/hibiken/asynq"
+	"github.com/xuri/excelize/v2"
+
+	"bankapi/config"
+	"bankapi/constants"
+	"bankapi/models"
+	"bankapi/requests"
+	"bankapi/security"
+	"bankapi/services"
+	"bankapi/stores/beneficiary"
+	"bankapi/utils"
+)
+
+// Store pays many accounts from an uploaded file. An upload is checked row by
+// row into a preview, once the user confirms it a task submits its valid rows
+// to the bank a few at a time as quick transfers. The bank sends an otp for
+// each submitted row, a row is paid once the user confirms its otp.
+type Store struct {
+	db                  *sql.DB
+	LoggerService       *commonSrv.LoggerService
+	beneficiary         *beneficiary.Store
+	taskEnqueuer        task.TaskEnqueuer
+	notificationService *services.NotificationService
+	auditLogService     services.AuditLogService
+}
+
+func NewStore(
+	log *commonSrv.LoggerService,
+	db *sql.DB,
+	beneficiaryStore *beneficiary.Store,
+	taskEnqueuer task.TaskEnqueuer,
+	auditLogService services.AuditLogService,
+) *Store {
+	return &Store{
+		db:                  db,
+		LoggerService:       log,
+		beneficiary:         beneficiaryStore,
+		taskEnqueuer:        taskEnqueuer,
+		notificationService: services.NewNotificationService(),
+		auditLogService:     auditLogService,
+	}
+}
+
+type executePayload struct {
+	BulkPayoutId string `json:"bulk_payout_id"`
+}
+
+// UploadBulkPayout checks the rows of a file and saves them as a preview,
+// rows with an error are kept with it and are not paid
+func (s *Store) UploadBulkPayout(ctx context.Context, authValues *models.AuthValues, fileName string, data []byte) (*models.BulkPayout, error) {
+	logData := &commonSrv.LogEntry{
+		Action:     constants.BULK_PAYOUT,
+		RequestURI: "/api/bulk-payouts",
+		Message:    "UploadBulkPayout log",
+		UserID:     authValues.UserId,
+		RequestID:  utils.GetRequestIDFromContext(ctx),
+	}
+
+	fileType, rows, err := requests.ParseBulkPayoutFile(fileName, data)
+	if err != nil {
+		return nil, err
+	}
+
+	maxRows := config.GetBulkPayoutConfig().MaxRows
+	if len(rows) == 0 || len(rows) > maxRows {
+		return nil, fmt.Errorf(constants.BulkPayoutRowsErrorMessage, maxRows)
+	}
+
+	existingAccount, err := models.GetAccountDataByUserId(s.db, authValues.UserId)
+	if err != nil {
+		logData.Message = "UploadBulkPayout: Error getting account data"
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	bp := &models.BulkPayout{
+		UserId:    authValues.UserId,
+		FileName:  truncate(fileName, 255),
+		FileType:  fileType,
+		Status:    models.BulkPayoutStatusPreview,
+		TotalRows: len(rows),
+		DeviceId:  authValues.DeviceId,
+		Rows:      make([]models.BulkPayoutRow, 0, len(rows)),
+	}
+
+	var totalPaise int64
+	ifscErrors := make(map[string]error)
+	for i := range rows {
+		row, err := s.previewRow(&rows[i], existingAccount.AccountNumber, ifscErrors)
+		if err != nil {
+			logData.Message = "UploadBulkPayout: Error checking row:- " + err.Error()
+			s.LoggerService.LogError(logData)
+			return nil, err
+		}
+
+		if row.Status == models.BulkPayoutRowStatusValid {
+			bp.ValidRows++
+			totalPaise += toPaise(row.Amount)
+		}
+		bp.Rows = append(bp.Rows, *row)
+	}
+	bp.TotalAmount = fmt.Sprintf("%d.%02d", totalPaise/100, totalPaise%100)
+
+	if err := models.InsertBulkPayout(s.db, bp); err != nil {
+		logData.Message = "UploadBulkPayout: Error saving bulk payout"
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	s.audit(ctx, authValues, constants.BULK_PAYOUT_UPLOADED, fmt.Sprintf("bulk_payout=%s rows=%d valid=%d amount=%s", bp.Id, bp.TotalRows, bp.ValidRows, bp.TotalAmount))
+
+	logData.Message = "UploadBulkPayout: bulk payout previewed"
+	s.LoggerService.LogInfo(logData)
+
+	return bp, nil
+}
+
+// previewRow checks a row, its ifsc must be in the ifsc data. ifscErrors
+// keeps the outcome of the ifsc codes already looked up.
+func (s *Store) previewRow(r *requests.BulkPayoutRow, ownAccount string, ifscErrors map[string]error) (*models.BulkPayoutRow, error) {
+	rowErr := r.Validate()
+
+	if rowErr == nil {
+		checked, ok := ifscErrors[r.BenfIfsc]
+		if !ok {
+			_, err := models.GetIFSCData(s.db, r.BenfIfsc)
+			if err != nil && !errors.Is(err, constants.ErrNoDataFound) {
+				return nil, err
+			}
+			if err != nil {
+				checked = errors.New("ifsc not found")
+			}
+			ifscErrors[r.BenfIfsc] = checked
+		}
+		rowErr = checked
+	}
+
+	if rowErr == nil && r.BenfAcctNo == ownAccount {
+		rowErr = errors.New("self transfer not allowed")
+	}
+
+	row := &models.BulkPayoutRow{
+		RowNumber:    r.RowNumber,
+		BenfName:     truncate(r.BenfName, 100),
+		BenfAccount:  truncate(maskAccount(r.BenfAcctNo), 50),
+		BenfIfsc:     truncate(r.BenfIfsc, 11),
+		BenfAcctType: truncate(r.BenfAcctType, 10),
+		PaymentMode:  "IMPS",
+		Amount:       "0",
+		Remarks:      truncate(r.Remarks, 100),
+		Status:       models.BulkPayoutRowStatusInvalid,
+	}
+	if strings.HasPrefix(r.BenfIfsc, "KVBL") {
+		row.PaymentMode = "IFT"
+	}
+
+	if rowErr != nil {
+		row.ErrorMessage.String, row.ErrorMessage.Valid = rowErr.Error(), true
+		return row, nil
+	}
+
+	encryptedAccount, err := security.Encrypt([]byte(r.BenfAcctNo), []byte(constants.AesPassPhrase))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt account number: %w", err)
+	}
+
+	row.BenfAccountEncrypted = encryptedAccount
+	row.Amount = r.Amount
+	row.Status = models.BulkPayoutRowStatusValid
+
+	return row, nil
+}
+
+// ListBulkPayouts returns the bulk payouts of the user
+func (s *Store) ListBulkPayouts(ctx context.Context, authValues *models.AuthValues) ([]models.BulkPayout, error) {
+	return models.ListBulkPayouts(s.db, authValues.UserId)
+}
+
+// GetBulkPayout returns a bulk payout of the user with its rows
+func (s *Store) GetBulkPayout(ctx context.Context, authValues *models.AuthValues, id string) (*models.BulkPayout, error) {
+	bp, err := s.getBulkPayout(authValues, id)
+	if err != nil {
+		return nil, err
+	}
+
+	bp.Rows, err = models.ListBulkPayoutRows(s.db, bp.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return bp, nil
+}
+
+// ConfirmBulkPayout queues the submission of the valid rows of a preview
+func (s *Store) ConfirmBulkPayout(ctx context.Context, authValues *models.AuthValues, id string) (*models.BulkPayout, error) {
+	logData := &commonSrv.LogEntry{
+		Action:     constants.BULK_PAYOUT,
+		RequestURI: "/api/bulk-payouts/:id/confirm",
+		Message:    "ConfirmBulkPayout log",
+		UserID:     authValues.UserId,
+		RequestID:  utils.GetRequestIDFromContext(ctx),
+	}
+
+	bp, err := s.getBulkPayout(authValues, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if bp.ValidRows == 0 {
+		return nil, errors.New(constants.BulkPayoutNoValidRowsMessage)
+	}
+
+	confirmed, err := models.ConfirmBulkPayout(s.db, bp.Id, authValues.DeviceId)
+	if err != nil {
+		logData.Message = "ConfirmBulkPayout: Error confirming bulk payout"
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+	if !confirmed {
+		return nil, fmt.Errorf(constants.BulkPayoutStatusErrorMessage, "confirmed")
+	}
+
+	if _, _, err := s.taskEnqueuer.EnqueueNow(constants.BulkPayoutExecuteType, &executePayload{BulkPayoutId: bp.Id}, "default"); err != nil {
+		logData.Message = "ConfirmBulkPayout: Error queueing bulk payout:- " + err.Error()
+		s.LoggerService.LogError(logData)
+
+		if _, err := models.UpdateBulkPayoutStatus(s.db, bp.Id, models.BulkPayoutStatusQueued, models.BulkPayoutStatusPreview); err != nil {
+			logData.Message = "ConfirmBulkPayout: Error reverting bulk payout to preview"
+			s.LoggerService.LogError(logData)
+		}
+		return nil, errors.New(constants.RetryErrorMessage)
+	}
+
+	bp.Status = models.BulkPayoutStatusQueued
+	bp.DeviceId = authValues.DeviceId
+
+	s.audit(ctx, authValues, constants.BULK_PAYOUT_CONFIRMED, fmt.Sprintf("bulk_payout=%s valid=%d amount=%s", bp.Id, bp.ValidRows, bp.TotalAmount))
+
+	logData.Message = "ConfirmBulkPayout: bulk payout queued"
+	s.LoggerService.LogInfo(logData)
+
+	return bp, nil
+}
+
+// ExecuteHandler pays the valid rows of a confirmed bulk payout with at most
+// BULK_PAYOUT_CONCURRENCY payments at the bank at once. Rows are claimed
+// before they are submitted, a retried task does not pay a row twice.
+func (s *Store) ExecuteHandler(ctx context.Context, t *asynq.Task) error {
+	var payload executePayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w: %w", err, asynq.SkipRetry)
+	}
+
+	logData := &commonSrv.LogEntry{
+		Action:  constants.BULK_PAYOUT,
+		Message: "ExecuteHandler log",
+	}
+
+	bp, err := models.GetBulkPayout(s.db, payload.BulkPayoutId)
+	if err != nil {
+		if errors.Is(err, constants.ErrNoDataFound) {
+			return nil
+		}
+		return err
+	}
+	logData.UserID = bp.UserId
+
+	switch bp.Status {
+	case models.BulkPayoutStatusQueued:
+		if _, err := models.UpdateBulkPayoutStatus(s.db, bp.Id, models.BulkPayoutStatusQueued, models.BulkPayoutStatusProcessing); err != nil {
+			return err
+		}
+	case models.BulkPayoutStatusProcessing:
+	default:
+		return nil
+	}
+
+	rows, err := models.ListBulkPayoutRows(s.db, bp.Id)
+	if err != nil {
+		return err
+	}
+
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, config.GetBulkPayoutConfig().Concurrency)
+	)
+	for i := range rows {
+		row := &rows[i]
+		switch row.Status {
+		case models.BulkPayoutRowStatusValid:
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				s.payRow(ctx, bp, row)
+			}()
+		case models.BulkPayoutRowStatusProcessing:
+			// claimed by an attempt that did not finish, the bank may have
+			// the payment so it is not submitted again
+			row.Status = models.BulkPayoutRowStatusFailed
+			row.ErrorMessage.String, row.ErrorMessage.Valid = "payment status unknown, check your transactions before paying again", true
+			if err := models.UpdateBulkPayoutRowResult(s.db, row.Id, row.Status, "", "", row.ErrorMessage.String); err != nil {
+				logData.Message = "ExecuteHandler: Error updating row:- " + err.Error()
+				s.LoggerService.LogError(logData)
+			}
+		}
+	}
+	wg.Wait()
+
+	submitted := 0
+	for _, row := range rows {
+		if row.Status == models.BulkPayoutRowStatusAwaitingOtp {
+			submitted++
+		}
+	}
+
+	status := models.BulkPayoutStatusCompleted
+	if submitted > 0 {
+		status = models.BulkPayoutStatusAwaitingOtp
+	}
+	if _, err := models.UpdateBulkPayoutStatus(s.db, bp.Id, models.BulkPayoutStatusProcessing, status); err != nil {
+		return err
+	}
+
+	if submitted > 0 {
+		s.notify(bp.UserId, constants.BULK_PAYOUT_COMPLETED,
+			"Confirm your bulk payout",
+			fmt.Sprintf("%d of %d payouts of %s were submitted. Enter the otp the bank sent for each in the Paydoh app to complete them.", submitted, bp.ValidRows, bp.FileName),
+		)
+	} else {
+		s.notify(bp.UserId, constants.BULK_PAYOUT_COMPLETED,
+			"Bulk payout failed",
+			fmt.Sprintf("None of the %d payouts of %s could be submitted. Download the result file in the Paydoh app for the error of each payout.", bp.ValidRows, bp.FileName),
+		)
+	}
+
+	logData.Message = fmt.Sprintf("ExecuteHandler: bulk payout %s submitted, %d of %d waiting for otp", bp.Id, submitted, bp.ValidRows)
+	s.LoggerService.LogInfo(logData)
+
+	return nil
+}
+
+// payRow submits the payment of a row, a submitted row waits for the otp
+// the bank sends for it and is paid by ConfirmBulkPayoutRow
+func (s *Store) payRow(ctx context.Context, bp *models.BulkPayout, row *models.BulkPayoutRow) {
+	logData := &commonSrv.LogEntry{
+		Action:  constants.BULK_PAYOUT,
+		Message: "payRow log",
+		UserID:  bp.UserId,
+	}
+
+	claimed, err := models.ClaimBulkPayoutRow(s.db, row.Id)
+	if err != nil || !claimed {
+		if err != nil {
+			logData.Message = "payRow: Error claiming row:- " + err.Error()
+			s.LoggerService.LogError(logData)
+		}
+		return
+	}
+
+	var (
+		txnIdentifier string
+		payErr        error
+	)
+
+	accountNumber, err := security.Decrypt(row.BenfAccountEncrypted, []byte(constants.AesPassPhrase))
+	if err != nil {
+		payErr = fmt.Errorf("failed to decrypt account number: %w", err)
+	} else {
+		response, txn, err := s.beneficiary.SubmitPayoutPayment(ctx, logData, bp.UserId, bp.DeviceId, &requests.PaymentRequest{
+			PaymentMode:   row.PaymentMode,
+			BenfName:      row.BenfName,
+			BenfIfsc:      row.BenfIfsc,
+			BenfAcctNo:    accountNumber,
+			BenfAcctType:  row.BenfAcctType,
+			Amount:        row.Amount,
+			Remarks:       row.Remarks,
+			ResendOtp:     "N",
+			RetryFlag:     "N",
+			QuickTransfer: "Y",
+		})
+		switch {
+		case err != nil:
+			payErr = err
+		case response == nil:
+			payErr = errors.New(constants.RetryErrorMessage)
+		case response.ErrorCode != "" && response.ErrorCode != "0" && response.ErrorCode != "00":
+			txnIdentifier, payErr = txn, errors.New(response.ErrorMessage)
+		default:
+			txnIdentifier = txn
+		}
+	}
+
+	row.Status = models.BulkPayoutRowStatusAwaitingOtp
+	errorMessage := ""
+	if payErr != nil {
+		row.Status = models.BulkPayoutRowStatusFailed
+		errorMessage = payErr.Error()
+	}
+
+	if err := models.UpdateBulkPayoutRowResult(s.db, row.Id, row.Status, txnIdentifier, "", errorMessage); err != nil {
+		logData.Message = fmt.Sprintf("payRow: Error saving result of row %d:- %s", row.RowNumber, err.Error())
+		s.LoggerService.LogError(logData)
+	}
+}
+
+// ConfirmBulkPayoutRow pays a submitted row with the otp the bank sent for
+// it and saves its UTR. A wrong otp keeps the row waiting for the otp, the
+// bulk payout is completed once no row waits.
+func (s *Store) ConfirmBulkPayoutRow(ctx context.Context, authValues *models.AuthValues, id, rowId, otp string) (*models.BulkPayoutRow, error) {
+	logData := &commonSrv.LogEntry{
+		Action:     constants.BULK_PAYOUT,
+		RequestURI: "/api/bulk-payouts/:id/rows/:row_id/confirm",
+		Message:    "ConfirmBulkPayoutRow log",
+		UserID:     authValues.UserId,
+		RequestID:  utils.GetRequestIDFromContext(ctx),
+	}
+
+	bp, err := s.getBulkPayout(authValues, id)
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := models.GetBulkPayoutRow(s.db, bp.Id, rowId)
+	if err != nil {
+		return nil, err
+	}
+
+	if row.Status != models.BulkPayoutRowStatusAwaitingOtp || !row.TxnIdentifier.Valid {
+		return nil, errors.New(constants.BulkPayoutOtpErrorMessage)
+	}
+
+	response, err := s.beneficiary.ConfirmPayment(ctx, logData, authValues.UserId, row.TxnIdentifier.String, otp)
+	if err != nil {
+		return nil, err
+	}
+
+	row.Status = models.BulkPayoutRowStatusSuccess
+	row.Utr.String, row.Utr.Valid = response.TxnRefNo, response.TxnRefNo != ""
+	row.ErrorMessage = models.NullString{}
+	if err := models.UpdateBulkPayoutRowResult(s.db, row.Id, row.Status, row.TxnIdentifier.String, response.TxnRefNo, ""); err != nil {
+		logData.Message = "ConfirmBulkPayoutRow: Error updating row"
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	waiting, err := models.CountBulkPayoutRows(s.db, bp.Id, models.BulkPayoutRowStatusAwaitingOtp)
+	if err != nil {
+		logData.Message = "ConfirmBulkPayoutRow: " + err.Error()
+		s.LoggerService.LogError(logData)
+	} else if waiting == 0 {
+		if _, err := models.UpdateBulkPayoutStatus(s.db, bp.Id, models.BulkPayoutStatusAwaitingOtp, models.BulkPayoutStatusCompleted); err != nil {
+			logData.Message = "ConfirmBulkPayoutRow: " + err.Error()
+			s.LoggerService.LogError(logData)
+		}
+	}
+
+	logData.Message = fmt.Sprintf("ConfirmBulkPayoutRow: row %d of bulk payout %s paid", row.RowNumber, bp.Id)
+	s.LoggerService.LogInfo(logData)
+
+	return row, nil
+}
+
+// ResultFile returns the rows of a bulk payout with the status and UTR of
+// each as a file of the type that was uploaded
+func (s *Store) ResultFile(ctx context.Context, authValues *models.AuthValues, id string) ([]byte, string, error) {
+	bp, err := s.GetBulkPayout(ctx, authValues, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	records := [][]string{{"Row", "Beneficiary Name", "Account Number", "IFSC", "Amount", "Remarks", "Status", "UTR", "Error"}}
+	for _, row := range bp.Rows {
+		records = append(records, []string{
+			strconv.Itoa(row.RowNumber),
+			row.BenfName,
+			row.BenfAccount,
+			row.BenfIfsc,
+			row.Amount,
+			row.Remarks,
+			row.Status,
+			row.Utr.String,
+			row.ErrorMessage.String,
+		})
+	}
+
+	fileName := fmt.Sprintf("bulk_payout_%s_result.%s", bp.Id, bp.FileType)
+
+	if bp.FileType == requests.BulkPayoutFileXlsx {
+		file := excelize.NewFile()
+		defer file.Close()
+
+		sheet := file.GetSheetName(0)
+		for i, record := range records {
+			cell, err := excelize.CoordinatesToCellName(1, i+1)
+			if err != nil {
+				return nil, "", err
+			}
+			if err := file.SetSheetRow(sheet, cell, &record); err != nil {
+				return nil, "", fmt.Errorf("failed to write result file: %w", err)
+			}
+		}
+
+		buffer, err := file.WriteToBuffer()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to write result file: %w", err)
+		}
+		return buffer.Bytes(), fileName, nil
+	}
+
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	if err := writer.WriteAll(records); err != nil {
+		return nil, "", fmt.Errorf("failed to write result file: %w", err)
+	}
+
+	return buffer.Bytes(), fileName, nil
+}
+
+func (s *Store) getBulkPayout(authValues *models.AuthValues, id string) (*models.BulkPayout, error) {
+	bp, err := models.GetBulkPayout(s.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if bp.UserId != authValues.UserId {
+		return nil, constants.ErrNoDataFound
+	}
+
+	return bp, nil
+}
+
+// notify sends the user a push notification
+func (s *Store) notify(userId, purpose, title, body string) {
+	logData := &commonSrv.LogEntry{
+		Action:  constants.BULK_PAYOUT,
+		Message: "notify log",
+		UserID:  userId,
+	}
+
+	deviceData, err := models.FindOneDeviceByUserID(s.db, userId)
+	if err != nil || !deviceData.DeviceToken.Valid || deviceData.DeviceToken.String == "" {
+		return
+	}
+
+	request := &requests.NotificationRequest{}
+	if err := request.CreateNotificationPayload(
+		[]requests.NotificationUser{{
+			UserId:      userId,
+			DeviceToken: deviceData.DeviceToken.String,
+			OS:          deviceData.OS.String,
+			PackageId:   deviceData.PackageId,
+		}},
+		title,
+		body,
+		purpose,
+		purpose,
+	); err != nil {
+		return
+	}
+
+	if _, err := s.notificationService.SendNotification(request); err != nil {
+		logData.Message = "notify: Error sending notification:- " + err.Error()
+		s.LoggerService.LogError(logData)
+	}
+}
+
+func (s *Store) audit(ctx context.Context, authValues *models.AuthValues, action, body string) {
+	if err := s.auditLogService.Save(ctx, &services.AuditLog{
+		UserID:         authValues.UserId,
+		SourceIP:       authValues.DeviceIp,
+		DeviceID:       authValues.DeviceId,
+		RequestBody:    body,
+		ResponseStatus: http.StatusOK,
+		Action:         action,
+	}); err != nil {
+		s.LoggerService.LogError(&commonSrv.LogEntry{
+			Action:  constants.BULK_PAYOUT,
+			Message: "bulk payout audit log:- " + err.Error(),
+			UserID:  authValues.UserId,
+		})
+	}
+}
+
+func maskAccount(accountNumber string) string {
+	if len(accountNumber) <= 4 {
+		return accountNumber
+	}
+	return strings.Repeat("X", len(accountNumber)-4) + accountNumber[len(accountNumber)-4:]
+}
+
+// toPaise converts an amount checked by requests.BulkPayoutRow.Validate
+func toPaise(amount string) int64 {
+	rupees, paise, _ := strings.Cut(amount, ".")
+	r, _ := strconv.ParseInt(rupees, 10, 64)
+	p, _ := strconv.ParseInt(paise, 10, 64)
+	return r*100 + p
+}
+
+// truncate keeps the first max characters of a value of an invalid row, to
+// fit its column
+func truncate(value string, max int) string {
+	runes := []rune(value)
+	if len(runes) <= max {
+		return value
+	}
+	return string(runes[:max])
+}
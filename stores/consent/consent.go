@@ -4,7 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
-	"errors"
 	"time"
 
 	"bitbucket.org/paydoh/paydoh-commons/database"
@@ -23,7 +22,7 @@ type Consent interface {
 
 type Store struct {
 	db            *sql.DB
-	bankService   *services.BankApiService
+	bankService   services.BankProvider
 	memory        *database.InMemory
 	m             *database.Document
 	LoggerService *commonSrv.LoggerService
@@ -35,7 +34,7 @@ func NewStore(log *commonSrv.LoggerService, db *sql.DB, m *database.Document, me
 		m:             m,
 		memory:        memory,
 		LoggerService: log,
-		bankService:   services.NewBankApiService(log, memory),
+		bankService:   services.NewBankProvider(log, memory),
 	}
 }
 
@@ -65,10 +64,8 @@ func (s *Store) UpdateConsent(ctx context.Context, request *requests.ConsentRequ
 
 	consentResponse, err := s.bankService.PostUserConsent(ctx, outGoingRequest, nil)
 	if err != nil {
-		if bankErr := s.bankService.HandleBankSpecificError(err, func(errorCode string) (string, bool) {
-			return constants.GetConsentErrorMessage(errorCode)
-		}); bankErr != nil {
-			return errors.New(bankErr.ErrorMessage)
+		if bankErr := s.bankService.HandleBankSpecificError(ctx, err); bankErr != nil {
+			return bankErr
 		}
 
 		logData.Message = "UpdateConsent: Error posting user consent to bank service"
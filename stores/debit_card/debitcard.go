@@ -35,18 +35,16 @@ type DebitCard interface {
 }
 
 type Store struct {
-	db                      *sql.DB
-	m                       *database.Document
-	memory                  *database.InMemory
-	debitcardservice        *services.DebitcardApiService
-	debitCardControlService *services.DebitcardControlApiService
-	bankservice             *services.BankApiService
-	LoggerService           *commonSrv.LoggerService
-	s3Client                storage.S3Client
-	cachedTrackingFile      *excelize.File
-	lastFileDownloadTime    time.Time
-	auditLogSrv             services.AuditLogService
-	taskEnqueuer            task.TaskEnqueuer
+	db                   *sql.DB
+	m                    *database.Document
+	memory               *database.InMemory
+	bankservice          services.BankProvider
+	LoggerService        *commonSrv.LoggerService
+	s3Client             storage.S3Client
+	cachedTrackingFile   *excelize.File
+	lastFileDownloadTime time.Time
+	auditLogSrv          services.AuditLogService
+	taskEnqueuer         task.TaskEnqueuer
 }
 
 type CardStatus struct {
@@ -70,21 +68,17 @@ type FTPConfig struct {
 }
 
 func NewStore(log *commonSrv.LoggerService, db *sql.DB, m *database.Document, memory *database.InMemory, s3Client storage.S3Client, auditLogSrv services.AuditLogService, taskEnqueuer task.TaskEnqueuer) *Store {
-	debitcardService := services.NewDebitcardApiService(log, memory)
-	debitcardcontrolService := services.NewDebitcardControlApiService(log, memory)
-	bankService := services.NewBankApiService(log, memory)
+	bankService := services.NewBankProvider(log, memory)
 
 	return &Store{
-		db:                      db,
-		m:                       m,
-		memory:                  memory,
-		debitcardservice:        debitcardService,
-		LoggerService:           log,
-		bankservice:             bankService,
-		s3Client:                s3Client,
-		debitCardControlService: debitcardcontrolService,
-		auditLogSrv:             auditLogSrv,
-		taskEnqueuer:            taskEnqueuer,
+		db:            db,
+		m:             m,
+		memory:        memory,
+		LoggerService: log,
+		bankservice:   bankService,
+		s3Client:      s3Client,
+		auditLogSrv:   auditLogSrv,
+		taskEnqueuer:  taskEnqueuer,
 	}
 }
 
@@ -223,16 +217,14 @@ func (s *Store) GeneratePhysicalDebitCard(ctx context.Context, accountDetail *mo
 		}
 	}
 
-	_, err = s.debitcardservice.DebitCardPhysicalGeneration(ctx, request)
+	_, err = s.bankservice.DebitCardPhysicalGeneration(ctx, request)
 	if err != nil {
-		bankErr := s.bankservice.HandleBankSpecificError(err, func(errorCode string) (string, bool) {
-			return constants.GetPhysicalDebitCardErrorMessage(errorCode)
-		})
+		bankErr := s.bankservice.HandleBankSpecificError(ctx, err)
 
 		if bankErr != nil {
 			logData.Message = fmt.Sprintf("DebitCardGeneration: Bank error encountered (ErrorCode: %s)", bankErr.ErrorCode)
 			s.LoggerService.LogError(logData)
-			return nil, errors.New(bankErr.ErrorMessage)
+			return nil, bankErr
 		}
 
 		return nil, err
@@ -268,28 +260,26 @@ func (s *Store) GenerateVirtualDebitCard(ctx context.Context, debitCardData *mod
 	var virtualCardRes *responses.GenerateDebitcardResponse
 	var opErr error
 
-	virtualCardRes, opErr = s.debitcardservice.DebitCardVirtualGeneration(ctx, virtualCardRequest)
+	virtualCardRes, opErr = s.bankservice.DebitCardVirtualGeneration(ctx, virtualCardRequest)
 	if opErr != nil {
-		bankErr := s.bankservice.HandleBankSpecificError(opErr, func(errorCode string) (string, bool) {
-			s.ErrorLogData(logData, fmt.Sprintf("Bank side error encountered (ErrorCode: %s)", errorCode))
-			return constants.GetVirtualDebitCardErrorMessage(errorCode)
-		})
+		bankErr := s.bankservice.HandleBankSpecificError(ctx, opErr)
 
 		if bankErr != nil {
-			if msg, retryable := constants.GetVirtualDebitCardErrorMessageRetry(bankErr.ErrorCode); retryable {
+			s.ErrorLogData(logData, fmt.Sprintf("Bank side error encountered (ErrorCode: %s)", bankErr.ErrorCode))
+			if bankErr.Retryable {
 				err := utils.RetryFunc(func() error {
 					s.ErrorLogData(logData, "DebitCardGeneration: Retrying virtual debit card generation")
-					virtualCardRes, opErr = s.debitcardservice.DebitCardVirtualGeneration(ctx, virtualCardRequest)
+					virtualCardRes, opErr = s.bankservice.DebitCardVirtualGeneration(ctx, virtualCardRequest)
 					return opErr
-				}, 2)
+				}, bankErr.MaxRetries)
 
 				if err != nil {
 					s.ErrorLogData(logData, "DebitCardGeneration: Callback failed after retries")
-					return nil, errors.New(msg)
+					return nil, bankErr
 				}
 			} else {
 				s.ErrorLogData(logData, fmt.Sprintf("DebitCardGeneration: Bank error encountered (ErrorCode: %s)", bankErr.ErrorCode))
-				return nil, errors.New(bankErr.ErrorMessage)
+				return nil, bankErr
 			}
 		}
 		return nil, opErr
@@ -409,29 +399,27 @@ func (s *Store) DebitCardDetail(ctx context.Context, authValue *models.AuthValue
 	var result *responses.DebitcardDetailResponse
 	var opErr error
 
-	result, opErr = s.debitcardservice.GetDebitCardDetails(ctx, req)
+	result, opErr = s.bankservice.GetDebitCardDetails(ctx, req)
 	if opErr != nil {
-		bankErr := s.bankservice.HandleBankSpecificError(opErr, func(errorCode string) (string, bool) {
-			return constants.GetDebitCardFetchErrorMessage(errorCode)
-		})
+		bankErr := s.bankservice.HandleBankSpecificError(ctx, opErr)
 
 		if bankErr != nil {
 			logData.Message = fmt.Sprintf("Bank error encountered (ErrorCode: %s)", bankErr.ErrorCode)
 			s.LoggerService.LogError(logData)
 
-			if msg, retryable := constants.GetDebitCardFetchRetryErrorMessage(bankErr.ErrorCode); retryable {
+			if bankErr.Retryable {
 				err := utils.RetryFunc(func() error {
-					result, opErr = s.debitcardservice.GetDebitCardDetails(ctx, req)
+					result, opErr = s.bankservice.GetDebitCardDetails(ctx, req)
 					return opErr
-				}, 2)
+				}, bankErr.MaxRetries)
 
 				if err != nil {
 					logData.Message = "DebitCardGeneration: Callback failed after retries"
 					s.LoggerService.LogError(logData)
-					return nil, errors.New(msg)
+					return nil, bankErr
 				}
 			} else {
-				return nil, errors.New(bankErr.ErrorMessage)
+				return nil, bankErr
 			}
 		}
 
@@ -595,17 +583,15 @@ func (s *Store) SetTransactionLimit(ctx context.Context, auth *models.AuthValues
 	var otpRes *responses.SetDebitCardOTPResponse
 	var opErr error
 
-	otpRes, opErr = s.debitcardservice.SendOTPForDebitCard(ctx, optReq)
+	otpRes, opErr = s.bankservice.SendOTPForDebitCard(ctx, optReq)
 	if opErr != nil {
-		bankErr := s.bankservice.HandleBankSpecificError(opErr, func(errorCode string) (string, bool) {
-			return constants.GetOTPErrorMessage(errorCode)
-		})
+		bankErr := s.bankservice.HandleBankSpecificError(ctx, opErr)
 
 		if bankErr != nil {
 			logData.Message = fmt.Sprintf("Bank error encountered (ErrorCode: %s)", bankErr.ErrorCode)
 			s.LoggerService.LogError(logData)
 
-			if msg, retryable := constants.GetOTPRetryErrorMessage(bankErr.ErrorCode); retryable {
+			if bankErr.Retryable {
 				err := utils.RetryFunc(func() error {
 					txnID, err := security.GenerateRandomUUID(20)
 					if err != nil {
@@ -614,17 +600,17 @@ func (s *Store) SetTransactionLimit(ctx context.Context, auth *models.AuthValues
 						return err
 					}
 					optReq.TxnIdentifier = txnID
-					otpRes, opErr = s.debitcardservice.SendOTPForDebitCard(ctx, optReq)
+					otpRes, opErr = s.bankservice.SendOTPForDebitCard(ctx, optReq)
 					return opErr
-				}, 2)
+				}, bankErr.MaxRetries)
 
 				if err != nil {
 					logData.Message = "SetTransactionLimit: Callback failed after retries"
 					s.LoggerService.LogError(logData)
-					return nil, errors.New(msg)
+					return nil, bankErr
 				}
 			} else {
-				return nil, errors.New(bankErr.ErrorMessage)
+				return nil, bankErr
 			}
 		}
 
@@ -787,17 +773,15 @@ func (s *Store) SetCardStatus(ctx context.Context, auth *models.AuthValues, requ
 	var otpRes *responses.SetDebitCardOTPResponse
 	var opErr error
 
-	otpRes, opErr = s.debitcardservice.SendOTPForDebitCard(ctx, optReq)
+	otpRes, opErr = s.bankservice.SendOTPForDebitCard(ctx, optReq)
 	if opErr != nil {
-		bankErr := s.bankservice.HandleBankSpecificError(opErr, func(errorCode string) (string, bool) {
-			return constants.GetOTPErrorMessage(errorCode)
-		})
+		bankErr := s.bankservice.HandleBankSpecificError(ctx, opErr)
 
 		if bankErr != nil {
 			logData.Message = fmt.Sprintf("Bank error encountered (ErrorCode: %s)", bankErr.ErrorCode)
 			s.LoggerService.LogError(logData)
 
-			if msg, retryable := constants.GetOTPRetryErrorMessage(bankErr.ErrorCode); retryable {
+			if bankErr.Retryable {
 				err := utils.RetryFunc(func() error {
 					txnID, err := security.GenerateRandomUUID(20)
 					if err != nil {
@@ -806,17 +790,17 @@ func (s *Store) SetCardStatus(ctx context.Context, auth *models.AuthValues, requ
 						return err
 					}
 					optReq.TxnIdentifier = txnID
-					otpRes, opErr = s.debitcardservice.SendOTPForDebitCard(ctx, optReq)
+					otpRes, opErr = s.bankservice.SendOTPForDebitCard(ctx, optReq)
 					return opErr
-				}, 2)
+				}, bankErr.MaxRetries)
 
 				if err != nil {
 					logData.Message = "SetTransactionLimit: Callback failed after retries"
 					s.LoggerService.LogError(logData)
-					return nil, errors.New(msg)
+					return nil, bankErr
 				}
 			} else {
-				return nil, errors.New(bankErr.ErrorMessage)
+				return nil, bankErr
 			}
 		}
 
@@ -873,7 +857,7 @@ func (s *Store) EditTransaction(ctx context.Context, authValue *models.AuthValue
 		return nil, err
 	}
 
-	res, err := s.debitCardControlService.EditTransaction(ctx, req, transactionId)
+	res, err := s.bankservice.EditTransaction(ctx, req, transactionId)
 	if err != nil {
 		logData.Message = "EditTransactionLimit: Error while Updating Transaction Limit"
 		s.LoggerService.LogError(logData)
@@ -899,7 +883,7 @@ func (s *Store) EditTransaction(ctx context.Context, authValue *models.AuthValue
 		return res, nil
 	}
 
-	encryptReq, err := security.Encrypt(request, []byte(authValue.Key))
+	encryptReq, err := security.Encrypt(request, []byte(authValue.SigningKey))
 	if err != nil {
 		logData.Message = "UpdateAdressLog:error while encrypting request"
 		logData.EndTime = time.Now()
@@ -945,7 +929,7 @@ func (s *Store) CardBlock(ctx context.Context, authValue *models.AuthValues, tra
 		return nil, err
 	}
 
-	res, err := s.debitCardControlService.CardBlock(ctx, request, transactionId)
+	res, err := s.bankservice.CardBlock(ctx, request, transactionId)
 	if err != nil {
 		logData.Message = "CardBlockUnblock: Error while Updating Card Block Unblock Status"
 		s.LoggerService.LogError(logData)
@@ -983,7 +967,7 @@ func (s *Store) CardBlock(ctx context.Context, authValue *models.AuthValues, tra
 		return res, nil
 	}
 
-	encryptReq, err := security.Encrypt(req, []byte(authValue.Key))
+	encryptReq, err := security.Encrypt(req, []byte(authValue.SigningKey))
 	if err != nil {
 		logData.Message = "UpdateAdressLog:error while encrypting request"
 		logData.EndTime = time.Now()
@@ -1090,6 +1074,11 @@ func (s *Store) PublicKeyAndLogin(ctx context.Context, transactionId string, aut
 		if err != nil {
 			logData.Message = "GetPublicKeyAndLogin: Error while Adding card"
 			s.LoggerService.LogError(logData)
+			// the next attempt should start from a fresh login
+			if err := s.bankservice.InvalidateCardControlLogin(ctx, auth.UserId, login.CustomerID); err != nil {
+				logData.Message = "GetPublicKeyAndLogin: Error while invalidating card login " + err.Error()
+				s.LoggerService.LogError(logData)
+			}
 			return "", "", "", err
 		}
 		enid = add_card.ENID
@@ -1109,7 +1098,7 @@ func (s *Store) ListCardControl(ctx context.Context, cid, enid, publicKey, trans
 	}
 	request := requests.ListCardControlRequest{}
 	request.Bind(cid, enid, publicKey)
-	response, err := s.debitCardControlService.ListCardControl(ctx, &request, transactionID)
+	response, err := s.bankservice.ListCardControl(ctx, &request, transactionID)
 	if err != nil {
 		logData.Message = "ListCardControl:Error while getting ListCardControl Data"
 		s.LoggerService.LogError(logData)
@@ -1137,20 +1126,14 @@ func (s *Store) KeyFetch(ctx context.Context, transactionID string) (string, err
 		ID: "66",
 	}
 
-	response, err := s.debitCardControlService.KeyFetch(ctx, req, transactionID)
+	publicKey, err := s.bankservice.CardControlPublicKey(ctx, req, transactionID)
 	if err != nil {
 		logData.Message = "KeyFetchApi: Error while getting PublicKey from bank" + err.Error()
 		s.LoggerService.LogError(logData)
 		return "", err
 	}
 
-	if response == nil {
-		logData.Message = "GetPublicKeyAndLogin: PublicKey is getting null"
-		s.LoggerService.LogError(logData)
-		return "", errors.New("public key fetching error")
-	}
-
-	return response.PublicKey, nil
+	return publicKey, nil
 }
 
 func (s *Store) LoginAndRegister(ctx context.Context, userId, publicKey, transactionID string) (*responses.LoginResponse, error) {
@@ -1180,7 +1163,7 @@ func (s *Store) LoginAndRegister(ctx context.Context, userId, publicKey, transac
 
 	req.PublicKey = publicKey
 
-	response, err := s.debitCardControlService.Login(ctx, &req, transactionID)
+	response, err := s.bankservice.CardControlLogin(ctx, userId, &req, transactionID)
 	if err != nil {
 		logData.Message = "LoginAndRegister: Error while Login for DebitCard"
 		s.LoggerService.LogError(logData)
@@ -1226,7 +1209,7 @@ func (s *Store) AddCard(ctx context.Context, userId, cid, publicKey, transaction
 
 	request.Bind(name, expYearIn2, month, cardNo, cid, publicKey)
 
-	response, err := s.debitCardControlService.AddCard(ctx, &request, transactionID)
+	response, err := s.bankservice.AddCard(ctx, &request, transactionID)
 	if err != nil {
 		logData.Message = "AddCard: Error while AddCard"
 		s.LoggerService.LogError(logData)
@@ -1258,7 +1241,7 @@ func (s *Store) ListCard(ctx context.Context, cid, publickey, transactionId stri
 	}
 	request := requests.ListCardRequest{}
 	request.Bind(cid, publickey)
-	response, err := s.debitCardControlService.ListCard(ctx, &request, transactionId)
+	response, err := s.bankservice.ListCard(ctx, &request, transactionId)
 	if err != nil {
 		logData.Message = "ListCard: Error while List Card API"
 		s.LoggerService.LogError(logData)
@@ -1286,7 +1269,7 @@ func (s *Store) FetchTransaction(ctx context.Context, userId, enid, publicKey, d
 	request := requests.FetchTransactionRequest{}
 	request.Bind(CustomerId, enid, publicKey, deliveryChannel)
 
-	response, err := s.debitCardControlService.FetchTransaction(ctx, &request, transactionId)
+	response, err := s.bankservice.FetchTransaction(ctx, &request, transactionId)
 	if err != nil {
 		logData.Message = "FetchTransaction: Error while FetchingTransaction Limit Detail"
 		s.LoggerService.LogError(logData)
@@ -1337,17 +1320,15 @@ func (s *Store) SetDebitCardPin(ctx context.Context, authValue *models.AuthValue
 	var otpRes *responses.SetDebitCardOTPResponse
 	var opErr error
 
-	otpRes, opErr = s.debitcardservice.SendOTPForDebitCard(ctx, optReq)
+	otpRes, opErr = s.bankservice.SendOTPForDebitCard(ctx, optReq)
 	if opErr != nil {
-		bankErr := s.bankservice.HandleBankSpecificError(opErr, func(errorCode string) (string, bool) {
-			return constants.GetDebitCardFetchErrorMessage(errorCode)
-		})
+		bankErr := s.bankservice.HandleBankSpecificError(ctx, opErr)
 
 		if bankErr != nil {
 			logData.Message = fmt.Sprintf("Bank error encountered (ErrorCode: %s)", bankErr.ErrorCode)
 			s.LoggerService.LogError(logData)
 
-			if msg, retryable := constants.GetDebitCardFetchRetryErrorMessage(bankErr.ErrorCode); retryable {
+			if bankErr.Retryable {
 				err := utils.RetryFunc(func() error {
 					txnID, err := security.GenerateRandomUUID(20)
 					if err != nil {
@@ -1356,17 +1337,17 @@ func (s *Store) SetDebitCardPin(ctx context.Context, authValue *models.AuthValue
 						return err
 					}
 					optReq.TxnIdentifier = txnID
-					otpRes, opErr = s.debitcardservice.SendOTPForDebitCard(ctx, optReq)
+					otpRes, opErr = s.bankservice.SendOTPForDebitCard(ctx, optReq)
 					return opErr
-				}, 2)
+				}, bankErr.MaxRetries)
 
 				if err != nil {
 					logData.Message = "SetDebitCardPin: Callback failed after retries"
 					s.LoggerService.LogError(logData)
-					return nil, errors.New(msg)
+					return nil, bankErr
 				}
 			} else {
-				return nil, errors.New(bankErr.ErrorMessage)
+				return nil, bankErr
 			}
 		}
 
@@ -1421,17 +1402,15 @@ func (s *Store) VerifyDebitCardOTP(ctx context.Context, authValue *models.AuthVa
 	var optRes *responses.VerifyDebitCardOTPResponse
 	var opErr error
 
-	optRes, opErr = s.debitcardservice.VerifyOTPForDebitCard(ctx, optReq)
+	optRes, opErr = s.bankservice.VerifyOTPForDebitCard(ctx, optReq)
 	if opErr != nil {
-		bankErr := s.bankservice.HandleBankSpecificError(opErr, func(errorCode string) (string, bool) {
-			return constants.GetOTPErrorMessage(errorCode)
-		})
+		bankErr := s.bankservice.HandleBankSpecificError(ctx, opErr)
 
 		if bankErr != nil {
 			logData.Message = fmt.Sprintf("Bank error encountered (ErrorCode: %s)", bankErr.ErrorCode)
 			s.LoggerService.LogError(logData)
 
-			if msg, retryable := constants.GetOTPRetryErrorMessage(bankErr.ErrorCode); retryable {
+			if bankErr.Retryable {
 				err := utils.RetryFunc(func() error {
 					txnID, err := security.GenerateRandomUUID(20)
 					if err != nil {
@@ -1440,17 +1419,17 @@ func (s *Store) VerifyDebitCardOTP(ctx context.Context, authValue *models.AuthVa
 						return err
 					}
 					optReq.TxnIdentifier = txnID
-					optRes, opErr = s.debitcardservice.VerifyOTPForDebitCard(ctx, optReq)
+					optRes, opErr = s.bankservice.VerifyOTPForDebitCard(ctx, optReq)
 					return opErr
-				}, 2)
+				}, bankErr.MaxRetries)
 
 				if err != nil {
 					logData.Message = "verifyDebitCardOTP: Callback failed after retries"
 					s.LoggerService.LogError(logData)
-					return nil, errors.New(msg)
+					return nil, bankErr
 				}
 			} else {
-				return nil, errors.New(bankErr.ErrorMessage)
+				return nil, bankErr
 			}
 		}
 
@@ -1478,29 +1457,27 @@ func (s *Store) VerifyDebitCardOTP(ctx context.Context, authValue *models.AuthVa
 		var result *responses.DebitcardDetailResponse
 		var opErr error
 
-		result, opErr = s.debitcardservice.GetDebitCardDetails(ctx, req)
+		result, opErr = s.bankservice.GetDebitCardDetails(ctx, req)
 		if opErr != nil {
-			bankErr := s.bankservice.HandleBankSpecificError(opErr, func(errorCode string) (string, bool) {
-				return constants.GetDebitCardFetchErrorMessage(errorCode)
-			})
+			bankErr := s.bankservice.HandleBankSpecificError(ctx, opErr)
 
 			if bankErr != nil {
 				logData.Message = fmt.Sprintf("Bank error encountered (ErrorCode: %s)", bankErr.ErrorCode)
 				s.LoggerService.LogError(logData)
 
-				if msg, retryable := constants.GetDebitCardFetchRetryErrorMessage(bankErr.ErrorCode); retryable {
+				if bankErr.Retryable {
 					err := utils.RetryFunc(func() error {
-						result, opErr = s.debitcardservice.GetDebitCardDetails(ctx, req)
+						result, opErr = s.bankservice.GetDebitCardDetails(ctx, req)
 						return opErr
-					}, 2)
+					}, bankErr.MaxRetries)
 
 					if err != nil {
 						logData.Message = "verifyDebitCardOTP: Callback failed after retries"
 						s.LoggerService.LogError(logData)
-						return nil, errors.New(msg)
+						return nil, bankErr
 					}
 				} else {
-					return nil, errors.New(bankErr.ErrorMessage)
+					return nil, bankErr
 				}
 			}
 
@@ -1519,21 +1496,19 @@ func (s *Store) VerifyDebitCardOTP(ctx context.Context, authValue *models.AuthVa
 			return nil, err
 		}
 
-		_, err = s.debitcardservice.SetDebitCardPin(ctx, req2)
+		_, err = s.bankservice.SetDebitCardPin(ctx, req2)
 
 		if err != nil {
 			logData.Message = "DebitCardDetail: Error while Setting DebitCard pin"
 			s.LoggerService.LogError(logData)
 
-			bankErr := s.bankservice.HandleBankSpecificError(err, func(errorCode string) (string, bool) {
-				return constants.GetDebitCardSetPinErrorMessage(errorCode)
-			})
+			bankErr := s.bankservice.HandleBankSpecificError(ctx, err)
 
 			if bankErr != nil {
 				logData.Message = fmt.Sprintf("Bank error encountered (ErrorCode: %s)", bankErr.ErrorCode)
 				s.LoggerService.LogError(logData)
 
-				if msg, retryable := constants.GetDebitCardSetPinRetryErrorMessage(bankErr.ErrorCode); retryable {
+				if bankErr.Retryable {
 					retryErr := utils.RetryFunc(func() error {
 						txnID, err := security.GenerateRandomUUID(20)
 						if err != nil {
@@ -1542,17 +1517,17 @@ func (s *Store) VerifyDebitCardOTP(ctx context.Context, authValue *models.AuthVa
 							return err
 						}
 						req2.TxnIdentifier = txnID
-						_, opErr := s.debitcardservice.SetDebitCardPin(ctx, req2)
+						_, opErr := s.bankservice.SetDebitCardPin(ctx, req2)
 						return opErr
-					}, 2)
+					}, bankErr.MaxRetries)
 
 					if retryErr != nil {
 						logData.Message = "SetDebitCardPin: Callback failed after retries"
 						s.LoggerService.LogError(logData)
-						return nil, errors.New(msg)
+						return nil, bankErr
 					}
 				} else {
-					return nil, errors.New(bankErr.ErrorMessage)
+					return nil, bankErr
 				}
 			}
 
@@ -1569,7 +1544,7 @@ func (s *Store) VerifyDebitCardOTP(ctx context.Context, authValue *models.AuthVa
 			s.LoggerService.LogError(logData)
 			return nil, nil
 		}
-		encryptReq, err := security.Encrypt(requestData, []byte(authValue.Key))
+		encryptReq, err := security.Encrypt(requestData, []byte(authValue.SigningKey))
 		if err != nil {
 			logData.Message = "UpdateAdressLog:error while encrypting request"
 			logData.EndTime = time.Now()
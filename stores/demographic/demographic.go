@@ -29,13 +29,13 @@ type Store struct {
 	db                  *sql.DB
 	m                   *database.Document
 	memory              *database.InMemory
-	bankService         *services.BankApiService
+	bankService         services.BankProvider
 	notificationService *services.NotificationService
 	LoggerService       *commonSrv.LoggerService
 }
 
 func NewStore(log *commonSrv.LoggerService, db *sql.DB, m *database.Document, memory *database.InMemory) *Store {
-	bankService := services.NewBankApiService(log, memory)
+	bankService := services.NewBankProvider(log, memory)
 	notificationService := services.NewNotificationService()
 	return &Store{
 		db:                  db,
@@ -75,14 +75,12 @@ func (s *Store) GetDemographicData(ctx context.Context, authValues *models.AuthV
 	response, err := s.bankService.GetDemographicData(ctx, request)
 
 	if err != nil {
-		bankErr := s.bankService.HandleBankSpecificError(err, func(errorCode string) (string, bool) {
-			return constants.GetDemographicErrorMessage(errorCode)
-		})
+		bankErr := s.bankService.HandleBankSpecificError(ctx, err)
 
 		if bankErr != nil {
 			logData.Message = fmt.Sprintf("GetDemographicData: Bank error encountered (ErrorCode: %s)", bankErr.ErrorCode)
 			s.LoggerService.LogError(logData)
-			return nil, errors.New(bankErr.ErrorMessage)
+			return nil, bankErr
 		}
 
 		return nil, err
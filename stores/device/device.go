@@ -0,0 +1,404 @@
+package device
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"bitbucket.org/paydoh/paydoh-commons/database"
+	commonSrv "bitbucket.org/paydoh/paydoh-commons/services"
+
+	"bankapi/config"
+	"bankapi/constants"
+	"bankapi/models"
+	"bankapi/requests"
+	"bankapi/security"
+	"bankapi/services"
+	"bankapi/stores/open"
+	"bankapi/utils"
+)
+
+const deviceChangeOtpLength = 6
+
+// Store is the registry of the devices a user may bank from
+type Store struct {
+	db                  *sql.DB
+	memory              *database.InMemory
+	LoggerService       *commonSrv.LoggerService
+	open                *open.OpenStore
+	notificationService *services.NotificationService
+	auditLogService     services.AuditLogService
+}
+
+func NewStore(
+	log *commonSrv.LoggerService,
+	db *sql.DB,
+	memory *database.InMemory,
+	openStore *open.OpenStore,
+	auditLogService services.AuditLogService,
+) *Store {
+	return &Store{
+		db:                  db,
+		memory:              memory,
+		LoggerService:       log,
+		open:                openStore,
+		notificationService: services.NewNotificationService(),
+		auditLogService:     auditLogService,
+	}
+}
+
+// ListDevices returns the trusted devices of a user, marking the one making the request
+func (s *Store) ListDevices(ctx context.Context, authValues *models.AuthValues) ([]models.TrustedDevice, error) {
+	devices, err := models.ListTrustedDevices(s.db, authValues.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range devices {
+		devices[i].Current = devices[i].DeviceId == authValues.DeviceId
+	}
+
+	return devices, nil
+}
+
+// DeregisterDevice revokes a trusted device of the user and logs it out. The
+// device making the request cannot deregister itself.
+func (s *Store) DeregisterDevice(ctx context.Context, authValues *models.AuthValues, id string) error {
+	logData := &commonSrv.LogEntry{
+		Action:     constants.DEVICE,
+		RequestURI: "/api/device/:id",
+		Message:    "DeregisterDevice log",
+		UserID:     authValues.UserId,
+		RequestID:  utils.GetRequestIDFromContext(ctx),
+	}
+
+	device, err := models.GetTrustedDevice(s.db, id)
+	if err != nil {
+		return err
+	}
+
+	if device.UserId != authValues.UserId || device.Status == models.DeviceStatusRevoked {
+		return constants.ErrNoDataFound
+	}
+
+	if device.DeviceId == authValues.DeviceId {
+		return errors.New("the current device cannot be deregistered")
+	}
+
+	if err := models.RevokeTrustedDevice(s.db, device.Id); err != nil {
+		return err
+	}
+
+	if err := s.logoutDevices(authValues.UserId, []string{device.DeviceId}); err != nil {
+		logData.Message = "DeregisterDevice: Error logging out device:- " + err.Error()
+		s.LoggerService.LogError(logData)
+	}
+
+	if err := models.RevokeDeviceKeysOfDevices(s.db, authValues.UserId, []string{device.DeviceId}); err != nil {
+		logData.Message = "DeregisterDevice: Error revoking device keys:- " + err.Error()
+		s.LoggerService.LogError(logData)
+	}
+
+	s.audit(ctx, authValues, constants.DEVICE_DEREGISTERED, fmt.Sprintf("device=%s", device.Id))
+
+	logData.Message = "DeregisterDevice: device deregistered"
+	s.LoggerService.LogInfo(logData)
+
+	return nil
+}
+
+// InitiateDeviceChange registers the device of the request as a pending
+// device, sends an otp to the registered mobile number and returns the message
+// the device sends by sms to bind its sim, as the sim verification does.
+func (s *Store) InitiateDeviceChange(ctx context.Context, authValues *models.AuthValues, request *requests.AuthenticationRequest) (interface{}, error) {
+	logData := &commonSrv.LogEntry{
+		Action:     constants.DEVICE,
+		RequestURI: "/api/device/change/initiate",
+		Message:    "InitiateDeviceChange log",
+		UserID:     authValues.UserId,
+		RequestID:  utils.GetRequestIDFromContext(ctx),
+	}
+
+	dataRequest, err := request.DecrypToData(authValues.Key)
+	if err != nil {
+		logData.Message = "InitiateDeviceChange: Error while decrypting data"
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	if authValues.DeviceId == "" || dataRequest.DeviceId != authValues.DeviceId {
+		return nil, errors.New("device id does not match the X-Device-ID header")
+	}
+
+	user, err := models.GetUserDataByUserId(s.db, authValues.UserId)
+	if err != nil {
+		logData.Message = "InitiateDeviceChange: Error getting user data"
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	device := models.NewTrustedDevice()
+	device.UserId = authValues.UserId
+	device.DeviceId = authValues.DeviceId
+	device.OS = models.NullString{NullString: sql.NullString{String: authValues.OS, Valid: authValues.OS != ""}}
+	device.OSVersion = models.NullString{NullString: sql.NullString{String: authValues.OSVersion, Valid: authValues.OSVersion != ""}}
+	device.PackageId = sql.NullString{String: dataRequest.PackageId, Valid: dataRequest.PackageId != ""}
+	device.DeviceToken = sql.NullString{String: dataRequest.DeviceToken, Valid: dataRequest.DeviceToken != ""}
+
+	if dataRequest.SimVendorId != "" {
+		encryptedSimVendorId, err := security.Encrypt([]byte(dataRequest.SimVendorId), []byte(authValues.SigningKey))
+		if err != nil {
+			logData.Message = "InitiateDeviceChange: Error while encrypting sim vendor id"
+			s.LoggerService.LogError(logData)
+			return nil, err
+		}
+		device.SimVendorId = sql.NullString{String: encryptedSimVendorId, Valid: true}
+	}
+
+	if err := models.CreatePendingDevice(s.db, device); err != nil {
+		logData.Message = "InitiateDeviceChange: Error creating pending device:- " + err.Error()
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	deviceConfig := config.GetDeviceConfig()
+
+	otp, err := security.GenerateOTP(deviceChangeOtpLength)
+	if err != nil {
+		return nil, err
+	}
+
+	otpKey := fmt.Sprintf(constants.DeviceChangeOtpKeyFormat, authValues.UserId, authValues.DeviceId)
+	if err := s.memory.Set(otpKey, security.HashToken(otp), deviceConfig.ChangeOtpTTL); err != nil {
+		logData.Message = "InitiateDeviceChange: Error while setting otp in memory"
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	if err := s.notificationService.SendOtp(&requests.SendOtpRequest{
+		MobileNumber: user.MobileNumber,
+		Otp:          otp,
+		Purpose:      constants.DEVICE_CHANGED,
+		ExpiresIn:    int64(deviceConfig.ChangeOtpTTL.Seconds()),
+	}); err != nil {
+		logData.Message = "InitiateDeviceChange: Error sending otp:- " + err.Error()
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	message := security.GenerateRandomCode(36)
+
+	encryptedMessage, err := security.Encrypt([]byte(message), []byte(authValues.SigningKey))
+	if err != nil {
+		logData.Message = "InitiateDeviceChange: Error while encrypting message"
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	if err := s.memory.Set(fmt.Sprintf(constants.DeviceChangeSimKeyFormat, authValues.UserId), encryptedMessage, deviceConfig.ChangeOtpTTL); err != nil {
+		logData.Message = "InitiateDeviceChange: Error while setting message in memory"
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	logData.Message = "InitiateDeviceChange: device change initiated"
+	logData.EndTime = time.Now()
+	s.LoggerService.LogInfo(logData)
+
+	return map[string]interface{}{
+		"message":        encryptedMessage,
+		"mobile_number":  constants.ROUTE_SMS_MOBILE_NUMBER,
+		"otp_expires_in": int64(deviceConfig.ChangeOtpTTL.Seconds()),
+	}, nil
+}
+
+// GetDeviceChangeStatus reports whether the sim of the pending device is bound
+func (s *Store) GetDeviceChangeStatus(ctx context.Context, authValues *models.AuthValues) (interface{}, error) {
+	device, err := models.FindTrustedDevice(s.db, authValues.UserId, authValues.DeviceId)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"status":          device.Status,
+		"is_sim_verified": device.SimVerifiedAt.Valid,
+	}, nil
+}
+
+// VerifyDeviceChange trusts the pending device once its sim is bound and the
+// user confirmed it with the otp and the mpin. The previous devices are
+// revoked and notified, the new device has limited payments while it cools.
+func (s *Store) VerifyDeviceChange(ctx context.Context, authValues *models.AuthValues, request *requests.DeviceChangeVerifyRequest) (*models.TrustedDevice, error) {
+	logData := &commonSrv.LogEntry{
+		Action:     constants.DEVICE,
+		RequestURI: "/api/device/change/verify",
+		Message:    "VerifyDeviceChange log",
+		UserID:     authValues.UserId,
+		RequestID:  utils.GetRequestIDFromContext(ctx),
+	}
+
+	device, err := models.FindTrustedDevice(s.db, authValues.UserId, authValues.DeviceId)
+	if err != nil {
+		if errors.Is(err, constants.ErrNoDataFound) {
+			return nil, errors.New("no device change in progress")
+		}
+		return nil, err
+	}
+
+	if device.Status != models.DeviceStatusPending {
+		return nil, errors.New("no device change in progress")
+	}
+
+	if !device.SimVerifiedAt.Valid {
+		return nil, errors.New("sim binding of the device is not verified")
+	}
+
+	otpKey := fmt.Sprintf(constants.DeviceChangeOtpKeyFormat, authValues.UserId, authValues.DeviceId)
+	otpHash, err := s.memory.Get(otpKey)
+	if err != nil || subtle.ConstantTimeCompare([]byte(otpHash), []byte(security.HashToken(request.Otp))) != 1 {
+		logData.Message = "VerifyDeviceChange: invalid otp"
+		s.LoggerService.LogError(logData)
+		return nil, errors.New(constants.DeviceChangeInvalidOtpErrorMessage)
+	}
+
+	if err := s.open.VerifyMpin(ctx, &requests.MpinRequest{Mpin: request.Mpin}, authValues); err != nil {
+		return nil, err
+	}
+
+	if err := s.memory.Delete(otpKey); err != nil {
+		logData.Message = "VerifyDeviceChange: Error deleting otp from memory"
+		s.LoggerService.LogError(logData)
+	}
+
+	// the previous device is notified at the token it registered with
+	previousDevice, err := models.FindOneDeviceByUserID(s.db, authValues.UserId)
+	if err != nil && !errors.Is(err, constants.ErrDeviceNotFound) {
+		return nil, err
+	}
+
+	deviceData, err := boundDeviceData(authValues, device)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := models.TrustDevice(s.db, device, time.Now().Add(config.GetDeviceConfig().CoolingPeriod), deviceData, previousDevice != nil)
+	if err != nil {
+		logData.Message = "VerifyDeviceChange: Error trusting device:- " + err.Error()
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	if err := s.logoutDevices(authValues.UserId, revoked); err != nil {
+		logData.Message = "VerifyDeviceChange: Error logging out previous devices:- " + err.Error()
+		s.LoggerService.LogError(logData)
+	}
+
+	// biometric login of the previous devices stops with their device data binding
+	if err := models.RevokeDeviceKeysOfDevices(s.db, authValues.UserId, revoked); err != nil {
+		logData.Message = "VerifyDeviceChange: Error revoking device keys:- " + err.Error()
+		s.LoggerService.LogError(logData)
+	}
+
+	if previousDevice != nil {
+		s.notifyPreviousDevice(authValues.UserId, previousDevice, logData)
+	}
+
+	s.audit(ctx, authValues, constants.DEVICE_CHANGED, fmt.Sprintf("device=%s cooling_until=%s", device.Id, device.CoolingUntil.Format(time.RFC3339)))
+
+	logData.Message = "VerifyDeviceChange: device trusted"
+	logData.EndTime = time.Now()
+	s.LoggerService.LogInfo(logData)
+
+	device.Current = true
+
+	return device, nil
+}
+
+// boundDeviceData is the device data of the user, which upi and
+// notifications use, pointed at the new device
+func boundDeviceData(authValues *models.AuthValues, device *models.TrustedDevice) (*models.DeviceData, error) {
+	encryptedDeviceId, err := security.Encrypt([]byte(device.DeviceId), []byte(authValues.SigningKey))
+	if err != nil {
+		return nil, err
+	}
+
+	deviceData := models.NewDeviceData()
+	deviceData.UserId = authValues.UserId
+	deviceData.DeviceId = encryptedDeviceId
+	deviceData.DeviceIp = sql.NullString{String: authValues.DeviceIp, Valid: authValues.DeviceIp != ""}
+	deviceData.SimVendorId = device.SimVendorId
+	deviceData.OS = device.OS.NullString
+	deviceData.OSVersion = device.OSVersion.NullString
+	deviceData.PackageId = device.PackageId.String
+	deviceData.DeviceToken = device.DeviceToken
+	deviceData.IsSimVerified = true
+	deviceData.IsActive = true
+
+	return deviceData, nil
+}
+
+func (s *Store) notifyPreviousDevice(userId string, previousDevice *models.DeviceData, logData *commonSrv.LogEntry) {
+	if !previousDevice.DeviceToken.Valid || previousDevice.DeviceToken.String == "" {
+		return
+	}
+
+	request := &requests.NotificationRequest{}
+	if err := request.CreateNotificationPayload(
+		[]requests.NotificationUser{{
+			UserId:      userId,
+			DeviceToken: previousDevice.DeviceToken.String,
+			OS:          previousDevice.OS.String,
+			PackageId:   previousDevice.PackageId,
+		}},
+		"New device registered",
+		"Your Paydoh account was moved to a new device. If this was not you, contact support immediately.",
+		constants.DEVICE_CHANGED,
+		constants.DEVICE_CHANGED,
+	); err != nil {
+		return
+	}
+
+	if _, err := s.notificationService.SendNotification(request); err != nil {
+		logData.Message = "VerifyDeviceChange: Error notifying previous device:- " + err.Error()
+		s.LoggerService.LogError(logData)
+	}
+}
+
+// logoutDevices revokes the sessions of devices of the user
+func (s *Store) logoutDevices(userId string, deviceIds []string) error {
+	for _, deviceId := range deviceIds {
+		sessionIds, err := models.RevokeUserSessions(s.db, userId, deviceId, models.SessionRevokedDevice)
+		if err != nil {
+			return err
+		}
+
+		for _, sessionId := range sessionIds {
+			if err := s.memory.Delete(fmt.Sprintf(constants.SessionKeyFormat, sessionId)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) audit(ctx context.Context, authValues *models.AuthValues, action, body string) {
+	if err := s.auditLogService.Save(ctx, &services.AuditLog{
+		UserID:         authValues.UserId,
+		SourceIP:       authValues.DeviceIp,
+		DeviceID:       authValues.DeviceId,
+		RequestBody:    body,
+		ResponseStatus: http.StatusOK,
+		Action:         action,
+	}); err != nil {
+		s.LoggerService.LogError(&commonSrv.LogEntry{
+			Action:  constants.DEVICE,
+			Message: "device audit log:- " + err.Error(),
+			UserID:  authValues.UserId,
+		})
+	}
+}
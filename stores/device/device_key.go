@@ -0,0 +1,148 @@
+package device
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	commonSrv "bitbucket.org/paydoh/paydoh-commons/services"
+
+	"bankapi/config"
+	"bankapi/constants"
+	"bankapi/devicekey"
+	"bankapi/models"
+	"bankapi/requests"
+	"bankapi/security"
+	"bankapi/stepup"
+	"bankapi/utils"
+)
+
+// RegisterDeviceKey registers the public key of the device of the request for
+// biometric login and step-up, replacing its previous key. The device must be
+// the one bound in the device data.
+func (s *Store) RegisterDeviceKey(ctx context.Context, authValues *models.AuthValues, request *requests.RegisterDeviceKeyRequest) (*models.DeviceKey, error) {
+	logData := &commonSrv.LogEntry{
+		Action:     constants.DEVICE,
+		RequestURI: "/api/device/keys",
+		Message:    "RegisterDeviceKey log",
+		UserID:     authValues.UserId,
+		RequestID:  utils.GetRequestIDFromContext(ctx),
+	}
+
+	if _, err := security.ParseDevicePublicKey(request.Algorithm, request.PublicKey); err != nil {
+		return nil, err
+	}
+
+	deviceData, err := devicekey.BoundDeviceData(s.db, authValues.UserId, authValues.SigningKey, authValues.DeviceId)
+	if err != nil {
+		if errors.Is(err, constants.ErrDeviceNotFound) || errors.Is(err, constants.ErrDeviceNotTrusted) {
+			return nil, errors.New("a key can only be registered for the device bound to the account")
+		}
+		logData.Message = "RegisterDeviceKey: Error getting device data:- " + err.Error()
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	key := models.NewDeviceKey()
+	key.UserId = authValues.UserId
+	key.DeviceDataId = deviceData.Id.String()
+	key.DeviceId = authValues.DeviceId
+	key.Algorithm = request.Algorithm
+	key.PublicKey = request.PublicKey
+
+	if err := models.CreateDeviceKey(s.db, key); err != nil {
+		logData.Message = "RegisterDeviceKey: Error creating device key:- " + err.Error()
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	s.audit(ctx, authValues, constants.DEVICE_KEY_REGISTERED, fmt.Sprintf("key=%s algorithm=%s", key.Id, key.Algorithm))
+
+	logData.Message = "RegisterDeviceKey: device key registered"
+	s.LoggerService.LogInfo(logData)
+
+	key.Current = true
+
+	return key, nil
+}
+
+// ListDeviceKeys returns the active keys of a user, marking the key of the device making the request
+func (s *Store) ListDeviceKeys(ctx context.Context, authValues *models.AuthValues) ([]models.DeviceKey, error) {
+	keys, err := models.ListDeviceKeys(s.db, authValues.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range keys {
+		keys[i].Current = keys[i].DeviceId == authValues.DeviceId
+	}
+
+	return keys, nil
+}
+
+// RevokeDeviceKey turns biometric login off for a key of the user
+func (s *Store) RevokeDeviceKey(ctx context.Context, authValues *models.AuthValues, id string) error {
+	key, err := models.GetDeviceKey(s.db, id)
+	if err != nil {
+		return err
+	}
+
+	if key.UserId != authValues.UserId || key.Status != models.DeviceKeyStatusActive {
+		return constants.ErrNoDataFound
+	}
+
+	if err := models.RevokeDeviceKey(s.db, key.Id); err != nil {
+		return err
+	}
+
+	s.audit(ctx, authValues, constants.DEVICE_KEY_REVOKED, fmt.Sprintf("key=%s", key.Id))
+
+	return nil
+}
+
+// IssueStepUpChallenge issues a step-up challenge for the key of the device of the request
+func (s *Store) IssueStepUpChallenge(ctx context.Context, authValues *models.AuthValues) (*devicekey.Challenge, error) {
+	key, err := models.FindActiveDeviceKey(s.db, authValues.UserId, authValues.DeviceId)
+	if err != nil {
+		if errors.Is(err, constants.ErrNoDataFound) {
+			return nil, errors.New("biometric verification is not set up on this device")
+		}
+		return nil, err
+	}
+
+	return devicekey.Issue(ctx, key, devicekey.PurposeStepUp, authValues.SessionId)
+}
+
+// VerifyStepUpChallenge checks a signed step-up challenge, the signature
+// stands in for the mpin and grants its step-up factor to the session
+func (s *Store) VerifyStepUpChallenge(ctx context.Context, authValues *models.AuthValues, request *requests.DeviceKeySignatureRequest) error {
+	logData := &commonSrv.LogEntry{
+		Action:     constants.DEVICE,
+		RequestURI: "/api/device/keys/step-up/verify",
+		Message:    "VerifyStepUpChallenge log",
+		UserID:     authValues.UserId,
+		RequestID:  utils.GetRequestIDFromContext(ctx),
+	}
+
+	challenge, key, err := devicekey.Verify(ctx, s.db, request.ChallengeId, devicekey.PurposeStepUp, authValues.DeviceId, request.Signature)
+	if err != nil {
+		logData.Message = "VerifyStepUpChallenge: " + err.Error()
+		s.LoggerService.LogError(logData)
+		return err
+	}
+
+	if challenge.UserId != authValues.UserId || challenge.SessionId != authValues.SessionId {
+		return devicekey.ErrInvalidChallenge
+	}
+
+	subject := &stepup.Subject{UserId: authValues.UserId, SessionId: authValues.SessionId, DeviceId: authValues.DeviceId}
+	if err := stepup.Grant(ctx, subject, config.StepUpFactorMpin); err != nil {
+		logData.Message = "VerifyStepUpChallenge: Error granting step-up:- " + err.Error()
+		s.LoggerService.LogError(logData)
+		return err
+	}
+
+	s.audit(ctx, authValues, constants.DEVICE_KEY_STEP_UP, fmt.Sprintf("key=%s", key.Id))
+
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"bitbucket.org/paydoh/paydoh-commons/database"
@@ -23,6 +24,9 @@ import (
 	"bankapi/utils"
 )
 
+// vcipInvokeEndpoint is the KVB path of VcipInvoke, its response codes are in the bank error registry
+const vcipInvokeEndpoint = "/fintech/vcip/invoke"
+
 type Kyc interface {
 	UpdateKycConsent(authValues *models.AuthValues, request *requests.KycConsentRequest) (interface{}, error)
 	GetKycConsent(authValues *models.AuthValues) (interface{}, error)
@@ -32,13 +36,13 @@ type Store struct {
 	db            *sql.DB
 	m             *database.Document
 	memory        *database.InMemory
-	bankService   *services.BankApiService
+	bankService   services.BankProvider
 	LoggerService *commonSrv.LoggerService
 	auditLogSrv   services.AuditLogService
 }
 
 func NewStore(log *commonSrv.LoggerService, db *sql.DB, m *database.Document, memory *database.InMemory, auditLogSrv services.AuditLogService) *Store {
-	bankService := services.NewBankApiService(log, memory)
+	bankService := services.NewBankProvider(log, memory)
 	return &Store{
 		db:            db,
 		m:             m,
@@ -222,10 +226,8 @@ func (s *Store) ConsentAddition(ctx context.Context, request *requests.KycConsen
 	if len(consentDetailsData) > 0 {
 		_, err := s.bankService.PostUserConsent(ctx, outGoingRequest, consentDetailsData)
 		if err != nil {
-			if bankErr := s.bankService.HandleBankSpecificError(err, func(errorCode string) (string, bool) {
-				return constants.GetConsentErrorMessage(errorCode)
-			}); bankErr != nil {
-				return errors.New(bankErr.ErrorMessage)
+			if bankErr := s.bankService.HandleBankSpecificError(ctx, err); bankErr != nil {
+				return bankErr
 			}
 
 			logData.Message = "UpdateKycConsent: Error posting user consent to bank service"
@@ -260,7 +262,7 @@ func (s *Store) ConsentAddition(ctx context.Context, request *requests.KycConsen
 			return err
 		}
 
-		encryptReq, err := security.Encrypt(requestData, []byte(authValues.Key))
+		encryptReq, err := security.Encrypt(requestData, []byte(authValues.SigningKey))
 		if err != nil {
 			logData.Message = "UpdateAdressLog:error while encrypting request"
 			logData.EndTime = time.Now()
@@ -411,10 +413,19 @@ func (s *Store) GetVcipUrl(ctx context.Context, authValues *models.AuthValues) (
 		return "", opErr
 	}
 
-	if errorMessage, exists := constants.GetVcipInvokeErrorMessage(response.ResponseCode, response.Status); exists {
+	// the response code of a vcip invoke is looked up in the bank error registry,
+	// a 200 is only an error when its status is not success
+	language := utils.GetLanguageFromContext(ctx)
+	entry, exists := services.BankErrors().Lookup(vcipInvokeEndpoint, response.ResponseCode)
+	if exists && !entry.Retryable && !(response.ResponseCode == constants.VCIPInvokeErrorCode200 &&
+		strings.ToLower(response.Status) == constants.VCIPInvokeErrorCode200Success) {
 		logData.Message = fmt.Sprintf("Non-retryable error detected. ResponseCode: %s", response.ResponseCode)
 		s.LoggerService.LogError(logData)
-		return "", errors.New(errorMessage)
+		return "", errors.New(entry.Message(language))
+	}
+	maxRetries := 2
+	if exists && entry.Retryable {
+		maxRetries = entry.MaxRetries
 	}
 
 	retryErr := utils.RetryFunc(func() error {
@@ -425,7 +436,7 @@ func (s *Store) GetVcipUrl(ctx context.Context, authValues *models.AuthValues) (
 			return errors.New(lastRetryMessage)
 		}
 
-		if retryMessage, retryable := constants.GetVcipInvokeErrorRetryMessage(response.ResponseCode); retryable {
+		if entry, exists := services.BankErrors().Lookup(vcipInvokeEndpoint, response.ResponseCode); exists && entry.Retryable {
 			logData.Message = fmt.Sprintf("Retryable error detected. ResponseCode: %s, retrying...", response.ResponseCode)
 			s.LoggerService.LogError(logData)
 
@@ -434,12 +445,12 @@ func (s *Store) GetVcipUrl(ctx context.Context, authValues *models.AuthValues) (
 				return opErr
 			}
 
-			lastRetryMessage = retryMessage
-			return errors.New(retryMessage)
+			lastRetryMessage = entry.Message(language)
+			return errors.New(lastRetryMessage)
 		}
 
 		return nil
-	}, 2)
+	}, maxRetries)
 
 	if retryErr != nil {
 		logData.Message = fmt.Sprintf("VCIP API invocation failed: %s", lastRetryMessage)
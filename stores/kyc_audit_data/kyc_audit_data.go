@@ -19,12 +19,12 @@ type KycAuditStore struct {
 	db            *sql.DB
 	m             *database.Document
 	memory        *database.InMemory
-	bankService   *services.BankApiService
+	bankService   services.BankProvider
 	LoggerService *commonSrv.LoggerService
 }
 
 func NewKycAuditStore(log *commonSrv.LoggerService, db *sql.DB, m *database.Document, memory *database.InMemory) *KycAuditStore {
-	bankService := services.NewBankApiService(log, memory)
+	bankService := services.NewBankProvider(log, memory)
 	return &KycAuditStore{
 		db:            db,
 		m:             m,
@@ -30,7 +30,7 @@ type Store struct {
 	db            *sql.DB
 	m             *database.Document
 	memory        *database.InMemory
-	bankService   *services.BankApiService
+	bankService   services.BankProvider
 	LoggerService *commonSrv.LoggerService
 	auditLogSrv   services.AuditLogService
 }
@@ -42,7 +42,7 @@ func NewStore(
 	memory *database.InMemory,
 	auditLogSrv services.AuditLogService,
 ) *Store {
-	bankService := services.NewBankApiService(log, memory)
+	bankService := services.NewBankProvider(log, memory)
 	return &Store{
 		db:            db,
 		m:             m,
@@ -193,31 +193,25 @@ func (s *Store) AddNominee(ctx context.Context, authValues *models.AuthValues, r
 
 	response, opErr = s.bankService.CreateAddNominee(ctx, outgoingRequest)
 	if opErr != nil {
-		bankErr := s.bankService.HandleBankSpecificError(opErr, func(errorCode string) (string, bool) {
-			return constants.GetNomineeErrorMessage(errorCode)
-		})
+		bankErr := s.bankService.HandleBankSpecificError(ctx, opErr)
 
 		if bankErr != nil {
 			logData.Message = fmt.Sprintf("Bank error encountered (ErrorCode: %s)", bankErr.ErrorCode)
 			s.LoggerService.LogError(logData)
 
-			if errorMessage, exists := constants.GetNomineeErrorMessage(bankErr.ErrorCode); exists {
-				return nil, errors.New(errorMessage)
-			}
-
-			if msg, retryable := constants.GetNomineeErrorRetryMessages(bankErr.ErrorCode); retryable {
+			if bankErr.Retryable {
 				err := utils.RetryFunc(func() error {
 					response, opErr = s.bankService.CreateAddNominee(ctx, outgoingRequest)
 					return opErr
-				}, 2)
+				}, bankErr.MaxRetries)
 
 				if err != nil {
 					logData.Message = "AddNominee: CreateAddNominee failed after retries"
 					s.LoggerService.LogError(logData)
-					return nil, errors.New(msg)
+					return nil, bankErr
 				}
 			} else {
-				return nil, errors.New(bankErr.ErrorMessage)
+				return nil, bankErr
 			}
 		} else {
 			return nil, opErr
@@ -315,31 +309,25 @@ func (s *Store) VerifyNomineeOtp(ctx context.Context, authValues *models.AuthVal
 
 	response, opErr = s.bankService.VerifyNomineeOTP(ctx, outgoingRequest)
 	if opErr != nil {
-		bankErr := s.bankService.HandleBankSpecificError(opErr, func(errorCode string) (string, bool) {
-			return constants.GetNomineeErrorMessage(errorCode)
-		})
+		bankErr := s.bankService.HandleBankSpecificError(ctx, opErr)
 
 		if bankErr != nil {
 			logData.Message = fmt.Sprintf("Bank error encountered (ErrorCode: %s)", bankErr.ErrorCode)
 			s.LoggerService.LogError(logData)
 
-			if errorMessage, exists := constants.GetNomineeErrorMessage(bankErr.ErrorCode); exists {
-				return nil, errors.New(errorMessage)
-			}
-
-			if msg, retryable := constants.GetNomineeErrorRetryMessages(bankErr.ErrorCode); retryable {
+			if bankErr.Retryable {
 				err := utils.RetryFunc(func() error {
 					response, opErr = s.bankService.VerifyNomineeOTP(ctx, outgoingRequest)
 					return opErr
-				}, 2)
+				}, bankErr.MaxRetries)
 
 				if err != nil {
 					logData.Message = "VerifyNomineeOtp failed after retries"
 					s.LoggerService.LogError(logData)
-					return nil, errors.New(msg)
+					return nil, bankErr
 				}
 			} else {
-				return nil, errors.New(bankErr.ErrorMessage)
+				return nil, bankErr
 			}
 		} else {
 			return nil, opErr
@@ -33,7 +33,7 @@ type Store struct {
 	db                  *sql.DB
 	m                   *database.Document
 	memory              *database.InMemory
-	bankService         *services.BankApiService
+	bankService         services.BankProvider
 	notificationService *services.NotificationService
 	LoggerService       *commonSrv.LoggerService
 	AuthStore           *authorization.AuthorizationStore
@@ -46,7 +46,7 @@ func NewStore(
 	memory *database.InMemory,
 	authStore *authorization.AuthorizationStore,
 ) *Store {
-	bankService := services.NewBankApiService(log, memory)
+	bankService := services.NewBankProvider(log, memory)
 	notificationService := services.NewNotificationService()
 	return &Store{
 		db:                  db,
@@ -270,14 +270,12 @@ func (s *Store) CreateBankAccount(ctx context.Context, authValues *models.AuthVa
 
 	demographicResponse, err := s.bankService.GetDemographicData(ctx, demographicRequest)
 	if err != nil {
-		bankErr := s.bankService.HandleBankSpecificError(err, func(errorCode string) (string, bool) {
-			return constants.GetDemographicErrorMessage(errorCode)
-		})
+		bankErr := s.bankService.HandleBankSpecificError(ctx, err)
 
 		if bankErr != nil {
 			logData.Message = fmt.Sprintf("GetDemographicData: Bank error encountered (ErrorCode: %s)", bankErr.ErrorCode)
 			s.LoggerService.LogError(logData)
-			return nil, errors.New(bankErr.ErrorMessage)
+			return nil, bankErr
 		}
 
 		return nil, err
@@ -382,31 +380,25 @@ func (s *Store) CreateBankAccount(ctx context.Context, authValues *models.AuthVa
 
 	response, opErr = s.bankService.CreatebankAccount(ctx, outGoingCreateBankAccountRequest)
 	if opErr != nil {
-		bankErr := s.bankService.HandleBankSpecificError(opErr, func(errorCode string) (string, bool) {
-			return constants.GetAccountCreationErrorMessage(errorCode)
-		})
+		bankErr := s.bankService.HandleBankSpecificError(ctx, opErr)
 
 		if bankErr != nil {
 			logData.Message = fmt.Sprintf("Bank error encountered (ErrorCode: %s)", bankErr.ErrorCode)
 			s.LoggerService.LogError(logData)
 
-			if errorMessage, exists := constants.GetAccountCreationErrorMessage(bankErr.ErrorCode); exists {
-				return nil, errors.New(errorMessage)
-			}
-
-			if msg, retryable := constants.GetAccountCreationRetryErrorCode(bankErr.ErrorCode); retryable {
+			if bankErr.Retryable {
 				err := utils.RetryFunc(func() error {
 					response, opErr = s.bankService.CreatebankAccount(ctx, outGoingCreateBankAccountRequest)
 					return opErr
-				}, 2)
+				}, bankErr.MaxRetries)
 
 				if err != nil {
 					logData.Message = "CreateBankAccount failed after retries"
 					s.LoggerService.LogError(logData)
-					return nil, errors.New(msg)
+					return nil, bankErr
 				}
 			} else {
-				return nil, errors.New(bankErr.ErrorMessage)
+				return nil, bankErr
 			}
 		} else {
 			return nil, opErr
@@ -0,0 +1,170 @@
+package open
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	commonSrv "bitbucket.org/paydoh/paydoh-commons/services"
+	"golang.org/x/crypto/bcrypt"
+
+	"bankapi/config"
+	"bankapi/constants"
+	"bankapi/models"
+	"bankapi/requests"
+	"bankapi/services"
+	"bankapi/utils"
+)
+
+// formatLockoutWait rounds the rest of a lockout up to minutes or hours
+func formatLockoutWait(wait time.Duration) string {
+	if wait >= time.Hour {
+		hours := int(math.Ceil(wait.Hours()))
+		if hours == 1 {
+			return "1 hour"
+		}
+		return fmt.Sprintf("%d hours", hours)
+	}
+
+	minutes := int(math.Ceil(wait.Minutes()))
+	if minutes <= 1 {
+		return "1 minute"
+	}
+	return fmt.Sprintf("%d minutes", minutes)
+}
+
+// checkMpin compares mpin with the stored hash under the lockout policy, a
+// locked mpin is not compared and a wrong one counts towards the next lockout
+func (s *OpenStore) checkMpin(ctx context.Context, authValues *models.AuthValues, mpinHash, mpin string, logData *commonSrv.LogEntry) error {
+	lockoutConfig, err := config.GetMpinLockoutConfig()
+	if err != nil {
+		return err
+	}
+
+	attemptData, err := models.GetMpinAttempts(s.db, authValues.UserId)
+	if err != nil {
+		logData.Message = "checkMpin: Error fetching attempt data"
+		s.LoggerService.LogError(logData)
+		return err
+	}
+
+	if attemptData == nil {
+		attemptData, err = models.CreateMpinAttempts(s.db, authValues.UserId)
+		if err != nil {
+			logData.Message = "checkMpin: Error creating attempt data"
+			s.LoggerService.LogError(logData)
+			return err
+		}
+	}
+
+	// a locked mpin is not checked, guesses during a lockout do not count
+	if attemptData.IsLocked() {
+		logData.Message = "checkMpin: MPIN is locked"
+		s.LoggerService.LogError(logData)
+		return fmt.Errorf(constants.MpinLockedErrorMessage, formatLockoutWait(time.Until(attemptData.LockedUntil.Time)))
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(mpinHash), []byte(mpin)); err == nil {
+		return nil
+	}
+
+	attemptData, locked, err := models.RecordMpinFailure(s.db, authValues.UserId, lockoutConfig)
+	if errors.Is(err, constants.ErrMpinLocked) {
+		logData.Message = "checkMpin: MPIN was locked by a concurrent attempt"
+		s.LoggerService.LogError(logData)
+		return fmt.Errorf(constants.MpinLockedErrorMessage, formatLockoutWait(time.Until(attemptData.LockedUntil.Time)))
+	}
+	if err != nil {
+		logData.Message = "checkMpin: Error updating attempt data"
+		s.LoggerService.LogError(logData)
+		return err
+	}
+
+	logData.Message = "checkMpin: Incorrect MPIN"
+	s.LoggerService.LogError(logData)
+
+	if locked {
+		s.notifyMpinLockout(ctx, authValues, attemptData, logData.RequestURI)
+		return fmt.Errorf(constants.MpinLockedErrorMessage, formatLockoutWait(time.Until(attemptData.LockedUntil.Time)))
+	}
+
+	return fmt.Errorf("incorrect MPIN. You have %d attempts remaining", lockoutConfig.MaxAttempts-attemptData.Attempts)
+}
+
+// notifyMpinLockout tells the user by push and sms that their mpin was
+// locked, so a lockout they did not cause is noticed, and writes an audit entry
+func (s *OpenStore) notifyMpinLockout(ctx context.Context, authValues *models.AuthValues, attemptData *models.MpinAttemptData, requestURI string) {
+	logData := &commonSrv.LogEntry{
+		Action:     constants.OPEN,
+		RequestURI: requestURI,
+		Message:    "notifyMpinLockout log",
+		UserID:     authValues.UserId,
+		RequestID:  utils.GetRequestIDFromContext(ctx),
+	}
+
+	lockedFor := formatLockoutWait(time.Until(attemptData.LockedUntil.Time))
+
+	if err := s.auditLogSrv.Save(ctx, &services.AuditLog{
+		UserID:         authValues.UserId,
+		SourceIP:       authValues.DeviceIp,
+		DeviceID:       authValues.DeviceId,
+		RequestURL:     requestURI,
+		HTTPMethod:     http.MethodPost,
+		RequestBody:    fmt.Sprintf("lockout=%d locked_until=%s", attemptData.LockoutCount, attemptData.LockedUntil.Time.Format(time.RFC3339)),
+		ResponseStatus: http.StatusBadRequest,
+		Action:         constants.MPIN_LOCKED,
+	}); err != nil {
+		logData.Message = "notifyMpinLockout: Error saving audit log"
+		s.LoggerService.LogError(logData)
+	}
+
+	title := "MPIN locked"
+	body := fmt.Sprintf("Your Paydoh MPIN was locked for %s after too many incorrect attempts. If this was not you, reset your MPIN or contact support.", lockedFor)
+	notificationService := services.NewNotificationService()
+
+	deviceData, err := models.FindOneDeviceByUserID(s.db, authValues.UserId)
+	if err == nil && deviceData.DeviceToken.Valid && deviceData.DeviceToken.String != "" {
+		request := &requests.NotificationRequest{}
+		if err := request.CreateNotificationPayload(
+			[]requests.NotificationUser{{
+				UserId:      authValues.UserId,
+				DeviceToken: deviceData.DeviceToken.String,
+				OS:          deviceData.OS.String,
+				PackageId:   deviceData.PackageId,
+			}},
+			title,
+			body,
+			constants.MPIN_LOCKED,
+			constants.MPIN_LOCKED,
+		); err == nil {
+			if _, err := notificationService.SendNotification(request); err != nil {
+				logData.Message = "notifyMpinLockout: Error sending notification:- " + err.Error()
+				s.LoggerService.LogError(logData)
+			}
+		}
+	}
+
+	user, err := models.GetUserDataByUserId(s.db, authValues.UserId)
+	if err != nil {
+		logData.Message = "notifyMpinLockout: Error getting user data"
+		s.LoggerService.LogError(logData)
+		return
+	}
+
+	if err := notificationService.SendSms(&requests.SendSmsRequest{
+		MobileNumber: user.MobileNumber,
+		Message:      body,
+		Purpose:      constants.MPIN_LOCKED,
+	}); err != nil {
+		logData.Message = "notifyMpinLockout: Error sending sms:- " + err.Error()
+		s.LoggerService.LogError(logData)
+	}
+}
+
+// ListMpinLockouts returns the users whose mpin is locked now, for support
+func (s *OpenStore) ListMpinLockouts(ctx context.Context) ([]models.MpinLockout, error) {
+	return models.ListMpinLockouts(s.db)
+}
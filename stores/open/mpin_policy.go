@@ -0,0 +1,55 @@
+package open
+
+import (
+	"errors"
+
+	"bankapi/config"
+	"bankapi/constants"
+	"bankapi/models"
+	"bankapi/security"
+)
+
+// validateNewMpin checks a new mpin against the strength rules with the date of
+// birth and mobile number of the user, and against their previous mpins
+func (s *OpenStore) validateNewMpin(userId, mpin string) error {
+	var dateOfBirth, mobileNumber string
+
+	personalInformation, err := models.GetPersonalInformation(s.db, userId)
+	if err != nil && !errors.Is(err, constants.ErrNoDataFound) {
+		return err
+	}
+	if personalInformation != nil {
+		dateOfBirth = personalInformation.DateOfBirth
+	}
+
+	user, err := models.GetUserDataByUserId(s.db, userId)
+	if err != nil {
+		return err
+	}
+	mobileNumber = user.MobileNumber
+
+	if err := security.CheckMpinStrength(mpin, dateOfBirth, mobileNumber); err != nil {
+		return err
+	}
+
+	historySize := config.GetMpinHistorySize()
+	reused, err := models.IsMpinInHistory(s.db, userId, mpin, historySize)
+	if err != nil {
+		return err
+	}
+	if reused {
+		return security.NewMpinReusedError(historySize)
+	}
+
+	return nil
+}
+
+// recordMpinHistory adds the mpin the user has just set to their history
+func (s *OpenStore) recordMpinHistory(userId string) error {
+	mpinData, err := models.FindOneMpinByUserId(s.db, userId)
+	if err != nil {
+		return err
+	}
+
+	return models.InsertMpinHistory(s.db, userId, mpinData.MPIN, config.GetMpinHistorySize())
+}
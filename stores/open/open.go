@@ -2,13 +2,12 @@ package open
 
 import (
 	"context"
+	"crypto/subtle"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
-	"os"
-	"strconv"
 	"strings"
 	"time"
 
@@ -20,6 +19,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
 
+	"bankapi/config"
 	"bankapi/constants"
 	"bankapi/models"
 	"bankapi/requests"
@@ -27,9 +27,12 @@ import (
 	"bankapi/rpc"
 	"bankapi/security"
 	"bankapi/services"
+	"bankapi/stepup"
 	"bankapi/utils"
 )
 
+const stepUpOtpLength = 6
+
 type Open interface {
 	GetRelations(userId, os, osVersion, key string) (interface{}, error)
 	GetStates(userId, os, osVersion, key string) (interface{}, error)
@@ -48,7 +51,7 @@ type Open interface {
 
 type OpenStore struct {
 	db            *sql.DB
-	bankService   *services.BankApiService
+	bankService   services.BankProvider
 	m             *database.Document
 	memory        *database.InMemory
 	ctx           context.Context
@@ -67,7 +70,7 @@ func NewOpenStore(
 	client rpc.PaymentServiceClient,
 	auditLogSrv services.AuditLogService,
 ) *OpenStore {
-	bankService := services.NewBankApiService(log, memory)
+	bankService := services.NewBankProvider(log, memory)
 	service := httpservice.NewHttpService(constants.PaymentServiceURL)
 	return &OpenStore{
 		db:            db,
@@ -577,12 +580,23 @@ func (s *OpenStore) SetMpin(ctx context.Context, request *requests.MpinRequest,
 			logData.Message = "SetMpin: Mpin not found, inserting new mpin"
 			s.LoggerService.LogError(logData)
 
+			if err := s.validateNewMpin(authValues.UserId, request.Mpin); err != nil {
+				logData.Message = "SetMpin: Mpin rejected:- " + err.Error()
+				s.LoggerService.LogError(logData)
+				return err
+			}
+
 			if err := models.InsertMpin(s.db, request, authValues.UserId); err != nil {
 				logData.Message = "SetMpin: Error inserting mpin"
 				s.LoggerService.LogError(logData)
 				return err
 			}
 
+			if err := s.recordMpinHistory(authValues.UserId); err != nil {
+				logData.Message = "SetMpin: Error saving mpin history"
+				s.LoggerService.LogError(logData)
+			}
+
 			// update onboarding status
 			if err := models.UpdateUserOnboardingStatus(constants.M_PIN_SETUP_STAGE, authValues.UserId); err != nil {
 				logData.Message = "SetMpin: error while updating onboarding status"
@@ -725,14 +739,12 @@ func (s *OpenStore) SyncIfscApi(ctx context.Context) error {
 			response, err := s.bankService.GetIfscData(request)
 
 			if err != nil {
-				bankErr := s.bankService.HandleBankSpecificError(err, func(errorCode string) (string, bool) {
-					return constants.GetIfscSyncErrorMessage(errorCode)
-				})
+				bankErr := s.bankService.HandleBankSpecificError(ctx, err)
 
 				if bankErr != nil {
 					logData.Message = fmt.Sprintf("SyncIfscApi: Bank error encountered (ErrorCode: %s)", bankErr.ErrorCode)
 					s.LoggerService.LogError(logData)
-					return errors.New(bankErr.ErrorMessage)
+					return bankErr
 				}
 
 				return err
@@ -794,14 +806,12 @@ func (s *OpenStore) SyncIfscApi(ctx context.Context) error {
 	response, err := s.bankService.GetIfscData(request)
 
 	if err != nil {
-		bankErr := s.bankService.HandleBankSpecificError(err, func(errorCode string) (string, bool) {
-			return constants.GetIfscSyncErrorMessage(errorCode)
-		})
+		bankErr := s.bankService.HandleBankSpecificError(ctx, err)
 
 		if bankErr != nil {
 			logData.Message = fmt.Sprintf("SyncIfscApi: Bank error encountered (ErrorCode: %s)", bankErr.ErrorCode)
 			s.LoggerService.LogError(logData)
-			return errors.New(bankErr.ErrorMessage)
+			return bankErr
 		}
 
 		return err
@@ -851,18 +861,6 @@ func (s *OpenStore) VerifyMpin(ctx context.Context, request *requests.MpinReques
 		RequestID:  utils.GetRequestIDFromContext(ctx),
 	}
 
-	maxAttempts, err := strconv.Atoi(os.Getenv("MAX_MPIN_ATTEMPTS"))
-	if err != nil {
-		return fmt.Errorf("failed to parse MAX_MPIN_ATTEMPTS: %v", err)
-	}
-
-	// lockPeriodMinutes, err := strconv.Atoi(os.Getenv("MPIN_LOCK_PERIOD"))
-	// if err != nil {
-	// 	return fmt.Errorf("failed to parse MPIN_LOCK_PERIOD: %v", err)
-	// }
-
-	// lockPeriod := time.Duration(lockPeriodMinutes) * time.Minute
-
 	mpinData, err := models.FindOneMpinByUserId(s.db, authValues.UserId)
 	if err != nil {
 		logData.Message = "VerifyMpin: Error fetching MPIN"
@@ -873,57 +871,118 @@ func (s *OpenStore) VerifyMpin(ctx context.Context, request *requests.MpinReques
 		return err
 	}
 
-	attemptData, err := models.GetMpinAttempts(s.db, authValues.UserId)
-	if err != nil {
-		logData.Message = "VerifyMpin: Error fetching attempt data"
-		s.LoggerService.LogError(logData)
+	if err := s.checkMpin(ctx, authValues, mpinData.MPIN, request.Mpin, logData); err != nil {
 		return err
 	}
 
-	if attemptData == nil {
-		attemptData, err = models.CreateMpinAttempts(s.db, authValues.UserId)
-		if err != nil {
-			logData.Message = "VerifyMpin: Error creating attempt data"
-			s.LoggerService.LogError(logData)
-			return err
-		}
+	if err := models.ResetMpinAttempts(s.db, authValues.UserId); err != nil {
+		logData.Message = "VerifyMpin: Error resetting attempt data"
+		s.LoggerService.LogError(logData)
+		return err
 	}
 
-	if attemptData.Attempts >= maxAttempts {
-		logData.Message = "VerifyMpin: Account locked due to too many incorrect attempts"
+	logData.Message = "VerifyMpin: MPIN verified successfully"
+	s.LoggerService.LogInfo(logData)
+
+	// a verified mpin unlocks the routes whose step-up policy requires it
+	if err := stepup.Grant(ctx, stepUpSubject(authValues), config.StepUpFactorMpin); err != nil {
+		logData.Message = "VerifyMpin: Error granting step-up:- " + err.Error()
 		s.LoggerService.LogError(logData)
-		return errors.New("account locked. please reset your MPIN")
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(mpinData.MPIN), []byte(request.Mpin)); err != nil {
+	return nil
+}
 
-		attemptData.Attempts++
-		attemptData.LastAttempt = sql.NullTime{Time: time.Now(), Valid: true}
+func stepUpSubject(authValues *models.AuthValues) *stepup.Subject {
+	return &stepup.Subject{
+		UserId:    authValues.UserId,
+		SessionId: authValues.SessionId,
+		DeviceId:  authValues.DeviceId,
+	}
+}
 
-		if err := models.UpdateMpinAttempts(s.db, attemptData); err != nil {
-			logData.Message = "VerifyMpin: Error updating attempt data"
-			s.LoggerService.LogError(logData)
-			return err
-		}
+// SendStepUpOtp sends an otp to the registered mobile number, verifying it
+// grants the otp factor of step-up policies to the session
+func (s *OpenStore) SendStepUpOtp(ctx context.Context, authValues *models.AuthValues) (interface{}, error) {
+	logData := &commonSrv.LogEntry{
+		Action:     constants.STEP_UP,
+		RequestURI: "/api/open/step-up/otp",
+		Message:    "SendStepUpOtp log",
+		UserID:     authValues.UserId,
+		RequestID:  utils.GetRequestIDFromContext(ctx),
+	}
 
-		remainingAttempts := maxAttempts - attemptData.Attempts
-		logData.Message = "VerifyMpin: Incorrect MPIN"
+	user, err := models.GetUserDataByUserId(s.db, authValues.UserId)
+	if err != nil {
+		logData.Message = "SendStepUpOtp: Error getting user data"
 		s.LoggerService.LogError(logData)
+		return nil, err
+	}
 
-		if remainingAttempts <= 0 {
-			return errors.New("account locked. please reset your MPIN")
-		}
+	otp, err := security.GenerateOTP(stepUpOtpLength)
+	if err != nil {
+		return nil, err
+	}
 
-		return fmt.Errorf("incorrect MPIN. You have %d attempts remaining", remainingAttempts)
+	otpTTL := config.GetStepUpOtpTTL()
+	otpKey := fmt.Sprintf(constants.StepUpOtpKeyFormat, authValues.UserId, stepUpSubject(authValues).SessionKey())
+	if err := s.memory.Set(otpKey, security.HashToken(otp), otpTTL); err != nil {
+		logData.Message = "SendStepUpOtp: Error while setting otp in memory"
+		s.LoggerService.LogError(logData)
+		return nil, err
 	}
 
-	if err := models.ResetMpinAttempts(s.db, authValues.UserId); err != nil {
-		logData.Message = "VerifyMpin: Error resetting attempt data"
+	if err := services.NewNotificationService().SendOtp(&requests.SendOtpRequest{
+		MobileNumber: user.MobileNumber,
+		Otp:          otp,
+		Purpose:      constants.STEP_UP,
+		ExpiresIn:    int64(otpTTL.Seconds()),
+	}); err != nil {
+		logData.Message = "SendStepUpOtp: Error sending otp:- " + err.Error()
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	logData.Message = "SendStepUpOtp: otp sent"
+	s.LoggerService.LogInfo(logData)
+
+	return map[string]interface{}{
+		"otp_expires_in": int64(otpTTL.Seconds()),
+	}, nil
+}
+
+// VerifyStepUpOtp checks the otp sent by SendStepUpOtp, it can be used once
+func (s *OpenStore) VerifyStepUpOtp(ctx context.Context, request *requests.StepUpOtpRequest, authValues *models.AuthValues) error {
+	logData := &commonSrv.LogEntry{
+		Action:     constants.STEP_UP,
+		RequestURI: "/api/open/step-up/verify-otp",
+		Message:    "VerifyStepUpOtp log",
+		UserID:     authValues.UserId,
+		RequestID:  utils.GetRequestIDFromContext(ctx),
+	}
+
+	subject := stepUpSubject(authValues)
+	otpKey := fmt.Sprintf(constants.StepUpOtpKeyFormat, authValues.UserId, subject.SessionKey())
+	otpHash, err := s.memory.Get(otpKey)
+	if err != nil || subtle.ConstantTimeCompare([]byte(otpHash), []byte(security.HashToken(request.Otp))) != 1 {
+		logData.Message = "VerifyStepUpOtp: invalid otp"
+		s.LoggerService.LogError(logData)
+		return errors.New(constants.StepUpInvalidOtpErrorMessage)
+	}
+
+	if err := s.memory.Delete(otpKey); err != nil {
+		logData.Message = "VerifyStepUpOtp: Error deleting otp from memory"
 		s.LoggerService.LogError(logData)
 		return err
 	}
 
-	logData.Message = "VerifyMpin: MPIN verified successfully"
+	if err := stepup.Grant(ctx, subject, config.StepUpFactorOtp); err != nil {
+		logData.Message = "VerifyStepUpOtp: Error granting step-up:- " + err.Error()
+		s.LoggerService.LogError(logData)
+		return err
+	}
+
+	logData.Message = "VerifyStepUpOtp: otp verified"
 	s.LoggerService.LogInfo(logData)
 
 	return nil
@@ -951,11 +1010,9 @@ func (s *OpenStore) ResetMpin(ctx context.Context, request *requests.ResetMpinRe
 		return errors.New("MPIN already reset with the same value")
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(storedMpin.MPIN), []byte(request.ExistingMpin))
-	if err != nil {
-		logData.Message = "ResetMpin: Existing MPIN is incorrect"
-		s.LoggerService.LogError(logData)
-		return errors.New("incorrect existing MPIN")
+	// a wrong existing mpin counts towards the lockout like one on verify-mpin
+	if err := s.checkMpin(ctx, authValues, storedMpin.MPIN, request.ExistingMpin, logData); err != nil {
+		return err
 	}
 
 	if request.ExistingMpin == request.NewMpin {
@@ -964,6 +1021,12 @@ func (s *OpenStore) ResetMpin(ctx context.Context, request *requests.ResetMpinRe
 		return errors.New("new MPIN cannot be the same as the existing MPIN")
 	}
 
+	if err := s.validateNewMpin(authValues.UserId, request.NewMpin); err != nil {
+		logData.Message = "ResetMpin: New MPIN rejected:- " + err.Error()
+		s.LoggerService.LogError(logData)
+		return err
+	}
+
 	newMpinHash, err := bcrypt.GenerateFromPassword([]byte(request.NewMpin), bcrypt.DefaultCost)
 	if err != nil {
 		logData.Message = "ResetMpin: Error Encrypting new MPIN"
@@ -977,6 +1040,16 @@ func (s *OpenStore) ResetMpin(ctx context.Context, request *requests.ResetMpinRe
 		return err
 	}
 
+	if err := models.InsertMpinHistory(s.db, authValues.UserId, string(newMpinHash), config.GetMpinHistorySize()); err != nil {
+		logData.Message = "ResetMpin: Error saving mpin history"
+		s.LoggerService.LogError(logData)
+	}
+
+	if err := models.ResetMpinAttempts(s.db, authValues.UserId); err != nil {
+		logData.Message = "ResetMpin: Error resetting attempt data"
+		s.LoggerService.LogError(logData)
+	}
+
 	// save audit log
 
 	if err := s.auditLogSrv.Save(ctx, &services.AuditLog{
@@ -997,7 +1070,10 @@ func (s *OpenStore) ResetMpin(ctx context.Context, request *requests.ResetMpinRe
 	return nil
 }
 
-func (s *OpenStore) VerifyForgottenMpinResetRequest(ctx context.Context, request *requests.ForgotMpinResetRequest, authValues *models.AuthValues) error {
+// VerifyForgottenMpinResetRequest checks the account details of the user and
+// sends an otp to the registered mobile number, UpdateMpin needs it to set the
+// new mpin
+func (s *OpenStore) VerifyForgottenMpinResetRequest(ctx context.Context, request *requests.ForgotMpinResetRequest, authValues *models.AuthValues) (interface{}, error) {
 	logData := &commonSrv.LogEntry{
 		Action:     constants.OPEN,
 		RequestURI: "/api/open/verify-forgot-mpin",
@@ -1010,39 +1086,39 @@ func (s *OpenStore) VerifyForgottenMpinResetRequest(ctx context.Context, request
 	if err != nil {
 		logData.Message = "VerifyForgottenMpinResetRequest: Error fetching account details"
 		s.LoggerService.LogError(logData)
-		return err
+		return nil, err
 	}
 
 	personalInformation, err := models.GetPersonalInformation(s.db, authValues.UserId)
 	if err != nil {
 		logData.Message = "VerifyForgottenMpinResetRequest: Error fetching personal information"
 		s.LoggerService.LogError(logData)
-		return err
+		return nil, err
 	}
 
 	if request.AccountNumber != accountDetails.AccountNumber {
 		logData.Message = "VerifyForgottenMpinResetRequest: Account number does not match"
 		s.LoggerService.LogError(logData)
-		return errors.New("account number does not match")
+		return nil, errors.New("account number does not match")
 	}
 
 	if request.Email != personalInformation.Email {
 		logData.Message = "VerifyForgottenMpinResetRequest: Email does not match"
 		s.LoggerService.LogError(logData)
-		return errors.New("email does not match")
+		return nil, errors.New("email does not match")
 	}
 
 	if !strings.EqualFold(request.MotherMaidenName, accountDetails.MotherMaidenName.String) {
 		logData.Message = "VerifyForgottenMpinResetRequest: Mother maiden name does not match"
 		s.LoggerService.LogError(logData)
-		return errors.New("mother maiden name does not match")
+		return nil, errors.New("mother maiden name does not match")
 	}
 
 	if accountDetails.IsAddrSameAsAadhaar {
 		if request.PinCode != personalInformation.PinCode.String {
 			logData.Message = "VerifyForgottenMpinResetRequest: pincode does not match personal information"
 			s.LoggerService.LogError(logData)
-			return errors.New("pincode does not match")
+			return nil, errors.New("pincode does not match")
 		}
 	} else {
 
@@ -1050,16 +1126,51 @@ func (s *OpenStore) VerifyForgottenMpinResetRequest(ctx context.Context, request
 		if err := json.Unmarshal([]byte(accountDetails.CommunicationAddress.String), &userCommunicationAddress); err != nil {
 			logData.Message = "VerifyForgottenMpinResetRequest: Error unmarshaling communication address"
 			s.LoggerService.LogError(logData)
-			return errors.New("invalid communication address format")
+			return nil, errors.New("invalid communication address format")
 		}
 
 		if request.PinCode != userCommunicationAddress.PinCode {
 			logData.Message = "VerifyForgottenMpinResetRequest: pincode does not match communication address"
 			s.LoggerService.LogError(logData)
-			return errors.New("pincode does not match")
+			return nil, errors.New("pincode does not match")
 		}
 	}
 
+	lockoutConfig, err := config.GetMpinLockoutConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := models.GetUserDataByUserId(s.db, authValues.UserId)
+	if err != nil {
+		logData.Message = "VerifyForgottenMpinResetRequest: Error fetching user data"
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	otp, err := security.GenerateOTP(stepUpOtpLength)
+	if err != nil {
+		return nil, err
+	}
+
+	otpKey := fmt.Sprintf(constants.ForgotMpinOtpKeyFormat, authValues.UserId)
+	if err := s.memory.Set(otpKey, security.HashToken(otp), lockoutConfig.ForgotOtpTTL); err != nil {
+		logData.Message = "VerifyForgottenMpinResetRequest: Error while setting otp in memory"
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	if err := services.NewNotificationService().SendOtp(&requests.SendOtpRequest{
+		MobileNumber: user.MobileNumber,
+		Otp:          otp,
+		Purpose:      constants.FORGOT_MPIN,
+		ExpiresIn:    int64(lockoutConfig.ForgotOtpTTL.Seconds()),
+	}); err != nil {
+		logData.Message = "VerifyForgottenMpinResetRequest: Error sending otp:- " + err.Error()
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
 	// save audit log
 
 	if err := s.auditLogSrv.Save(ctx, &services.AuditLog{
@@ -1077,9 +1188,13 @@ func (s *OpenStore) VerifyForgottenMpinResetRequest(ctx context.Context, request
 	logData.EndTime = time.Now()
 	s.LoggerService.LogInfo(logData)
 
-	return nil
+	return map[string]interface{}{
+		"otp_expires_in": int64(lockoutConfig.ForgotOtpTTL.Seconds()),
+	}, nil
 }
 
+// UpdateMpin sets the new mpin of a forgotten mpin reset with the otp sent by
+// VerifyForgottenMpinResetRequest, it unlocks a locked mpin
 func (s *OpenStore) UpdateMpin(ctx context.Context, request *requests.UpdateMpinRequest, authValues *models.AuthValues) error {
 	logData := &commonSrv.LogEntry{
 		Action:     constants.OPEN,
@@ -1089,6 +1204,14 @@ func (s *OpenStore) UpdateMpin(ctx context.Context, request *requests.UpdateMpin
 		RequestID:  utils.GetRequestIDFromContext(ctx),
 	}
 
+	otpKey := fmt.Sprintf(constants.ForgotMpinOtpKeyFormat, authValues.UserId)
+	otpHash, err := s.memory.Get(otpKey)
+	if err != nil || subtle.ConstantTimeCompare([]byte(otpHash), []byte(security.HashToken(request.Otp))) != 1 {
+		logData.Message = "UpdateMpin: invalid otp"
+		s.LoggerService.LogError(logData)
+		return errors.New(constants.ForgotMpinInvalidOtpErrorMessage)
+	}
+
 	storedMpin, err := models.FindOneMpinByUserId(s.db, authValues.UserId)
 	if err != nil {
 		logData.Message = "ResetMpin: Error finding existing MPIN"
@@ -1102,6 +1225,12 @@ func (s *OpenStore) UpdateMpin(ctx context.Context, request *requests.UpdateMpin
 		return errors.New("your new mpin matches your current one. please enter a different mpin")
 	}
 
+	if err := s.validateNewMpin(authValues.UserId, request.Mpin); err != nil {
+		logData.Message = "UpdateMpin: New MPIN rejected:- " + err.Error()
+		s.LoggerService.LogError(logData)
+		return err
+	}
+
 	newMpinHash, err := bcrypt.GenerateFromPassword([]byte(request.Mpin), bcrypt.DefaultCost)
 	if err != nil {
 		logData.Message = "UpdateMpin: Error Encrypting new MPIN"
@@ -1115,12 +1244,22 @@ func (s *OpenStore) UpdateMpin(ctx context.Context, request *requests.UpdateMpin
 		return err
 	}
 
+	if err := models.InsertMpinHistory(s.db, authValues.UserId, string(newMpinHash), config.GetMpinHistorySize()); err != nil {
+		logData.Message = "UpdateMpin: Error saving mpin history"
+		s.LoggerService.LogError(logData)
+	}
+
 	if err := models.ResetMpinAttempts(s.db, authValues.UserId); err != nil {
 		logData.Message = "UpdateMpin: Error resetting MPIN attempts"
 		s.LoggerService.LogError(logData)
 		return err
 	}
 
+	if err := s.memory.Delete(otpKey); err != nil {
+		logData.Message = "UpdateMpin: Error deleting otp from memory"
+		s.LoggerService.LogError(logData)
+	}
+
 	logData.Message = "UpdateMpin: MPIN updated and attempts reset successfully"
 	logData.EndTime = time.Now()
 	s.LoggerService.LogInfo(logData)
@@ -19,12 +19,12 @@ type PaymentCallbackStore struct {
 	db            *sql.DB
 	m             *database.Document
 	memory        *database.InMemory
-	bankService   *services.BankApiService
+	bankService   services.BankProvider
 	LoggerService *commonSrv.LoggerService
 }
 
 func NewPaymentCallbackStore(log *commonSrv.LoggerService, db *sql.DB, m *database.Document, memory *database.InMemory) *PaymentCallbackStore {
-	bankService := services.NewBankApiService(log, memory)
+	bankService := services.NewBankProvider(log, memory)
 	return &PaymentCallbackStore{
 		db:            db,
 		m:             m,
@@ -0,0 +1,669 @@
+package standinginstruction
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	commonSrv "bitbucket.org/paydoh/paydoh-commons/services"
+	"github.com/hibiken/asynq"
+
+	"bankapi/config"
+	"bankapi/constants"
+	"bankapi/models"
+	"bankapi/requests"
+	"bankapi/security"
+	"bankapi/services"
+	"bankapi/stores/beneficiary"
+	"bankapi/utils"
+)
+
+// Store runs the scheduled and recurring transfers of users. Every run is an
+// asynq task scheduled at its time, runs submit the payment as a manual
+// payment does and the user confirms it with the otp the bank sends.
+type Store struct {
+	db                  *sql.DB
+	LoggerService       *commonSrv.LoggerService
+	beneficiary         *beneficiary.Store
+	scheduler           *asynq.Client
+	notificationService *services.NotificationService
+	auditLogService     services.AuditLogService
+}
+
+func NewStore(
+	log *commonSrv.LoggerService,
+	db *sql.DB,
+	beneficiaryStore *beneficiary.Store,
+	auditLogService services.AuditLogService,
+) *Store {
+	return &Store{
+		db:            db,
+		LoggerService: log,
+		beneficiary:   beneficiaryStore,
+		scheduler: asynq.NewClient(asynq.RedisClientOpt{
+			Addr:     constants.RedisURL,
+			Username: constants.RedisUserName,
+			Password: constants.RedisPassword,
+			DB:       constants.RedisDB,
+		}),
+		notificationService: services.NewNotificationService(),
+		auditLogService:     auditLogService,
+	}
+}
+
+// runPayload is the payload of the execute and reminder tasks of a run. A
+// task whose run is no longer the next run of its standing instruction was
+// skipped, paused or cancelled and does nothing.
+type runPayload struct {
+	StandingInstructionId string    `json:"standing_instruction_id"`
+	RunAt                 time.Time `json:"run_at"`
+}
+
+// CreateStandingInstruction schedules transfers to a verified beneficiary of
+// the user. The amount must be within the limits of the device that creates it.
+func (s *Store) CreateStandingInstruction(ctx context.Context, authValues *models.AuthValues, r *requests.StandingInstructionRequest) (*models.StandingInstruction, error) {
+	logData := &commonSrv.LogEntry{
+		Action:     constants.STANDING_INSTRUCTION,
+		RequestURI: "/api/standing-instructions",
+		Message:    "CreateStandingInstruction log",
+		UserID:     authValues.UserId,
+		RequestID:  utils.GetRequestIDFromContext(ctx),
+	}
+
+	if strings.ToLower(r.PaymentMode) == "ift" && r.BenfIfsc[:4] != "KVBL" {
+		return nil, errors.New("invalid ifsc code for ift payment mode")
+	}
+
+	ben, err := models.GetBeneficiaryByID(s.db, r.BenfId, r.BenfAcctNo, r.BenfMobNo, r.BenfIfsc)
+	if err != nil && !errors.Is(err, constants.ErrNoDataFound) {
+		logData.Message = "CreateStandingInstruction: Error fetching beneficiary"
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+	if ben == nil || ben.UserId != authValues.UserId {
+		return nil, errors.New(constants.StandingInstructionBeneficiaryErrorMessage)
+	}
+
+	if err := models.CheckDeviceCoolingLimit(s.db, authValues.UserId, authValues.DeviceId, r.Amount); err != nil {
+		return nil, err
+	}
+
+	encryptedAccount, err := security.Encrypt([]byte(r.BenfAcctNo), []byte(constants.AesPassPhrase))
+	if err != nil {
+		logData.Message = "CreateStandingInstruction: Error encrypting account number"
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	si := &models.StandingInstruction{
+		UserId:               authValues.UserId,
+		BenfId:               r.BenfId,
+		BenfName:             r.BenfName,
+		BenfIfsc:             r.BenfIfsc,
+		BenfAccount:          strings.Repeat("X", len(r.BenfAcctNo)-4) + r.BenfAcctNo[len(r.BenfAcctNo)-4:],
+		BenfAccountEncrypted: encryptedAccount,
+		BenfAcctType:         r.BenfAcctType,
+		BenfMobNo:            r.BenfMobNo,
+		PaymentMode:          r.PaymentMode,
+		Amount:               r.Amount,
+		Remarks:              r.Remarks,
+		Frequency:            r.Frequency,
+		StartDate:            r.StartDate,
+		Status:               models.StandingInstructionStatusActive,
+		DeviceId:             authValues.DeviceId,
+	}
+	if r.Frequency == models.StandingInstructionMonthly {
+		si.DayOfMonth = r.DayOfMonth
+	}
+	if r.EndDate != "" && r.Frequency != models.StandingInstructionOnce {
+		si.EndDate.String, si.EndDate.Valid = r.EndDate, true
+	}
+
+	nextRunAt, ok := si.NextRunAfter(time.Now())
+	if !ok {
+		return nil, errors.New(constants.StandingInstructionScheduleErrorMessage)
+	}
+	si.NextRunAt = &nextRunAt
+
+	if err := models.InsertStandingInstruction(s.db, si); err != nil {
+		logData.Message = "CreateStandingInstruction: Error saving standing instruction"
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	if err := s.schedule(si); err != nil {
+		logData.Message = "CreateStandingInstruction: Error scheduling standing instruction:- " + err.Error()
+		s.LoggerService.LogError(logData)
+
+		if err := models.UpdateStandingInstructionSchedule(s.db, si.Id, models.StandingInstructionStatusCancelled, nil); err != nil {
+			logData.Message = "CreateStandingInstruction: Error cancelling standing instruction"
+			s.LoggerService.LogError(logData)
+		}
+		return nil, errors.New(constants.RetryErrorMessage)
+	}
+
+	s.audit(ctx, authValues, constants.STANDING_INSTRUCTION_CREATED, fmt.Sprintf("standing_instruction=%s frequency=%s amount=%s", si.Id, si.Frequency, si.Amount))
+
+	logData.Message = "CreateStandingInstruction: standing instruction created"
+	s.LoggerService.LogInfo(logData)
+
+	return si, nil
+}
+
+// ListStandingInstructions returns the standing instructions of the user
+func (s *Store) ListStandingInstructions(ctx context.Context, authValues *models.AuthValues) ([]models.StandingInstruction, error) {
+	return models.ListStandingInstructions(s.db, authValues.UserId)
+}
+
+// ListExecutions returns the runs of a standing instruction of the user
+func (s *Store) ListExecutions(ctx context.Context, authValues *models.AuthValues, id string) ([]models.StandingInstructionExecution, error) {
+	si, err := s.getStandingInstruction(authValues, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.ListStandingInstructionExecutions(s.db, si.Id)
+}
+
+// PauseStandingInstruction stops the runs of a standing instruction until it is resumed
+func (s *Store) PauseStandingInstruction(ctx context.Context, authValues *models.AuthValues, id string) (*models.StandingInstruction, error) {
+	si, err := s.getStandingInstruction(authValues, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if si.Status != models.StandingInstructionStatusActive {
+		return nil, fmt.Errorf(constants.StandingInstructionStatusErrorMessage, "paused")
+	}
+
+	if err := s.updateSchedule(si, models.StandingInstructionStatusPaused, nil); err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, authValues, constants.STANDING_INSTRUCTION_UPDATED, fmt.Sprintf("standing_instruction=%s status=%s", si.Id, si.Status))
+
+	return si, nil
+}
+
+// ResumeStandingInstruction schedules the runs of a paused standing
+// instruction again from now, the runs missed while paused are not made
+func (s *Store) ResumeStandingInstruction(ctx context.Context, authValues *models.AuthValues, id string) (*models.StandingInstruction, error) {
+	si, err := s.getStandingInstruction(authValues, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if si.Status != models.StandingInstructionStatusPaused {
+		return nil, fmt.Errorf(constants.StandingInstructionStatusErrorMessage, "resumed")
+	}
+
+	nextRunAt, ok := si.NextRunAfter(time.Now())
+	if !ok {
+		return nil, errors.New(constants.StandingInstructionScheduleErrorMessage)
+	}
+
+	if err := s.updateSchedule(si, models.StandingInstructionStatusActive, &nextRunAt); err != nil {
+		return nil, err
+	}
+
+	if err := s.schedule(si); err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, authValues, constants.STANDING_INSTRUCTION_UPDATED, fmt.Sprintf("standing_instruction=%s status=%s", si.Id, si.Status))
+
+	return si, nil
+}
+
+// SkipStandingInstruction skips the next run of a standing instruction, the
+// runs after it are made
+func (s *Store) SkipStandingInstruction(ctx context.Context, authValues *models.AuthValues, id string) (*models.StandingInstruction, error) {
+	si, err := s.getStandingInstruction(authValues, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if si.Status != models.StandingInstructionStatusActive || si.NextRunAt == nil {
+		return nil, fmt.Errorf(constants.StandingInstructionStatusErrorMessage, "skipped")
+	}
+
+	if err := models.SkipStandingInstructionExecution(s.db, si, *si.NextRunAt); err != nil {
+		return nil, err
+	}
+
+	if err := s.advance(si, *si.NextRunAt); err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, authValues, constants.STANDING_INSTRUCTION_UPDATED, fmt.Sprintf("standing_instruction=%s skipped", si.Id))
+
+	return si, nil
+}
+
+// CancelStandingInstruction stops the runs of a standing instruction for good
+func (s *Store) CancelStandingInstruction(ctx context.Context, authValues *models.AuthValues, id string) error {
+	si, err := s.getStandingInstruction(authValues, id)
+	if err != nil {
+		return err
+	}
+
+	if si.Status != models.StandingInstructionStatusActive && si.Status != models.StandingInstructionStatusPaused {
+		return fmt.Errorf(constants.StandingInstructionStatusErrorMessage, "cancelled")
+	}
+
+	if err := s.updateSchedule(si, models.StandingInstructionStatusCancelled, nil); err != nil {
+		return err
+	}
+
+	s.audit(ctx, authValues, constants.STANDING_INSTRUCTION_UPDATED, fmt.Sprintf("standing_instruction=%s status=%s", si.Id, si.Status))
+
+	return nil
+}
+
+// ConfirmExecution confirms the payment of a run with the otp the bank sent
+// the user. A wrong otp keeps the run waiting for the otp.
+func (s *Store) ConfirmExecution(ctx context.Context, authValues *models.AuthValues, id, executionId, otp string) (*models.StandingInstructionExecution, error) {
+	logData := &commonSrv.LogEntry{
+		Action:     constants.STANDING_INSTRUCTION,
+		RequestURI: "/api/standing-instructions/:id/executions/:execution_id/confirm",
+		Message:    "ConfirmExecution log",
+		UserID:     authValues.UserId,
+		RequestID:  utils.GetRequestIDFromContext(ctx),
+	}
+
+	execution, err := models.GetStandingInstructionExecution(s.db, executionId)
+	if err != nil {
+		return nil, err
+	}
+
+	if execution.UserId != authValues.UserId || execution.StandingInstructionId != id {
+		return nil, constants.ErrNoDataFound
+	}
+
+	if execution.Status != models.ExecutionStatusAwaitingOtp || !execution.TxnIdentifier.Valid {
+		return nil, errors.New(constants.StandingInstructionOtpErrorMessage)
+	}
+
+	if _, err := s.beneficiary.ConfirmPayment(ctx, logData, authValues.UserId, execution.TxnIdentifier.String, otp); err != nil {
+		return nil, err
+	}
+
+	if err := models.UpdateStandingInstructionExecution(s.db, execution.Id, models.ExecutionStatusSuccess, "", ""); err != nil {
+		logData.Message = "ConfirmExecution: Error updating execution"
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+	execution.Status = models.ExecutionStatusSuccess
+	execution.ErrorMessage = models.NullString{}
+
+	logData.Message = "ConfirmExecution: payment confirmed"
+	s.LoggerService.LogInfo(logData)
+
+	return execution, nil
+}
+
+// ExecuteHandler makes a run of a standing instruction. A run the bank could
+// not be reached for is retried by asynq up to STANDING_INSTRUCTION_MAX_RETRIES
+// times, a run the bank turns down, that no longer meets the limits or whose
+// beneficiary is not verified fails at once.
+func (s *Store) ExecuteHandler(ctx context.Context, t *asynq.Task) error {
+	var payload runPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w: %w", err, asynq.SkipRetry)
+	}
+
+	si, ok, err := s.dueStandingInstruction(&payload)
+	if err != nil || !ok {
+		return err
+	}
+
+	ctx = context.WithValue(ctx, "user_id", si.UserId)
+	logData := &commonSrv.LogEntry{
+		Action:     constants.STANDING_INSTRUCTION,
+		RequestURI: constants.StandingInstructionExecuteType,
+		Message:    "ExecuteHandler log",
+		UserID:     si.UserId,
+	}
+
+	execution, err := models.StartStandingInstructionExecution(s.db, si, payload.RunAt)
+	if err != nil {
+		return err
+	}
+
+	// the run was made by an earlier attempt
+	if execution.Status != models.ExecutionStatusPending {
+		return s.advance(si, payload.RunAt)
+	}
+
+	paymentRequest, err := s.paymentRequest(si)
+	if err != nil {
+		return s.failExecution(ctx, si, execution, err)
+	}
+
+	// every attempt of a run sends the payment with the same identifier, so an
+	// attempt after one the bank may have taken is not paid twice
+	if execution.TxnIdentifier.Valid {
+		paymentRequest.TxnIdentifier = execution.TxnIdentifier.String
+	} else {
+		if paymentRequest.TxnIdentifier, err = security.GenerateRandomUUID(15); err != nil {
+			return err
+		}
+		if err := models.UpdateStandingInstructionExecution(s.db, execution.Id, models.ExecutionStatusPending, paymentRequest.TxnIdentifier, ""); err != nil {
+			logData.Message = "ExecuteHandler: Error updating execution"
+			s.LoggerService.LogError(logData)
+			return err
+		}
+	}
+
+	response, txnIdentifier, err := s.beneficiary.SubmitPayment(ctx, logData, si.UserId, si.DeviceId, paymentRequest)
+	if err == nil && response == nil {
+		err = errors.New(constants.RetryErrorMessage)
+	}
+	if err == nil && response.ErrorCode != "" && response.ErrorCode != "0" && response.ErrorCode != "00" {
+		// the bank turned the payment down, trying it again with the same
+		// identifier cannot succeed
+		if err := models.ReleasePaymentReservation(s.db, txnIdentifier); err != nil {
+			logData.Message = "ExecuteHandler: " + err.Error()
+			s.LoggerService.LogError(logData)
+		}
+		return s.failExecution(ctx, si, execution, errors.New(response.ErrorMessage))
+	}
+	if err != nil {
+		retryCount, _ := asynq.GetRetryCount(ctx)
+		maxRetry, _ := asynq.GetMaxRetry(ctx)
+		if retryCount < maxRetry {
+			if err := models.UpdateStandingInstructionExecution(s.db, execution.Id, models.ExecutionStatusPending, "", err.Error()); err != nil {
+				logData.Message = "ExecuteHandler: Error updating execution"
+				s.LoggerService.LogError(logData)
+			}
+			return err
+		}
+
+		return s.failExecution(ctx, si, execution, err)
+	}
+
+	if err := models.UpdateStandingInstructionExecution(s.db, execution.Id, models.ExecutionStatusAwaitingOtp, txnIdentifier, ""); err != nil {
+		logData.Message = "ExecuteHandler: Error updating execution"
+		s.LoggerService.LogError(logData)
+		return err
+	}
+
+	s.notify(si.UserId, constants.STANDING_INSTRUCTION_OTP,
+		"Confirm your scheduled transfer",
+		fmt.Sprintf("Your scheduled transfer of ₹%s to %s is ready. Enter the otp sent by the bank in the Paydoh app to complete it.", si.Amount, si.BenfName),
+	)
+
+	logData.Message = "ExecuteHandler: payment submitted"
+	s.LoggerService.LogInfo(logData)
+
+	return s.advance(si, payload.RunAt)
+}
+
+// ReminderHandler sends the pre-debit reminder of a run
+func (s *Store) ReminderHandler(ctx context.Context, t *asynq.Task) error {
+	var payload runPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w: %w", err, asynq.SkipRetry)
+	}
+
+	si, ok, err := s.dueStandingInstruction(&payload)
+	if err != nil || !ok {
+		return err
+	}
+
+	runAt := payload.RunAt.In(config.GetStandingInstructionConfig().Location)
+	s.notify(si.UserId, constants.STANDING_INSTRUCTION_REMINDER,
+		"Upcoming scheduled transfer",
+		fmt.Sprintf("₹%s will be transferred to %s on %s. You can skip or pause it in the Paydoh app before then.", si.Amount, si.BenfName, runAt.Format("02 Jan 2006, 03:04 PM")),
+	)
+
+	return nil
+}
+
+// ScheduleStandingInstructions schedules the next run of every active
+// standing instruction again, runs already scheduled are kept. It recovers
+// runs whose task was lost.
+func (s *Store) ScheduleStandingInstructions(ctx context.Context) {
+	logData := &commonSrv.LogEntry{
+		Action:  constants.STANDING_INSTRUCTION,
+		Message: "ScheduleStandingInstructions log",
+	}
+
+	standingInstructions, err := models.ListScheduledStandingInstructions(s.db)
+	if err != nil {
+		logData.Message = "ScheduleStandingInstructions: Error listing standing instructions:- " + err.Error()
+		s.LoggerService.LogError(logData)
+		return
+	}
+
+	for i := range standingInstructions {
+		if err := s.schedule(&standingInstructions[i]); err != nil {
+			logData.Message = fmt.Sprintf("ScheduleStandingInstructions: Error scheduling %s:- %s", standingInstructions[i].Id, err.Error())
+			s.LoggerService.LogError(logData)
+		}
+	}
+}
+
+func (s *Store) getStandingInstruction(authValues *models.AuthValues, id string) (*models.StandingInstruction, error) {
+	si, err := models.GetStandingInstruction(s.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if si.UserId != authValues.UserId {
+		return nil, constants.ErrNoDataFound
+	}
+
+	return si, nil
+}
+
+// dueStandingInstruction returns the standing instruction of a task when the
+// run of the task is still its next run
+func (s *Store) dueStandingInstruction(payload *runPayload) (*models.StandingInstruction, bool, error) {
+	si, err := models.GetStandingInstruction(s.db, payload.StandingInstructionId)
+	if err != nil {
+		if errors.Is(err, constants.ErrNoDataFound) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	if si.Status != models.StandingInstructionStatusActive || si.NextRunAt == nil || !si.NextRunAt.Equal(payload.RunAt) {
+		return nil, false, nil
+	}
+
+	return si, true, nil
+}
+
+// paymentRequest is the payment of a run, the beneficiary must still be
+// verified, SubmitPayment counts the amount against the limits
+func (s *Store) paymentRequest(si *models.StandingInstruction) (*requests.PaymentRequest, error) {
+	accountNumber, err := security.Decrypt(si.BenfAccountEncrypted, []byte(constants.AesPassPhrase))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt account number: %w", err)
+	}
+
+	ben, err := models.GetBeneficiaryByID(s.db, si.BenfId, accountNumber, si.BenfMobNo, si.BenfIfsc)
+	if err != nil && !errors.Is(err, constants.ErrNoDataFound) {
+		return nil, err
+	}
+	if ben == nil || ben.UserId != si.UserId {
+		return nil, errors.New(constants.StandingInstructionBeneficiaryErrorMessage)
+	}
+
+	return &requests.PaymentRequest{
+		PaymentMode:   si.PaymentMode,
+		BenfId:        si.BenfId,
+		BenfName:      si.BenfName,
+		BenfIfsc:      si.BenfIfsc,
+		BenfAcctNo:    accountNumber,
+		BenfAcctType:  si.BenfAcctType,
+		BenfMobNo:     si.BenfMobNo,
+		Amount:        si.Amount,
+		Remarks:       si.Remarks,
+		ResendOtp:     "N",
+		RetryFlag:     "N",
+		QuickTransfer: "N",
+	}, nil
+}
+
+// failExecution records a run as failed, tells the user and moves on to the next run
+func (s *Store) failExecution(ctx context.Context, si *models.StandingInstruction, execution *models.StandingInstructionExecution, cause error) error {
+	if err := models.UpdateStandingInstructionExecution(s.db, execution.Id, models.ExecutionStatusFailed, "", cause.Error()); err != nil {
+		return err
+	}
+
+	s.notify(si.UserId, constants.STANDING_INSTRUCTION_FAILED,
+		"Scheduled transfer failed",
+		fmt.Sprintf("Your scheduled transfer of ₹%s to %s could not be made: %s", si.Amount, si.BenfName, cause.Error()),
+	)
+
+	return s.advance(si, execution.RunAt)
+}
+
+// advance moves a standing instruction on to the run after runAt, or
+// completes it when none is left. A run made late does not make up the runs
+// it delayed, the next run is after now.
+func (s *Store) advance(si *models.StandingInstruction, runAt time.Time) error {
+	after := runAt
+	if now := time.Now(); now.After(after) {
+		after = now
+	}
+
+	nextRunAt, ok := si.NextRunAfter(after)
+	if !ok {
+		return s.updateSchedule(si, models.StandingInstructionStatusCompleted, nil)
+	}
+
+	if err := s.updateSchedule(si, models.StandingInstructionStatusActive, &nextRunAt); err != nil {
+		return err
+	}
+
+	return s.schedule(si)
+}
+
+func (s *Store) updateSchedule(si *models.StandingInstruction, status string, nextRunAt *time.Time) error {
+	if err := models.UpdateStandingInstructionSchedule(s.db, si.Id, status, nextRunAt); err != nil {
+		return err
+	}
+
+	si.Status = status
+	si.NextRunAt = nextRunAt
+
+	return nil
+}
+
+// schedule enqueues the execute task of the next run and its reminder, the
+// task ids keep a run from being scheduled twice
+func (s *Store) schedule(si *models.StandingInstruction) error {
+	if si.NextRunAt == nil {
+		return nil
+	}
+
+	payload, err := json.Marshal(&runPayload{
+		StandingInstructionId: si.Id,
+		RunAt:                 *si.NextRunAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	standingInstructionConfig := config.GetStandingInstructionConfig()
+	runId := fmt.Sprintf("%s:%d", si.Id, si.NextRunAt.Unix())
+
+	_, err = s.scheduler.Enqueue(
+		asynq.NewTask(constants.StandingInstructionExecuteType, payload),
+		asynq.ProcessAt(*si.NextRunAt),
+		asynq.TaskID(constants.StandingInstructionExecuteType+":"+runId),
+		asynq.MaxRetry(standingInstructionConfig.MaxRetries),
+	)
+	if err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		return fmt.Errorf("failed to schedule standing instruction: %w", err)
+	}
+
+	remindAt := si.NextRunAt.Add(-standingInstructionConfig.ReminderLead)
+	if standingInstructionConfig.ReminderLead <= 0 || !remindAt.After(time.Now()) {
+		return nil
+	}
+
+	_, err = s.scheduler.Enqueue(
+		asynq.NewTask(constants.StandingInstructionReminderType, payload),
+		asynq.ProcessAt(remindAt),
+		asynq.TaskID(constants.StandingInstructionReminderType+":"+runId),
+		asynq.MaxRetry(1),
+	)
+	if err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		return fmt.Errorf("failed to schedule standing instruction reminder: %w", err)
+	}
+
+	return nil
+}
+
+// notify sends the user a push notification and an sms
+func (s *Store) notify(userId, purpose, title, body string) {
+	logData := &commonSrv.LogEntry{
+		Action:  constants.STANDING_INSTRUCTION,
+		Message: "notify log",
+		UserID:  userId,
+	}
+
+	deviceData, err := models.FindOneDeviceByUserID(s.db, userId)
+	if err == nil && deviceData.DeviceToken.Valid && deviceData.DeviceToken.String != "" {
+		request := &requests.NotificationRequest{}
+		if err := request.CreateNotificationPayload(
+			[]requests.NotificationUser{{
+				UserId:      userId,
+				DeviceToken: deviceData.DeviceToken.String,
+				OS:          deviceData.OS.String,
+				PackageId:   deviceData.PackageId,
+			}},
+			title,
+			body,
+			purpose,
+			purpose,
+		); err == nil {
+			if _, err := s.notificationService.SendNotification(request); err != nil {
+				logData.Message = "notify: Error sending notification:- " + err.Error()
+				s.LoggerService.LogError(logData)
+			}
+		}
+	}
+
+	user, err := models.GetUserDataByUserId(s.db, userId)
+	if err != nil {
+		logData.Message = "notify: Error getting user data"
+		s.LoggerService.LogError(logData)
+		return
+	}
+
+	if err := s.notificationService.SendSms(&requests.SendSmsRequest{
+		MobileNumber: user.MobileNumber,
+		Message:      body,
+		Purpose:      purpose,
+	}); err != nil {
+		logData.Message = "notify: Error sending sms:- " + err.Error()
+		s.LoggerService.LogError(logData)
+	}
+}
+
+func (s *Store) audit(ctx context.Context, authValues *models.AuthValues, action, body string) {
+	if err := s.auditLogService.Save(ctx, &services.AuditLog{
+		UserID:         authValues.UserId,
+		SourceIP:       authValues.DeviceIp,
+		DeviceID:       authValues.DeviceId,
+		RequestBody:    body,
+		ResponseStatus: http.StatusOK,
+		Action:         action,
+	}); err != nil {
+		s.LoggerService.LogError(&commonSrv.LogEntry{
+			Action:  constants.STANDING_INSTRUCTION,
+			Message: "standing instruction audit log:- " + err.Error(),
+			UserID:  authValues.UserId,
+		})
+	}
+}
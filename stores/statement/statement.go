@@ -36,9 +36,9 @@ type Store struct {
 	db                 *sql.DB
 	m                  *database.Document
 	TransactionHistory *transaction.TransactionStore
-	service            *services.BankApiService
+	service            services.BankProvider
 	LoggerService      *commonSrv.LoggerService
-	bankService        *services.BankApiService
+	bankService        services.BankProvider
 }
 
 type Transaction struct {
@@ -75,11 +75,11 @@ func NewStore(
 	memory *database.InMemory,
 	txnHistory *transaction.TransactionStore,
 ) *Store {
-	bankService := services.NewBankApiService(log, memory)
+	bankService := services.NewBankProvider(log, memory)
 	return &Store{
 		db:                 db,
 		m:                  m,
-		service:            services.NewBankApiService(log, memory),
+		service:            bankService,
 		LoggerService:      log,
 		TransactionHistory: txnHistory,
 		bankService:        bankService,
@@ -22,9 +22,11 @@ import (
 	"bankapi/stores/authentication"
 	"bankapi/stores/authorization"
 	"bankapi/stores/beneficiary"
+	bulkpayout "bankapi/stores/bulk_payout"
 	"bankapi/stores/consent"
 	debitcard "bankapi/stores/debit_card"
 	"bankapi/stores/demographic"
+	"bankapi/stores/device"
 	"bankapi/stores/faq"
 	"bankapi/stores/kyc"
 	"bankapi/stores/kyc_audit_data"
@@ -33,37 +35,44 @@ import (
 	"bankapi/stores/onboarding"
 	"bankapi/stores/open"
 	"bankapi/stores/payment_beneficiary"
+	standinginstruction "bankapi/stores/standing_instruction"
 	"bankapi/stores/statement"
 	staticParameters "bankapi/stores/static_parameters"
 	"bankapi/stores/transaction"
+	transferlimit "bankapi/stores/transfer_limit"
 	"bankapi/stores/upi"
 	"bankapi/stores/user_details"
 	"bankapi/stores/webhook"
+	"bankapi/utils"
 )
 
 type Stores struct {
-	Authorization      *authorization.AuthorizationStore
-	Authentication     *authentication.AuthenticationStore
-	Webhook            *webhook.WebhookStore
-	Open               *open.OpenStore
-	Onboarding         *onboarding.Store
-	Kyc                *kyc.Store
-	Demographic        *demographic.Store
-	Nominee            *nominee.Store
-	Beneficiary        *beneficiary.Store
-	Consent            *consent.Store
-	Upi                *upi.Store
-	Statement          *statement.Store
-	KycAuditStore      *kyc_audit_data.KycAuditStore
-	Payment            *payment_beneficiary.PaymentCallbackStore
-	UserDetail         *user_details.Store
-	DebitCard          *debitcard.Store
-	TransactionHistory *transaction.TransactionStore
-	StaticParams       *staticParameters.Store
-	AddressStore       *address.AddressStore
-	EmailStore         *mail.Store
-	AuditLogService    bankServices.AuditLogService
-	FaqStore           faq.FAQStore
+	Authorization       *authorization.AuthorizationStore
+	Authentication      *authentication.AuthenticationStore
+	Webhook             *webhook.WebhookStore
+	Open                *open.OpenStore
+	Onboarding          *onboarding.Store
+	Kyc                 *kyc.Store
+	Demographic         *demographic.Store
+	Nominee             *nominee.Store
+	Beneficiary         *beneficiary.Store
+	Consent             *consent.Store
+	Upi                 *upi.Store
+	Statement           *statement.Store
+	KycAuditStore       *kyc_audit_data.KycAuditStore
+	Payment             *payment_beneficiary.PaymentCallbackStore
+	UserDetail          *user_details.Store
+	DebitCard           *debitcard.Store
+	TransactionHistory  *transaction.TransactionStore
+	StaticParams        *staticParameters.Store
+	AddressStore        *address.AddressStore
+	EmailStore          *mail.Store
+	AuditLogService     bankServices.AuditLogService
+	FaqStore            faq.FAQStore
+	Device              *device.Store
+	StandingInstruction *standinginstruction.Store
+	BulkPayout          *bulkpayout.Store
+	TransferLimit       *transferlimit.Store
 }
 
 func NewStores(
@@ -84,6 +93,19 @@ func NewStores(
 		fmt.Println(err)
 	}
 
+	bankServices.SetBankProviderSelector(func(ctx context.Context) string {
+		userId := utils.GetUserIDFromContext(ctx)
+		if userId == "" {
+			return ""
+		}
+
+		provider, err := models.GetUserBankProvider(db, userId)
+		if err != nil {
+			return ""
+		}
+		return provider
+	})
+
 	newTaskEnqueuer := task.NewAsynqTaskEnqueuer(constants.RedisURL, constants.RedisUserName, constants.RedisPassword, constants.RedisDB)
 	auditLogSrv := bankServices.NewAuditLogService(logSrv, newTaskEnqueuer)
 	authorizationStore := authorization.NewAuthorizationStore(logSrv, db, ctx, mongo, memory, newTaskEnqueuer, auditLogSrv)
@@ -107,29 +129,37 @@ func NewStores(
 	updateAddress := address.NewStore(logSrv, db, memory, auditLogSrv)
 	mail := mail.NewStore(logSrv, db, mongo, memory)
 	faqStore := faq.NewFAQStore(logSrv)
+	deviceStore := device.NewStore(logSrv, db, memory, openStore, auditLogSrv)
+	standingInstructionStore := standinginstruction.NewStore(logSrv, db, bn, auditLogSrv)
+	bulkPayoutStore := bulkpayout.NewStore(logSrv, db, bn, newTaskEnqueuer, auditLogSrv)
+	transferLimitStore := transferlimit.NewStore(logSrv, db, auditLogSrv)
 	return &Stores{
-		Authorization:      authorizationStore,
-		Authentication:     authenticationStore,
-		Webhook:            webhookStore,
-		Open:               openStore,
-		Onboarding:         o,
-		Kyc:                k,
-		Demographic:        d,
-		Nominee:            n,
-		Beneficiary:        bn,
-		Consent:            cn,
-		Upi:                u,
-		Statement:          st,
-		KycAuditStore:      kas,
-		Payment:            paymentCallback,
-		UserDetail:         userDetails,
-		DebitCard:          debitcard,
-		TransactionHistory: txnHistory,
-		StaticParams:       staticParameters,
-		AddressStore:       updateAddress,
-		EmailStore:         mail,
-		AuditLogService:    auditLogSrv,
-		FaqStore:           faqStore,
+		Authorization:       authorizationStore,
+		Authentication:      authenticationStore,
+		Webhook:             webhookStore,
+		Open:                openStore,
+		Onboarding:          o,
+		Kyc:                 k,
+		Demographic:         d,
+		Nominee:             n,
+		Beneficiary:         bn,
+		Consent:             cn,
+		Upi:                 u,
+		Statement:           st,
+		KycAuditStore:       kas,
+		Payment:             paymentCallback,
+		UserDetail:          userDetails,
+		DebitCard:           debitcard,
+		TransactionHistory:  txnHistory,
+		StaticParams:        staticParameters,
+		AddressStore:        updateAddress,
+		EmailStore:          mail,
+		AuditLogService:     auditLogSrv,
+		FaqStore:            faqStore,
+		Device:              deviceStore,
+		StandingInstruction: standingInstructionStore,
+		BulkPayout:          bulkPayoutStore,
+		TransferLimit:       transferLimitStore,
 	}
 }
 
@@ -157,6 +187,11 @@ func (s *Stores) BindStore(awsIstance *amazon.Aws) gin.HandlerFunc {
 		ctx.Set("update_address_store", s.AddressStore)
 		ctx.Set("email_store", s.EmailStore)
 		ctx.Set("faq_store", s.FaqStore)
+		ctx.Set("device_store", s.Device)
+		ctx.Set("standing_instruction_store", s.StandingInstruction)
+		ctx.Set("bulk_payout_store", s.BulkPayout)
+		ctx.Set("transfer_limit_store", s.TransferLimit)
+		ctx.Set("audit_log_service", s.AuditLogService)
 		ctx.Next()
 	}
 }
@@ -280,28 +315,52 @@ func GetStores(ctx *gin.Context) (*Stores, error) {
 		return nil, fmt.Errorf("FAQ store not bound")
 	}
 
+	deviceStore, ok := ctx.MustGet("device_store").(*device.Store)
+	if !ok {
+		return nil, fmt.Errorf("device store not bound")
+	}
+
+	standingInstructionStore, ok := ctx.MustGet("standing_instruction_store").(*standinginstruction.Store)
+	if !ok {
+		return nil, fmt.Errorf("standing instruction store not bound")
+	}
+
+	bulkPayoutStore, ok := ctx.MustGet("bulk_payout_store").(*bulkpayout.Store)
+	if !ok {
+		return nil, fmt.Errorf("bulk payout store not bound")
+	}
+
+	transferLimitStore, ok := ctx.MustGet("transfer_limit_store").(*transferlimit.Store)
+	if !ok {
+		return nil, fmt.Errorf("transfer limit store not bound")
+	}
+
 	return &Stores{
-		Authorization:      ao,
-		Authentication:     au,
-		Webhook:            wb,
-		Open:               os,
-		Onboarding:         onboardingStore,
-		Kyc:                kycStore,
-		Demographic:        demographicStore,
-		Nominee:            nomineeStore,
-		Beneficiary:        beneficiaryStore,
-		Consent:            consentStore,
-		Upi:                upiStore,
-		Statement:          statementStore,
-		KycAuditStore:      kycAuditStore,
-		Payment:            paymentStore,
-		UserDetail:         userDetails,
-		DebitCard:          debitcard,
-		TransactionHistory: txnHistory,
-		StaticParams:       staticParameter,
-		AddressStore:       updateAddress,
-		EmailStore:         email,
-		FaqStore:           faqStore,
+		Authorization:       ao,
+		Authentication:      au,
+		Webhook:             wb,
+		Open:                os,
+		Onboarding:          onboardingStore,
+		Kyc:                 kycStore,
+		Demographic:         demographicStore,
+		Nominee:             nomineeStore,
+		Beneficiary:         beneficiaryStore,
+		Consent:             consentStore,
+		Upi:                 upiStore,
+		Statement:           statementStore,
+		KycAuditStore:       kycAuditStore,
+		Payment:             paymentStore,
+		UserDetail:          userDetails,
+		DebitCard:           debitcard,
+		TransactionHistory:  txnHistory,
+		StaticParams:        staticParameter,
+		AddressStore:        updateAddress,
+		EmailStore:          email,
+		FaqStore:            faqStore,
+		Device:              deviceStore,
+		StandingInstruction: standingInstructionStore,
+		BulkPayout:          bulkPayoutStore,
+		TransferLimit:       transferLimitStore,
 	}, nil
 }
 
@@ -365,12 +424,15 @@ func GetAuthValue(ctx *gin.Context) (*models.AuthValues, error) {
 	}
 
 	return &models.AuthValues{
-		UserId:    userId,
-		Key:       key,
-		DeviceIp:  deviceIp,
-		OS:        os,
-		OSVersion: osVersion,
-		LatLong:   latLong,
+		UserId:     userId,
+		Key:        key,
+		SigningKey: ctx.GetString("sign_key"),
+		DeviceIp:   deviceIp,
+		OS:         os,
+		OSVersion:  osVersion,
+		LatLong:    latLong,
+		SessionId:  ctx.GetString("session_id"),
+		DeviceId:   ctx.GetString("device_id"),
 	}, nil
 }
 
@@ -388,6 +450,19 @@ func (s *Stores) StartPeriodicTask() {
 
 		for range ticker.C {
 			store.Open.SyncIfscApi(context.Background())
+			store.StandingInstruction.ScheduleStandingInstructions(context.Background())
+		}
+	}(s)
+
+	// payments waiting for an otp that was never entered give back their
+	// transfer limit usage soon after the otp expires
+	go func(store *Stores) {
+		ticker := time.NewTicker(5 * time.Minute)
+
+		defer ticker.Stop()
+
+		for range ticker.C {
+			store.Beneficiary.ExpirePaymentReservations(context.Background())
 		}
 	}(s)
 }
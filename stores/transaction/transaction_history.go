@@ -3,7 +3,6 @@ package transaction
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -28,7 +27,7 @@ type TransactionStore struct {
 	db            *sql.DB
 	m             *database.Document
 	memory        *database.InMemory
-	service       *services.BankApiService
+	service       services.BankProvider
 	LoggerService *commonSrv.LoggerService
 }
 
@@ -38,7 +37,7 @@ func NewTransactionStore(log *commonSrv.LoggerService, db *sql.DB, m *database.D
 		m:             m,
 		memory:        memory,
 		LoggerService: log,
-		service:       services.NewBankApiService(log, memory),
+		service:       services.NewBankProvider(log, memory),
 	}
 }
 
@@ -77,28 +76,26 @@ func (ts *TransactionStore) GetTransactionData(ctx context.Context, req requests
 
     response, opErr = ts.service.FetchTransactionHistory(ctx, reqData)
     if opErr != nil {
-        bankErr := ts.service.HandleBankSpecificError(opErr, func(errorCode string) (string, bool) {
-            return constants.GetCasaTxnErrorMessage(errorCode)
-        })
+        bankErr := ts.service.HandleBankSpecificError(ctx, opErr)
 
         if bankErr != nil {
             logData.Message = fmt.Sprintf("Bank error encountered (ErrorCode: %s)", bankErr.ErrorCode)
             ts.LoggerService.LogError(logData)
 
-            if msg, retryable := constants.GetCasaTxnErrorRetryMessage(bankErr.ErrorCode); retryable {
+            if bankErr.Retryable {
                 err := utils.RetryFunc(func() error {
                     reqData.TxnIdentifier = generateTxnIdentifier()
                     response, opErr = ts.service.FetchTransactionHistory(ctx, reqData)
                     return opErr
-                }, 2)
+                }, bankErr.MaxRetries)
 
                 if err != nil {
                     logData.Message = "GetTransactionData failed after retries"
                     ts.LoggerService.LogError(logData)
-                    return nil, errors.New(msg)
+                    return nil, bankErr
                 }
             } else {
-                return nil, errors.New(bankErr.ErrorMessage)
+                return nil, bankErr
             }
         } else {
             return nil, opErr
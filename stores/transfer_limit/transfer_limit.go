@@ -0,0 +1,149 @@
+package transferlimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	commonSrv "bitbucket.org/paydoh/paydoh-commons/services"
+
+	"bankapi/config"
+	"bankapi/constants"
+	"bankapi/models"
+	"bankapi/requests"
+	"bankapi/services"
+	"bankapi/utils"
+)
+
+// Store shows users their transfer limits and what they can still send, and
+// lets them lower the limits of their tier. The limits are checked by the
+// payment stores with models.ReserveTransferLimit.
+type Store struct {
+	db              *sql.DB
+	LoggerService   *commonSrv.LoggerService
+	auditLogService services.AuditLogService
+}
+
+func NewStore(log *commonSrv.LoggerService, db *sql.DB, auditLogService services.AuditLogService) *Store {
+	return &Store{
+		db:              db,
+		LoggerService:   log,
+		auditLogService: auditLogService,
+	}
+}
+
+// GetTransferLimits returns the limits of every payment mode with the usage
+// and remaining headroom of today and this month
+func (s *Store) GetTransferLimits(ctx context.Context, authValues *models.AuthValues) ([]models.TransferLimitHeadroom, error) {
+	logData := &commonSrv.LogEntry{
+		Action:     constants.TRANSFER_LIMIT,
+		RequestURI: "/api/transfer-limits",
+		Message:    "GetTransferLimits log",
+		UserID:     authValues.UserId,
+		RequestID:  utils.GetRequestIDFromContext(ctx),
+	}
+
+	headroom, err := models.GetTransferLimitHeadroom(s.db, authValues.UserId)
+	if err != nil {
+		logData.Message = "GetTransferLimits: Error getting transfer limits:- " + err.Error()
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	return headroom, nil
+}
+
+// SetTransferLimit sets the limits of a payment mode, each must be at most
+// the limit of the tier of the user
+func (s *Store) SetTransferLimit(ctx context.Context, authValues *models.AuthValues, r *requests.TransferLimitRequest) ([]models.TransferLimitHeadroom, error) {
+	logData := &commonSrv.LogEntry{
+		Action:     constants.TRANSFER_LIMIT,
+		RequestURI: "/api/transfer-limits",
+		Message:    "SetTransferLimit log",
+		UserID:     authValues.UserId,
+		RequestID:  utils.GetRequestIDFromContext(ctx),
+	}
+
+	tier, err := models.GetTransferLimitTier(s.db, authValues.UserId)
+	if err != nil {
+		logData.Message = "SetTransferLimit: Error getting transfer limit tier:- " + err.Error()
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	tierLimit, err := config.GetTransferLimit(tier, r.PaymentMode)
+	if err != nil {
+		logData.Message = "SetTransferLimit: Error getting tier limit:- " + err.Error()
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	userLimit := &models.UserTransferLimit{
+		UserId:         authValues.UserId,
+		PaymentMode:    r.PaymentMode,
+		PerTransaction: r.PerTransaction,
+		Daily:          r.Daily,
+		Monthly:        r.Monthly,
+	}
+
+	if err := validateTransferLimit(tierLimit, userLimit); err != nil {
+		return nil, err
+	}
+
+	if err := models.SaveUserTransferLimit(s.db, userLimit); err != nil {
+		logData.Message = "SetTransferLimit: Error saving transfer limit:- " + err.Error()
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	s.audit(ctx, authValues, fmt.Sprintf("mode=%s per_transaction=%d daily=%d monthly=%d", r.PaymentMode, r.PerTransaction, r.Daily, r.Monthly))
+
+	logData.Message = "SetTransferLimit: transfer limit updated"
+	s.LoggerService.LogInfo(logData)
+
+	return s.GetTransferLimits(ctx, authValues)
+}
+
+// validateTransferLimit checks a user limit is within the limit of the tier
+// and the limits it gives are in order
+func validateTransferLimit(tierLimit *config.TransferLimit, userLimit *models.UserTransferLimit) error {
+	for _, limit := range []struct {
+		name  string
+		value int64
+		max   int64
+	}{
+		{name: "per transaction " + userLimit.PaymentMode, value: userLimit.PerTransaction, max: tierLimit.PerTransaction},
+		{name: "daily " + userLimit.PaymentMode, value: userLimit.Daily, max: tierLimit.Daily},
+		{name: "monthly " + userLimit.PaymentMode, value: userLimit.Monthly, max: tierLimit.Monthly},
+	} {
+		if limit.value > limit.max {
+			return fmt.Errorf(constants.TransferLimitAboveTierErrorMessage, limit.name, limit.max)
+		}
+	}
+
+	effective := models.EffectiveTransferLimit(tierLimit, userLimit)
+	if effective.PerTransaction > effective.Daily || effective.Daily > effective.Monthly {
+		return errors.New(constants.TransferLimitOrderErrorMessage)
+	}
+
+	return nil
+}
+
+func (s *Store) audit(ctx context.Context, authValues *models.AuthValues, body string) {
+	if err := s.auditLogService.Save(ctx, &services.AuditLog{
+		UserID:         authValues.UserId,
+		SourceIP:       authValues.DeviceIp,
+		DeviceID:       authValues.DeviceId,
+		RequestBody:    body,
+		ResponseStatus: http.StatusOK,
+		Action:         constants.TRANSFER_LIMIT_UPDATED,
+	}); err != nil {
+		s.LoggerService.LogError(&commonSrv.LogEntry{
+			Action:  constants.TRANSFER_LIMIT,
+			Message: "transfer limit audit log:- " + err.Error(),
+			UserID:  authValues.UserId,
+		})
+	}
+}
@@ -20,6 +20,7 @@ import (
 	commonSrv "bitbucket.org/paydoh/paydoh-commons/services"
 	"bitbucket.org/paydoh/paydoh-commons/types"
 
+	"bankapi/config"
 	"bankapi/constants"
 	"bankapi/models"
 	"bankapi/requests"
@@ -50,13 +51,13 @@ type Store struct {
 	db            *sql.DB
 	m             *database.Document
 	redis         *database.InMemory
-	bankService   *services.BankApiService
+	bankService   services.BankProvider
 	LoggerService *commonSrv.LoggerService
 	auditLogSrv   services.AuditLogService
 }
 
 func NewStore(log *commonSrv.LoggerService, db *sql.DB, m *database.Document, memory *database.InMemory, redis *database.InMemory, auditLogSrv services.AuditLogService) *Store {
-	bankService := services.NewBankApiService(log, memory)
+	bankService := services.NewBankProvider(log, memory)
 	return &Store{
 		db:            db,
 		m:             m,
@@ -132,7 +133,7 @@ func (s *Store) SimBindingAndSmsVerification(ctx context.Context, authValues *mo
 			return nil, errors.New("device ID not found in context")
 		}
 	} else {
-		decryptedDeviceId, err = security.Decrypt(existingUserData.DeviceId, []byte(authValues.Key))
+		decryptedDeviceId, err = security.Decrypt(existingUserData.DeviceId, []byte(authValues.SigningKey))
 		if err != nil {
 			logData.Message = "SimBindingAndSmsVerification: Error decrypting device ID"
 			s.LoggerService.LogError(logData)
@@ -337,7 +338,7 @@ func (s *Store) CreateUpiID(ctx context.Context, authValues *models.AuthValues,
 		return nil, err
 	}
 
-	decryptedDeviceId, err := security.Decrypt(existingUserData.DeviceId, []byte(authValues.Key))
+	decryptedDeviceId, err := security.Decrypt(existingUserData.DeviceId, []byte(authValues.SigningKey))
 	if err != nil {
 		logData.Message = "CreateUpiID: Error decrypting device id"
 		s.LoggerService.LogError(logData)
@@ -537,14 +538,12 @@ func (s *Store) CreateUpiID(ctx context.Context, authValues *models.AuthValues,
 			// delete client id data
 			models.DeleteClientIDByUserId(authValues.UserId)
 
-			bankErr := s.bankService.HandleBankSpecificError(demographicErr, func(errorCode string) (string, bool) {
-				return constants.GetDemographicErrorMessage(errorCode)
-			})
+			bankErr := s.bankService.HandleBankSpecificError(ctx, demographicErr)
 
 			if bankErr != nil {
 				logData.Message = fmt.Sprintf("GetDemographicData: Bank error encountered (ErrorCode: %s)", bankErr.ErrorCode)
 				s.LoggerService.LogError(logData)
-				return nil, errors.New(bankErr.ErrorMessage)
+				return nil, bankErr
 			}
 			return nil, demographicErr
 		}
@@ -933,7 +932,7 @@ func (s *Store) RemapExistingUpiID(ctx context.Context, authValues *models.AuthV
 
 	currentDeviceID := utils.GetDeviceIdFromContext(ctx)
 	if currentDeviceID != "" {
-		encryptedDeviceID, err := security.Encrypt([]byte(currentDeviceID), []byte(authValues.Key))
+		encryptedDeviceID, err := security.Encrypt([]byte(currentDeviceID), []byte(authValues.SigningKey))
 		if err != nil {
 			logData.Message = "SetUPIPin: Error encrypting device ID"
 			s.LoggerService.LogError(logData)
@@ -1430,7 +1429,7 @@ func (s *Store) ValidateVPA(ctx context.Context, authValues *models.AuthValues,
 	return encrypted, nil
 }
 
-func (s *Store) ProcessPaymentWithVPA(ctx context.Context, authValues *models.AuthValues, request *requests.PayMoneyWithVpaRequest) (interface{}, error) {
+func (s *Store) ProcessPaymentWithVPA(ctx context.Context, authValues *models.AuthValues, request *requests.PayMoneyWithVpaRequest) (_ interface{}, err error) {
 
 	startTime := time.Now()
 
@@ -1447,6 +1446,40 @@ func (s *Store) ProcessPaymentWithVPA(ctx context.Context, authValues *models.Au
 		Message:       "payment with VPA log",
 	}
 
+	deviceCoolingReservation, err := models.ReserveDeviceCooling(s.db, authValues.UserId, authValues.DeviceId, request.PayerAmount)
+	if err != nil {
+		logData.Message = "ProcessPaymentWithVPA: " + err.Error()
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
+	// the usage is released when the payment does not reach the bank or the bank rejects it
+	var reservation *models.TransferLimitReservation
+	paid := false
+	defer func() {
+		if err == nil || paid {
+			return
+		}
+		if err := models.ReleaseDeviceCooling(s.db, deviceCoolingReservation); err != nil {
+			logData.Message = "ProcessPaymentWithVPA: " + err.Error()
+			s.LoggerService.LogError(logData)
+		}
+		if reservation == nil {
+			return
+		}
+		if err := models.ReleaseTransferLimit(s.db, reservation); err != nil {
+			logData.Message = "ProcessPaymentWithVPA: " + err.Error()
+			s.LoggerService.LogError(logData)
+		}
+	}()
+
+	reservation, err = models.ReserveTransferLimit(s.db, authValues.UserId, config.TransferModeUpi, request.PayerAmount)
+	if err != nil {
+		logData.Message = "ProcessPaymentWithVPA: " + err.Error()
+		s.LoggerService.LogError(logData)
+		return nil, err
+	}
+
 	existingUserData, err := models.FindOneDeviceByUserID(s.db, authValues.UserId)
 	if err != nil {
 		logData.Message = "ProcessPaymentWithVPA: Error getting user data"
@@ -1548,6 +1581,7 @@ func (s *Store) ProcessPaymentWithVPA(ctx context.Context, authValues *models.Au
 		s.LoggerService.LogError(logData)
 		return nil, errors.New(userPaymentwithVpaResponse.Response.ResponseMessage)
 	}
+	paid = true
 
 	// Extract necessary data from response
 	resp := userPaymentwithVpaResponse.Response.Response.Ns2RespPay
@@ -1978,7 +2012,7 @@ func (s *Store) GetUpiTokenXml(ctx context.Context, authValues *models.AuthValue
 
 	if request.Challenge != "" {
 		key := fmt.Sprintf("upi_token:%s:%s", authValues.UserId, deviceData.DeviceId)
-		if _, err = s.bankService.Memory.Get(key); err != nil {
+		if _, err = s.redis.Get(key); err != nil {
 			logData.Message = "GetUpiTokenXml: Error retrieving UPI token from memory"
 			s.LoggerService.LogError(logData)
 
@@ -2075,7 +2109,7 @@ func (s *Store) GetUpiToken(ctx context.Context, authValues *models.AuthValues,
 	}
 
 	key := fmt.Sprintf("upi_token:%s:%s", authValues.UserId, existingUserData.DeviceId)
-	tokenData, _ := s.bankService.Memory.Get(key)
+	tokenData, _ := s.redis.Get(key)
 	if len(tokenData) > 0 || tokenData == "null" {
 		// encrypted, err := security.Encrypt([]byte(tokenData), []byte(authValues.Key))
 		if err != nil {
@@ -2086,7 +2120,7 @@ func (s *Store) GetUpiToken(ctx context.Context, authValues *models.AuthValues,
 		return tokenData, nil
 	}
 
-	decryptedDeviceId, err := security.Decrypt(existingUserData.DeviceId, []byte(authValues.Key))
+	decryptedDeviceId, err := security.Decrypt(existingUserData.DeviceId, []byte(authValues.SigningKey))
 	if err != nil {
 		logData.Message = "CreateUpiID: Error decrypting device id"
 		s.LoggerService.LogError(logData)
@@ -2248,7 +2282,7 @@ func (s *Store) SaveUPIToken(ctx context.Context, userId, deviceIp, tokenData st
 	// key := fmt.Sprintf("upi_token:%s:%s", userId, deviceIp)
 	// tokenTTL := time.Hour * 24 * 30
 
-	// err := s.bankService.Memory.Set(key, tokenData, tokenTTL)
+	// err := s.redis.Set(key, tokenData, tokenTTL)
 	// if err != nil {
 	// 	logData.Message = fmt.Sprintf("Error saving UPI token: %v", err)
 	// 	s.LoggerService.LogError(logData)
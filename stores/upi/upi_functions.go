@@ -117,7 +117,7 @@ func (s *Store) GenerateCryptoInfo(ctx context.Context, authValues *models.AuthV
 		return "", err
 	}
 
-	decryptedDeviceId, err := security.Decrypt(existingUserData.DeviceId, []byte(authValues.Key))
+	decryptedDeviceId, err := security.Decrypt(existingUserData.DeviceId, []byte(authValues.SigningKey))
 	if err != nil {
 		return "", err
 	}
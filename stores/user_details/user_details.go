@@ -10,7 +10,6 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
-	"errors"
 	"time"
 
 	"bitbucket.org/paydoh/paydoh-commons/database"
@@ -24,20 +23,17 @@ type UserDetails interface {
 type Store struct {
 	db            *sql.DB
 	m             *database.Document
-	bankService   *services.BankApiService
-	debitService  *services.DebitcardApiService
+	bankService   services.BankProvider
 	LoggerService *commonSrv.LoggerService
 }
 
 func NewStore(log *commonSrv.LoggerService, db *sql.DB, m *database.Document, memory *database.InMemory, redis *database.InMemory) *Store {
-	bankService := services.NewBankApiService(log, memory)
-	debitcardService := services.NewDebitcardApiService(log, memory)
+	bankService := services.NewBankProvider(log, memory)
 
 	return &Store{
 		db:            db,
 		m:             m,
 		bankService:   bankService,
-		debitService:  debitcardService,
 		LoggerService: log,
 	}
 }
@@ -66,10 +62,8 @@ func (s *Store) GetUserDetailData(ctx context.Context, authValues *models.AuthVa
 
 	accountDetail, err := s.bankService.GetAccountDetail(ctx, request)
 	if err != nil {
-		if bankErr := s.bankService.HandleBankSpecificError(err, func(errorCode string) (string, bool) {
-			return constants.GetAccountDetailFetchErrorMessage(errorCode)
-		}); bankErr != nil {
-			return nil, errors.New(bankErr.ErrorMessage)
+		if bankErr := s.bankService.HandleBankSpecificError(ctx, err); bankErr != nil {
+			return nil, bankErr
 		}
 
 		logData.Message = "GetUserDetail: Error while getting Account Detail from BankAPI"
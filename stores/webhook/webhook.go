@@ -6,12 +6,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"time"
 
 	"bitbucket.org/paydoh/paydoh-commons/database"
 	commonSrv "bitbucket.org/paydoh/paydoh-commons/services"
 	"github.com/redis/go-redis/v9"
 
+	"bankapi/config"
 	"bankapi/constants"
 	"bankapi/models"
 	"bankapi/requests"
@@ -28,13 +30,13 @@ type WebhookStore struct {
 	db                  *sql.DB
 	memory              *database.InMemory
 	notificationService *services.NotificationService
-	bankService         *services.BankApiService
+	bankService         services.BankProvider
 	LoggerService       *commonSrv.LoggerService
 }
 
 func NewWebhookStore(log *commonSrv.LoggerService, db *sql.DB, memory *database.InMemory) *WebhookStore {
 	notificationService := services.NewNotificationService()
-	bankService := services.NewBankApiService(log, memory)
+	bankService := services.NewBankProvider(log, memory)
 	return &WebhookStore{
 		db:                  db,
 		memory:              memory,
@@ -508,19 +510,37 @@ func (s *WebhookStore) ProvideRewardsPoint(ctx context.Context, request *request
 		return err
 	}
 
+	// the partner tran id is the idempotency key of the rewards transfer
+	idempotencyKey, claimed, err := s.claimRewardsTransfer(request, logData.RequestURI)
+	if err != nil {
+		logData.Message = "ProvideRewardsPoint: Error claiming idempotency key"
+		s.LoggerService.LogError(logData)
+		return err
+	}
+
+	if !claimed {
+		logData.Message = "ProvideRewardsPoint: Repeated rewards transfer " + request.TranId
+		s.LoggerService.LogInfo(logData)
+		return idempotencyKey.err
+	}
+
 	response, err := s.bankService.RewardsTransfer(ctx, outgoingRewardsRequest)
 	if err != nil {
 		logData.Message = "Error during rewards transfer"
 		s.LoggerService.LogError(logData)
+		s.completeRewardsTransfer(idempotencyKey.key, err)
 		return err
 	}
 
 	if response.ErrorCode != "00" && response.ErrorCode != "0" {
 		logData.Message = fmt.Sprintf("Rewards transfer failed: %s", response.ErrorMessage)
 		s.LoggerService.LogError(logData)
+		s.completeRewardsTransfer(idempotencyKey.key, errors.New(response.ErrorMessage))
 		return errors.New(response.ErrorMessage)
 	}
 
+	s.completeRewardsTransfer(idempotencyKey.key, nil)
+
 	byteudd, err := json.Marshal(response)
 	if err != nil {
 		logData.Message = "ProvideRewardsPoint: Error marshaling to JSON"
@@ -536,3 +556,63 @@ func (s *WebhookStore) ProvideRewardsPoint(ctx context.Context, request *request
 
 	return nil
 }
+
+// rewardsTransfer is the idempotency state of a rewards transfer, err is the
+// stored outcome when the transfer was already made
+type rewardsTransfer struct {
+	key *models.IdempotencyKey
+	err error
+}
+
+func (s *WebhookStore) claimRewardsTransfer(request *requests.RewardsRequest, route string) (*rewardsTransfer, bool, error) {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return nil, false, err
+	}
+
+	requestHash := utils.RequestHash(route, payload)
+
+	idempotencyKey, claimed, err := models.ClaimIdempotencyKey(s.db, &models.IdempotencyKey{
+		UserId:      request.BenfUserId,
+		Key:         request.TranId,
+		Route:       route,
+		RequestHash: requestHash,
+	}, config.GetIdempotencyKeyTTL(), config.GetIdempotencyKeyLease())
+	if err != nil {
+		return nil, false, err
+	}
+
+	if claimed {
+		return &rewardsTransfer{key: idempotencyKey}, true, nil
+	}
+
+	if idempotencyKey.Route != route || idempotencyKey.RequestHash != requestHash {
+		return nil, false, errors.New(constants.IdempotencyKeyReusedErrorMessage)
+	}
+
+	if idempotencyKey.Status != models.IdempotencyCompleted {
+		return nil, false, errors.New(constants.IdempotencyKeyInProgressErrorMessage)
+	}
+
+	transfer := &rewardsTransfer{key: idempotencyKey}
+	if idempotencyKey.ResponseStatus.Int64 != http.StatusOK {
+		transfer.err = errors.New(idempotencyKey.ResponseBody.String)
+	}
+
+	return transfer, false, nil
+}
+
+func (s *WebhookStore) completeRewardsTransfer(key *models.IdempotencyKey, transferErr error) {
+	status, body := http.StatusOK, ""
+	if transferErr != nil {
+		status, body = http.StatusBadRequest, transferErr.Error()
+	}
+
+	if err := models.CompleteIdempotencyKey(s.db, key, status, body); err != nil {
+		s.LoggerService.LogError(&commonSrv.LogEntry{
+			Action:     constants.WEBHOOK,
+			RequestURI: "/api/webhook/rewards-point",
+			Message:    "completeRewardsTransfer: Error storing rewards transfer outcome " + err.Error(),
+		})
+	}
+}
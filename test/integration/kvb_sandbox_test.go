@@ -0,0 +1,477 @@
+package integration
+
+import (
+	"bankapi/constants"
+	"bankapi/metrics"
+	"bankapi/requests"
+	"bankapi/services"
+	"bankapi/test/kvbsandbox"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"bitbucket.org/paydoh/paydoh-commons/database"
+	commonSrv "bitbucket.org/paydoh/paydoh-commons/services"
+	"bitbucket.org/paydoh/paydoh-commons/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sandboxKey = "0123456789abcdef0123456789abcdef"
+
+func setupSandboxBankService(t *testing.T) (*services.BankApiService, *kvbsandbox.Server, func()) {
+	settings.LoadEnvFile()
+
+	if len(constants.BankEncryptionKey) != 32 {
+		constants.BankEncryptionKey = sandboxKey
+	}
+	if len(constants.CardControlEncryptionKey) != 32 {
+		constants.CardControlEncryptionKey = sandboxKey
+	}
+
+	sandbox := kvbsandbox.NewServer(kvbsandbox.Config{
+		BankKey: constants.BankEncryptionKey,
+		CardKey: constants.CardControlEncryptionKey,
+	})
+
+	kvbURL := constants.KvbUatURL
+	constants.KvbUatURL = sandbox.URL
+
+	loggerSrv, err := commonSrv.NewLoggerService(&commonSrv.Config{
+		AwsRegion:          constants.AWSRegion,
+		AwsAccessKeyID:     constants.AWSAccessKeyID,
+		AwsSecretAccessKey: constants.AWSSecretAccessKey,
+		EnableS3Upload:     false,
+	})
+	require.NoError(t, err)
+
+	memory, err := database.NewInMemory(context.Background(), database.InMemoryConfig{
+		NetworkType: constants.RedisNetworkType,
+		Address:     constants.RedisURL,
+		Username:    constants.RedisUserName,
+		Password:    constants.RedisPassword,
+	})
+	require.NoError(t, err)
+
+	// tokens cached by earlier runs belong to another server
+	memory.Delete("kvb_auth_token")
+
+	bankSrv := services.NewBankApiService(loggerSrv, memory)
+
+	cleanup := func() {
+		memory.Delete("kvb_auth_token")
+		memory.Close()
+		sandbox.Close()
+		constants.KvbUatURL = kvbURL
+	}
+
+	return bankSrv, sandbox, cleanup
+}
+
+func sandboxPaymentRequest() *requests.OutgoingPaymentRequest {
+	return &requests.OutgoingPaymentRequest{
+		ApplicantId:   "APP0001",
+		TxnIdentifier: "TXN0001",
+		PaymentMode:   "IMPS",
+		AccountNo:     "1234567890123456",
+		BenfId:        "BEN01",
+		BenfName:      "Test Beneficiary",
+		BenfIfsc:      "KVBL0001234",
+		BenfAcctNo:    "0000000000000001234567890",
+		BenfAcctType:  "SA",
+		Amount:        "100",
+		TxnRemarks:    "sandbox",
+		ResendOtp:     "N",
+		QuickTransfer: "N",
+	}
+}
+
+func TestSandboxPaymentScenarios(t *testing.T) {
+	bankSrv, sandbox, cleanup := setupSandboxBankService(t)
+	defer cleanup()
+
+	tests := []struct {
+		name          string
+		scenarios     []kvbsandbox.Scenario
+		wantErrorCode string
+		wantCalls     int
+	}{
+		{
+			name:      "success",
+			wantCalls: 1,
+		},
+		{
+			name:          "non retriable MW error",
+			scenarios:     []kvbsandbox.Scenario{kvbsandbox.BankError(constants.PaymentCallbackErrorCodeMW0014, "Insufficient balance")},
+			wantErrorCode: constants.PaymentCallbackErrorCodeMW0014,
+			wantCalls:     1,
+		},
+		{
+			name:      "retriable MW error then success",
+			scenarios: []kvbsandbox.Scenario{kvbsandbox.BankError(constants.RetryErrorMW9999, "Technical error")},
+			wantCalls: 2,
+		},
+		{
+			name:      "token expired then success",
+			scenarios: []kvbsandbox.Scenario{kvbsandbox.TokenExpired()},
+			wantCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sandbox.Reset()
+			bankSrv.Memory.Delete("kvb_auth_token")
+			sandbox.Script("/fintech/payment", tt.scenarios...)
+
+			resp, err := bankSrv.PaymentSubmission(context.Background(), sandboxPaymentRequest())
+
+			assert.Len(t, sandbox.Calls("/fintech/payment"), tt.wantCalls)
+
+			if tt.wantErrorCode != "" {
+				require.Error(t, err)
+				bankErr := bankSrv.ExtractBankError(err)
+				require.NotNil(t, bankErr)
+				assert.Equal(t, tt.wantErrorCode, bankErr.ErrorCode)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "TXN0001", resp.TxnIdentifier)
+			assert.NotEmpty(t, resp.TxnRefNo)
+		})
+	}
+}
+
+func TestSandboxTokenRefreshOnExpiry(t *testing.T) {
+	bankSrv, sandbox, cleanup := setupSandboxBankService(t)
+	defer cleanup()
+
+	_, err := bankSrv.PaymentSubmission(context.Background(), sandboxPaymentRequest())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sandbox.TokensIssued())
+
+	sandbox.ExpireTokens()
+
+	_, err = bankSrv.PaymentSubmission(context.Background(), sandboxPaymentRequest())
+	require.NoError(t, err)
+	assert.Equal(t, 2, sandbox.TokensIssued())
+
+	calls := sandbox.Calls("/fintech/payment")
+	require.Len(t, calls, 3)
+	assert.Equal(t, http.StatusUnauthorized, calls[1].Status)
+}
+
+func TestSandboxBackOfficeTimeout(t *testing.T) {
+	bankSrv, sandbox, cleanup := setupSandboxBankService(t)
+	defer cleanup()
+
+	sandbox.Script("/fintech/payment", kvbsandbox.Status(http.StatusGatewayTimeout))
+
+	_, err := bankSrv.PaymentSubmission(context.Background(), sandboxPaymentRequest())
+	require.Error(t, err)
+
+	sandbox.Script("/fintech/payment", kvbsandbox.Timeout(200*time.Millisecond))
+
+	resp, err := bankSrv.PaymentSubmission(context.Background(), sandboxPaymentRequest())
+	require.NoError(t, err)
+	assert.NotEmpty(t, resp.TxnRefNo)
+}
+
+func TestSandboxConcurrentTokenRequestsShareOneToken(t *testing.T) {
+	bankSrv, sandbox, cleanup := setupSandboxBankService(t)
+	defer cleanup()
+
+	var wg sync.WaitGroup
+	tokens := make([]string, 20)
+
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			token, err := bankSrv.GenerateToken(context.Background())
+			if assert.NoError(t, err) {
+				tokens[i] = token.AccessToken
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, sandbox.TokensIssued())
+	for _, token := range tokens {
+		assert.Equal(t, tokens[0], token)
+	}
+}
+
+func TestSandboxRecordAndReplay(t *testing.T) {
+	fixtureDir := t.TempDir()
+	t.Setenv("KVB_FIXTURE_DIR", fixtureDir)
+	t.Setenv("KVB_TRAFFIC_MODE", "record")
+
+	bankSrv, sandbox, cleanup := setupSandboxBankService(t)
+	defer cleanup()
+
+	recorded, err := bankSrv.PaymentSubmission(context.Background(), sandboxPaymentRequest())
+	require.NoError(t, err)
+
+	fixture, err := os.ReadFile(filepath.Join(fixtureDir, "fintech_payment", "0001.json"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(fixture), "1234567890123456", "account number should be redacted")
+	assert.NotContains(t, string(fixture), "Test Beneficiary", "name should be redacted")
+
+	// replay must not reach the sandbox
+	sandbox.Close()
+	t.Setenv("KVB_TRAFFIC_MODE", "replay")
+	bankSrv.Memory.Delete("kvb_auth_token")
+
+	replayed, err := services.NewBankApiService(bankSrv.LoggerService, bankSrv.Memory).PaymentSubmission(context.Background(), sandboxPaymentRequest())
+	require.NoError(t, err)
+	assert.Equal(t, recorded.TxnRefNo, replayed.TxnRefNo)
+	assert.Equal(t, recorded.TxnIdentifier, replayed.TxnIdentifier)
+}
+
+func TestSandboxBankMetrics(t *testing.T) {
+	bankSrv, sandbox, cleanup := setupSandboxBankService(t)
+	defer cleanup()
+
+	sandbox.Script("/fintech/payment",
+		kvbsandbox.BankError(constants.RetryErrorMW9999, "Technical error"),
+		kvbsandbox.BankError(constants.PaymentCallbackErrorCodeMW0014, "Insufficient balance"),
+	)
+
+	_, err := bankSrv.PaymentSubmission(context.Background(), sandboxPaymentRequest())
+	require.Error(t, err)
+
+	recorder := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := recorder.Body.String()
+
+	assert.Contains(t, body, `bankapi_kvb_requests_total{endpoint="/fintech/payment",error_code="MW0014",service="bank",status="200"}`)
+	assert.Contains(t, body, `bankapi_kvb_retries_total{endpoint="/fintech/payment",reason="bank_error",service="bank"}`)
+	assert.Contains(t, body, `bankapi_kvb_token_refreshes_total{result="success",session="oauth_token"}`)
+}
+
+func sandboxAccountCreateRequest() *requests.OutgoingCreateBankAccountRequest {
+	return &requests.OutgoingCreateBankAccountRequest{
+		ApplicationID: "APPL0001",
+		UserID:        "USER0001",
+		CustomerDtls: []requests.CustomerDtls{{
+			ApplicantId: "APP0001",
+			Nationality: "IN",
+			NamePrefix:  "Mr",
+		}},
+	}
+}
+
+func TestSandboxOnboardingAccountCreate(t *testing.T) {
+	bankSrv, sandbox, cleanup := setupSandboxBankService(t)
+	defer cleanup()
+
+	tests := []struct {
+		name          string
+		scenarios     []kvbsandbox.Scenario
+		wantErrorCode string
+		wantCalls     int
+	}{
+		{
+			name:      "success",
+			wantCalls: 1,
+		},
+		{
+			name:          "invalid applicant details",
+			scenarios:     []kvbsandbox.Scenario{kvbsandbox.BankError("80004", "Invalid input")},
+			wantErrorCode: "80004",
+			wantCalls:     1,
+		},
+		{
+			name:      "retriable MW error then success",
+			scenarios: []kvbsandbox.Scenario{kvbsandbox.BankError(constants.RetryErrorMW9999, "Technical error")},
+			wantCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sandbox.Reset()
+			bankSrv.Memory.Delete("kvb_auth_token")
+			sandbox.Script("/fintech/account-create", tt.scenarios...)
+
+			resp, err := bankSrv.CreatebankAccount(context.Background(), sandboxAccountCreateRequest())
+
+			calls := sandbox.Calls("/fintech/account-create")
+			assert.Len(t, calls, tt.wantCalls)
+
+			if tt.wantErrorCode != "" {
+				require.Error(t, err)
+				bankErr := bankSrv.ExtractBankError(err)
+				require.NotNil(t, bankErr)
+				assert.Equal(t, tt.wantErrorCode, bankErr.ErrorCode)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "APPL0001", resp.ApplicationID)
+			assert.Contains(t, string(calls[len(calls)-1].Body), "APP0001", "applicant should reach the bank")
+		})
+	}
+}
+
+func sandboxReqPayRequest() *requests.OutgoingReqPayApiRequest {
+	request := &requests.OutgoingReqPayApiRequest{}
+	request.ReqPay.MobileNo = "9999999999"
+	request.ReqPay.TransactionType = "PAY"
+	request.ReqPay.Payeeaddr = "payee@kvb"
+	request.ReqPay.TxnID = "UPITXN0001"
+	return request
+}
+
+func TestSandboxUpiPay(t *testing.T) {
+	bankSrv, sandbox, cleanup := setupSandboxBankService(t)
+	defer cleanup()
+
+	tests := []struct {
+		name         string
+		scenarios    []kvbsandbox.Scenario
+		wantUpiError string
+		wantCalls    int
+	}{
+		{
+			name:      "success",
+			wantCalls: 1,
+		},
+		{
+			name:         "declined by the payer psp",
+			scenarios:    []kvbsandbox.Scenario{kvbsandbox.BankError("U30", "Debit has failed")},
+			wantUpiError: "U30",
+			wantCalls:    1,
+		},
+		{
+			name:      "retriable MW error then success",
+			scenarios: []kvbsandbox.Scenario{kvbsandbox.BankError(constants.RetryErrorMW9999, "Technical error")},
+			wantCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sandbox.Reset()
+			bankSrv.Memory.Delete("kvb_auth_token")
+			sandbox.Script("/fintech/upi/req-pay", tt.scenarios...)
+
+			resp, err := bankSrv.PayWithVpa(context.Background(), sandboxReqPayRequest())
+
+			calls := sandbox.Calls("/fintech/upi/req-pay")
+			assert.Len(t, calls, tt.wantCalls)
+
+			if tt.wantUpiError != "" {
+				require.Error(t, err)
+				bankErr := bankSrv.ExtractBankError(err)
+				require.NotNil(t, bankErr)
+				assert.Equal(t, tt.wantUpiError, bankErr.UpiError.ResponseCode)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "0", resp.Response.ResponseCode)
+			assert.Contains(t, string(calls[len(calls)-1].Body), "UPITXN0001", "transaction id should reach the bank")
+		})
+	}
+}
+
+func TestSandboxUpiBalanceEnquiry(t *testing.T) {
+	bankSrv, sandbox, cleanup := setupSandboxBankService(t)
+	defer cleanup()
+
+	request := &requests.OutgoingReqBalEnqApiRequest{}
+	request.ReqBalEnq.TxnID = "UPIBAL0001"
+	request.ReqBalEnq.Payeraddr = "payer@kvb"
+
+	resp, err := bankSrv.RequestCheckAccountBalance(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, "0", resp.Response.ResponseCode)
+
+	sandbox.Script("/fintech/upi/req-bal-enq", kvbsandbox.BankError("ZM", "Invalid MPIN"))
+
+	_, err = bankSrv.RequestCheckAccountBalance(context.Background(), request)
+	require.Error(t, err)
+	bankErr := bankSrv.ExtractBankError(err)
+	require.NotNil(t, bankErr)
+	assert.Equal(t, "ZM", bankErr.UpiError.ResponseCode)
+	assert.Len(t, sandbox.Calls("/fintech/upi/req-bal-enq"), 2)
+}
+
+func TestSandboxUpiCollect(t *testing.T) {
+	bankSrv, sandbox, cleanup := setupSandboxBankService(t)
+	defer cleanup()
+
+	pending := kvbsandbox.SuccessWith(map[string]interface{}{
+		"Response": map[string]interface{}{
+			"ResponseCode":    "0",
+			"ResponseMessage": "Success",
+			"Response": []interface{}{map[string]interface{}{
+				"TRANSACTIONID": "COLLECT0001",
+				"PAYEEADDR":     "merchant@kvb",
+				"PAYERAMOUNT":   "250.00",
+			}},
+		},
+	})
+	sandbox.Script("/fintech/upi/collect-details", pending)
+
+	countRequest := &requests.OutgoingUpiMoneyCollectCountApiRequest{}
+	countRequest.CollectCount.MobileNo = "9999999999"
+
+	count, err := bankSrv.CollectCount(context.Background(), countRequest)
+	require.NoError(t, err)
+	assert.Equal(t, "0", count.Response.ResponseCode)
+
+	detailsRequest := &requests.OutgoingUpiMoneyCollectDetailsApiRequest{}
+	detailsRequest.CollectDetails.MobileNo = "9999999999"
+
+	details, err := bankSrv.CollectDetails(context.Background(), detailsRequest)
+	require.NoError(t, err)
+	require.Len(t, details.Response.Response, 1)
+	assert.Equal(t, "COLLECT0001", details.Response.Response[0].TransactionID)
+
+	approvalRequest := &requests.OutgoingUpiMoneyCollectApprovalApiRequest{}
+	approvalRequest.Approval.MobileNo = "9999999999"
+	approvalRequest.Approval.Payeraddr = "payer@kvb"
+	approvalRequest.Approval.Type = "APPROVE"
+
+	approval, err := bankSrv.CollectApproval(context.Background(), approvalRequest)
+	require.NoError(t, err)
+	assert.Equal(t, "0", approval.Response.ResponseCode)
+
+	assert.Len(t, sandbox.Calls("/fintech/upi/collect-count"), 1)
+	assert.Len(t, sandbox.Calls("/fintech/upi/collect-details"), 1)
+	assert.Len(t, sandbox.Calls("/fintech/upi/approval"), 1)
+}
+
+func TestSandboxOpenBreakerStopsRetries(t *testing.T) {
+	t.Setenv("KVB_BREAKER_FINTECH_UPI_REQ_VAL_ADD_FAILURE_THRESHOLD", "2")
+
+	bankSrv, sandbox, cleanup := setupSandboxBankService(t)
+	defer cleanup()
+
+	sandbox.Script("/fintech/upi/req-val-add",
+		kvbsandbox.BankError(constants.RetryErrorMW9999, "Technical error"),
+		kvbsandbox.BankError(constants.RetryErrorMW9999, "Technical error"),
+		kvbsandbox.BankError(constants.RetryErrorMW9999, "Technical error"),
+	)
+
+	request := &requests.OutgoingReqValAddApiRequest{}
+	_, err := bankSrv.ValidateVpaAddress(context.Background(), request)
+	require.Error(t, err)
+
+	// the breaker opened after the second attempt, the third never reached the bank
+	assert.Len(t, sandbox.Calls("/fintech/upi/req-val-add"), 2)
+	for _, status := range services.BankBreakerStatus() {
+		if status.Endpoint == "/fintech/upi/req-val-add" {
+			assert.Equal(t, services.BreakerOpen, status.State)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package kvbsandbox
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type responder func(req map[string]interface{}) interface{}
+
+// defaultResponses holds the success bodies of the endpoints the flows under
+// test depend on, anything else answers with a bare success code.
+var defaultResponses = map[string]responder{
+	"/fintech/account-create": func(req map[string]interface{}) interface{} {
+		return map[string]interface{}{
+			"ErrorCode":     "0",
+			"ErrorMessage":  "Success",
+			"ApplicationId": field(req, "ApplicationId"),
+			"serviceName":   "AccountCreation",
+		}
+	},
+	"/fintech/beneficiary-registration": func(req map[string]interface{}) interface{} {
+		return map[string]interface{}{
+			"ApplicantId":     field(req, "ApplicantId"),
+			"TxnIdentifier":   txnIdentifier(req),
+			"AccountNo":       field(req, "AccountNo"),
+			"BenfId":          randomID(6),
+			"Txn_Status":      "Success",
+			"ErrorCode":       "0",
+			"ErrorMessage":    "Success",
+			"ActivatedDtTime": time.Now().Format("2006-01-02 15:04:05"),
+		}
+	},
+	"/fintech/payment": func(req map[string]interface{}) interface{} {
+		return map[string]interface{}{
+			"ApplicantId":   field(req, "ApplicantId"),
+			"AccountNo":     field(req, "AccountNo"),
+			"Txn_Status":    "Success",
+			"TxnStatus":     "Success",
+			"ErrorCode":     "0",
+			"ErrorMessage":  "Success",
+			"TxnIdentifier": txnIdentifier(req),
+			"TxnRefNo":      randomID(6),
+		}
+	},
+	"/fintech/payment-direct": func(req map[string]interface{}) interface{} {
+		return map[string]interface{}{
+			"ErrorCode":    "0",
+			"ErrorMessage": "Success",
+			"ApplicantId":  field(req, "ApplicantId"),
+			"serviceName":  "FundTransfer",
+		}
+	},
+	"/fintech/account-statement": func(req map[string]interface{}) interface{} {
+		return map[string]interface{}{
+			"ErrorCode":      "0",
+			"ErrorMessage":   "Success",
+			"AccountDetails": map[string]interface{}{},
+		}
+	},
+	"/fintech/upi/collect-count": func(req map[string]interface{}) interface{} {
+		return upiSuccess([]interface{}{})
+	},
+	"/fintech/upi/collect-details": func(req map[string]interface{}) interface{} {
+		return upiSuccess([]interface{}{})
+	},
+	"/fintech/card/key/fetch": func(req map[string]interface{}) interface{} {
+		return map[string]interface{}{
+			"status code": "200",
+			"publickey":   randomID(32),
+			"resid":       randomID(8),
+			"shashkey":    randomID(16),
+			"rc":          "00",
+			"desc":        "Success",
+		}
+	},
+	"/fintech/card/login": func(req map[string]interface{}) interface{} {
+		return map[string]interface{}{
+			"cid":  randomID(8),
+			"rc":   "00",
+			"desc": "Success",
+		}
+	},
+	"/fintech/card/list": func(req map[string]interface{}) interface{} {
+		return []interface{}{cardSuccess()}
+	},
+	"/fintech/card/view": func(req map[string]interface{}) interface{} {
+		return []interface{}{cardSuccess()}
+	},
+	"/fintech/v2/card/debit/generate": func(req map[string]interface{}) interface{} {
+		return map[string]interface{}{
+			"ErrorCode":     "0",
+			"ErrorMessage":  "Success",
+			"ApplicantId":   field(req, "ApplicantId"),
+			"TxnIdentifier": txnIdentifier(req),
+			"proxyNumber":   randomID(6),
+		}
+	},
+}
+
+func (s *Server) responseBody(path string, body []byte, scenario Scenario) interface{} {
+	if scenario.Body != nil {
+		return scenario.Body
+	}
+
+	if scenario.ErrorCode != "" {
+		return errorBody(path, scenario.ErrorCode, scenario.ErrorMessage)
+	}
+
+	req := make(map[string]interface{})
+	json.Unmarshal(body, &req)
+
+	if respond, ok := defaultResponses[path]; ok {
+		return respond(req)
+	}
+
+	switch {
+	case isCardControl(path):
+		return cardSuccess()
+	case isUpi(path):
+		return upiSuccess(nil)
+	default:
+		return map[string]interface{}{
+			"ApplicantId":  field(req, "ApplicantId"),
+			"ErrorCode":    "0",
+			"ErrorMessage": "Success",
+		}
+	}
+}
+
+// errorBody shapes an error the way each family of endpoints reports it.
+func errorBody(path, code, message string) interface{} {
+	switch {
+	case isCardControl(path):
+		return map[string]interface{}{
+			"rc":   code,
+			"desc": message,
+		}
+	case isUpi(path):
+		return map[string]interface{}{
+			"Response": map[string]interface{}{
+				"ResponseCode":    code,
+				"ResponseMessage": message,
+			},
+		}
+	default:
+		return map[string]interface{}{
+			"ErrorCode":    code,
+			"ErrorMessage": message,
+		}
+	}
+}
+
+func upiSuccess(response interface{}) map[string]interface{} {
+	body := map[string]interface{}{
+		"ResponseCode":    "0",
+		"ResponseMessage": "Success",
+		"MessageID":       randomID(8),
+	}
+	if response != nil {
+		body["Response"] = response
+	}
+	return map[string]interface{}{"Response": body}
+}
+
+func cardSuccess() map[string]interface{} {
+	return map[string]interface{}{
+		"rc":   "00",
+		"desc": "Success",
+	}
+}
+
+func field(req map[string]interface{}, key string) interface{} {
+	if v, ok := req[key]; ok {
+		return v
+	}
+	return ""
+}
+
+func txnIdentifier(req map[string]interface{}) interface{} {
+	if v, ok := req["TxnIdentifier"]; ok && v != "" {
+		return v
+	}
+	return randomID(8)
+}
@@ -0,0 +1,56 @@
+package kvbsandbox
+
+import (
+	"net/http"
+	"time"
+)
+
+// Scenario describes how the sandbox answers a single call to an endpoint.
+// Scenarios queued with Server.Script are consumed in order, once the queue
+// for a path is empty the endpoint falls back to its default success response.
+type Scenario struct {
+	// Status is the HTTP status code returned, zero means 200.
+	Status int
+	// Delay holds the response back to simulate a back office timeout.
+	Delay time.Duration
+	// ErrorCode and ErrorMessage are written into the decrypted body using the
+	// error shape of the endpoint (MW codes, UPI ResponseCode or card rc/desc).
+	ErrorCode    string
+	ErrorMessage string
+	// Body replaces the default decrypted response body when set.
+	Body interface{}
+	// Raw skips the encryption envelope and writes Body as plain JSON, the
+	// same way KVB answers some failures without "encryptRes".
+	Raw bool
+}
+
+// Success answers with the default success response of the endpoint.
+func Success() Scenario {
+	return Scenario{}
+}
+
+// SuccessWith answers with the given decrypted body.
+func SuccessWith(body interface{}) Scenario {
+	return Scenario{Body: body}
+}
+
+// BankError answers with a middleware error code such as MW9999 or MW0014.
+func BankError(code, message string) Scenario {
+	return Scenario{ErrorCode: code, ErrorMessage: message}
+}
+
+// TokenExpired invalidates every issued access token and answers 401, the
+// next call made with a refreshed token is served normally.
+func TokenExpired() Scenario {
+	return Scenario{Status: http.StatusUnauthorized}
+}
+
+// Timeout delays the response by d before answering with the default body.
+func Timeout(d time.Duration) Scenario {
+	return Scenario{Delay: d}
+}
+
+// Status answers with the given HTTP status and an empty body.
+func Status(code int) Scenario {
+	return Scenario{Status: code}
+}
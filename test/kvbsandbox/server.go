@@ -0,0 +1,341 @@
+// Package kvbsandbox is an in-process stand-in for the KVB fintech gateway.
+// It speaks the same encrypted envelope as the bank ({"encryptReq": ...} in,
+// {"encryptRes": ...} out), issues oauth tokens and serves scripted outcomes
+// per endpoint so integration tests can run bank flows offline.
+package kvbsandbox
+
+import (
+	"bankapi/requests"
+	"bankapi/responses"
+	"bankapi/utils"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+const tokenPath = "/nbfc/v1/oauth/cc/accesstoken"
+
+type Config struct {
+	// BankKey encrypts the fintech envelope, CardKey the card control one.
+	BankKey string
+	CardKey string
+	// Username and Password are checked against the token request basic
+	// auth when set.
+	Username string
+	Password string
+	// TokenExpiresIn is returned as expires_in, defaults to 3600 seconds.
+	TokenExpiresIn int
+}
+
+// Call is a request received by the sandbox, with the envelope removed.
+type Call struct {
+	Path          string
+	Body          []byte
+	TransactionID string
+	Status        int
+}
+
+type Server struct {
+	*httptest.Server
+
+	config Config
+
+	mu      sync.Mutex
+	tokens  map[string]bool
+	scripts map[string][]Scenario
+	calls   []Call
+}
+
+// NewServer starts a sandbox listening on a random local port. Point
+// constants.KvbUatURL at Server.URL before the bank services are created.
+func NewServer(config Config) *Server {
+	if config.TokenExpiresIn == 0 {
+		config.TokenExpiresIn = 3600
+	}
+
+	s := &Server{
+		config:  config,
+		tokens:  make(map[string]bool),
+		scripts: make(map[string][]Scenario),
+	}
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// Script queues scenarios for path, each call consumes the next one.
+func (s *Server) Script(path string, scenarios ...Scenario) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts[path] = append(s.scripts[path], scenarios...)
+}
+
+// Reset drops queued scenarios, recorded calls and issued tokens.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = make(map[string]bool)
+	s.scripts = make(map[string][]Scenario)
+	s.calls = nil
+}
+
+// ExpireTokens invalidates every issued token, the next fintech call
+// answers 401 until a new token is requested.
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = make(map[string]bool)
+}
+
+// Calls returns the recorded calls for path, or all calls when path is empty.
+func (s *Server) Calls(path string) []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]Call, 0, len(s.calls))
+	for _, c := range s.calls {
+		if path == "" || c.Path == path {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// TokensIssued returns how many access tokens were handed out.
+func (s *Server) TokensIssued() int {
+	return len(s.Calls(tokenPath))
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == tokenPath {
+		s.serveToken(w, r)
+		return
+	}
+
+	scenario := s.next(r.URL.Path)
+
+	if scenario.Delay > 0 {
+		select {
+		case <-time.After(scenario.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if scenario.Status == http.StatusUnauthorized {
+		s.ExpireTokens()
+		s.record(Call{Path: r.URL.Path, Status: http.StatusUnauthorized})
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if !s.authorized(r) {
+		s.record(Call{Path: r.URL.Path, Status: http.StatusUnauthorized})
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	raw, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	card := isCardControl(r.URL.Path)
+
+	body, transactionID, err := s.openEnvelope(raw, card)
+	if err != nil {
+		s.record(Call{Path: r.URL.Path, Status: http.StatusBadRequest})
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status := http.StatusOK
+	if scenario.Status != 0 {
+		status = scenario.Status
+	}
+	s.record(Call{Path: r.URL.Path, Body: body, TransactionID: transactionID, Status: status})
+
+	if status != http.StatusOK {
+		w.WriteHeader(status)
+		return
+	}
+
+	payload, err := json.Marshal(s.responseBody(r.URL.Path, body, scenario))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if !scenario.Raw {
+		payload, err = s.sealEnvelope(payload, card)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(payload)
+}
+
+func (s *Server) serveToken(w http.ResponseWriter, r *http.Request) {
+	if s.config.Username != "" {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != s.config.Username || password != s.config.Password {
+			s.record(Call{Path: tokenPath, Status: http.StatusUnauthorized})
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+
+	scenario := s.next(tokenPath)
+	if scenario.Status != 0 && scenario.Status != http.StatusOK {
+		s.record(Call{Path: tokenPath, Status: scenario.Status})
+		w.WriteHeader(scenario.Status)
+		return
+	}
+
+	token := randomID(16)
+
+	s.mu.Lock()
+	s.tokens[token] = true
+	s.mu.Unlock()
+	s.record(Call{Path: tokenPath, Status: http.StatusOK})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responses.TokenResponse{
+		AccessToken: token,
+		ExpiresIn:   fmt.Sprint(s.config.TokenExpiresIn),
+		TokenType:   "Bearer",
+	})
+}
+
+func (s *Server) next(path string) Scenario {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queue := s.scripts[path]
+	if len(queue) == 0 {
+		return Success()
+	}
+	s.scripts[path] = queue[1:]
+	return queue[0]
+}
+
+func (s *Server) record(call Call) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, call)
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokens[token]
+}
+
+// openEnvelope returns the decrypted request body. Card control requests are
+// wrapped twice: the outer layer carries {"input": ..., "TransactionId": ...}.
+func (s *Server) openEnvelope(raw []byte, card bool) ([]byte, string, error) {
+	if len(raw) == 0 {
+		return raw, "", nil
+	}
+
+	encReq := requests.EncryptedReq{}
+	if err := json.Unmarshal(raw, &encReq); err != nil {
+		return nil, "", fmt.Errorf("failed to unmarshal envelope: %w", err)
+	}
+
+	if encReq.EncryptReq == "" {
+		return nil, "", errors.New("'encryptReq' key is missing")
+	}
+
+	if !card {
+		body, err := utils.DecryptResponse(encReq.EncryptReq, s.config.BankKey)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to decrypt request: %w", err)
+		}
+		return body, "", nil
+	}
+
+	outer, err := utils.DecryptResponse(encReq.EncryptReq, s.config.CardKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decrypt card request: %w", err)
+	}
+
+	wrapped := make(map[string]string)
+	if err := json.Unmarshal(outer, &wrapped); err != nil {
+		return nil, "", fmt.Errorf("failed to unmarshal card request: %w", err)
+	}
+
+	body, err := utils.DecryptResponse(wrapped["input"], s.config.CardKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decrypt card input: %w", err)
+	}
+
+	return body, wrapped["TransactionId"], nil
+}
+
+func (s *Server) sealEnvelope(body []byte, card bool) ([]byte, error) {
+	key := s.config.BankKey
+	if card {
+		key = s.config.CardKey
+
+		inner, err := encryptValue(body, key)
+		if err != nil {
+			return nil, err
+		}
+
+		body, err = json.Marshal(map[string]string{"response": inner})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	encrypted, err := encryptValue(body, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(responses.EncryptedRes{EncryptRes: encrypted})
+}
+
+// encryptValue reuses the request envelope helper and unwraps the cipher text.
+func encryptValue(body []byte, key string) (string, error) {
+	sealed, err := utils.GenerateEncryptedReqV2(body, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt response: %w", err)
+	}
+
+	encReq := requests.EncryptedReq{}
+	if err := json.Unmarshal(sealed, &encReq); err != nil {
+		return "", err
+	}
+
+	return encReq.EncryptReq, nil
+}
+
+func isCardControl(path string) bool {
+	return strings.HasPrefix(path, "/fintech/card/")
+}
+
+func isUpi(path string) bool {
+	return strings.HasPrefix(path, "/fintech/upi/")
+}
+
+func randomID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,115 @@
+package unittest
+
+import (
+	"bankapi/services"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBankErrorRegistryLookup(t *testing.T) {
+	registry, err := services.NewBankErrorRegistry("")
+	require.NoError(t, err)
+
+	// MW0011 is an input error for consent but a retryable one for payments
+	entry, ok := registry.Lookup("/fintech/v2/customer/consent", "MW0011")
+	require.True(t, ok)
+	assert.False(t, entry.Retryable)
+	assert.Equal(t, 400, entry.HTTPStatus)
+
+	entry, ok = registry.Lookup("/fintech/payment", "MW0011")
+	require.True(t, ok)
+	assert.True(t, entry.Retryable)
+	assert.Equal(t, 2, entry.MaxRetries)
+	assert.Equal(t, 503, entry.HTTPStatus)
+
+	// codes unknown to the operation come from the default operation
+	entry, ok = registry.Lookup("/fintech/payment", "MW9999")
+	require.True(t, ok)
+	assert.Equal(t, "default", entry.Operation)
+
+	_, ok = registry.Lookup("/fintech/payment", "UNKNOWN")
+	assert.False(t, ok)
+}
+
+func TestBankErrorRegistryRetry(t *testing.T) {
+	registry, err := services.NewBankErrorRegistry("")
+	require.NoError(t, err)
+
+	// the interceptor retries the default errors only
+	entry, ok := registry.Retry("/fintech/v2/account/details", "", "MW9999")
+	require.True(t, ok)
+	assert.Equal(t, "default", entry.Operation)
+	assert.Equal(t, 2, entry.MaxRetries)
+
+	// operation errors are retried by the store, which changes the request
+	entry, ok = registry.Retry("/fintech/beneficiary-registration", "MW9999")
+	require.NotNil(t, entry)
+	assert.False(t, ok)
+	assert.Equal(t, "beneficiary", entry.Operation)
+
+	// BANK_UNAVAILABLE is retryable but has no retries left to the interceptor
+	_, ok = registry.Retry("/fintech/payment", "BANK_UNAVAILABLE")
+	assert.False(t, ok)
+
+	entry, ok = registry.Retry("/fintech/card/control/edit", "MW0011")
+	require.NotNil(t, entry)
+	assert.False(t, ok)
+	assert.Equal(t, "card_control", entry.Operation)
+	assert.Equal(t, 400, entry.HTTPStatus)
+}
+
+func TestBankErrorEntryMessage(t *testing.T) {
+	registry, err := services.NewBankErrorRegistry("")
+	require.NoError(t, err)
+
+	entry, ok := registry.Lookup("/fintech/v2/otp", "90")
+	require.True(t, ok)
+
+	assert.Equal(t, entry.Messages["hi"], entry.Message("hi-IN,hi;q=0.9,en;q=0.8"))
+	assert.Equal(t, entry.Messages["en"], entry.Message("fr-FR"))
+	assert.Equal(t, entry.Messages["en"], entry.Message(""))
+}
+
+func TestBankErrorRegistryFileOverride(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "bank_errors.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`
+operations:
+  statement: [/fintech/statement]
+errors:
+  - operation: statement
+    code: ST0001
+    messages:
+      en: "Statement is not available for the selected period."
+  - operation: payment
+    code: MW0016
+    messages:
+      en: "Insufficient balance."
+    http_status: 422
+`), 0o600))
+
+	registry, err := services.NewBankErrorRegistry(file)
+	require.NoError(t, err)
+
+	entry, ok := registry.Lookup("/fintech/statement", "ST0001")
+	require.True(t, ok)
+	assert.Equal(t, 400, entry.HTTPStatus)
+
+	entry, ok = registry.Lookup("/fintech/payment-direct", "MW0016")
+	require.True(t, ok)
+	assert.Equal(t, "Insufficient balance.", entry.Message("en"))
+	assert.Equal(t, 422, entry.HTTPStatus)
+
+	// a broken file keeps the loaded content
+	require.NoError(t, os.WriteFile(file, []byte("errors: [{code: X1}]"), 0o600))
+	assert.Error(t, registry.Reload())
+
+	_, ok = registry.Lookup("/fintech/statement", "ST0001")
+	assert.True(t, ok)
+
+	_, err = services.NewBankErrorRegistry(file)
+	assert.Error(t, err)
+}
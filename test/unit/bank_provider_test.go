@@ -0,0 +1,86 @@
+package unittest
+
+import (
+	"bankapi/requests"
+	"bankapi/responses"
+	"bankapi/services"
+	"context"
+	"testing"
+
+	"bitbucket.org/paydoh/paydoh-commons/database"
+	commonSrv "bitbucket.org/paydoh/paydoh-commons/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBankProvider answers payments itself, every other call panics
+type fakeBankProvider struct {
+	services.BankProvider
+	payments []*requests.OutgoingPaymentRequest
+}
+
+func (f *fakeBankProvider) Name() string {
+	return "fake"
+}
+
+func (f *fakeBankProvider) PaymentSubmission(ctx context.Context, request *requests.OutgoingPaymentRequest) (*responses.PaymentSubmissionResponse, error) {
+	f.payments = append(f.payments, request)
+	return &responses.PaymentSubmissionResponse{TxnIdentifier: request.TxnIdentifier, TxnStatus: "SUCCESS"}, nil
+}
+
+func registerFakeBankProvider(t *testing.T) *fakeBankProvider {
+	fake := &fakeBankProvider{}
+	services.RegisterBankProvider("fake", func(log *commonSrv.LoggerService, memory *database.InMemory) services.BankProvider {
+		return fake
+	})
+	t.Cleanup(func() {
+		services.UnregisterBankProvider("fake")
+		services.SetBankProviderSelector(nil)
+	})
+	return fake
+}
+
+func TestBankProviderDefault(t *testing.T) {
+	fake := registerFakeBankProvider(t)
+	t.Setenv("BANK_PROVIDER_DEFAULT", "fake")
+
+	provider := services.NewBankProvider(nil, nil)
+	assert.Equal(t, "fake", provider.Name())
+
+	response, err := provider.PaymentSubmission(context.Background(), &requests.OutgoingPaymentRequest{TxnIdentifier: "TXN1"})
+	require.NoError(t, err)
+	assert.Equal(t, "TXN1", response.TxnIdentifier)
+	assert.Len(t, fake.payments, 1)
+}
+
+func TestBankProviderPerUser(t *testing.T) {
+	fake := registerFakeBankProvider(t)
+	t.Setenv("BANK_PROVIDER_DEFAULT", "")
+
+	services.SetBankProviderSelector(func(ctx context.Context) string {
+		if ctx.Value("user_id") == "fake-bank-user" {
+			return "fake"
+		}
+		return ""
+	})
+
+	provider := services.NewBankProvider(nil, nil)
+	assert.Equal(t, services.BankProviderKVB, provider.Name())
+
+	ctx := context.WithValue(context.Background(), "user_id", "fake-bank-user")
+	_, err := provider.PaymentSubmission(ctx, &requests.OutgoingPaymentRequest{TxnIdentifier: "TXN2"})
+	require.NoError(t, err)
+	assert.Len(t, fake.payments, 1)
+}
+
+func TestValidateBankProviders(t *testing.T) {
+	t.Setenv("BANK_PROVIDER_DEFAULT", "")
+	assert.NoError(t, services.ValidateBankProviders())
+
+	t.Setenv("BANK_PROVIDER_DEFAULT", "unknown")
+	assert.Error(t, services.ValidateBankProviders())
+
+	registerFakeBankProvider(t)
+	t.Setenv("BANK_PROVIDER_DEFAULT", "fake")
+	assert.NoError(t, services.ValidateBankProviders())
+}
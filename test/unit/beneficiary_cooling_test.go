@@ -0,0 +1,67 @@
+package unittest
+
+import (
+	"bankapi/config"
+	"bankapi/constants"
+	"bankapi/models"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReserveBeneficiaryCooling(t *testing.T) {
+	t.Setenv("BENEFICIARY_COOLING_LIMIT", "10000")
+
+	coolingUntil := time.Now().Add(12 * time.Hour)
+
+	t.Run("no cooling beneficiary", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery(`FROM beneficiaries`).WithArgs("user-1", "XXXXXXXX9012", "HDFC0001234").
+			WillReturnError(sql.ErrNoRows)
+
+		reservation, err := models.ReserveBeneficiaryCooling(db, "user-1", "123456789012", "HDFC0001234", "50000")
+		require.NoError(t, err)
+		assert.Nil(t, reservation)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("within cooling limit", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery(`FROM beneficiaries`).WithArgs("user-1", "XXXXXXXX9012", "HDFC0001234").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "cooling_until", "cooling_used"}).AddRow("benf-1", coolingUntil, 400000))
+		mock.ExpectQuery(`UPDATE beneficiaries SET cooling_used`).WithArgs("benf-1", int64(600000), int64(1000000)).
+			WillReturnRows(sqlmock.NewRows([]string{"cooling_used"}).AddRow(1000000))
+
+		reservation, err := models.ReserveBeneficiaryCooling(db, "user-1", "123456789012", "HDFC0001234", "6000")
+		require.NoError(t, err)
+		assert.Equal(t, &models.BeneficiaryCoolingReservation{BeneficiaryId: "benf-1", Amount: 600000}, reservation)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("above cooling limit", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery(`FROM beneficiaries`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "cooling_until", "cooling_used"}).AddRow("benf-1", coolingUntil, 400000))
+		mock.ExpectQuery(`UPDATE beneficiaries SET cooling_used`).
+			WillReturnRows(sqlmock.NewRows([]string{"cooling_used"}))
+
+		_, err = models.ReserveBeneficiaryCooling(db, "user-1", "123456789012", "HDFC0001234", "6000.01")
+		assert.EqualError(t, err, fmt.Sprintf(constants.BeneficiaryCoolingLimitErrorMessage,
+			"10000.00", coolingUntil.In(config.ISTLocation()).Format("02 Jan 2006, 03:04 PM"), "6000.00"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
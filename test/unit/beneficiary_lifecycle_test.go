@@ -0,0 +1,149 @@
+package unittest
+
+import (
+	"bankapi/constants"
+	"bankapi/models"
+	"bankapi/responses"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcileBeneficiaries(t *testing.T) {
+	bankBeneficiary := func(benfId, name string) responses.BeneficiaryDetails {
+		return responses.BeneficiaryDetails{
+			BenfID:       benfId,
+			BenfName:     name,
+			BenfMob:      "9876543210",
+			BenfAcctNo:   "123456789012",
+			BenfIFSC:     "HDFC0001234",
+			BenfAcctType: "SB",
+			PaymentMode:  "IMPS",
+		}
+	}
+	localBeneficiary := func(benfId, name string, isActive bool) models.BeneficiaryDTO {
+		return models.BeneficiaryDTO{
+			BenfId:        benfId,
+			BenfName:      name,
+			BenfMobNo:     "9876543210",
+			BenfAccountNo: "XXXXXXXX9012",
+			BenfIfsc:      "HDFC0001234",
+			BenfAcctType:  "SB",
+			PaymentMode:   "IMPS",
+			IsActive:      isActive,
+		}
+	}
+	removed := sql.NullTime{Time: time.Now(), Valid: true}
+
+	disabled := localBeneficiary("disabled", "Disabled", false)
+	disabled.DisabledAt = removed
+	deleted := localBeneficiary("deleted", "Deleted", false)
+	deleted.DeletedAt = removed
+
+	local := []models.BeneficiaryDTO{
+		localBeneficiary("same", "Same", true),
+		localBeneficiary("renamed", "Old Name", true),
+		localBeneficiary("not-activated", "Not Activated", false),
+		localBeneficiary("missing-at-bank", "Missing At Bank", true),
+		localBeneficiary("never-activated", "Never Activated", false),
+		disabled,
+		deleted,
+	}
+	bank := []responses.BeneficiaryDetails{
+		bankBeneficiary("same", "SAME"),
+		bankBeneficiary("renamed", "New Name"),
+		bankBeneficiary("not-activated", "Not Activated"),
+		bankBeneficiary("missing-local", "Missing Local"),
+		bankBeneficiary("disabled", "Disabled"),
+		bankBeneficiary("deleted", "Deleted"),
+	}
+
+	drifts := make(map[string]string)
+	for _, drift := range models.ReconcileBeneficiaries(local, bank) {
+		drifts[drift.BenfId] = drift.Drift
+	}
+
+	assert.Equal(t, map[string]string{
+		"renamed":         models.BeneficiaryDriftDetails,
+		"not-activated":   models.BeneficiaryDriftNotActivated,
+		"missing-at-bank": models.BeneficiaryDriftMissingAtBank,
+		"missing-local":   models.BeneficiaryDriftMissingLocal,
+	}, drifts)
+}
+
+func TestCheckBeneficiaryPayable(t *testing.T) {
+	tests := []struct {
+		name       string
+		rows       *sqlmock.Rows
+		wantErrMsg string
+	}{
+		{
+			name:       "disabled",
+			rows:       sqlmock.NewRows([]string{"disabled_at", "deleted_at"}).AddRow(time.Now(), nil),
+			wantErrMsg: constants.BeneficiaryDisabledErrorMessage,
+		},
+		{
+			name:       "deleted",
+			rows:       sqlmock.NewRows([]string{"disabled_at", "deleted_at"}).AddRow(nil, time.Now()),
+			wantErrMsg: constants.BeneficiaryNotFoundErrorMessage,
+		},
+		{
+			name: "not disabled or deleted",
+			rows: sqlmock.NewRows([]string{"disabled_at", "deleted_at"}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectQuery(`FROM beneficiaries`).WithArgs("user-1", "XXXXXXXX9012", "HDFC0001234").WillReturnRows(tt.rows)
+
+			err = models.CheckBeneficiaryPayable(db, "user-1", "123456789012", "HDFC0001234")
+			if tt.wantErrMsg != "" {
+				assert.EqualError(t, err, tt.wantErrMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestBeneficiaryBindLocalState(t *testing.T) {
+	response := &responses.FetchBeneficiaryResponse{
+		BeneficiaryDetails: []responses.BeneficiaryDetails{
+			{BenfID: "kept", BenfName: "Kept", BenfAcctNo: "123456789012", BenfStatus: "active"},
+			{BenfID: "disabled", BenfName: "Disabled", BenfAcctNo: "123456789013", BenfStatus: "active"},
+			{BenfID: "deleted", BenfName: "Deleted", BenfAcctNo: "123456789014", BenfStatus: "active"},
+		},
+	}
+	removed := sql.NullTime{Time: time.Now(), Valid: true}
+	data := []models.BeneficiaryDTO{
+		{BenfId: "kept", IsActive: true},
+		{BenfId: "disabled", DisabledAt: removed},
+		{BenfId: "deleted", DeletedAt: removed},
+	}
+	data[0].BenfNickName.String = "Mom"
+
+	beneficiaries, err := models.NewBeneficiaryDetail().Bind(response, data)
+	require.NoError(t, err)
+
+	statuses := make(map[string]string)
+	for _, ben := range beneficiaries {
+		statuses[ben.BenfId] = ben.BenfStatus
+		if ben.BenfId == "kept" {
+			assert.Equal(t, "Mom", ben.BenfNickName)
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"kept":     "active",
+		"disabled": models.BeneficiaryStatusDisabled,
+	}, statuses)
+}
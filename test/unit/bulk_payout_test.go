@@ -0,0 +1,93 @@
+package unittest
+
+import (
+	"bankapi/requests"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestParseBulkPayoutFile(t *testing.T) {
+	csvData := "\xef\xbb\xbfBeneficiary Name,Account Number,IFSC Code,Amount,Remarks\n" +
+		"Acme Traders,123456789012,hdfc0001234,\"1,250.5\",March invoice\n" +
+		",,,,\n" +
+		"Globex, 987654321098 ,SBIN0005678,99,\n"
+
+	fileType, rows, err := requests.ParseBulkPayoutFile("vendors.CSV", []byte(csvData))
+	require.NoError(t, err)
+	assert.Equal(t, requests.BulkPayoutFileCsv, fileType)
+	require.Len(t, rows, 2)
+	assert.Equal(t, requests.BulkPayoutRow{
+		RowNumber:  2,
+		BenfName:   "Acme Traders",
+		BenfAcctNo: "123456789012",
+		BenfIfsc:   "HDFC0001234",
+		Amount:     "1250.5",
+		Remarks:    "March invoice",
+	}, rows[0])
+	assert.Equal(t, 4, rows[1].RowNumber)
+	assert.Equal(t, "987654321098", rows[1].BenfAcctNo)
+
+	file := excelize.NewFile()
+	sheet := file.GetSheetName(0)
+	require.NoError(t, file.SetSheetRow(sheet, "A1", &[]interface{}{"Name", "Account No", "IFSC", "Amount", "Remarks", "Account Type"}))
+	require.NoError(t, file.SetSheetRow(sheet, "A2", &[]interface{}{"Initech", 123456789012345, "ICIC0000001", 500, "Rent", "ca"}))
+	buffer, err := file.WriteToBuffer()
+	require.NoError(t, err)
+
+	fileType, rows, err = requests.ParseBulkPayoutFile("vendors.xlsx", buffer.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, requests.BulkPayoutFileXlsx, fileType)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "123456789012345", rows[0].BenfAcctNo)
+	assert.Equal(t, "CA", rows[0].BenfAcctType)
+	assert.Equal(t, "500", rows[0].Amount)
+
+	_, _, err = requests.ParseBulkPayoutFile("vendors.pdf", []byte(csvData))
+	assert.True(t, errors.Is(err, requests.ErrBulkPayoutFile))
+
+	_, _, err = requests.ParseBulkPayoutFile("vendors.csv", []byte("Name,Account Number,Amount\nAcme,123456789012,10\n"))
+	assert.True(t, errors.Is(err, requests.ErrBulkPayoutFile))
+}
+
+func TestBulkPayoutRowValidate(t *testing.T) {
+	valid := func() requests.BulkPayoutRow {
+		return requests.BulkPayoutRow{
+			BenfName:   "Acme Traders",
+			BenfAcctNo: "123456789012",
+			BenfIfsc:   "HDFC0001234",
+			Amount:     "1250.5",
+		}
+	}
+
+	row := valid()
+	require.NoError(t, row.Validate())
+	assert.Equal(t, "1250.50", row.Amount)
+	assert.Equal(t, "SA", row.BenfAcctType)
+	assert.Equal(t, "Bulk payout", row.Remarks)
+
+	tests := []struct {
+		name   string
+		change func(r *requests.BulkPayoutRow)
+	}{
+		{name: "missing name", change: func(r *requests.BulkPayoutRow) { r.BenfName = "" }},
+		{name: "short account", change: func(r *requests.BulkPayoutRow) { r.BenfAcctNo = "12345678" }},
+		{name: "account with letters", change: func(r *requests.BulkPayoutRow) { r.BenfAcctNo = "12345678901A" }},
+		{name: "bad ifsc", change: func(r *requests.BulkPayoutRow) { r.BenfIfsc = "HDFC1001234" }},
+		{name: "bad account type", change: func(r *requests.BulkPayoutRow) { r.BenfAcctType = "NRE" }},
+		{name: "zero amount", change: func(r *requests.BulkPayoutRow) { r.Amount = "0.00" }},
+		{name: "three decimals", change: func(r *requests.BulkPayoutRow) { r.Amount = "10.005" }},
+		{name: "negative amount", change: func(r *requests.BulkPayoutRow) { r.Amount = "-10" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row := valid()
+			tt.change(&row)
+			assert.Error(t, row.Validate())
+		})
+	}
+}
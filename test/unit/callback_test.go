@@ -0,0 +1,214 @@
+package unittest
+
+import (
+	"bankapi/config"
+	"bankapi/middleware"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"bitbucket.org/paydoh/paydoh-commons/database"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCallbackPartner(t *testing.T) {
+	t.Setenv("CALLBACK_PAYMENT_AUTH", "hmac")
+	t.Setenv("CALLBACK_PAYMENT_SECRET", "secret")
+	t.Setenv("CALLBACK_PAYMENT_ALLOWED_IPS", "10.0.0.0/24, 192.168.1.7")
+	t.Setenv("CALLBACK_MAX_SKEW", "60")
+
+	partner, err := config.GetCallbackPartner(config.CallbackPartnerPayment)
+	require.NoError(t, err)
+
+	assert.Equal(t, config.CallbackAuthHmac, partner.Auth)
+	assert.Equal(t, "secret", partner.Secret)
+	assert.Equal(t, time.Minute, partner.MaxSkew)
+	assert.True(t, partner.AllowsIP("10.0.0.42"))
+	assert.True(t, partner.AllowsIP("192.168.1.7"))
+	assert.False(t, partner.AllowsIP("192.168.1.8"))
+	assert.False(t, partner.AllowsIP("not-an-ip"))
+}
+
+func TestGetCallbackPartnerDefaults(t *testing.T) {
+	t.Setenv("CALLBACK_API_KEY", "shared")
+
+	// partners are on hmac auth unless configured otherwise, and there is no
+	// shared secret to fall back on
+	partner, err := config.GetCallbackPartner(config.CallbackPartnerSms)
+	require.NoError(t, err)
+
+	assert.Equal(t, config.CallbackAuthHmac, partner.Auth)
+	assert.Empty(t, partner.Secret)
+	assert.True(t, partner.AllowsIP("203.0.113.9"))
+
+	t.Setenv("CALLBACK_SMS_AUTH", "basic")
+	_, err = config.GetCallbackPartner(config.CallbackPartnerSms)
+	assert.Error(t, err)
+}
+
+func TestCallbackSignature(t *testing.T) {
+	body := []byte(`{"status":"SUCCESS"}`)
+	signature := middleware.CallbackSignature("secret", "1700000000", "nonce-1", "POST", "/callback/normal-payment", body)
+
+	assert.Len(t, signature, 64)
+	assert.Equal(t, signature, middleware.CallbackSignature("secret", "1700000000", "nonce-1", "POST", "/callback/normal-payment", body))
+	assert.NotEqual(t, signature, middleware.CallbackSignature("secret", "1700000001", "nonce-1", "POST", "/callback/normal-payment", body))
+	assert.NotEqual(t, signature, middleware.CallbackSignature("secret", "1700000000", "nonce-1", "POST", "/callback/account-create", body))
+	assert.NotEqual(t, signature, middleware.CallbackSignature("other", "1700000000", "nonce-1", "POST", "/callback/normal-payment", body))
+}
+
+// useTestRedis points config.GetRedis to an in memory redis for the test
+func useTestRedis(t *testing.T) *miniredis.Miniredis {
+	server := miniredis.RunT(t)
+	memory, err := database.NewInMemory(context.Background(), database.InMemoryConfig{
+		NetworkType: "tcp",
+		Address:     server.Addr(),
+	})
+	require.NoError(t, err)
+	config.SetRedis(memory)
+	return server
+}
+
+// newCallbackRouter serves /callback behind the middleware of the payment
+// partner, trusting no proxy as main does
+func newCallbackRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("CALLBACK_PAYMENT_AUTH", "hmac")
+	t.Setenv("CALLBACK_PAYMENT_SECRET", "secret")
+	t.Setenv("CALLBACK_PAYMENT_ALLOWED_IPS", "10.0.0.0/24")
+	t.Setenv("CALLBACK_MAX_SKEW", "60")
+	t.Setenv("CALLBACK_MAX_BODY_BYTES", "64")
+
+	router := gin.New()
+	require.NoError(t, router.SetTrustedProxies(nil))
+	router.POST("/callback", middleware.CallbackMiddleware(config.CallbackPartnerPayment), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func signedCallback(secret string, timestamp time.Time, nonce string, body []byte) *http.Request {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	req := httptest.NewRequest(http.MethodPost, "/callback", bytes.NewReader(body))
+	req.RemoteAddr = "10.0.0.7:43000"
+	req.Header.Set("X-Timestamp", unix)
+	req.Header.Set("X-Nonce", nonce)
+	req.Header.Set("X-Signature", middleware.CallbackSignature(secret, unix, nonce, http.MethodPost, "/callback", body))
+	return req
+}
+
+func serveCallback(router *gin.Engine, req *http.Request) int {
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder.Code
+}
+
+func TestCallbackMiddlewareRejectsReplayedNonce(t *testing.T) {
+	useTestRedis(t)
+	router := newCallbackRouter(t)
+	body := []byte(`{"status":"SUCCESS"}`)
+
+	assert.Equal(t, http.StatusOK, serveCallback(router, signedCallback("secret", time.Now(), "nonce-1", body)))
+	assert.Equal(t, http.StatusUnauthorized, serveCallback(router, signedCallback("secret", time.Now(), "nonce-1", body)))
+	assert.Equal(t, http.StatusOK, serveCallback(router, signedCallback("secret", time.Now(), "nonce-2", body)))
+}
+
+func TestCallbackMiddlewareRejectsTimestampSkew(t *testing.T) {
+	useTestRedis(t)
+	router := newCallbackRouter(t)
+	body := []byte(`{}`)
+
+	assert.Equal(t, http.StatusUnauthorized, serveCallback(router, signedCallback("secret", time.Now().Add(-2*time.Minute), "old", body)))
+	assert.Equal(t, http.StatusUnauthorized, serveCallback(router, signedCallback("secret", time.Now().Add(2*time.Minute), "future", body)))
+	assert.Equal(t, http.StatusOK, serveCallback(router, signedCallback("secret", time.Now().Add(-30*time.Second), "recent", body)))
+}
+
+func TestCallbackMiddlewareRejectsBadSignature(t *testing.T) {
+	useTestRedis(t)
+	router := newCallbackRouter(t)
+
+	assert.Equal(t, http.StatusUnauthorized, serveCallback(router, signedCallback("other", time.Now(), "nonce-1", []byte(`{}`))))
+
+	// the body is covered by the signature
+	req := signedCallback("secret", time.Now(), "nonce-2", []byte(`{"amount":1}`))
+	req.Body = http.NoBody
+	assert.Equal(t, http.StatusUnauthorized, serveCallback(router, req))
+
+	req = signedCallback("secret", time.Now(), "nonce-3", []byte(`{}`))
+	req.Header.Del("X-Signature")
+	assert.Equal(t, http.StatusUnauthorized, serveCallback(router, req))
+
+	req = signedCallback("secret", time.Now(), "nonce-4", bytes.Repeat([]byte("a"), 65))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, serveCallback(router, req))
+}
+
+func TestCallbackMiddlewareAllowsListedIPs(t *testing.T) {
+	useTestRedis(t)
+	router := newCallbackRouter(t)
+
+	req := signedCallback("secret", time.Now(), "nonce-1", []byte(`{}`))
+	req.RemoteAddr = "192.168.1.7:43000"
+	assert.Equal(t, http.StatusForbidden, serveCallback(router, req))
+
+	// X-Forwarded-For is only believed from a trusted proxy
+	req = signedCallback("secret", time.Now(), "nonce-2", []byte(`{}`))
+	req.RemoteAddr = "192.168.1.7:43000"
+	req.Header.Set("X-Forwarded-For", "10.0.0.7")
+	assert.Equal(t, http.StatusForbidden, serveCallback(router, req))
+
+	assert.Equal(t, http.StatusOK, serveCallback(router, signedCallback("secret", time.Now(), "nonce-3", []byte(`{}`))))
+}
+
+func TestCallbackMiddlewareApiKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("CALLBACK_SMS_AUTH", "api_key")
+	t.Setenv("CALLBACK_SMS_SECRET", "sms-key")
+
+	router := gin.New()
+	router.GET("/callback", middleware.CallbackMiddleware(config.CallbackPartnerSms), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/callback", nil)
+	assert.Equal(t, http.StatusUnauthorized, serveCallback(router, req))
+
+	req = httptest.NewRequest(http.MethodGet, "/callback", nil)
+	req.Header.Set("X-API-Key", "sms-key")
+	assert.Equal(t, http.StatusOK, serveCallback(router, req))
+}
+
+func TestValidateCallbackPartners(t *testing.T) {
+	assert.Error(t, config.ValidateCallbackPartners(), "internal and admin partners need their own secret")
+
+	t.Setenv("CALLBACK_INTERNAL_SECRET", "internal-secret")
+	assert.Error(t, config.ValidateCallbackPartners())
+
+	t.Setenv("CALLBACK_ADMIN_SECRET", "admin-secret")
+	require.NoError(t, config.ValidateCallbackPartners())
+
+	t.Setenv("CALLBACK_REWARDS_ALLOWED_IPS", "10.0.0.0/99")
+	assert.Error(t, config.ValidateCallbackPartners())
+}
+
+func TestValidateTrustedProxies(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "")
+	assert.Error(t, config.ValidateTrustedProxies(), "an unset TRUSTED_PROXIES would make the load balancer the client")
+
+	t.Setenv("TRUSTED_PROXIES", "none")
+	require.NoError(t, config.ValidateTrustedProxies())
+	assert.Empty(t, config.GetTrustedProxies())
+
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/16, 192.168.1.7")
+	require.NoError(t, config.ValidateTrustedProxies())
+	assert.Equal(t, []string{"10.0.0.0/16", "192.168.1.7"}, config.GetTrustedProxies())
+
+	t.Setenv("TRUSTED_PROXIES", "load-balancer")
+	assert.Error(t, config.ValidateTrustedProxies())
+}
@@ -0,0 +1,133 @@
+package unittest
+
+import (
+	"bankapi/config"
+	"bankapi/services"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBreakerRegistry(now *time.Time) *services.BreakerRegistry {
+	return services.NewBreakerRegistry(func(endpoint string) *config.BankBreakerConfig {
+		return &config.BankBreakerConfig{
+			FailureThreshold: 2,
+			OpenTimeout:      30 * time.Second,
+			MaxConcurrent:    2,
+		}
+	}, func() time.Time { return *now })
+}
+
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	now := time.Now()
+	registry := newTestBreakerRegistry(&now)
+
+	for i := 0; i < 2; i++ {
+		release, err := registry.Acquire("/fintech/payment")
+		require.NoError(t, err)
+		release(true)
+	}
+
+	_, err := registry.Acquire("/fintech/payment")
+	assert.True(t, services.IsBankUnavailable(err), "breaker should be open")
+
+	// other endpoints are not affected
+	release, err := registry.Acquire("/fintech/account-statement")
+	require.NoError(t, err)
+	release(false)
+
+	// after the open timeout a single trial call is let through
+	now = now.Add(31 * time.Second)
+	release, err = registry.Acquire("/fintech/payment")
+	require.NoError(t, err)
+
+	_, err = registry.Acquire("/fintech/payment")
+	assert.True(t, services.IsBankUnavailable(err), "only one trial call while half open")
+
+	release(false)
+
+	release, err = registry.Acquire("/fintech/payment")
+	require.NoError(t, err)
+	release(false)
+
+	for _, status := range registry.Status() {
+		assert.Equal(t, services.BreakerClosed, status.State, status.Endpoint)
+	}
+}
+
+func TestBreakerReopensWhenTrialFails(t *testing.T) {
+	now := time.Now()
+	registry := newTestBreakerRegistry(&now)
+
+	for i := 0; i < 2; i++ {
+		release, err := registry.Acquire("/fintech/upi/req-pay")
+		require.NoError(t, err)
+		release(true)
+	}
+
+	now = now.Add(31 * time.Second)
+	release, err := registry.Acquire("/fintech/upi/req-pay")
+	require.NoError(t, err)
+	release(true)
+
+	_, err = registry.Acquire("/fintech/upi/req-pay")
+	assert.True(t, services.IsBankUnavailable(err))
+	assert.Equal(t, services.BreakerOpen, registry.Status()[0].State)
+}
+
+func TestBreakerIgnoresLateResultWhileHalfOpen(t *testing.T) {
+	now := time.Now()
+	registry := newTestBreakerRegistry(&now)
+
+	late, err := registry.Acquire("/fintech/payment")
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		release, err := registry.Acquire("/fintech/payment")
+		require.NoError(t, err)
+		release(true)
+	}
+
+	now = now.Add(31 * time.Second)
+	trial, err := registry.Acquire("/fintech/payment")
+	require.NoError(t, err)
+
+	// a call made before the breaker opened must not close it
+	late(false)
+	assert.Equal(t, services.BreakerHalfOpen, registry.Status()[0].State)
+
+	_, err = registry.Acquire("/fintech/payment")
+	assert.True(t, services.IsBankUnavailable(err), "the trial call is still in flight")
+
+	trial(true)
+	assert.Equal(t, services.BreakerOpen, registry.Status()[0].State)
+}
+
+func TestBulkheadLimitsConcurrentCalls(t *testing.T) {
+	now := time.Now()
+	registry := newTestBreakerRegistry(&now)
+
+	first, err := registry.Acquire("/fintech/payment")
+	require.NoError(t, err)
+	second, err := registry.Acquire("/fintech/payment")
+	require.NoError(t, err)
+
+	_, err = registry.Acquire("/fintech/payment")
+	assert.True(t, services.IsBankUnavailable(err), "bulkhead should be full")
+
+	first(false)
+	// releasing twice must not free an extra slot
+	first(false)
+
+	third, err := registry.Acquire("/fintech/payment")
+	require.NoError(t, err)
+
+	_, err = registry.Acquire("/fintech/payment")
+	assert.True(t, services.IsBankUnavailable(err))
+
+	second(false)
+	third(false)
+	assert.Equal(t, 0, registry.Status()[0].InFlight)
+}
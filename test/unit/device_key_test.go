@@ -0,0 +1,62 @@
+package unittest
+
+import (
+	"bankapi/models"
+	"bankapi/security"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodePublicKey(t *testing.T, publicKey interface{}) string {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(der)
+}
+
+func TestVerifyDeviceKeySignatureES256(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	publicKey := encodePublicKey(t, &privateKey.PublicKey)
+
+	challenge := []byte("challenge")
+	digest := sha256.Sum256(challenge)
+	signature, err := ecdsa.SignASN1(rand.Reader, privateKey, digest[:])
+	require.NoError(t, err)
+	encodedSignature := base64.StdEncoding.EncodeToString(signature)
+
+	assert.NoError(t, security.VerifyDeviceKeySignature(models.DeviceKeyAlgorithmES256, publicKey, challenge, encodedSignature))
+	assert.Error(t, security.VerifyDeviceKeySignature(models.DeviceKeyAlgorithmES256, publicKey, []byte("other"), encodedSignature))
+	assert.Error(t, security.VerifyDeviceKeySignature(models.DeviceKeyAlgorithmEd25519, publicKey, challenge, encodedSignature))
+}
+
+func TestVerifyDeviceKeySignatureEd25519(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	encodedKey := encodePublicKey(t, publicKey)
+
+	challenge := []byte("challenge")
+	signature := base64.RawURLEncoding.EncodeToString(ed25519.Sign(privateKey, challenge))
+
+	assert.NoError(t, security.VerifyDeviceKeySignature(models.DeviceKeyAlgorithmEd25519, encodedKey, challenge, signature))
+	assert.Error(t, security.VerifyDeviceKeySignature(models.DeviceKeyAlgorithmEd25519, encodedKey, []byte("other"), signature))
+}
+
+func TestParseDevicePublicKeyRejectsOtherCurves(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	_, err = security.ParseDevicePublicKey(models.DeviceKeyAlgorithmES256, encodePublicKey(t, &privateKey.PublicKey))
+	assert.Error(t, err)
+
+	_, err = security.ParseDevicePublicKey(models.DeviceKeyAlgorithmES256, "not a key")
+	assert.Error(t, err)
+}
@@ -0,0 +1,54 @@
+package unittest
+
+import (
+	"bankapi/config"
+	"bankapi/requests"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayloadEnvelopeData(t *testing.T) {
+	envelope := requests.NewPayloadEnvelope()
+	require.NoError(t, envelope.Unmarshal(`{"timestamp":1700000000,"nonce":"n-1","payload":{"mpin":"1234"}}`))
+
+	data, err := envelope.Data()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1700000000), envelope.Timestamp)
+	assert.Equal(t, "n-1", envelope.Nonce)
+	assert.JSONEq(t, `{"mpin":"1234"}`, data)
+
+	encoded := requests.NewPayloadEnvelope()
+	require.NoError(t, encoded.Unmarshal(`{"timestamp":1700000000,"nonce":"n-2","payload":"{\"mpin\":\"1234\"}"}`))
+
+	data, err = encoded.Data()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"mpin":"1234"}`, data)
+}
+
+func TestPayloadEnvelopeRequiresReplayFields(t *testing.T) {
+	for _, decrypted := range []string{
+		`{"mpin":"1234"}`,
+		`{"timestamp":1700000000,"payload":{"mpin":"1234"}}`,
+		`{"nonce":"n-1","payload":{"mpin":"1234"}}`,
+		`{"timestamp":1700000000,"nonce":"n-1"}`,
+		`not json`,
+	} {
+		assert.Error(t, requests.NewPayloadEnvelope().Unmarshal(decrypted), decrypted)
+	}
+}
+
+func TestGetPayloadEnvelopeConfig(t *testing.T) {
+	envelopeConfig := config.GetPayloadEnvelopeConfig()
+	assert.Equal(t, config.PayloadVersionLegacy, envelopeConfig.MinVersion)
+	assert.Equal(t, 5*time.Minute, envelopeConfig.MaxSkew)
+
+	t.Setenv("PAYLOAD_MIN_VERSION", "2")
+	t.Setenv("PAYLOAD_MAX_SKEW", "30")
+
+	envelopeConfig = config.GetPayloadEnvelopeConfig()
+	assert.Equal(t, config.PayloadVersionEnvelope, envelopeConfig.MinVersion)
+	assert.Equal(t, 30*time.Second, envelopeConfig.MaxSkew)
+}
@@ -0,0 +1,141 @@
+package unittest
+
+import (
+	"bankapi/config"
+	"bankapi/health"
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestChecker() *health.Checker {
+	return health.NewChecker(&config.HealthConfig{
+		CacheTTL: time.Minute,
+		Timeout:  50 * time.Millisecond,
+		Critical: map[string]bool{health.CheckRedis: true},
+	})
+}
+
+func TestHealthCriticalCheckDown(t *testing.T) {
+	checker := newTestChecker()
+	checker.Register(health.CheckPostgres, func(ctx context.Context) error { return nil })
+	checker.Register(health.CheckRedis, func(ctx context.Context) error { return errors.New("connection refused") })
+
+	report := checker.Ready(context.Background())
+	assert.Equal(t, health.StatusDown, report.Status)
+
+	require.Len(t, report.Checks, 2)
+	assert.Equal(t, health.CheckPostgres, report.Checks[0].Name)
+	assert.Equal(t, health.StatusUp, report.Checks[0].Status)
+	assert.Equal(t, health.CheckRedis, report.Checks[1].Name)
+	assert.True(t, report.Checks[1].Critical)
+	assert.Equal(t, "connection refused", report.Checks[1].Error)
+}
+
+func TestHealthNonCriticalCheckDegrades(t *testing.T) {
+	checker := newTestChecker()
+	checker.Register(health.CheckRedis, func(ctx context.Context) error { return nil })
+	checker.Register(health.CheckClamAV, func(ctx context.Context) error { return errors.New("no clamd") })
+
+	assert.Equal(t, health.StatusDegraded, checker.Ready(context.Background()).Status)
+}
+
+func TestHealthResultsAreCached(t *testing.T) {
+	checker := newTestChecker()
+
+	calls := 0
+	checker.Register(health.CheckRedis, func(ctx context.Context) error {
+		calls++
+		return nil
+	})
+
+	checker.Ready(context.Background())
+	checker.Ready(context.Background())
+	assert.Equal(t, 1, calls)
+}
+
+func TestHealthCheckTimeout(t *testing.T) {
+	checker := newTestChecker()
+	checker.Register(health.CheckClamAV, func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	startTime := time.Now()
+	report := checker.Ready(context.Background())
+
+	assert.Less(t, time.Since(startTime), 500*time.Millisecond)
+	assert.Equal(t, health.StatusDown, report.Checks[0].Status)
+	assert.Equal(t, "check timed out", report.Checks[0].Error)
+}
+
+func TestHealthProbeIgnoresCallerCancellation(t *testing.T) {
+	checker := newTestChecker()
+	checker.Register(health.CheckRedis, func(ctx context.Context) error { return ctx.Err() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report := checker.Ready(ctx)
+	assert.Equal(t, health.StatusUp, report.Status)
+}
+
+func TestHealthCancelledResultIsNotCached(t *testing.T) {
+	checker := newTestChecker()
+
+	var calls atomic.Int32
+	checker.Register(health.CheckRedis, func(ctx context.Context) error {
+		if calls.Add(1) == 1 {
+			return context.Canceled
+		}
+		return nil
+	})
+
+	assert.Equal(t, health.StatusDown, checker.Ready(context.Background()).Status)
+	assert.Equal(t, health.StatusUp, checker.Ready(context.Background()).Status)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestHealthProbesRunWithoutTheLock(t *testing.T) {
+	checker := newTestChecker()
+
+	var calls atomic.Int32
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	checker.Register(health.CheckRedis, func(ctx context.Context) error {
+		calls.Add(1)
+		close(started)
+		<-unblock
+		return nil
+	})
+
+	reports := make(chan *health.Report, 2)
+	go func() { reports <- checker.Ready(context.Background()) }()
+	<-started
+
+	registered := make(chan struct{})
+	go func() {
+		checker.Register(health.CheckClamAV, func(ctx context.Context) error { return nil })
+		close(registered)
+	}()
+	select {
+	case <-registered:
+	case <-time.After(time.Second):
+		t.Fatal("Register blocked while a probe was running")
+	}
+
+	// a second caller waits for the running probe instead of starting another
+	go func() { reports <- checker.Ready(context.Background()) }()
+	time.Sleep(10 * time.Millisecond)
+	close(unblock)
+
+	for i := 0; i < 2; i++ {
+		assert.NotEqual(t, health.StatusDown, (<-reports).Status)
+	}
+	assert.Equal(t, int32(1), calls.Load())
+}
@@ -0,0 +1,115 @@
+package unittest
+
+import (
+	"bankapi/config"
+	"bankapi/middleware"
+	"bankapi/models"
+	"bankapi/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newIdempotentRouter serves /pay with status behind IdempotencyMiddleware on a mocked db
+func newIdempotentRouter(t *testing.T, status int) (*gin.Engine, sqlmock.Sqlmock) {
+	return newIdempotentPaymentRouter(t, status, false)
+}
+
+// newIdempotentPaymentRouter is newIdempotentRouter for a handler that sends
+// the payment to the bank when bankCalled is set
+func newIdempotentPaymentRouter(t *testing.T, status int, bankCalled bool) (*gin.Engine, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	previous := config.DB
+	config.DB = db
+	t.Cleanup(func() { config.DB = previous })
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/pay", func(c *gin.Context) {
+		c.Set("user_id", "user-1")
+	}, middleware.IdempotencyMiddleware(), func(c *gin.Context) {
+		if bankCalled {
+			utils.MarkBankCalled(c.Request.Context())
+		}
+		c.JSON(status, gin.H{"status": status})
+	})
+	return router, mock
+}
+
+func idempotentRequest(router *gin.Engine) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/pay", nil)
+	req.Header.Set("Idempotency-Key", "key-1")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestIdempotencyMiddlewareStoresOutcome(t *testing.T) {
+	router, mock := newIdempotentRouter(t, http.StatusOK)
+	claimedAt := time.Now()
+
+	mock.ExpectQuery("INSERT INTO idempotency_keys").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("id-1", claimedAt))
+	mock.ExpectExec("UPDATE idempotency_keys SET").
+		WithArgs(models.IdempotencyCompleted, http.StatusOK, `{"status":200}`, "id-1", claimedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.Equal(t, http.StatusOK, idempotentRequest(router).Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIdempotencyMiddlewareReleasesServerErrors(t *testing.T) {
+	router, mock := newIdempotentRouter(t, http.StatusBadGateway)
+	claimedAt := time.Now()
+
+	mock.ExpectQuery("INSERT INTO idempotency_keys").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("id-1", claimedAt))
+	mock.ExpectExec("DELETE FROM idempotency_keys").
+		WithArgs("id-1", claimedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.Equal(t, http.StatusBadGateway, idempotentRequest(router).Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIdempotencyMiddlewareStoresServerErrorsAfterBankCall(t *testing.T) {
+	router, mock := newIdempotentPaymentRouter(t, http.StatusServiceUnavailable, true)
+	claimedAt := time.Now()
+
+	mock.ExpectQuery("INSERT INTO idempotency_keys").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("id-1", claimedAt))
+	mock.ExpectExec("UPDATE idempotency_keys SET").
+		WithArgs(models.IdempotencyCompleted, http.StatusServiceUnavailable, `{"status":503}`, "id-1", claimedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.Equal(t, http.StatusServiceUnavailable, idempotentRequest(router).Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIdempotencyMiddlewareRejectsInProgress(t *testing.T) {
+	router, mock := newIdempotentRouter(t, http.StatusOK)
+
+	mock.ExpectQuery("INSERT INTO idempotency_keys").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}))
+	mock.ExpectQuery("SELECT (.+) FROM idempotency_keys").
+		WithArgs("user-1", "key-1").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "user_id", "idempotency_key", "route", "request_hash", "status",
+			"response_status", "response_body", "created_at", "updated_at",
+		}).AddRow(
+			"id-1", "user-1", "key-1", "/pay", utils.RequestHash("/pay", nil), models.IdempotencyProcessing,
+			nil, nil, time.Now(), time.Now(),
+		))
+
+	assert.Equal(t, http.StatusConflict, idempotentRequest(router).Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,55 @@
+package unittest
+
+import (
+	"bankapi/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotateJwtKeyRetiresSigningKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	key := &models.JwtKey{Kid: "kid-2", Algorithm: "RS256", PrivateKey: "encrypted"}
+	rotateBefore := time.Now().Add(-time.Hour)
+	retiredExpiresAt := time.Now().Add(15 * time.Minute)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT EXISTS`).WithArgs(rotateBefore).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec(`UPDATE jwt_signing_keys SET retired_at`).WithArgs(retiredExpiresAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO jwt_signing_keys`).
+		WithArgs(key.Kid, key.Algorithm, key.PrivateKey, key.PublicKey).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+	mock.ExpectCommit()
+
+	rotated, err := models.RotateJwtKey(db, key, rotateBefore, retiredExpiresAt)
+	require.NoError(t, err)
+	assert.True(t, rotated)
+	assert.False(t, key.CreatedAt.IsZero())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRotateJwtKeySkipsWhenAlreadyRotated(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT EXISTS`).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	rotated, err := models.RotateJwtKey(db, &models.JwtKey{Kid: "kid-2", Algorithm: "HS256"}, time.Now(), time.Now())
+	require.NoError(t, err)
+	assert.False(t, rotated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
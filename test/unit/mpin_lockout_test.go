@@ -0,0 +1,101 @@
+package unittest
+
+import (
+	"bankapi/config"
+	"bankapi/constants"
+	"bankapi/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMpinLockoutPeriods(t *testing.T) {
+	lockoutConfig, err := config.GetMpinLockoutConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 5, lockoutConfig.MaxAttempts)
+	assert.Equal(t, 5*time.Minute, lockoutConfig.LockoutPeriod(1))
+	assert.Equal(t, 30*time.Minute, lockoutConfig.LockoutPeriod(2))
+	assert.Equal(t, 24*time.Hour, lockoutConfig.LockoutPeriod(3))
+	assert.Equal(t, 24*time.Hour, lockoutConfig.LockoutPeriod(7))
+
+	for _, value := range []string{"300,,600", "0", "five"} {
+		t.Setenv("MPIN_LOCKOUT_PERIODS", value)
+		_, err := config.GetMpinLockoutConfig()
+		assert.Error(t, err, value)
+	}
+}
+
+func TestRecordMpinFailure(t *testing.T) {
+	lockoutConfig := &config.MpinLockoutConfig{
+		MaxAttempts: 3,
+		Periods:     []time.Duration{time.Minute, time.Hour},
+	}
+
+	tests := []struct {
+		name         string
+		attempts     int
+		lockoutCount int
+		wantLocked   bool
+		wantPeriod   time.Duration
+	}{
+		{name: "below the limit", attempts: 0, lockoutCount: 0, wantLocked: false},
+		{name: "first lockout", attempts: 2, lockoutCount: 0, wantLocked: true, wantPeriod: time.Minute},
+		{name: "escalated lockout", attempts: 2, lockoutCount: 1, wantLocked: true, wantPeriod: time.Hour},
+		{name: "last period repeats", attempts: 2, lockoutCount: 4, wantLocked: true, wantPeriod: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(`FROM mpin_attempt_data WHERE user_id = \$1 FOR UPDATE`).WithArgs("user-1").
+				WillReturnRows(sqlmock.NewRows([]string{"attempts", "lockout_count", "locked_until"}).AddRow(tt.attempts, tt.lockoutCount, nil))
+			mock.ExpectExec(`UPDATE mpin_attempt_data`).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			data, locked, err := models.RecordMpinFailure(db, "user-1", lockoutConfig)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantLocked, locked)
+			if tt.wantLocked {
+				assert.Equal(t, 0, data.Attempts)
+				assert.Equal(t, tt.lockoutCount+1, data.LockoutCount)
+				assert.True(t, data.IsLocked())
+				assert.WithinDuration(t, time.Now().Add(tt.wantPeriod), data.LockedUntil.Time, time.Second)
+			} else {
+				assert.Equal(t, tt.attempts+1, data.Attempts)
+				assert.False(t, data.IsLocked())
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRecordMpinFailureAlreadyLocked(t *testing.T) {
+	lockoutConfig := &config.MpinLockoutConfig{
+		MaxAttempts: 3,
+		Periods:     []time.Duration{time.Minute},
+	}
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	// a concurrent attempt locked the mpin between the caller's check and this one
+	lockedUntil := time.Now().Add(time.Minute)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM mpin_attempt_data WHERE user_id = \$1 FOR UPDATE`).WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"attempts", "lockout_count", "locked_until"}).AddRow(0, 1, lockedUntil))
+	mock.ExpectRollback()
+
+	data, locked, err := models.RecordMpinFailure(db, "user-1", lockoutConfig)
+	assert.ErrorIs(t, err, constants.ErrMpinLocked)
+	assert.False(t, locked)
+	assert.True(t, data.IsLocked())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
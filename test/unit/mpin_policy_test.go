@@ -0,0 +1,72 @@
+package unittest
+
+import (
+	"bankapi/constants"
+	"bankapi/models"
+	"bankapi/security"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestCheckMpinStrength(t *testing.T) {
+	tests := []struct {
+		mpin     string
+		wantCode string
+	}{
+		{mpin: "1111", wantCode: constants.MpinRepeatedDigitsErrorCode},
+		{mpin: "1212", wantCode: constants.MpinRepeatedDigitsErrorCode},
+		{mpin: "3456", wantCode: constants.MpinSequentialErrorCode},
+		{mpin: "9876", wantCode: constants.MpinSequentialErrorCode},
+		{mpin: "1503", wantCode: constants.MpinDateOfBirthErrorCode},
+		{mpin: "0315", wantCode: constants.MpinDateOfBirthErrorCode},
+		{mpin: "1992", wantCode: constants.MpinDateOfBirthErrorCode},
+		{mpin: "4170", wantCode: constants.MpinMobileNumberErrorCode},
+		{mpin: "2580", wantCode: ""},
+		{mpin: "7391", wantCode: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mpin, func(t *testing.T) {
+			err := security.CheckMpinStrength(tt.mpin, "15-03-1992", "9841705523")
+			if tt.wantCode == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var policyErr *security.MpinPolicyError
+			require.True(t, errors.As(err, &policyErr))
+			assert.Equal(t, tt.wantCode, policyErr.Code)
+		})
+	}
+
+	assert.Error(t, security.CheckMpinStrength("1992", "1992-03-15T00:00:00Z", ""))
+	assert.NoError(t, security.CheckMpinStrength("1503", "", ""))
+}
+
+func TestIsMpinInHistory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	oldHash, err := bcrypt.GenerateFromPassword([]byte("2580"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	mock.ExpectQuery(`FROM mpin_history`).WithArgs("user-1", 3).
+		WillReturnRows(sqlmock.NewRows([]string{"mpin_hash"}).AddRow(string(oldHash)))
+	reused, err := models.IsMpinInHistory(db, "user-1", "2580", 3)
+	require.NoError(t, err)
+	assert.True(t, reused)
+
+	mock.ExpectQuery(`FROM mpin_history`).WithArgs("user-1", 3).
+		WillReturnRows(sqlmock.NewRows([]string{"mpin_hash"}).AddRow(string(oldHash)))
+	reused, err = models.IsMpinInHistory(db, "user-1", "7391", 3)
+	require.NoError(t, err)
+	assert.False(t, reused)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
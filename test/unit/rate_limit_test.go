@@ -0,0 +1,152 @@
+package unittest
+
+import (
+	"bankapi/config"
+	"bankapi/middleware"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRateLimit(t *testing.T) {
+	rateLimit, err := config.GetRateLimit(config.RateLimitOtp)
+	require.NoError(t, err)
+	assert.Equal(t, 5, rateLimit.Limit)
+	assert.Equal(t, 5*time.Minute, rateLimit.Window)
+	assert.Equal(t, []string{config.RateLimitByUser, config.RateLimitByDevice}, rateLimit.KeyBy)
+
+	t.Setenv("RATE_LIMIT_OTP", "3/60")
+	rateLimit, err = config.GetRateLimit(config.RateLimitOtp)
+	require.NoError(t, err)
+	assert.Equal(t, 3, rateLimit.Limit)
+	assert.Equal(t, time.Minute, rateLimit.Window)
+}
+
+func TestGetRateLimitRejectsInvalidValues(t *testing.T) {
+	_, err := config.GetRateLimit("unknown")
+	assert.Error(t, err)
+
+	for _, value := range []string{"3", "0/60", "3/0", "three/60"} {
+		t.Setenv("RATE_LIMIT_MPIN", value)
+		_, err := config.GetRateLimit(config.RateLimitMpin)
+		assert.Error(t, err, value)
+	}
+}
+
+// newRateLimitedRouter serves /limited behind the policy, as user-1 when
+// authenticated is set
+func newRateLimitedRouter(t *testing.T, policy string, authenticated bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	require.NoError(t, router.SetTrustedProxies(nil))
+	router.POST("/limited", func(c *gin.Context) {
+		if authenticated {
+			c.Set("user_id", "user-1")
+		}
+	}, middleware.RateLimitMiddleware(policy), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func rateLimitedRequest(router *gin.Engine, deviceId string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/limited", nil)
+	req.Header.Set("X-Device-ID", deviceId)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestRateLimitMiddlewareSlidingWindow(t *testing.T) {
+	useTestRedis(t)
+	t.Setenv("RATE_LIMIT_MPIN", "2/1")
+	t.Setenv("RATE_LIMIT_BLOCK_AFTER", "0")
+	router := newRateLimitedRouter(t, config.RateLimitMpin, true)
+
+	assert.Equal(t, http.StatusOK, rateLimitedRequest(router, "device-1").Code)
+	assert.Equal(t, http.StatusOK, rateLimitedRequest(router, "device-1").Code)
+
+	rejected := rateLimitedRequest(router, "device-1")
+	assert.Equal(t, http.StatusTooManyRequests, rejected.Code)
+	assert.Equal(t, "1", rejected.Header().Get("Retry-After"))
+
+	// the user budget is shared by all devices of the user
+	assert.Equal(t, http.StatusTooManyRequests, rateLimitedRequest(router, "device-2").Code)
+
+	// requests leave the window one at a time once it has passed them
+	time.Sleep(1100 * time.Millisecond)
+	assert.Equal(t, http.StatusOK, rateLimitedRequest(router, "device-1").Code)
+}
+
+func TestRateLimitMiddlewareBlocksUser(t *testing.T) {
+	server := useTestRedis(t)
+	t.Setenv("RATE_LIMIT_MPIN", "1/60")
+	t.Setenv("RATE_LIMIT_BLOCK_AFTER", "2")
+	t.Setenv("RATE_LIMIT_BLOCK_DURATION", "900")
+	router := newRateLimitedRouter(t, config.RateLimitMpin, true)
+
+	assert.Equal(t, http.StatusOK, rateLimitedRequest(router, "device-1").Code)
+	// a new device id on every request must not escape the escalation
+	assert.Equal(t, http.StatusTooManyRequests, rateLimitedRequest(router, "device-2").Code)
+	assert.False(t, server.Exists("rate_limit_block:user:user-1"))
+	assert.Equal(t, http.StatusTooManyRequests, rateLimitedRequest(router, "device-3").Code)
+	assert.True(t, server.Exists("rate_limit_block:user:user-1"))
+
+	blocked := rateLimitedRequest(router, "device-4")
+	assert.Equal(t, http.StatusTooManyRequests, blocked.Code)
+	assert.Equal(t, "900", blocked.Header().Get("Retry-After"))
+}
+
+func rateLimitedLogin(router *gin.Engine, deviceId, mobileNumber string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/limited", strings.NewReader(`{"mobile_number":"`+mobileNumber+`"}`))
+	req.Header.Set("X-Device-ID", deviceId)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestRateLimitMiddlewareKeysLoginsByDeviceAndMobile(t *testing.T) {
+	useTestRedis(t)
+	t.Setenv("RATE_LIMIT_AUTHORIZATION", "1/60")
+	t.Setenv("TRUSTED_PROXIES", "none")
+	router := newRateLimitedRouter(t, config.RateLimitAuthorization, false)
+
+	assert.Equal(t, http.StatusOK, rateLimitedLogin(router, "device-1", "9000000001").Code)
+	assert.Equal(t, http.StatusTooManyRequests, rateLimitedLogin(router, "device-1", "9000000002").Code)
+	assert.Equal(t, http.StatusTooManyRequests, rateLimitedLogin(router, "device-2", "9000000001").Code)
+
+	// other users behind the same load balancer keep their budget
+	assert.Equal(t, http.StatusOK, rateLimitedLogin(router, "device-3", "9000000003").Code)
+}
+
+func TestRateLimitMiddlewareBlocksIpBeforeAuthentication(t *testing.T) {
+	server := useTestRedis(t)
+	t.Setenv("RATE_LIMIT_AUTHORIZATION", "1/60")
+	t.Setenv("RATE_LIMIT_BLOCK_AFTER", "1")
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8")
+	router := newRateLimitedRouter(t, config.RateLimitAuthorization, false)
+
+	assert.Equal(t, http.StatusOK, rateLimitedLogin(router, "device-1", "9000000001").Code)
+	assert.Equal(t, http.StatusTooManyRequests, rateLimitedLogin(router, "device-2", "9000000001").Code)
+	assert.True(t, server.Exists("rate_limit_block:ip:192.0.2.1"))
+	assert.Equal(t, http.StatusTooManyRequests, rateLimitedLogin(router, "device-3", "9000000003").Code)
+}
+
+func TestRateLimitMiddlewareDoesNotBlockIpWithoutTrustedProxies(t *testing.T) {
+	server := useTestRedis(t)
+	t.Setenv("RATE_LIMIT_AUTHORIZATION", "1/60")
+	t.Setenv("RATE_LIMIT_BLOCK_AFTER", "1")
+	t.Setenv("TRUSTED_PROXIES", "none")
+	router := newRateLimitedRouter(t, config.RateLimitAuthorization, false)
+
+	assert.Equal(t, http.StatusOK, rateLimitedLogin(router, "device-1", "9000000001").Code)
+	assert.Equal(t, http.StatusTooManyRequests, rateLimitedLogin(router, "device-2", "9000000001").Code)
+	assert.False(t, server.Exists("rate_limit_block:ip:192.0.2.1"))
+	assert.Equal(t, http.StatusOK, rateLimitedLogin(router, "device-3", "9000000003").Code)
+}
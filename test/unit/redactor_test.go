@@ -0,0 +1,45 @@
+package unittest
+
+import (
+	"bankapi/services"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactorMasksPII(t *testing.T) {
+	redactor := services.NewRedactor(map[string]string{"CustName": services.RedactName})
+
+	body := []byte(`{
+		"ApplicantId": "APP0001",
+		"AccountNo": "1234567890123456",
+		"BenfMobNo": "9876543210",
+		"BenfName": "Ravi Kumar",
+		"CustName": "Ravi Kumar",
+		"Details": [{"AadhaarNo": "123412341234", "Amount": "100"}],
+		"Amount": 100
+	}`)
+
+	var got map[string]interface{}
+	require.NoError(t, json.Unmarshal(redactor.Redact(body), &got))
+
+	assert.Equal(t, "APP0001", got["ApplicantId"])
+	assert.Equal(t, "XXXXXXXXXXXX3456", got["AccountNo"])
+	assert.Equal(t, "XXXXXX3210", got["BenfMobNo"])
+	assert.Equal(t, "REDACTED NAME", got["BenfName"])
+	assert.Equal(t, "REDACTED NAME", got["CustName"])
+	assert.Equal(t, float64(100), got["Amount"])
+
+	details := got["Details"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "XXXXXXXX1234", details["AadhaarNo"])
+	assert.Equal(t, "100", details["Amount"])
+}
+
+func TestRedactorKeepsNonJSONBody(t *testing.T) {
+	redactor := services.NewRedactor(nil)
+
+	body := []byte("grant_type=client_credentials")
+	assert.Equal(t, body, redactor.Redact(body))
+}
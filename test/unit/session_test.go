@@ -0,0 +1,325 @@
+package unittest
+
+import (
+	"bankapi/config"
+	"bankapi/constants"
+	"bankapi/devicekey"
+	"bankapi/models"
+	"bankapi/requests"
+	"bankapi/security"
+	"bankapi/sessionkey"
+	"bankapi/stores/authorization"
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+
+	commonSrv "bitbucket.org/paydoh/paydoh-commons/services"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateOpaqueTokenIsUnique(t *testing.T) {
+	first, err := security.GenerateOpaqueToken(32)
+	require.NoError(t, err)
+	second, err := security.GenerateOpaqueToken(32)
+	require.NoError(t, err)
+
+	assert.Len(t, first, 43)
+	assert.NotEqual(t, first, second)
+}
+
+func TestHashTokenIsStable(t *testing.T) {
+	hash := security.HashToken("refresh-token")
+
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, security.HashToken("refresh-token"))
+	assert.NotEqual(t, hash, security.HashToken("refresh-token2"))
+}
+
+func TestDeriveSessionKeyMatchesTheApp(t *testing.T) {
+	clientKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	clientDer, err := x509.MarshalPKIXPublicKey(clientKey.PublicKey())
+	require.NoError(t, err)
+
+	serverPublicKey, sessionKey, err := security.DeriveSessionKey(base64.StdEncoding.EncodeToString(clientDer))
+	require.NoError(t, err)
+	assert.Len(t, sessionKey, 32)
+
+	// what the app does with the server public key of the login response
+	serverDer, err := base64.StdEncoding.DecodeString(serverPublicKey)
+	require.NoError(t, err)
+	parsed, err := x509.ParsePKIXPublicKey(serverDer)
+	require.NoError(t, err)
+	ecdsaKey, ok := parsed.(*ecdsa.PublicKey)
+	require.True(t, ok)
+	serverKey, err := ecdsaKey.ECDH()
+	require.NoError(t, err)
+
+	secret, err := clientKey.ECDH(serverKey)
+	require.NoError(t, err)
+	key := make([]byte, 16)
+	_, err = io.ReadFull(hkdf.New(sha256.New, secret, nil, []byte("paydoh-session-key")), key)
+	require.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(key), sessionKey)
+
+	encrypted, err := security.Encrypt([]byte("payload"), []byte(sessionKey))
+	require.NoError(t, err)
+	decrypted, err := security.Decrypt(encrypted, []byte(hex.EncodeToString(key)))
+	require.NoError(t, err)
+	assert.Equal(t, "payload", decrypted)
+}
+
+func TestDeriveSessionKeyRejectsInvalidKeys(t *testing.T) {
+	otherCurve, err := ecdh.P384().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(otherCurve.PublicKey())
+	require.NoError(t, err)
+
+	for _, value := range []string{"", "not a key", base64.StdEncoding.EncodeToString(der)} {
+		_, _, err := security.DeriveSessionKey(value)
+		assert.ErrorIs(t, err, security.ErrInvalidSessionPublicKey, value)
+	}
+}
+
+// newSessionStore returns an authorization store on a mocked db with the
+// keys of session-1 of user-1 saved in redis
+func newSessionStore(t *testing.T) (*authorization.AuthorizationStore, sqlmock.Sqlmock, *miniredis.Miniredis) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	passphrase, jwtKey := constants.AesPassPhrase, constants.JwtKey
+	constants.AesPassPhrase = "0123456789abcdef0123456789abcdef"
+	constants.JwtKey = "jwt-key"
+	t.Cleanup(func() {
+		constants.AesPassPhrase = passphrase
+		constants.JwtKey = jwtKey
+	})
+
+	server := useTestRedis(t)
+	require.NoError(t, sessionkey.Save("session-1", &sessionkey.Session{
+		UserId:     "user-1",
+		Key:        "session-key",
+		SigningKey: "signing-key",
+		Exchanged:  true,
+	}, time.Hour))
+
+	store := authorization.NewAuthorizationStore(&commonSrv.LoggerService{}, db, context.Background(), nil, config.GetRedis(), nil, nil)
+	return store, mock, server
+}
+
+func userSessionRows(userId, deviceId string) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{
+		"id", "user_id", "device_id", "user_agent", "ip_address",
+		"created_at", "last_used_at", "expires_at", "revoked_at", "revoked_reason",
+	}).AddRow(
+		"session-1", userId, deviceId, "app", "192.0.2.1",
+		time.Now(), time.Now(), time.Now().Add(time.Hour), nil, nil,
+	)
+}
+
+func refreshTokenRows(usedAt interface{}) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "session_id", "expires_at", "used_at"}).
+		AddRow("token-1", "session-1", time.Now().Add(time.Hour), usedAt)
+}
+
+func deviceContext(deviceId string) context.Context {
+	return context.WithValue(context.Background(), "device_id", deviceId)
+}
+
+func TestRefreshTokenRotates(t *testing.T) {
+	store, mock, _ := newSessionStore(t)
+
+	mock.ExpectQuery("UPDATE refresh_tokens SET used_at").
+		WithArgs(security.HashToken("refresh-1")).
+		WillReturnRows(refreshTokenRows(time.Now()))
+	mock.ExpectQuery("SELECT (.+) FROM user_sessions").
+		WithArgs("session-1").
+		WillReturnRows(userSessionRows("user-1", "device-1"))
+	mock.ExpectExec("UPDATE user_sessions SET last_used_at").
+		WithArgs("session-1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO refresh_tokens").
+		WithArgs("session-1", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	tokens, err := store.RefreshToken(deviceContext("device-1"), &requests.RefreshTokenRequest{RefreshToken: "refresh-1"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "session-1", tokens.SessionId)
+	assert.NotEmpty(t, tokens.RefreshToken)
+	assert.NotEqual(t, "refresh-1", tokens.RefreshToken)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	keys, err := sessionkey.Load("session-1")
+	require.NoError(t, err)
+	assert.Equal(t, "session-key", keys.Key)
+}
+
+func TestRefreshTokenOfLegacySessionNeedsOptIn(t *testing.T) {
+	store, mock, server := newSessionStore(t)
+	// a session from before the keys were kept server side
+	server.Del("session:session-1")
+
+	mock.ExpectQuery("UPDATE refresh_tokens SET used_at").
+		WithArgs(security.HashToken("refresh-1")).
+		WillReturnRows(refreshTokenRows(time.Now()))
+	mock.ExpectQuery("SELECT (.+) FROM user_sessions").
+		WithArgs("session-1").
+		WillReturnRows(userSessionRows("user-1", "device-1"))
+
+	_, err := store.RefreshToken(deviceContext("device-1"), &requests.RefreshTokenRequest{RefreshToken: "refresh-1"})
+
+	assert.EqualError(t, err, constants.InvalidRefreshTokenErrorMessage)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRefreshTokenReuseRevokesSession(t *testing.T) {
+	store, mock, server := newSessionStore(t)
+
+	mock.ExpectQuery("UPDATE refresh_tokens SET used_at").
+		WithArgs(security.HashToken("refresh-1")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "session_id", "expires_at", "used_at"}))
+	mock.ExpectQuery("SELECT (.+) FROM refresh_tokens").
+		WithArgs(security.HashToken("refresh-1")).
+		WillReturnRows(refreshTokenRows(time.Now()))
+	mock.ExpectExec("UPDATE user_sessions SET revoked_at").
+		WithArgs("session-1", models.SessionRevokedReuse).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err := store.RefreshToken(deviceContext("device-1"), &requests.RefreshTokenRequest{RefreshToken: "refresh-1"})
+
+	assert.EqualError(t, err, constants.InvalidRefreshTokenErrorMessage)
+	assert.False(t, server.Exists("session:session-1"), "the keys of the session should be dropped")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRefreshTokenFromAnotherDeviceRevokesSession(t *testing.T) {
+	store, mock, server := newSessionStore(t)
+
+	mock.ExpectQuery("UPDATE refresh_tokens SET used_at").
+		WithArgs(security.HashToken("refresh-1")).
+		WillReturnRows(refreshTokenRows(time.Now()))
+	mock.ExpectQuery("SELECT (.+) FROM user_sessions").
+		WithArgs("session-1").
+		WillReturnRows(userSessionRows("user-1", "device-1"))
+	mock.ExpectExec("UPDATE user_sessions SET revoked_at").
+		WithArgs("session-1", models.SessionRevokedDeviceMismatch).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err := store.RefreshToken(deviceContext("device-2"), &requests.RefreshTokenRequest{RefreshToken: "refresh-1"})
+
+	assert.EqualError(t, err, constants.InvalidRefreshTokenErrorMessage)
+	assert.False(t, server.Exists("session:session-1"), "the keys of the session should be dropped")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRevokeSession(t *testing.T) {
+	store, mock, server := newSessionStore(t)
+
+	mock.ExpectQuery("SELECT (.+) FROM user_sessions").
+		WithArgs("session-1").
+		WillReturnRows(userSessionRows("user-1", "device-1"))
+	mock.ExpectExec("UPDATE user_sessions SET revoked_at").
+		WithArgs("session-1", models.SessionRevokedByUser).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := store.RevokeSession(context.Background(), &models.AuthValues{UserId: "user-1", SessionId: "session-2"}, "session-1")
+
+	require.NoError(t, err)
+	assert.False(t, server.Exists("session:session-1"), "the access token of the session should stop working")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRevokeSessionOfAnotherUser(t *testing.T) {
+	store, mock, server := newSessionStore(t)
+
+	mock.ExpectQuery("SELECT (.+) FROM user_sessions").
+		WithArgs("session-1").
+		WillReturnRows(userSessionRows("user-1", "device-1"))
+
+	err := store.RevokeSession(context.Background(), &models.AuthValues{UserId: "user-2", SessionId: "session-2"}, "session-1")
+
+	assert.ErrorIs(t, err, constants.ErrNoDataFound)
+	assert.True(t, server.Exists("session:session-1"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func userDataRows(userId string) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "user_id", "mobile_number", "signing_key", "created_at", "updated_at"}).
+		AddRow("00000000-0000-0000-0000-000000000001", userId, "9999999999", "signing-key", time.Now(), time.Now())
+}
+
+func deviceKeyRows(userId, deviceId string) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{
+		"id", "user_id", "device_data_id", "device_id", "algorithm",
+		"public_key", "status", "last_used_at", "revoked_at", "created_at",
+	}).AddRow(
+		"key-1", userId, "device-data-1", deviceId, "ES256",
+		"public-key", models.DeviceKeyStatusActive, nil, nil, time.Now(),
+	)
+}
+
+func TestAuthorizationByUserIdRequiresDeviceKeyChallenge(t *testing.T) {
+	store, mock, server := newSessionStore(t)
+
+	// a challenge of the key of another device of the user
+	challenge, err := devicekey.Issue(context.Background(), &models.DeviceKey{
+		Id:       "key-2",
+		UserId:   "user-1",
+		DeviceId: "device-2",
+	}, devicekey.PurposeLogin, "")
+	require.NoError(t, err)
+
+	for _, challengeId := range []string{"", "unknown", challenge.Id} {
+		mock.ExpectQuery("FROM user_data").
+			WithArgs("user-1").
+			WillReturnRows(userDataRows("user-1"))
+		if challengeId == "" {
+			mock.ExpectQuery("FROM device_keys").
+				WithArgs("user-1", "device-1", models.DeviceKeyStatusActive).
+				WillReturnRows(deviceKeyRows("user-1", "device-1"))
+		}
+
+		_, err := store.GetAuthorizationTokenByUserId(deviceContext("device-1"), &requests.AuthorizationRequest{
+			UserId:      "user-1",
+			ChallengeId: challengeId,
+			Signature:   "signature",
+		})
+		assert.ErrorIs(t, err, devicekey.ErrInvalidChallenge, challengeId)
+	}
+
+	// no session was created and the current one is kept
+	assert.True(t, server.Exists("session:session-1"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuthorizationByUserIdWithoutDeviceKey(t *testing.T) {
+	store, mock, _ := newSessionStore(t)
+
+	mock.ExpectQuery("FROM user_data").
+		WithArgs("user-1").
+		WillReturnRows(userDataRows("user-1"))
+	mock.ExpectQuery("FROM device_keys").
+		WithArgs("user-1", "device-1", models.DeviceKeyStatusActive).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	// the login goes on without a challenge and stops at the missing ip
+	_, err := store.GetAuthorizationTokenByUserId(deviceContext("device-1"), &requests.AuthorizationRequest{UserId: "user-1"})
+
+	assert.EqualError(t, err, "device ip should not be empty")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
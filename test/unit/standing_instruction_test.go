@@ -0,0 +1,95 @@
+package unittest
+
+import (
+	"bankapi/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStandingInstructionNextRunAfter(t *testing.T) {
+	ist := time.FixedZone("IST", 5*60*60+30*60)
+	at := func(date string, hour int) time.Time {
+		day, _ := time.ParseInLocation("2006-01-02", date, ist)
+		return day.Add(time.Duration(hour) * time.Hour)
+	}
+	endDate := func(date string) models.NullString {
+		var end models.NullString
+		end.String, end.Valid = date, true
+		return end
+	}
+
+	tests := []struct {
+		name string
+		si   models.StandingInstruction
+		from time.Time
+		want time.Time
+	}{
+		{
+			name: "once before its date",
+			si:   models.StandingInstruction{Frequency: models.StandingInstructionOnce, StartDate: "2025-06-10"},
+			from: at("2025-06-01", 12),
+			want: at("2025-06-10", 9),
+		},
+		{
+			name: "once after its run",
+			si:   models.StandingInstruction{Frequency: models.StandingInstructionOnce, StartDate: "2025-06-10"},
+			from: at("2025-06-10", 9),
+		},
+		{
+			name: "weekly catches up to the next week",
+			si:   models.StandingInstruction{Frequency: models.StandingInstructionWeekly, StartDate: "2025-06-02"},
+			from: at("2025-06-20", 12),
+			want: at("2025-06-23", 9),
+		},
+		{
+			name: "weekly until the end date",
+			si:   models.StandingInstruction{Frequency: models.StandingInstructionWeekly, StartDate: "2025-06-02", EndDate: endDate("2025-06-09")},
+			from: at("2025-06-02", 9),
+			want: at("2025-06-09", 9),
+		},
+		{
+			name: "weekly after the end date",
+			si:   models.StandingInstruction{Frequency: models.StandingInstructionWeekly, StartDate: "2025-06-02", EndDate: endDate("2025-06-09")},
+			from: at("2025-06-09", 9),
+		},
+		{
+			name: "monthly on the day of the start date",
+			si:   models.StandingInstruction{Frequency: models.StandingInstructionMonthly, StartDate: "2025-01-15"},
+			from: at("2025-01-01", 0),
+			want: at("2025-01-15", 9),
+		},
+		{
+			name: "monthly on a day before the start date",
+			si:   models.StandingInstruction{Frequency: models.StandingInstructionMonthly, StartDate: "2025-01-20", DayOfMonth: 5},
+			from: at("2025-01-01", 0),
+			want: at("2025-02-05", 9),
+		},
+		{
+			name: "monthly on the last day of a short month",
+			si:   models.StandingInstruction{Frequency: models.StandingInstructionMonthly, StartDate: "2025-01-31", DayOfMonth: 31},
+			from: at("2025-02-01", 0),
+			want: at("2025-02-28", 9),
+		},
+		{
+			name: "monthly after a short month",
+			si:   models.StandingInstruction{Frequency: models.StandingInstructionMonthly, StartDate: "2025-01-31", DayOfMonth: 31},
+			from: at("2025-02-28", 9),
+			want: at("2025-03-31", 9),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, ok := tt.si.NextRunAfter(tt.from)
+			if tt.want.IsZero() {
+				assert.False(t, ok)
+				return
+			}
+
+			assert.True(t, ok)
+			assert.True(t, tt.want.Equal(next), "want %s, got %s", tt.want, next)
+		})
+	}
+}
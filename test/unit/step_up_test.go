@@ -0,0 +1,151 @@
+package unittest
+
+import (
+	"bankapi/config"
+	"bankapi/constants"
+	"bankapi/middleware"
+	"bankapi/stepup"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var stepUpSubject = &stepup.Subject{UserId: "user-1", SessionId: "session-1", DeviceId: "device-1"}
+
+func TestStepUpMissingUntilGranted(t *testing.T) {
+	useTestRedis(t)
+	policy := &config.StepUpPolicy{
+		Policy:  config.StepUpBulkPayout,
+		Factors: []string{config.StepUpFactorMpin, config.StepUpFactorOtp},
+		MaxAge:  5 * time.Minute,
+	}
+
+	missing, err := stepup.Missing(context.Background(), nil, policy, stepUpSubject)
+	require.NoError(t, err)
+	assert.Equal(t, []string{config.StepUpFactorMpin, config.StepUpFactorOtp}, missing)
+
+	require.NoError(t, stepup.Grant(context.Background(), stepUpSubject, config.StepUpFactorMpin))
+
+	missing, err = stepup.Missing(context.Background(), nil, policy, stepUpSubject)
+	require.NoError(t, err)
+	assert.Equal(t, []string{config.StepUpFactorOtp}, missing)
+
+	require.NoError(t, stepup.Grant(context.Background(), stepUpSubject, config.StepUpFactorOtp))
+
+	missing, err = stepup.Missing(context.Background(), nil, policy, stepUpSubject)
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+
+	otherSession := &stepup.Subject{UserId: "user-1", SessionId: "session-2", DeviceId: "device-1"}
+	missing, err = stepup.Missing(context.Background(), nil, policy, otherSession)
+	require.NoError(t, err)
+	assert.Equal(t, []string{config.StepUpFactorMpin, config.StepUpFactorOtp}, missing, "grants should not unlock another session")
+}
+
+func TestStepUpMissingStaleGrant(t *testing.T) {
+	server := useTestRedis(t)
+	policy := &config.StepUpPolicy{
+		Policy:  config.StepUpCardControl,
+		Factors: []string{config.StepUpFactorMpin},
+		MaxAge:  5 * time.Minute,
+	}
+
+	key := fmt.Sprintf(constants.StepUpGrantKeyFormat, "user-1", "session-1", config.StepUpFactorMpin)
+	require.NoError(t, server.Set(key, strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10)))
+
+	missing, err := stepup.Missing(context.Background(), nil, policy, stepUpSubject)
+	require.NoError(t, err)
+	assert.Equal(t, []string{config.StepUpFactorMpin}, missing)
+}
+
+func TestStepUpMissingUntrustedDevice(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	policy := &config.StepUpPolicy{
+		Policy:  config.StepUpDeviceBound,
+		Factors: []string{config.StepUpFactorDevice},
+	}
+
+	mock.ExpectQuery("FROM trusted_devices").
+		WithArgs("user-1", "device-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	missing, err := stepup.Missing(context.Background(), db, policy, stepUpSubject)
+	require.NoError(t, err)
+	assert.Equal(t, []string{config.StepUpFactorDevice}, missing)
+
+	missing, err = stepup.Missing(context.Background(), db, policy, &stepup.Subject{UserId: "user-1"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{config.StepUpFactorDevice}, missing, "requests without a device id are not trusted")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStepUpGrantRequiresUser(t *testing.T) {
+	useTestRedis(t)
+
+	assert.Error(t, stepup.Grant(context.Background(), &stepup.Subject{SessionId: "session-1"}, config.StepUpFactorMpin))
+}
+
+// newStepUpRouter serves /beneficiary behind StepUpMiddleware for session-1 of user-1
+func newStepUpRouter(t *testing.T) *gin.Engine {
+	useTestRedis(t)
+	t.Setenv("STEP_UP_BENEFICIARY", "mpin/300")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/beneficiary", func(c *gin.Context) {
+		c.Set("user_id", "user-1")
+		c.Set("session_id", "session-1")
+		c.Set("device_id", "device-1")
+	}, middleware.StepUpMiddleware(config.StepUpBeneficiary), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": http.StatusOK})
+	})
+	return router
+}
+
+func stepUpRequest(router *gin.Engine) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/beneficiary", nil))
+	return recorder
+}
+
+func TestStepUpMiddlewareRejectsMissingFactors(t *testing.T) {
+	router := newStepUpRouter(t)
+
+	recorder := stepUpRequest(router)
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+
+	var body struct {
+		ErrorCode string `json:"ERROR_CODE"`
+		Data      struct {
+			Policy    string   `json:"policy"`
+			Factors   []string `json:"factors"`
+			MaxAgeSec int64    `json:"max_age_sec"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	assert.Equal(t, constants.StepUpRequiredErrorCode, body.ErrorCode)
+	assert.Equal(t, config.StepUpBeneficiary, body.Data.Policy)
+	assert.Equal(t, []string{config.StepUpFactorMpin}, body.Data.Factors)
+	assert.Equal(t, int64(300), body.Data.MaxAgeSec)
+}
+
+func TestStepUpMiddlewareAllowsGrantedSession(t *testing.T) {
+	router := newStepUpRouter(t)
+
+	require.NoError(t, stepup.Grant(context.Background(), stepUpSubject, config.StepUpFactorMpin))
+
+	assert.Equal(t, http.StatusOK, stepUpRequest(router).Code)
+}
@@ -0,0 +1,209 @@
+package unittest
+
+import (
+	"bankapi/config"
+	"bankapi/constants"
+	"bankapi/models"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTransferLimit(t *testing.T) {
+	limit, err := config.GetTransferLimit(config.TransferTierFullKyc, config.TransferModeImps)
+	require.NoError(t, err)
+	assert.Equal(t, int64(500000), limit.PerTransaction)
+	assert.Equal(t, int64(2500000), limit.Monthly)
+
+	t.Setenv("TRANSFER_LIMIT_MIN_KYC_UPI", "5000/10000/20000")
+	limit, err = config.GetTransferLimit(config.TransferTierMinKyc, config.TransferModeUpi)
+	require.NoError(t, err)
+	assert.Equal(t, config.TransferLimit{
+		Tier:           config.TransferTierMinKyc,
+		Mode:           config.TransferModeUpi,
+		PerTransaction: 5000,
+		Daily:          10000,
+		Monthly:        20000,
+	}, *limit)
+
+	t.Setenv("TRANSFER_LIMIT_MIN_KYC_UPI", "5000/10000")
+	_, err = config.GetTransferLimit(config.TransferTierMinKyc, config.TransferModeUpi)
+	assert.Error(t, err)
+
+	_, err = config.GetTransferLimit(config.TransferTierMinKyc, "RTGS")
+	assert.Error(t, err)
+}
+
+func TestParseAmountPaise(t *testing.T) {
+	tests := []struct {
+		amount  string
+		paise   int64
+		wantErr bool
+	}{
+		{amount: "100", paise: 10000},
+		{amount: "100.5", paise: 10050},
+		{amount: "0.05", paise: 5},
+		{amount: "100.", wantErr: true},
+		{amount: "100.005", wantErr: true},
+		{amount: "-1", wantErr: true},
+		{amount: "abc", wantErr: true},
+		{amount: "1000000000000.99", paise: 100000000000099},
+		{amount: "1000000000001", wantErr: true},
+		// r*100 overflows an int64 without the bound
+		{amount: "92233720368547759", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount, func(t *testing.T) {
+			paise, err := models.ParseAmountPaise(tt.amount)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.paise, paise)
+		})
+	}
+}
+
+func TestFormatPaise(t *testing.T) {
+	assert.Equal(t, "100.50", models.FormatPaise(10050))
+	assert.Equal(t, "0.05", models.FormatPaise(5))
+	assert.Equal(t, "0.00", models.FormatPaise(0))
+}
+
+func expectFullKycUser(mock sqlmock.Sqlmock, userDaily int64) {
+	mock.ExpectQuery(`FROM account_data`).WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "account_number", "customer_id", "upi_id", "created_at", "updated_at"}).
+			AddRow("5f0c7a52-6d0c-4b8e-9a41-3f1d2f4f8b10", "user-1", "1234567890123", "customer-1", nil, time.Now().AddDate(-1, 0, 0), time.Now()))
+	mock.ExpectQuery(`FROM kyc_audit_data`).WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"vkyc_audit_status"}).AddRow("approved"))
+	mock.ExpectQuery(`FROM user_transfer_limits`).WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "payment_mode", "per_transaction", "daily", "monthly", "updated_at"}).
+			AddRow("user-1", config.TransferModeImps, 0, userDaily, 0, time.Now()))
+}
+
+func TestReserveTransferLimit(t *testing.T) {
+	t.Run("within limits", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		expectFullKycUser(mock, 20000)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO transfer_limit_usage`).
+			WithArgs("user-1", config.TransferModeImps, models.TransferLimitPeriodDay, sqlmock.AnyArg(), int64(1500050), int64(2000000)).
+			WillReturnRows(sqlmock.NewRows([]string{"amount"}).AddRow(1500050))
+		mock.ExpectQuery(`INSERT INTO transfer_limit_usage`).
+			WithArgs("user-1", config.TransferModeImps, models.TransferLimitPeriodMonth, sqlmock.AnyArg(), int64(1500050), int64(250000000)).
+			WillReturnRows(sqlmock.NewRows([]string{"amount"}).AddRow(1500050))
+		mock.ExpectCommit()
+
+		reservation, err := models.ReserveTransferLimit(db, "user-1", "imps", "15000.50")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1500050), reservation.Amount)
+		assert.Equal(t, config.TransferModeImps, reservation.PaymentMode)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("above daily limit", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		expectFullKycUser(mock, 20000)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO transfer_limit_usage`).
+			WillReturnRows(sqlmock.NewRows([]string{"amount"}))
+		mock.ExpectRollback()
+		mock.ExpectQuery(`FROM transfer_limit_usage`).
+			WillReturnRows(sqlmock.NewRows([]string{"payment_mode", "period", "amount"}).
+				AddRow(config.TransferModeImps, models.TransferLimitPeriodDay, 1500050).
+				AddRow(config.TransferModeImps, models.TransferLimitPeriodMonth, 1500050))
+
+		_, err = models.ReserveTransferLimit(db, "user-1", config.TransferModeImps, "5000")
+		assert.EqualError(t, err, fmt.Sprintf(constants.TransferLimitDailyErrorMessage, config.TransferModeImps, "4999.50"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("above per transaction limit", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		expectFullKycUser(mock, 0)
+
+		_, err = models.ReserveTransferLimit(db, "user-1", config.TransferModeImps, "500000.01")
+		assert.EqualError(t, err, fmt.Sprintf(constants.TransferLimitPerTransactionErrorMessage, config.TransferModeImps, "500000.00"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPaymentReservationMatches(t *testing.T) {
+	reservation := models.NewPaymentReservation("txn-1", &models.TransferLimitReservation{
+		UserId:      "user-1",
+		PaymentMode: config.TransferModeImps,
+		Amount:      150050,
+	}, nil, nil, "123456789012", "HDFC0001234", time.Now())
+
+	assert.True(t, reservation.Matches("imps", "1500.50", "123456789012", "HDFC0001234"))
+	assert.False(t, reservation.Matches("imps", "1500.51", "123456789012", "HDFC0001234"))
+	assert.False(t, reservation.Matches("imps", "1500.50", "123456789013", "HDFC0001234"))
+	assert.False(t, reservation.Matches("imps", "1500.50", "123456789012", "HDFC0001235"))
+	assert.False(t, reservation.Matches("neft", "1500.50", "123456789012", "HDFC0001234"))
+}
+
+var paymentReservationReleaseColumns = []string{
+	"user_id", "payment_mode", "amount", "day", "month",
+	"cooling_beneficiary_id", "cooling_amount", "cooling_device_id", "device_cooling_amount",
+}
+
+func TestReleasePaymentReservation(t *testing.T) {
+	t.Run("reserved", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		day := time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM payment_reservations`).
+			WithArgs("txn-1", models.PaymentReservationStatusReserved).
+			WillReturnRows(sqlmock.NewRows(paymentReservationReleaseColumns).
+				AddRow("user-1", config.TransferModeImps, 150050, day, day.AddDate(0, 0, -9), "ben-1", 150050, "device-row", 150050))
+		mock.ExpectExec(`UPDATE transfer_limit_usage`).
+			WithArgs("user-1", config.TransferModeImps, models.TransferLimitPeriodDay, "2025-06-10", models.TransferLimitPeriodMonth, int64(150050), "2025-06-01").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(`UPDATE beneficiaries SET cooling_used`).
+			WithArgs("ben-1", int64(150050)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE trusted_devices SET cooling_used`).
+			WithArgs("device-row", int64(150050)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE payment_reservations`).
+			WithArgs("txn-1", models.PaymentReservationStatusReleased).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		assert.NoError(t, models.ReleasePaymentReservation(db, "txn-1"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("already released or confirmed", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM payment_reservations`).
+			WithArgs("txn-1", models.PaymentReservationStatusReserved).
+			WillReturnRows(sqlmock.NewRows(paymentReservationReleaseColumns))
+		mock.ExpectRollback()
+
+		assert.NoError(t, models.ReleasePaymentReservation(db, "txn-1"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,145 @@
+package unittest
+
+import (
+	"bankapi/config"
+	"bankapi/constants"
+	"bankapi/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReserveDeviceCooling(t *testing.T) {
+	t.Setenv("DEVICE_COOLING_PAYMENT_LIMIT", "5000")
+
+	coolingUntil := time.Now().Add(time.Hour)
+
+	t.Run("device not cooling", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery(`FROM trusted_devices`).WithArgs("user-1", "device-1", models.DeviceStatusTrusted).
+			WillReturnError(sql.ErrNoRows)
+
+		reservation, err := models.ReserveDeviceCooling(db, "user-1", "device-1", "100000")
+		require.NoError(t, err)
+		assert.Nil(t, reservation)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("within what is left of the limit", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery(`FROM trusted_devices`).WithArgs("user-1", "device-1", models.DeviceStatusTrusted).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "cooling_until", "cooling_used"}).AddRow("device-row", coolingUntil, 300000))
+		mock.ExpectQuery(`UPDATE trusted_devices SET cooling_used`).WithArgs("device-row", int64(200000), int64(500000)).
+			WillReturnRows(sqlmock.NewRows([]string{"cooling_used"}).AddRow(500000))
+
+		reservation, err := models.ReserveDeviceCooling(db, "user-1", "device-1", "2000")
+		require.NoError(t, err)
+		assert.Equal(t, &models.DeviceCoolingReservation{TrustedDeviceId: "device-row", Amount: 200000}, reservation)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("above what is left of the limit", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		// each payment is within the limit, together they are not
+		mock.ExpectQuery(`FROM trusted_devices`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "cooling_until", "cooling_used"}).AddRow("device-row", coolingUntil, 300000))
+		mock.ExpectQuery(`UPDATE trusted_devices SET cooling_used`).
+			WillReturnRows(sqlmock.NewRows([]string{"cooling_used"}))
+
+		_, err = models.ReserveDeviceCooling(db, "user-1", "device-1", "2000.01")
+		assert.EqualError(t, err, fmt.Sprintf(constants.DeviceCoolingLimitErrorMessage,
+			"5000.00", coolingUntil.In(config.ISTLocation()).Format("02 Jan 2006, 03:04 PM"), "2000.00"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestTrustDeviceRevokesPreviousDevices(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	device := &models.TrustedDevice{Id: "device-row", UserId: "user-1", DeviceId: "device-2"}
+	deviceData := models.NewDeviceData()
+	deviceData.UserId = device.UserId
+	deviceData.DeviceId = "encrypted-device-2"
+	deviceData.IsActive = true
+	coolingUntil := time.Now().Add(24 * time.Hour)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE trusted_devices`).
+		WithArgs(device.Id, models.DeviceStatusTrusted, coolingUntil, models.DeviceStatusPending).
+		WillReturnRows(sqlmock.NewRows([]string{"trusted_at", "cooling_until"}).AddRow(time.Now(), coolingUntil))
+	mock.ExpectQuery(`UPDATE trusted_devices SET status`).
+		WithArgs(device.UserId, device.Id, models.DeviceStatusRevoked, models.DeviceStatusTrusted).
+		WillReturnRows(sqlmock.NewRows([]string{"device_id"}).AddRow("device-1"))
+	mock.ExpectExec(`UPDATE device_data SET`).
+		WithArgs("encrypted-device-2", true, device.UserId).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	revoked, err := models.TrustDevice(db, device, coolingUntil, deviceData, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"device-1"}, revoked)
+	assert.Equal(t, models.DeviceStatusTrusted, device.Status)
+	assert.True(t, device.InCoolingPeriod())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTrustDeviceRollsBackWhenDeviceDataFails(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	device := &models.TrustedDevice{Id: "device-row", UserId: "user-1", DeviceId: "device-2"}
+	deviceData := models.NewDeviceData()
+	deviceData.UserId = device.UserId
+	deviceData.DeviceId = "encrypted-device-2"
+	coolingUntil := time.Now().Add(24 * time.Hour)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE trusted_devices`).
+		WillReturnRows(sqlmock.NewRows([]string{"trusted_at", "cooling_until"}).AddRow(time.Now(), coolingUntil))
+	mock.ExpectQuery(`UPDATE trusted_devices SET status`).
+		WillReturnRows(sqlmock.NewRows([]string{"device_id"}))
+	mock.ExpectExec(`INSERT INTO device_data`).WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+
+	_, err = models.TrustDevice(db, device, coolingUntil, deviceData, false)
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertEnrolledDevice(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	deviceData := models.NewDeviceData()
+	deviceData.UserId = "user-1"
+	deviceData.DeviceId = "encrypted-device-1"
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO device_data`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO trusted_devices`).
+		WithArgs("user-1", "device-1", models.DeviceStatusTrusted, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, models.InsertEnrolledDevice(db, deviceData, &models.TrustedDevice{UserId: "user-1", DeviceId: "device-1"}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -161,3 +161,28 @@ func TestParseName(t *testing.T) {
 		}
 	}
 }
+
+func TestRequestHash(t *testing.T) {
+	base := utils.RequestHash("/api/upi/pay-vpa", []byte(`{"amount":"100","payee_vpa":"a@kvb"}`))
+
+	tests := []struct {
+		name    string
+		route   string
+		payload string
+		same    bool
+	}{
+		{"same payload", "/api/upi/pay-vpa", `{"amount":"100","payee_vpa":"a@kvb"}`, true},
+		{"reordered keys and whitespace", "/api/upi/pay-vpa", "{ \"payee_vpa\": \"a@kvb\",\n \"amount\": \"100\" }", true},
+		{"different amount", "/api/upi/pay-vpa", `{"amount":"1000","payee_vpa":"a@kvb"}`, false},
+		{"different route", "/api/beneficiary/payment", `{"amount":"100","payee_vpa":"a@kvb"}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := utils.RequestHash(tt.route, []byte(tt.payload))
+			if (got == base) != tt.same {
+				t.Errorf("RequestHash() equal = %v, want %v", got == base, tt.same)
+			}
+		})
+	}
+}
@@ -1,7 +1,10 @@
 package utils
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,6 +12,7 @@ import (
 	"math/rand"
 	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"bitbucket.org/paydoh/paydoh-commons/database"
@@ -164,6 +168,27 @@ func GetRequestIDFromContext(ctx context.Context) string {
 	return userID
 }
 
+// WithBankCallTracking returns a context in which MarkBankCalled records that
+// a payment was sent to the bank
+func WithBankCallTracking(ctx context.Context) context.Context {
+	return context.WithValue(ctx, "bank_called", new(atomic.Bool))
+}
+
+// MarkBankCalled records that a payment was sent to the bank, from then on
+// its outcome is not known unless the bank answered
+func MarkBankCalled(ctx context.Context) {
+	if called, ok := ctx.Value("bank_called").(*atomic.Bool); ok {
+		called.Store(true)
+	}
+}
+
+// BankCalled reports whether MarkBankCalled was called for a context made by
+// WithBankCallTracking
+func BankCalled(ctx context.Context) bool {
+	called, ok := ctx.Value("bank_called").(*atomic.Bool)
+	return ok && called.Load()
+}
+
 func GetSourceIPFromContext(ctx context.Context) string {
 	value := ctx.Value("source_ip")
 	if value == nil {
@@ -206,6 +231,21 @@ func GetDeviceIdFromContext(ctx context.Context) string {
 	return deviceId
 }
 
+// GetLanguageFromContext returns the Accept-Language header of the request
+func GetLanguageFromContext(ctx context.Context) string {
+	value := ctx.Value("language")
+	if value == nil {
+		return ""
+	}
+
+	language, ok := value.(string)
+	if !ok {
+		return ""
+	}
+
+	return language
+}
+
 func GetAppVersionFromContext(ctx context.Context) string {
 	value := ctx.Value("app_version")
 	if value == nil {
@@ -369,3 +409,22 @@ func DateFormat(dateString string) string {
 	newFormat := "02-01-2006 03:04 PM"
 	return parsedTime.Format(newFormat)
 }
+
+// RequestHash fingerprints a request for idempotency checks. JSON payloads are
+// hashed in canonical form so key order and whitespace do not matter.
+func RequestHash(route string, payload []byte) string {
+	var data interface{}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err == nil {
+		if canonical, err := json.Marshal(data); err == nil {
+			payload = canonical
+		}
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(route))
+	hash.Write([]byte{0})
+	hash.Write(payload)
+	return hex.EncodeToString(hash.Sum(nil))
+}